@@ -0,0 +1,127 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	adminpb "github.com/ortuman/jackal/pkg/admin/pb"
+	"github.com/spf13/cobra"
+)
+
+var (
+	federationList string
+	federationAuth string
+)
+
+// NewFederationCommand returns the cobra command for "federation".
+func NewFederationCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "federation <subcommand>",
+		Short: "S2S federation policy related commands",
+	}
+
+	ac.AddCommand(newFederationShowCommand())
+	ac.AddCommand(newFederationModeCommand())
+	ac.AddCommand(newFederationDomainCommand())
+
+	return ac
+}
+
+func newFederationShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Shows current federation policy",
+		Run:   federationShowCommandFunc,
+	}
+}
+
+func newFederationModeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "mode <allowlist|denylist>",
+		Short: "Sets federation policy mode",
+		Run:   federationModeCommandFunc,
+	}
+}
+
+func newFederationDomainCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "domain <pattern> [options]",
+		Short: "Updates federation policy of a domain pattern",
+		Run:   federationDomainCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&federationList, "list", "none", "List the domain pattern belongs to (allow, deny or none)")
+	cmd.Flags().StringVar(&federationAuth, "auth", "", "Required authentication method (any, certificate or dialback)")
+
+	return &cmd
+}
+
+// federationShowCommandFunc executes the "federation show" command.
+func federationShowCommandFunc(cmd *cobra.Command, _ []string) {
+	cc, ctx, cancel := mustFederationClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.GetPolicy(ctx, &adminpb.GetPolicyRequest{})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.GetFederationPolicy(resp)
+}
+
+// federationModeCommandFunc executes the "federation mode" command.
+func federationModeCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("federation mode command requires mode as its argument"))
+	}
+	cc, ctx, cancel := mustFederationClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.SetMode(ctx, &adminpb.SetModeRequest{Mode: args[0]})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.SetFederationMode(args[0], resp)
+}
+
+// federationDomainCommandFunc executes the "federation domain" command.
+func federationDomainCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("federation domain command requires domain pattern as its argument"))
+	}
+	var list adminpb.DomainList
+	switch federationList {
+	case "none":
+		list = adminpb.DomainList_DOMAIN_LIST_NONE
+	case "allow":
+		list = adminpb.DomainList_DOMAIN_LIST_ALLOW
+	case "deny":
+		list = adminpb.DomainList_DOMAIN_LIST_DENY
+	default:
+		ExitWithError(ExitBadArgs, fmt.Errorf("invalid list value: %s", federationList))
+	}
+	cc, ctx, cancel := mustFederationClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.SetDomain(ctx, &adminpb.SetDomainRequest{
+		Domain: args[0],
+		List:   list,
+		Auth:   federationAuth,
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.SetFederationDomain(args[0], resp)
+}
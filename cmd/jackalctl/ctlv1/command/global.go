@@ -57,6 +57,18 @@ func mustUsersClientFromCmd(cmd *cobra.Command) (adminpb.UsersClient, context.Co
 	return adminpb.NewUsersClient(conn), ctx, cancel
 }
 
+func mustInvitesClientFromCmd(cmd *cobra.Command) (adminpb.InvitesClient, context.Context, context.CancelFunc) {
+	conn := connFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	return adminpb.NewInvitesClient(conn), ctx, cancel
+}
+
+func mustFederationClientFromCmd(cmd *cobra.Command) (adminpb.FederationClient, context.Context, context.CancelFunc) {
+	conn := connFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	return adminpb.NewFederationClient(conn), ctx, cancel
+}
+
 func initDisplayFromCmd(cmd *cobra.Command) {
 	display = &simplePrinter{}
 }
@@ -0,0 +1,76 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"time"
+
+	adminpb "github.com/ortuman/jackal/pkg/admin/pb"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var (
+	inviteMaxUses int
+	inviteExpiry  time.Duration
+)
+
+// NewInviteCommand returns the cobra command for "invite".
+func NewInviteCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "invite <subcommand>",
+		Short: "Invite related commands",
+	}
+
+	ac.AddCommand(newInviteCreateCommand())
+
+	return ac
+}
+
+func newInviteCreateCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "create <domain> [options]",
+		Short: "Creates a new account registration invite",
+		Run:   inviteCreateCommandFunc,
+	}
+
+	cmd.Flags().IntVar(&inviteMaxUses, "max-uses", 1, "Number of accounts that can be registered using the invite (0 means unlimited)")
+	cmd.Flags().DurationVar(&inviteExpiry, "expiry", time.Hour*24*7, "Invite validity period")
+
+	return &cmd
+}
+
+// inviteCreateCommandFunc executes the "invite create" command.
+func inviteCreateCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("invite create command requires domain as its argument"))
+	}
+	if inviteMaxUses < 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("invalid max uses value: %d", inviteMaxUses))
+	}
+	cc, ctx, cancel := mustInvitesClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.CreateInvite(ctx, &adminpb.CreateInviteRequest{
+		Domain:  args[0],
+		MaxUses: int32(inviteMaxUses),
+		Expiry:  durationpb.New(inviteExpiry),
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.CreateInvite(resp)
+}
@@ -16,6 +16,8 @@ package command
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	adminpb "github.com/ortuman/jackal/pkg/admin/pb"
 )
@@ -24,6 +26,10 @@ type printer interface {
 	CreateUser(name string, _ *adminpb.CreateUserResponse)
 	ChangeUserPassword(*adminpb.ChangeUserPasswordResponse)
 	DeleteUser(string, *adminpb.DeleteUserResponse)
+	CreateInvite(*adminpb.CreateInviteResponse)
+	GetFederationPolicy(*adminpb.GetPolicyResponse)
+	SetFederationMode(mode string, _ *adminpb.SetModeResponse)
+	SetFederationDomain(domain string, _ *adminpb.SetDomainResponse)
 }
 
 type simplePrinter struct{}
@@ -39,3 +45,24 @@ func (p *simplePrinter) ChangeUserPassword(*adminpb.ChangeUserPasswordResponse)
 func (p *simplePrinter) DeleteUser(user string, _ *adminpb.DeleteUserResponse) {
 	fmt.Printf("User %s deleted\n", user)
 }
+
+func (p *simplePrinter) CreateInvite(resp *adminpb.CreateInviteResponse) {
+	fmt.Printf("Invite %s created (expires at %s)\n", resp.GetUri(), resp.GetExpiresAt().AsTime().Format(time.RFC3339))
+}
+
+func (p *simplePrinter) GetFederationPolicy(resp *adminpb.GetPolicyResponse) {
+	fmt.Printf("Mode: %s\n", resp.GetMode())
+	fmt.Printf("Allow: %s\n", strings.Join(resp.GetAllow(), ", "))
+	fmt.Printf("Deny: %s\n", strings.Join(resp.GetDeny(), ", "))
+	for _, ds := range resp.GetDomains() {
+		fmt.Printf("Domain %s requires %s auth\n", ds.GetDomain(), ds.GetAuth())
+	}
+}
+
+func (p *simplePrinter) SetFederationMode(mode string, _ *adminpb.SetModeResponse) {
+	fmt.Printf("Federation mode set to %s\n", mode)
+}
+
+func (p *simplePrinter) SetFederationDomain(domain string, _ *adminpb.SetDomainResponse) {
+	fmt.Printf("Federation policy for %s updated\n", domain)
+}
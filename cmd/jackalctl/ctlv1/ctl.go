@@ -51,6 +51,8 @@ func init() {
 
 	rootCmd.AddCommand(
 		command.NewUserCommand(),
+		command.NewInviteCommand(),
+		command.NewFederationCommand(),
 		command.NewVersionCommand(),
 	)
 }
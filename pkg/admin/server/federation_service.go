@@ -0,0 +1,96 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminserver
+
+import (
+	"context"
+	"errors"
+
+	kitlog "github.com/go-kit/log"
+
+	"github.com/go-kit/log/level"
+
+	federationpb "github.com/ortuman/jackal/pkg/admin/pb"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type federationService struct {
+	federationpb.UnimplementedFederationServer
+	policy *federation.Sync
+	logger kitlog.Logger
+}
+
+func newFederationService(policy *federation.Sync, logger kitlog.Logger) federationpb.FederationServer {
+	return &federationService{
+		policy: policy,
+		logger: logger,
+	}
+}
+
+func (s *federationService) GetPolicy(_ context.Context, _ *federationpb.GetPolicyRequest) (*federationpb.GetPolicyResponse, error) {
+	cfg := s.policy.Config()
+
+	resp := &federationpb.GetPolicyResponse{
+		Mode:  cfg.Mode,
+		Allow: cfg.Allow,
+		Deny:  cfg.Deny,
+	}
+	for _, dc := range cfg.Domains {
+		resp.Domains = append(resp.Domains, &federationpb.DomainSettings{
+			Domain: dc.Domain,
+			Auth:   dc.Auth,
+		})
+	}
+	return resp, nil
+}
+
+func (s *federationService) SetMode(ctx context.Context, req *federationpb.SetModeRequest) (*federationpb.SetModeResponse, error) {
+	if err := s.policy.SetMode(ctx, req.GetMode()); err != nil {
+		return nil, policyUpdateError(err)
+	}
+	level.Info(s.logger).Log("msg", "federation policy mode updated", "mode", req.GetMode())
+
+	return &federationpb.SetModeResponse{}, nil
+}
+
+func (s *federationService) SetDomain(ctx context.Context, req *federationpb.SetDomainRequest) (*federationpb.SetDomainResponse, error) {
+	var list federation.List
+	switch req.GetList() {
+	case federationpb.DomainList_DOMAIN_LIST_ALLOW:
+		list = federation.AllowList
+	case federationpb.DomainList_DOMAIN_LIST_DENY:
+		list = federation.DenyList
+	default:
+		list = federation.NoList
+	}
+	if err := s.policy.SetDomain(ctx, req.GetDomain(), list, req.GetAuth()); err != nil {
+		return nil, policyUpdateError(err)
+	}
+	level.Info(s.logger).Log("msg", "federation domain policy updated",
+		"domain", req.GetDomain(),
+		"list", req.GetList().String(),
+		"auth", req.GetAuth(),
+	)
+	return &federationpb.SetDomainResponse{}, nil
+}
+
+func policyUpdateError(err error) error {
+	if errors.Is(err, federation.ErrInvalidConfig) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminserver
+
+import (
+	"context"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+
+	"github.com/go-kit/log/level"
+
+	invitespb "github.com/ortuman/jackal/pkg/admin/pb"
+	"github.com/ortuman/jackal/pkg/module/xep0401"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultInviteExpiry = time.Hour * 24 * 7
+
+type invitesService struct {
+	invitespb.UnimplementedInvitesServer
+	invites *xep0401.Invites
+	logger  kitlog.Logger
+}
+
+func newInvitesService(rep repository.Repository, logger kitlog.Logger) invitespb.InvitesServer {
+	return &invitesService{
+		invites: xep0401.NewInvites(rep),
+		logger:  logger,
+	}
+}
+
+func (s *invitesService) CreateInvite(ctx context.Context, req *invitespb.CreateInviteRequest) (*invitespb.CreateInviteResponse, error) {
+	domain := req.GetDomain()
+	if len(domain) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invite domain not specified")
+	}
+	expiry := defaultInviteExpiry
+	if req.GetExpiry() != nil {
+		expiry = req.GetExpiry().AsDuration()
+	}
+	inv, err := s.invites.Issue(ctx, domain, "", int(req.GetMaxUses()), expiry)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	level.Info(s.logger).Log("msg", "invite created", "domain", domain, "max_uses", inv.MaxUses)
+
+	return &invitespb.CreateInviteResponse{
+		Token:     inv.Token,
+		Uri:       xep0401.URI(inv),
+		ExpiresAt: inv.ExpiresAt,
+	}, nil
+}
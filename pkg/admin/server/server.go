@@ -28,6 +28,7 @@ import (
 	adminpb "github.com/ortuman/jackal/pkg/admin/pb"
 	"github.com/ortuman/jackal/pkg/auth/pepper"
 	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
 	"github.com/ortuman/jackal/pkg/storage/repository"
 	"google.golang.org/grpc"
 )
@@ -44,6 +45,7 @@ type Server struct {
 	rep     repository.Repository
 	peppers *pepper.Keys
 	hk      *hook.Hooks
+	policy  *federation.Sync
 	logger  kitlog.Logger
 }
 
@@ -60,6 +62,7 @@ func New(
 	rep repository.Repository,
 	peppers *pepper.Keys,
 	hk *hook.Hooks,
+	policy *federation.Sync,
 	logger kitlog.Logger,
 ) *Server {
 	if cfg.Disabled {
@@ -71,6 +74,7 @@ func New(
 		rep:      rep,
 		peppers:  peppers,
 		hk:       hk,
+		policy:   policy,
 		logger:   logger,
 	}
 }
@@ -94,6 +98,8 @@ func (s *Server) Start(_ context.Context) error {
 			grpc.UnaryInterceptor(grpc_prometheus.UnaryServerInterceptor),
 		)
 		adminpb.RegisterUsersServer(grpcServer, newUsersService(s.rep, s.peppers, s.hk, s.logger))
+		adminpb.RegisterInvitesServer(grpcServer, newInvitesService(s.rep, s.logger))
+		adminpb.RegisterFederationServer(grpcServer, newFederationService(s.policy, s.logger))
 		if err := grpcServer.Serve(s.ln); err != nil {
 			if atomic.LoadInt32(&s.active) == 1 {
 				level.Error(s.logger).Log("msg", "admin server error", "err", err)
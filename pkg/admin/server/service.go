@@ -15,33 +15,23 @@
 package adminserver
 
 import (
-	"bytes"
 	"context"
-	"crypto/rand"
-	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
-	"encoding/base64"
 	"fmt"
-	"hash"
 
 	kitlog "github.com/go-kit/log"
 
 	"github.com/go-kit/log/level"
 
 	userspb "github.com/ortuman/jackal/pkg/admin/pb"
+	"github.com/ortuman/jackal/pkg/auth"
 	"github.com/ortuman/jackal/pkg/auth/pepper"
 	"github.com/ortuman/jackal/pkg/hook"
 	usermodel "github.com/ortuman/jackal/pkg/model/user"
 	"github.com/ortuman/jackal/pkg/storage/repository"
-	"golang.org/x/crypto/pbkdf2"
-	"golang.org/x/crypto/sha3"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-const iterationCount = 15_000
-
 type usersService struct {
 	userspb.UnimplementedUsersServer
 	rep     repository.Repository
@@ -89,6 +79,10 @@ func (s *usersService) ChangeUserPassword(ctx context.Context, req *userspb.Chan
 	if err := s.upsertUser(ctx, username, req.GetNewPassword()); err != nil {
 		return nil, err
 	}
+	// revoke all issued FAST tokens
+	if err := s.rep.DeleteFastTokens(ctx, username); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 	level.Info(s.logger).Log("msg", "password updated", "username", username)
 
 	return &userspb.ChangeUserPasswordResponse{}, nil
@@ -102,6 +96,9 @@ func (s *usersService) DeleteUser(ctx context.Context, req *userspb.DeleteUserRe
 	if err := s.rep.DeleteUser(ctx, username); err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	if err := s.rep.DeleteFastTokens(ctx, username); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 	// run user deleted hook
 	_, err := s.hk.Run(hook.UserDeleted, &hook.ExecutionContext{
 		Info: &hook.UserInfo{
@@ -140,40 +137,16 @@ func (s *usersService) ensureUserAlreadyExists(ctx context.Context, username str
 }
 
 func (s *usersService) upsertUser(ctx context.Context, username, password string) error {
-	salt := make([]byte, 32)
-	_, err := rand.Read(salt)
+	scram, err := auth.NewScramCredentials(password, s.peppers)
 	if err != nil {
 		return status.Error(codes.Internal, err.Error())
 	}
-	buf := bytes.NewBuffer(salt)
-	pp := s.peppers.GetActiveKey()
-	buf.WriteString(pp)
-	pepperedSalt := buf.Bytes()
-
-	// generate password hashes
-	hSHA1 := hashPassword([]byte(password), pepperedSalt, iterationCount, sha1.Size, sha1.New)
-	hSHA256 := hashPassword([]byte(password), pepperedSalt, iterationCount, sha256.Size, sha256.New)
-	hSHA512 := hashPassword([]byte(password), pepperedSalt, iterationCount, sha512.Size, sha512.New)
-	hSHA3512 := hashPassword([]byte(password), pepperedSalt, iterationCount, sha512.Size, sha3.New512)
-
 	usr := usermodel.User{
 		Username: username,
-		Scram:    &usermodel.Scram{},
-	}
-	usr.Scram.Sha1 = base64.RawURLEncoding.EncodeToString(hSHA1)
-	usr.Scram.Sha256 = base64.RawURLEncoding.EncodeToString(hSHA256)
-	usr.Scram.Sha512 = base64.RawURLEncoding.EncodeToString(hSHA512)
-	usr.Scram.Sha3512 = base64.RawURLEncoding.EncodeToString(hSHA3512)
-	usr.Scram.Salt = base64.RawURLEncoding.EncodeToString(salt)
-	usr.Scram.IterationCount = iterationCount
-	usr.Scram.PepperId = s.peppers.GetActiveID()
-
+		Scram:    scram,
+	}
 	if err := s.rep.UpsertUser(ctx, &usr); err != nil {
 		return status.Error(codes.Internal, err.Error())
 	}
 	return nil
 }
-
-func hashPassword(password, salt []byte, iterations int, hKeyLen int, h func() hash.Hash) []byte {
-	return pbkdf2.Key(password, salt, iterations, hKeyLen, h)
-}
@@ -0,0 +1,106 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/hook"
+	usermodel "github.com/ortuman/jackal/pkg/model/user"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+// Anonymous represents a SASL ANONYMOUS authenticator (RFC 4505).
+// Every successful authentication provisions a new ephemeral user with a random username.
+type Anonymous struct {
+	rep           repository.User
+	hk            *hook.Hooks
+	username      string
+	authenticated bool
+}
+
+// NewAnonymous returns a new anonymous authenticator instance.
+func NewAnonymous(rep repository.User, hk *hook.Hooks) *Anonymous {
+	return &Anonymous{
+		rep: rep,
+		hk:  hk,
+	}
+}
+
+// Mechanism returns authenticator mechanism name.
+func (a *Anonymous) Mechanism() string {
+	return "ANONYMOUS"
+}
+
+// Username returns authenticated username in case authentication process has been completed.
+func (a *Anonymous) Username() string {
+	if a.authenticated {
+		return a.username
+	}
+	return ""
+}
+
+// Authenticated returns whether or not user has been authenticated.
+func (a *Anonymous) Authenticated() bool {
+	return a.authenticated
+}
+
+// UsesChannelBinding returns whether or not anonymous authenticator requires channel binding bytes.
+func (a *Anonymous) UsesChannelBinding() bool {
+	return false
+}
+
+// ProcessElement process an incoming authenticator element.
+func (a *Anonymous) ProcessElement(ctx context.Context, elem stravaganza.Element) (stravaganza.Element, *SASLError) {
+	if elem.Name() != "auth" || a.authenticated {
+		return nil, newSASLError(NotAuthorized, nil)
+	}
+	// trace information is intentionally ignored (RFC 4505, section 2)
+	username := uuid.New().String()
+
+	exists, err := a.rep.UserExists(ctx, username)
+	if err != nil {
+		return nil, newSASLError(TemporaryAuthFailure, err)
+	}
+	if exists {
+		return nil, newSASLError(TemporaryAuthFailure, nil)
+	}
+	if err := a.rep.UpsertUser(ctx, &usermodel.User{Username: username}); err != nil {
+		return nil, newSASLError(TemporaryAuthFailure, err)
+	}
+	_, err = a.hk.Run(hook.UserCreated, &hook.ExecutionContext{
+		Info: &hook.UserInfo{
+			Username: username,
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, newSASLError(TemporaryAuthFailure, err)
+	}
+	a.username = username
+	a.authenticated = true
+
+	return stravaganza.NewBuilder("success").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		Build(), nil
+}
+
+// Reset resets anonymous authenticator internal state.
+func (a *Anonymous) Reset() {
+	a.authenticated = false
+	a.username = ""
+}
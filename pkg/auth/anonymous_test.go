@@ -0,0 +1,113 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/hook"
+	usermodel "github.com/ortuman/jackal/pkg/model/user"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymous_Authenticate(t *testing.T) {
+	// given
+	var provisioned *usermodel.User
+
+	repMock := &usersRepository{}
+	repMock.UserExistsFunc = func(_ context.Context, _ string) (bool, error) {
+		return false, nil
+	}
+	repMock.UpsertUserFunc = func(_ context.Context, user *usermodel.User) error {
+		provisioned = user
+		return nil
+	}
+	var createdUsername string
+
+	hk := hook.NewHooks()
+	hk.AddHook(hook.UserCreated, func(execCtx *hook.ExecutionContext) error {
+		createdUsername = execCtx.Info.(*hook.UserInfo).Username
+		return nil
+	}, hook.DefaultPriority)
+
+	authr := NewAnonymous(repMock, hk)
+
+	// when
+	resp, saslErr := authr.ProcessElement(context.Background(), anonymousAuthElement())
+
+	// then
+	require.Nil(t, saslErr)
+	require.Equal(t, "success", resp.Name())
+	require.True(t, authr.Authenticated())
+	require.NotEmpty(t, authr.Username())
+
+	require.NotNil(t, provisioned)
+	require.Equal(t, authr.Username(), provisioned.Username)
+	require.Equal(t, authr.Username(), createdUsername)
+}
+
+func TestAnonymous_UniqueUsernames(t *testing.T) {
+	// given
+	repMock := &usersRepository{}
+	repMock.UserExistsFunc = func(_ context.Context, _ string) (bool, error) {
+		return false, nil
+	}
+	repMock.UpsertUserFunc = func(_ context.Context, _ *usermodel.User) error {
+		return nil
+	}
+	authr := NewAnonymous(repMock, hook.NewHooks())
+
+	// when
+	_, saslErr := authr.ProcessElement(context.Background(), anonymousAuthElement())
+	require.Nil(t, saslErr)
+	username0 := authr.Username()
+
+	authr.Reset()
+
+	_, saslErr = authr.ProcessElement(context.Background(), anonymousAuthElement())
+	require.Nil(t, saslErr)
+	username1 := authr.Username()
+
+	// then
+	require.NotEqual(t, username0, username1)
+}
+
+func TestAnonymous_ExistingUser(t *testing.T) {
+	// given
+	repMock := &usersRepository{}
+	repMock.UserExistsFunc = func(_ context.Context, _ string) (bool, error) {
+		return true, nil
+	}
+	authr := NewAnonymous(repMock, hook.NewHooks())
+
+	// when
+	_, saslErr := authr.ProcessElement(context.Background(), anonymousAuthElement())
+
+	// then
+	require.NotNil(t, saslErr)
+	require.Equal(t, TemporaryAuthFailure, saslErr.Reason)
+	require.Len(t, repMock.UpsertUserCalls(), 0)
+	require.False(t, authr.Authenticated())
+}
+
+func anonymousAuthElement() stravaganza.Element {
+	return stravaganza.NewBuilder("auth").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		WithAttribute("mechanism", "ANONYMOUS").
+		WithText("=").
+		Build()
+}
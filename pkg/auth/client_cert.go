@@ -0,0 +1,238 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/transport"
+)
+
+var (
+	oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidXMPPAddr       = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 8, 5}
+)
+
+var errNoCertificateIdentity = errors.New("auth: no valid identity found in client certificate")
+
+type localHosts interface {
+	IsLocalHost(h string) bool
+}
+
+// otherName represents an X.509 SAN otherName entry (RFC 5280, section 4.2.1.6).
+type otherName struct {
+	TypeID asn1.ObjectIdentifier
+	Value  asn1.RawValue // [0] EXPLICIT ANY
+}
+
+// ClientCertificate represents a SASL EXTERNAL authenticator based on TLS client certificates.
+type ClientCertificate struct {
+	tr            transport.Transport
+	roots         *x509.CertPool
+	hosts         localHosts
+	rep           repository.User
+	username      string
+	authenticated bool
+}
+
+// NewClientCertificate returns a new client certificate authenticator instance.
+func NewClientCertificate(
+	tr transport.Transport,
+	roots *x509.CertPool,
+	hosts localHosts,
+	rep repository.User,
+) *ClientCertificate {
+	return &ClientCertificate{
+		tr:    tr,
+		roots: roots,
+		hosts: hosts,
+		rep:   rep,
+	}
+}
+
+// Mechanism returns authenticator mechanism name.
+func (c *ClientCertificate) Mechanism() string {
+	return "EXTERNAL"
+}
+
+// Username returns authenticated username in case authentication process has been completed.
+func (c *ClientCertificate) Username() string {
+	if c.authenticated {
+		return c.username
+	}
+	return ""
+}
+
+// Authenticated returns whether or not user has been authenticated.
+func (c *ClientCertificate) Authenticated() bool {
+	return c.authenticated
+}
+
+// UsesChannelBinding returns whether or not client certificate authenticator requires channel binding bytes.
+func (c *ClientCertificate) UsesChannelBinding() bool {
+	return false
+}
+
+// ProcessElement process an incoming authenticator element.
+func (c *ClientCertificate) ProcessElement(ctx context.Context, elem stravaganza.Element) (stravaganza.Element, *SASLError) {
+	if elem.Name() != "auth" || c.authenticated {
+		return nil, newSASLError(NotAuthorized, nil)
+	}
+	// an empty authorization identity is transmitted as a single '=' character (RFC 6120, section 6.4.2)
+	var authzID string
+	if txt := elem.Text(); len(txt) > 0 && txt != "=" {
+		b, err := base64.StdEncoding.DecodeString(txt)
+		if err != nil {
+			return nil, newSASLError(IncorrectEncoding, err)
+		}
+		authzID = string(b)
+	}
+	certs := c.tr.PeerCertificates()
+	if len(certs) == 0 {
+		return nil, newSASLError(NotAuthorized, nil)
+	}
+	if err := c.verifyChain(certs); err != nil {
+		return nil, newSASLError(NotAuthorized, err)
+	}
+	username, err := c.getUsername(certs[0], authzID)
+	if err != nil {
+		return nil, newSASLError(NotAuthorized, err)
+	}
+	exists, err := c.rep.UserExists(ctx, username)
+	if err != nil {
+		return nil, newSASLError(TemporaryAuthFailure, err)
+	}
+	if !exists {
+		return nil, newSASLError(NotAuthorized, nil)
+	}
+	c.username = username
+	c.authenticated = true
+
+	return stravaganza.NewBuilder("success").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		Build(), nil
+}
+
+// Reset resets client certificate authenticator internal state.
+func (c *ClientCertificate) Reset() {
+	c.authenticated = false
+	c.username = ""
+}
+
+func (c *ClientCertificate) verifyChain(certs []*x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
+
+func (c *ClientCertificate) getUsername(cert *x509.Certificate, authzID string) (string, error) {
+	// certificate xmppAddr identities take precedence over subject common name (RFC 6120, section 13.7.1.4)
+	ids := xmppAddrs(cert)
+	if len(ids) == 0 && len(cert.Subject.CommonName) > 0 {
+		ids = []string{cert.Subject.CommonName}
+	}
+	var usernames []string
+	for _, id := range ids {
+		if username := c.usernameFromIdentity(id); len(username) > 0 {
+			usernames = append(usernames, username)
+		}
+	}
+	switch {
+	case len(usernames) == 0:
+		return "", errNoCertificateIdentity
+
+	case len(authzID) > 0:
+		username := c.usernameFromIdentity(authzID)
+		for _, u := range usernames {
+			if u == username {
+				return username, nil
+			}
+		}
+		return "", errNoCertificateIdentity
+
+	case len(usernames) > 1:
+		return "", errors.New("auth: ambiguous client certificate identity")
+	}
+	return usernames[0], nil
+}
+
+func (c *ClientCertificate) usernameFromIdentity(id string) string {
+	if !strings.Contains(id, "@") {
+		// plain username, domain value is irrelevant here
+		j, err := jid.New(id, "localhost", "", false)
+		if err != nil {
+			return ""
+		}
+		return j.Node()
+	}
+	j, err := jid.NewWithString(id, false)
+	if err != nil || !j.IsBare() || !c.hosts.IsLocalHost(j.Domain()) {
+		return ""
+	}
+	return j.Node()
+}
+
+func xmppAddrs(cert *x509.Certificate) []string {
+	var addrs []string
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSubjectAltName) {
+			continue
+		}
+		var seq asn1.RawValue
+		if _, err := asn1.Unmarshal(ext.Value, &seq); err != nil || !seq.IsCompound {
+			return nil
+		}
+		rest := seq.Bytes
+		for len(rest) > 0 {
+			var gn asn1.RawValue
+			var err error
+
+			rest, err = asn1.Unmarshal(rest, &gn)
+			if err != nil {
+				return addrs
+			}
+			if gn.Class != asn1.ClassContextSpecific || gn.Tag != 0 {
+				continue // not an otherName
+			}
+			var on otherName
+			if _, err := asn1.UnmarshalWithParams(gn.FullBytes, &on, "tag:0"); err != nil || !on.TypeID.Equal(oidXMPPAddr) {
+				continue
+			}
+			if on.Value.Class != asn1.ClassContextSpecific || on.Value.Tag != 0 {
+				continue
+			}
+			var addr string
+			if _, err := asn1.UnmarshalWithParams(on.Value.Bytes, &addr, "utf8"); err == nil {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,239 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCertificate_Authenticate(t *testing.T) {
+	ca, caKey := testCACertificate(t)
+
+	var tcs = map[string]struct {
+		commonName       string
+		xmppAddrs        []string
+		authzID          string
+		expectedUsername string
+		expectedErr      SASLErrorReason
+	}{
+		"xmppAddr": {
+			commonName:       "Miguel Ángel",
+			xmppAddrs:        []string{"ortuman@jackal.im"},
+			expectedUsername: "ortuman",
+		},
+		"CommonName": {
+			commonName:       "ortuman",
+			expectedUsername: "ortuman",
+		},
+		"CommonNameJID": {
+			commonName:       "ortuman@jackal.im",
+			expectedUsername: "ortuman",
+		},
+		"AuthzID": {
+			xmppAddrs:        []string{"noelia@jackal.im", "ortuman@jackal.im"},
+			authzID:          "ortuman@jackal.im",
+			expectedUsername: "ortuman",
+		},
+		"Ambiguous": {
+			xmppAddrs:   []string{"noelia@jackal.im", "ortuman@jackal.im"},
+			expectedErr: NotAuthorized,
+		},
+		"AuthzIDMismatch": {
+			xmppAddrs:   []string{"ortuman@jackal.im"},
+			authzID:     "noelia@jackal.im",
+			expectedErr: NotAuthorized,
+		},
+		"RemoteDomain": {
+			xmppAddrs:   []string{"ortuman@example.org"},
+			expectedErr: NotAuthorized,
+		},
+		"UnknownUser": {
+			xmppAddrs:   []string{"noelia@jackal.im"},
+			expectedErr: NotAuthorized,
+		},
+	}
+	for tn, tc := range tcs {
+		t.Run(tn, func(t *testing.T) {
+			// given
+			cert := testClientCertificate(t, ca, caKey, tc.commonName, tc.xmppAddrs)
+
+			trMock := &transportMock{}
+			trMock.PeerCertificatesFunc = func() []*x509.Certificate {
+				return []*x509.Certificate{cert}
+			}
+			authr := NewClientCertificate(trMock, testCertPool(ca), testHostsMock(), testUserExistsMock("ortuman"))
+
+			// when
+			resp, saslErr := authr.ProcessElement(context.Background(), externalAuthElement(tc.authzID))
+
+			// then
+			if tc.expectedErr != 0 {
+				require.NotNil(t, saslErr)
+				require.Equal(t, tc.expectedErr, saslErr.Reason)
+				require.False(t, authr.Authenticated())
+				return
+			}
+			require.Nil(t, saslErr)
+			require.Equal(t, "success", resp.Name())
+			require.True(t, authr.Authenticated())
+			require.Equal(t, tc.expectedUsername, authr.Username())
+		})
+	}
+}
+
+func TestClientCertificate_UntrustedCertificate(t *testing.T) {
+	// given
+	ca, _ := testCACertificate(t)
+	otherCA, otherCAKey := testCACertificate(t)
+
+	cert := testClientCertificate(t, otherCA, otherCAKey, "", []string{"ortuman@jackal.im"})
+
+	trMock := &transportMock{}
+	trMock.PeerCertificatesFunc = func() []*x509.Certificate {
+		return []*x509.Certificate{cert}
+	}
+	authr := NewClientCertificate(trMock, testCertPool(ca), testHostsMock(), testUserExistsMock("ortuman"))
+
+	// when
+	_, saslErr := authr.ProcessElement(context.Background(), externalAuthElement(""))
+
+	// then
+	require.NotNil(t, saslErr)
+	require.Equal(t, NotAuthorized, saslErr.Reason)
+}
+
+func TestClientCertificate_NoPeerCertificates(t *testing.T) {
+	// given
+	ca, _ := testCACertificate(t)
+
+	trMock := &transportMock{}
+	trMock.PeerCertificatesFunc = func() []*x509.Certificate { return nil }
+
+	authr := NewClientCertificate(trMock, testCertPool(ca), testHostsMock(), testUserExistsMock("ortuman"))
+
+	// when
+	_, saslErr := authr.ProcessElement(context.Background(), externalAuthElement(""))
+
+	// then
+	require.NotNil(t, saslErr)
+	require.Equal(t, NotAuthorized, saslErr.Reason)
+}
+
+func externalAuthElement(authzID string) stravaganza.Element {
+	txt := "="
+	if len(authzID) > 0 {
+		txt = base64.StdEncoding.EncodeToString([]byte(authzID))
+	}
+	return stravaganza.NewBuilder("auth").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		WithAttribute("mechanism", "EXTERNAL").
+		WithText(txt).
+		Build()
+}
+
+func testHostsMock() *hostsMock {
+	return &hostsMock{
+		IsLocalHostFunc: func(h string) bool { return h == "jackal.im" },
+	}
+}
+
+func testUserExistsMock(username string) *usersRepository {
+	return &usersRepository{
+		UserExistsFunc: func(_ context.Context, u string) (bool, error) {
+			return u == username, nil
+		},
+	}
+}
+
+func testCertPool(certs ...*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool
+}
+
+func testCACertificate(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "jackal test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	b, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(b)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func testClientCertificate(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, cn string, xmppAddrs []string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if len(xmppAddrs) > 0 {
+		var names []asn1.RawValue
+		for _, addr := range xmppAddrs {
+			val, err := asn1.MarshalWithParams(addr, "utf8")
+			require.NoError(t, err)
+
+			on, err := asn1.MarshalWithParams(otherName{
+				TypeID: oidXMPPAddr,
+				Value:  asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: val},
+			}, "tag:0")
+			require.NoError(t, err)
+
+			names = append(names, asn1.RawValue{FullBytes: on})
+		}
+		san, err := asn1.Marshal(names)
+		require.NoError(t, err)
+
+		tmpl.ExtraExtensions = []pkix.Extension{{Id: oidSubjectAltName, Value: san}}
+	}
+	b, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(b)
+	require.NoError(t, err)
+	return cert
+}
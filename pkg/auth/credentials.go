@@ -0,0 +1,64 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+
+	"github.com/ortuman/jackal/pkg/auth/pepper"
+	usermodel "github.com/ortuman/jackal/pkg/model/user"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/sha3"
+)
+
+const scramIterationCount = 15_000
+
+// NewScramCredentials derives a new set of salted SCRAM credentials out of a plain text password.
+func NewScramCredentials(password string, peppers *pepper.Keys) (*usermodel.Scram, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(nil)
+	buf.Write(salt)
+	buf.WriteString(peppers.GetActiveKey())
+	pepperedSalt := buf.Bytes()
+
+	// generate password hashes
+	hSHA1 := hashPassword([]byte(password), pepperedSalt, scramIterationCount, sha1.Size, sha1.New)
+	hSHA256 := hashPassword([]byte(password), pepperedSalt, scramIterationCount, sha256.Size, sha256.New)
+	hSHA512 := hashPassword([]byte(password), pepperedSalt, scramIterationCount, sha512.Size, sha512.New)
+	hSHA3512 := hashPassword([]byte(password), pepperedSalt, scramIterationCount, sha512.Size, sha3.New512)
+
+	return &usermodel.Scram{
+		Sha1:           base64.RawURLEncoding.EncodeToString(hSHA1),
+		Sha256:         base64.RawURLEncoding.EncodeToString(hSHA256),
+		Sha512:         base64.RawURLEncoding.EncodeToString(hSHA512),
+		Sha3512:        base64.RawURLEncoding.EncodeToString(hSHA3512),
+		Salt:           base64.RawURLEncoding.EncodeToString(salt),
+		IterationCount: scramIterationCount,
+		PepperId:       peppers.GetActiveID(),
+	}, nil
+}
+
+func hashPassword(password, salt []byte, iterations int, hKeyLen int, h func() hash.Hash) []byte {
+	return pbkdf2.Key(password, salt, iterations, hKeyLen, h)
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewScramCredentials(t *testing.T) {
+	// given
+	peppers := testPeppers()
+
+	// when
+	scram, err := NewScramCredentials("1234", peppers)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, int64(scramIterationCount), scram.IterationCount)
+	require.Equal(t, "v1", scram.PepperId)
+
+	salt, err := base64.RawURLEncoding.DecodeString(scram.Salt)
+	require.NoError(t, err)
+
+	pepperedSalt := append(salt, []byte(pepperKey)...)
+
+	var tcs = map[ScramType]string{
+		ScramSHA1:    scram.Sha1,
+		ScramSHA256:  scram.Sha256,
+		ScramSHA512:  scram.Sha512,
+		ScramSHA3512: scram.Sha3512,
+	}
+	for tp, expected := range tcs {
+		h := testScramAuthPbkdf2([]byte("1234"), pepperedSalt, tp, scramIterationCount)
+		require.Equal(t, expected, base64.RawURLEncoding.EncodeToString(h))
+	}
+}
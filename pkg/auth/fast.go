@@ -0,0 +1,279 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/transport"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const fastTokenSize = 32
+
+// FastType represents a FAST token authenticator class.
+type FastType int
+
+const (
+	// FastNone represents HT-SHA-256-NONE authentication method.
+	FastNone FastType = iota
+
+	// FastUniq represents HT-SHA-256-UNIQ authentication method.
+	FastUniq
+
+	// FastExpr represents HT-SHA-256-EXPR authentication method.
+	FastExpr
+)
+
+// Fast represents a FAST token authenticator (XEP-0484).
+type Fast struct {
+	tr            transport.Transport
+	tp            FastType
+	rep           repository.FastToken
+	token         *fastmodel.Token
+	usedPrevious  bool
+	authenticated bool
+}
+
+// NewFast returns a new FAST token authenticator instance.
+func NewFast(tr transport.Transport, fastType FastType, rep repository.FastToken) *Fast {
+	return &Fast{
+		tr:  tr,
+		tp:  fastType,
+		rep: rep,
+	}
+}
+
+// Mechanism returns authenticator mechanism name.
+func (f *Fast) Mechanism() string {
+	switch f.tp {
+	case FastNone:
+		return "HT-SHA-256-NONE"
+	case FastUniq:
+		return "HT-SHA-256-UNIQ"
+	case FastExpr:
+		return "HT-SHA-256-EXPR"
+	}
+	return ""
+}
+
+// Username returns authenticated username in case authentication process has been completed.
+func (f *Fast) Username() string {
+	if f.authenticated {
+		return f.token.Username
+	}
+	return ""
+}
+
+// Authenticated returns whether or not user has been authenticated.
+func (f *Fast) Authenticated() bool {
+	return f.authenticated
+}
+
+// UsesChannelBinding returns whether or not FAST authenticator requires channel binding bytes.
+func (f *Fast) UsesChannelBinding() bool {
+	return f.tp != FastNone
+}
+
+// ProcessElement process an incoming authenticator element.
+func (f *Fast) ProcessElement(ctx context.Context, elem stravaganza.Element) (stravaganza.Element, *SASLError) {
+	if elem.Name() != "auth" || f.authenticated {
+		return nil, newSASLError(NotAuthorized, nil)
+	}
+	if len(elem.Text()) == 0 {
+		return nil, newSASLError(IncorrectEncoding, nil)
+	}
+	b, err := base64.StdEncoding.DecodeString(elem.Text())
+	if err != nil {
+		return nil, newSASLError(IncorrectEncoding, err)
+	}
+	// initial response: username NUL HMAC(token, "Initiator" || cb-data)
+	sp := bytes.SplitN(b, []byte{0}, 2)
+	if len(sp) != 2 || len(sp[0]) == 0 || len(sp[1]) == 0 {
+		return nil, newSASLError(MalformedRequest, nil)
+	}
+	username, proof := string(sp[0]), sp[1]
+
+	tokens, err := f.rep.FetchFastTokens(ctx, username)
+	if err != nil {
+		return nil, newSASLError(TemporaryAuthFailure, err)
+	}
+	cb := f.channelBindingBytes()
+	if f.UsesChannelBinding() && len(cb) == 0 {
+		return nil, newSASLError(NotAuthorized, nil) // channel binding data not available
+	}
+	now := time.Now()
+
+	for _, tk := range tokens {
+		if tk.Mechanism != f.Mechanism() {
+			continue
+		}
+		switch {
+		case isValidFastToken(tk.Token, tk.ExpiresAt, now) && hmac.Equal(proof, fastHMAC(tk.Token, "Initiator", cb)):
+			if len(tk.PreviousToken) > 0 {
+				// rotated token has been used, so the previous one is no longer needed
+				tk.PreviousToken = ""
+				tk.PreviousExpiresAt = nil
+				if err := f.rep.UpsertFastToken(ctx, tk); err != nil {
+					return nil, newSASLError(TemporaryAuthFailure, err)
+				}
+			}
+			return f.succeed(tk, tk.Token, false, cb), nil
+
+		case isValidFastToken(tk.PreviousToken, tk.PreviousExpiresAt, now) && hmac.Equal(proof, fastHMAC(tk.PreviousToken, "Initiator", cb)):
+			return f.succeed(tk, tk.PreviousToken, true, cb), nil
+		}
+	}
+	return nil, newSASLError(NotAuthorized, nil)
+}
+
+// Reset resets FAST authenticator internal state.
+func (f *Fast) Reset() {
+	f.authenticated = false
+
+	f.token = nil
+	f.usedPrevious = false
+}
+
+func (f *Fast) succeed(tk *fastmodel.Token, secret string, usedPrevious bool, cb []byte) stravaganza.Element {
+	f.token = tk
+	f.usedPrevious = usedPrevious
+	f.authenticated = true
+
+	return stravaganza.NewBuilder("success").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		WithText(base64.StdEncoding.EncodeToString(fastHMAC(secret, "Responder", cb))).
+		Build()
+}
+
+func (f *Fast) channelBindingBytes() []byte {
+	switch f.tp {
+	case FastUniq:
+		return f.tr.ChannelBindingBytes(transport.TLSUnique)
+	case FastExpr:
+		return f.tr.ChannelBindingBytes(transport.TLSExporter)
+	}
+	return nil
+}
+
+// FastTokens handles FAST token issuance, rotation and invalidation.
+type FastTokens struct {
+	rep    repository.FastToken
+	expiry time.Duration
+}
+
+// NewFastTokens returns a new FastTokens instance.
+func NewFastTokens(rep repository.FastToken, expiry time.Duration) *FastTokens {
+	return &FastTokens{
+		rep:    rep,
+		expiry: expiry,
+	}
+}
+
+// Authenticators returns the set of FAST authenticators associated to tr transport.
+func (t *FastTokens) Authenticators(tr transport.Transport) []Authenticator {
+	return []Authenticator{
+		NewFast(tr, FastNone, t.rep),
+		NewFast(tr, FastUniq, t.rep),
+		NewFast(tr, FastExpr, t.rep),
+	}
+}
+
+// Issue generates and stores a new FAST token for the user-agent identified by clientID.
+// In case a token was already issued to the same user-agent it will remain valid until the new one is used.
+func (t *FastTokens) Issue(ctx context.Context, username, clientID, mechanism string) (*fastmodel.Token, error) {
+	secret, err := generateFastToken()
+	if err != nil {
+		return nil, err
+	}
+	tk := &fastmodel.Token{
+		Username:  username,
+		ClientId:  clientID,
+		Mechanism: mechanism,
+		Token:     secret,
+		ExpiresAt: timestamppb.New(time.Now().Add(t.expiry)),
+	}
+	tokens, err := t.rep.FetchFastTokens(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	for _, prevTk := range tokens {
+		if prevTk.ClientId != clientID || prevTk.Mechanism != mechanism {
+			continue
+		}
+		tk.PreviousToken = prevTk.Token
+		tk.PreviousExpiresAt = prevTk.ExpiresAt
+		break
+	}
+	if err := t.rep.UpsertFastToken(ctx, tk); err != nil {
+		return nil, err
+	}
+	return tk, nil
+}
+
+// Rotate returns the FAST token that should be handed over to a client authenticated by means of a.
+// A nil token is returned in case a is not a FAST authenticator or its token doesn't need to be replaced yet.
+func (t *FastTokens) Rotate(ctx context.Context, a Authenticator) (*fastmodel.Token, error) {
+	f, ok := a.(*Fast)
+	if !ok || !f.authenticated {
+		return nil, nil
+	}
+	tk := f.token
+	switch {
+	case f.usedPrevious:
+		// client has not received its current token yet
+		return tk, nil
+	case time.Until(tk.ExpiresAt.AsTime()) < t.expiry/2:
+		return t.Issue(ctx, tk.Username, tk.ClientId, tk.Mechanism)
+	}
+	return nil, nil
+}
+
+// Invalidate removes the FAST token used to authenticate by means of a.
+func (t *FastTokens) Invalidate(ctx context.Context, a Authenticator) error {
+	f, ok := a.(*Fast)
+	if !ok || !f.authenticated {
+		return nil
+	}
+	return t.rep.DeleteFastToken(ctx, f.token.Username, f.token.ClientId)
+}
+
+func generateFastToken() (string, error) {
+	b := make([]byte, fastTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func isValidFastToken(secret string, expiresAt *timestamppb.Timestamp, now time.Time) bool {
+	return len(secret) > 0 && expiresAt != nil && now.Before(expiresAt.AsTime())
+}
+
+func fastHMAC(secret, label string, cb []byte) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(label))
+	m.Write(cb)
+	return m.Sum(nil)
+}
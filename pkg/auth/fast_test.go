@@ -0,0 +1,265 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	"github.com/ortuman/jackal/pkg/transport"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestFast_Mechanisms(t *testing.T) {
+	// given
+	auth0 := &Fast{tp: FastNone}
+	auth1 := &Fast{tp: FastUniq}
+	auth2 := &Fast{tp: FastExpr}
+
+	// then
+	require.Equal(t, "HT-SHA-256-NONE", auth0.Mechanism())
+	require.False(t, auth0.UsesChannelBinding())
+
+	require.Equal(t, "HT-SHA-256-UNIQ", auth1.Mechanism())
+	require.True(t, auth1.UsesChannelBinding())
+
+	require.Equal(t, "HT-SHA-256-EXPR", auth2.Mechanism())
+	require.True(t, auth2.UsesChannelBinding())
+}
+
+func TestFast_Authenticate(t *testing.T) {
+	// given
+	cbBytes := []byte("exporter-bytes")
+
+	trMock := &transportMock{}
+	trMock.ChannelBindingBytesFunc = func(mechanism transport.ChannelBindingMechanism) []byte {
+		require.Equal(t, transport.TLSExporter, mechanism)
+		return cbBytes
+	}
+	tk := &fastmodel.Token{
+		Username:          "ortuman",
+		ClientId:          "client-1",
+		Mechanism:         "HT-SHA-256-EXPR",
+		Token:             "t0k3n",
+		ExpiresAt:         timestamppb.New(time.Now().Add(time.Hour)),
+		PreviousToken:     "0ld-t0k3n",
+		PreviousExpiresAt: timestamppb.New(time.Now().Add(time.Minute)),
+	}
+	repMock := &fastTokenRepositoryMock{}
+	repMock.FetchFastTokensFunc = func(_ context.Context, _ string) ([]*fastmodel.Token, error) {
+		return []*fastmodel.Token{tk}, nil
+	}
+	repMock.UpsertFastTokenFunc = func(_ context.Context, _ *fastmodel.Token) error { return nil }
+
+	authr := NewFast(trMock, FastExpr, repMock)
+
+	// when
+	resp, saslErr := authr.ProcessElement(context.Background(), fastAuthElement("ortuman", "t0k3n", cbBytes))
+
+	// then
+	require.Nil(t, saslErr)
+	require.True(t, authr.Authenticated())
+	require.Equal(t, "ortuman", authr.Username())
+
+	require.Equal(t, "success", resp.Name())
+	require.Equal(t, base64.StdEncoding.EncodeToString(fastHMAC("t0k3n", "Responder", cbBytes)), resp.Text())
+
+	// previous token has been discarded
+	require.Len(t, repMock.UpsertFastTokenCalls(), 1)
+	require.Empty(t, repMock.UpsertFastTokenCalls()[0].Token.PreviousToken)
+}
+
+func TestFast_AuthenticateWithPreviousToken(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+
+	tk := &fastmodel.Token{
+		Username:          "ortuman",
+		ClientId:          "client-1",
+		Mechanism:         "HT-SHA-256-NONE",
+		Token:             "t0k3n",
+		ExpiresAt:         timestamppb.New(time.Now().Add(time.Hour)),
+		PreviousToken:     "0ld-t0k3n",
+		PreviousExpiresAt: timestamppb.New(time.Now().Add(time.Minute)),
+	}
+	repMock := &fastTokenRepositoryMock{}
+	repMock.FetchFastTokensFunc = func(_ context.Context, _ string) ([]*fastmodel.Token, error) {
+		return []*fastmodel.Token{tk}, nil
+	}
+	authr := NewFast(trMock, FastNone, repMock)
+	tokens := NewFastTokens(repMock, time.Hour*24)
+
+	// when
+	_, saslErr := authr.ProcessElement(context.Background(), fastAuthElement("ortuman", "0ld-t0k3n", nil))
+	rotatedTk, err := tokens.Rotate(context.Background(), authr)
+
+	// then
+	require.Nil(t, saslErr)
+	require.Nil(t, err)
+	require.True(t, authr.Authenticated())
+
+	require.Equal(t, tk, rotatedTk) // current token is handed over again
+}
+
+func TestFast_AuthenticateFailed(t *testing.T) {
+	var tcs = map[string]struct {
+		token             *fastmodel.Token
+		secret            string
+		expectedErrReason SASLErrorReason
+	}{
+		"InvalidToken": {
+			token: &fastmodel.Token{
+				Username:  "ortuman",
+				Mechanism: "HT-SHA-256-NONE",
+				Token:     "t0k3n",
+				ExpiresAt: timestamppb.New(time.Now().Add(time.Hour)),
+			},
+			secret:            "f00",
+			expectedErrReason: NotAuthorized,
+		},
+		"ExpiredToken": {
+			token: &fastmodel.Token{
+				Username:  "ortuman",
+				Mechanism: "HT-SHA-256-NONE",
+				Token:     "t0k3n",
+				ExpiresAt: timestamppb.New(time.Now().Add(-time.Hour)),
+			},
+			secret:            "t0k3n",
+			expectedErrReason: NotAuthorized,
+		},
+		"MechanismMismatch": {
+			token: &fastmodel.Token{
+				Username:  "ortuman",
+				Mechanism: "HT-SHA-256-UNIQ",
+				Token:     "t0k3n",
+				ExpiresAt: timestamppb.New(time.Now().Add(time.Hour)),
+			},
+			secret:            "t0k3n",
+			expectedErrReason: NotAuthorized,
+		},
+	}
+	for tName, tCase := range tcs {
+		t.Run(tName, func(t *testing.T) {
+			// given
+			repMock := &fastTokenRepositoryMock{}
+			repMock.FetchFastTokensFunc = func(_ context.Context, _ string) ([]*fastmodel.Token, error) {
+				return []*fastmodel.Token{tCase.token}, nil
+			}
+			authr := NewFast(&transportMock{}, FastNone, repMock)
+
+			// when
+			_, saslErr := authr.ProcessElement(context.Background(), fastAuthElement("ortuman", tCase.secret, nil))
+
+			// then
+			require.NotNil(t, saslErr)
+			require.Equal(t, tCase.expectedErrReason, saslErr.Reason)
+			require.False(t, authr.Authenticated())
+		})
+	}
+}
+
+func TestFast_AuthenticateMissingChannelBinding(t *testing.T) {
+	// given
+	repMock := &fastTokenRepositoryMock{}
+	repMock.FetchFastTokensFunc = func(_ context.Context, _ string) ([]*fastmodel.Token, error) {
+		return []*fastmodel.Token{{
+			Username:  "ortuman",
+			Mechanism: "HT-SHA-256-UNIQ",
+			Token:     "t0k3n",
+			ExpiresAt: timestamppb.New(time.Now().Add(time.Hour)),
+		}}, nil
+	}
+	trMock := &transportMock{}
+	trMock.ChannelBindingBytesFunc = func(_ transport.ChannelBindingMechanism) []byte {
+		return nil // i.e. TLS 1.3 tls-unique
+	}
+	authr := NewFast(trMock, FastUniq, repMock)
+
+	// when
+	_, saslErr := authr.ProcessElement(context.Background(), fastAuthElement("ortuman", "t0k3n", nil))
+
+	// then
+	require.NotNil(t, saslErr)
+	require.Equal(t, NotAuthorized, saslErr.Reason)
+	require.False(t, authr.Authenticated())
+}
+
+func TestFastTokens_Issue(t *testing.T) {
+	// given
+	prevExpiresAt := timestamppb.New(time.Now().Add(time.Hour))
+
+	repMock := &fastTokenRepositoryMock{}
+	repMock.FetchFastTokensFunc = func(_ context.Context, _ string) ([]*fastmodel.Token, error) {
+		return []*fastmodel.Token{{
+			Username:  "ortuman",
+			ClientId:  "client-1",
+			Mechanism: "HT-SHA-256-NONE",
+			Token:     "0ld-t0k3n",
+			ExpiresAt: prevExpiresAt,
+		}}, nil
+	}
+	repMock.UpsertFastTokenFunc = func(_ context.Context, _ *fastmodel.Token) error { return nil }
+
+	tokens := NewFastTokens(repMock, time.Hour*24)
+
+	// when
+	tk, err := tokens.Issue(context.Background(), "ortuman", "client-1", "HT-SHA-256-NONE")
+
+	// then
+	require.Nil(t, err)
+	require.NotEmpty(t, tk.Token)
+	require.NotEqual(t, "0ld-t0k3n", tk.Token)
+	require.True(t, tk.ExpiresAt.AsTime().After(time.Now().Add(time.Hour*23)))
+
+	require.Equal(t, "0ld-t0k3n", tk.PreviousToken)
+	require.Equal(t, prevExpiresAt, tk.PreviousExpiresAt)
+
+	require.Len(t, repMock.UpsertFastTokenCalls(), 1)
+}
+
+func TestFastTokens_Invalidate(t *testing.T) {
+	// given
+	repMock := &fastTokenRepositoryMock{}
+	repMock.DeleteFastTokenFunc = func(_ context.Context, _, _ string) error { return nil }
+
+	tokens := NewFastTokens(repMock, time.Hour*24)
+
+	authr := &Fast{
+		tp:            FastNone,
+		token:         &fastmodel.Token{Username: "ortuman", ClientId: "client-1"},
+		authenticated: true,
+	}
+
+	// when
+	err := tokens.Invalidate(context.Background(), authr)
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, repMock.DeleteFastTokenCalls(), 1)
+	require.Equal(t, "client-1", repMock.DeleteFastTokenCalls()[0].ClientID)
+}
+
+func fastAuthElement(username, secret string, cb []byte) stravaganza.Element {
+	payload := append([]byte(username+"\x00"), fastHMAC(secret, "Initiator", cb)...)
+	return stravaganza.NewBuilder("auth").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		WithText(base64.StdEncoding.EncodeToString(payload)).
+		Build()
+}
@@ -34,3 +34,13 @@ type authUserRepository interface {
 type extGrpcClient interface {
 	authpb.AuthenticatorClient
 }
+
+//go:generate moq -out fast_repository.mock_test.go . authFastTokenRepository:fastTokenRepositoryMock
+type authFastTokenRepository interface {
+	repository.FastToken
+}
+
+//go:generate moq -out hosts.mock_test.go . authHosts:hostsMock
+type authHosts interface {
+	localHosts
+}
@@ -0,0 +1,264 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	// register hash functions
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+var (
+	// ErrMalformedToken will be returned by Verify in case token cannot be decoded.
+	ErrMalformedToken = errors.New("jwt: malformed token")
+
+	// ErrInvalidSignature will be returned by Verify in case no configured key validates token signature.
+	ErrInvalidSignature = errors.New("jwt: invalid signature")
+
+	// ErrInvalidClaims will be returned by Verify in case token registered claims are not valid.
+	ErrInvalidClaims = errors.New("jwt: invalid claims")
+)
+
+// Config contains JWT verification configuration.
+type Config struct {
+	// Keys contains the set of static verification keys indexed by key identifier.
+	// A key can be either a PEM encoded public key or an HMAC shared secret.
+	Keys map[string]string `fig:"keys"`
+
+	// JWKSFile is the path to a local JSON Web Key Set file.
+	JWKSFile string `fig:"jwks_file"`
+
+	// Issuer, if set, must match token 'iss' claim.
+	Issuer string `fig:"issuer"`
+
+	// Audience, if set, must be contained in token 'aud' claim.
+	Audience string `fig:"audience"`
+
+	// Leeway defines the clock skew allowed when validating token time claims.
+	Leeway time.Duration `fig:"leeway" default:"30s"`
+
+	// AllowMissingExpiration, if set, accepts tokens with no 'exp' claim, which never expire.
+	AllowMissingExpiration bool `fig:"allow_missing_expiration"`
+}
+
+// Claims represents a set of decoded JWT claims.
+type Claims map[string]interface{}
+
+// String returns the string value associated to a claim.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verifier validates JWT signatures and registered claims.
+type Verifier struct {
+	keys       []verificationKey
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	requireExp bool
+	nowFn      func() time.Time
+}
+
+// NewVerifier returns an initialized Verifier instance.
+func NewVerifier(cfg Config) (*Verifier, error) {
+	var keys []verificationKey
+	for kid, s := range cfg.Keys {
+		key, err := parseStaticKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid key %s: %v", kid, err)
+		}
+		keys = append(keys, verificationKey{kid: kid, key: key})
+	}
+	if len(cfg.JWKSFile) > 0 {
+		jwks, err := loadJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwks...)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwt: no verification keys configured")
+	}
+	return &Verifier{
+		keys:       keys,
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		leeway:     cfg.Leeway,
+		requireExp: !cfg.AllowMissingExpiration,
+		nowFn:      time.Now,
+	}, nil
+}
+
+// Verify validates token signature and registered claims, returning its decoded claim set.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	var hdr header
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return nil, ErrMalformedToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+
+	var verified bool
+	for _, k := range v.keys {
+		if len(hdr.Kid) > 0 && len(k.kid) > 0 && hdr.Kid != k.kid {
+			continue
+		}
+		if verifySignature(hdr.Alg, k.key, signingInput, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidSignature
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) validateClaims(claims Claims) error {
+	now := v.nowFn()
+	exp, ok := claims["exp"].(float64)
+	switch {
+	case ok:
+		if now.After(time.Unix(int64(exp), 0).Add(v.leeway)) {
+			return fmt.Errorf("%w: token expired", ErrInvalidClaims)
+		}
+	case v.requireExp:
+		return fmt.Errorf("%w: missing expiration", ErrInvalidClaims)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if now.Add(v.leeway).Before(time.Unix(int64(nbf), 0)) {
+			return fmt.Errorf("%w: token not valid yet", ErrInvalidClaims)
+		}
+	}
+	if len(v.issuer) > 0 && claims.String("iss") != v.issuer {
+		return fmt.Errorf("%w: issuer mismatch", ErrInvalidClaims)
+	}
+	if len(v.audience) > 0 && !containsAudience(claims["aud"], v.audience) {
+		return fmt.Errorf("%w: audience mismatch", ErrInvalidClaims)
+	}
+	return nil
+}
+
+func verifySignature(alg string, key interface{}, signingInput, sig []byte) bool {
+	var h crypto.Hash
+	switch alg {
+	case "HS256", "RS256", "PS256", "ES256":
+		h = crypto.SHA256
+	case "HS384", "RS384", "PS384", "ES384":
+		h = crypto.SHA384
+	case "HS512", "RS512", "PS512", "ES512":
+		h = crypto.SHA512
+	case "EdDSA":
+		pk, ok := key.(ed25519.PublicKey)
+		return ok && ed25519.Verify(pk, signingInput, sig)
+	default:
+		return false // 'none' and unknown algorithms are never accepted
+	}
+	hw := h.New()
+	hw.Write(signingInput)
+	digest := hw.Sum(nil)
+
+	switch alg[0] {
+	case 'H':
+		secret, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		m := hmac.New(h.New, secret)
+		m.Write(signingInput)
+		return hmac.Equal(sig, m.Sum(nil))
+
+	case 'R':
+		pk, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pk, h, digest, sig) == nil
+
+	case 'P':
+		pk, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPSS(pk, h, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+
+	case 'E':
+		pk, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return false
+		}
+		bitSize := pk.Curve.Params().BitSize
+		if (alg == "ES256" && bitSize != 256) || (alg == "ES384" && bitSize != 384) || (alg == "ES512" && bitSize != 521) {
+			return false
+		}
+		keySize := (bitSize + 7) / 8
+		if len(sig) != 2*keySize {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:keySize])
+		s := new(big.Int).SetBytes(sig[keySize:])
+		return ecdsa.Verify(pk, digest, r, s)
+	}
+	return false
+}
+
+func containsAudience(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_StaticKeys(t *testing.T) {
+	// given
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	v, err := NewVerifier(Config{
+		Keys: map[string]string{
+			"hs":  "s3cr3t",
+			"rsa": encodePublicKey(t, &rsaKey.PublicKey),
+			"ec":  encodePublicKey(t, &ecKey.PublicKey),
+		},
+	})
+	require.Nil(t, err)
+
+	claims := map[string]interface{}{"sub": "ortuman", "exp": time.Now().Add(time.Hour).Unix()}
+
+	// then
+	for _, tk := range []string{
+		signToken(t, "HS256", "hs", []byte("s3cr3t"), claims),
+		signToken(t, "RS256", "rsa", rsaKey, claims),
+		signToken(t, "ES256", "ec", ecKey, claims),
+		signToken(t, "RS256", "", rsaKey, claims),
+	} {
+		c, err := v.Verify(tk)
+		require.Nil(t, err)
+		require.Equal(t, "ortuman", c.String("sub"))
+	}
+}
+
+func TestVerifier_JWKSFile(t *testing.T) {
+	// given
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"OKP","kid":"ed","crv":"Ed25519","x":"%s"},{"kty":"RSA","kid":"rsa","n":"%s","e":"AQAB"}]}`,
+		base64.RawURLEncoding.EncodeToString(pub),
+		base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+	)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	require.Nil(t, os.WriteFile(jwksFile, []byte(jwks), 0600))
+
+	v, err := NewVerifier(Config{JWKSFile: jwksFile, AllowMissingExpiration: true})
+	require.Nil(t, err)
+
+	claims := map[string]interface{}{"preferred_username": "noelia"}
+
+	// then
+	c, err := v.Verify(signToken(t, "EdDSA", "ed", priv, claims))
+	require.Nil(t, err)
+	require.Equal(t, "noelia", c.String("preferred_username"))
+
+	c, err = v.Verify(signToken(t, "PS256", "rsa", rsaKey, claims))
+	require.Nil(t, err)
+	require.Equal(t, "noelia", c.String("preferred_username"))
+}
+
+func TestVerifier_Failures(t *testing.T) {
+	// given
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	v, err := NewVerifier(Config{
+		Keys: map[string]string{
+			"hs":  "s3cr3t",
+			"rsa": encodePublicKey(t, &rsaKey.PublicKey),
+		},
+		Issuer:   "https://sso.jackal.im",
+		Audience: "jackal",
+		Leeway:   time.Second,
+	})
+	require.Nil(t, err)
+
+	validClaims := map[string]interface{}{
+		"iss": "https://sso.jackal.im", "aud": []string{"jackal", "other"}, "exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	var tcs = map[string]struct {
+		token       string
+		expectedErr error
+	}{
+		"Valid":         {signToken(t, "HS256", "hs", []byte("s3cr3t"), validClaims), nil},
+		"Malformed":     {"foo.bar", ErrMalformedToken},
+		"NoneAlgorithm": {signToken(t, "none", "", nil, validClaims), ErrInvalidSignature},
+		"UnknownSigner": {signToken(t, "RS256", "rsa", otherKey, validClaims), ErrInvalidSignature},
+		"KeyIDMismatch": {signToken(t, "HS256", "rsa", []byte("s3cr3t"), validClaims), ErrInvalidSignature},
+		"MissingExpiration": {signToken(t, "HS256", "hs", []byte("s3cr3t"), map[string]interface{}{
+			"iss": "https://sso.jackal.im", "aud": "jackal",
+		}), ErrInvalidClaims},
+		"IssuerMismatch":   {signToken(t, "HS256", "hs", []byte("s3cr3t"), map[string]interface{}{"iss": "foo", "aud": "jackal"}), ErrInvalidClaims},
+		"AudienceMismatch": {signToken(t, "HS256", "hs", []byte("s3cr3t"), map[string]interface{}{"iss": "https://sso.jackal.im", "aud": "foo"}), ErrInvalidClaims},
+		"Expired": {signToken(t, "HS256", "hs", []byte("s3cr3t"), map[string]interface{}{
+			"iss": "https://sso.jackal.im", "aud": "jackal", "exp": time.Now().Add(-time.Minute).Unix(),
+		}), ErrInvalidClaims},
+		"NotValidYet": {signToken(t, "HS256", "hs", []byte("s3cr3t"), map[string]interface{}{
+			"iss": "https://sso.jackal.im", "aud": "jackal", "nbf": time.Now().Add(time.Minute).Unix(),
+		}), ErrInvalidClaims},
+	}
+	for tName, tCase := range tcs {
+		t.Run(tName, func(t *testing.T) {
+			_, err := v.Verify(tCase.token)
+			require.ErrorIs(t, err, tCase.expectedErr)
+		})
+	}
+}
+
+func TestVerifier_NoKeys(t *testing.T) {
+	_, err := NewVerifier(Config{})
+	require.NotNil(t, err)
+}
+
+func encodePublicKey(t *testing.T, pub interface{}) string {
+	t.Helper()
+	b, err := x509.MarshalPKIXPublicKey(pub)
+	require.Nil(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}))
+}
+
+func signToken(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+
+	hdr := map[string]string{"alg": alg, "typ": "JWT"}
+	if len(kid) > 0 {
+		hdr["kid"] = kid
+	}
+	hb, _ := json.Marshal(hdr)
+	cb, _ := json.Marshal(claims)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		m := hmac.New(sha256.New, k)
+		m.Write([]byte(signingInput))
+		sig = m.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if alg == "PS256" {
+			sig, err = rsa.SignPSS(rand.Reader, k, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		}
+		require.Nil(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.Nil(t, err)
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signingInput))
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
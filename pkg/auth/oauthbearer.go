@@ -0,0 +1,227 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/auth/jwt"
+	"github.com/ortuman/jackal/pkg/hook"
+	usermodel "github.com/ortuman/jackal/pkg/model/user"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+const (
+	oauthBearerKVSeparator = "\x01"
+
+	oauthBearerInvalidTokenStatus = `{"status":"invalid_token"}`
+)
+
+var errOAuthBearerInvalidUsername = errors.New("oauthbearer: invalid username claim")
+
+type oauthBearerState int
+
+const (
+	startOAuthBearerState oauthBearerState = iota
+	failedOAuthBearerState
+)
+
+// OAuthBearer represents an OAUTHBEARER authenticator (RFC 7628) that validates JWT bearer tokens.
+type OAuthBearer struct {
+	verifier      *jwt.Verifier
+	usernameClaim string
+	autoProvision bool
+	rep           repository.User
+	hk            *hook.Hooks
+	state         oauthBearerState
+	username      string
+	authenticated bool
+}
+
+// NewOAuthBearer returns a new OAUTHBEARER authenticator instance.
+func NewOAuthBearer(
+	verifier *jwt.Verifier,
+	usernameClaim string,
+	autoProvision bool,
+	rep repository.User,
+	hk *hook.Hooks,
+) *OAuthBearer {
+	return &OAuthBearer{
+		verifier:      verifier,
+		usernameClaim: usernameClaim,
+		autoProvision: autoProvision,
+		rep:           rep,
+		hk:            hk,
+	}
+}
+
+// Mechanism returns authenticator mechanism name.
+func (o *OAuthBearer) Mechanism() string {
+	return "OAUTHBEARER"
+}
+
+// Username returns authenticated username in case authentication process has been completed.
+func (o *OAuthBearer) Username() string {
+	if o.authenticated {
+		return o.username
+	}
+	return ""
+}
+
+// Authenticated returns whether or not user has been authenticated.
+func (o *OAuthBearer) Authenticated() bool {
+	return o.authenticated
+}
+
+// UsesChannelBinding returns whether or not OAUTHBEARER authenticator requires channel binding bytes.
+func (o *OAuthBearer) UsesChannelBinding() bool {
+	return false
+}
+
+// ProcessElement process an incoming authenticator element.
+func (o *OAuthBearer) ProcessElement(ctx context.Context, elem stravaganza.Element) (stravaganza.Element, *SASLError) {
+	if elem.Name() == "auth" && o.state == startOAuthBearerState && !o.authenticated {
+		return o.handleStart(ctx, elem)
+	}
+	// any response following an error status challenge is a client acknowledgment (RFC 7628, section 3.2.3)
+	return nil, newSASLError(NotAuthorized, nil)
+}
+
+// Reset resets OAUTHBEARER authenticator internal state.
+func (o *OAuthBearer) Reset() {
+	o.authenticated = false
+
+	o.state = startOAuthBearerState
+	o.username = ""
+}
+
+func (o *OAuthBearer) handleStart(ctx context.Context, elem stravaganza.Element) (stravaganza.Element, *SASLError) {
+	if len(elem.Text()) == 0 {
+		return nil, newSASLError(IncorrectEncoding, nil)
+	}
+	b, err := base64.StdEncoding.DecodeString(elem.Text())
+	if err != nil {
+		return nil, newSASLError(IncorrectEncoding, err)
+	}
+	authzID, token, saslErr := parseOAuthBearerMessage(string(b))
+	if saslErr != nil {
+		return nil, saslErr
+	}
+	claims, err := o.verifier.Verify(token)
+	if err != nil {
+		return o.fail(), nil
+	}
+	username, err := o.getUsername(claims, authzID)
+	if err != nil {
+		return o.fail(), nil
+	}
+	exists, err := o.rep.UserExists(ctx, username)
+	if err != nil {
+		return nil, newSASLError(TemporaryAuthFailure, err)
+	}
+	if !exists {
+		if !o.autoProvision {
+			return o.fail(), nil
+		}
+		if err := o.provisionUser(ctx, username); err != nil {
+			return nil, newSASLError(TemporaryAuthFailure, err)
+		}
+	}
+	o.username = username
+	o.authenticated = true
+
+	return stravaganza.NewBuilder("success").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		Build(), nil
+}
+
+func (o *OAuthBearer) getUsername(claims jwt.Claims, authzID string) (string, error) {
+	username := claims.String(o.usernameClaim)
+	if len(username) == 0 {
+		return "", errOAuthBearerInvalidUsername
+	}
+	// domain value is irrelevant here, only node part is validated
+	j, err := jid.New(username, "localhost", "", false)
+	if err != nil || j.Node() != username {
+		return "", errOAuthBearerInvalidUsername
+	}
+	if len(authzID) > 0 {
+		az, err := jid.NewWithString(authzID, false)
+		if err != nil || az.Node() != username {
+			return "", errOAuthBearerInvalidUsername
+		}
+	}
+	return username, nil
+}
+
+func (o *OAuthBearer) provisionUser(ctx context.Context, username string) error {
+	if err := o.rep.UpsertUser(ctx, &usermodel.User{Username: username}); err != nil {
+		return err
+	}
+	_, err := o.hk.Run(hook.UserCreated, &hook.ExecutionContext{
+		Info: &hook.UserInfo{
+			Username: username,
+		},
+		Context: ctx,
+	})
+	return err
+}
+
+func (o *OAuthBearer) fail() stravaganza.Element {
+	o.state = failedOAuthBearerState
+
+	return stravaganza.NewBuilder("challenge").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		WithText(base64.StdEncoding.EncodeToString([]byte(oauthBearerInvalidTokenStatus))).
+		Build()
+}
+
+// parseOAuthBearerMessage returns authorization identity and bearer token contained in a client initial response.
+func parseOAuthBearerMessage(msg string) (authzID string, token string, saslErr *SASLError) {
+	// gs2-header kvsep *kvpair kvsep (RFC 7628, section 3.1)
+	sp := strings.SplitN(msg, oauthBearerKVSeparator, 2)
+	if len(sp) != 2 {
+		return "", "", newSASLError(MalformedRequest, nil)
+	}
+	gs2Header := strings.Split(sp[0], ",")
+	if len(gs2Header) != 3 || (gs2Header[0] != "n" && gs2Header[0] != "y") || len(gs2Header[2]) > 0 {
+		return "", "", newSASLError(MalformedRequest, nil)
+	}
+	if len(gs2Header[1]) > 0 {
+		if !strings.HasPrefix(gs2Header[1], "a=") {
+			return "", "", newSASLError(MalformedRequest, nil)
+		}
+		authzID = gs2Header[1][2:]
+	}
+	for _, kv := range strings.Split(sp[1], oauthBearerKVSeparator) {
+		if !strings.HasPrefix(kv, "auth=") {
+			continue
+		}
+		scheme, credentials, ok := strings.Cut(kv[5:], " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return "", "", newSASLError(MalformedRequest, nil)
+		}
+		token = strings.TrimSpace(credentials)
+	}
+	if len(token) == 0 {
+		return "", "", newSASLError(MalformedRequest, nil)
+	}
+	return authzID, token, nil
+}
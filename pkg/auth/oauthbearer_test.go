@@ -0,0 +1,195 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/auth/jwt"
+	"github.com/ortuman/jackal/pkg/hook"
+	usermodel "github.com/ortuman/jackal/pkg/model/user"
+	"github.com/stretchr/testify/require"
+)
+
+const tJWTSecret = "s3cr3t"
+
+func TestOAuthBearer_Authenticate(t *testing.T) {
+	// given
+	repMock := &usersRepository{}
+	repMock.UserExistsFunc = func(_ context.Context, username string) (bool, error) {
+		return username == "ortuman", nil
+	}
+	authr := NewOAuthBearer(testJWTVerifier(t), "preferred_username", false, repMock, hook.NewHooks())
+
+	tk := testJWT(map[string]interface{}{"preferred_username": "ortuman", "exp": time.Now().Add(time.Hour).Unix()})
+
+	// when
+	resp, saslErr := authr.ProcessElement(context.Background(), oauthBearerAuthElement("n,a=ortuman@jackal.im,", tk))
+
+	// then
+	require.Nil(t, saslErr)
+	require.Equal(t, "success", resp.Name())
+	require.True(t, authr.Authenticated())
+	require.Equal(t, "ortuman", authr.Username())
+}
+
+func TestOAuthBearer_AutoProvision(t *testing.T) {
+	// given
+	var provisioned *usermodel.User
+
+	repMock := &usersRepository{}
+	repMock.UserExistsFunc = func(_ context.Context, _ string) (bool, error) {
+		return false, nil
+	}
+	repMock.UpsertUserFunc = func(_ context.Context, user *usermodel.User) error {
+		provisioned = user
+		return nil
+	}
+	var createdUsername string
+
+	hk := hook.NewHooks()
+	hk.AddHook(hook.UserCreated, func(execCtx *hook.ExecutionContext) error {
+		createdUsername = execCtx.Info.(*hook.UserInfo).Username
+		return nil
+	}, hook.DefaultPriority)
+
+	authr := NewOAuthBearer(testJWTVerifier(t), "sub", true, repMock, hk)
+
+	// when
+	_, saslErr := authr.ProcessElement(context.Background(), oauthBearerAuthElement("n,,", testJWT(map[string]interface{}{"sub": "noelia"})))
+
+	// then
+	require.Nil(t, saslErr)
+	require.True(t, authr.Authenticated())
+
+	require.NotNil(t, provisioned)
+	require.Equal(t, "noelia", provisioned.Username)
+	require.Equal(t, "noelia", createdUsername)
+}
+
+func TestOAuthBearer_Failures(t *testing.T) {
+	var tcs = map[string]struct {
+		gs2Header         string
+		token             string
+		expectsChallenge  bool
+		expectedErrReason SASLErrorReason
+	}{
+		"InvalidSignature": {
+			gs2Header:        "n,,",
+			token:            testJWT(map[string]interface{}{"sub": "ortuman"})[:10] + "x.y.z",
+			expectsChallenge: true,
+		},
+		"UnknownUser": {
+			gs2Header:        "n,,",
+			token:            testJWT(map[string]interface{}{"sub": "romeo"}),
+			expectsChallenge: true,
+		},
+		"MissingClaim": {
+			gs2Header:        "n,,",
+			token:            testJWT(map[string]interface{}{"name": "ortuman"}),
+			expectsChallenge: true,
+		},
+		"AuthzIDMismatch": {
+			gs2Header:        "n,a=noelia@jackal.im,",
+			token:            testJWT(map[string]interface{}{"sub": "ortuman"}),
+			expectsChallenge: true,
+		},
+		"MalformedGS2Header": {
+			gs2Header:         "p=tls-unique,,",
+			token:             testJWT(map[string]interface{}{"sub": "ortuman"}),
+			expectedErrReason: MalformedRequest,
+		},
+		"MissingToken": {
+			gs2Header:         "n,,",
+			expectedErrReason: MalformedRequest,
+		},
+	}
+	for tName, tCase := range tcs {
+		t.Run(tName, func(t *testing.T) {
+			// given
+			repMock := &usersRepository{}
+			repMock.UserExistsFunc = func(_ context.Context, username string) (bool, error) {
+				return username == "ortuman", nil
+			}
+			authr := NewOAuthBearer(testJWTVerifier(t), "sub", false, repMock, hook.NewHooks())
+
+			// when
+			resp, saslErr := authr.ProcessElement(context.Background(), oauthBearerAuthElement(tCase.gs2Header, tCase.token))
+
+			// then
+			require.False(t, authr.Authenticated())
+			if !tCase.expectsChallenge {
+				require.NotNil(t, saslErr)
+				require.Equal(t, tCase.expectedErrReason, saslErr.Reason)
+				return
+			}
+			require.Nil(t, saslErr)
+			require.Equal(t, "challenge", resp.Name())
+
+			status, _ := base64.StdEncoding.DecodeString(resp.Text())
+			require.Equal(t, `{"status":"invalid_token"}`, string(status))
+
+			// client acknowledges error
+			_, saslErr = authr.ProcessElement(context.Background(), stravaganza.NewBuilder("response").
+				WithAttribute(stravaganza.Namespace, saslNamespace).
+				WithText(base64.StdEncoding.EncodeToString([]byte("\x01"))).
+				Build(),
+			)
+			require.NotNil(t, saslErr)
+			require.Equal(t, NotAuthorized, saslErr.Reason)
+		})
+	}
+}
+
+func testJWTVerifier(t *testing.T) *jwt.Verifier {
+	t.Helper()
+	v, err := jwt.NewVerifier(jwt.Config{Keys: map[string]string{"k1": tJWTSecret}})
+	require.Nil(t, err)
+	return v
+}
+
+func testJWT(claims map[string]interface{}) string {
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	hb, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	cb, _ := json.Marshal(claims)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+
+	m := hmac.New(sha256.New, []byte(tJWTSecret))
+	m.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+func oauthBearerAuthElement(gs2Header, token string) stravaganza.Element {
+	msg := gs2Header + "\x01host=jackal.im\x01port=5222\x01"
+	if len(token) > 0 {
+		msg += "auth=Bearer " + token + "\x01"
+	}
+	msg += "\x01"
+	return stravaganza.NewBuilder("auth").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		WithAttribute("mechanism", "OAUTHBEARER").
+		WithText(base64.StdEncoding.EncodeToString([]byte(msg))).
+		Build()
+}
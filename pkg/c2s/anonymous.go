@@ -0,0 +1,126 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/host"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+// AnonymousCleaner removes every trace of an ephemeral anonymous user as soon as its C2S stream gets terminated.
+// Hibernated streams (XEP-0198) are left alone until they either expire or get resumed.
+type AnonymousCleaner struct {
+	hosts  hosts
+	rep    repository.Repository
+	hk     *hook.Hooks
+	logger kitlog.Logger
+
+	mu         sync.Mutex
+	hibernated map[string]struct{}
+}
+
+// NewAnonymousCleaner returns a new initialized AnonymousCleaner instance.
+func NewAnonymousCleaner(
+	hosts *host.Hosts,
+	rep repository.Repository,
+	hk *hook.Hooks,
+	logger kitlog.Logger,
+) *AnonymousCleaner {
+	return &AnonymousCleaner{
+		hosts:      hosts,
+		rep:        rep,
+		hk:         hk,
+		logger:     logger,
+		hibernated: make(map[string]struct{}),
+	}
+}
+
+// Start starts anonymous cleaner.
+func (c *AnonymousCleaner) Start(_ context.Context) error {
+	c.hk.AddHook(hook.C2SStreamHibernated, c.onHibernate, hook.DefaultPriority)
+	c.hk.AddHook(hook.C2SStreamTerminated, c.onTerminate, hook.LowPriority)
+	return nil
+}
+
+// Stop stops anonymous cleaner.
+func (c *AnonymousCleaner) Stop(_ context.Context) error {
+	c.hk.RemoveHook(hook.C2SStreamHibernated, c.onHibernate)
+	c.hk.RemoveHook(hook.C2SStreamTerminated, c.onTerminate)
+	return nil
+}
+
+func (c *AnonymousCleaner) onHibernate(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+	if !c.isAnonymous(inf) {
+		return nil
+	}
+	c.mu.Lock()
+	c.hibernated[inf.ID] = struct{}{}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *AnonymousCleaner) onTerminate(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+	if !c.isAnonymous(inf) {
+		return nil
+	}
+	c.mu.Lock()
+	_, wasHibernated := c.hibernated[inf.ID]
+	delete(c.hibernated, inf.ID)
+	c.mu.Unlock()
+
+	if wasHibernated && isResumeConflict(inf.DisconnectError) {
+		return nil // hibernated stream has been resumed by a new one
+	}
+	ctx := execCtx.Context
+	username := inf.JID.Node()
+
+	if err := c.rep.DeleteUser(ctx, username); err != nil {
+		return err
+	}
+	if err := c.rep.DeleteFastTokens(ctx, username); err != nil {
+		return err
+	}
+	// let every module wipe out its own user data (roster, blocklist, last activity, ...)
+	_, err := c.hk.Run(hook.UserDeleted, &hook.ExecutionContext{
+		Info: &hook.UserInfo{
+			Username: username,
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		return err
+	}
+	level.Info(c.logger).Log("msg", "deleted anonymous user", "username", username)
+	return nil
+}
+
+func (c *AnonymousCleaner) isAnonymous(inf *hook.C2SStreamInfo) bool {
+	return inf.JID != nil && len(inf.JID.Node()) > 0 && c.hosts.IsAnonymousHost(inf.JID.Domain())
+}
+
+func isResumeConflict(err error) bool {
+	var sErr *streamerror.Error
+	return errors.As(err, &sErr) && sErr.Reason == streamerror.Conflict
+}
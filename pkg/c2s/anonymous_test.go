@@ -0,0 +1,128 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"context"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymousCleaner_Terminate(t *testing.T) {
+	var tcs = map[string]struct {
+		jid             string
+		hibernated      bool
+		discErr         error
+		expectedDeleted bool
+	}{
+		"AnonymousUser": {
+			jid:             "5c5fa8c6@anon.jackal.im/yard",
+			expectedDeleted: true,
+		},
+		"RegularUser": {
+			jid:             "ortuman@jackal.im/yard",
+			expectedDeleted: false,
+		},
+		"HibernatedStreamExpired": {
+			jid:             "5c5fa8c6@anon.jackal.im/yard",
+			hibernated:      true,
+			expectedDeleted: true,
+		},
+		"HibernatedStreamResumed": {
+			jid:             "5c5fa8c6@anon.jackal.im/yard",
+			hibernated:      true,
+			discErr:         streamerror.E(streamerror.Conflict),
+			expectedDeleted: false,
+		},
+		"ActiveStreamConflict": {
+			jid:             "5c5fa8c6@anon.jackal.im/yard",
+			discErr:         streamerror.E(streamerror.Conflict),
+			expectedDeleted: true,
+		},
+	}
+	for tn, tc := range tcs {
+		t.Run(tn, func(t *testing.T) {
+			// given
+			hMock := &hostsMock{}
+			hMock.IsAnonymousHostFunc = func(host string) bool { return host == "anon.jackal.im" }
+
+			var deletedUsers []string
+			repMock := &repositoryMock{}
+			repMock.DeleteUserFunc = func(_ context.Context, username string) error {
+				deletedUsers = append(deletedUsers, username)
+				return nil
+			}
+			repMock.DeleteFastTokensFunc = func(_ context.Context, _ string) error { return nil }
+
+			var hookedUsername string
+
+			hk := hook.NewHooks()
+			hk.AddHook(hook.UserDeleted, func(execCtx *hook.ExecutionContext) error {
+				hookedUsername = execCtx.Info.(*hook.UserInfo).Username
+				return nil
+			}, hook.DefaultPriority)
+
+			c := &AnonymousCleaner{
+				hosts:      hMock,
+				rep:        repMock,
+				hk:         hk,
+				logger:     kitlog.NewNopLogger(),
+				hibernated: make(map[string]struct{}),
+			}
+			require.NoError(t, c.Start(context.Background()))
+			defer func() { _ = c.Stop(context.Background()) }()
+
+			userJID, _ := jid.NewWithString(tc.jid, true)
+
+			if tc.hibernated {
+				_, err := hk.Run(hook.C2SStreamHibernated, &hook.ExecutionContext{
+					Info: &hook.C2SStreamInfo{
+						ID:  "c2s:1",
+						JID: userJID,
+					},
+					Context: context.Background(),
+				})
+				require.NoError(t, err)
+				require.Len(t, deletedUsers, 0)
+			}
+
+			// when
+			_, err := hk.Run(hook.C2SStreamTerminated, &hook.ExecutionContext{
+				Info: &hook.C2SStreamInfo{
+					ID:              "c2s:1",
+					JID:             userJID,
+					DisconnectError: tc.discErr,
+				},
+				Context: context.Background(),
+			})
+
+			// then
+			require.NoError(t, err)
+			if !tc.expectedDeleted {
+				require.Len(t, deletedUsers, 0)
+				require.Empty(t, hookedUsername)
+				return
+			}
+			require.Equal(t, []string{userJID.Node()}, deletedUsers)
+			require.Len(t, repMock.DeleteFastTokensCalls(), 1)
+			require.Equal(t, userJID.Node(), hookedUsername)
+		})
+	}
+}
@@ -14,7 +14,11 @@
 
 package c2s
 
-import "time"
+import (
+	"time"
+
+	"github.com/ortuman/jackal/pkg/auth/jwt"
+)
 
 // ListenersConfig defines a set of C2S listener configurations.
 type ListenersConfig []ListenerConfig
@@ -28,8 +32,43 @@ type ListenerConfig struct {
 	Port int `fig:"port" default:"5222"`
 
 	// Transport specifies the type of transport used for incoming connections.
+	// Valid values are 'socket', 'websocket' and 'bosh'.
 	Transport string `fig:"transport" default:"socket"`
 
+	// WebSocket contains WebSocket transport related configuration.
+	WebSocket struct {
+		// Path defines the HTTP path at which WebSocket connections are accepted.
+		Path string `fig:"path" default:"/xmpp-websocket"`
+
+		// AllowedOrigins contains the set of origins allowed to open a WebSocket connection.
+		// If empty, only same origin requests will be accepted.
+		AllowedOrigins []string `fig:"allowed_origins"`
+	} `fig:"websocket"`
+
+	// BOSH contains BOSH transport related configuration.
+	BOSH struct {
+		// Path defines the HTTP path at which BOSH requests are accepted.
+		Path string `fig:"path" default:"/http-bind"`
+
+		// MaxWait defines the longest time a BOSH request may be held.
+		MaxWait time.Duration `fig:"max_wait" default:"60s"`
+
+		// MaxHold defines the maximum number of BOSH requests that may be held at the same time.
+		MaxHold int `fig:"max_hold" default:"1"`
+
+		// Inactivity defines the longest time a BOSH session may stay without pending requests.
+		Inactivity time.Duration `fig:"inactivity" default:"60s"`
+
+		// Polling defines the shortest allowed interval between polling requests.
+		Polling time.Duration `fig:"polling" default:"2s"`
+
+		// MaxSessions defines the maximum number of simultaneously active BOSH sessions.
+		MaxSessions int `fig:"max_sessions" default:"10000"`
+
+		// AllowedOrigins contains the set of origins allowed to perform cross-origin BOSH requests.
+		AllowedOrigins []string `fig:"allowed_origins"`
+	} `fig:"bosh"`
+
 	// DirectTLS, if true, tls.Listen will be used as network listener.
 	DirectTLS bool `fig:"direct_tls"`
 
@@ -38,6 +77,39 @@ type ListenerConfig struct {
 		// Mechanisms contains enabled SASL mechanisms.
 		Mechanisms []string `fig:"mechanisms" default:"[scram_sha_1, scram_sha_256, scram_sha_512, scram_sha3_512]"`
 
+		// SASL2, if true, Extensible SASL Profile (XEP-0388) along with Bind 2 (XEP-0386)
+		// will be offered to connecting clients.
+		SASL2 bool `fig:"sasl2"`
+
+		// FAST contains FAST token authentication (XEP-0484) configuration.
+		// Tokens are only issued over SASL2 authenticated streams.
+		FAST struct {
+			// Enabled, if true, FAST tokens will be issued on client request.
+			Enabled bool `fig:"enabled"`
+
+			// TokenExpiry defines the amount of time an issued token remains valid.
+			// Tokens used within the second half of its lifetime will be rotated.
+			TokenExpiry time.Duration `fig:"token_expiry" default:"336h"`
+		} `fig:"fast"`
+
+		// OAuthBearer contains OAUTHBEARER mechanism (RFC 7628) configuration.
+		OAuthBearer struct {
+			// JWT contains bearer token verification configuration.
+			JWT jwt.Config `fig:"jwt"`
+
+			// UsernameClaim is the token claim mapped to the authenticated username.
+			UsernameClaim string `fig:"username_claim" default:"sub"`
+
+			// AutoProvision, if true, users will be created on first successful login.
+			AutoProvision bool `fig:"auto_provision"`
+		} `fig:"oauthbearer"`
+
+		// ClientCertificates contains EXTERNAL mechanism (XEP-0178) configuration.
+		ClientCertificates struct {
+			// CAFile is the path to the PEM encoded CA bundle used to verify client certificates.
+			CAFile string `fig:"ca_file"`
+		} `fig:"client_certificates"`
+
 		// External contains external authenticator configuration.
 		External struct {
 			Address  string `fig:"address"`
@@ -45,6 +117,20 @@ type ListenerConfig struct {
 		} `fig:"external"`
 	} `fig:"sasl"`
 
+	// AllowRegistration, if true, in-band registration (XEP-0077) will be offered to not yet
+	// authenticated streams. Requires register module to be enabled.
+	AllowRegistration bool `fig:"allow_registration"`
+
+	// CSI contains Client State Indication (XEP-0352) configuration.
+	CSI struct {
+		// Enabled, if true, clients will be able to indicate their active/inactive state.
+		// Non-urgent stanzas will be held back while a client remains inactive.
+		Enabled bool `fig:"enabled"`
+
+		// MaxBufferSize is the maximum number of stanzas held back before being flushed to an inactive client.
+		MaxBufferSize int `fig:"max_buffer_size" default:"100"`
+	} `fig:"csi"`
+
 	// CompressionLevel is the compression level that may be applied to the stream.
 	// Valid values are 'default', 'best', 'speed' and 'no_compression'.
 	CompressionLevel string `fig:"compression_level" default:"default"`
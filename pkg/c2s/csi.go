@@ -0,0 +1,147 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"context"
+
+	"github.com/jackal-xmpp/stravaganza"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/ortuman/jackal/pkg/hook"
+)
+
+const (
+	csiInactiveInfoKey = "csi:inactive"
+
+	carbonsNamespace = "urn:xmpp:carbons:2"
+	mamNamespace     = "urn:xmpp:mam:2"
+	forwardNamespace = "urn:xmpp:forward:0"
+)
+
+func (s *inC2S) processClientState(ctx context.Context, elem stravaganza.Element) error {
+	switch elem.Name() {
+	case "active":
+		return s.setInactive(ctx, false)
+	case "inactive":
+		return s.setInactive(ctx, true)
+	default:
+		return s.disconnect(ctx, streamerror.E(streamerror.UnsupportedStanzaType))
+	}
+}
+
+func (s *inC2S) setInactive(ctx context.Context, inactive bool) error {
+	if s.isInactive() == inactive {
+		return nil
+	}
+	if err := s.SetInfoValue(ctx, csiInactiveInfoKey, inactive); err != nil {
+		return err
+	}
+	hookName := hook.C2SStreamInactive
+	if !inactive {
+		if err := s.flushCSIBuffer(ctx); err != nil {
+			return err
+		}
+		hookName = hook.C2SStreamActive
+	}
+	_, err := s.runHook(ctx, hookName, &hook.C2SStreamInfo{
+		ID:       s.ID().String(),
+		JID:      s.JID(),
+		Presence: s.Presence(),
+	})
+	return err
+}
+
+func (s *inC2S) isInactive() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.inf.Bool(csiInactiveInfoKey)
+}
+
+func (s *inC2S) holdBackStanza(ctx context.Context, stanza stravaganza.Stanza) error {
+	if pr, ok := stanza.(*stravaganza.Presence); ok {
+		// only the latest presence of every contact resource is worth delivering
+		s.csiBuf = removeBufferedPresence(s.csiBuf, pr.FromJID().String())
+	}
+	s.csiBuf = append(s.csiBuf, stanza)
+
+	if len(s.csiBuf) >= s.cfg.csiMaxBufferSize {
+		return s.flushCSIBuffer(ctx)
+	}
+	return nil
+}
+
+func (s *inC2S) flushCSIBuffer(ctx context.Context) error {
+	buf := s.csiBuf
+	s.csiBuf = nil
+
+	// held stanzas must always be accounted (e.g. stream management), even if they can't be written anymore
+	for _, stanza := range buf {
+		if err := s.deliverElement(ctx, stanza); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeBufferedPresence(buf []stravaganza.Stanza, from string) []stravaganza.Stanza {
+	for i, stanza := range buf {
+		pr, ok := stanza.(*stravaganza.Presence)
+		if !ok || pr.FromJID().String() != from || !isAvailabilityPresence(pr) {
+			continue
+		}
+		return append(buf[:i], buf[i+1:]...)
+	}
+	return buf
+}
+
+// isUrgentStanza tells whether a stanza should be delivered right away to an inactive client.
+func isUrgentStanza(stanza stravaganza.Stanza) bool {
+	switch stz := stanza.(type) {
+	case *stravaganza.Message:
+		// chat states, receipts and alike can wait
+		msg := forwardedMessage(stz)
+		return msg.Child("body") != nil || msg.Child("subject") != nil
+	case *stravaganza.Presence:
+		return !isAvailabilityPresence(stz)
+	default:
+		return true
+	}
+}
+
+// forwardedMessage returns the message wrapped into a carbon copy or archive result, or msg itself otherwise.
+func forwardedMessage(msg *stravaganza.Message) stravaganza.Element {
+	wrapper := msg.ChildNamespace("sent", carbonsNamespace)
+	if wrapper == nil {
+		wrapper = msg.ChildNamespace("received", carbonsNamespace)
+	}
+	if wrapper == nil {
+		wrapper = msg.ChildNamespace("result", mamNamespace)
+	}
+	if wrapper == nil {
+		return msg
+	}
+	fwd := wrapper.ChildNamespace("forwarded", forwardNamespace)
+	if fwd == nil {
+		return msg
+	}
+	if fwdMsg := fwd.Child("message"); fwdMsg != nil {
+		return fwdMsg
+	}
+	return msg
+}
+
+func isAvailabilityPresence(pr *stravaganza.Presence) bool {
+	return pr.IsAvailable() || pr.IsUnavailable()
+}
@@ -0,0 +1,259 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/runqueue/v2"
+	"github.com/jackal-xmpp/stravaganza"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInC2S_CSIHoldBackStanzas(t *testing.T) {
+	// given
+	stm, outBuf, hk := testCSIStream(10)
+
+	var sentCount int
+	hk.AddHook(hook.C2SStreamElementSent, func(_ *hook.ExecutionContext) error {
+		sentCount++
+		return nil
+	}, hook.DefaultPriority)
+
+	// when
+	stm.handleSessionResult(testCSIElement("inactive"), nil)
+
+	_ = stm.sendElement(context.Background(), testCSIPresence("noelia@jackal.im/yard", "away"))
+	_ = stm.sendElement(context.Background(), testCSIPresence("noelia@jackal.im/yard", "xa"))
+	_ = stm.sendElement(context.Background(), testCSIPresence("noelia@jackal.im/balcony", "dnd"))
+	_ = stm.sendElement(context.Background(), testCSIMessage(false))
+
+	heldBack := outBuf.String()
+	heldBackSentCount := sentCount
+
+	_ = stm.sendElement(context.Background(), testCSIMessage(true))
+
+	// then
+	require.True(t, stm.Info().Bool(csiInactiveInfoKey))
+
+	require.Len(t, heldBack, 0)
+	require.Equal(t, 0, heldBackSentCount)
+
+	require.Equal(t, `<presence from='noelia@jackal.im/yard' to='ortuman@jackal.im/balcony'><show>xa</show></presence>`+
+		`<presence from='noelia@jackal.im/balcony' to='ortuman@jackal.im/balcony'><show>dnd</show></presence>`+
+		`<message from='noelia@jackal.im/yard' to='ortuman@jackal.im/balcony' type='chat'><composing xmlns='http://jabber.org/protocol/chatstates'/></message>`+
+		`<message from='noelia@jackal.im/yard' to='ortuman@jackal.im/balcony' type='chat'><body>See you there</body></message>`,
+		outBuf.String(),
+	)
+	require.Equal(t, 4, sentCount) // coalesced presence never gets accounted
+}
+
+func TestInC2S_CSIActive(t *testing.T) {
+	// given
+	stm, outBuf, hk := testCSIStream(10)
+
+	var activeHookRun bool
+	hk.AddHook(hook.C2SStreamActive, func(_ *hook.ExecutionContext) error {
+		activeHookRun = true
+		return nil
+	}, hook.DefaultPriority)
+
+	stm.handleSessionResult(testCSIElement("inactive"), nil)
+	_ = stm.sendElement(context.Background(), testCSIPresence("noelia@jackal.im/yard", "away"))
+
+	// when
+	stm.handleSessionResult(testCSIElement("active"), nil)
+
+	// then
+	require.False(t, stm.Info().Bool(csiInactiveInfoKey))
+	require.True(t, activeHookRun)
+	require.Equal(t, `<presence from='noelia@jackal.im/yard' to='ortuman@jackal.im/balcony'><show>away</show></presence>`, outBuf.String())
+	require.Len(t, stm.csiBuf, 0)
+}
+
+func TestInC2S_CSIMaxBufferSize(t *testing.T) {
+	// given
+	stm, outBuf, _ := testCSIStream(2)
+
+	stm.handleSessionResult(testCSIElement("inactive"), nil)
+
+	// when
+	_ = stm.sendElement(context.Background(), testCSIPresence("noelia@jackal.im/yard", "away"))
+	heldBackLen := outBuf.Len()
+
+	_ = stm.sendElement(context.Background(), testCSIPresence("noelia@jackal.im/balcony", "away"))
+
+	// then
+	require.Equal(t, 0, heldBackLen)
+	require.NotEqual(t, 0, outBuf.Len())
+	require.Len(t, stm.csiBuf, 0)
+}
+
+func TestInC2S_CSITimeoutDisconnect(t *testing.T) {
+	// given
+	stm, outBuf, hk := testCSIStream(10)
+
+	ssMock := stm.session.(*sessionMock)
+	ssMock.CloseFunc = func(_ context.Context) error { return nil }
+
+	// stream management accounts every sent stanza, keeping them around for a later resumption
+	var smQueue []string
+	hk.AddHook(hook.C2SStreamElementSent, func(execCtx *hook.ExecutionContext) error {
+		if stanza, ok := execCtx.Info.(*hook.C2SStreamInfo).Element.(stravaganza.Stanza); ok {
+			smQueue = append(smQueue, stanza.String())
+		}
+		return nil
+	}, hook.DefaultPriority)
+
+	discCh := make(chan struct{})
+	hk.AddHook(hook.C2SStreamDisconnected, func(_ *hook.ExecutionContext) error {
+		close(discCh)
+		return hook.ErrStopped // hibernated
+	}, hook.DefaultPriority)
+
+	stm.handleSessionResult(testCSIElement("inactive"), nil)
+	_ = stm.sendElement(context.Background(), testCSIPresence("noelia@jackal.im/yard", "away"))
+	_ = stm.sendElement(context.Background(), testCSIMessage(false))
+
+	// when
+	stm.Disconnect(streamerror.E(streamerror.ConnectionTimeout))
+
+	select {
+	case <-discCh:
+		break
+	case <-time.After(disconnectTimeout + time.Second):
+		require.Fail(t, "stream not disconnected")
+	}
+
+	// then
+	require.Equal(t, `<stream:error><connection-timeout xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error>`, outBuf.String())
+	require.Equal(t, []string{
+		testCSIPresence("noelia@jackal.im/yard", "away").String(),
+		testCSIMessage(false).String(),
+	}, smQueue)
+}
+
+func TestCSI_IsUrgentStanza(t *testing.T) {
+	var tcs = map[string]struct {
+		stanza         stravaganza.Stanza
+		expectedUrgent bool
+	}{
+		"MessageWithBody":        {testCSIMessage(true), true},
+		"ChatState":              {testCSIMessage(false), false},
+		"Presence":               {testCSIPresence("noelia@jackal.im/yard", "away"), false},
+		"SentCarbonWithBody":     {testCSIForwardedMessage("sent", carbonsNamespace, true), true},
+		"SentCarbonChatState":    {testCSIForwardedMessage("sent", carbonsNamespace, false), false},
+		"ReceivedCarbonWithBody": {testCSIForwardedMessage("received", carbonsNamespace, true), true},
+		"ArchiveResultWithBody":  {testCSIForwardedMessage("result", mamNamespace, true), true},
+		"ArchiveResultChatState": {testCSIForwardedMessage("result", mamNamespace, false), false},
+	}
+	for tn, tc := range tcs {
+		t.Run(tn, func(t *testing.T) {
+			require.Equal(t, tc.expectedUrgent, isUrgentStanza(tc.stanza))
+		})
+	}
+}
+
+func testCSIStream(maxBufferSize int) (*inC2S, *bytes.Buffer, *hook.Hooks) {
+	resMngMock := &resourceManagerMock{}
+	resMngMock.PutResourceFunc = func(_ context.Context, _ c2smodel.ResourceDesc) error { return nil }
+
+	outBuf := bytes.NewBuffer(nil)
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, element stravaganza.Element) error {
+		return element.ToXML(outBuf, true)
+	}
+	hk := hook.NewHooks()
+
+	jd, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+	stm := &inC2S{
+		cfg: inCfg{
+			reqTimeout:       time.Minute,
+			csi:              true,
+			csiMaxBufferSize: maxBufferSize,
+		},
+		state:   inBinded,
+		flags:   flags{flg: fSecured | fAuthenticated | fBinded},
+		rq:      runqueue.New("in_c2s:test"),
+		doneCh:  make(chan struct{}),
+		jd:      jd,
+		inf:     c2smodel.NewInfoMap(),
+		session: ssMock,
+		resMng:  resMngMock,
+		hk:      hk,
+		logger:  kitlog.NewNopLogger(),
+	}
+	return stm, outBuf, hk
+}
+
+func testCSIElement(name string) stravaganza.Element {
+	return stravaganza.NewBuilder(name).
+		WithAttribute(stravaganza.Namespace, csiNamespace).
+		Build()
+}
+
+func testCSIPresence(from, show string) *stravaganza.Presence {
+	pr, _ := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, "ortuman@jackal.im/balcony").
+		WithChild(stravaganza.NewBuilder("show").WithText(show).Build()).
+		BuildPresence()
+	return pr
+}
+
+func testCSIMessage(withBody bool) *stravaganza.Message {
+	b := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im/balcony").
+		WithAttribute(stravaganza.Type, stravaganza.ChatType)
+	if withBody {
+		b.WithChild(stravaganza.NewBuilder("body").WithText("See you there").Build())
+	} else {
+		b.WithChild(
+			stravaganza.NewBuilder("composing").
+				WithAttribute(stravaganza.Namespace, "http://jabber.org/protocol/chatstates").
+				Build(),
+		)
+	}
+	msg, _ := b.BuildMessage()
+	return msg
+}
+
+func testCSIForwardedMessage(wrapper, namespace string, withBody bool) *stravaganza.Message {
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im/balcony").
+		WithChild(
+			stravaganza.NewBuilder(wrapper).
+				WithAttribute(stravaganza.Namespace, namespace).
+				WithChild(
+					stravaganza.NewBuilder("forwarded").
+						WithAttribute(stravaganza.Namespace, forwardNamespace).
+						WithChild(testCSIMessage(withBody)).
+						Build(),
+				).
+				Build(),
+		).
+		BuildMessage()
+	return msg
+}
@@ -17,6 +17,7 @@ package c2s
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"sync"
@@ -64,12 +65,21 @@ const (
 const (
 	maxAuthFailed  = 5
 	maxAuthAborted = 1
+
+	externalMechanismName  = "EXTERNAL"
+	anonymousMechanismName = "ANONYMOUS"
 )
 
 var (
 	disconnectTimeout = time.Second * 5
 )
 
+var (
+	errMaxSessionsReached = errors.New("c2s: max session count reached")
+	errResourceConflict   = errors.New("c2s: resource conflict")
+	errInvalidResource    = errors.New("c2s: invalid resource")
+)
+
 type resourceConflict int8
 
 const (
@@ -86,18 +96,26 @@ type inCfg struct {
 	resConflict         resourceConflict
 	useTLS              bool
 	tlsConfig           *tls.Config
+	clientCAs           *x509.CertPool
+	sasl2               bool
+	allowRegistration   bool
+	csi                 bool
+	csiMaxBufferSize    int
 }
 
 type authState struct {
 	authenticators []auth.Authenticator
+	fastAuthrs     []auth.Authenticator
 	active         auth.Authenticator
 	failedTimes    int
 	abortTimes     int
+	sasl2Req       stravaganza.Element
 }
 
 func (a *authState) reset() {
 	a.active.Reset()
 	a.active = nil
+	a.sasl2Req = nil
 }
 
 type inC2S struct {
@@ -105,6 +123,7 @@ type inC2S struct {
 	cfg          inCfg
 	tr           transport.Transport
 	authSt       authState
+	fastTk       fastTokens
 	hosts        hosts
 	router       router.Router
 	comps        components
@@ -118,6 +137,7 @@ type inC2S struct {
 	discTm       *time.Timer
 	doneCh       chan struct{}
 	sendDisabled bool
+	csiBuf       []stravaganza.Stanza
 
 	mu    sync.RWMutex
 	state state
@@ -131,6 +151,7 @@ func newInC2S(
 	cfg inCfg,
 	tr transport.Transport,
 	authenticators []auth.Authenticator,
+	fastTk fastTokens,
 	hosts *host.Hosts,
 	router router.Router,
 	comps *component.Components,
@@ -179,6 +200,10 @@ func newInC2S(
 		hk:      hk,
 		logger:  sLogger,
 	}
+	if fastTk != nil {
+		stm.fastTk = fastTk
+		stm.authSt.fastAuthrs = fastTk.Authenticators(tr)
+	}
 	if cfg.useTLS {
 		stm.flags.setSecured() // stream already secured
 	}
@@ -291,6 +316,7 @@ func (s *inC2S) Resume(ctx context.Context, jd *jid.JID, pr *stravaganza.Presenc
 	s.jd = jd
 	s.pr = pr
 	s.inf = c2smodel.NewInfoMapFromInfo(inf)
+	s.inf.SetBool(csiInactiveInfoKey, false) // client state is not preserved across resumption
 	s.mu.Unlock()
 
 	s.session.SetFromJID(jd)
@@ -363,7 +389,7 @@ func (s *inC2S) readLoop() {
 		s.handleSessionResult(elem, sErr)
 
 		switch s.getState() {
-		case inAuthenticated:
+		case inAuthenticated, inBinded:
 			authTm.Stop()
 		case inDisconnected, inTerminated:
 			return
@@ -478,11 +504,17 @@ func (s *inC2S) handleConnected(ctx context.Context, elem stravaganza.Element) e
 	case "auth":
 		return s.startAuthentication(ctx, elem)
 
+	case "authenticate":
+		return s.startSASL2Authentication(ctx, elem)
+
 	case "iq":
 		if elem.ChildNamespace("query", "jabber:iq:auth") != nil {
 			// do not allow non-SASL authentication
 			return s.sendElement(ctx, stanzaerror.E(stanzaerror.ServiceUnavailable, elem).Element())
 		}
+		if iq, ok := elem.(*stravaganza.IQ); ok && s.cfg.allowRegistration {
+			return s.processPreAuthIQ(ctx, iq)
+		}
 		fallthrough
 
 	case "message", "presence":
@@ -494,6 +526,9 @@ func (s *inC2S) handleConnected(ctx context.Context, elem stravaganza.Element) e
 }
 
 func (s *inC2S) handleAuthenticating(ctx context.Context, elem stravaganza.Element) error {
+	if s.authSt.sasl2Req != nil {
+		return s.handleSASL2Authenticating(ctx, elem)
+	}
 	if elem.Attribute(stravaganza.Namespace) != saslNamespace {
 		return s.disconnect(ctx, streamerror.E(streamerror.InvalidNamespace))
 	}
@@ -524,6 +559,9 @@ func (s *inC2S) handleAuthenticated(ctx context.Context, elem stravaganza.Elemen
 }
 
 func (s *inC2S) handleBinded(ctx context.Context, elem stravaganza.Element) error {
+	if s.cfg.csi && elem.Attribute(stravaganza.Namespace) == csiNamespace {
+		return s.processClientState(ctx, elem)
+	}
 	switch stanza := elem.(type) {
 	case stravaganza.Stanza:
 		return s.processStanza(ctx, stanza)
@@ -765,25 +803,74 @@ func (s *inC2S) unauthenticatedFeatures() []stravaganza.Element {
 	shouldOfferSASL := !isSocketTr || (isSocketTr && s.flags.isSecured())
 
 	if shouldOfferSASL && len(s.authSt.authenticators) > 0 {
-		supportsCb := s.tr.SupportsChannelBinding()
+		mechanisms := s.mechanismElements()
 
 		sb := stravaganza.NewBuilder("mechanisms")
 		sb.WithAttribute(stravaganza.Namespace, saslNamespace)
-		for _, authenticator := range s.authSt.authenticators {
-			if authenticator.UsesChannelBinding() && !supportsCb {
-				continue // transport doesn't support channel binding
-			}
-			sb.WithChild(
-				stravaganza.NewBuilder("mechanism").
-					WithText(authenticator.Mechanism()).
-					Build(),
-			)
-		}
+		sb.WithChildren(mechanisms...)
 		features = append(features, sb.Build())
+
+		if s.cfg.sasl2 {
+			features = append(features, s.sasl2Feature(mechanisms))
+		}
+		// attach in-band registration feature
+		if s.cfg.allowRegistration && !s.hosts.IsAnonymousHost(s.Domain()) {
+			features = append(features, s.mods.PreAuthStreamFeatures()...)
+		}
 	}
 	return features
 }
 
+func (s *inC2S) processPreAuthIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	isInsecureSocket := s.tr.Type() == transport.Socket && !s.flags.isSecured()
+	if isInsecureSocket || s.hosts.IsAnonymousHost(s.Domain()) {
+		return s.sendElement(ctx, stanzaerror.E(stanzaerror.NotAllowed, iq).Element())
+	}
+	resp, err := s.mods.ProcessPreAuthIQ(ctx, iq, module.PreAuthInfo{
+		StreamID:   s.ID(),
+		RemoteAddr: s.tr.RemoteAddr(),
+	})
+	if err != nil {
+		return err
+	}
+	// stream is not bound to any account yet, so omit addressing attributes
+	return s.sendElement(ctx, stravaganza.NewBuilderFromElement(resp).
+		WithoutAttribute(stravaganza.From).
+		WithoutAttribute(stravaganza.To).
+		Build(),
+	)
+}
+
+func (s *inC2S) mechanismElements() []stravaganza.Element {
+	var mechanisms []stravaganza.Element
+
+	supportsCb := s.tr.SupportsChannelBinding()
+	for _, authenticator := range s.authSt.authenticators {
+		if !s.isMechanismAvailable(authenticator) {
+			continue
+		}
+		if authenticator.UsesChannelBinding() && !supportsCb {
+			continue // transport doesn't support channel binding
+		}
+		if authenticator.Mechanism() == externalMechanismName && len(s.tr.PeerCertificates()) == 0 {
+			continue // no client certificate presented
+		}
+		mechanisms = append(mechanisms,
+			stravaganza.NewBuilder("mechanism").
+				WithText(authenticator.Mechanism()).
+				Build(),
+		)
+	}
+	return mechanisms
+}
+
+// isMechanismAvailable tells whether authenticator can be used over the current stream domain.
+// Anonymous hosts exclusively offer ANONYMOUS mechanism, which in turn is not available on regular hosts.
+func (s *inC2S) isMechanismAvailable(authenticator auth.Authenticator) bool {
+	isAnonymous := authenticator.Mechanism() == anonymousMechanismName
+	return isAnonymous == s.hosts.IsAnonymousHost(s.Domain())
+}
+
 func (s *inC2S) authenticatedFeatures(ctx context.Context) ([]stravaganza.Element, error) {
 	var features []stravaganza.Element
 
@@ -816,6 +903,14 @@ func (s *inC2S) authenticatedFeatures(ctx context.Context) ([]stravaganza.Elemen
 		Build()
 	features = append(features, sessElem)
 
+	// client state indication feature
+	if s.cfg.csi {
+		csiElem := stravaganza.NewBuilder("csi").
+			WithAttribute(stravaganza.Namespace, csiNamespace).
+			Build()
+		features = append(features, csiElem)
+	}
+
 	// include module stream features
 	modFeatures, err := s.mods.StreamFeatures(ctx, s.JID().Domain())
 	if err != nil {
@@ -825,7 +920,7 @@ func (s *inC2S) authenticatedFeatures(ctx context.Context) ([]stravaganza.Elemen
 }
 
 func (s *inC2S) proceedStartTLS(ctx context.Context, elem stravaganza.Element) error {
-	if s.flags.isSecured() {
+	if s.flags.isSecured() || s.tr.Type() != transport.Socket {
 		return s.disconnect(ctx, streamerror.E(streamerror.NotAuthorized))
 	}
 	ns := elem.Attribute(stravaganza.Namespace)
@@ -841,9 +936,14 @@ func (s *inC2S) proceedStartTLS(ctx context.Context, elem stravaganza.Element) e
 	); err != nil {
 		return err
 	}
-	s.tr.StartTLS(&tls.Config{
+	tlsCfg := &tls.Config{
 		Certificates: s.hosts.Certificates(),
-	}, false)
+	}
+	if s.cfg.clientCAs != nil {
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+		tlsCfg.ClientCAs = s.cfg.clientCAs
+	}
+	s.tr.StartTLS(tlsCfg, false)
 
 	level.Info(s.logger).Log("msg", "secured C2S stream")
 
@@ -857,7 +957,7 @@ func (s *inC2S) startAuthentication(ctx context.Context, elem stravaganza.Elemen
 	}
 	mechanism := elem.Attribute("mechanism")
 	for _, authenticator := range s.authSt.authenticators {
-		if authenticator.Mechanism() != mechanism {
+		if authenticator.Mechanism() != mechanism || !s.isMechanismAvailable(authenticator) {
 			continue
 		}
 		s.authSt.active = authenticator
@@ -915,6 +1015,16 @@ func (s *inC2S) failAuthentication(ctx context.Context, saslErr *auth.SASLError)
 	if s.authSt.failedTimes >= maxAuthFailed {
 		return s.disconnect(ctx, streamerror.E(streamerror.PolicyViolation))
 	}
+	if s.authSt.sasl2Req != nil {
+		// SASL2 failure ends up current authentication exchange
+		s.authSt.reset()
+		s.setState(inConnected)
+		return s.sendElement(ctx, sasl2FailureElement(
+			stravaganza.NewBuilderFromElement(saslErr.Element()).
+				WithAttribute(stravaganza.Namespace, saslNamespace).
+				Build(),
+		))
+	}
 	failureElem := stravaganza.NewBuilder("failure").
 		WithAttribute(stravaganza.Namespace, saslNamespace).
 		WithChild(saslErr.Element()).
@@ -972,6 +1082,40 @@ func (s *inC2S) bindResource(ctx context.Context, iq *stravaganza.IQ) error {
 	if iq.Attribute(stravaganza.Type) != stravaganza.SetType || bind == nil {
 		return s.sendElement(ctx, stanzaerror.E(stanzaerror.NotAllowed, iq).Element())
 	}
+	var res string
+	if resElem := bind.Child("resource"); resElem != nil {
+		res = resElem.Text()
+	} else {
+		res = uuid.New().String() // server generated
+	}
+	switch err := s.bind(ctx, res); err {
+	case nil:
+		break
+	case errMaxSessionsReached:
+		return s.disconnect(ctx, maxSessionCountError())
+	case errResourceConflict:
+		return s.sendElement(ctx, stanzaerror.E(stanzaerror.Conflict, iq).Element())
+	case errInvalidResource:
+		return s.sendElement(ctx, stanzaerror.E(stanzaerror.BadRequest, iq).Element())
+	default:
+		return err
+	}
+
+	// notify successful binding
+	resIQ := xmpputil.MakeResultIQ(iq,
+		stravaganza.NewBuilder("bind").
+			WithAttribute(stravaganza.Namespace, bindNamespace).
+			WithChild(
+				stravaganza.NewBuilder("jid").
+					WithText(s.JID().String()).
+					Build(),
+			).
+			Build(),
+	)
+	return s.sendElement(ctx, resIQ)
+}
+
+func (s *inC2S) bind(ctx context.Context, res string) error {
 	// fetch active resources
 	rss, err := s.resMng.GetResources(ctx, s.Username())
 	if err != nil {
@@ -980,53 +1124,42 @@ func (s *inC2S) bindResource(ctx context.Context, iq *stravaganza.IQ) error {
 	// check is max session count has been reached
 	maxSessions := s.shapers.MatchingJID(s.JID()).MaxSessions
 	if len(rss) == maxSessions {
-		se := streamerror.E(streamerror.PolicyViolation)
-		se.ApplicationElement = stravaganza.NewBuilder("reached-max-session-count").
-			WithAttribute(stravaganza.Namespace, "urn:xmpp:errors").
-			Build()
-		return s.disconnect(ctx, se)
+		return errMaxSessionsReached
 	}
 
-	var res string
-	if resElem := bind.Child("resource"); resElem != nil {
-		res = resElem.Text()
+	// check if another stream with same resource value did already connect
+	for _, rs := range rss {
+		if rs.JID().Resource() != res {
+			continue
+		}
+		switch s.cfg.resConflict {
+		// replace by a server generated resourcepart
+		case override:
+			res = uuid.New().String()
+			break
 
-		// check if another stream with same resource value did already connect
-		for _, rs := range rss {
-			if rs.JID().Resource() != res {
-				continue
-			}
-			switch s.cfg.resConflict {
-			// replace by a server generated resourcepart
-			case override:
-				res = uuid.New().String()
-				break
-
-			// disconnect previously connected resource
-			case terminateOld:
-				se := streamerror.E(streamerror.PolicyViolation)
-				se.ApplicationElement = stravaganza.NewBuilder("resource-conflict").
-					WithAttribute(stravaganza.Namespace, "urn:xmpp:errors").
-					Build()
-				if err := s.router.C2S().Disconnect(ctx, rs, se); err != nil {
-					return err
-				}
-				break
-
-			// disallow resource binding
-			case disallow:
-				return s.sendElement(ctx, stanzaerror.E(stanzaerror.Conflict, iq).Element())
+		// disconnect previously connected resource
+		case terminateOld:
+			se := streamerror.E(streamerror.PolicyViolation)
+			se.ApplicationElement = stravaganza.NewBuilder("resource-conflict").
+				WithAttribute(stravaganza.Namespace, "urn:xmpp:errors").
+				Build()
+			if err := s.router.C2S().Disconnect(ctx, rs, se); err != nil {
+				return err
 			}
 			break
+
+		// disallow resource binding
+		case disallow:
+			return errResourceConflict
 		}
-	} else {
-		res = uuid.New().String() // server generated
+		break
 	}
 
 	// set stream jid and presence
 	userJID, err := jid.New(s.Username(), s.Domain(), res, false)
 	if err != nil {
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.BadRequest, iq).Element())
+		return errInvalidResource
 	}
 	s.setJID(userJID)
 	s.session.SetFromJID(userJID)
@@ -1049,22 +1182,7 @@ func (s *inC2S) bindResource(ctx context.Context, iq *stravaganza.IQ) error {
 		ID:  s.ID().String(),
 		JID: s.JID(),
 	})
-	if err != nil {
-		return err
-	}
-
-	// notify successful binding
-	resIQ := xmpputil.MakeResultIQ(iq,
-		stravaganza.NewBuilder("bind").
-			WithAttribute(stravaganza.Namespace, bindNamespace).
-			WithChild(
-				stravaganza.NewBuilder("jid").
-					WithText(s.JID().String()).
-					Build(),
-			).
-			Build(),
-	)
-	return s.sendElement(ctx, resIQ)
+	return err
 }
 
 func (s *inC2S) disconnect(ctx context.Context, streamErr *streamerror.Error) error {
@@ -1096,7 +1214,7 @@ func (s *inC2S) disconnect(ctx context.Context, streamErr *streamerror.Error) er
 func (s *inC2S) close(ctx context.Context, disconnectErr error) error {
 	switch s.getState() {
 	case inDisconnected:
-		return s.terminate(ctx) // disconnected... terminate stream
+		return s.terminate(ctx, disconnectErr) // disconnected... terminate stream
 	case inTerminated:
 		return nil // terminated... we're done here
 	default:
@@ -1107,6 +1225,10 @@ func (s *inC2S) close(ctx context.Context, disconnectErr error) error {
 	if s.discTm != nil {
 		s.discTm.Stop()
 	}
+	// deliver held back stanzas, so that they can be accounted before disconnecting
+	if err := s.flushCSIBuffer(ctx); err != nil {
+		return err
+	}
 	// run disconnected C2S hook
 	halted, err := s.runHook(ctx, hook.C2SStreamDisconnected, &hook.C2SStreamInfo{
 		ID:              s.ID().String(),
@@ -1120,10 +1242,10 @@ func (s *inC2S) close(ctx context.Context, disconnectErr error) error {
 	if err != nil {
 		return err
 	}
-	return s.terminate(ctx)
+	return s.terminate(ctx, disconnectErr)
 }
 
-func (s *inC2S) terminate(ctx context.Context) error {
+func (s *inC2S) terminate(ctx context.Context, disconnectErr error) error {
 	// unregister C2S stream
 	if err := s.router.C2S().Unregister(s); err != nil {
 		return err
@@ -1138,8 +1260,9 @@ func (s *inC2S) terminate(ctx context.Context) error {
 	_ = s.tr.Close()
 
 	_, err := s.runHook(ctx, hook.C2SStreamTerminated, &hook.C2SStreamInfo{
-		ID:  s.ID().String(),
-		JID: s.JID(),
+		ID:              s.ID().String(),
+		JID:             s.JID(),
+		DisconnectError: disconnectErr,
 	})
 	if err != nil {
 		return err
@@ -1159,12 +1282,27 @@ func (s *inC2S) sendElement(ctx context.Context, elem stravaganza.Element) error
 	if s.sendDisabled {
 		return nil
 	}
-	_ = s.session.Send(ctx, elem)
+	if stanza, ok := elem.(stravaganza.Stanza); ok && s.isInactive() {
+		if !isUrgentStanza(stanza) {
+			return s.holdBackStanza(ctx, stanza)
+		}
+		// preserve delivery order
+		if err := s.flushCSIBuffer(ctx); err != nil {
+			return err
+		}
+	}
+	return s.deliverElement(ctx, elem)
+}
 
-	reportOutgoingRequest(
-		elem.Name(),
-		elem.Attribute(stravaganza.Type),
-	)
+func (s *inC2S) deliverElement(ctx context.Context, elem stravaganza.Element) error {
+	if !s.sendDisabled {
+		_ = s.session.Send(ctx, elem)
+
+		reportOutgoingRequest(
+			elem.Name(),
+			elem.Attribute(stravaganza.Type),
+		)
+	}
 	// run element sent hook
 	_, err := s.runHook(ctx, hook.C2SStreamElementSent, &hook.C2SStreamInfo{
 		ID:      s.ID().String(),
@@ -1174,6 +1312,14 @@ func (s *inC2S) sendElement(ctx context.Context, elem stravaganza.Element) error
 	return err
 }
 
+func maxSessionCountError() *streamerror.Error {
+	se := streamerror.E(streamerror.PolicyViolation)
+	se.ApplicationElement = stravaganza.NewBuilder("reached-max-session-count").
+		WithAttribute(stravaganza.Namespace, "urn:xmpp:errors").
+		Build()
+	return se
+}
+
 func (s *inC2S) getResource() c2smodel.ResourceDesc {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
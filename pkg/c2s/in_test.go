@@ -18,7 +18,9 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"io"
+	"net"
 	"strings"
 	"sync"
 	"testing"
@@ -33,6 +35,7 @@ import (
 	"github.com/ortuman/jackal/pkg/auth"
 	"github.com/ortuman/jackal/pkg/hook"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 	"github.com/ortuman/jackal/pkg/transport"
@@ -146,11 +149,14 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 
 		// input
 		state         state
+		transportType transport.Type
 		sessionResFn  func() (stravaganza.Element, error)
 		authProcessFn func(_ context.Context, _ stravaganza.Element) (stravaganza.Element, *auth.SASLError)
 		routeError    error
 		hubResources  []c2smodel.ResourceDesc
 		flags         uint8
+		sasl2         bool
+		registration  bool
 
 		// expectations
 		expectedOutput        string
@@ -187,6 +193,120 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' id='c2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>PLAIN</mechanism></mechanisms></stream:features>`,
 			expectedState:  inConnected,
 		},
+		{
+			name:          "Connecting/WebSocket",
+			state:         inConnecting,
+			transportType: transport.WebSocket,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("open").
+					WithAttribute(stravaganza.Namespace, "urn:ietf:params:xml:ns:xmpp-framing").
+					WithAttribute(stravaganza.To, "localhost").
+					WithAttribute(stravaganza.Version, "1.0").
+					Build(), nil
+			},
+			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' id='c2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>PLAIN</mechanism></mechanisms></stream:features>`,
+			expectedState:  inConnected,
+		},
+		{
+			name:          "Connecting/WebSocketAuthenticated",
+			state:         inConnecting,
+			transportType: transport.WebSocket,
+			flags:         fAuthenticated,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("open").
+					WithAttribute(stravaganza.Namespace, "urn:ietf:params:xml:ns:xmpp-framing").
+					WithAttribute(stravaganza.To, "localhost").
+					WithAttribute(stravaganza.Version, "1.0").
+					Build(), nil
+			},
+			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' id='c2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'><required/></bind><session xmlns='urn:ietf:params:xml:ns:xmpp-session'/></stream:features>`,
+			expectedState:  inAuthenticated,
+		},
+		{
+			name:          "Connected/WebSocketStartTLS",
+			state:         inConnected,
+			transportType: transport.WebSocket,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("starttls").
+					WithAttribute(stravaganza.Namespace, tlsNamespace).
+					Build(), nil
+			},
+			expectedOutput: `<stream:error><not-authorized xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error></stream:stream>`,
+			expectedState:  inTerminated,
+		},
+		{
+			name:  "Connecting/SecuredSASL2",
+			state: inConnecting,
+			flags: fSecured,
+			sasl2: true,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("stream:stream").
+					WithAttribute(stravaganza.Namespace, "jabber:client").
+					WithAttribute(stravaganza.StreamNamespace, "http://etherx.jabber.org/streams").
+					WithAttribute(stravaganza.To, "localhost").
+					WithAttribute(stravaganza.Version, "1.0").
+					Build(), nil
+			},
+			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' id='c2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>PLAIN</mechanism></mechanisms><authentication xmlns='urn:xmpp:sasl:2'><mechanism>PLAIN</mechanism><inline><sm xmlns='urn:xmpp:sm:3'/><bind xmlns='urn:xmpp:bind:0'><inline><feature var='urn:xmpp:carbons:2'/></inline></bind></inline></authentication></stream:features>`,
+			expectedState:  inConnected,
+		},
+		{
+			name:         "Connecting/SecuredRegistration",
+			state:        inConnecting,
+			flags:        fSecured,
+			registration: true,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("stream:stream").
+					WithAttribute(stravaganza.Namespace, "jabber:client").
+					WithAttribute(stravaganza.StreamNamespace, "http://etherx.jabber.org/streams").
+					WithAttribute(stravaganza.To, "localhost").
+					WithAttribute(stravaganza.Version, "1.0").
+					Build(), nil
+			},
+			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' id='c2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>PLAIN</mechanism></mechanisms><register xmlns='http://jabber.org/features/iq-register'/></stream:features>`,
+			expectedState:  inConnected,
+		},
+		{
+			name:         "Connected/Registration",
+			state:        inConnected,
+			flags:        fSecured,
+			registration: true,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewIQBuilder().
+					WithAttribute(stravaganza.ID, "reg1").
+					WithAttribute(stravaganza.Type, stravaganza.GetType).
+					WithAttribute(stravaganza.From, "localhost").
+					WithAttribute(stravaganza.To, "localhost").
+					WithChild(
+						stravaganza.NewBuilder("query").
+							WithAttribute(stravaganza.Namespace, "jabber:iq:register").
+							Build(),
+					).
+					BuildIQ()
+			},
+			expectedOutput: `<iq id='reg1' type='result'/>`,
+			expectedState:  inConnected,
+		},
+		{
+			name:  "Connected/RegistrationNotAllowed",
+			state: inConnected,
+			flags: fSecured,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewIQBuilder().
+					WithAttribute(stravaganza.ID, "reg1").
+					WithAttribute(stravaganza.Type, stravaganza.GetType).
+					WithAttribute(stravaganza.From, "localhost").
+					WithAttribute(stravaganza.To, "localhost").
+					WithChild(
+						stravaganza.NewBuilder("query").
+							WithAttribute(stravaganza.Namespace, "jabber:iq:register").
+							Build(),
+					).
+					BuildIQ()
+			},
+			expectedOutput: `<stream:error><not-authorized xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error></stream:stream>`,
+			expectedState:  inTerminated,
+		},
 		{
 			name:  "Connecting/SecuredAndAuthenticated",
 			state: inConnecting,
@@ -338,6 +458,64 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			expectedOutput: `<failure xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><incorrect-encoding/></failure>`,
 			expectedState:  inAuthenticating,
 		},
+		{
+			name:  "Connected/SASL2Disabled",
+			state: inConnected,
+			flags: fSecured,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("authenticate").
+					WithAttribute(stravaganza.Namespace, sasl2Namespace).
+					WithAttribute("mechanism", "PLAIN").
+					Build(), nil
+			},
+			expectedOutput: `<stream:error><unsupported-stanza-type xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error></stream:stream>`,
+			expectedState:  inTerminated,
+		},
+		{
+			name:  "Connected/SASL2Challenge",
+			state: inConnected,
+			flags: fSecured,
+			sasl2: true,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("authenticate").
+					WithAttribute(stravaganza.Namespace, sasl2Namespace).
+					WithAttribute("mechanism", "PLAIN").
+					WithChild(
+						stravaganza.NewBuilder("initial-response").
+							WithText("biwsbj1vcnR1bWFuLHI9MTIzNA==").
+							Build(),
+					).
+					Build(), nil
+			},
+			authProcessFn: func(_ context.Context, elem stravaganza.Element) (stravaganza.Element, *auth.SASLError) {
+				if elem.Name() != "auth" || elem.Text() != "biwsbj1vcnR1bWFuLHI9MTIzNA==" {
+					return nil, &auth.SASLError{Reason: auth.MalformedRequest}
+				}
+				return stravaganza.NewBuilder("challenge").
+					WithAttribute(stravaganza.Namespace, saslNamespace).
+					WithText("cj0xMjM0").
+					Build(), nil
+			},
+			expectedOutput: `<challenge xmlns='urn:xmpp:sasl:2'>cj0xMjM0</challenge>`,
+			expectedState:  inAuthenticating,
+		},
+		{
+			name:  "Authenticating/SASL2Fail",
+			state: inAuthenticating,
+			flags: fSecured,
+			sasl2: true,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("response").
+					WithAttribute(stravaganza.Namespace, sasl2Namespace).
+					WithText("Yz1iaXdzLHI9MTIzNA==").
+					Build(), nil
+			},
+			authProcessFn: func(_ context.Context, _ stravaganza.Element) (stravaganza.Element, *auth.SASLError) {
+				return nil, &auth.SASLError{Reason: auth.NotAuthorized}
+			},
+			expectedOutput: `<failure xmlns='urn:xmpp:sasl:2'><not-authorized xmlns='urn:ietf:params:xml:ns:xmpp-sasl'/></failure>`,
+			expectedState:  inConnected,
+		},
 		{
 			name:  "Authenticated/BindSuccess",
 			state: inAuthenticated,
@@ -640,15 +818,22 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			authMock := &authenticatorMock{}
 
 			// transport mock
-			trMock.TypeFunc = func() transport.Type { return transport.Socket }
+			trMock.TypeFunc = func() transport.Type {
+				if tt.transportType != 0 {
+					return tt.transportType
+				}
+				return transport.Socket
+			}
 			trMock.StartTLSFunc = func(cfg *tls.Config, asClient bool) {}
 			trMock.SupportsChannelBindingFunc = func() bool { return false }
 			trMock.EnableCompressionFunc = func(_ compress.Level) {}
 			trMock.SetReadRateLimiterFunc = func(rLim *rate.Limiter) error { return nil }
 			trMock.CloseFunc = func() error { return nil }
+			trMock.RemoteAddrFunc = func() net.Addr { return nil }
 
 			// hosts mock
 			hMock.IsLocalHostFunc = func(host string) bool { return host == "localhost" }
+			hMock.IsAnonymousHostFunc = func(host string) bool { return false }
 			hMock.CertificatesFunc = func() []tls.Certificate { return nil }
 
 			// router mocks
@@ -673,13 +858,32 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			// modules mock
 			modsMock.StreamFeaturesFunc = func(_ context.Context, _ string) ([]stravaganza.Element, error) { return nil, nil }
 			modsMock.IsModuleIQFunc = func(iq *stravaganza.IQ) bool { return false }
+			modsMock.SASL2InlineFeaturesFunc = func() []stravaganza.Element {
+				return []stravaganza.Element{
+					stravaganza.NewBuilder("sm").WithAttribute(stravaganza.Namespace, "urn:xmpp:sm:3").Build(),
+				}
+			}
+			modsMock.BindInlineFeaturesFunc = func() []string { return []string{"urn:xmpp:carbons:2"} }
+			modsMock.PreAuthStreamFeaturesFunc = func() []stravaganza.Element {
+				return []stravaganza.Element{
+					stravaganza.NewBuilder("register").WithAttribute(stravaganza.Namespace, "http://jabber.org/features/iq-register").Build(),
+				}
+			}
+			modsMock.ProcessPreAuthIQFunc = func(_ context.Context, iq *stravaganza.IQ, _ module.PreAuthInfo) (stravaganza.Stanza, error) {
+				return iq.ResultBuilder().BuildIQ()
+			}
 
 			// authenticator mock
 			authMock.MechanismFunc = func() string { return "PLAIN" }
-			authMock.AuthenticatedFunc = func() bool { return true }
+			var challenged bool
+			authMock.AuthenticatedFunc = func() bool { return !challenged }
 			authMock.ResetFunc = func() {}
 			authMock.UsernameFunc = func() string { return "ortuman" }
-			authMock.ProcessElementFunc = tt.authProcessFn
+			authMock.ProcessElementFunc = func(ctx context.Context, elem stravaganza.Element) (stravaganza.Element, *auth.SASLError) {
+				out, saslErr := tt.authProcessFn(ctx, elem)
+				challenged = out != nil && out.Name() == "challenge"
+				return out, saslErr
+			}
 			authMock.UsesChannelBindingFunc = func() bool { return false }
 
 			// session mock
@@ -722,10 +926,12 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			userJID, _ := jid.NewWithString("ortuman@localhost", true)
 			stm := &inC2S{
 				cfg: inCfg{
-					reqTimeout:       time.Minute,
-					maxStanzaSize:    8192,
-					compressionLevel: compress.DefaultCompression,
-					resConflict:      disallow,
+					reqTimeout:        time.Minute,
+					maxStanzaSize:     8192,
+					compressionLevel:  compress.DefaultCompression,
+					resConflict:       disallow,
+					sasl2:             tt.sasl2,
+					allowRegistration: tt.registration,
 				},
 				state:  tt.state,
 				flags:  flags{flg: tt.flags},
@@ -747,6 +953,12 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 				hk:      hook.NewHooks(),
 				logger:  kitlog.NewNopLogger(),
 			}
+			if tt.sasl2 && tt.state == inAuthenticating {
+				stm.authSt.sasl2Req = stravaganza.NewBuilder("authenticate").
+					WithAttribute(stravaganza.Namespace, sasl2Namespace).
+					WithAttribute("mechanism", "PLAIN").
+					Build()
+			}
 			// when
 			stm.handleSessionResult(tt.sessionResFn())
 
@@ -759,6 +971,112 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 	}
 }
 
+func TestInC2S_ExternalMechanism(t *testing.T) {
+	var tcs = map[string]struct {
+		peerCerts          []*x509.Certificate
+		expectedMechanisms []string
+	}{
+		"WithClientCertificate": {
+			peerCerts:          []*x509.Certificate{{}},
+			expectedMechanisms: []string{"SCRAM-SHA-1", "EXTERNAL"},
+		},
+		"WithoutClientCertificate": {
+			expectedMechanisms: []string{"SCRAM-SHA-1"},
+		},
+	}
+	for tn, tc := range tcs {
+		t.Run(tn, func(t *testing.T) {
+			// given
+			trMock := &transportMock{}
+			trMock.SupportsChannelBindingFunc = func() bool { return false }
+			trMock.PeerCertificatesFunc = func() []*x509.Certificate { return tc.peerCerts }
+
+			scramMock := &authenticatorMock{}
+			scramMock.MechanismFunc = func() string { return "SCRAM-SHA-1" }
+			scramMock.UsesChannelBindingFunc = func() bool { return false }
+
+			externalMock := &authenticatorMock{}
+			externalMock.MechanismFunc = func() string { return "EXTERNAL" }
+			externalMock.UsesChannelBindingFunc = func() bool { return false }
+
+			hMock := &hostsMock{}
+			hMock.IsAnonymousHostFunc = func(host string) bool { return false }
+
+			stm := &inC2S{
+				tr:    trMock,
+				hosts: hMock,
+				authSt: authState{
+					authenticators: []auth.Authenticator{scramMock, externalMock},
+				},
+			}
+
+			// when
+			elems := stm.mechanismElements()
+
+			// then
+			var mechanisms []string
+			for _, elem := range elems {
+				mechanisms = append(mechanisms, elem.Text())
+			}
+			require.Equal(t, tc.expectedMechanisms, mechanisms)
+		})
+	}
+}
+
+func TestInC2S_AnonymousMechanism(t *testing.T) {
+	var tcs = map[string]struct {
+		domain             string
+		expectedMechanisms []string
+	}{
+		"AnonymousHost": {
+			domain:             "anon.jackal.im",
+			expectedMechanisms: []string{"ANONYMOUS"},
+		},
+		"RegularHost": {
+			domain:             "jackal.im",
+			expectedMechanisms: []string{"SCRAM-SHA-1"},
+		},
+	}
+	for tn, tc := range tcs {
+		t.Run(tn, func(t *testing.T) {
+			// given
+			trMock := &transportMock{}
+			trMock.SupportsChannelBindingFunc = func() bool { return false }
+
+			hMock := &hostsMock{}
+			hMock.IsAnonymousHostFunc = func(host string) bool { return host == "anon.jackal.im" }
+
+			scramMock := &authenticatorMock{}
+			scramMock.MechanismFunc = func() string { return "SCRAM-SHA-1" }
+			scramMock.UsesChannelBindingFunc = func() bool { return false }
+
+			anonymousMock := &authenticatorMock{}
+			anonymousMock.MechanismFunc = func() string { return "ANONYMOUS" }
+			anonymousMock.UsesChannelBindingFunc = func() bool { return false }
+
+			domainJID, _ := jid.NewWithString(tc.domain, true)
+			stm := &inC2S{
+				jd:    domainJID,
+				tr:    trMock,
+				hosts: hMock,
+				authSt: authState{
+					authenticators: []auth.Authenticator{scramMock, anonymousMock},
+				},
+			}
+
+			// when
+			elems := stm.mechanismElements()
+
+			// then
+			var mechanisms []string
+			for _, elem := range elems {
+				mechanisms = append(mechanisms, elem.Text())
+			}
+			require.Equal(t, tc.expectedMechanisms, mechanisms)
+		})
+	}
+}
+
 func TestInC2S_HandleSessionError(t *testing.T) {
 	var tests = []struct {
 		name           string
@@ -25,6 +25,8 @@ import (
 	"github.com/ortuman/jackal/pkg/cluster/kv"
 	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	clustermodel "github.com/ortuman/jackal/pkg/model/cluster"
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 	"github.com/ortuman/jackal/pkg/storage/repository"
@@ -56,6 +58,14 @@ type c2sAuthenticator interface {
 	auth.Authenticator
 }
 
+//go:generate moq -out fast_tokens.mock_test.go . fastTokens:fastTokensMock
+type fastTokens interface {
+	Authenticators(tr transport.Transport) []auth.Authenticator
+	Issue(ctx context.Context, username, clientID, mechanism string) (*fastmodel.Token, error)
+	Rotate(ctx context.Context, a auth.Authenticator) (*fastmodel.Token, error)
+	Invalidate(ctx context.Context, a auth.Authenticator) error
+}
+
 //go:generate moq -out repository.mock_test.go . c2sRepository:repositoryMock
 type c2sRepository interface {
 	repository.Repository
@@ -75,6 +85,7 @@ type globalC2SRouter interface {
 type hosts interface {
 	Certificates() []tls.Certificate
 	IsLocalHost(host string) bool
+	IsAnonymousHost(host string) bool
 }
 
 //go:generate moq -out session.mock_test.go . session
@@ -126,6 +137,15 @@ type modules interface {
 
 	IsModuleIQ(iq *stravaganza.IQ) bool
 	ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error
+
+	PreAuthStreamFeatures() []stravaganza.Element
+	ProcessPreAuthIQ(ctx context.Context, iq *stravaganza.IQ, inf module.PreAuthInfo) (stravaganza.Stanza, error)
+
+	SASL2InlineFeatures() []stravaganza.Element
+	ProcessSASL2Inline(ctx context.Context, elem stravaganza.Element, stm stream.C2S) (stravaganza.Element, error)
+
+	BindInlineFeatures() []string
+	ProcessBindInline(ctx context.Context, elem stravaganza.Element, stm stream.C2S) (stravaganza.Element, error)
 }
 
 //go:generate moq -out resourcemanager.mock_test.go . resourceManager
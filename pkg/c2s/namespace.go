@@ -17,9 +17,13 @@ package c2s
 const (
 	streamNamespace        = "http://etherx.jabber.org/streams"
 	saslNamespace          = "urn:ietf:params:xml:ns:xmpp-sasl"
+	sasl2Namespace         = "urn:xmpp:sasl:2"
+	bind2Namespace         = "urn:xmpp:bind:0"
+	fastNamespace          = "urn:xmpp:fast:0"
 	tlsNamespace           = "urn:ietf:params:xml:ns:xmpp-tls"
 	compressNamespace      = "http://jabber.org/protocol/compress"
 	bindNamespace          = "urn:ietf:params:xml:ns:xmpp-bind"
 	sessionNamespace       = "urn:ietf:params:xml:ns:xmpp-session"
+	csiNamespace           = "urn:xmpp:csi:0"
 	blockingErrorNamespace = "urn:xmpp:blocking:errors"
 )
@@ -0,0 +1,327 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+)
+
+func (s *inC2S) sasl2Feature(mechanisms []stravaganza.Element) stravaganza.Element {
+	// Bind 2 inline features
+	ib := stravaganza.NewBuilder("inline")
+	for _, ns := range s.mods.BindInlineFeatures() {
+		ib.WithChild(
+			stravaganza.NewBuilder("feature").
+				WithAttribute("var", ns).
+				Build(),
+		)
+	}
+	bindElem := stravaganza.NewBuilder("bind").
+		WithAttribute(stravaganza.Namespace, bind2Namespace).
+		WithChild(ib.Build()).
+		Build()
+
+	inb := stravaganza.NewBuilder("inline").
+		WithChildren(s.mods.SASL2InlineFeatures()...)
+	if fastElem := s.fastFeature(); fastElem != nil {
+		inb.WithChild(fastElem)
+	}
+	inlineElem := inb.WithChild(bindElem).Build()
+
+	return stravaganza.NewBuilder("authentication").
+		WithAttribute(stravaganza.Namespace, sasl2Namespace).
+		WithChildren(mechanisms...).
+		WithChild(inlineElem).
+		Build()
+}
+
+func (s *inC2S) startSASL2Authentication(ctx context.Context, elem stravaganza.Element) error {
+	if !s.cfg.sasl2 {
+		return s.disconnect(ctx, streamerror.E(streamerror.UnsupportedStanzaType))
+	}
+	if elem.Attribute(stravaganza.Namespace) != sasl2Namespace {
+		return s.disconnect(ctx, streamerror.E(streamerror.InvalidNamespace))
+	}
+	mechanism := elem.Attribute("mechanism")
+	authenticators := s.authSt.authenticators
+	if s.isFastMechanism(mechanism) { // skip FAST mechanisms not supported by transport
+		authenticators = append(authenticators[:len(authenticators):len(authenticators)], s.authSt.fastAuthrs...)
+	}
+	for _, authenticator := range authenticators {
+		if authenticator.Mechanism() != mechanism || !s.isMechanismAvailable(authenticator) {
+			continue
+		}
+		s.authSt.active = authenticator
+		s.authSt.sasl2Req = elem
+		s.setState(inAuthenticating)
+
+		// translate initial response into its SASL counterpart
+		ab := stravaganza.NewBuilder("auth").
+			WithAttribute(stravaganza.Namespace, saslNamespace).
+			WithAttribute("mechanism", mechanism)
+		if ir := elem.Child("initial-response"); ir != nil {
+			ab.WithText(ir.Text())
+		}
+		return s.continueSASL2Authentication(ctx, ab.Build())
+	}
+	// ...mechanism not found...
+	return s.sendElement(ctx, sasl2FailureElement(
+		stravaganza.NewBuilder("invalid-mechanism").
+			WithAttribute(stravaganza.Namespace, saslNamespace).
+			Build(),
+	))
+}
+
+func (s *inC2S) handleSASL2Authenticating(ctx context.Context, elem stravaganza.Element) error {
+	if elem.Attribute(stravaganza.Namespace) != sasl2Namespace {
+		return s.disconnect(ctx, streamerror.E(streamerror.InvalidNamespace))
+	}
+	switch elem.Name() {
+	case "abort": // initiating entity aborted the handshake
+		return s.abortAuthentication(ctx)
+
+	case "response":
+		return s.continueSASL2Authentication(ctx,
+			stravaganza.NewBuilder("response").
+				WithAttribute(stravaganza.Namespace, saslNamespace).
+				WithText(elem.Text()).
+				Build(),
+		)
+
+	default:
+		return s.disconnect(ctx, streamerror.E(streamerror.UnsupportedStanzaType))
+	}
+}
+
+func (s *inC2S) continueSASL2Authentication(ctx context.Context, elem stravaganza.Element) error {
+	resp, saslErr := s.authSt.active.ProcessElement(ctx, elem)
+	if saslErr != nil {
+		return s.failAuthentication(ctx, saslErr)
+	}
+	if s.authSt.active.Authenticated() {
+		return s.finishSASL2Authentication(ctx, resp.Text())
+	}
+	return s.sendElement(ctx, stravaganza.NewBuilder("challenge").
+		WithAttribute(stravaganza.Namespace, sasl2Namespace).
+		WithText(resp.Text()).
+		Build(),
+	)
+}
+
+func (s *inC2S) finishSASL2Authentication(ctx context.Context, additionalData string) error {
+	req := s.authSt.sasl2Req
+	username := s.authSt.active.Username()
+
+	j, _ := jid.New(username, s.Domain(), "", true)
+	s.setJID(j)
+	s.flags.setAuthenticated()
+
+	// update rate limiter
+	if err := s.updateRateLimiter(); err != nil {
+		return err
+	}
+	level.Info(s.logger).Log("msg", "authenticated C2S stream", "username", username, "sasl2", true)
+
+	// FAST requests must be processed before resetting authenticator state
+	tokenElem := s.processFastInline(ctx, req)
+
+	s.authSt.reset()
+	s.setState(inAuthenticated) // stream is not restarted after a SASL2 success
+
+	// process inline requests
+	var inlineElems []stravaganza.Element
+	var bindReq stravaganza.Element
+
+	for _, child := range req.AllChildren() {
+		ns := child.Attribute(stravaganza.Namespace)
+		switch {
+		case len(ns) == 0 || ns == sasl2Namespace:
+			continue // initial-response and user-agent elements
+
+		case ns == fastNamespace:
+			continue // already processed
+
+		case ns == bind2Namespace && child.Name() == "bind":
+			bindReq = child
+
+		default:
+			inlineElem, err := s.mods.ProcessSASL2Inline(ctx, child, s)
+			if err != nil {
+				return err
+			}
+			if inlineElem != nil {
+				inlineElems = append(inlineElems, inlineElem)
+			}
+		}
+	}
+	var boundElem stravaganza.Element
+	if bindReq != nil && !s.flags.isBinded() {
+		var err error
+		boundElem, err = s.bind2(ctx, bindReq)
+		switch err {
+		case nil:
+			break
+		case errMaxSessionsReached:
+			return s.disconnect(ctx, maxSessionCountError())
+		default:
+			return err
+		}
+	}
+	sb := stravaganza.NewBuilder("success").
+		WithAttribute(stravaganza.Namespace, sasl2Namespace)
+	if len(additionalData) > 0 {
+		sb.WithChild(
+			stravaganza.NewBuilder("additional-data").
+				WithText(additionalData).
+				Build(),
+		)
+	}
+	sb.WithChild(
+		stravaganza.NewBuilder("authorization-identifier").
+			WithText(s.JID().String()).
+			Build(),
+	)
+	sb.WithChildren(inlineElems...)
+	if tokenElem != nil {
+		sb.WithChild(tokenElem)
+	}
+	if boundElem != nil {
+		sb.WithChild(boundElem)
+	}
+	return s.sendElement(ctx, sb.Build())
+}
+
+func (s *inC2S) bind2(ctx context.Context, bindReq stravaganza.Element) (stravaganza.Element, error) {
+	// resource is always server generated, prefixed by client tag if present (XEP-0386, section 4)
+	res := uuid.New().String()
+	if tag := bindReq.Child("tag"); tag != nil && len(tag.Text()) > 0 {
+		if _, err := jid.New(s.Username(), s.Domain(), tag.Text()+"."+res, false); err == nil {
+			res = tag.Text() + "." + res
+		}
+	}
+	if err := s.bind(ctx, res); err != nil {
+		return nil, err
+	}
+	// enable inline features
+	bb := stravaganza.NewBuilder("bound").
+		WithAttribute(stravaganza.Namespace, bind2Namespace)
+
+	for _, child := range bindReq.AllChildren() {
+		ns := child.Attribute(stravaganza.Namespace)
+		if len(ns) == 0 || ns == bind2Namespace {
+			continue // tag element
+		}
+		inlineElem, err := s.mods.ProcessBindInline(ctx, child, s)
+		if err != nil {
+			return nil, err
+		}
+		if inlineElem != nil {
+			bb.WithChild(inlineElem)
+		}
+	}
+	return bb.Build(), nil
+}
+
+func (s *inC2S) fastFeature() stravaganza.Element {
+	if s.fastTk == nil {
+		return nil
+	}
+	fb := stravaganza.NewBuilder("fast").
+		WithAttribute(stravaganza.Namespace, fastNamespace)
+
+	supportsCb := s.tr.SupportsChannelBinding()
+	for _, authenticator := range s.authSt.fastAuthrs {
+		if authenticator.UsesChannelBinding() && !supportsCb {
+			continue // transport doesn't support channel binding
+		}
+		fb.WithChild(
+			stravaganza.NewBuilder("mechanism").
+				WithText(authenticator.Mechanism()).
+				Build(),
+		)
+	}
+	return fb.Build()
+}
+
+func (s *inC2S) processFastInline(ctx context.Context, req stravaganza.Element) stravaganza.Element {
+	if s.fastTk == nil {
+		return nil
+	}
+	var tk *fastmodel.Token
+	var err error
+
+	// TLS 0-RTT is not supported, hence there's no need to keep track of fast counter value (XEP-0484, section 3.3)
+	fastElem := req.ChildNamespace("fast", fastNamespace)
+	reqTkElem := req.ChildNamespace("request-token", fastNamespace)
+
+	switch {
+	case fastElem != nil && fastElem.Attribute("invalidate") == "true":
+		err = s.fastTk.Invalidate(ctx, s.authSt.active)
+
+	case reqTkElem != nil:
+		mechanism := reqTkElem.Attribute("mechanism")
+		if !s.isFastMechanism(mechanism) {
+			break
+		}
+		var clientID string
+		if uaElem := req.Child("user-agent"); uaElem != nil {
+			clientID = uaElem.Attribute("id")
+		}
+		if len(clientID) == 0 {
+			break // tokens are bound to a user-agent identifier
+		}
+		tk, err = s.fastTk.Issue(ctx, s.authSt.active.Username(), clientID, mechanism)
+
+	default:
+		tk, err = s.fastTk.Rotate(ctx, s.authSt.active)
+	}
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to process FAST request", "err", err)
+		return nil
+	}
+	if tk == nil {
+		return nil
+	}
+	return stravaganza.NewBuilder("token").
+		WithAttribute(stravaganza.Namespace, fastNamespace).
+		WithAttribute("expiry", tk.ExpiresAt.AsTime().UTC().Format(time.RFC3339)).
+		WithAttribute("token", tk.Token).
+		Build()
+}
+
+func (s *inC2S) isFastMechanism(mechanism string) bool {
+	for _, authenticator := range s.authSt.fastAuthrs {
+		if authenticator.Mechanism() != mechanism {
+			continue
+		}
+		return !authenticator.UsesChannelBinding() || s.tr.SupportsChannelBinding()
+	}
+	return false
+}
+
+func sasl2FailureElement(condition stravaganza.Element) stravaganza.Element {
+	return stravaganza.NewBuilder("failure").
+		WithAttribute(stravaganza.Namespace, sasl2Namespace).
+		WithChild(condition).
+		Build()
+}
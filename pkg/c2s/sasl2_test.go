@@ -0,0 +1,366 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/runqueue/v2"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/auth"
+	"github.com/ortuman/jackal/pkg/hook"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/transport"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestInC2S_SASL2AuthenticateAndBind(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.Socket }
+	trMock.SetReadRateLimiterFunc = func(_ *rate.Limiter) error { return nil }
+
+	c2sRouterMock := &c2sRouterMock{}
+	c2sRouterMock.BindFunc = func(_ stream.C2SID) error { return nil }
+
+	routerMock := &routerMock{}
+	routerMock.C2SFunc = func() router.C2SRouter { return c2sRouterMock }
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourcesFunc = func(_ context.Context, _ string) ([]c2smodel.ResourceDesc, error) {
+		return nil, nil
+	}
+	resMngMock.PutResourceFunc = func(_ context.Context, _ c2smodel.ResourceDesc) error { return nil }
+
+	var bindInlineNamespaces []string
+	modsMock := &modulesMock{}
+	modsMock.ProcessSASL2InlineFunc = func(_ context.Context, _ stravaganza.Element, _ stream.C2S) (stravaganza.Element, error) {
+		return nil, nil
+	}
+	modsMock.ProcessBindInlineFunc = func(_ context.Context, elem stravaganza.Element, _ stream.C2S) (stravaganza.Element, error) {
+		ns := elem.Attribute(stravaganza.Namespace)
+		bindInlineNamespaces = append(bindInlineNamespaces, ns)
+		if ns != "urn:xmpp:sm:3" {
+			return nil, nil
+		}
+		return stravaganza.NewBuilder("enabled").
+			WithAttribute(stravaganza.Namespace, ns).
+			WithAttribute("id", "sm-1").
+			Build(), nil
+	}
+
+	authMock := &authenticatorMock{}
+	authMock.MechanismFunc = func() string { return "PLAIN" }
+	authMock.AuthenticatedFunc = func() bool { return true }
+	authMock.ResetFunc = func() {}
+	authMock.UsernameFunc = func() string { return "ortuman" }
+	authMock.ProcessElementFunc = func(_ context.Context, elem stravaganza.Element) (stravaganza.Element, *auth.SASLError) {
+		return stravaganza.NewBuilder("success").
+			WithAttribute(stravaganza.Namespace, saslNamespace).
+			WithText("dj1zZXJ2ZXJzaWc=").
+			Build(), nil
+	}
+
+	outBuf := bytes.NewBuffer(nil)
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, element stravaganza.Element) error {
+		return element.ToXML(outBuf, true)
+	}
+	ssMock.SetFromJIDFunc = func(_ *jid.JID) {}
+
+	domainJID, _ := jid.NewWithString("localhost", true)
+	stm := &inC2S{
+		cfg: inCfg{
+			reqTimeout: time.Minute,
+			sasl2:      true,
+		},
+		state:   inConnected,
+		flags:   flags{flg: fSecured},
+		rq:      runqueue.New("in_c2s:test"),
+		doneCh:  make(chan struct{}),
+		jd:      domainJID,
+		tr:      trMock,
+		inf:     c2smodel.NewInfoMap(),
+		hosts:   &hostsMock{IsAnonymousHostFunc: func(_ string) bool { return false }},
+		router:  routerMock,
+		mods:    modsMock,
+		authSt:  authState{authenticators: []auth.Authenticator{authMock}},
+		session: ssMock,
+		resMng:  resMngMock,
+		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
+	}
+
+	// when
+	stm.handleSessionResult(
+		stravaganza.NewBuilder("authenticate").
+			WithAttribute(stravaganza.Namespace, sasl2Namespace).
+			WithAttribute("mechanism", "PLAIN").
+			WithChild(
+				stravaganza.NewBuilder("initial-response").
+					WithText("AG9ydHVtYW4AY29uMmNvam9uZXM=").
+					Build(),
+			).
+			WithChild(
+				stravaganza.NewBuilder("user-agent").
+					WithAttribute("id", "d4565fa7-4d72-4749-b3d3-740edbf87770").
+					Build(),
+			).
+			WithChild(
+				stravaganza.NewBuilder("bind").
+					WithAttribute(stravaganza.Namespace, bind2Namespace).
+					WithChild(stravaganza.NewBuilder("tag").WithText("Conversations").Build()).
+					WithChild(stravaganza.NewBuilder("enable").WithAttribute(stravaganza.Namespace, "urn:xmpp:carbons:2").Build()).
+					WithChild(stravaganza.NewBuilder("enable").WithAttribute(stravaganza.Namespace, "urn:xmpp:sm:3").Build()).
+					Build(),
+			).
+			Build(), nil,
+	)
+
+	// then
+	require.Equal(t, inBinded, stm.getState())
+	require.True(t, stm.flags.isAuthenticated())
+	require.True(t, stm.flags.isBinded())
+	require.Nil(t, stm.authSt.active)
+
+	require.Equal(t, "ortuman", stm.Username())
+	require.Regexp(t, regexp.MustCompile(`^Conversations\..+`), stm.Resource())
+
+	require.Equal(t, []string{"urn:xmpp:carbons:2", "urn:xmpp:sm:3"}, bindInlineNamespaces)
+
+	expectedOutput := `<success xmlns='urn:xmpp:sasl:2'><additional-data>dj1zZXJ2ZXJzaWc=</additional-data><authorization-identifier>` +
+		stm.JID().String() +
+		`</authorization-identifier><bound xmlns='urn:xmpp:bind:0'><enabled xmlns='urn:xmpp:sm:3' id='sm-1'/></bound></success>`
+	require.Equal(t, expectedOutput, outBuf.String())
+}
+
+func TestInC2S_SASL2FastFeature(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.SupportsChannelBindingFunc = func() bool { return false }
+
+	modsMock := &modulesMock{}
+	modsMock.SASL2InlineFeaturesFunc = func() []stravaganza.Element { return nil }
+	modsMock.BindInlineFeaturesFunc = func() []string { return nil }
+
+	noneMock := &authenticatorMock{}
+	noneMock.MechanismFunc = func() string { return "HT-SHA-256-NONE" }
+	noneMock.UsesChannelBindingFunc = func() bool { return false }
+
+	exprMock := &authenticatorMock{}
+	exprMock.MechanismFunc = func() string { return "HT-SHA-256-EXPR" }
+	exprMock.UsesChannelBindingFunc = func() bool { return true }
+
+	stm := &inC2S{
+		tr:     trMock,
+		mods:   modsMock,
+		fastTk: &fastTokensMock{},
+		authSt: authState{fastAuthrs: []auth.Authenticator{noneMock, exprMock}},
+	}
+
+	// when
+	elem := stm.sasl2Feature(nil)
+
+	// then
+	require.Equal(t, `<authentication xmlns='urn:xmpp:sasl:2'><inline><fast xmlns='urn:xmpp:fast:0'><mechanism>HT-SHA-256-NONE</mechanism></fast><bind xmlns='urn:xmpp:bind:0'><inline/></bind></inline></authentication>`, elem.String())
+}
+
+func TestInC2S_SASL2FastRequestToken(t *testing.T) {
+	// given
+	expiresAt := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	trMock := &transportMock{}
+	trMock.SupportsChannelBindingFunc = func() bool { return false }
+	trMock.SetReadRateLimiterFunc = func(_ *rate.Limiter) error { return nil }
+
+	fastMock := &fastTokensMock{}
+	fastMock.IssueFunc = func(_ context.Context, username, clientID, mechanism string) (*fastmodel.Token, error) {
+		return &fastmodel.Token{
+			Username:  username,
+			ClientId:  clientID,
+			Mechanism: mechanism,
+			Token:     "WU2a",
+			ExpiresAt: timestamppb.New(expiresAt),
+		}, nil
+	}
+	noneMock := &authenticatorMock{}
+	noneMock.MechanismFunc = func() string { return "HT-SHA-256-NONE" }
+	noneMock.UsesChannelBindingFunc = func() bool { return false }
+
+	authMock := &authenticatorMock{}
+	authMock.MechanismFunc = func() string { return "PLAIN" }
+	authMock.AuthenticatedFunc = func() bool { return true }
+	authMock.ResetFunc = func() {}
+	authMock.UsernameFunc = func() string { return "ortuman" }
+	authMock.ProcessElementFunc = func(_ context.Context, elem stravaganza.Element) (stravaganza.Element, *auth.SASLError) {
+		return stravaganza.NewBuilder("success").
+			WithAttribute(stravaganza.Namespace, saslNamespace).
+			Build(), nil
+	}
+
+	outBuf := bytes.NewBuffer(nil)
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, element stravaganza.Element) error {
+		return element.ToXML(outBuf, true)
+	}
+	ssMock.SetFromJIDFunc = func(_ *jid.JID) {}
+
+	domainJID, _ := jid.NewWithString("localhost", true)
+	stm := &inC2S{
+		cfg: inCfg{
+			reqTimeout: time.Minute,
+			sasl2:      true,
+		},
+		state:   inConnected,
+		flags:   flags{flg: fSecured},
+		rq:      runqueue.New("in_c2s:test"),
+		doneCh:  make(chan struct{}),
+		jd:      domainJID,
+		tr:      trMock,
+		inf:     c2smodel.NewInfoMap(),
+		hosts:   &hostsMock{IsAnonymousHostFunc: func(_ string) bool { return false }},
+		mods:    &modulesMock{},
+		fastTk:  fastMock,
+		authSt:  authState{authenticators: []auth.Authenticator{authMock}, fastAuthrs: []auth.Authenticator{noneMock}},
+		session: ssMock,
+		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
+	}
+
+	// when
+	stm.handleSessionResult(
+		stravaganza.NewBuilder("authenticate").
+			WithAttribute(stravaganza.Namespace, sasl2Namespace).
+			WithAttribute("mechanism", "PLAIN").
+			WithChild(
+				stravaganza.NewBuilder("initial-response").
+					WithText("AG9ydHVtYW4AY29uMmNvam9uZXM=").
+					Build(),
+			).
+			WithChild(
+				stravaganza.NewBuilder("user-agent").
+					WithAttribute("id", "d4565fa7-4d72-4749-b3d3-740edbf87770").
+					Build(),
+			).
+			WithChild(
+				stravaganza.NewBuilder("request-token").
+					WithAttribute(stravaganza.Namespace, fastNamespace).
+					WithAttribute("mechanism", "HT-SHA-256-NONE").
+					Build(),
+			).
+			Build(), nil,
+	)
+
+	// then
+	require.Equal(t, inAuthenticated, stm.getState())
+
+	require.Len(t, fastMock.IssueCalls(), 1)
+	require.Equal(t, "ortuman", fastMock.IssueCalls()[0].Username)
+	require.Equal(t, "d4565fa7-4d72-4749-b3d3-740edbf87770", fastMock.IssueCalls()[0].ClientID)
+
+	expectedOutput := `<success xmlns='urn:xmpp:sasl:2'><authorization-identifier>ortuman@localhost</authorization-identifier>` +
+		`<token xmlns='urn:xmpp:fast:0' expiry='2022-10-01T12:00:00Z' token='WU2a'/></success>`
+	require.Equal(t, expectedOutput, outBuf.String())
+}
+
+func TestInC2S_SASL2FastInvalidate(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.SetReadRateLimiterFunc = func(_ *rate.Limiter) error { return nil }
+
+	fastMock := &fastTokensMock{}
+	fastMock.InvalidateFunc = func(_ context.Context, _ auth.Authenticator) error { return nil }
+
+	authMock := &authenticatorMock{}
+	authMock.MechanismFunc = func() string { return "HT-SHA-256-NONE" }
+	authMock.UsesChannelBindingFunc = func() bool { return false }
+	authMock.AuthenticatedFunc = func() bool { return true }
+	authMock.ResetFunc = func() {}
+	authMock.UsernameFunc = func() string { return "ortuman" }
+	authMock.ProcessElementFunc = func(_ context.Context, elem stravaganza.Element) (stravaganza.Element, *auth.SASLError) {
+		return stravaganza.NewBuilder("success").
+			WithAttribute(stravaganza.Namespace, saslNamespace).
+			WithText("UmVzcG9uZGVy").
+			Build(), nil
+	}
+
+	outBuf := bytes.NewBuffer(nil)
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, element stravaganza.Element) error {
+		return element.ToXML(outBuf, true)
+	}
+	ssMock.SetFromJIDFunc = func(_ *jid.JID) {}
+
+	domainJID, _ := jid.NewWithString("localhost", true)
+	stm := &inC2S{
+		cfg: inCfg{
+			reqTimeout: time.Minute,
+			sasl2:      true,
+		},
+		state:   inConnected,
+		flags:   flags{flg: fSecured},
+		rq:      runqueue.New("in_c2s:test"),
+		doneCh:  make(chan struct{}),
+		jd:      domainJID,
+		tr:      trMock,
+		inf:     c2smodel.NewInfoMap(),
+		hosts:   &hostsMock{IsAnonymousHostFunc: func(_ string) bool { return false }},
+		mods:    &modulesMock{},
+		fastTk:  fastMock,
+		authSt:  authState{fastAuthrs: []auth.Authenticator{authMock}},
+		session: ssMock,
+		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
+	}
+
+	// when
+	stm.handleSessionResult(
+		stravaganza.NewBuilder("authenticate").
+			WithAttribute(stravaganza.Namespace, sasl2Namespace).
+			WithAttribute("mechanism", "HT-SHA-256-NONE").
+			WithChild(
+				stravaganza.NewBuilder("initial-response").
+					WithText("b3J0dW1hbgA=").
+					Build(),
+			).
+			WithChild(
+				stravaganza.NewBuilder("fast").
+					WithAttribute(stravaganza.Namespace, fastNamespace).
+					WithAttribute("invalidate", "true").
+					Build(),
+			).
+			Build(), nil,
+	)
+
+	// then
+	require.Equal(t, inAuthenticated, stm.getState())
+	require.Len(t, fastMock.InvalidateCalls(), 1)
+	require.Len(t, fastMock.RotateCalls(), 0)
+
+	expectedOutput := `<success xmlns='urn:xmpp:sasl:2'><additional-data>UmVzcG9uZGVy</additional-data>` +
+		`<authorization-identifier>ortuman@localhost</authorization-identifier></success>`
+	require.Equal(t, expectedOutput, outBuf.String())
+}
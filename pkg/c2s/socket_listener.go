@@ -17,14 +17,20 @@ package c2s
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net"
+	"net/http"
+	"os"
 	"strconv"
 	"sync/atomic"
 	"time"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/gorilla/websocket"
 	"github.com/ortuman/jackal/pkg/auth"
+	"github.com/ortuman/jackal/pkg/auth/jwt"
 	"github.com/ortuman/jackal/pkg/auth/pepper"
 	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	"github.com/ortuman/jackal/pkg/component"
@@ -36,15 +42,25 @@ import (
 	"github.com/ortuman/jackal/pkg/storage/repository"
 	"github.com/ortuman/jackal/pkg/transport"
 	"github.com/ortuman/jackal/pkg/transport/compress"
+	"github.com/samber/lo"
 )
 
 const (
 	listenKeepAlive = time.Second * 15
 
+	socketTransport    = "socket"
+	webSocketTransport = "websocket"
+	boshTransport      = "bosh"
+
+	webSocketSubprotocol = "xmpp"
+
 	scramSHA1Mechanism    = "scram_sha_1"
 	scramSHA256Mechanism  = "scram_sha_256"
 	scramSHA512Mechanism  = "scram_sha_512"
 	scramSHA3512Mechanism = "scram_sha3_512"
+	oauthBearerMechanism  = "oauthbearer"
+	externalMechanism     = "external"
+	anonymousMechanism    = "anonymous"
 )
 
 var cmpLevelMap = map[string]compress.Level{
@@ -63,6 +79,8 @@ var resConflictMap = map[string]resourceConflict{
 type SocketListener struct {
 	cfg     ListenerConfig
 	extAuth *auth.External
+	jwtVrf  *jwt.Verifier
+	certCAs *x509.CertPool
 	hosts   *host.Hosts
 	router  router.Router
 	comps   *component.Components
@@ -70,6 +88,7 @@ type SocketListener struct {
 	resMng  resourcemanager.Manager
 	rep     repository.Repository
 	peppers *pepper.Keys
+	fastTk  fastTokens
 	shapers shaper.Shapers
 	hk      *hook.Hooks
 	logger  kitlog.Logger
@@ -77,8 +96,9 @@ type SocketListener struct {
 	tlsCfg        *tls.Config
 	connHandlerFn func(conn net.Conn)
 
-	ln     net.Listener
-	active uint32
+	ln      net.Listener
+	httpSrv *http.Server
+	active  uint32
 }
 
 // NewListeners creates and initializes a set of C2S listeners based of cfg configuration.
@@ -149,18 +169,41 @@ func newSocketListener(
 		hk:      hk,
 		logger:  logger,
 	}
+	if cfg.SASL.SASL2 && cfg.SASL.FAST.Enabled {
+		ln.fastTk = auth.NewFastTokens(rep, cfg.SASL.FAST.TokenExpiry)
+	}
 	ln.connHandlerFn = ln.handleConn
 	return ln
 }
 
 // Start starts listening on a TCP network address to handle incoming C2S connections.
 func (l *SocketListener) Start(ctx context.Context) error {
+	switch l.cfg.Transport {
+	case "", socketTransport, webSocketTransport, boshTransport:
+		break
+	default:
+		return fmt.Errorf("c2s: unrecognized transport type: %s", l.cfg.Transport)
+	}
 	if l.extAuth != nil {
 		// dial external authenticator
 		if err := l.extAuth.Start(ctx); err != nil {
 			return err
 		}
 	}
+	if lo.Contains(l.cfg.SASL.Mechanisms, oauthBearerMechanism) {
+		jwtVrf, err := jwt.NewVerifier(l.cfg.SASL.OAuthBearer.JWT)
+		if err != nil {
+			return err
+		}
+		l.jwtVrf = jwtVrf
+	}
+	if lo.Contains(l.cfg.SASL.Mechanisms, externalMechanism) {
+		certCAs, err := loadCertPool(l.cfg.SASL.ClientCertificates.CAFile)
+		if err != nil {
+			return err
+		}
+		l.certCAs = certCAs
+	}
 	var err error
 	var ln net.Listener
 
@@ -176,11 +219,24 @@ func (l *SocketListener) Start(ctx context.Context) error {
 			Certificates: l.hosts.Certificates(),
 			MinVersion:   tls.VersionTLS12,
 		}
+		if l.certCAs != nil {
+			l.tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+			l.tlsCfg.ClientCAs = l.certCAs
+		}
 		ln = tls.NewListener(ln, l.tlsCfg)
 	}
 	l.ln = ln
 	l.active = 1
 
+	switch l.cfg.Transport {
+	case webSocketTransport:
+		l.serveHTTP(l.cfg.WebSocket.Path, l.webSocketHandler())
+		return nil
+
+	case boshTransport:
+		l.serveHTTP(l.cfg.BOSH.Path, l.boshHandler())
+		return nil
+	}
 	go func() {
 		for atomic.LoadUint32(&l.active) == 1 {
 			conn, err := l.ln.Accept()
@@ -201,7 +257,11 @@ func (l *SocketListener) Start(ctx context.Context) error {
 // Stop stops handling incoming C2S connections and closes underlying TCP listener.
 func (l *SocketListener) Stop(ctx context.Context) error {
 	atomic.StoreUint32(&l.active, 0)
-	if err := l.ln.Close(); err != nil {
+	if l.httpSrv != nil {
+		if err := l.httpSrv.Shutdown(ctx); err != nil {
+			return err
+		}
+	} else if err := l.ln.Close(); err != nil {
 		return err
 	}
 	if l.extAuth != nil {
@@ -214,12 +274,90 @@ func (l *SocketListener) Stop(ctx context.Context) error {
 	return nil
 }
 
+func (l *SocketListener) serveHTTP(path string, handler http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	l.httpSrv = &http.Server{Handler: mux}
+
+	go func() {
+		if err := l.httpSrv.Serve(l.ln); err != nil && err != http.ErrServerClosed {
+			level.Error(l.logger).Log("msg", "failed to serve C2S HTTP connections", "err", err)
+		}
+	}()
+	level.Info(l.logger).Log("msg", fmt.Sprintf("accepting C2S %s connections", l.cfg.Transport),
+		"bind_addr", l.getAddress(),
+		"path", path,
+		"direct_tls", l.cfg.DirectTLS,
+	)
+}
+
+func (l *SocketListener) webSocketHandler() http.Handler {
+	upgrader := &websocket.Upgrader{
+		Subprotocols: []string{webSocketSubprotocol},
+	}
+	if len(l.cfg.WebSocket.AllowedOrigins) > 0 {
+		upgrader.CheckOrigin = l.isAllowedOrigin
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.handleWebSocket(upgrader, w, r)
+	})
+}
+
+func (l *SocketListener) boshHandler() http.Handler {
+	return transport.NewBOSHHandler(transport.BOSHConfig{
+		MaxWait:        l.cfg.BOSH.MaxWait,
+		MaxHold:        l.cfg.BOSH.MaxHold,
+		Inactivity:     l.cfg.BOSH.Inactivity,
+		Polling:        l.cfg.BOSH.Polling,
+		MaxRequestSize: l.cfg.MaxStanzaSize,
+		MaxSessions:    l.cfg.BOSH.MaxSessions,
+		AllowedOrigins: l.cfg.BOSH.AllowedOrigins,
+	}, l.hosts, l.handleTransport)
+}
+
+func (l *SocketListener) handleWebSocket(upgrader *websocket.Upgrader, w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		level.Warn(l.logger).Log("msg", "failed to upgrade websocket connection", "err", err)
+		return
+	}
+	// client must negotiate 'xmpp' subprotocol (RFC 7395, section 3.1)
+	if conn.Subprotocol() != webSocketSubprotocol {
+		_ = conn.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseProtocolError, "xmpp subprotocol required"),
+			time.Now().Add(time.Second),
+		)
+		_ = conn.Close()
+		return
+	}
+	l.handleTransport(
+		transport.NewWebSocketTransport(conn, l.cfg.ConnectTimeout, l.cfg.KeepAliveTimeout),
+	)
+}
+
+func (l *SocketListener) isAllowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	for _, allowedOrigin := range l.cfg.WebSocket.AllowedOrigins {
+		if origin == allowedOrigin {
+			return true
+		}
+	}
+	return false
+}
+
 func (l *SocketListener) handleConn(conn net.Conn) {
-	tr := transport.NewSocketTransport(conn, l.cfg.ConnectTimeout, l.cfg.KeepAliveTimeout)
+	l.handleTransport(
+		transport.NewSocketTransport(conn, l.cfg.ConnectTimeout, l.cfg.KeepAliveTimeout),
+	)
+}
+
+func (l *SocketListener) handleTransport(tr transport.Transport) {
 	stm, err := newInC2S(
 		l.getInConfig(),
 		tr,
 		l.getAuthenticators(tr),
+		l.fastTk,
 		l.hosts,
 		l.router,
 		l.comps,
@@ -262,6 +400,22 @@ func (l *SocketListener) getAuthenticators(tr transport.Transport) []auth.Authen
 		case scramSHA3512Mechanism:
 			res = append(res, auth.NewScram(tr, auth.ScramSHA3512, false, l.rep, l.peppers))
 			res = append(res, auth.NewScram(tr, auth.ScramSHA3512, true, l.rep, l.peppers))
+
+		case oauthBearerMechanism:
+			res = append(res, auth.NewOAuthBearer(
+				l.jwtVrf,
+				l.cfg.SASL.OAuthBearer.UsernameClaim,
+				l.cfg.SASL.OAuthBearer.AutoProvision,
+				l.rep,
+				l.hk,
+			))
+
+		case externalMechanism:
+			res = append(res, auth.NewClientCertificate(tr, l.certCAs, l.hosts, l.rep))
+
+		case anonymousMechanism:
+			res = append(res, auth.NewAnonymous(l.rep, l.hk))
+
 		default:
 			level.Warn(l.logger).Log("msg", "unsupported authentication mechanism", "mechanism", mechanism)
 		}
@@ -278,9 +432,29 @@ func (l *SocketListener) getInConfig() inCfg {
 		resConflict:         resConflictMap[l.cfg.ResourceConflict],
 		useTLS:              l.cfg.DirectTLS,
 		tlsConfig:           l.tlsCfg,
+		clientCAs:           l.certCAs,
+		sasl2:               l.cfg.SASL.SASL2,
+		allowRegistration:   l.cfg.AllowRegistration,
+		csi:                 l.cfg.CSI.Enabled,
+		csiMaxBufferSize:    l.cfg.CSI.MaxBufferSize,
 	}
 }
 
 func (l *SocketListener) getAddress() string {
 	return l.cfg.BindAddr + ":" + strconv.Itoa(l.cfg.Port)
 }
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	if len(caFile) == 0 {
+		return nil, fmt.Errorf("c2s: %s mechanism requires a client certificates CA file", externalMechanism)
+	}
+	b, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("c2s: no valid certificates found in %s", caFile)
+	}
+	return pool, nil
+}
@@ -16,12 +16,16 @@ package c2s
 
 import (
 	"context"
+	"io"
 	"net"
+	"net/http"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	kitlog "github.com/go-kit/log"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,3 +58,84 @@ func TestSocketListener_Listen(t *testing.T) {
 
 	require.Equal(t, uint32(0), atomic.LoadUint32(&s.active))
 }
+
+func TestSocketListener_ListenWebSocket(t *testing.T) {
+	// given
+	s := &SocketListener{
+		cfg: ListenerConfig{
+			BindAddr:  "",
+			Port:      51125,
+			Transport: webSocketTransport,
+		},
+		logger: kitlog.NewNopLogger(),
+	}
+	s.cfg.WebSocket.Path = "/xmpp-websocket"
+
+	// when
+	err := s.Start(context.Background())
+	require.Nil(t, err)
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws://localhost:51125/xmpp-websocket", nil)
+	require.Nil(t, err)
+
+	_, _, readErr := conn.ReadMessage()
+	_ = s.Stop(context.Background())
+
+	// then
+	require.Equal(t, "", resp.Header.Get("Sec-Websocket-Protocol"))
+
+	closeErr, ok := readErr.(*websocket.CloseError)
+	require.True(t, ok)
+	require.Equal(t, websocket.CloseProtocolError, closeErr.Code)
+
+	require.Equal(t, uint32(0), atomic.LoadUint32(&s.active))
+}
+
+func TestSocketListener_ListenBOSH(t *testing.T) {
+	// given
+	s := &SocketListener{
+		cfg: ListenerConfig{
+			BindAddr:  "",
+			Port:      51127,
+			Transport: boshTransport,
+		},
+		logger: kitlog.NewNopLogger(),
+	}
+	s.cfg.BOSH.Path = "/http-bind"
+	s.cfg.BOSH.MaxWait = time.Second
+	s.cfg.BOSH.MaxHold = 1
+
+	// when
+	err := s.Start(context.Background())
+	require.Nil(t, err)
+
+	resp, err := http.Post(
+		"http://localhost:51127/http-bind",
+		"text/xml; charset=utf-8",
+		strings.NewReader(`<body rid='1' sid='foo' xmlns='http://jabber.org/protocol/httpbind'/>`),
+	)
+	require.Nil(t, err)
+
+	b, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	_ = s.Stop(context.Background())
+
+	// then
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `<body xmlns='http://jabber.org/protocol/httpbind' type='terminate' condition='item-not-found'/>`, string(b))
+}
+
+func TestSocketListener_UnrecognizedTransport(t *testing.T) {
+	// given
+	s := &SocketListener{
+		cfg:    ListenerConfig{Port: 51126, Transport: "carrier_pigeon"},
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	err := s.Start(context.Background())
+
+	// then
+	require.NotNil(t, err)
+}
@@ -0,0 +1,262 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0045
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+type roleChange struct {
+	target *occupant
+	role   role
+	reason string
+}
+
+type affiliationChange struct {
+	jid         *jid.JID
+	affiliation affiliation
+	reason      string
+}
+
+func (m *Muc) processAdminIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	r, err := m.lockRoom(ctx, iq.ToJID(), false)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	if r == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
+		return nil
+	}
+	defer m.unlockRoom(r)
+
+	q := iq.ChildNamespace("query", mucAdminNamespace)
+	items := q.Children("item")
+	if len(items) == 0 {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	if iq.IsGet() {
+		m.sendAdminList(ctx, r, iq, items[0])
+		return nil
+	}
+	// validate all requested changes before applying any of them
+	var roleChanges []roleChange
+	var affChanges []affiliationChange
+
+	for _, item := range items {
+		var reason string
+		if rElem := item.Child("reason"); rElem != nil {
+			reason = rElem.Text()
+		}
+		switch {
+		case len(item.Attribute("role")) > 0:
+			rc, errReason, ok := r.validateRoleChange(iq.FromJID(), item, reason)
+			if !ok {
+				_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, errReason))
+				return nil
+			}
+			roleChanges = append(roleChanges, rc)
+
+		case len(item.Attribute("affiliation")) > 0:
+			ac, errReason, ok := r.validateAffiliationChange(iq.FromJID(), item, reason)
+			if !ok {
+				_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, errReason))
+				return nil
+			}
+			affChanges = append(affChanges, ac)
+
+		default:
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+			return nil
+		}
+	}
+	if !r.keepsOwner(affChanges) {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Conflict))
+		return nil
+	}
+	for _, rc := range roleChanges {
+		m.applyRoleChange(ctx, r, rc)
+	}
+	for _, ac := range affChanges {
+		if err := m.applyAffiliationChange(ctx, r, ac); err != nil {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+			return err
+		}
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+	return nil
+}
+
+func (m *Muc) sendAdminList(ctx context.Context, r *room, iq *stravaganza.IQ, item stravaganza.Element) {
+	actorAff := r.affiliation(iq.FromJID())
+	actorOcc := r.occupantByJID(iq.FromJID())
+
+	qb := stravaganza.NewBuilder("query").
+		WithAttribute(stravaganza.Namespace, mucAdminNamespace)
+
+	if affStr := item.Attribute("affiliation"); len(affStr) > 0 {
+		aff, ok := parseAffiliation(affStr)
+		if !ok || aff == noneAffiliation {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+			return
+		}
+		if actorAff < adminAffiliation {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+			return
+		}
+		var jids []string
+		for j, a := range r.affiliations {
+			if a.Affiliation == aff.String() {
+				jids = append(jids, j)
+			}
+		}
+		sort.Strings(jids)
+
+		for _, j := range jids {
+			itemB := stravaganza.NewBuilder("item").
+				WithAttribute("affiliation", aff.String()).
+				WithAttribute("jid", j)
+			if reason := r.affiliations[j].Reason; len(reason) > 0 {
+				itemB.WithChild(stravaganza.NewBuilder("reason").WithText(reason).Build())
+			}
+			qb.WithChild(itemB.Build())
+		}
+		_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, qb.Build()))
+		return
+	}
+	rl, ok := parseRole(item.Attribute("role"))
+	if !ok || rl == noneRole {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return
+	}
+	if actorOcc == nil || actorOcc.role != moderatorRole {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return
+	}
+	for _, occ := range r.occupants {
+		if occ.role != rl {
+			continue
+		}
+		qb.WithChild(
+			stravaganza.NewBuilder("item").
+				WithAttribute("affiliation", r.affiliation(occ.jid).String()).
+				WithAttribute("role", occ.role.String()).
+				WithAttribute("nick", occ.nick).
+				WithAttribute("jid", occ.jid.String()).
+				Build(),
+		)
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, qb.Build()))
+}
+
+func (r *room) validateRoleChange(actorJID *jid.JID, item stravaganza.Element, reason string) (roleChange, stanzaerror.Reason, bool) {
+	newRole, ok := parseRole(item.Attribute("role"))
+	if !ok {
+		return roleChange{}, stanzaerror.BadRequest, false
+	}
+	actorOcc := r.occupantByJID(actorJID)
+	if actorOcc == nil || actorOcc.role != moderatorRole {
+		return roleChange{}, stanzaerror.Forbidden, false
+	}
+	target := r.occupantByNick(item.Attribute("nick"))
+	if target == nil {
+		return roleChange{}, stanzaerror.ItemNotFound, false
+	}
+	actorAff := r.affiliation(actorJID)
+	targetAff := r.affiliation(target.jid)
+
+	switch {
+	case (newRole == moderatorRole || target.role == moderatorRole) && actorAff < adminAffiliation:
+		return roleChange{}, stanzaerror.NotAllowed, false
+	case newRole < target.role && targetAff >= adminAffiliation:
+		return roleChange{}, stanzaerror.NotAllowed, false
+	}
+	return roleChange{target: target, role: newRole, reason: reason}, 0, true
+}
+
+func (r *room) validateAffiliationChange(actorJID *jid.JID, item stravaganza.Element, reason string) (affiliationChange, stanzaerror.Reason, bool) {
+	newAff, ok := parseAffiliation(item.Attribute("affiliation"))
+	if !ok {
+		return affiliationChange{}, stanzaerror.BadRequest, false
+	}
+	targetJID, err := jid.NewWithString(item.Attribute("jid"), false)
+	if err != nil {
+		return affiliationChange{}, stanzaerror.JIDMalformed, false
+	}
+	actorAff := r.affiliation(actorJID)
+	targetAff := r.affiliation(targetJID)
+
+	switch {
+	case actorAff < adminAffiliation:
+		return affiliationChange{}, stanzaerror.Forbidden, false
+	case actorAff == adminAffiliation && (newAff >= adminAffiliation || targetAff >= adminAffiliation):
+		return affiliationChange{}, stanzaerror.NotAllowed, false
+	}
+	return affiliationChange{jid: targetJID.ToBareJID(), affiliation: newAff, reason: reason}, 0, true
+}
+
+// keepsOwner tells whether the room will still have at least one owner after applying changes.
+func (r *room) keepsOwner(changes []affiliationChange) bool {
+	owners := make(map[string]bool)
+	for j, aff := range r.affiliations {
+		if aff.Affiliation == ownerAffiliation.String() {
+			owners[j] = true
+		}
+	}
+	for _, c := range changes {
+		owners[c.jid.String()] = c.affiliation == ownerAffiliation
+	}
+	for _, isOwner := range owners {
+		if isOwner {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Muc) applyRoleChange(ctx context.Context, r *room, rc roleChange) {
+	if rc.role == noneRole {
+		m.kickOccupant(ctx, r, rc.target, statusKicked, rc.reason)
+		return
+	}
+	rc.target.role = rc.role
+	m.broadcastPresence(ctx, r, rc.target, nil)
+}
+
+func (m *Muc) applyAffiliationChange(ctx context.Context, r *room, ac affiliationChange) error {
+	aff := r.setAffiliation(ac.jid, ac.affiliation, ac.reason)
+	if err := m.storeAffiliation(ctx, r, aff); err != nil {
+		return err
+	}
+	for _, occ := range r.occupantsByBareJID(ac.jid) {
+		switch {
+		case ac.affiliation == outcastAffiliation:
+			m.kickOccupant(ctx, r, occ, statusBanned, ac.reason)
+		case r.cfg.MembersOnly && ac.affiliation < memberAffiliation:
+			m.kickOccupant(ctx, r, occ, statusAffiliationLost, ac.reason)
+		default:
+			occ.role = r.defaultRole(ac.affiliation)
+			m.broadcastPresence(ctx, r, occ, nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0045
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/jackal-xmpp/stravaganza/jid"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/module/xep0030"
+)
+
+// Identities satisfies xep0030.InfoProvider interface.
+func (m *Muc) Identities(ctx context.Context, toJID, _ *jid.JID, node string) []discomodel.Identity {
+	if len(node) > 0 {
+		return nil
+	}
+	if len(toJID.Node()) == 0 {
+		return []discomodel.Identity{{Category: "conference", Type: "text", Name: m.cfg.Name}}
+	}
+	cfg, _, _ := m.roomInfo(ctx, toJID)
+	if cfg == nil {
+		return nil
+	}
+	name := cfg.Name
+	if len(name) == 0 {
+		name = toJID.Node()
+	}
+	return []discomodel.Identity{{Category: "conference", Type: "text", Name: name}}
+}
+
+// Items satisfies xep0030.InfoProvider interface.
+func (m *Muc) Items(ctx context.Context, toJID, _ *jid.JID, node string) ([]discomodel.Item, error) {
+	if len(node) > 0 {
+		return nil, xep0030.ErrEntityNotFound
+	}
+	if len(toJID.Node()) > 0 {
+		cfg, _, err := m.roomInfo(ctx, toJID)
+		if err != nil {
+			return nil, err
+		}
+		if cfg == nil {
+			return nil, xep0030.ErrEntityNotFound
+		}
+		return nil, nil // occupants are not disclosed
+	}
+	return m.publicRooms(ctx)
+}
+
+// Features satisfies xep0030.InfoProvider interface.
+func (m *Muc) Features(ctx context.Context, toJID, _ *jid.JID, node string) ([]discomodel.Feature, error) {
+	if len(node) > 0 {
+		return nil, xep0030.ErrEntityNotFound
+	}
+	if len(toJID.Node()) == 0 {
+		return []discomodel.Feature{discoInfoNamespace, discoItemsNamespace, mucNamespace}, nil
+	}
+	cfg, _, err := m.roomInfo(ctx, toJID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, xep0030.ErrEntityNotFound
+	}
+	features := []discomodel.Feature{
+		discoInfoNamespace,
+		mucNamespace,
+		choose(cfg.Public, "muc_public", "muc_hidden"),
+		choose(cfg.Persistent, "muc_persistent", "muc_temporary"),
+		choose(cfg.MembersOnly, "muc_membersonly", "muc_open"),
+		choose(cfg.Moderated, "muc_moderated", "muc_unmoderated"),
+		choose(len(cfg.Password) > 0, "muc_passwordprotected", "muc_unsecured"),
+		choose(cfg.NonAnonymous, "muc_nonanonymous", "muc_semianonymous"),
+	}
+	if cfg.Logging {
+		features = append(features, mamNamespace, retractNamespace, moderateNamespace)
+	}
+	return features, nil
+}
+
+// Forms satisfies xep0030.InfoProvider interface.
+func (m *Muc) Forms(ctx context.Context, toJID, _ *jid.JID, node string) ([]xep0004.DataForm, error) {
+	if len(node) > 0 || len(toJID.Node()) == 0 {
+		return nil, nil
+	}
+	cfg, occupants, err := m.roomInfo(ctx, toJID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, nil
+	}
+	return []xep0004.DataForm{{
+		Type: xep0004.Result,
+		Fields: []xep0004.Field{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{roomInfoNamespace}},
+			{Var: "muc#roominfo_description", Label: "Description", Values: []string{cfg.Description}},
+			{Var: "muc#roominfo_occupants", Label: "Number of occupants", Values: []string{strconv.Itoa(occupants)}},
+		},
+	}}, nil
+}
+
+// roomInfo returns a room configuration snapshot along with its current number of occupants.
+// A nil configuration is returned in case the room does not exist or it's not yet available.
+func (m *Muc) roomInfo(ctx context.Context, roomJID *jid.JID) (*mucmodel.RoomConfig, int, error) {
+	r, err := m.lockRoom(ctx, roomJID, false)
+	if err != nil || r == nil {
+		return nil, 0, err
+	}
+	defer m.unlockRoom(r)
+
+	if r.locked {
+		return nil, 0, nil
+	}
+	return r.cfg, len(r.occupants), nil
+}
+
+func (m *Muc) publicRooms(ctx context.Context) ([]discomodel.Item, error) {
+	stored, err := m.rep.FetchRooms(ctx, m.cfg.Host)
+	if err != nil {
+		return nil, err
+	}
+	rooms := make(map[string]*mucmodel.RoomConfig, len(stored))
+	for _, rm := range stored {
+		if rm.Config != nil {
+			rooms[rm.Jid] = rm.Config
+		}
+	}
+	// in-memory state takes precedence
+	m.mu.Lock()
+	active := make([]*room, 0, len(m.rooms))
+	for _, r := range m.rooms {
+		active = append(active, r)
+	}
+	m.mu.Unlock()
+
+	for _, r := range active {
+		r.mu.Lock()
+		if !r.locked && !r.evicted {
+			rooms[r.jid.String()] = r.cfg
+		}
+		r.mu.Unlock()
+	}
+
+	var items []discomodel.Item
+	for roomJID, cfg := range rooms {
+		if !cfg.Public {
+			continue
+		}
+		items = append(items, discomodel.Item{Jid: roomJID, Name: cfg.Name})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Jid < items[j].Jid })
+	return items, nil
+}
+
+func choose(cond bool, a, b string) string {
+	if cond {
+		return a
+	}
+	return b
+}
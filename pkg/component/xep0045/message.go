@@ -0,0 +1,192 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0045
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+func (m *Muc) processMessage(ctx context.Context, msg *stravaganza.Message) error {
+	toJID := msg.ToJID()
+	if len(toJID.Node()) == 0 || msg.IsError() {
+		return nil
+	}
+	r, err := m.lockRoom(ctx, toJID, false)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.InternalServerError))
+		return err
+	}
+	if r == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.ItemNotFound))
+		return nil
+	}
+	defer m.unlockRoom(r)
+
+	switch {
+	case msg.IsGroupChat() && len(toJID.Resource()) == 0:
+		return m.processGroupChat(ctx, r, msg)
+
+	case len(toJID.Resource()) > 0 && !msg.IsGroupChat():
+		m.processPrivateMessage(ctx, r, msg)
+		return nil
+
+	case msg.ChildNamespace("x", mucUserNamespace) != nil:
+		m.processInvitation(ctx, r, msg)
+		return nil
+
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.BadRequest))
+		return nil
+	}
+}
+
+func (m *Muc) processGroupChat(ctx context.Context, r *room, msg *stravaganza.Message) error {
+	occ := r.occupantByJID(msg.FromJID())
+	if occ == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.NotAcceptable))
+		return nil
+	}
+	if occ.role < participantRole {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.Forbidden))
+		return nil
+	}
+	if retract := msg.ChildNamespace("retract", retractNamespace); retract != nil {
+		ok, err := m.retractOccupantMessage(ctx, r, occ, msg, retract.Attribute("id"))
+		if err != nil || !ok {
+			return err
+		}
+	}
+	subject := msg.Child("subject")
+	switch {
+	case subject != nil && !msg.IsMessageWithBody():
+		if occ.role != moderatorRole && !r.cfg.ChangeSubject {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.Forbidden))
+			return nil
+		}
+		r.subject = subject.Text()
+		if r.persisted {
+			if err := m.rep.UpsertRoom(ctx, r.model()); err != nil {
+				return err
+			}
+		}
+		m.broadcastMessage(ctx, r, occ, msg)
+		return nil
+
+	case !msg.IsMessageWithBody():
+		m.broadcastMessage(ctx, r, occ, msg) // chat states and other payloads
+		return nil
+	}
+	fromJID := r.occupantJID(occ.nick)
+	if r.cfg.Logging {
+		archiveID := r.jid.String()
+		stanzaID := uuid.New().String()
+
+		archiveMsg, err := stravaganza.NewBuilderFromElement(msg).
+			WithoutChildrenNamespace("stanza-id", "urn:xmpp:sid:0").
+			WithAttribute(stravaganza.From, fromJID.String()).
+			WithAttribute(stravaganza.To, archiveID).
+			BuildMessage()
+		if err != nil {
+			return err
+		}
+		msg = xmpputil.MakeStanzaIDMessage(archiveMsg, stanzaID, archiveID)
+		if err := m.mam.ArchiveMessageFrom(ctx, msg, archiveID, stanzaID, occ.jid.ToBareJID().String()); err != nil {
+			return err
+		}
+	}
+	m.broadcastMessage(ctx, r, occ, msg)
+	return nil
+}
+
+func (m *Muc) broadcastMessage(ctx context.Context, r *room, occ *occupant, msg *stravaganza.Message) {
+	fromJID := r.occupantJID(occ.nick)
+	for _, o := range r.occupants {
+		outMsg, _ := stravaganza.NewBuilderFromElement(msg).
+			WithAttribute(stravaganza.From, fromJID.String()).
+			WithAttribute(stravaganza.To, o.jid.String()).
+			BuildMessage()
+		_, _ = m.router.Route(ctx, outMsg)
+	}
+}
+
+func (m *Muc) processPrivateMessage(ctx context.Context, r *room, msg *stravaganza.Message) {
+	occ := r.occupantByJID(msg.FromJID())
+	if occ == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.NotAcceptable))
+		return
+	}
+	target := r.occupantByNick(msg.ToJID().Resource())
+	if target == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.ItemNotFound))
+		return
+	}
+	outMsg, _ := stravaganza.NewBuilderFromElement(msg).
+		WithAttribute(stravaganza.From, r.occupantJID(occ.nick).String()).
+		WithAttribute(stravaganza.To, target.jid.String()).
+		WithoutChildrenNamespace("x", mucUserNamespace).
+		WithChild(
+			stravaganza.NewBuilder("x").
+				WithAttribute(stravaganza.Namespace, mucUserNamespace).
+				Build(),
+		).
+		BuildMessage()
+	_, _ = m.router.Route(ctx, outMsg)
+}
+
+func (m *Muc) processInvitation(ctx context.Context, r *room, msg *stravaganza.Message) {
+	invite := msg.ChildNamespace("x", mucUserNamespace).Child("invite")
+	if invite == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.BadRequest))
+		return
+	}
+	fromJID := msg.FromJID()
+	if r.occupantByJID(fromJID) == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.NotAcceptable))
+		return
+	}
+	if r.cfg.MembersOnly && !r.cfg.AllowInvites && r.affiliation(fromJID) < adminAffiliation {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.Forbidden))
+		return
+	}
+	inviteeJID, err := jid.NewWithString(invite.Attribute(stravaganza.To), false)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.JIDMalformed))
+		return
+	}
+	inviteB := stravaganza.NewBuilder("invite").
+		WithAttribute(stravaganza.From, fromJID.ToBareJID().String())
+	if reason := invite.Child("reason"); reason != nil {
+		inviteB.WithChild(reason)
+	}
+	xB := stravaganza.NewBuilder("x").
+		WithAttribute(stravaganza.Namespace, mucUserNamespace).
+		WithChild(inviteB.Build())
+	if len(r.cfg.Password) > 0 {
+		xB.WithChild(stravaganza.NewBuilder("password").WithText(r.cfg.Password).Build())
+	}
+	outMsg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.From, r.jid.String()).
+		WithAttribute(stravaganza.To, inviteeJID.String()).
+		WithChild(xB.Build()).
+		BuildMessage()
+	_, _ = m.router.Route(ctx, outMsg)
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0045
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/module/xep0313"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const moderateFallbackText = "A message in this room has been moderated, but it's unsupported by your client."
+
+// processModerateIQ handles a moderator request to retract an occupant message (XEP-0425).
+func (m *Muc) processModerateIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	moderate := iq.ChildNamespace("moderate", moderateNamespace)
+	stanzaID := moderate.Attribute("id")
+	if !iq.IsSet() || len(stanzaID) == 0 || moderate.ChildNamespace("retract", retractNamespace) == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	r, err := m.lockRoom(ctx, iq.ToJID(), false)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	if r == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
+		return nil
+	}
+	defer m.unlockRoom(r)
+
+	byJID := m.moderatorJID(r, iq.FromJID())
+	if byJID == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return nil
+	}
+	archiveMsg, err := m.mam.FetchMessage(ctx, r.jid.String(), stanzaID)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	if archiveMsg == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
+		return nil
+	}
+	moderation := &xep0313.Moderation{By: byJID.String()}
+	if reason := moderate.Child("reason"); reason != nil {
+		moderation.Reason = reason.Text()
+	}
+	if err := m.mam.RetractMessage(ctx, archiveMsg, moderation); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	if err := m.broadcastModeration(ctx, r, stanzaID, moderation); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+	return nil
+}
+
+// retractOccupantMessage tombstones an archived message retracted by its own sender (XEP-0424).
+// It returns false in case the retraction has been rejected.
+func (m *Muc) retractOccupantMessage(ctx context.Context, r *room, occ *occupant, msg *stravaganza.Message, stanzaID string) (bool, error) {
+	if !r.cfg.Logging || len(stanzaID) == 0 {
+		return true, nil
+	}
+	archiveMsg, err := m.mam.FetchMessage(ctx, r.jid.String(), stanzaID)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.InternalServerError))
+		return false, err
+	}
+	if archiveMsg == nil {
+		return true, nil
+	}
+	// nicks can be reused, so authorship is checked against sender real JID
+	if archiveMsg.SenderJid != occ.jid.ToBareJID().String() {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.Forbidden))
+		return false, nil
+	}
+	if err := m.mam.RetractMessage(ctx, archiveMsg, nil); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.InternalServerError))
+		return false, err
+	}
+	return true, nil
+}
+
+// moderatorJID returns the JID a moderation requested by userJID is attributed to,
+// or nil if userJID is not allowed to moderate r room messages.
+func (m *Muc) moderatorJID(r *room, userJID *jid.JID) *jid.JID {
+	if occ := r.occupantByJID(userJID); occ != nil && occ.role == moderatorRole {
+		return r.occupantJID(occ.nick)
+	}
+	for _, moderator := range m.cfg.Moderators {
+		if moderator == userJID.ToBareJID().String() {
+			return userJID.ToBareJID()
+		}
+	}
+	return nil
+}
+
+func (m *Muc) broadcastModeration(ctx context.Context, r *room, stanzaID string, moderation *xep0313.Moderation) error {
+	retractB := stravaganza.NewBuilder("retract").
+		WithAttribute(stravaganza.Namespace, retractNamespace).
+		WithAttribute(stravaganza.ID, stanzaID).
+		WithChild(
+			stravaganza.NewBuilder("moderated").
+				WithAttribute(stravaganza.Namespace, moderateNamespace).
+				WithAttribute("by", moderation.By).
+				Build(),
+		)
+	if len(moderation.Reason) > 0 {
+		retractB.WithChild(stravaganza.NewBuilder("reason").WithText(moderation.Reason).Build())
+	}
+	msg, err := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.From, r.jid.String()).
+		WithAttribute(stravaganza.To, r.jid.String()).
+		WithAttribute(stravaganza.Type, stravaganza.GroupChatType).
+		WithChild(retractB.Build()).
+		WithChild(
+			stravaganza.NewBuilder("fallback").
+				WithAttribute(stravaganza.Namespace, "urn:xmpp:fallback:0").
+				WithAttribute("for", retractNamespace).
+				Build(),
+		).
+		WithChild(stravaganza.NewBuilder("body").WithText(moderateFallbackText).Build()).
+		BuildMessage()
+	if err != nil {
+		return err
+	}
+	// archive the retraction itself, so that occupants catching up through MAM learn about it
+	if r.cfg.Logging {
+		archiveID := r.jid.String()
+		archiveStanzaID := uuid.New().String()
+
+		msg = xmpputil.MakeStanzaIDMessage(msg, archiveStanzaID, archiveID)
+		if err := m.mam.ArchiveMessage(ctx, msg, archiveID, archiveStanzaID); err != nil {
+			return err
+		}
+	}
+	for _, o := range r.occupants {
+		outMsg, _ := stravaganza.NewBuilderFromElement(msg).
+			WithAttribute(stravaganza.To, o.jid.String()).
+			BuildMessage()
+		_, _ = m.router.Route(ctx, outMsg)
+	}
+	return nil
+}
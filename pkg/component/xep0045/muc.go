@@ -0,0 +1,241 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0045
+
+import (
+	"context"
+	"sync"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/module/xep0030"
+	"github.com/ortuman/jackal/pkg/module/xep0313"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	// ComponentName represents multi-user chat component name.
+	ComponentName = "muc"
+
+	// XEPNumber represents multi-user chat XEP number.
+	XEPNumber = "0045"
+
+	mucNamespace        = "http://jabber.org/protocol/muc"
+	mucUserNamespace    = "http://jabber.org/protocol/muc#user"
+	mucAdminNamespace   = "http://jabber.org/protocol/muc#admin"
+	mucOwnerNamespace   = "http://jabber.org/protocol/muc#owner"
+	roomConfigNamespace = "http://jabber.org/protocol/muc#roomconfig"
+	roomInfoNamespace   = "http://jabber.org/protocol/muc#roominfo"
+
+	discoInfoNamespace  = "http://jabber.org/protocol/disco#info"
+	discoItemsNamespace = "http://jabber.org/protocol/disco#items"
+	mamNamespace        = "urn:xmpp:mam:2"
+	retractNamespace    = "urn:xmpp:message-retract:1"
+	moderateNamespace   = "urn:xmpp:message-moderate:1"
+)
+
+// Config contains multi-user chat component configuration options.
+type Config struct {
+	// Host is the multi-user chat service domain (e.g. conference.jackal.im).
+	// An empty value disables the component.
+	Host string `fig:"host"`
+
+	// Name is the service name announced via service discovery.
+	Name string `fig:"name" default:"Chatrooms"`
+
+	// HistorySize defines the maximum number of messages sent as discussion history to a joining occupant.
+	HistorySize int `fig:"history_size" default:"20"`
+
+	// ArchiveQueueSize defines the maximum number of archived messages per room.
+	ArchiveQueueSize int `fig:"archive_queue_size" default:"1000"`
+
+	// Moderators contains the bare JIDs allowed to moderate messages in any room of the service.
+	Moderators []string `fig:"moderators"`
+}
+
+// Muc represents a multi-user chat (XEP-0045) component type.
+//
+// Room configuration and affiliations are persisted through the repository, while occupants are
+// kept in memory. Occupancy is thus local to the node handling the component, so in a clustered
+// deployment all occupants of a room are expected to reach it through the same node.
+type Muc struct {
+	cfg    Config
+	router router.Router
+	rep    repository.Repository
+	mam    *xep0313.Service
+	hk     *hook.Hooks
+	logger kitlog.Logger
+
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+// New returns a new initialized multi-user chat component instance.
+func New(
+	cfg Config,
+	router router.Router,
+	rep repository.Repository,
+	hk *hook.Hooks,
+	logger kitlog.Logger,
+) *Muc {
+	logger = kitlog.With(logger, "component", ComponentName, "xep", XEPNumber)
+	return &Muc{
+		cfg:    cfg,
+		router: router,
+		rep:    rep,
+		mam:    xep0313.NewService(router, hk, rep, cfg.ArchiveQueueSize, logger),
+		hk:     hk,
+		logger: logger,
+		rooms:  make(map[string]*room),
+	}
+}
+
+// Host returns multi-user chat component host domain.
+func (m *Muc) Host() string { return m.cfg.Host }
+
+// Name returns multi-user chat component name.
+func (m *Muc) Name() string { return ComponentName }
+
+// ProcessStanza processes a stanza addressed to the multi-user chat service or any of its rooms.
+func (m *Muc) ProcessStanza(ctx context.Context, stanza stravaganza.Stanza) error {
+	switch stz := stanza.(type) {
+	case *stravaganza.IQ:
+		return m.processIQ(ctx, stz)
+	case *stravaganza.Presence:
+		return m.processPresence(ctx, stz)
+	case *stravaganza.Message:
+		return m.processMessage(ctx, stz)
+	}
+	return nil
+}
+
+// Start starts multi-user chat component.
+func (m *Muc) Start(_ context.Context) error {
+	m.hk.AddHook(hook.C2SStreamPresenceReceived, m.onPresenceReceived, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamDisconnected, m.onDisconnect, hook.DefaultPriority)
+
+	level.Info(m.logger).Log("msg", "started muc component", "host", m.cfg.Host)
+	return nil
+}
+
+// Stop stops multi-user chat component.
+func (m *Muc) Stop(_ context.Context) error {
+	m.hk.RemoveHook(hook.C2SStreamPresenceReceived, m.onPresenceReceived)
+	m.hk.RemoveHook(hook.C2SStreamDisconnected, m.onDisconnect)
+
+	level.Info(m.logger).Log("msg", "stopped muc component", "host", m.cfg.Host)
+	return nil
+}
+
+func (m *Muc) processIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	if !iq.IsGet() && !iq.IsSet() {
+		return nil // ignore results and errors
+	}
+	toJID := iq.ToJID()
+	switch {
+	case iq.ChildNamespace("query", discoInfoNamespace) != nil, iq.ChildNamespace("query", discoItemsNamespace) != nil:
+		return xep0030.ProcessProviderIQ(ctx, m.router, m, iq)
+
+	case len(toJID.Node()) == 0 || len(toJID.Resource()) > 0:
+		break
+
+	case iq.ChildNamespace("query", mucAdminNamespace) != nil:
+		return m.processAdminIQ(ctx, iq)
+
+	case iq.ChildNamespace("query", mucOwnerNamespace) != nil:
+		return m.processOwnerIQ(ctx, iq)
+
+	case iq.ChildNamespace("query", mamNamespace) != nil, iq.ChildNamespace("metadata", mamNamespace) != nil:
+		return m.processArchiveIQ(ctx, iq)
+
+	case iq.ChildNamespace("moderate", moderateNamespace) != nil:
+		return m.processModerateIQ(ctx, iq)
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ServiceUnavailable))
+	return nil
+}
+
+func (m *Muc) processArchiveIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	r, err := m.lockRoom(ctx, iq.ToJID(), false)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	if r == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
+		return nil
+	}
+	allowed := r.occupantByJID(iq.FromJID()) != nil || r.affiliation(iq.FromJID()) >= memberAffiliation
+	archiveID := r.jid.String()
+	m.unlockRoom(r)
+
+	if !allowed {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return nil
+	}
+	return m.mam.ProcessArchiveIQ(ctx, iq, archiveID)
+}
+
+func (m *Muc) onPresenceReceived(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+
+	pr, ok := inf.Element.(*stravaganza.Presence)
+	if !ok || !pr.IsUnavailable() {
+		return nil
+	}
+	fromJID := pr.FromJID()
+	toJID := pr.ToJID()
+	if !toJID.IsBare() || !toJID.MatchesWithOptions(fromJID, jid.MatchesBare) {
+		return nil // not a broadcast presence
+	}
+	return m.leaveAllRooms(execCtx.Context, fromJID)
+}
+
+func (m *Muc) onDisconnect(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+	if inf.JID == nil {
+		return nil
+	}
+	return m.leaveAllRooms(execCtx.Context, inf.JID)
+}
+
+// leaveAllRooms makes userJID leave every room it's currently occupying.
+func (m *Muc) leaveAllRooms(ctx context.Context, userJID *jid.JID) error {
+	m.mu.Lock()
+	rooms := make([]*room, 0, len(m.rooms))
+	for _, r := range m.rooms {
+		rooms = append(rooms, r)
+	}
+	m.mu.Unlock()
+
+	for _, r := range rooms {
+		r.mu.Lock()
+		if r.evicted {
+			r.mu.Unlock()
+			continue
+		}
+		if occ := r.occupantByJID(userJID); occ != nil {
+			m.leaveRoom(ctx, r, occ, nil)
+		}
+		m.unlockRoom(r)
+	}
+	return nil
+}
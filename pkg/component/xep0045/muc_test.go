@@ -0,0 +1,760 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0045
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/module/xep0313"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/stretchr/testify/require"
+)
+
+type testRouter struct {
+	mu      sync.Mutex
+	stanzas []stravaganza.Stanza
+}
+
+func (r *testRouter) route(_ context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stanzas = append(r.stanzas, stanza)
+	return nil, nil
+}
+
+func (r *testRouter) flush() []stravaganza.Stanza {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := r.stanzas
+	r.stanzas = nil
+	return ret
+}
+
+func TestMuc_CreateInstantRoom(t *testing.T) {
+	// given
+	m, rtr, _ := testMuc()
+
+	// when
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("ortuman@jackal.im/yard", "lobby@conference.jackal.im/ortuman"))
+	created := rtr.flush()
+
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("noelia@jackal.im/balcony", "lobby@conference.jackal.im/noelia"))
+	rejected := rtr.flush()
+
+	_ = m.ProcessStanza(context.Background(), testInstantRoomIQ("ortuman@jackal.im/yard", "lobby@conference.jackal.im"))
+	configured := rtr.flush()
+
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("noelia@jackal.im/balcony", "lobby@conference.jackal.im/noelia"))
+	joined := rtr.flush()
+
+	// then
+	require.Len(t, created, 2) // self-presence + subject
+
+	x := created[0].ChildNamespace("x", mucUserNamespace)
+	require.NotNil(t, x)
+	require.Equal(t, "owner", x.Child("item").Attribute("affiliation"))
+	require.Equal(t, "moderator", x.Child("item").Attribute("role"))
+	require.Equal(t, []string{"201", "110"}, statusCodes(x))
+	require.NotNil(t, created[1].Child("subject"))
+
+	require.Len(t, rejected, 1)
+	require.Equal(t, stravaganza.ErrorType, rejected[0].Type())
+	require.NotNil(t, rejected[0].Child("error").Child("item-not-found"))
+
+	require.Len(t, configured, 1)
+	require.Equal(t, stravaganza.ResultType, configured[0].Type())
+
+	require.Len(t, joined, 4) // owner presence + joined presence broadcast + subject
+	require.Equal(t, "lobby@conference.jackal.im/ortuman", joined[0].FromJID().String())
+	require.Equal(t, "noelia@jackal.im/balcony", joined[0].ToJID().String())
+
+	x = joined[0].ChildNamespace("x", mucUserNamespace)
+	require.Empty(t, x.Child("item").Attribute("jid")) // semi-anonymous room
+
+	var toOwner, toSelf bool
+	for _, stanza := range joined[1:3] {
+		require.Equal(t, "lobby@conference.jackal.im/noelia", stanza.FromJID().String())
+		switch stanza.ToJID().String() {
+		case "ortuman@jackal.im/yard":
+			toOwner = true
+			x := stanza.ChildNamespace("x", mucUserNamespace)
+			require.Equal(t, "noelia@jackal.im/balcony", x.Child("item").Attribute("jid")) // visible to moderators
+		case "noelia@jackal.im/balcony":
+			toSelf = true
+			require.Equal(t, []string{"110"}, statusCodes(stanza.ChildNamespace("x", mucUserNamespace)))
+		}
+	}
+	require.True(t, toOwner)
+	require.True(t, toSelf)
+}
+
+func TestMuc_GroupChat(t *testing.T) {
+	// given
+	m, rtr, repMock := testMuc()
+
+	var archived []*archivemodel.Message
+	txMock := &txMock{}
+	txMock.InsertArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		archived = append(archived, message)
+		return nil
+	}
+	txMock.DeleteArchiveOldestMessagesFunc = func(ctx context.Context, archiveID string, maxElements int) error {
+		return nil
+	}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, txMock)
+	}
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	// when
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.To, "lobby@conference.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GroupChatType).
+		WithChild(stravaganza.NewBuilder("body").WithText("Hi there!").Build()).
+		BuildMessage()
+	_ = m.ProcessStanza(context.Background(), msg)
+
+	// then
+	reflected := rtr.flush()
+	require.Len(t, reflected, 2)
+
+	for _, stanza := range reflected {
+		require.Equal(t, "lobby@conference.jackal.im/noelia", stanza.FromJID().String())
+		require.NotNil(t, stanza.ChildNamespace("stanza-id", "urn:xmpp:sid:0"))
+	}
+	require.Len(t, archived, 1)
+	require.Equal(t, "lobby@conference.jackal.im", archived[0].ArchiveId)
+	require.Equal(t, "lobby@conference.jackal.im/noelia", archived[0].FromJid)
+}
+
+func TestMuc_VisitorCannotSpeak(t *testing.T) {
+	// given
+	m, rtr, _ := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	m.rooms["lobby@conference.jackal.im"].occupantByNick("noelia").role = visitorRole
+
+	// when
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.To, "lobby@conference.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GroupChatType).
+		WithChild(stravaganza.NewBuilder("body").WithText("Hi there!").Build()).
+		BuildMessage()
+	_ = m.ProcessStanza(context.Background(), msg)
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+	require.NotNil(t, stanzas[0].Child("error").Child("forbidden"))
+}
+
+func TestMuc_KickOccupant(t *testing.T) {
+	// given
+	m, rtr, _ := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	// when
+	iq := testAdminIQ("ortuman@jackal.im/yard", stravaganza.NewBuilder("item").
+		WithAttribute("nick", "noelia").
+		WithAttribute("role", "none").
+		WithChild(stravaganza.NewBuilder("reason").WithText("Spamming").Build()).
+		Build(),
+	)
+	_ = m.ProcessStanza(context.Background(), iq)
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 3) // two unavailable presences + result
+
+	for _, stanza := range stanzas[:2] {
+		require.Equal(t, stravaganza.UnavailableType, stanza.Type())
+		x := stanza.ChildNamespace("x", mucUserNamespace)
+		require.Contains(t, statusCodes(x), "307")
+		require.Equal(t, "Spamming", x.Child("item").Child("reason").Text())
+	}
+	require.Equal(t, stravaganza.ResultType, stanzas[2].Type())
+
+	r := m.rooms["lobby@conference.jackal.im"]
+	require.Nil(t, r.occupantByNick("noelia"))
+}
+
+func TestMuc_BanUser(t *testing.T) {
+	// given
+	m, rtr, _ := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	// when
+	iq := testAdminIQ("ortuman@jackal.im/yard", stravaganza.NewBuilder("item").
+		WithAttribute("jid", "noelia@jackal.im").
+		WithAttribute("affiliation", "outcast").
+		Build(),
+	)
+	_ = m.ProcessStanza(context.Background(), iq)
+	banned := rtr.flush()
+
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("noelia@jackal.im/balcony", "lobby@conference.jackal.im/noelia"))
+	rejoined := rtr.flush()
+
+	// then
+	require.Len(t, banned, 3)
+	require.Contains(t, statusCodes(banned[0].ChildNamespace("x", mucUserNamespace)), "301")
+
+	require.Len(t, rejoined, 1)
+	require.NotNil(t, rejoined[0].Child("error").Child("forbidden"))
+}
+
+func TestMuc_AdminPermissions(t *testing.T) {
+	// given
+	m, rtr, _ := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	// when
+	iq := testAdminIQ("noelia@jackal.im/balcony", stravaganza.NewBuilder("item").
+		WithAttribute("nick", "ortuman").
+		WithAttribute("role", "none").
+		Build(),
+	)
+	_ = m.ProcessStanza(context.Background(), iq)
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.NotNil(t, stanzas[0].Child("error").Child("forbidden"))
+}
+
+func TestMuc_LastOwner(t *testing.T) {
+	// given
+	m, rtr, _ := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	// when
+	iq := testAdminIQ("ortuman@jackal.im/yard", stravaganza.NewBuilder("item").
+		WithAttribute("jid", "ortuman@jackal.im").
+		WithAttribute("affiliation", "member").
+		Build(),
+	)
+	_ = m.ProcessStanza(context.Background(), iq)
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.NotNil(t, stanzas[0].Child("error").Child("conflict"))
+}
+
+func TestMuc_PersistentRoom(t *testing.T) {
+	// given
+	m, rtr, repMock := testMuc()
+
+	var storedRoom *mucmodel.Room
+	var storedAffs []*mucmodel.Affiliation
+
+	txMock := &txMock{}
+	txMock.UpsertRoomFunc = func(ctx context.Context, room *mucmodel.Room) error {
+		storedRoom = room
+		return nil
+	}
+	txMock.UpsertRoomAffiliationFunc = func(ctx context.Context, aff *mucmodel.Affiliation) error {
+		storedAffs = append(storedAffs, aff)
+		return nil
+	}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, txMock)
+	}
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("ortuman@jackal.im/yard", "lobby@conference.jackal.im/ortuman"))
+
+	// when
+	form := xep0004.DataForm{
+		Type: xep0004.Submit,
+		Fields: []xep0004.Field{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{roomConfigNamespace}},
+			{Var: roomNameField, Values: []string{"The Lobby"}},
+			{Var: persistentRoomField, Values: []string{"1"}},
+			{Var: membersOnlyField, Values: []string{"1"}},
+		},
+	}
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "cfg1").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "lobby@conference.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, mucOwnerNamespace).
+				WithChild(form.Element()).
+				Build(),
+		).
+		BuildIQ()
+	rtr.flush()
+	_ = m.ProcessStanza(context.Background(), iq)
+
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("noelia@jackal.im/balcony", "lobby@conference.jackal.im/noelia"))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 2)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+	require.NotNil(t, stanzas[1].Child("error").Child("registration-required"))
+
+	require.NotNil(t, storedRoom)
+	require.Equal(t, "lobby@conference.jackal.im", storedRoom.Jid)
+	require.Equal(t, "The Lobby", storedRoom.Config.Name)
+	require.True(t, storedRoom.Config.MembersOnly)
+
+	require.Len(t, storedAffs, 1)
+	require.Equal(t, "ortuman@jackal.im", storedAffs[0].Jid)
+	require.Equal(t, "owner", storedAffs[0].Affiliation)
+}
+
+func TestMuc_LoadStoredRoom(t *testing.T) {
+	// given
+	m, rtr, repMock := testMuc()
+	repMock.FetchRoomFunc = func(ctx context.Context, roomJID string) (*mucmodel.Room, error) {
+		return &mucmodel.Room{
+			Jid:     roomJID,
+			Config:  &mucmodel.RoomConfig{Name: "The Lobby", Persistent: true, Public: true},
+			Subject: "Welcome!",
+		}, nil
+	}
+	repMock.FetchRoomAffiliationsFunc = func(ctx context.Context, roomJID string) ([]*mucmodel.Affiliation, error) {
+		return []*mucmodel.Affiliation{
+			{RoomJid: roomJID, Jid: "noelia@jackal.im", Affiliation: "admin"},
+		}, nil
+	}
+
+	// when
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("noelia@jackal.im/balcony", "lobby@conference.jackal.im/noelia"))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 2)
+
+	x := stanzas[0].ChildNamespace("x", mucUserNamespace)
+	require.Equal(t, "admin", x.Child("item").Attribute("affiliation"))
+	require.Equal(t, "moderator", x.Child("item").Attribute("role"))
+	require.Equal(t, []string{"110"}, statusCodes(x))
+
+	require.Equal(t, "Welcome!", stanzas[1].Child("subject").Text())
+}
+
+func TestMuc_LoadRoomDoesNotBlockOtherRooms(t *testing.T) {
+	// given
+	m, _, repMock := testMuc()
+
+	fetchCh := make(chan struct{})
+	releaseCh := make(chan struct{})
+	repMock.FetchRoomFunc = func(ctx context.Context, roomJID string) (*mucmodel.Room, error) {
+		if roomJID == "slow@conference.jackal.im" {
+			close(fetchCh)
+			<-releaseCh
+		}
+		return nil, nil
+	}
+	slowJID, _ := jid.NewWithString("slow@conference.jackal.im", true)
+	lobbyJID, _ := jid.NewWithString("lobby@conference.jackal.im", true)
+
+	slowCh := make(chan *room, 1)
+	go func() {
+		r, _ := m.lockRoom(context.Background(), slowJID, true)
+		slowCh <- r
+	}()
+	<-fetchCh
+
+	// when
+	r, err := m.lockRoom(context.Background(), lobbyJID, true)
+
+	// then
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	m.unlockRoom(r)
+
+	close(releaseCh)
+	r = <-slowCh
+	require.NotNil(t, r)
+	require.True(t, r.locked)
+	m.unlockRoom(r)
+}
+
+func TestMuc_NickChange(t *testing.T) {
+	// given
+	m, rtr, _ := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	// when
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("noelia@jackal.im/balcony", "lobby@conference.jackal.im/ortuman"))
+	conflict := rtr.flush()
+
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("noelia@jackal.im/balcony", "lobby@conference.jackal.im/noe"))
+	changed := rtr.flush()
+
+	// then
+	require.Len(t, conflict, 1)
+	require.NotNil(t, conflict[0].Child("error").Child("conflict"))
+
+	require.Len(t, changed, 4)
+	for _, stanza := range changed[:2] {
+		require.Equal(t, stravaganza.UnavailableType, stanza.Type())
+		x := stanza.ChildNamespace("x", mucUserNamespace)
+		require.Contains(t, statusCodes(x), "303")
+		require.Equal(t, "noe", x.Child("item").Attribute("nick"))
+	}
+	for _, stanza := range changed[2:] {
+		require.Equal(t, "lobby@conference.jackal.im/noe", stanza.FromJID().String())
+	}
+}
+
+func TestMuc_Disconnect(t *testing.T) {
+	// given
+	m, rtr, _ := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	// when
+	userJID, _ := jid.NewWithString("noelia@jackal.im/balcony", true)
+	_, _ = m.hk.Run(hook.C2SStreamDisconnected, &hook.ExecutionContext{
+		Info:    &hook.C2SStreamInfo{JID: userJID},
+		Context: context.Background(),
+	})
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 2)
+	for _, stanza := range stanzas {
+		require.Equal(t, stravaganza.UnavailableType, stanza.Type())
+		require.Equal(t, "lobby@conference.jackal.im/noelia", stanza.FromJID().String())
+	}
+	require.Nil(t, m.rooms["lobby@conference.jackal.im"].occupantByNick("noelia"))
+}
+
+func TestMuc_RoomDiscoInfo(t *testing.T) {
+	// given
+	m, rtr, _ := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "disco1").
+		WithAttribute(stravaganza.From, "romeo@montague.lit/garden").
+		WithAttribute(stravaganza.To, "lobby@conference.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, discoInfoNamespace).
+				Build(),
+		).
+		BuildIQ()
+	_ = m.ProcessStanza(context.Background(), iq)
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+
+	q := stanzas[0].ChildNamespace("query", discoInfoNamespace)
+	require.NotNil(t, q)
+	require.Equal(t, "conference", q.Child("identity").Attribute("category"))
+
+	var features []string
+	for _, f := range q.Children("feature") {
+		features = append(features, f.Attribute("var"))
+	}
+	require.Contains(t, features, mucNamespace)
+	require.Contains(t, features, "muc_public")
+	require.Contains(t, features, "muc_temporary")
+	require.Contains(t, features, mamNamespace)
+
+	x := q.ChildNamespace("x", xep0004.FormNamespace)
+	require.NotNil(t, x)
+}
+
+func TestMuc_ModerateMessage(t *testing.T) {
+	// given
+	m, rtr, repMock := testMuc()
+
+	txMock := &txMock{}
+	txMock.InsertArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		return nil
+	}
+	txMock.DeleteArchiveOldestMessagesFunc = func(ctx context.Context, archiveID string, maxElements int) error {
+		return nil
+	}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, txMock)
+	}
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	repMock.FetchArchiveMessagesFunc = func(ctx context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error) {
+		msg, _ := stravaganza.NewMessageBuilder().
+			WithAttribute(stravaganza.From, "lobby@conference.jackal.im/noelia").
+			WithAttribute(stravaganza.To, "lobby@conference.jackal.im").
+			WithAttribute(stravaganza.Type, stravaganza.GroupChatType).
+			WithChild(stravaganza.NewBuilder("body").WithText("Buy cheap pills!").Build()).
+			BuildMessage()
+		return []*archivemodel.Message{{
+			ArchiveId: archiveID,
+			Id:        f.Ids[0],
+			FromJid:   "lobby@conference.jackal.im/noelia",
+			Message:   msg.Proto(),
+		}}, nil
+	}
+	var updated *archivemodel.Message
+	repMock.UpdateArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		updated = message
+		return nil
+	}
+
+	// when
+	_ = m.ProcessStanza(context.Background(), testModerateIQ("ortuman@jackal.im/yard", "s1234"))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 3) // retraction broadcast + result
+
+	for _, stanza := range stanzas[:2] {
+		retract := stanza.ChildNamespace("retract", retractNamespace)
+		require.NotNil(t, retract)
+		require.Equal(t, "s1234", retract.Attribute("id"))
+		require.Equal(t, "lobby@conference.jackal.im/ortuman", retract.ChildNamespace("moderated", moderateNamespace).Attribute("by"))
+		require.Equal(t, "Spam", retract.Child("reason").Text())
+	}
+	require.Equal(t, stravaganza.ResultType, stanzas[2].Type())
+
+	require.NotNil(t, updated)
+	require.Equal(t, "s1234", updated.Id)
+
+	tombstone := stravaganza.NewBuilderFromProto(updated.Message).Build()
+	require.Nil(t, tombstone.Child("body"))
+	require.NotNil(t, tombstone.ChildNamespace("retracted", retractNamespace))
+}
+
+func TestMuc_ModerateForbidden(t *testing.T) {
+	// given
+	m, rtr, repMock := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	// when
+	_ = m.ProcessStanza(context.Background(), testModerateIQ("noelia@jackal.im/balcony", "s1234"))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+	require.NotNil(t, stanzas[0].Child("error").Child("forbidden"))
+
+	require.Len(t, repMock.UpdateArchiveMessageCalls(), 0)
+}
+
+func TestMuc_RetractForeignMessage(t *testing.T) {
+	// given
+	m, rtr, repMock := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	repMock.FetchArchiveMessagesFunc = func(ctx context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error) {
+		return []*archivemodel.Message{{
+			ArchiveId: archiveID,
+			Id:        f.Ids[0],
+			FromJid:   "lobby@conference.jackal.im/ortuman",
+			SenderJid: "ortuman@jackal.im",
+		}}, nil
+	}
+
+	// when
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.To, "lobby@conference.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GroupChatType).
+		WithChild(
+			stravaganza.NewBuilder("retract").
+				WithAttribute(stravaganza.Namespace, retractNamespace).
+				WithAttribute(stravaganza.ID, "s1234").
+				Build(),
+		).
+		BuildMessage()
+	_ = m.ProcessStanza(context.Background(), msg)
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+	require.NotNil(t, stanzas[0].Child("error").Child("forbidden"))
+
+	require.Len(t, repMock.UpdateArchiveMessageCalls(), 0)
+}
+
+func TestMuc_RetractReusedNickMessage(t *testing.T) {
+	// given
+	m, rtr, repMock := testMuc()
+	testSetupRoom(m, rtr, "noelia@jackal.im/balcony")
+
+	// message sent by a former occupant that used the same nick
+	repMock.FetchArchiveMessagesFunc = func(ctx context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error) {
+		return []*archivemodel.Message{{
+			ArchiveId: archiveID,
+			Id:        f.Ids[0],
+			FromJid:   "lobby@conference.jackal.im/noelia",
+			SenderJid: "romeo@jackal.im",
+		}}, nil
+	}
+
+	// when
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.To, "lobby@conference.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GroupChatType).
+		WithChild(
+			stravaganza.NewBuilder("retract").
+				WithAttribute(stravaganza.Namespace, retractNamespace).
+				WithAttribute(stravaganza.ID, "s1234").
+				Build(),
+		).
+		BuildMessage()
+	_ = m.ProcessStanza(context.Background(), msg)
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+	require.NotNil(t, stanzas[0].Child("error").Child("forbidden"))
+
+	require.Len(t, repMock.UpdateArchiveMessageCalls(), 0)
+}
+
+func testMuc() (*Muc, *testRouter, *repositoryMock) {
+	rtr := &testRouter{}
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = rtr.route
+
+	repMock := &repositoryMock{}
+	repMock.FetchRoomFunc = func(ctx context.Context, roomJID string) (*mucmodel.Room, error) {
+		return nil, nil
+	}
+	repMock.FetchArchiveMessagesFunc = func(ctx context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error) {
+		return nil, nil
+	}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return nil
+	}
+	hk := hook.NewHooks()
+	m := &Muc{
+		cfg:    Config{Host: "conference.jackal.im", Name: "Chatrooms", HistorySize: 20},
+		router: routerMock,
+		rep:    repMock,
+		mam:    xep0313.NewService(routerMock, hk, repMock, 100, kitlog.NewNopLogger()),
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+		rooms:  make(map[string]*room),
+	}
+	return m, rtr, repMock
+}
+
+// testSetupRoom creates an unlocked lobby room owned by ortuman where userJID has joined as 'noelia'.
+func testSetupRoom(m *Muc, rtr *testRouter, userJID string) {
+	_ = m.ProcessStanza(context.Background(), testJoinPresence("ortuman@jackal.im/yard", "lobby@conference.jackal.im/ortuman"))
+	_ = m.ProcessStanza(context.Background(), testInstantRoomIQ("ortuman@jackal.im/yard", "lobby@conference.jackal.im"))
+	_ = m.ProcessStanza(context.Background(), testJoinPresence(userJID, "lobby@conference.jackal.im/noelia"))
+	rtr.flush()
+}
+
+func testJoinPresence(from, to string) *stravaganza.Presence {
+	pr, _ := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, to).
+		WithChild(
+			stravaganza.NewBuilder("x").
+				WithAttribute(stravaganza.Namespace, mucNamespace).
+				Build(),
+		).
+		BuildPresence()
+	return pr
+}
+
+func testInstantRoomIQ(from, to string) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "create1").
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, to).
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, mucOwnerNamespace).
+				WithChild(
+					stravaganza.NewBuilder("x").
+						WithAttribute(stravaganza.Namespace, xep0004.FormNamespace).
+						WithAttribute(stravaganza.Type, xep0004.Submit).
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
+
+func testAdminIQ(from string, item stravaganza.Element) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "admin1").
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, "lobby@conference.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, mucAdminNamespace).
+				WithChild(item).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
+
+func testModerateIQ(from, stanzaID string) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "mod1").
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, "lobby@conference.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("moderate").
+				WithAttribute(stravaganza.Namespace, moderateNamespace).
+				WithAttribute(stravaganza.ID, stanzaID).
+				WithChild(
+					stravaganza.NewBuilder("retract").
+						WithAttribute(stravaganza.Namespace, retractNamespace).
+						Build(),
+				).
+				WithChild(stravaganza.NewBuilder("reason").WithText("Spam").Build()).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
+
+func statusCodes(x stravaganza.Element) []string {
+	var codes []string
+	for _, st := range x.Children("status") {
+		codes = append(codes, st.Attribute("code"))
+	}
+	return codes
+}
@@ -0,0 +1,302 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0045
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	roomNameField          = "muc#roomconfig_roomname"
+	roomDescField          = "muc#roomconfig_roomdesc"
+	persistentRoomField    = "muc#roomconfig_persistentroom"
+	publicRoomField        = "muc#roomconfig_publicroom"
+	membersOnlyField       = "muc#roomconfig_membersonly"
+	moderatedRoomField     = "muc#roomconfig_moderatedroom"
+	passwordProtectedField = "muc#roomconfig_passwordprotectedroom"
+	roomSecretField        = "muc#roomconfig_roomsecret"
+	whoisField             = "muc#roomconfig_whois"
+	maxUsersField          = "muc#roomconfig_maxusers"
+	allowInvitesField      = "muc#roomconfig_allowinvites"
+	changeSubjectField     = "muc#roomconfig_changesubject"
+	enableLoggingField     = "muc#roomconfig_enablelogging"
+)
+
+func (m *Muc) processOwnerIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	r, err := m.lockRoom(ctx, iq.ToJID(), false)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	if r == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
+		return nil
+	}
+	defer m.unlockRoom(r)
+
+	if r.affiliation(iq.FromJID()) != ownerAffiliation {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return nil
+	}
+	q := iq.ChildNamespace("query", mucOwnerNamespace)
+	switch {
+	case iq.IsGet():
+		_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq,
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, mucOwnerNamespace).
+				WithChild(r.configForm().Element()).
+				Build(),
+		))
+		return nil
+
+	case q.Child("destroy") != nil:
+		return m.destroyRoom(ctx, r, iq, q.Child("destroy"))
+
+	case q.ChildNamespace("x", xep0004.FormNamespace) != nil:
+		return m.configureRoom(ctx, r, iq, q.ChildNamespace("x", xep0004.FormNamespace))
+
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+}
+
+func (m *Muc) configureRoom(ctx context.Context, r *room, iq *stravaganza.IQ, x stravaganza.Element) error {
+	form, err := xep0004.NewFormFromElement(x)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	switch form.Type {
+	case xep0004.Cancel:
+		if r.locked {
+			// cancelling initial configuration destroys the room
+			m.evacuateRoom(ctx, r, nil, "")
+		}
+		_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+		return nil
+
+	case xep0004.Submit:
+		break
+
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	cfg, err := applyConfigForm(r.cfg, form)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.NotAcceptable))
+		return nil
+	}
+	r.cfg = cfg
+	if err := m.storeRoom(ctx, r); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	wasLocked := r.locked
+	r.locked = false
+
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+
+	if !wasLocked {
+		m.notifyConfigChange(ctx, r)
+	}
+	level.Info(m.logger).Log("msg", "room configured", "room", r.jid.String(), "persistent", r.cfg.Persistent)
+	return nil
+}
+
+func (m *Muc) destroyRoom(ctx context.Context, r *room, iq *stravaganza.IQ, destroy stravaganza.Element) error {
+	if r.persisted {
+		if err := m.deleteStoredRoom(ctx, r); err != nil {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+			return err
+		}
+		r.persisted = false
+	}
+	if err := m.mam.DeleteArchive(ctx, r.jid.String()); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	var reason string
+	if rElem := destroy.Child("reason"); rElem != nil {
+		reason = rElem.Text()
+	}
+	m.evacuateRoom(ctx, r, destroy, reason)
+
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+
+	level.Info(m.logger).Log("msg", "room destroyed", "room", r.jid.String())
+	return nil
+}
+
+// evacuateRoom makes every occupant leave the room, which gets evicted once unlocked.
+func (m *Muc) evacuateRoom(ctx context.Context, r *room, destroy stravaganza.Element, reason string) {
+	destroyB := stravaganza.NewBuilder("destroy")
+	if destroy != nil {
+		if altJID := destroy.Attribute("jid"); len(altJID) > 0 {
+			destroyB.WithAttribute("jid", altJID)
+		}
+	}
+	if len(reason) > 0 {
+		destroyB.WithChild(stravaganza.NewBuilder("reason").WithText(reason).Build())
+	}
+	for _, occ := range r.occupants {
+		x := stravaganza.NewBuilder("x").
+			WithAttribute(stravaganza.Namespace, mucUserNamespace).
+			WithChildren(
+				stravaganza.NewBuilder("item").
+					WithAttribute("affiliation", noneAffiliation.String()).
+					WithAttribute("role", noneRole.String()).
+					Build(),
+				destroyB.Build(),
+			).
+			Build()
+		_, _ = m.router.Route(ctx, xmpputil.MakePresence(r.occupantJID(occ.nick), occ.jid, stravaganza.UnavailableType, []stravaganza.Element{x}))
+	}
+	r.occupants = nil
+}
+
+func (m *Muc) notifyConfigChange(ctx context.Context, r *room) {
+	for _, occ := range r.occupants {
+		msg, _ := stravaganza.NewMessageBuilder().
+			WithAttribute(stravaganza.From, r.jid.String()).
+			WithAttribute(stravaganza.To, occ.jid.String()).
+			WithAttribute(stravaganza.Type, stravaganza.GroupChatType).
+			WithChild(
+				stravaganza.NewBuilder("x").
+					WithAttribute(stravaganza.Namespace, mucUserNamespace).
+					WithChild(statusElement(statusConfigChanged)).
+					Build(),
+			).
+			BuildMessage()
+		_, _ = m.router.Route(ctx, msg)
+	}
+}
+
+func (r *room) configForm() *xep0004.DataForm {
+	whois := "moderators"
+	if r.cfg.NonAnonymous {
+		whois = "anyone"
+	}
+	return &xep0004.DataForm{
+		Type:  xep0004.Form,
+		Title: "Configuration for " + r.jid.String(),
+		Fields: []xep0004.Field{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{roomConfigNamespace}},
+			{Var: roomNameField, Type: xep0004.TextSingle, Label: "Natural-Language Room Name", Values: []string{r.cfg.Name}},
+			{Var: roomDescField, Type: xep0004.TextSingle, Label: "Short Description of Room", Values: []string{r.cfg.Description}},
+			{Var: persistentRoomField, Type: xep0004.Boolean, Label: "Make Room Persistent?", Values: []string{boolValue(r.cfg.Persistent)}},
+			{Var: publicRoomField, Type: xep0004.Boolean, Label: "Make Room Publicly Searchable?", Values: []string{boolValue(r.cfg.Public)}},
+			{Var: membersOnlyField, Type: xep0004.Boolean, Label: "Make Room Members-Only?", Values: []string{boolValue(r.cfg.MembersOnly)}},
+			{Var: moderatedRoomField, Type: xep0004.Boolean, Label: "Make Room Moderated?", Values: []string{boolValue(r.cfg.Moderated)}},
+			{Var: passwordProtectedField, Type: xep0004.Boolean, Label: "Password Required to Enter?", Values: []string{boolValue(len(r.cfg.Password) > 0)}},
+			{Var: roomSecretField, Type: xep0004.TextPrivate, Label: "Password", Values: []string{r.cfg.Password}},
+			{
+				Var:    whoisField,
+				Type:   xep0004.ListSingle,
+				Label:  "Who May Discover Real JIDs?",
+				Values: []string{whois},
+				Options: []xep0004.Option{
+					{Label: "Moderators Only", Value: "moderators"},
+					{Label: "Anyone", Value: "anyone"},
+				},
+			},
+			{Var: maxUsersField, Type: xep0004.TextSingle, Label: "Maximum Number of Occupants (0 for unlimited)", Values: []string{strconv.Itoa(int(r.cfg.MaxOccupants))}},
+			{Var: allowInvitesField, Type: xep0004.Boolean, Label: "Allow Occupants to Invite Others?", Values: []string{boolValue(r.cfg.AllowInvites)}},
+			{Var: changeSubjectField, Type: xep0004.Boolean, Label: "Allow Occupants to Change Subject?", Values: []string{boolValue(r.cfg.ChangeSubject)}},
+			{Var: enableLoggingField, Type: xep0004.Boolean, Label: "Archive Room Messages?", Values: []string{boolValue(r.cfg.Logging)}},
+		},
+	}
+}
+
+// applyConfigForm returns the result of applying submitted form values over cfg.
+// Fields not present in the form keep their current values.
+func applyConfigForm(cfg *mucmodel.RoomConfig, form *xep0004.DataForm) (*mucmodel.RoomConfig, error) {
+	retVal := &mucmodel.RoomConfig{
+		Name:          cfg.Name,
+		Description:   cfg.Description,
+		Persistent:    cfg.Persistent,
+		Public:        cfg.Public,
+		MembersOnly:   cfg.MembersOnly,
+		Moderated:     cfg.Moderated,
+		Password:      cfg.Password,
+		NonAnonymous:  cfg.NonAnonymous,
+		MaxOccupants:  cfg.MaxOccupants,
+		AllowInvites:  cfg.AllowInvites,
+		ChangeSubject: cfg.ChangeSubject,
+		Logging:       cfg.Logging,
+	}
+	passwordProtected := len(cfg.Password) > 0
+
+	for _, field := range form.Fields {
+		var value string
+		if len(field.Values) > 0 {
+			value = field.Values[0]
+		}
+		var err error
+		switch field.Var {
+		case roomNameField:
+			retVal.Name = value
+		case roomDescField:
+			retVal.Description = value
+		case persistentRoomField:
+			retVal.Persistent, err = strconv.ParseBool(value)
+		case publicRoomField:
+			retVal.Public, err = strconv.ParseBool(value)
+		case membersOnlyField:
+			retVal.MembersOnly, err = strconv.ParseBool(value)
+		case moderatedRoomField:
+			retVal.Moderated, err = strconv.ParseBool(value)
+		case passwordProtectedField:
+			passwordProtected, err = strconv.ParseBool(value)
+		case roomSecretField:
+			retVal.Password = value
+		case whoisField:
+			retVal.NonAnonymous = value == "anyone"
+		case maxUsersField:
+			var maxUsers int
+			maxUsers, err = strconv.Atoi(value)
+			retVal.MaxOccupants = int32(maxUsers)
+		case allowInvitesField:
+			retVal.AllowInvites, err = strconv.ParseBool(value)
+		case changeSubjectField:
+			retVal.ChangeSubject, err = strconv.ParseBool(value)
+		case enableLoggingField:
+			retVal.Logging, err = strconv.ParseBool(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !passwordProtected {
+		retVal.Password = ""
+	}
+	return retVal, nil
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
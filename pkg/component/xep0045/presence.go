@@ -0,0 +1,323 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0045
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	statusNonAnonymous    = 100
+	statusConfigChanged   = 104
+	statusSelfPresence    = 110
+	statusRoomCreated     = 201
+	statusBanned          = 301
+	statusNickChanged     = 303
+	statusKicked          = 307
+	statusAffiliationLost = 321
+)
+
+const historyTimeFormat = "2006-01-02T15:04:05Z"
+
+func (m *Muc) processPresence(ctx context.Context, pr *stravaganza.Presence) error {
+	toJID := pr.ToJID()
+	if len(toJID.Node()) == 0 || pr.IsError() {
+		return nil
+	}
+	if !pr.IsAvailable() && !pr.IsUnavailable() {
+		return nil
+	}
+	if len(toJID.Resource()) == 0 {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(pr, stanzaerror.JIDMalformed))
+		return nil
+	}
+	r, err := m.lockRoom(ctx, toJID, pr.IsAvailable())
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(pr, stanzaerror.InternalServerError))
+		return err
+	}
+	if r == nil {
+		return nil // unavailable presence to non existing room
+	}
+	defer m.unlockRoom(r)
+
+	occ := r.occupantByJID(pr.FromJID())
+	switch {
+	case occ == nil && pr.IsAvailable():
+		return m.joinRoom(ctx, r, pr)
+
+	case occ == nil:
+		return nil
+
+	case pr.IsUnavailable():
+		m.leaveRoom(ctx, r, occ, pr)
+		return nil
+
+	case occ.nick != toJID.Resource():
+		return m.changeNick(ctx, r, occ, pr)
+
+	default:
+		occ.presence = pr
+		m.broadcastPresence(ctx, r, occ, nil)
+		return nil
+	}
+}
+
+func (m *Muc) joinRoom(ctx context.Context, r *room, pr *stravaganza.Presence) error {
+	fromJID := pr.FromJID()
+	nick := pr.ToJID().Resource()
+
+	created := len(r.occupants) == 0 && r.locked
+	if created {
+		r.setAffiliation(fromJID, ownerAffiliation, "")
+	}
+	x := pr.ChildNamespace("x", mucNamespace)
+
+	if reason, ok := r.canJoin(fromJID, nick, x); !ok {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(pr, reason))
+		return nil
+	}
+	occ := &occupant{
+		nick:     nick,
+		jid:      fromJID,
+		role:     r.defaultRole(r.affiliation(fromJID)),
+		presence: pr,
+	}
+	// send current occupants presences to the new one
+	for _, o := range r.occupants {
+		_, _ = m.router.Route(ctx, r.occupantPresence(o, occ, o.presence.Type(), nil))
+	}
+	r.addOccupant(occ)
+
+	var selfCodes []int
+	if created {
+		selfCodes = append(selfCodes, statusRoomCreated)
+	}
+	m.broadcastPresence(ctx, r, occ, selfCodes)
+
+	if err := m.sendHistory(ctx, r, occ, x); err != nil {
+		return err
+	}
+	m.sendSubject(ctx, r, occ)
+
+	level.Info(m.logger).Log("msg", "occupant joined room", "room", r.jid.String(), "nick", nick, "jid", fromJID.String())
+	return nil
+}
+
+// canJoin tells whether userJID is allowed to enter the room using nick, returning the rejection reason otherwise.
+func (r *room) canJoin(userJID *jid.JID, nick string, x stravaganza.Element) (stanzaerror.Reason, bool) {
+	aff := r.affiliation(userJID)
+	switch {
+	case r.locked && aff != ownerAffiliation:
+		return stanzaerror.ItemNotFound, false
+	case aff == outcastAffiliation:
+		return stanzaerror.Forbidden, false
+	case r.cfg.MembersOnly && aff < memberAffiliation:
+		return stanzaerror.RegistrationRequired, false
+	case len(r.cfg.Password) > 0 && aff < ownerAffiliation && passwordFrom(x) != r.cfg.Password:
+		return stanzaerror.NotAuthorized, false
+	case r.occupantByNick(nick) != nil:
+		return stanzaerror.Conflict, false
+	case r.cfg.MaxOccupants > 0 && len(r.occupants) >= int(r.cfg.MaxOccupants) && aff < adminAffiliation:
+		return stanzaerror.ServiceUnavailable, false
+	}
+	return 0, true
+}
+
+func (m *Muc) leaveRoom(ctx context.Context, r *room, occ *occupant, pr *stravaganza.Presence) {
+	var children []stravaganza.Element
+	if pr != nil {
+		children = pr.AllChildren()
+	}
+	occ.presence = xmpputil.MakePresence(occ.jid, r.occupantJID(occ.nick), stravaganza.UnavailableType, children)
+	occ.role = noneRole
+
+	m.broadcastPresence(ctx, r, occ, nil)
+	r.removeOccupant(occ)
+
+	level.Info(m.logger).Log("msg", "occupant left room", "room", r.jid.String(), "nick", occ.nick, "jid", occ.jid.String())
+}
+
+func (m *Muc) changeNick(ctx context.Context, r *room, occ *occupant, pr *stravaganza.Presence) error {
+	newNick := pr.ToJID().Resource()
+	if r.occupantByNick(newNick) != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(pr, stanzaerror.Conflict))
+		return nil
+	}
+	// announce old nick unavailability
+	for _, o := range r.occupants {
+		_, _ = m.router.Route(ctx, r.nickChangePresence(occ, o, newNick))
+	}
+	occ.nick = newNick
+	occ.presence = pr
+	m.broadcastPresence(ctx, r, occ, nil)
+	return nil
+}
+
+// kickOccupant removes occ from the room notifying all occupants using the given status code.
+func (m *Muc) kickOccupant(ctx context.Context, r *room, occ *occupant, code int, reason string) {
+	occ.role = noneRole
+	occ.presence = xmpputil.MakePresence(occ.jid, r.occupantJID(occ.nick), stravaganza.UnavailableType, nil)
+
+	for _, o := range r.occupants {
+		_, _ = m.router.Route(ctx, r.occupantPresenceWithReason(occ, o, []int{code}, reason))
+	}
+	r.removeOccupant(occ)
+}
+
+// broadcastPresence sends occ presence to every room occupant.
+func (m *Muc) broadcastPresence(ctx context.Context, r *room, occ *occupant, selfCodes []int) {
+	for _, o := range r.occupants {
+		var codes []int
+		if o == occ {
+			codes = selfCodes
+		}
+		_, _ = m.router.Route(ctx, r.occupantPresence(occ, o, occ.presence.Type(), codes))
+	}
+}
+
+func (m *Muc) sendHistory(ctx context.Context, r *room, occ *occupant, x stravaganza.Element) error {
+	if !r.cfg.Logging || m.cfg.HistorySize <= 0 {
+		return nil
+	}
+	max := m.cfg.HistorySize
+	var since time.Time
+
+	if x != nil {
+		if h := x.Child("history"); h != nil {
+			if v, err := strconv.Atoi(h.Attribute("maxstanzas")); err == nil && v < max {
+				max = v
+			}
+			if v, err := strconv.Atoi(h.Attribute("seconds")); err == nil {
+				since = time.Now().Add(-time.Duration(v) * time.Second)
+			}
+			if v, err := time.Parse(historyTimeFormat, h.Attribute("since")); err == nil && v.After(since) {
+				since = v
+			}
+		}
+	}
+	if max <= 0 {
+		return nil
+	}
+	messages, err := m.mam.RecentMessages(ctx, r.jid.String(), since, max)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		stanza, err := stravaganza.NewBuilderFromProto(msg.Message).
+			WithAttribute(stravaganza.To, occ.jid.String()).
+			BuildStanza()
+		if err != nil {
+			continue
+		}
+		_, _ = m.router.Route(ctx, xmpputil.MakeDelayMessage(stanza, msg.Stamp.AsTime(), r.jid.String(), ""))
+	}
+	return nil
+}
+
+func (m *Muc) sendSubject(ctx context.Context, r *room, occ *occupant) {
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, r.jid.String()).
+		WithAttribute(stravaganza.To, occ.jid.String()).
+		WithAttribute(stravaganza.Type, stravaganza.GroupChatType).
+		WithChild(
+			stravaganza.NewBuilder("subject").
+				WithText(r.subject).
+				Build(),
+		).
+		BuildMessage()
+	_, _ = m.router.Route(ctx, msg)
+}
+
+// occupantPresence returns occ presence as seen by the receiver occupant.
+func (r *room) occupantPresence(occ, receiver *occupant, typ string, codes []int) *stravaganza.Presence {
+	itemB := r.itemBuilder(occ, receiver)
+	return r.buildPresence(occ, receiver, typ, itemB, codes)
+}
+
+// occupantPresenceWithReason returns occ unavailable presence including an item reason element.
+func (r *room) occupantPresenceWithReason(occ, receiver *occupant, codes []int, reason string) *stravaganza.Presence {
+	itemB := r.itemBuilder(occ, receiver)
+	if len(reason) > 0 {
+		itemB.WithChild(stravaganza.NewBuilder("reason").WithText(reason).Build())
+	}
+	return r.buildPresence(occ, receiver, stravaganza.UnavailableType, itemB, codes)
+}
+
+func (r *room) nickChangePresence(occ, receiver *occupant, newNick string) *stravaganza.Presence {
+	itemB := r.itemBuilder(occ, receiver).WithAttribute("nick", newNick)
+	return r.buildPresence(occ, receiver, stravaganza.UnavailableType, itemB, []int{statusNickChanged})
+}
+
+func (r *room) itemBuilder(occ, receiver *occupant) *stravaganza.Builder {
+	itemB := stravaganza.NewBuilder("item").
+		WithAttribute("affiliation", r.affiliation(occ.jid).String()).
+		WithAttribute("role", occ.role.String())
+	if r.cfg.NonAnonymous || receiver.role == moderatorRole || receiver == occ {
+		itemB.WithAttribute("jid", occ.jid.String())
+	}
+	return itemB
+}
+
+func (r *room) buildPresence(occ, receiver *occupant, typ string, itemB *stravaganza.Builder, codes []int) *stravaganza.Presence {
+	xB := stravaganza.NewBuilder("x").
+		WithAttribute(stravaganza.Namespace, mucUserNamespace).
+		WithChild(itemB.Build())
+
+	if receiver == occ {
+		if r.cfg.NonAnonymous {
+			codes = append(codes, statusNonAnonymous)
+		}
+		codes = append(codes, statusSelfPresence)
+	}
+	for _, code := range codes {
+		xB.WithChild(statusElement(code))
+	}
+	var children []stravaganza.Element
+	for _, child := range occ.presence.AllChildren() {
+		switch child.Attribute(stravaganza.Namespace) {
+		case mucNamespace, mucUserNamespace:
+			continue
+		}
+		children = append(children, child)
+	}
+	children = append(children, xB.Build())
+
+	return xmpputil.MakePresence(r.occupantJID(occ.nick), receiver.jid, typ, children)
+}
+
+func passwordFrom(x stravaganza.Element) string {
+	if x == nil {
+		return ""
+	}
+	if pwd := x.Child("password"); pwd != nil {
+		return pwd.Text()
+	}
+	return ""
+}
+
+func statusElement(code int) stravaganza.Element {
+	return stravaganza.NewBuilder("status").
+		WithAttribute("code", strconv.Itoa(code)).
+		Build()
+}
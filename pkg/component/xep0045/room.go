@@ -0,0 +1,337 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0045
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type affiliation int
+
+const (
+	outcastAffiliation affiliation = iota
+	noneAffiliation
+	memberAffiliation
+	adminAffiliation
+	ownerAffiliation
+)
+
+var affiliationNames = map[affiliation]string{
+	outcastAffiliation: "outcast",
+	noneAffiliation:    "none",
+	memberAffiliation:  "member",
+	adminAffiliation:   "admin",
+	ownerAffiliation:   "owner",
+}
+
+func (a affiliation) String() string { return affiliationNames[a] }
+
+func parseAffiliation(s string) (affiliation, bool) {
+	for a, name := range affiliationNames {
+		if name == s {
+			return a, true
+		}
+	}
+	return noneAffiliation, false
+}
+
+type role int
+
+const (
+	noneRole role = iota
+	visitorRole
+	participantRole
+	moderatorRole
+)
+
+var roleNames = map[role]string{
+	noneRole:        "none",
+	visitorRole:     "visitor",
+	participantRole: "participant",
+	moderatorRole:   "moderator",
+}
+
+func (r role) String() string { return roleNames[r] }
+
+func parseRole(s string) (role, bool) {
+	for r, name := range roleNames {
+		if name == s {
+			return r, true
+		}
+	}
+	return noneRole, false
+}
+
+type occupant struct {
+	nick     string
+	jid      *jid.JID
+	role     role
+	presence *stravaganza.Presence
+}
+
+type room struct {
+	mu sync.Mutex
+
+	jid          *jid.JID
+	cfg          *mucmodel.RoomConfig
+	subject      string
+	affiliations map[string]*mucmodel.Affiliation
+	occupants    []*occupant
+
+	locked    bool // newly created room awaiting owner configuration
+	persisted bool // room is stored in the repository
+	evicted   bool // room has been removed from the service room set
+}
+
+func newRoom(roomJID *jid.JID) *room {
+	return &room{
+		jid: roomJID,
+		cfg: &mucmodel.RoomConfig{
+			Public:  true,
+			Logging: true,
+		},
+		affiliations: make(map[string]*mucmodel.Affiliation),
+		locked:       true,
+	}
+}
+
+func (r *room) affiliation(userJID *jid.JID) affiliation {
+	aff := r.affiliations[userJID.ToBareJID().String()]
+	if aff == nil {
+		return noneAffiliation
+	}
+	a, _ := parseAffiliation(aff.Affiliation)
+	return a
+}
+
+func (r *room) setAffiliation(userJID *jid.JID, a affiliation, reason string) *mucmodel.Affiliation {
+	aff := &mucmodel.Affiliation{
+		RoomJid:     r.jid.String(),
+		Jid:         userJID.ToBareJID().String(),
+		Affiliation: a.String(),
+		Reason:      reason,
+	}
+	if a == noneAffiliation {
+		delete(r.affiliations, aff.Jid)
+	} else {
+		r.affiliations[aff.Jid] = aff
+	}
+	return aff
+}
+
+func (r *room) affiliationCount(a affiliation) int {
+	var count int
+	for _, aff := range r.affiliations {
+		if aff.Affiliation == a.String() {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *room) defaultRole(a affiliation) role {
+	switch {
+	case a >= adminAffiliation:
+		return moderatorRole
+	case a == memberAffiliation || !r.cfg.Moderated:
+		return participantRole
+	default:
+		return visitorRole
+	}
+}
+
+func (r *room) occupantByNick(nick string) *occupant {
+	for _, occ := range r.occupants {
+		if occ.nick == nick {
+			return occ
+		}
+	}
+	return nil
+}
+
+func (r *room) occupantByJID(userJID *jid.JID) *occupant {
+	for _, occ := range r.occupants {
+		if occ.jid.String() == userJID.String() {
+			return occ
+		}
+	}
+	return nil
+}
+
+func (r *room) occupantsByBareJID(userJID *jid.JID) []*occupant {
+	var retVal []*occupant
+	for _, occ := range r.occupants {
+		if occ.jid.MatchesWithOptions(userJID, jid.MatchesBare) {
+			retVal = append(retVal, occ)
+		}
+	}
+	return retVal
+}
+
+func (r *room) addOccupant(occ *occupant) {
+	r.occupants = append(r.occupants, occ)
+}
+
+func (r *room) removeOccupant(occ *occupant) {
+	for i, o := range r.occupants {
+		if o == occ {
+			r.occupants = append(r.occupants[:i], r.occupants[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *room) occupantJID(nick string) *jid.JID {
+	occJID, _ := jid.New(r.jid.Node(), r.jid.Domain(), nick, true)
+	return occJID
+}
+
+func (r *room) model() *mucmodel.Room {
+	return &mucmodel.Room{
+		Jid:     r.jid.String(),
+		Config:  r.cfg,
+		Subject: r.subject,
+	}
+}
+
+// lockRoom returns the locked room instance associated to roomJID, loading it from the repository when needed.
+// In case create is true a new room will be created if it doesn't exist yet.
+func (m *Muc) lockRoom(ctx context.Context, roomJID *jid.JID, create bool) (*room, error) {
+	roomJID = roomJID.ToBareJID()
+	for {
+		m.mu.Lock()
+		r := m.rooms[roomJID.String()]
+		if r != nil {
+			m.mu.Unlock()
+
+			r.mu.Lock()
+			if !r.evicted {
+				return r, nil
+			}
+			r.mu.Unlock() // evicted meanwhile... try again
+			continue
+		}
+		// register a locked placeholder, so that concurrent lookups wait for it to be loaded
+		// without holding the service lock during repository round trips.
+		r = newRoom(roomJID)
+		r.mu.Lock()
+		m.rooms[roomJID.String()] = r
+		m.mu.Unlock()
+
+		found, err := m.loadRoom(ctx, r)
+		if err != nil || (!found && !create) {
+			m.mu.Lock()
+			delete(m.rooms, roomJID.String())
+			m.mu.Unlock()
+			r.evicted = true
+			r.mu.Unlock()
+			return nil, err
+		}
+		return r, nil
+	}
+}
+
+// unlockRoom unlocks a previously locked room, evicting it from memory in case it became empty.
+func (m *Muc) unlockRoom(r *room) {
+	if len(r.occupants) == 0 && !r.evicted {
+		m.mu.Lock()
+		delete(m.rooms, r.jid.String())
+		m.mu.Unlock()
+		r.evicted = true
+	}
+	r.mu.Unlock()
+}
+
+// loadRoom fills r with its stored state, returning false in case it's not present in the repository.
+func (m *Muc) loadRoom(ctx context.Context, r *room) (bool, error) {
+	rm, err := m.rep.FetchRoom(ctx, r.jid.String())
+	if err != nil {
+		return false, err
+	}
+	if rm == nil {
+		return false, nil
+	}
+	affs, err := m.rep.FetchRoomAffiliations(ctx, r.jid.String())
+	if err != nil {
+		return false, err
+	}
+	r.cfg = rm.Config
+	r.subject = rm.Subject
+	r.affiliations = make(map[string]*mucmodel.Affiliation, len(affs))
+	r.locked = false
+	r.persisted = true
+
+	if r.cfg == nil {
+		r.cfg = &mucmodel.RoomConfig{}
+	}
+	for _, aff := range affs {
+		r.affiliations[aff.Jid] = aff
+	}
+	return true, nil
+}
+
+// storeRoom brings repository room state in line with its persistent configuration flag.
+func (m *Muc) storeRoom(ctx context.Context, r *room) error {
+	switch {
+	case r.cfg.Persistent:
+		err := m.rep.InTransaction(ctx, func(ctx context.Context, tx repository.Transaction) error {
+			if err := tx.UpsertRoom(ctx, r.model()); err != nil {
+				return err
+			}
+			for _, aff := range r.affiliations {
+				if err := tx.UpsertRoomAffiliation(ctx, aff); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		r.persisted = true
+
+	case r.persisted:
+		if err := m.deleteStoredRoom(ctx, r); err != nil {
+			return err
+		}
+		r.persisted = false
+	}
+	return nil
+}
+
+func (m *Muc) deleteStoredRoom(ctx context.Context, r *room) error {
+	return m.rep.InTransaction(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		if err := tx.DeleteRoomAffiliations(ctx, r.jid.String()); err != nil {
+			return err
+		}
+		return tx.DeleteRoom(ctx, r.jid.String())
+	})
+}
+
+func (m *Muc) storeAffiliation(ctx context.Context, r *room, aff *mucmodel.Affiliation) error {
+	if !r.persisted {
+		return nil
+	}
+	if aff.Affiliation == noneAffiliation.String() {
+		return m.rep.DeleteRoomAffiliation(ctx, aff.RoomJid, aff.Jid)
+	}
+	return m.rep.UpsertRoomAffiliation(ctx, aff)
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0060
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackal-xmpp/stravaganza/jid"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/module/xep0030"
+	pubsub "github.com/ortuman/jackal/pkg/module/xep0060"
+)
+
+// Identities satisfies xep0030.InfoProvider interface.
+func (c *PubSub) Identities(ctx context.Context, _, _ *jid.JID, node string) []discomodel.Identity {
+	if len(node) == 0 {
+		return []discomodel.Identity{{Category: "pubsub", Type: "service", Name: c.cfg.Name}}
+	}
+	n, _ := c.rep.FetchNode(ctx, c.cfg.Host, node)
+	if n == nil {
+		return nil
+	}
+	return []discomodel.Identity{{Category: "pubsub", Type: "leaf", Name: n.GetOptions().GetTitle()}}
+}
+
+// Items satisfies xep0030.InfoProvider interface.
+func (c *PubSub) Items(ctx context.Context, _, _ *jid.JID, node string) ([]discomodel.Item, error) {
+	if len(node) > 0 {
+		n, err := c.rep.FetchNode(ctx, c.cfg.Host, node)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			return nil, xep0030.ErrEntityNotFound
+		}
+		return nil, nil // items are retrieved using pubsub protocol
+	}
+	nodes, err := c.rep.FetchNodes(ctx, c.cfg.Host)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]discomodel.Item, 0, len(nodes))
+	for _, n := range nodes {
+		items = append(items, discomodel.Item{
+			Jid:  c.cfg.Host,
+			Node: n.Name,
+			Name: n.GetOptions().GetTitle(),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Node < items[j].Node })
+	return items, nil
+}
+
+// Features satisfies xep0030.InfoProvider interface.
+func (c *PubSub) Features(ctx context.Context, _, _ *jid.JID, node string) ([]discomodel.Feature, error) {
+	if len(node) > 0 {
+		n, err := c.rep.FetchNode(ctx, c.cfg.Host, node)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			return nil, xep0030.ErrEntityNotFound
+		}
+		return []discomodel.Feature{discoInfoNamespace, pubsub.Namespace}, nil
+	}
+	features := []discomodel.Feature{discoInfoNamespace, discoItemsNamespace, pubsub.Namespace}
+	return append(features, pubsub.Features...), nil
+}
+
+// Forms satisfies xep0030.InfoProvider interface.
+func (c *PubSub) Forms(_ context.Context, _, _ *jid.JID, _ string) ([]xep0004.DataForm, error) {
+	return nil, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0060
+
+import (
+	"context"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/host"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	"github.com/ortuman/jackal/pkg/module/xep0030"
+	pubsub "github.com/ortuman/jackal/pkg/module/xep0060"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	// ComponentName represents publish-subscribe component name.
+	ComponentName = "pubsub"
+
+	// XEPNumber represents publish-subscribe XEP number.
+	XEPNumber = "0060"
+
+	discoInfoNamespace  = "http://jabber.org/protocol/disco#info"
+	discoItemsNamespace = "http://jabber.org/protocol/disco#items"
+)
+
+// Config contains publish-subscribe component configuration options.
+type Config struct {
+	// Host is the publish-subscribe service domain (e.g. pubsub.jackal.im).
+	// An empty value disables the component.
+	Host string `fig:"host"`
+
+	// Name is the service name announced via service discovery.
+	Name string `fig:"name" default:"Publish-Subscribe"`
+
+	// MaxItems defines the default maximum number of items persisted per node.
+	MaxItems int64 `fig:"max_items" default:"10"`
+}
+
+// PubSub represents a publish-subscribe (XEP-0060) component type.
+//
+// Any local user is allowed to create nodes on the service, which are open by default.
+type PubSub struct {
+	cfg    Config
+	svc    *pubsub.Service
+	router router.Router
+	rep    repository.Repository
+	logger kitlog.Logger
+}
+
+// New returns a new initialized publish-subscribe component instance.
+func New(
+	cfg Config,
+	router router.Router,
+	hosts *host.Hosts,
+	rep repository.Repository,
+	logger kitlog.Logger,
+) *PubSub {
+	logger = kitlog.With(logger, "component", ComponentName, "xep", XEPNumber)
+	p := &policy{
+		hosts:    hosts,
+		maxItems: cfg.MaxItems,
+	}
+	return &PubSub{
+		cfg:    cfg,
+		svc:    pubsub.NewService(p, router, hosts, rep, logger),
+		router: router,
+		rep:    rep,
+		logger: logger,
+	}
+}
+
+// Host returns publish-subscribe component host domain.
+func (c *PubSub) Host() string { return c.cfg.Host }
+
+// Name returns publish-subscribe component name.
+func (c *PubSub) Name() string { return ComponentName }
+
+// ProcessStanza processes a stanza addressed to the publish-subscribe service.
+func (c *PubSub) ProcessStanza(ctx context.Context, stanza stravaganza.Stanza) error {
+	iq, ok := stanza.(*stravaganza.IQ)
+	if !ok {
+		return nil
+	}
+	if !iq.IsGet() && !iq.IsSet() {
+		return nil // ignore results and errors
+	}
+	toJID := iq.ToJID()
+	switch {
+	case len(toJID.Node()) > 0 || len(toJID.Resource()) > 0:
+		break
+
+	case iq.ChildNamespace("query", discoInfoNamespace) != nil, iq.ChildNamespace("query", discoItemsNamespace) != nil:
+		return xep0030.ProcessProviderIQ(ctx, c.router, c, iq)
+
+	case iq.ChildNamespace("pubsub", pubsub.Namespace) != nil, iq.ChildNamespace("pubsub", pubsub.OwnerNamespace) != nil:
+		return c.svc.ProcessIQ(ctx, c.cfg.Host, iq)
+	}
+	_, _ = c.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ServiceUnavailable))
+	return nil
+}
+
+// Start starts publish-subscribe component.
+func (c *PubSub) Start(_ context.Context) error {
+	level.Info(c.logger).Log("msg", "started pubsub component", "host", c.cfg.Host)
+	return nil
+}
+
+// Stop stops publish-subscribe component.
+func (c *PubSub) Stop(_ context.Context) error {
+	level.Info(c.logger).Log("msg", "stopped pubsub component", "host", c.cfg.Host)
+	return nil
+}
+
+// policy defines publish-subscribe component service behavior.
+type policy struct {
+	hosts    hosts
+	maxItems int64
+}
+
+// CanCreateNode satisfies xep0060.Policy interface.
+func (p *policy) CanCreateNode(_ context.Context, _ string, jd *jid.JID) bool {
+	return p.hosts.IsLocalHost(jd.Domain())
+}
+
+// AutoCreate satisfies xep0060.Policy interface.
+func (p *policy) AutoCreate() bool { return false }
+
+// DefaultNodeOptions satisfies xep0060.Policy interface.
+func (p *policy) DefaultNodeOptions() *pubsubmodel.Options {
+	return &pubsubmodel.Options{
+		AccessModel:           pubsub.OpenAccessModel,
+		PublishModel:          pubsub.PublishersPublishModel,
+		MaxItems:              p.maxItems,
+		PersistItems:          true,
+		DeliverPayloads:       true,
+		NotifyRetract:         true,
+		NotifyDelete:          true,
+		SendLastPublishedItem: pubsub.SendLastOnSub,
+	}
+}
+
+// ImplicitSubscribers satisfies xep0060.Policy interface.
+func (p *policy) ImplicitSubscribers(_ context.Context, _ *pubsubmodel.Node) ([]*jid.JID, error) {
+	return nil, nil
+}
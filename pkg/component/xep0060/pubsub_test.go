@@ -0,0 +1,187 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0060
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	pubsub "github.com/ortuman/jackal/pkg/module/xep0060"
+	"github.com/stretchr/testify/require"
+)
+
+type testRouter struct {
+	mu      sync.Mutex
+	stanzas []stravaganza.Stanza
+}
+
+func (r *testRouter) route(_ context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stanzas = append(r.stanzas, stanza)
+	return nil, nil
+}
+
+func (r *testRouter) flush() []stravaganza.Stanza {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := r.stanzas
+	r.stanzas = nil
+	return ret
+}
+
+func TestPubSub_CreateNode(t *testing.T) {
+	// given
+	c, rtr, repMock := testPubSub()
+
+	var created *pubsubmodel.Node
+	repMock.FetchNodeFunc = func(ctx context.Context, host, name string) (*pubsubmodel.Node, error) {
+		return nil, nil
+	}
+	repMock.UpsertNodeFunc = func(ctx context.Context, node *pubsubmodel.Node) error {
+		created = node
+		return nil
+	}
+
+	// when
+	_ = c.ProcessStanza(context.Background(), testCreateIQ("ortuman@jackal.im/yard", "pubsub.jackal.im", "princely_musings"))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+
+	require.NotNil(t, created)
+	require.Equal(t, "pubsub.jackal.im", created.Host)
+	require.Equal(t, "princely_musings", created.Name)
+	require.Equal(t, pubsub.OpenAccessModel, created.Options.AccessModel)
+	require.Equal(t, int64(10), created.Options.MaxItems)
+}
+
+func TestPubSub_CreateNodeForbidden(t *testing.T) {
+	// given
+	c, rtr, repMock := testPubSub()
+	repMock.FetchNodeFunc = func(ctx context.Context, host, name string) (*pubsubmodel.Node, error) {
+		return nil, nil
+	}
+
+	// when
+	_ = c.ProcessStanza(context.Background(), testCreateIQ("juliet@capulet.lit/balcony", "pubsub.jackal.im", "princely_musings"))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+	require.NotNil(t, stanzas[0].Child("error").Child("forbidden"))
+	require.Len(t, repMock.UpsertNodeCalls(), 0)
+}
+
+func TestPubSub_DiscoItems(t *testing.T) {
+	// given
+	c, rtr, repMock := testPubSub()
+	repMock.FetchNodesFunc = func(ctx context.Context, host string) ([]*pubsubmodel.Node, error) {
+		return []*pubsubmodel.Node{
+			{Host: host, Name: "tybalt", Options: &pubsubmodel.Options{}},
+			{Host: host, Name: "princely_musings", Options: &pubsubmodel.Options{Title: "Princely Musings"}},
+		}, nil
+	}
+
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "items-1").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "pubsub.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, discoItemsNamespace).
+				Build(),
+		).
+		BuildIQ()
+
+	// when
+	_ = c.ProcessStanza(context.Background(), iq)
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+
+	items := stanzas[0].ChildNamespace("query", discoItemsNamespace).Children("item")
+	require.Len(t, items, 2)
+	require.Equal(t, "princely_musings", items[0].Attribute("node"))
+	require.Equal(t, "Princely Musings", items[0].Attribute("name"))
+	require.Equal(t, "tybalt", items[1].Attribute("node"))
+}
+
+func TestPubSub_AddressedToNode(t *testing.T) {
+	// given
+	c, rtr, _ := testPubSub()
+
+	// when
+	_ = c.ProcessStanza(context.Background(), testCreateIQ("ortuman@jackal.im/yard", "node@pubsub.jackal.im", "princely_musings"))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+	require.NotNil(t, stanzas[0].Child("error").Child("service-unavailable"))
+}
+
+func testPubSub() (*PubSub, *testRouter, *repositoryMock) {
+	rtr := &testRouter{}
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = rtr.route
+
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+	repMock := &repositoryMock{}
+
+	cfg := Config{Host: "pubsub.jackal.im", Name: "Publish-Subscribe", MaxItems: 10}
+	p := &policy{hosts: hostsMock, maxItems: cfg.MaxItems}
+	c := &PubSub{
+		cfg:    cfg,
+		svc:    pubsub.NewService(p, routerMock, hostsMock, repMock, kitlog.NewNopLogger()),
+		router: routerMock,
+		rep:    repMock,
+		logger: kitlog.NewNopLogger(),
+	}
+	return c, rtr, repMock
+}
+
+func testCreateIQ(from, to, node string) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "create-1").
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, to).
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("pubsub").
+				WithAttribute(stravaganza.Namespace, pubsub.Namespace).
+				WithChild(
+					stravaganza.NewBuilder("create").
+						WithAttribute("node", node).
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackal-xmpp/stravaganza/jid"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/module/xep0030"
+)
+
+// Identities satisfies xep0030.InfoProvider interface.
+func (u *Upload) Identities(_ context.Context, _, _ *jid.JID, node string) []discomodel.Identity {
+	if len(node) > 0 {
+		return nil
+	}
+	return []discomodel.Identity{{Category: "store", Type: "file", Name: u.cfg.Name}}
+}
+
+// Items satisfies xep0030.InfoProvider interface.
+func (u *Upload) Items(_ context.Context, _, _ *jid.JID, node string) ([]discomodel.Item, error) {
+	if len(node) > 0 {
+		return nil, xep0030.ErrEntityNotFound
+	}
+	return nil, nil
+}
+
+// Features satisfies xep0030.InfoProvider interface.
+func (u *Upload) Features(_ context.Context, _, _ *jid.JID, node string) ([]discomodel.Feature, error) {
+	if len(node) > 0 {
+		return nil, xep0030.ErrEntityNotFound
+	}
+	return []discomodel.Feature{discoInfoNamespace, uploadNamespace}, nil
+}
+
+// Forms satisfies xep0030.InfoProvider interface.
+func (u *Upload) Forms(_ context.Context, _, _ *jid.JID, node string) ([]xep0004.DataForm, error) {
+	if len(node) > 0 || u.cfg.MaxFileSize <= 0 {
+		return nil, nil
+	}
+	return []xep0004.DataForm{{
+		Type: xep0004.Result,
+		Fields: []xep0004.Field{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{uploadNamespace}},
+			{Var: "max-file-size", Values: []string{strconv.FormatInt(u.cfg.MaxFileSize, 10)}},
+		},
+	}}, nil
+}
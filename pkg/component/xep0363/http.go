@@ -0,0 +1,178 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// ServeHTTP satisfies http.Handler interface.
+// Files are uploaded through PUT requests to a previously assigned slot URL and retrieved using GET.
+func (u *Upload) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, HEAD, GET, PUT")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPut:
+		u.handlePut(w, r)
+
+	case http.MethodGet, http.MethodHead:
+		u.handleGet(w, r)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (u *Upload) handlePut(w http.ResponseWriter, r *http.Request) {
+	slotPath, ok := parseSlotPath(r.URL.Path)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	s, status := u.acquireSlot(slotPath, r)
+	if s == nil {
+		w.WriteHeader(status)
+		return
+	}
+	filePath := filepath.Join(u.cfg.Dir, filepath.FromSlash(slotPath))
+	if _, err := os.Stat(filePath); err == nil {
+		u.releaseSlot(slotPath, false)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err := u.storeFile(filePath, r.Body, s.size); err != nil {
+		u.releaseSlot(slotPath, false)
+		level.Warn(u.logger).Log("msg", "failed to store uploaded file", "jid", s.owner, "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	u.releaseSlot(slotPath, true) // a slot can only be used once
+	level.Info(u.logger).Log("msg", "file uploaded", "jid", s.owner, "size", s.size)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// acquireSlot validates r against the slot assigned to slotPath and marks it as being uploaded.
+// In case of failure a nil slot is returned along with the HTTP status code to reply with.
+func (u *Upload) acquireSlot(slotPath string, r *http.Request) (*slot, int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	s := u.slots[slotPath]
+	switch {
+	case s == nil || !time.Now().Before(s.expiresAt):
+		return nil, http.StatusForbidden
+	case s.uploading:
+		return nil, http.StatusConflict
+	case r.ContentLength != s.size:
+		return nil, http.StatusBadRequest
+	case len(s.contentType) > 0 && r.Header.Get("Content-Type") != s.contentType:
+		return nil, http.StatusBadRequest
+	}
+	s.uploading = true
+	return s, 0
+}
+
+// releaseSlot removes the slot assigned to slotPath once its file has been stored,
+// otherwise it is left available for a new upload attempt.
+func (u *Upload) releaseSlot(slotPath string, consumed bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if consumed {
+		delete(u.slots, slotPath)
+		return
+	}
+	if s := u.slots[slotPath]; s != nil {
+		s.uploading = false
+	}
+}
+
+func (u *Upload) storeFile(filePath string, r io.Reader, size int64) error {
+	tmp, err := os.CreateTemp(filepath.Join(u.cfg.Dir, tmpDirName), "upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	n, err := io.Copy(tmp, io.LimitReader(r, size))
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if n != size {
+		return errors.New("xep0363: unexpected end of file")
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o700); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+func (u *Upload) handleGet(w http.ResponseWriter, r *http.Request) {
+	slotPath, ok := parseSlotPath(r.URL.Path)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(filepath.Join(u.cfg.Dir, filepath.FromSlash(slotPath)))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+}
+
+// parseSlotPath validates a request path and returns its slot relative path (user/id/filename).
+func parseSlotPath(p string) (string, bool) {
+	segments := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	if len(segments) != 3 {
+		return "", false
+	}
+	for _, s := range segments[:2] {
+		if len(s) == 0 || strings.HasPrefix(s, ".") {
+			return "", false
+		}
+	}
+	if !isValidFilename(segments[2]) {
+		return "", false
+	}
+	return path.Join(segments...), true
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const tmpDirName = ".tmp"
+
+func (u *Upload) initDir() error {
+	return os.MkdirAll(filepath.Join(u.cfg.Dir, tmpDirName), 0o700)
+}
+
+// usage returns the total number of bytes stored in a user directory.
+func (u *Upload) usage(userDir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(filepath.Join(u.cfg.Dir, userDir), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += fi.Size()
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return total, err
+}
+
+// expireFiles removes every uploaded file older than configured expiry, along with
+// the directories left empty.
+func (u *Upload) expireFiles() error {
+	if u.cfg.Expiry <= 0 {
+		return nil
+	}
+	deadline := time.Now().Add(-u.cfg.Expiry)
+
+	userDirs, err := os.ReadDir(u.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	for _, userDir := range userDirs {
+		if !userDir.IsDir() {
+			continue
+		}
+		userPath := filepath.Join(u.cfg.Dir, userDir.Name())
+		if userDir.Name() == tmpDirName {
+			// stale partial uploads
+			if err := removeOlderThan(userPath, deadline); err != nil {
+				return err
+			}
+			continue
+		}
+		slotDirs, err := os.ReadDir(userPath)
+		if err != nil {
+			return err
+		}
+		for _, slotDir := range slotDirs {
+			slotPath := filepath.Join(userPath, slotDir.Name())
+			if err := removeOlderThan(slotPath, deadline); err != nil {
+				return err
+			}
+			_ = os.Remove(slotPath) // only succeeds if empty
+		}
+		_ = os.Remove(userPath)
+	}
+	return nil
+}
+
+func removeOlderThan(dir string, deadline time.Time) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().Before(deadline) {
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/ortuman/jackal/pkg/host"
+	"github.com/ortuman/jackal/pkg/module/xep0030"
+	"github.com/ortuman/jackal/pkg/router"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	// ComponentName represents HTTP file upload component name.
+	ComponentName = "upload"
+
+	// XEPNumber represents HTTP file upload XEP number.
+	XEPNumber = "0363"
+
+	uploadNamespace = "urn:xmpp:http:upload:0"
+
+	discoInfoNamespace  = "http://jabber.org/protocol/disco#info"
+	discoItemsNamespace = "http://jabber.org/protocol/disco#items"
+)
+
+// Config contains HTTP file upload component configuration options.
+type Config struct {
+	// Host is the upload service domain (e.g. upload.jackal.im).
+	// An empty value disables the component.
+	Host string `fig:"host"`
+
+	// Name is the service name announced via service discovery.
+	Name string `fig:"name" default:"HTTP File Upload"`
+
+	// Port is the HTTP listener port used to serve uploaded files.
+	Port int `fig:"port" default:"5443"`
+
+	// URL is the public base URL handed out in upload slots (e.g. https://upload.jackal.im:5443).
+	// If not set, it will be derived from Host and Port.
+	URL string `fig:"url"`
+
+	// TLS contains the certificate used by the HTTP listener.
+	// If not set, files will be served over plain HTTP (i.e. behind a TLS terminating proxy).
+	TLS struct {
+		CertFile       string `fig:"cert_file"`
+		PrivateKeyFile string `fig:"privkey_file"`
+	} `fig:"tls"`
+
+	// Dir is the local directory where uploaded files are stored.
+	Dir string `fig:"dir" default:"upload"`
+
+	// MaxFileSize defines the maximum size in bytes of an uploaded file.
+	MaxFileSize int64 `fig:"max_file_size" default:"10485760"`
+
+	// Quota defines the maximum number of bytes stored per user. A zero value disables quotas.
+	Quota int64 `fig:"quota" default:"104857600"`
+
+	// SlotTimeout defines how long a requested upload slot remains valid.
+	SlotTimeout time.Duration `fig:"slot_timeout" default:"5m"`
+
+	// Expiry defines how long uploaded files are kept. A zero value keeps them forever.
+	Expiry time.Duration `fig:"expiry" default:"720h"`
+
+	// ExpiryInterval defines how often expired files are removed.
+	ExpiryInterval time.Duration `fig:"expiry_interval" default:"1h"`
+}
+
+// BaseURL returns the public base URL of the upload service.
+func (c Config) BaseURL() string {
+	if len(c.URL) > 0 {
+		return strings.TrimSuffix(c.URL, "/")
+	}
+	scheme := "http"
+	if len(c.TLS.CertFile) > 0 {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, c.Host, c.Port)
+}
+
+type slot struct {
+	owner       string
+	size        int64
+	contentType string
+	expiresAt   time.Time
+	uploading   bool
+}
+
+// Upload represents an HTTP file upload (XEP-0363) component type.
+//
+// Upload slots are kept in memory, so in a clustered deployment the slot PUT request is expected
+// to reach the same node that handed it out.
+type Upload struct {
+	cfg    Config
+	router router.Router
+	hosts  hosts
+	logger kitlog.Logger
+
+	mu     sync.Mutex
+	slots  map[string]*slot
+	doneCh chan chan struct{}
+}
+
+// New returns a new initialized HTTP file upload component instance.
+func New(
+	cfg Config,
+	router router.Router,
+	hosts *host.Hosts,
+	logger kitlog.Logger,
+) *Upload {
+	return &Upload{
+		cfg:    cfg,
+		router: router,
+		hosts:  hosts,
+		logger: kitlog.With(logger, "component", ComponentName, "xep", XEPNumber),
+		slots:  make(map[string]*slot),
+		doneCh: make(chan chan struct{}),
+	}
+}
+
+// Host returns HTTP file upload component host domain.
+func (u *Upload) Host() string { return u.cfg.Host }
+
+// Name returns HTTP file upload component name.
+func (u *Upload) Name() string { return ComponentName }
+
+// ProcessStanza processes a stanza addressed to the HTTP file upload service.
+func (u *Upload) ProcessStanza(ctx context.Context, stanza stravaganza.Stanza) error {
+	iq, ok := stanza.(*stravaganza.IQ)
+	if !ok {
+		return nil
+	}
+	if !iq.IsGet() && !iq.IsSet() {
+		return nil // ignore results and errors
+	}
+	toJID := iq.ToJID()
+	switch {
+	case len(toJID.Node()) > 0 || len(toJID.Resource()) > 0:
+		break
+
+	case iq.ChildNamespace("query", discoInfoNamespace) != nil, iq.ChildNamespace("query", discoItemsNamespace) != nil:
+		return xep0030.ProcessProviderIQ(ctx, u.router, u, iq)
+
+	case iq.IsGet() && iq.ChildNamespace("request", uploadNamespace) != nil:
+		return u.requestSlot(ctx, iq)
+	}
+	_, _ = u.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ServiceUnavailable))
+	return nil
+}
+
+// Start starts HTTP file upload component.
+func (u *Upload) Start(_ context.Context) error {
+	if err := u.initDir(); err != nil {
+		return err
+	}
+	go u.expireLoop()
+
+	level.Info(u.logger).Log("msg", "started upload component", "host", u.cfg.Host, "dir", u.cfg.Dir)
+	return nil
+}
+
+// Stop stops HTTP file upload component.
+func (u *Upload) Stop(_ context.Context) error {
+	ch := make(chan struct{})
+	u.doneCh <- ch
+	<-ch
+
+	level.Info(u.logger).Log("msg", "stopped upload component", "host", u.cfg.Host)
+	return nil
+}
+
+func (u *Upload) requestSlot(ctx context.Context, iq *stravaganza.IQ) error {
+	fromJID := iq.FromJID()
+	if !u.hosts.IsLocalHost(fromJID.Domain()) {
+		_, _ = u.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return nil
+	}
+	req := iq.ChildNamespace("request", uploadNamespace)
+
+	filename := req.Attribute("filename")
+	size, err := strconv.ParseInt(req.Attribute("size"), 10, 64)
+	if !isValidFilename(filename) || err != nil || size <= 0 {
+		_, _ = u.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	if u.cfg.MaxFileSize > 0 && size > u.cfg.MaxFileSize {
+		se := stanzaerror.E(stanzaerror.NotAcceptable, iq)
+		se.Text = "File too large"
+		se.ApplicationElement = stravaganza.NewBuilder("file-too-large").
+			WithAttribute(stravaganza.Namespace, uploadNamespace).
+			WithChild(
+				stravaganza.NewBuilder("max-file-size").
+					WithText(strconv.FormatInt(u.cfg.MaxFileSize, 10)).
+					Build(),
+			).
+			Build()
+		errStanza, _ := se.Stanza(false)
+		_, _ = u.router.Route(ctx, errStanza)
+		return nil
+	}
+	owner := fromJID.ToBareJID().String()
+	userDir := userDirName(owner)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.cfg.Quota > 0 {
+		usage, err := u.usage(userDir)
+		if err != nil {
+			_, _ = u.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+			return err
+		}
+		if usage+u.reservedSize(owner)+size > u.cfg.Quota {
+			se := stanzaerror.E(stanzaerror.ResourceConstraint, iq)
+			se.Text = "Quota exceeded"
+			errStanza, _ := se.Stanza(false)
+			_, _ = u.router.Route(ctx, errStanza)
+			return nil
+		}
+	}
+	slotPath := path.Join(userDir, uuid.New().String(), filename)
+	u.slots[slotPath] = &slot{
+		owner:       owner,
+		size:        size,
+		contentType: req.Attribute("content-type"),
+		expiresAt:   time.Now().Add(u.cfg.SlotTimeout),
+	}
+	slotURL := u.cfg.BaseURL() + "/" + escapePath(slotPath)
+
+	_, _ = u.router.Route(ctx, xmpputil.MakeResultIQ(iq,
+		stravaganza.NewBuilder("slot").
+			WithAttribute(stravaganza.Namespace, uploadNamespace).
+			WithChild(stravaganza.NewBuilder("put").WithAttribute("url", slotURL).Build()).
+			WithChild(stravaganza.NewBuilder("get").WithAttribute("url", slotURL).Build()).
+			Build(),
+	))
+	level.Info(u.logger).Log("msg", "upload slot assigned", "jid", owner, "size", size)
+	return nil
+}
+
+// reservedSize returns the total size of the pending upload slots assigned to owner.
+// This method should be called with mu held.
+func (u *Upload) reservedSize(owner string) int64 {
+	var reserved int64
+	now := time.Now()
+	for _, s := range u.slots {
+		if s.owner == owner && now.Before(s.expiresAt) {
+			reserved += s.size
+		}
+	}
+	return reserved
+}
+
+func (u *Upload) expireLoop() {
+	interval := u.cfg.ExpiryInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	tc := time.NewTicker(interval)
+	defer tc.Stop()
+
+	for {
+		select {
+		case <-tc.C:
+			u.expireSlots()
+			if err := u.expireFiles(); err != nil {
+				level.Warn(u.logger).Log("msg", "failed to expire uploaded files", "err", err)
+			}
+
+		case ch := <-u.doneCh:
+			close(ch)
+			return
+		}
+	}
+}
+
+func (u *Upload) expireSlots() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := time.Now()
+	for slotPath, s := range u.slots {
+		if !s.uploading && !now.Before(s.expiresAt) {
+			delete(u.slots, slotPath)
+		}
+	}
+}
+
+// userDirName returns the directory name where files uploaded by owner are stored.
+// Bare JIDs are hashed in order to avoid disclosing them in handed out URLs.
+func userDirName(owner string) string {
+	h := sha256.Sum256([]byte(owner))
+	return hex.EncodeToString(h[:16])
+}
+
+func isValidFilename(filename string) bool {
+	switch {
+	case len(filename) == 0, filename == ".", filename == "..":
+		return false
+	case strings.ContainsAny(filename, "/\\\x00"):
+		return false
+	}
+	return true
+}
+
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,296 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/stretchr/testify/require"
+)
+
+type testRouter struct {
+	mu      sync.Mutex
+	stanzas []stravaganza.Stanza
+}
+
+func (r *testRouter) route(_ context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stanzas = append(r.stanzas, stanza)
+	return nil, nil
+}
+
+func (r *testRouter) flush() []stravaganza.Stanza {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := r.stanzas
+	r.stanzas = nil
+	return ret
+}
+
+func TestUpload_RequestSlot(t *testing.T) {
+	// given
+	u, rtr := testUpload(t)
+
+	// when
+	_ = u.ProcessStanza(context.Background(), testSlotRequestIQ("ortuman@jackal.im/yard", "my picture.jpg", 1024))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+
+	slt := stanzas[0].ChildNamespace("slot", uploadNamespace)
+	require.NotNil(t, slt)
+
+	putURL := slt.Child("put").Attribute("url")
+	require.True(t, strings.HasPrefix(putURL, "https://upload.jackal.im/"))
+	require.True(t, strings.HasSuffix(putURL, "/my%20picture.jpg"))
+	require.Equal(t, putURL, slt.Child("get").Attribute("url"))
+	require.Len(t, u.slots, 1)
+}
+
+func TestUpload_RequestSlotFileTooLarge(t *testing.T) {
+	// given
+	u, rtr := testUpload(t)
+
+	// when
+	_ = u.ProcessStanza(context.Background(), testSlotRequestIQ("ortuman@jackal.im/yard", "movie.mp4", 4096))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+
+	errElem := stanzas[0].Child("error")
+	require.NotNil(t, errElem.Child("not-acceptable"))
+	require.Equal(t, "2048", errElem.ChildNamespace("file-too-large", uploadNamespace).Child("max-file-size").Text())
+	require.Len(t, u.slots, 0)
+}
+
+func TestUpload_RequestSlotQuotaExceeded(t *testing.T) {
+	// given
+	u, rtr := testUpload(t)
+
+	// when
+	_ = u.ProcessStanza(context.Background(), testSlotRequestIQ("ortuman@jackal.im/yard", "a.jpg", 2000))
+	_ = u.ProcessStanza(context.Background(), testSlotRequestIQ("ortuman@jackal.im/yard", "b.jpg", 2000))
+	_ = u.ProcessStanza(context.Background(), testSlotRequestIQ("noelia@jackal.im/yard", "b.jpg", 2000))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 3)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+	require.Equal(t, stravaganza.ErrorType, stanzas[1].Type())
+	require.NotNil(t, stanzas[1].Child("error").Child("resource-constraint"))
+	require.Equal(t, stravaganza.ResultType, stanzas[2].Type())
+}
+
+func TestUpload_RequestSlotRemoteUser(t *testing.T) {
+	// given
+	u, rtr := testUpload(t)
+
+	// when
+	_ = u.ProcessStanza(context.Background(), testSlotRequestIQ("juliet@capulet.lit/balcony", "a.jpg", 1024))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.NotNil(t, stanzas[0].Child("error").Child("forbidden"))
+}
+
+func TestUpload_PutAndGet(t *testing.T) {
+	// given
+	u, rtr := testUpload(t)
+
+	_ = u.ProcessStanza(context.Background(), testSlotRequestIQ("ortuman@jackal.im/yard", "hello.txt", 11))
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+
+	slotURL, _ := url.Parse(stanzas[0].ChildNamespace("slot", uploadNamespace).Child("put").Attribute("url"))
+
+	srv := httptest.NewServer(u)
+	defer srv.Close()
+
+	// when
+	putReq, _ := http.NewRequest(http.MethodPut, srv.URL+slotURL.EscapedPath(), bytes.NewReader([]byte("hello world")))
+	putResp, err := http.DefaultClient.Do(putReq)
+	require.NoError(t, err)
+	_ = putResp.Body.Close()
+
+	secondPutReq, _ := http.NewRequest(http.MethodPut, srv.URL+slotURL.EscapedPath(), bytes.NewReader([]byte("hello world")))
+	secondPutResp, err := http.DefaultClient.Do(secondPutReq)
+	require.NoError(t, err)
+	_ = secondPutResp.Body.Close()
+
+	getResp, err := http.Get(srv.URL + slotURL.EscapedPath())
+	require.NoError(t, err)
+	b, _ := io.ReadAll(getResp.Body)
+	_ = getResp.Body.Close()
+
+	// then
+	require.Equal(t, http.StatusCreated, putResp.StatusCode)
+	require.Equal(t, http.StatusForbidden, secondPutResp.StatusCode)
+	require.Equal(t, http.StatusOK, getResp.StatusCode)
+	require.Equal(t, "hello world", string(b))
+	require.Equal(t, "nosniff", getResp.Header.Get("X-Content-Type-Options"))
+}
+
+func TestUpload_PutSizeMismatch(t *testing.T) {
+	// given
+	u, rtr := testUpload(t)
+
+	_ = u.ProcessStanza(context.Background(), testSlotRequestIQ("ortuman@jackal.im/yard", "hello.txt", 64))
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+
+	slotURL, _ := url.Parse(stanzas[0].ChildNamespace("slot", uploadNamespace).Child("put").Attribute("url"))
+
+	// when
+	rec := httptest.NewRecorder()
+	u.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, slotURL.EscapedPath(), bytes.NewReader([]byte("hello world"))))
+
+	// then
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	usage, err := u.usage(userDirName("ortuman@jackal.im"))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), usage)
+}
+
+func TestUpload_PutRejectedKeepsSlot(t *testing.T) {
+	// given
+	u, rtr := testUpload(t)
+
+	_ = u.ProcessStanza(context.Background(), testSlotRequestIQ("ortuman@jackal.im/yard", "hello.txt", 11))
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+
+	slotURL, _ := url.Parse(stanzas[0].ChildNamespace("slot", uploadNamespace).Child("put").Attribute("url"))
+
+	// when
+	rejectedRec := httptest.NewRecorder()
+	u.ServeHTTP(rejectedRec, httptest.NewRequest(http.MethodPut, slotURL.EscapedPath(), bytes.NewReader([]byte("hello"))))
+
+	rec := httptest.NewRecorder()
+	u.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, slotURL.EscapedPath(), bytes.NewReader([]byte("hello world"))))
+
+	// then
+	require.Equal(t, http.StatusBadRequest, rejectedRec.Code)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Len(t, u.slots, 0)
+
+	usage, err := u.usage(userDirName("ortuman@jackal.im"))
+	require.NoError(t, err)
+	require.Equal(t, int64(11), usage)
+}
+
+func TestUpload_GetInvalidPath(t *testing.T) {
+	// given
+	u, _ := testUpload(t)
+	require.NoError(t, os.WriteFile(filepath.Join(u.cfg.Dir, "secret.txt"), []byte("secret"), 0o600))
+
+	// when
+	rec := httptest.NewRecorder()
+	u.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/../secret.txt", nil))
+
+	// then
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestUpload_ExpireFiles(t *testing.T) {
+	// given
+	u, _ := testUpload(t)
+
+	oldFile := filepath.Join(u.cfg.Dir, "u1", "s1", "old.txt")
+	newFile := filepath.Join(u.cfg.Dir, "u1", "s2", "new.txt")
+	for _, f := range []string{oldFile, newFile} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(f), 0o700))
+		require.NoError(t, os.WriteFile(f, []byte("data"), 0o600))
+	}
+	past := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(oldFile, past, past))
+
+	// when
+	err := u.expireFiles()
+
+	// then
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Dir(oldFile))
+	require.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(newFile)
+	require.NoError(t, err)
+}
+
+func testUpload(t *testing.T) (*Upload, *testRouter) {
+	rtr := &testRouter{}
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = rtr.route
+
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+	u := &Upload{
+		cfg: Config{
+			Host:        "upload.jackal.im",
+			URL:         "https://upload.jackal.im/",
+			Dir:         t.TempDir(),
+			MaxFileSize: 2048,
+			Quota:       3000,
+			SlotTimeout: time.Minute,
+			Expiry:      24 * time.Hour,
+		},
+		router: routerMock,
+		hosts:  hostsMock,
+		logger: kitlog.NewNopLogger(),
+		slots:  make(map[string]*slot),
+	}
+	require.NoError(t, u.initDir())
+	return u, rtr
+}
+
+func testSlotRequestIQ(from, filename string, size int) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "upload-1").
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, "upload.jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("request").
+				WithAttribute(stravaganza.Namespace, uploadNamespace).
+				WithAttribute("filename", filename).
+				WithAttribute("size", strconv.Itoa(size)).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
@@ -32,6 +32,15 @@ const (
 	// C2SStreamTerminated hook runs when a C2S connection is terminated.
 	C2SStreamTerminated = "c2s.stream.terminated"
 
+	// C2SStreamHibernated hook runs when a disconnected C2S stream is kept around waiting to be resumed (XEP-0198).
+	C2SStreamHibernated = "c2s.stream.hibernated"
+
+	// C2SStreamActive hook runs when a C2S stream client indicates it became active (XEP-0352).
+	C2SStreamActive = "c2s.stream.active"
+
+	// C2SStreamInactive hook runs when a C2S stream client indicates it became inactive (XEP-0352).
+	C2SStreamInactive = "c2s.stream.inactive"
+
 	// C2SStreamElementReceived hook runs when a XMPP element is received over a C2S stream.
 	C2SStreamElementReceived = "c2s.stream.element_received"
 
@@ -57,6 +66,7 @@ const (
 	C2SStreamMessageRouted = "c2s.stream.message_routed"
 
 	// C2SStreamElementSent hook runs when an XMPP element is sent over a C2S stream.
+	// Stanzas held back while the client is inactive (XEP-0352) are reported once actually delivered.
 	C2SStreamElementSent = "c2s.stream.element_sent"
 )
 
@@ -0,0 +1,35 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import "github.com/jackal-xmpp/stravaganza/jid"
+
+const (
+	// InviteRedeemed hook runs whenever an account is registered by means of an invite token.
+	InviteRedeemed = "invite.redeemed"
+)
+
+// InviteInfo contains all information associated to an invite event.
+type InviteInfo struct {
+	// Token is the invite token associated to this event.
+	Token string
+
+	// Inviter is the bare JID of the user that issued the invite.
+	// Nil value means the invite was issued by an administrator.
+	Inviter *jid.JID
+
+	// Invitee is the bare JID of the account registered by means of the invite.
+	Invitee *jid.JID
+}
@@ -24,6 +24,9 @@ const (
 
 	// ArchiveMessageArchived hook runs whenever a message is archived.
 	ArchiveMessageArchived = "mam.message.archieved"
+
+	// ArchiveMessageRetracted hook runs whenever an archived message is replaced by a retraction tombstone.
+	ArchiveMessageRetracted = "mam.message.retracted"
 )
 
 // MamInfo contains all information associated to a mam (XEP-0313) event.
@@ -32,6 +35,7 @@ type MamInfo struct {
 	ArchiveID string
 
 	// Message is the message stanza associated to this event.
+	// On retraction events it contains the resulting tombstone.
 	Message *archivemodel.Message
 
 	// Filters contains filters applied to the archive queried event.
@@ -29,6 +29,7 @@ type Hosts struct {
 	mu          sync.RWMutex
 	defaultHost string
 	hosts       map[string]tls.Certificate
+	anonymous   map[string]struct{}
 }
 
 // Configs contains a set of host configurations.
@@ -41,12 +42,16 @@ type Config struct {
 		CertFile       string `fig:"cert_file"`
 		PrivateKeyFile string `fig:"privkey_file"`
 	} `fig:"tls"`
+
+	// Anonymous, if true, host will be exclusively used for ephemeral SASL ANONYMOUS accounts.
+	Anonymous bool `fig:"anonymous"`
 }
 
 // NewHosts creates and initializes a Hosts instance.
 func NewHosts(cfg Configs) (*Hosts, error) {
 	hs := &Hosts{
-		hosts: make(map[string]tls.Certificate),
+		hosts:     make(map[string]tls.Certificate),
+		anonymous: make(map[string]struct{}),
 	}
 	if len(cfg) == 0 {
 		cer, err := tlsutil.LoadCertificate("", "", defaultDomain)
@@ -66,6 +71,9 @@ func NewHosts(cfg Configs) (*Hosts, error) {
 		} else {
 			hs.RegisterHost(config.Domain, cer)
 		}
+		if config.Anonymous {
+			hs.RegisterAnonymousHost(config.Domain)
+		}
 	}
 	return hs, nil
 }
@@ -85,6 +93,16 @@ func (hs *Hosts) RegisterHost(h string, cer tls.Certificate) {
 	hs.hosts[h] = cer
 }
 
+// RegisterAnonymousHost flags a previously registered host as anonymous.
+func (hs *Hosts) RegisterAnonymousHost(h string) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if hs.anonymous == nil {
+		hs.anonymous = make(map[string]struct{})
+	}
+	hs.anonymous[h] = struct{}{}
+}
+
 // DefaultHostName returns default host name value.
 func (hs *Hosts) DefaultHostName() string {
 	hs.mu.RLock()
@@ -100,6 +118,14 @@ func (hs *Hosts) IsLocalHost(h string) bool {
 	return ok
 }
 
+// IsAnonymousHost tells whether or not h value corresponds to an anonymous local host.
+func (hs *Hosts) IsAnonymousHost(h string) bool {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	_, ok := hs.anonymous[h]
+	return ok
+}
+
 // HostNames returns the list of all registered local hosts.
 func (hs *Hosts) HostNames() []string {
 	hs.mu.RLock()
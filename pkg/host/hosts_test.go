@@ -55,3 +55,20 @@ func TestHosts_Domains(t *testing.T) {
 	require.True(t, h.IsLocalHost("jackal.org"))
 	require.True(t, h.IsLocalHost("jackal.net"))
 }
+
+func TestHosts_Anonymous(t *testing.T) {
+	// given
+	h := &Hosts{
+		hosts: make(map[string]tls.Certificate),
+	}
+
+	// when
+	h.RegisterDefaultHost("jackal.im", tls.Certificate{})
+	h.RegisterHost("anon.jackal.im", tls.Certificate{})
+	h.RegisterAnonymousHost("anon.jackal.im")
+
+	// then
+	require.True(t, h.IsLocalHost("anon.jackal.im"))
+	require.True(t, h.IsAnonymousHost("anon.jackal.im"))
+	require.False(t, h.IsAnonymousHost("jackal.im"))
+}
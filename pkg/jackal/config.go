@@ -18,6 +18,8 @@ import (
 	"path/filepath"
 
 	"github.com/ortuman/jackal/pkg/module/xep0313"
+	"github.com/ortuman/jackal/pkg/module/xep0357"
+	"github.com/ortuman/jackal/pkg/module/xep0401"
 
 	"github.com/kkyr/fig"
 	adminserver "github.com/ortuman/jackal/pkg/admin/server"
@@ -25,13 +27,18 @@ import (
 	"github.com/ortuman/jackal/pkg/c2s"
 	"github.com/ortuman/jackal/pkg/cluster/kv"
 	clusterserver "github.com/ortuman/jackal/pkg/cluster/server"
+	"github.com/ortuman/jackal/pkg/component/xep0045"
+	"github.com/ortuman/jackal/pkg/component/xep0060"
 	"github.com/ortuman/jackal/pkg/component/xep0114"
+	"github.com/ortuman/jackal/pkg/component/xep0363"
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/module/offline"
+	"github.com/ortuman/jackal/pkg/module/xep0077"
 	"github.com/ortuman/jackal/pkg/module/xep0092"
 	"github.com/ortuman/jackal/pkg/module/xep0198"
 	"github.com/ortuman/jackal/pkg/module/xep0199"
 	"github.com/ortuman/jackal/pkg/s2s"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/storage"
 )
@@ -71,14 +78,19 @@ type C2SConfig struct {
 
 // S2SConfig defines S2S subsystem configuration.
 type S2SConfig struct {
-	Listeners s2s.ListenersConfig `fig:"listeners"`
-	Out       s2s.OutConfig       `fig:"out"`
+	Listeners  s2s.ListenersConfig `fig:"listeners"`
+	Out        s2s.OutConfig       `fig:"out"`
+	TLS        s2s.TLSConfig       `fig:"tls"`
+	Federation federation.Config   `fig:"federation"`
 }
 
 // ComponentsConfig defines application components configuration.
 type ComponentsConfig struct {
 	Listeners xep0114.ListenersConfig `fig:"listeners"`
 	Secret    string                  `fig:"secret"`
+	MUC       xep0045.Config          `fig:"muc"`
+	PubSub    xep0060.Config          `fig:"pubsub"`
+	Upload    xep0363.Config          `fig:"upload"`
 }
 
 // ModulesConfig defines application modules configuration.
@@ -89,6 +101,9 @@ type ModulesConfig struct {
 	// Offline: offline storage
 	Offline offline.Config `fig:"offline"`
 
+	// XEP-0077: In-Band Registration
+	Register xep0077.Config `fig:"register"`
+
 	// XEP-0092: Software Version
 	Version xep0092.Config `fig:"version"`
 
@@ -100,6 +115,12 @@ type ModulesConfig struct {
 
 	// XEP-0313: Message Archive Management
 	Mam xep0313.Config `fig:"mam"`
+
+	// XEP-0357: Push Notifications
+	Push xep0357.Config `fig:"push"`
+
+	// XEP-0401: Easy User Onboarding
+	Invite xep0401.Config `fig:"invite"`
 }
 
 // Config defines jackal application configuration.
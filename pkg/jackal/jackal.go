@@ -40,7 +40,10 @@ import (
 	clusterserver "github.com/ortuman/jackal/pkg/cluster/server"
 	"github.com/ortuman/jackal/pkg/component"
 	"github.com/ortuman/jackal/pkg/component/extcomponentmanager"
+	"github.com/ortuman/jackal/pkg/component/xep0045"
+	"github.com/ortuman/jackal/pkg/component/xep0060"
 	"github.com/ortuman/jackal/pkg/component/xep0114"
+	"github.com/ortuman/jackal/pkg/component/xep0363"
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/log"
@@ -48,9 +51,11 @@ import (
 	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/s2s"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/storage"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/storage/retention"
 	"github.com/ortuman/jackal/pkg/util/crashreporter"
 	"github.com/ortuman/jackal/pkg/version"
 )
@@ -115,6 +120,9 @@ type Jackal struct {
 	localRouter    *c2s.LocalRouter
 	clusterRouter  *clusterrouter.Router
 	s2sOutProvider *s2s.OutProvider
+	s2sPolicy      *federation.Policy
+	s2sPolicySync  *federation.Sync
+	s2sCertVrf     *s2s.CertVerifier
 	router         router.Router
 	mods           *module.Modules
 	comps          *component.Components
@@ -231,16 +239,21 @@ func (j *Jackal) Run() error {
 	if err := j.initShapers(cfg.Shapers); err != nil {
 		return err
 	}
-	j.initS2SOut(cfg.S2S.Out)
-	j.initRouters()
+	if err := j.initS2SOut(cfg.S2S.Out, cfg.S2S.TLS, cfg.S2S.Federation); err != nil {
+		return err
+	}
+	j.initRouters(cfg.S2S.Out.Queue)
 
 	// init components & modules
-	j.initComponents()
+	j.initComponents(cfg.Components)
 
 	if err := j.initModules(cfg.Modules); err != nil {
 		return err
 	}
 
+	// init retention scheduler
+	j.initRetention(cfg.Storage.Retention)
+
 	// init admin server
 	j.initAdminServer(cfg.Admin)
 
@@ -311,6 +324,13 @@ func (j *Jackal) initRepository(cfg storage.Config) error {
 	return nil
 }
 
+func (j *Jackal) initRetention(cfg retention.Config) {
+	if !cfg.IsEnabled() {
+		return
+	}
+	j.registerStartStopper(retention.New(cfg, j.rep, j.hosts, j.logger))
+}
+
 func (j *Jackal) initHosts(configs host.Configs) error {
 	h, err := host.NewHosts(configs)
 	if err != nil {
@@ -362,6 +382,7 @@ func (j *Jackal) initListeners(
 	for _, ln := range c2sListeners {
 		j.registerStartStopper(ln)
 	}
+	j.registerStartStopper(c2s.NewAnonymousCleaner(j.hosts, j.rep, j.hk, j.logger))
 
 	// s2s listeners
 	if len(s2sListenersCfg) > 0 {
@@ -379,6 +400,8 @@ func (j *Jackal) initListeners(
 			j.kv,
 			j.shapers,
 			j.hk,
+			j.s2sPolicy,
+			j.s2sCertVrf,
 			j.logger,
 		)
 		for _, ln := range s2sListeners {
@@ -404,18 +427,33 @@ func (j *Jackal) initListeners(
 	return nil
 }
 
-func (j *Jackal) initS2SOut(cfg s2s.OutConfig) {
-	j.s2sOutProvider = s2s.NewOutProvider(cfg, j.hosts, j.kv, j.shapers, j.hk, j.logger)
+func (j *Jackal) initS2SOut(cfg s2s.OutConfig, tlsCfg s2s.TLSConfig, federationCfg federation.Config) error {
+	policy, err := federation.New(federationCfg)
+	if err != nil {
+		return err
+	}
+	j.s2sPolicy = policy
+	j.s2sPolicySync = federation.NewSync(policy, j.kv, j.logger)
+	j.registerStartStopper(j.s2sPolicySync)
+
+	certVrf, err := s2s.NewCertVerifier(tlsCfg)
+	if err != nil {
+		return err
+	}
+	j.s2sCertVrf = certVrf
+
+	j.s2sOutProvider = s2s.NewOutProvider(cfg, j.hosts, j.kv, j.shapers, j.hk, j.s2sPolicy, j.s2sCertVrf, j.logger)
 	j.registerStartStopper(j.s2sOutProvider)
+	return nil
 }
 
-func (j *Jackal) initRouters() {
+func (j *Jackal) initRouters(queueCfg s2s.OutQueueConfig) {
 	// init C2S router
 	j.localRouter = c2s.NewLocalRouter(j.hosts)
 	j.clusterRouter = clusterrouter.New(j.clusterConnMng)
 
 	c2sRouter := c2s.NewRouter(j.localRouter, j.clusterRouter, j.resMng, j.rep, j.hk, j.logger)
-	s2sRouter := s2s.NewRouter(j.s2sOutProvider)
+	s2sRouter := s2s.NewRouter(j.s2sOutProvider, c2sRouter, queueCfg, j.logger)
 
 	// init global router
 	j.router = router.New(j.hosts, c2sRouter, s2sRouter)
@@ -423,12 +461,29 @@ func (j *Jackal) initRouters() {
 	return
 }
 
-func (j *Jackal) initComponents() {
-	j.comps = component.NewComponents(nil, j.hk, j.logger)
+func (j *Jackal) initComponents(cfg ComponentsConfig) {
+	var comps []component.Component
+	if len(cfg.MUC.Host) > 0 {
+		comps = append(comps, xep0045.New(cfg.MUC, j.router, j.rep, j.hk, j.logger))
+	}
+	if len(cfg.PubSub.Host) > 0 {
+		comps = append(comps, xep0060.New(cfg.PubSub, j.router, j.hosts, j.rep, j.logger))
+	}
+	var upload *xep0363.Upload
+	if len(cfg.Upload.Host) > 0 {
+		upload = xep0363.New(cfg.Upload, j.router, j.hosts, j.logger)
+		comps = append(comps, upload)
+	}
+	j.comps = component.NewComponents(comps, j.hk, j.logger)
 	j.extCompMng = extcomponentmanager.New(j.kv, j.clusterConnMng, j.comps, j.logger)
 
 	j.registerStartStopper(j.comps)
 	j.registerStartStopper(j.extCompMng)
+
+	if upload != nil {
+		tlsCfg := cfg.Upload.TLS
+		j.registerStartStopper(newUploadServer(cfg.Upload.Port, tlsCfg.CertFile, tlsCfg.PrivateKeyFile, upload, j.logger))
+	}
 }
 
 func (j *Jackal) initModules(cfg ModulesConfig) error {
@@ -452,7 +507,7 @@ func (j *Jackal) initModules(cfg ModulesConfig) error {
 }
 
 func (j *Jackal) initAdminServer(cfg adminserver.Config) {
-	adminSrv := adminserver.New(cfg, j.rep, j.peppers, j.hk, j.logger)
+	adminSrv := adminserver.New(cfg, j.rep, j.peppers, j.hk, j.s2sPolicySync, j.logger)
 	j.registerStartStopper(adminSrv)
 }
 
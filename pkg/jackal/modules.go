@@ -22,8 +22,10 @@ import (
 	"github.com/ortuman/jackal/pkg/module/xep0030"
 	"github.com/ortuman/jackal/pkg/module/xep0049"
 	"github.com/ortuman/jackal/pkg/module/xep0054"
+	"github.com/ortuman/jackal/pkg/module/xep0077"
 	"github.com/ortuman/jackal/pkg/module/xep0092"
 	"github.com/ortuman/jackal/pkg/module/xep0115"
+	"github.com/ortuman/jackal/pkg/module/xep0163"
 	"github.com/ortuman/jackal/pkg/module/xep0191"
 	"github.com/ortuman/jackal/pkg/module/xep0198"
 	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
@@ -31,6 +33,8 @@ import (
 	"github.com/ortuman/jackal/pkg/module/xep0202"
 	"github.com/ortuman/jackal/pkg/module/xep0280"
 	"github.com/ortuman/jackal/pkg/module/xep0313"
+	"github.com/ortuman/jackal/pkg/module/xep0357"
+	"github.com/ortuman/jackal/pkg/module/xep0401"
 )
 
 var defaultModules = []string{
@@ -42,6 +46,7 @@ var defaultModules = []string{
 	xep0054.ModuleName,
 	xep0092.ModuleName,
 	xep0115.ModuleName,
+	xep0163.ModuleName,
 	xep0191.ModuleName,
 	xep0198.ModuleName,
 	xep0199.ModuleName,
@@ -80,6 +85,11 @@ var modFns = map[string]func(a *Jackal, cfg *ModulesConfig) module.Module{
 	xep0054.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
 		return xep0054.New(j.router, j.rep, j.hk, j.logger)
 	},
+	// XEP-0077: In-Band Registration
+	// (https://xmpp.org/extensions/xep-0077.html)
+	xep0077.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return xep0077.New(cfg.Register, j.router, j.resMng, j.rep, j.peppers, j.hk, j.logger)
+	},
 	// XEP-0092: Software Version
 	// (https://xmpp.org/extensions/xep-0092.html)
 	xep0092.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
@@ -90,6 +100,11 @@ var modFns = map[string]func(a *Jackal, cfg *ModulesConfig) module.Module{
 	xep0115.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
 		return xep0115.New(j.router, j.rep, j.hk, j.logger)
 	},
+	// XEP-0163: Personal Eventing Protocol
+	// (https://xmpp.org/extensions/xep-0163.html)
+	xep0163.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
+		return xep0163.New(j.router, j.hosts, j.resMng, j.rep, j.hk, j.logger)
+	},
 	// XEP-0191: Blocking Command
 	// (https://xmpp.org/extensions/xep-0191.html)
 	xep0191.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
@@ -121,4 +136,14 @@ var modFns = map[string]func(a *Jackal, cfg *ModulesConfig) module.Module{
 	xep0313.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
 		return xep0313.New(cfg.Mam, j.router, j.hosts, j.rep, j.hk, j.logger)
 	},
+	// XEP-0357: Push Notifications
+	// (https://xmpp.org/extensions/xep-0357.html)
+	xep0357.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return xep0357.New(cfg.Push, j.router, j.rep, j.hk, j.logger)
+	},
+	// XEP-0401: Easy User Onboarding
+	// (https://xmpp.org/extensions/xep-0401.html)
+	xep0401.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return xep0401.New(cfg.Invite, j.router, j.rep, j.hk, j.logger)
+	},
 }
@@ -0,0 +1,76 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jackal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// uploadServer serves HTTP file upload (XEP-0363) PUT and GET requests.
+// It runs on its own listener so that uploads can be exposed publicly over TLS
+// without exposing the metrics and pprof endpoints served by httpServer.
+type uploadServer struct {
+	port           int
+	certFile       string
+	privateKeyFile string
+	handler        http.Handler
+	srv            *http.Server
+	logger         kitlog.Logger
+}
+
+func newUploadServer(port int, certFile, privateKeyFile string, handler http.Handler, logger kitlog.Logger) *uploadServer {
+	return &uploadServer{
+		port:           port,
+		certFile:       certFile,
+		privateKeyFile: privateKeyFile,
+		handler:        handler,
+		logger:         logger,
+	}
+}
+
+func (u *uploadServer) Start(_ context.Context) error {
+	u.srv = &http.Server{Handler: u.handler}
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", u.port))
+	if err != nil {
+		return err
+	}
+	go func() {
+		var err error
+		if len(u.certFile) > 0 {
+			err = u.srv.ServeTLS(ln, u.certFile, u.privateKeyFile)
+		} else {
+			err = u.srv.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			level.Error(u.logger).Log("msg", "failed to serve upload HTTP", "err", err)
+		}
+	}()
+	level.Info(u.logger).Log("msg", "upload HTTP server listening", "port", u.port)
+	return nil
+}
+
+func (u *uploadServer) Stop(ctx context.Context) error {
+	if err := u.srv.Shutdown(ctx); err != nil {
+		return err
+	}
+	level.Info(u.logger).Log("msg", "closed upload HTTP server", "port", u.port)
+	return nil
+}
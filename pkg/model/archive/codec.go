@@ -35,3 +35,13 @@ func (x *Messages) MarshalBinary() (data []byte, err error) {
 func (x *Messages) UnmarshalBinary(data []byte) error {
 	return proto.Unmarshal(data, x)
 }
+
+// MarshalBinary satisfies encoding.BinaryMarshaler interface.
+func (x *Preferences) MarshalBinary() (data []byte, err error) {
+	return proto.Marshal(x)
+}
+
+// UnmarshalBinary satisfies encoding.BinaryUnmarshaler interface.
+func (x *Preferences) UnmarshalBinary(data []byte) error {
+	return proto.Unmarshal(data, x)
+}
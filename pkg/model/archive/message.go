@@ -0,0 +1,25 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archivemodel
+
+// Body returns the archived message body text.
+func (x *Message) Body() string {
+	for _, elem := range x.GetMessage().GetElements() {
+		if elem.GetName() == "body" {
+			return elem.GetText()
+		}
+	}
+	return ""
+}
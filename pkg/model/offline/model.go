@@ -0,0 +1,23 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offlinemodel
+
+import "github.com/jackal-xmpp/stravaganza"
+
+// Item represents a message stored in a user offline queue along with its node identifier (XEP-0013).
+type Item struct {
+	Node    string
+	Message *stravaganza.Message
+}
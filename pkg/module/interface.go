@@ -27,6 +27,11 @@ type iqProcessor interface {
 	IQProcessor
 }
 
+//go:generate moq -out preauth_iq_processor.mock_test.go . preAuthIQProcessor
+type preAuthIQProcessor interface {
+	PreAuthIQProcessor
+}
+
 //go:generate moq -out module.mock_test.go . module
 type module interface {
 	Module
@@ -16,6 +16,7 @@ package module
 
 import (
 	"context"
+	"net"
 
 	"github.com/go-kit/log/level"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/router/stream"
 )
 
 // Module represents generic module interface.
@@ -61,14 +63,67 @@ type IQProcessor interface {
 	ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error
 }
 
+// SASL2InlineProcessor represents a module type whose feature can be negotiated inline
+// within a SASL2 (XEP-0388) authentication request.
+type SASL2InlineProcessor interface {
+	Module
+
+	// SASL2InlineFeature returns the feature element advertised within SASL2 inline features.
+	SASL2InlineFeature() stravaganza.Element
+
+	// ProcessSASL2Inline will be invoked on behalf of a just authenticated stream whenever
+	// an inline element qualified by the feature namespace is requested.
+	// Returned element will be included into the SASL2 success response.
+	ProcessSASL2Inline(ctx context.Context, elem stravaganza.Element, stm stream.C2S) (stravaganza.Element, error)
+}
+
+// BindInlineProcessor represents a module type whose feature can be enabled inline
+// within a Bind 2 (XEP-0386) request.
+type BindInlineProcessor interface {
+	Module
+
+	// BindInlineFeature returns the namespace of the feature that can be enabled at bind time.
+	BindInlineFeature() string
+
+	// ProcessBindInline will be invoked on behalf of a just bound stream whenever
+	// an inline element qualified by the feature namespace is requested.
+	// Returned element, if any, will be included into the Bind 2 bound response.
+	ProcessBindInline(ctx context.Context, elem stravaganza.Element, stm stream.C2S) (stravaganza.Element, error)
+}
+
+// PreAuthIQProcessor represents an iq processor module type that can also handle
+// iq stanzas sent over a not yet authenticated stream.
+type PreAuthIQProcessor interface {
+	IQProcessor
+
+	// PreAuthStreamFeatures returns the feature elements advertised to not yet authenticated streams.
+	PreAuthStreamFeatures() []stravaganza.Element
+
+	// ProcessPreAuthIQ will be invoked whenever an iq stanza is received over a not yet authenticated stream.
+	// Returned stanza will be directly sent back to the originating stream.
+	ProcessPreAuthIQ(ctx context.Context, iq *stravaganza.IQ, inf PreAuthInfo) (stravaganza.Stanza, error)
+}
+
+// PreAuthInfo contains the information associated to a not yet authenticated stream.
+type PreAuthInfo struct {
+	// StreamID is the originating stream identifier.
+	StreamID stream.C2SID
+
+	// RemoteAddr is the originating stream remote network address.
+	RemoteAddr net.Addr
+}
+
 // Modules is the global module hub.
 type Modules struct {
-	mods         []Module
-	iqProcessors []IQProcessor
-	hosts        hosts
-	router       router.Router
-	hk           *hook.Hooks
-	logger       kitlog.Logger
+	mods                  []Module
+	iqProcessors          []IQProcessor
+	preAuthIQProcessors   []PreAuthIQProcessor
+	sasl2InlineProcessors []SASL2InlineProcessor
+	bindInlineProcessors  []BindInlineProcessor
+	hosts                 hosts
+	router                router.Router
+	hk                    *hook.Hooks
+	logger                kitlog.Logger
 }
 
 // NewModules returns a new initialized Modules instance.
@@ -160,6 +215,30 @@ func (m *Modules) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	return nil
 }
 
+// PreAuthStreamFeatures returns the features offered by modules to not yet authenticated streams.
+func (m *Modules) PreAuthStreamFeatures() []stravaganza.Element {
+	var sfs []stravaganza.Element
+	for _, pr := range m.preAuthIQProcessors {
+		sfs = append(sfs, pr.PreAuthStreamFeatures()...)
+	}
+	return sfs
+}
+
+// ProcessPreAuthIQ routes an iq received over a not yet authenticated stream to the corresponding module.
+// A service-unavailable error will be returned in case no module handles the iq namespace.
+func (m *Modules) ProcessPreAuthIQ(ctx context.Context, iq *stravaganza.IQ, inf PreAuthInfo) (stravaganza.Stanza, error) {
+	if children := iq.AllChildren(); len(children) > 0 {
+		ns := children[0].Attribute(stravaganza.Namespace)
+		for _, pr := range m.preAuthIQProcessors {
+			if !pr.MatchesNamespace(ns, true) {
+				continue
+			}
+			return pr.ProcessPreAuthIQ(ctx, iq, inf)
+		}
+	}
+	return stanzaerror.E(stanzaerror.ServiceUnavailable, iq).Stanza(false)
+}
+
 // StreamFeatures returns stream features of all registered modules.
 func (m *Modules) StreamFeatures(ctx context.Context, domain string) ([]stravaganza.Element, error) {
 	var sfs []stravaganza.Element
@@ -175,6 +254,50 @@ func (m *Modules) StreamFeatures(ctx context.Context, domain string) ([]stravaga
 	return sfs, nil
 }
 
+// SASL2InlineFeatures returns SASL2 inline features of all registered modules.
+func (m *Modules) SASL2InlineFeatures() []stravaganza.Element {
+	var sfs []stravaganza.Element
+	for _, pr := range m.sasl2InlineProcessors {
+		sfs = append(sfs, pr.SASL2InlineFeature())
+	}
+	return sfs
+}
+
+// ProcessSASL2Inline routes a SASL2 inline element to the corresponding module.
+// A nil element will be returned in case no module handles the requested feature.
+func (m *Modules) ProcessSASL2Inline(ctx context.Context, elem stravaganza.Element, stm stream.C2S) (stravaganza.Element, error) {
+	ns := elem.Attribute(stravaganza.Namespace)
+	for _, pr := range m.sasl2InlineProcessors {
+		if pr.SASL2InlineFeature().Attribute(stravaganza.Namespace) != ns {
+			continue
+		}
+		return pr.ProcessSASL2Inline(ctx, elem, stm)
+	}
+	return nil, nil
+}
+
+// BindInlineFeatures returns Bind 2 inline feature namespaces of all registered modules.
+func (m *Modules) BindInlineFeatures() []string {
+	var fs []string
+	for _, pr := range m.bindInlineProcessors {
+		fs = append(fs, pr.BindInlineFeature())
+	}
+	return fs
+}
+
+// ProcessBindInline routes a Bind 2 inline element to the corresponding module.
+// A nil element will be returned in case no module handles the requested feature.
+func (m *Modules) ProcessBindInline(ctx context.Context, elem stravaganza.Element, stm stream.C2S) (stravaganza.Element, error) {
+	ns := elem.Attribute(stravaganza.Namespace)
+	for _, pr := range m.bindInlineProcessors {
+		if pr.BindInlineFeature() != ns {
+			continue
+		}
+		return pr.ProcessBindInline(ctx, elem, stm)
+	}
+	return nil, nil
+}
+
 // IsEnabled tells whether a specific module it's been registered.
 func (m *Modules) IsEnabled(moduleName string) bool {
 	for _, mod := range m.mods {
@@ -196,5 +319,17 @@ func (m *Modules) setupModules() {
 		if ok {
 			m.iqProcessors = append(m.iqProcessors, iqPr)
 		}
+		preAuthPr, ok := mod.(PreAuthIQProcessor)
+		if ok {
+			m.preAuthIQProcessors = append(m.preAuthIQProcessors, preAuthPr)
+		}
+		sasl2Pr, ok := mod.(SASL2InlineProcessor)
+		if ok {
+			m.sasl2InlineProcessors = append(m.sasl2InlineProcessors, sasl2Pr)
+		}
+		bindPr, ok := mod.(BindInlineProcessor)
+		if ok {
+			m.bindInlineProcessors = append(m.bindInlineProcessors, bindPr)
+		}
 	}
 }
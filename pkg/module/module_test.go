@@ -93,3 +93,59 @@ func TestModules_ProcessIQ(t *testing.T) {
 	require.Len(t, iqPrMock.MatchesNamespaceCalls(), 1)
 	require.Len(t, iqPrMock.ProcessIQCalls(), 1)
 }
+
+func TestModules_ProcessPreAuthIQ(t *testing.T) {
+	// given
+	prMock := &preAuthIQProcessorMock{}
+	prMock.MatchesNamespaceFunc = func(namespace string, _ bool) bool {
+		return namespace == "jabber:iq:register"
+	}
+	prMock.ProcessPreAuthIQFunc = func(ctx context.Context, iq *stravaganza.IQ, inf PreAuthInfo) (stravaganza.Stanza, error) {
+		return iq, nil
+	}
+	prMock.PreAuthStreamFeaturesFunc = func() []stravaganza.Element {
+		return []stravaganza.Element{
+			stravaganza.NewBuilder("register").
+				WithAttribute(stravaganza.Namespace, "http://jabber.org/features/iq-register").
+				Build(),
+		}
+	}
+
+	mods := &Modules{
+		mods: []Module{prMock},
+	}
+	mods.setupModules()
+
+	iqFn := func(ns string) *stravaganza.IQ {
+		iq, _ := stravaganza.NewIQBuilder().
+			WithAttribute(stravaganza.ID, "iq0001").
+			WithAttribute(stravaganza.From, "jackal.im").
+			WithAttribute(stravaganza.To, "jackal.im").
+			WithAttribute(stravaganza.Type, stravaganza.GetType).
+			WithChild(
+				stravaganza.NewBuilder("query").
+					WithAttribute(stravaganza.Namespace, ns).
+					Build(),
+			).
+			BuildIQ()
+		return iq
+	}
+
+	// when
+	features := mods.PreAuthStreamFeatures()
+
+	resp0, err0 := mods.ProcessPreAuthIQ(context.Background(), iqFn("jabber:iq:register"), PreAuthInfo{})
+	resp1, err1 := mods.ProcessPreAuthIQ(context.Background(), iqFn("jabber:iq:version"), PreAuthInfo{})
+
+	// then
+	require.Len(t, features, 1)
+
+	require.Nil(t, err0)
+	require.Equal(t, stravaganza.GetType, resp0.Attribute(stravaganza.Type))
+
+	require.Nil(t, err1)
+	require.Equal(t, stravaganza.ErrorType, resp1.Attribute(stravaganza.Type))
+	require.NotNil(t, resp1.Child("error").Child("service-unavailable"))
+
+	require.Len(t, prMock.ProcessPreAuthIQCalls(), 1)
+}
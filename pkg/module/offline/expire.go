@@ -0,0 +1,110 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offline
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+)
+
+const (
+	expireNamespace = "jabber:x:expire"
+	delayNamespace  = "urn:xmpp:delay"
+)
+
+// stampExpiration annotates msg expire element, if any, with the time it has been stored at (XEP-0023).
+func stampExpiration(msg *stravaganza.Message, storedAt time.Time) *stravaganza.Message {
+	x := msg.ChildNamespace("x", expireNamespace)
+	if x == nil {
+		return msg
+	}
+	stampedX := stravaganza.NewBuilderFromElement(x).
+		WithAttribute("stored", strconv.FormatInt(storedAt.Unix(), 10)).
+		Build()
+	stampedMsg, _ := stravaganza.NewBuilderFromElement(msg).
+		WithoutChildrenNamespace("x", expireNamespace).
+		WithChild(stampedX).
+		BuildMessage()
+	return stampedMsg
+}
+
+// isExpirationElapsed tells whether a message expire element, if any, has already elapsed on arrival.
+func isExpirationElapsed(msg *stravaganza.Message) bool {
+	x := msg.ChildNamespace("x", expireNamespace)
+	if x == nil {
+		return false
+	}
+	seconds, err := strconv.Atoi(x.Attribute("seconds"))
+	return err == nil && seconds <= 0
+}
+
+// deliverable returns queued msg ready to be delivered at now, or nil in case it has already expired.
+// Expire element seconds value is decremented by the time the message has spent in the queue.
+func (m *Offline) deliverable(msg *stravaganza.Message, now time.Time) *stravaganza.Message {
+	storedAt := queuedAt(msg)
+	if storedAt.IsZero() {
+		return msg
+	}
+	age := now.Sub(storedAt)
+	if m.cfg.TTL > 0 && age >= m.cfg.TTL {
+		return nil
+	}
+	x := msg.ChildNamespace("x", expireNamespace)
+	if x == nil {
+		return msg
+	}
+	seconds, err := strconv.Atoi(x.Attribute("seconds"))
+	if err != nil {
+		return msg
+	}
+	remaining := seconds - int(age/time.Second)
+	if remaining <= 0 {
+		return nil
+	}
+	updatedX := stravaganza.NewBuilderFromElement(x).
+		WithAttribute("seconds", strconv.Itoa(remaining)).
+		Build()
+	updatedMsg, _ := stravaganza.NewBuilderFromElement(msg).
+		WithoutChildrenNamespace("x", expireNamespace).
+		WithChild(updatedX).
+		BuildMessage()
+	return updatedMsg
+}
+
+// queuedAt returns the time a message entered the offline queue.
+// Only server written values are taken into account: expire element stored value, if any, or otherwise
+// the last delay element issued by the local domain, since the one appended on storage always comes after
+// any delay element provided by the sender.
+func queuedAt(msg *stravaganza.Message) time.Time {
+	if x := msg.ChildNamespace("x", expireNamespace); x != nil {
+		if stored, err := strconv.ParseInt(x.Attribute("stored"), 10, 64); err == nil {
+			return time.Unix(stored, 0)
+		}
+	}
+	var stamp time.Time
+	for _, delay := range msg.ChildrenNamespace("delay", delayNamespace) {
+		if delay.Attribute(stravaganza.From) != msg.ToJID().Domain() {
+			continue
+		}
+		tm, err := time.Parse(time.RFC3339, delay.Attribute("stamp"))
+		if err != nil {
+			continue
+		}
+		stamp = tm
+	}
+	return stamp
+}
@@ -0,0 +1,309 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offline
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
+	offlinemodel "github.com/ortuman/jackal/pkg/model/offline"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/module/xep0030"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	offlineNamespace = "http://jabber.org/protocol/offline"
+
+	flexibleRetrievalCtxKey = "offline:flexible"
+)
+
+// MatchesNamespace tells whether namespace matches offline module.
+func (m *Offline) MatchesNamespace(namespace string, serverTarget bool) bool {
+	if serverTarget {
+		return false
+	}
+	return namespace == offlineNamespace
+}
+
+// ProcessIQ process a flexible offline message retrieval iq (XEP-0013).
+func (m *Offline) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	fromJID := iq.FromJID()
+	if !fromJID.MatchesWithOptions(iq.ToJID(), jid.MatchesBare) {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return nil
+	}
+	offline := iq.ChildNamespace("offline", offlineNamespace)
+	if offline == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	// from now on, offline messages are no longer flooded on initial presence
+	if err := m.setFlexibleRetrieval(ctx, fromJID); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	var err error
+	switch {
+	case iq.IsGet() && offline.Child("fetch") != nil:
+		err = m.fetchAll(ctx, iq)
+	case iq.IsSet() && offline.Child("purge") != nil:
+		err = m.purgeAll(ctx, iq)
+	case len(offline.Children("item")) > 0:
+		err = m.processItems(ctx, iq, offline.Children("item"))
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+	}
+	return err
+}
+
+// NodeProvider returns the disco info provider of the offline messages node.
+func (m *Offline) NodeProvider(node string) xep0030.InfoProvider {
+	if node != offlineNamespace {
+		return nil
+	}
+	return &nodeProvider{m: m}
+}
+
+func (m *Offline) fetchAll(ctx context.Context, iq *stravaganza.IQ) error {
+	username := iq.FromJID().Node()
+
+	items, err := m.rep.FetchOfflineItems(ctx, username)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	if err := m.sendItems(ctx, iq.FromJID(), items); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+
+	level.Info(m.logger).Log("msg", "fetched offline messages", "queue_size", len(items), "username", username)
+	return nil
+}
+
+func (m *Offline) purgeAll(ctx context.Context, iq *stravaganza.IQ) error {
+	username := iq.FromJID().Node()
+
+	lockID := offlineQueueLockID(username)
+
+	if err := m.rep.Lock(ctx, lockID); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	defer m.releaseLock(ctx, lockID)
+
+	if err := m.rep.DeleteOfflineMessages(ctx, username); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+
+	level.Info(m.logger).Log("msg", "purged offline messages", "username", username)
+	return nil
+}
+
+func (m *Offline) processItems(ctx context.Context, iq *stravaganza.IQ, elems []stravaganza.Element) error {
+	username := iq.FromJID().Node()
+
+	var action string
+	var nodes []string
+	for _, elem := range elems {
+		act := elem.Attribute("action")
+		node := elem.Attribute("node")
+		if len(node) == 0 || (len(action) > 0 && act != action) {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+			return nil
+		}
+		action = act
+		nodes = append(nodes, node)
+	}
+	switch {
+	case action == "view" && iq.IsGet():
+		return m.viewItems(ctx, iq, username, nodes)
+	case action == "remove" && iq.IsSet():
+		return m.removeItems(ctx, iq, username, nodes)
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+}
+
+func (m *Offline) viewItems(ctx context.Context, iq *stravaganza.IQ, username string, nodes []string) error {
+	var items []*offlinemodel.Item
+	now := time.Now()
+	for _, node := range nodes {
+		item, err := m.rep.FetchOfflineItem(ctx, username, node)
+		if err != nil {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+			return err
+		}
+		if item == nil || m.deliverable(item.Message, now) == nil {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
+			return nil
+		}
+		items = append(items, item)
+	}
+	if err := m.sendItems(ctx, iq.FromJID(), items); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+	return nil
+}
+
+func (m *Offline) removeItems(ctx context.Context, iq *stravaganza.IQ, username string, nodes []string) error {
+	lockID := offlineQueueLockID(username)
+
+	if err := m.rep.Lock(ctx, lockID); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	defer m.releaseLock(ctx, lockID)
+
+	for _, node := range nodes {
+		item, err := m.rep.FetchOfflineItem(ctx, username, node)
+		if err != nil {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+			return err
+		}
+		if item == nil {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
+			return nil
+		}
+	}
+	for _, node := range nodes {
+		if err := m.rep.DeleteOfflineItem(ctx, username, node); err != nil {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+			return err
+		}
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+	return nil
+}
+
+func (m *Offline) sendItems(ctx context.Context, userJID *jid.JID, items []*offlinemodel.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	stm, err := m.router.C2S().LocalStream(userJID.Node(), userJID.Resource())
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, item := range items {
+		dMsg := m.deliverable(item.Message, now)
+		if dMsg == nil {
+			continue // expired message
+		}
+		msg, err := stravaganza.NewBuilderFromElement(dMsg).
+			WithAttribute(stravaganza.To, userJID.String()).
+			WithChild(
+				stravaganza.NewBuilder("offline").
+					WithAttribute(stravaganza.Namespace, offlineNamespace).
+					WithChild(
+						stravaganza.NewBuilder("item").
+							WithAttribute("node", item.Node).
+							Build(),
+					).
+					Build(),
+			).
+			BuildMessage()
+		if err != nil {
+			return err
+		}
+		stm.SendElement(msg)
+	}
+	return nil
+}
+
+func (m *Offline) setFlexibleRetrieval(ctx context.Context, userJID *jid.JID) error {
+	if !userJID.IsFull() {
+		return nil
+	}
+	stm, err := m.router.C2S().LocalStream(userJID.Node(), userJID.Resource())
+	if err != nil {
+		return err
+	}
+	return stm.SetInfoValue(ctx, flexibleRetrievalCtxKey, true)
+}
+
+// nodeProvider serves the offline messages disco node, which is only visible to the queue owner.
+type nodeProvider struct {
+	m *Offline
+}
+
+func (p *nodeProvider) Identities(_ context.Context, _, _ *jid.JID, _ string) []discomodel.Identity {
+	return []discomodel.Identity{{Category: "automation", Type: "message-list"}}
+}
+
+func (p *nodeProvider) Items(ctx context.Context, toJID, fromJID *jid.JID, _ string) ([]discomodel.Item, error) {
+	if !fromJID.MatchesWithOptions(toJID, jid.MatchesBare) {
+		return nil, xep0030.ErrEntityNotFound
+	}
+	if err := p.m.setFlexibleRetrieval(ctx, fromJID); err != nil {
+		return nil, err
+	}
+	offlineItems, err := p.m.rep.FetchOfflineItems(ctx, toJID.Node())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]discomodel.Item, 0, len(offlineItems))
+
+	now := time.Now()
+	for _, item := range offlineItems {
+		if p.m.deliverable(item.Message, now) == nil {
+			continue // expired message
+		}
+		items = append(items, discomodel.Item{
+			Jid:  toJID.ToBareJID().String(),
+			Name: item.Message.FromJID().String(),
+			Node: item.Node,
+		})
+	}
+	return items, nil
+}
+
+func (p *nodeProvider) Features(ctx context.Context, toJID, fromJID *jid.JID, _ string) ([]discomodel.Feature, error) {
+	if !fromJID.MatchesWithOptions(toJID, jid.MatchesBare) {
+		return nil, xep0030.ErrEntityNotFound
+	}
+	// a client asking for the offline node is expected to retrieve its messages by itself
+	if err := p.m.setFlexibleRetrieval(ctx, fromJID); err != nil {
+		return nil, err
+	}
+	return []discomodel.Feature{offlineNamespace}, nil
+}
+
+func (p *nodeProvider) Forms(ctx context.Context, toJID, _ *jid.JID, _ string) ([]xep0004.DataForm, error) {
+	count, err := p.m.rep.CountOfflineMessages(ctx, toJID.Node())
+	if err != nil {
+		return nil, err
+	}
+	return []xep0004.DataForm{{
+		Type: xep0004.Result,
+		Fields: []xep0004.Field{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{offlineNamespace}},
+			{Var: "number_of_messages", Values: []string{strconv.Itoa(count)}},
+		},
+	}}, nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offline
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	offlinemodel "github.com/ortuman/jackal/pkg/model/offline"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/router/stream"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffline_FlexibleViewItem(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchOfflineItemFunc = func(ctx context.Context, username, node string) (*offlinemodel.Item, error) {
+		if node != "2" {
+			return nil, nil
+		}
+		return &offlinemodel.Item{Node: node, Message: testOfflineMessage()}, nil
+	}
+	m, stmMock, respStanzas := testFlexibleOffline(repMock)
+
+	output := bytes.NewBuffer(nil)
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		_ = elem.ToXML(output, true)
+		ch := make(chan error)
+		close(ch)
+		return ch
+	}
+
+	// when
+	err := m.ProcessIQ(context.Background(), testOfflineIQ(stravaganza.GetType, "view", "2"))
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, `<message from='noelia@jackal.im/yard' to='ortuman@jackal.im/balcony'><body>I&#39;ll give thee a wind.</body><offline xmlns='http://jabber.org/protocol/offline'><item node='2'/></offline></message>`, output.String())
+
+	require.Len(t, *respStanzas, 1)
+	require.Equal(t, stravaganza.ResultType, (*respStanzas)[0].Attribute(stravaganza.Type))
+
+	require.Len(t, stmMock.SetInfoValueCalls(), 1)
+	require.Equal(t, flexibleRetrievalCtxKey, stmMock.SetInfoValueCalls()[0].K)
+}
+
+func TestOffline_FlexibleRemoveUnknownItem(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.FetchOfflineItemFunc = func(ctx context.Context, username, node string) (*offlinemodel.Item, error) {
+		return nil, nil
+	}
+	repMock.DeleteOfflineItemFunc = func(ctx context.Context, username, node string) error {
+		return nil
+	}
+	m, _, respStanzas := testFlexibleOffline(repMock)
+
+	// when
+	err := m.ProcessIQ(context.Background(), testOfflineIQ(stravaganza.SetType, "remove", "5"))
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, *respStanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, (*respStanzas)[0].Attribute(stravaganza.Type))
+	require.NotNil(t, (*respStanzas)[0].Child("error").Child("item-not-found"))
+
+	require.Len(t, repMock.DeleteOfflineItemCalls(), 0)
+}
+
+func TestOffline_FlexibleNodeItems(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchOfflineItemsFunc = func(ctx context.Context, username string) ([]*offlinemodel.Item, error) {
+		return []*offlinemodel.Item{
+			{Node: "1", Message: testOfflineMessage()},
+			{Node: "2", Message: testOfflineMessage()},
+		}, nil
+	}
+	m, stmMock, _ := testFlexibleOffline(repMock)
+
+	userJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+	otherJID, _ := jid.NewWithString("noelia@jackal.im/yard", true)
+
+	// when
+	prov := m.NodeProvider(offlineNamespace)
+	items, err := prov.Items(context.Background(), userJID.ToBareJID(), userJID, offlineNamespace)
+	_, otherErr := prov.Items(context.Background(), userJID.ToBareJID(), otherJID, offlineNamespace)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "ortuman@jackal.im", items[0].Jid)
+	require.Equal(t, "noelia@jackal.im/yard", items[0].Name)
+	require.Equal(t, "1", items[0].Node)
+
+	require.Error(t, otherErr)
+	require.Len(t, stmMock.SetInfoValueCalls(), 1)
+
+	require.Nil(t, m.NodeProvider("urn:xmpp:unknown"))
+}
+
+func TestOffline_SkipDeliveryOnFlexibleRetrieval(t *testing.T) {
+	// given
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+	repMock := &repositoryMock{}
+
+	stmMock := &c2sStreamMock{}
+	stmMock.InfoFunc = func() c2smodel.Info {
+		return c2smodel.NewInfoMapFromMap(map[string]string{flexibleRetrievalCtxKey: "true"})
+	}
+	hk := hook.NewHooks()
+	m := &Offline{
+		cfg:    Config{QueueSize: 100},
+		hosts:  hostsMock,
+		rep:    repMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	fromJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+	toJID, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+	_, _ = hk.Run(hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: xmpputil.MakePresence(fromJID, toJID, stravaganza.AvailableType, nil),
+		},
+		Sender:  stmMock,
+		Context: context.Background(),
+	})
+
+	// then
+	require.Len(t, repMock.FetchOfflineMessagesCalls(), 0)
+}
+
+func testFlexibleOffline(repMock *repositoryMock) (*Offline, *c2sStreamMock, *[]stravaganza.Stanza) {
+	stmMock := &c2sStreamMock{}
+	stmMock.SetInfoValueFunc = func(ctx context.Context, k string, val interface{}) error {
+		return nil
+	}
+	c2sRouterMock := &c2sRouterMock{}
+	c2sRouterMock.LocalStreamFunc = func(username string, resource string) (stream.C2S, error) {
+		return stmMock, nil
+	}
+	var respStanzas []stravaganza.Stanza
+
+	routerMock := &routerMock{}
+	routerMock.C2SFunc = func() router.C2SRouter {
+		return c2sRouterMock
+	}
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m := &Offline{
+		cfg:    Config{QueueSize: 100},
+		router: routerMock,
+		rep:    repMock,
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+	return m, stmMock, &respStanzas
+}
+
+func testOfflineIQ(typ, action, node string) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "offline1").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/balcony").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithAttribute(stravaganza.Type, typ).
+		WithChild(
+			stravaganza.NewBuilder("offline").
+				WithAttribute(stravaganza.Namespace, offlineNamespace).
+				WithChild(
+					stravaganza.NewBuilder("item").
+						WithAttribute("action", action).
+						WithAttribute("node", node).
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
+
+func testOfflineMessage() *stravaganza.Message {
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im/balcony").
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("I'll give thee a wind.").
+				Build(),
+		).
+		BuildMessage()
+	return msg
+}
@@ -31,9 +31,15 @@ type globalRouter interface {
 	router.Router
 }
 
+//go:generate moq -out c2srouter.mock_test.go . c2sRouter
+type c2sRouter interface {
+	router.C2SRouter
+}
+
 //go:generate moq -out hosts.mock_test.go . hosts
 type hosts interface {
 	IsLocalHost(h string) bool
+	IsAnonymousHost(h string) bool
 }
 
 //go:generate moq -out resourcemanager.mock_test.go . resourceManager
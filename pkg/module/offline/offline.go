@@ -46,9 +46,17 @@ const ModuleName = "offline"
 type Config struct {
 	// QueueSize defines maximum offline queue size.
 	QueueSize int `fig:"queue_size" default:"200"`
+
+	// QueueBytes defines maximum offline queue size in bytes. Zero means no limit.
+	QueueBytes int `fig:"queue_bytes"`
+
+	// TTL defines the maximum amount of time a message is kept in the offline queue,
+	// regardless of its own expiration (XEP-0023). Zero means no limit.
+	TTL time.Duration `fig:"ttl"`
 }
 
 // Offline represents offline module type.
+// Besides flooding queued messages on initial presence, it implements flexible offline message retrieval (XEP-0013).
 type Offline struct {
 	cfg    Config
 	hosts  hosts
@@ -87,7 +95,7 @@ func (m *Offline) StreamFeature(_ context.Context, _ string) (stravaganza.Elemen
 
 // ServerFeatures returns offline module server disco features.
 func (m *Offline) ServerFeatures(_ context.Context) ([]string, error) {
-	return []string{offlineFeature}, nil
+	return []string{offlineFeature, offlineNamespace}, nil
 }
 
 // AccountFeatures returns offline module account disco features.
@@ -142,6 +150,11 @@ func (m *Offline) onMessageRouted(execCtx *hook.ExecutionContext) error {
 	if !m.hosts.IsLocalHost(toJID.Domain()) {
 		return nil
 	}
+	if m.hosts.IsAnonymousHost(toJID.Domain()) {
+		// anonymous users are ephemeral... there's no point in queueing their messages
+		_, _ = m.router.Route(execCtx.Context, xmpputil.MakeErrorStanza(msg, stanzaerror.ServiceUnavailable))
+		return hook.ErrStopped // already handled
+	}
 	return m.archiveMessage(execCtx.Context, msg)
 }
 
@@ -151,6 +164,10 @@ func (m *Offline) onC2SPresenceRecv(execCtx *hook.ExecutionContext) error {
 		// user has already queried the MAM archive.
 		return nil
 	}
+	if stm.Info().Bool(flexibleRetrievalCtxKey) {
+		// user retrieves offline messages on demand (XEP-0013)
+		return nil
+	}
 	inf := execCtx.Info.(*hook.C2SStreamInfo)
 
 	pr := inf.Element.(*stravaganza.Presence)
@@ -200,10 +217,18 @@ func (m *Offline) deliverOfflineMessages(ctx context.Context, stm stream.C2S) er
 		return err
 	}
 	// route offline messages
+	var delivered int
+
+	now := time.Now()
 	for _, msg := range ms {
-		stm.SendElement(msg)
+		dMsg := m.deliverable(msg, now)
+		if dMsg == nil {
+			continue // expired message
+		}
+		stm.SendElement(dMsg)
+		delivered++
 	}
-	level.Info(m.logger).Log("msg", "delivered offline messages", "queue_size", len(ms), "username", username)
+	level.Info(m.logger).Log("msg", "delivered offline messages", "queue_size", len(ms), "delivered", delivered, "username", username)
 
 	return nil
 }
@@ -212,6 +237,10 @@ func (m *Offline) archiveMessage(ctx context.Context, msg *stravaganza.Message)
 	toJID := msg.ToJID()
 	username := toJID.Node()
 
+	if isExpirationElapsed(msg) {
+		// there's no point in queueing an already expired message (XEP-0023)
+		return hook.ErrStopped
+	}
 	lockID := offlineQueueLockID(username)
 
 	if err := m.rep.Lock(ctx, lockID); err != nil {
@@ -227,9 +256,21 @@ func (m *Offline) archiveMessage(ctx context.Context, msg *stravaganza.Message)
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.ServiceUnavailable))
 		return hook.ErrStopped // already handled
 	}
-	// add delay info
-	dMsg := xmpputil.MakeDelayMessage(msg, time.Now(), toJID.Domain(), "Offline Storage")
-
+	// add delay and expiration info
+	now := time.Now()
+	dMsg := stampExpiration(xmpputil.MakeDelayMessage(msg, now, toJID.Domain(), "Offline Storage"), now)
+
+	if m.cfg.QueueBytes > 0 {
+		exceeded, err := m.exceedsQueueBytes(ctx, username, dMsg)
+		if err != nil {
+			return err
+		}
+		if exceeded {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.ServiceUnavailable))
+			level.Info(m.logger).Log("msg", "offline queue quota exceeded", "username", username)
+			return hook.ErrStopped // already handled
+		}
+	}
 	// enqueue offline message
 	if err := m.rep.InsertOfflineMessage(ctx, dMsg, username); err != nil {
 		return err
@@ -250,6 +291,18 @@ func (m *Offline) archiveMessage(ctx context.Context, msg *stravaganza.Message)
 	return hook.ErrStopped // already handled
 }
 
+func (m *Offline) exceedsQueueBytes(ctx context.Context, username string, msg *stravaganza.Message) (bool, error) {
+	qBytes, err := m.rep.CountOfflineBytes(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	b, err := msg.MarshalBinary()
+	if err != nil {
+		return false, err
+	}
+	return qBytes+len(b) > m.cfg.QueueBytes, nil
+}
+
 func (m *Offline) releaseLock(ctx context.Context, lockID string) {
 	if err := m.rep.Unlock(ctx, lockID); err != nil {
 		level.Warn(m.logger).Log("msg", "failed to release lock", "err", err)
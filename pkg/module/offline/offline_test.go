@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"testing"
+	"time"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/jackal-xmpp/stravaganza"
@@ -42,6 +43,7 @@ func TestOffline_ArchiveOfflineMessage(t *testing.T) {
 	}
 	hostsMock := &hostsMock{}
 	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+	hostsMock.IsAnonymousHostFunc = func(h string) bool { return false }
 
 	hk := hook.NewHooks()
 	m := &Offline{
@@ -88,6 +90,7 @@ func TestOffline_ArchiveOfflineMessageQueueFull(t *testing.T) {
 	}
 	hostsMock := &hostsMock{}
 	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+	hostsMock.IsAnonymousHostFunc = func(h string) bool { return false }
 
 	repMock := &repositoryMock{}
 	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
@@ -144,12 +147,67 @@ func TestOffline_ArchiveOfflineMessageQueueFull(t *testing.T) {
 	require.Equal(t, `<message from='ortuman@jackal.im/balcony' to='noelia@jackal.im/yard' type='error'><body>I&#39;ll give thee a wind.</body><error code='503' type='cancel'><service-unavailable xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></message>`, output.String())
 }
 
+func TestOffline_ArchiveOfflineMessageAnonymousUser(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	output := bytes.NewBuffer(nil)
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		_ = stanza.ToXML(output, true)
+		return nil, nil
+	}
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" || h == "anon.jackal.im" }
+	hostsMock.IsAnonymousHostFunc = func(h string) bool { return h == "anon.jackal.im" }
+
+	repMock := &repositoryMock{}
+
+	hk := hook.NewHooks()
+	m := &Offline{
+		cfg:    Config{QueueSize: 100},
+		router: routerMock,
+		hosts:  hostsMock,
+		rep:    repMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "noelia@jackal.im/yard")
+	b.WithAttribute("to", "5c5fa8c6@anon.jackal.im/balcony")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	msg, _ := b.BuildMessage()
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	halted, err := hk.Run(hook.C2SStreamMessageRouted, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: msg,
+		},
+		Context: context.Background(),
+	})
+
+	// then
+	require.Nil(t, err)
+	require.True(t, halted)
+
+	require.Len(t, repMock.InsertOfflineMessageCalls(), 0)
+
+	require.Equal(t, `<message from='5c5fa8c6@anon.jackal.im/balcony' to='noelia@jackal.im/yard' type='error'><body>I&#39;ll give thee a wind.</body><error code='503' type='cancel'><service-unavailable xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></message>`, output.String())
+}
+
 func TestOffline_DeliverOfflineMessages(t *testing.T) {
 	// given
 	routerMock := &routerMock{}
 
 	hostsMock := &hostsMock{}
 	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+	hostsMock.IsAnonymousHostFunc = func(h string) bool { return false }
 
 	repMock := &repositoryMock{}
 	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
@@ -223,3 +281,217 @@ func TestOffline_DeliverOfflineMessages(t *testing.T) {
 
 	require.Equal(t, `<message from='noelia@jackal.im/yard' to='ortuman@jackal.im/balcony'><body>I&#39;ll give thee a wind.</body></message>`, output.String())
 }
+
+func TestOffline_ArchiveOfflineMessageQuotaExceeded(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+	hostsMock.IsAnonymousHostFunc = func(h string) bool { return false }
+
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+
+	repMock.CountOfflineMessagesFunc = func(ctx context.Context, username string) (int, error) {
+		return 1, nil
+	}
+	repMock.CountOfflineBytesFunc = func(ctx context.Context, username string) (int, error) {
+		return 1000, nil
+	}
+	repMock.InsertOfflineMessageFunc = func(ctx context.Context, message *stravaganza.Message, username string) error {
+		return nil
+	}
+
+	hk := hook.NewHooks()
+	m := &Offline{
+		cfg:    Config{QueueSize: 100, QueueBytes: 1024},
+		router: routerMock,
+		hosts:  hostsMock,
+		rep:    repMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "noelia@jackal.im/yard")
+	b.WithAttribute("to", "ortuman@jackal.im/balcony")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	msg, _ := b.BuildMessage()
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	halted, err := hk.Run(hook.C2SStreamMessageRouted, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: msg,
+		},
+		Context: context.Background(),
+	})
+
+	// then
+	require.Nil(t, err)
+	require.True(t, halted)
+
+	require.Len(t, repMock.CountOfflineBytesCalls(), 1)
+	require.Len(t, repMock.InsertOfflineMessageCalls(), 0)
+
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, respStanzas[0].Type())
+	require.NotNil(t, respStanzas[0].Child("error").Child("service-unavailable"))
+}
+
+func TestOffline_ArchiveExpiringMessage(t *testing.T) {
+	// given
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+	hostsMock.IsAnonymousHostFunc = func(h string) bool { return false }
+
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+
+	repMock.CountOfflineMessagesFunc = func(ctx context.Context, username string) (int, error) {
+		return 0, nil
+	}
+	var stored []*stravaganza.Message
+	repMock.InsertOfflineMessageFunc = func(ctx context.Context, message *stravaganza.Message, username string) error {
+		stored = append(stored, message)
+		return nil
+	}
+
+	hk := hook.NewHooks()
+	m := &Offline{
+		cfg:    Config{QueueSize: 100},
+		hosts:  hostsMock,
+		rep:    repMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	for _, seconds := range []string{"0", "1800"} {
+		_, _ = hk.Run(hook.C2SStreamMessageRouted, &hook.ExecutionContext{
+			Info: &hook.C2SStreamInfo{
+				Element: testExpiringMessage(seconds, nil),
+			},
+			Context: context.Background(),
+		})
+	}
+
+	// then
+	require.Len(t, stored, 1)
+
+	x := stored[0].ChildNamespace("x", expireNamespace)
+	require.NotNil(t, x)
+	require.Equal(t, "1800", x.Attribute("seconds"))
+	require.NotEmpty(t, x.Attribute("stored"))
+}
+
+func TestOffline_DeliverSkipsExpiredMessages(t *testing.T) {
+	// given
+	now := time.Now()
+
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+
+	repMock.FetchOfflineMessagesFunc = func(ctx context.Context, username string) ([]*stravaganza.Message, error) {
+		hourAgo := now.Add(-time.Hour)
+		twoDaysAgo := now.Add(-48 * time.Hour)
+		minuteAgo := now.Add(-time.Minute)
+
+		return []*stravaganza.Message{
+			testExpiringMessage("60", &hourAgo),     // expired
+			testExpiringMessage("", &twoDaysAgo),    // exceeds TTL
+			testExpiringMessage("3600", &minuteAgo), // pending
+		}, nil
+	}
+	repMock.DeleteOfflineMessagesFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+
+	stmMock := &c2sStreamMock{}
+	stmMock.UsernameFunc = func() string {
+		return "ortuman"
+	}
+	var sent []stravaganza.Element
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sent = append(sent, elem)
+		ch := make(chan error)
+		close(ch)
+		return ch
+	}
+
+	m := &Offline{
+		cfg:    Config{QueueSize: 100, TTL: 24 * time.Hour},
+		rep:    repMock,
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	err := m.deliverOfflineMessages(context.Background(), stmMock)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, repMock.DeleteOfflineMessagesCalls(), 1)
+
+	require.Len(t, sent, 1)
+	x := sent[0].ChildNamespace("x", expireNamespace)
+	require.NotNil(t, x)
+	require.Equal(t, "3540", x.Attribute("seconds"))
+}
+
+func testExpiringMessage(seconds string, queuedAt *time.Time) *stravaganza.Message {
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "noelia@jackal.im/yard")
+	b.WithAttribute("to", "ortuman@jackal.im/balcony")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	if len(seconds) > 0 {
+		b.WithChild(
+			stravaganza.NewBuilder("x").
+				WithAttribute(stravaganza.Namespace, expireNamespace).
+				WithAttribute("seconds", seconds).
+				Build(),
+		)
+	}
+	msg, _ := b.BuildMessage()
+	if queuedAt != nil {
+		msg = xmpputil.MakeDelayMessage(msg, *queuedAt, "jackal.im", "Offline Storage")
+	}
+	return msg
+}
+
+func TestOffline_QueuedAtIgnoresSenderDelay(t *testing.T) {
+	// given
+	storedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	senderStamp := storedAt.Add(-365 * 24 * time.Hour)
+
+	msg := xmpputil.MakeDelayMessage(testExpiringMessage("", nil), senderStamp, "jackal.im", "")
+	msg = xmpputil.MakeDelayMessage(msg, storedAt, "jackal.im", "Offline Storage")
+
+	expiringMsg := xmpputil.MakeDelayMessage(testExpiringMessage("3600", nil), senderStamp, "jackal.im", "")
+	expiringMsg = stampExpiration(xmpputil.MakeDelayMessage(expiringMsg, storedAt, "jackal.im", "Offline Storage"), storedAt)
+
+	// then
+	require.True(t, storedAt.Equal(queuedAt(msg)))
+	require.True(t, storedAt.Equal(queuedAt(expiringMsg)))
+}
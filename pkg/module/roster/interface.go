@@ -34,6 +34,7 @@ type repTransaction interface {
 //go:generate moq -out hosts.mock_test.go . hosts
 type hosts interface {
 	IsLocalHost(h string) bool
+	IsAnonymousHost(h string) bool
 }
 
 //go:generate moq -out router.mock_test.go . globalRouter:routerMock
@@ -116,6 +116,7 @@ func (r *Roster) Start(_ context.Context) error {
 	r.hk.AddHook(hook.C2SStreamPresenceReceived, r.onPresenceRecv, hook.DefaultPriority)
 	r.hk.AddHook(hook.S2SInStreamPresenceReceived, r.onPresenceRecv, hook.DefaultPriority)
 	r.hk.AddHook(hook.UserDeleted, r.onUserDeleted, hook.DefaultPriority)
+	r.hk.AddHook(hook.InviteRedeemed, r.onInviteRedeemed, hook.DefaultPriority)
 
 	level.Info(r.logger).Log("msg", "started roster module")
 	return nil
@@ -126,6 +127,7 @@ func (r *Roster) Stop(_ context.Context) error {
 	r.hk.RemoveHook(hook.C2SStreamPresenceReceived, r.onPresenceRecv)
 	r.hk.RemoveHook(hook.S2SInStreamPresenceReceived, r.onPresenceRecv)
 	r.hk.RemoveHook(hook.UserDeleted, r.onUserDeleted)
+	r.hk.RemoveHook(hook.InviteRedeemed, r.onInviteRedeemed)
 
 	level.Info(r.logger).Log("msg", "stopped roster module")
 	return nil
@@ -162,6 +164,34 @@ func (r *Roster) onUserDeleted(execCtx *hook.ExecutionContext) error {
 	})
 }
 
+func (r *Roster) onInviteRedeemed(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.InviteInfo)
+	if inf.Inviter == nil {
+		return nil // issued by an administrator
+	}
+	if err := r.subscribeMutually(execCtx.Context, inf.Invitee, inf.Inviter); err != nil {
+		return fmt.Errorf("roster: failed to subscribe invitee: %w", err)
+	}
+	return nil
+}
+
+// subscribeMutually establishes a 'both' subscription between userJID and contactJID
+// by replaying the regular subscription handshake in both directions.
+func (r *Roster) subscribeMutually(ctx context.Context, userJID, contactJID *jid.JID) error {
+	for _, pr := range []*stravaganza.Presence{
+		xmpputil.MakePresence(userJID, contactJID, stravaganza.SubscribeType, nil),
+		xmpputil.MakePresence(contactJID, userJID, stravaganza.SubscribedType, nil),
+		xmpputil.MakePresence(contactJID, userJID, stravaganza.SubscribeType, nil),
+		xmpputil.MakePresence(userJID, contactJID, stravaganza.SubscribedType, nil),
+	} {
+		if err := r.processPresence(ctx, pr); err != nil {
+			return err
+		}
+	}
+	level.Info(r.logger).Log("msg", "established mutual subscription", "jid", contactJID, "username", userJID.Node())
+	return nil
+}
+
 func (r *Roster) processPresence(ctx context.Context, pr *stravaganza.Presence) error {
 	switch pr.Attribute(stravaganza.Type) {
 	case stravaganza.SubscribeType:
@@ -308,8 +338,8 @@ func (r *Roster) processSubscribe(ctx context.Context, presence *stravaganza.Pre
 	// stamp the presence stanza of type "subscribe" with the user's bare JID as the 'from' address
 	p := xmpputil.MakePresence(userJID, contactJID, stravaganza.SubscribeType, presence.AllChildren())
 
-	if r.hosts.IsLocalHost(contactJID.Domain()) {
-		// archive roster approval notification
+	if r.hosts.IsLocalHost(contactJID.Domain()) && !r.hosts.IsAnonymousHost(contactJID.Domain()) {
+		// archive roster approval notification (not applicable to ephemeral anonymous users)
 		if err := r.upsertNotification(ctx, contactJID.Node(), userJID, p); err != nil {
 			return err
 		}
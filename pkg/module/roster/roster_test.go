@@ -73,6 +73,7 @@ func TestRoster_SendRoster(t *testing.T) {
 	hMock.IsLocalHostFunc = func(h string) bool {
 		return h == "jackal.im"
 	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
 	resMngMock := &resourceManagerMock{}
 
 	r := &Roster{
@@ -144,6 +145,7 @@ func TestRoster_UpdateItem(t *testing.T) {
 	hMock.IsLocalHostFunc = func(h string) bool {
 		return h == "jackal.im"
 	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
 	resMngMock := &resourceManagerMock{}
 
 	jd0, _ := jid.New("ortuman", "jackal.im", "yard", true)
@@ -251,6 +253,7 @@ func TestRoster_RemoveItem(t *testing.T) {
 	hMock.IsLocalHostFunc = func(h string) bool {
 		return h == "jackal.im"
 	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
 	jd0, _ := jid.New("ortuman", "jackal.im", "yard", true)
 	jd1, _ := jid.New("ortuman", "jackal.im", "balcony", true)
 
@@ -363,6 +366,7 @@ func TestRoster_Subscribe(t *testing.T) {
 	hMock.IsLocalHostFunc = func(h string) bool {
 		return h == "jackal.im"
 	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
 	jd0, _ := jid.New("ortuman", "jackal.im", "yard", true)
 	jd1, _ := jid.New("ortuman", "jackal.im", "balcony", true)
 
@@ -459,6 +463,7 @@ func TestRoster_Subscribed(t *testing.T) {
 	hMock.IsLocalHostFunc = func(h string) bool {
 		return h == "jackal.im"
 	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
 	jd0, _ := jid.New("ortuman", "jackal.im", "balcony", true)
 	jd1, _ := jid.New("noelia", "jackal.im", "yard", true)
 
@@ -524,6 +529,97 @@ func TestRoster_Subscribed(t *testing.T) {
 	require.Equal(t, stravaganza.AvailableType, availPr.Attribute("type"))
 }
 
+func TestRoster_InviteRedeemed(t *testing.T) {
+	// given
+	var mtx sync.RWMutex
+
+	items := make(map[string]*rostermodel.Item)
+
+	repMock := &repositoryMock{}
+	repMock.FetchRosterItemFunc = func(ctx context.Context, username string, jid string) (*rostermodel.Item, error) {
+		mtx.RLock()
+		defer mtx.RUnlock()
+		return items[username+":"+jid], nil
+	}
+	txMock := &txMock{}
+	txMock.TouchRosterVersionFunc = func(ctx context.Context, username string) (int, error) {
+		return 1, nil
+	}
+	txMock.UpsertRosterItemFunc = func(ctx context.Context, ri *rostermodel.Item) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		items[ri.Username+":"+ri.Jid] = ri
+		return nil
+	}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, txMock)
+	}
+	repMock.UpsertRosterNotificationFunc = func(ctx context.Context, rn *rostermodel.Notification) error {
+		return nil
+	}
+	repMock.FetchRosterNotificationFunc = func(ctx context.Context, contact string, jid string) (*rostermodel.Notification, error) {
+		return &rostermodel.Notification{Contact: contact, Jid: jid}, nil
+	}
+	repMock.DeleteRosterNotificationFunc = func(ctx context.Context, contact string, jid string) error {
+		return nil
+	}
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		return nil, nil
+	}
+	hMock := &hostsMock{}
+	hMock.IsLocalHostFunc = func(h string) bool {
+		return h == "jackal.im"
+	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+		return nil, nil
+	}
+
+	hk := hook.NewHooks()
+	r := &Roster{
+		rep:    repMock,
+		resMng: resMngMock,
+		router: routerMock,
+		hosts:  hMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	// when
+	inviteeJID, _ := jid.NewWithString("ortuman@jackal.im", true)
+	inviterJID, _ := jid.NewWithString("noelia@jackal.im", true)
+
+	_ = r.Start(context.Background())
+	_, err := hk.Run(hook.InviteRedeemed, &hook.ExecutionContext{
+		Info: &hook.InviteInfo{
+			Token:   "t0k3n",
+			Inviter: inviterJID,
+			Invitee: inviteeJID,
+		},
+		Context: context.Background(),
+	})
+
+	// then
+	require.Nil(t, err)
+
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	require.Len(t, items, 2)
+
+	usrRi := items["ortuman:noelia@jackal.im"]
+	require.NotNil(t, usrRi)
+	require.Equal(t, rostermodel.Both, usrRi.Subscription)
+	require.False(t, usrRi.Ask)
+
+	cntRi := items["noelia:ortuman@jackal.im"]
+	require.NotNil(t, cntRi)
+	require.Equal(t, rostermodel.Both, cntRi.Subscription)
+	require.False(t, cntRi.Ask)
+}
+
 func TestRoster_Unsubscribe(t *testing.T) {
 	// given
 	var mtx sync.RWMutex
@@ -570,6 +666,7 @@ func TestRoster_Unsubscribe(t *testing.T) {
 	hMock.IsLocalHostFunc = func(h string) bool {
 		return h == "jackal.im"
 	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
 	jd0, _ := jid.New("ortuman", "jackal.im", "balcony", true)
 	jd1, _ := jid.New("noelia", "jackal.im", "yard", true)
 
@@ -684,6 +781,7 @@ func TestRoster_Unsubscribed(t *testing.T) {
 	hMock.IsLocalHostFunc = func(h string) bool {
 		return h == "jackal.im"
 	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
 	jd0, _ := jid.New("ortuman", "jackal.im", "balcony", true)
 	jd1, _ := jid.New("noelia", "jackal.im", "yard", true)
 
@@ -780,6 +878,7 @@ func TestRoster_Probe(t *testing.T) {
 	hMock.IsLocalHostFunc = func(h string) bool {
 		return h == "jackal.im"
 	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
 	jd0, _ := jid.New("ortuman", "jackal.im", "balcony", true)
 
 	resMngMock := &resourceManagerMock{}
@@ -890,6 +989,7 @@ func TestRoster_Available(t *testing.T) {
 	hMock.IsLocalHostFunc = func(h string) bool {
 		return h == "jackal.im"
 	}
+	hMock.IsAnonymousHostFunc = func(h string) bool { return false }
 	jd1, _ := jid.New("noelia", "jackal.im", "yard", true)
 
 	resMngMock := &resourceManagerMock{}
@@ -45,11 +45,23 @@ func newAccountProvider(
 	}
 }
 
-func (p *accountProvider) Identities(_ context.Context, _, _ *jid.JID, _ string) []discomodel.Identity {
+func (p *accountProvider) Identities(ctx context.Context, toJID, fromJID *jid.JID, node string) []discomodel.Identity {
+	if len(node) > 0 {
+		if prov := p.nodeProvider(node); prov != nil {
+			return prov.Identities(ctx, toJID, fromJID, node)
+		}
+		return nil
+	}
 	return []discomodel.Identity{{Type: "registered", Category: "account"}}
 }
 
-func (p *accountProvider) Items(ctx context.Context, toJID, fromJID *jid.JID, _ string) ([]discomodel.Item, error) {
+func (p *accountProvider) Items(ctx context.Context, toJID, fromJID *jid.JID, node string) ([]discomodel.Item, error) {
+	if len(node) > 0 {
+		if prov := p.nodeProvider(node); prov != nil {
+			return prov.Items(ctx, toJID, fromJID, node)
+		}
+		return nil, ErrEntityNotFound
+	}
 	if err := p.checkIfSubscribedTo(ctx, toJID, fromJID); err != nil {
 		return nil, err
 	}
@@ -68,7 +80,13 @@ func (p *accountProvider) Items(ctx context.Context, toJID, fromJID *jid.JID, _
 	return items, nil
 }
 
-func (p *accountProvider) Features(ctx context.Context, toJID, fromJID *jid.JID, _ string) ([]discomodel.Feature, error) {
+func (p *accountProvider) Features(ctx context.Context, toJID, fromJID *jid.JID, node string) ([]discomodel.Feature, error) {
+	if len(node) > 0 {
+		if prov := p.nodeProvider(node); prov != nil {
+			return prov.Features(ctx, toJID, fromJID, node)
+		}
+		return nil, ErrEntityNotFound
+	}
 	if err := p.checkIfSubscribedTo(ctx, toJID, fromJID); err != nil {
 		return nil, err
 	}
@@ -85,9 +103,27 @@ func (p *accountProvider) Features(ctx context.Context, toJID, fromJID *jid.JID,
 }
 
 func (p *accountProvider) Forms(ctx context.Context, toJID, fromJID *jid.JID, node string) ([]xep0004.DataForm, error) {
+	if len(node) > 0 {
+		if prov := p.nodeProvider(node); prov != nil {
+			return prov.Forms(ctx, toJID, fromJID, node)
+		}
+	}
 	return nil, nil
 }
 
+func (p *accountProvider) nodeProvider(node string) InfoProvider {
+	for _, mod := range p.mods {
+		np, ok := mod.(AccountNodeProvider)
+		if !ok {
+			continue
+		}
+		if prov := np.NodeProvider(node); prov != nil {
+			return prov
+		}
+	}
+	return nil
+}
+
 func (p *accountProvider) checkIfSubscribedTo(ctx context.Context, toJID, fromJID *jid.JID) error {
 	isSubscribed, err := p.isSubscribedTo(ctx, toJID, fromJID)
 	if err != nil {
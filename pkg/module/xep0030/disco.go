@@ -41,6 +41,9 @@ const (
 
 var errSubscriptionRequired = errors.New("xep0030: subscription required")
 
+// ErrEntityNotFound can be returned by an InfoProvider to report that the requested entity or node does not exist.
+var ErrEntityNotFound = errors.New("xep0030: entity not found")
+
 // InfoProvider represents a general entity disco info provider interface.
 type InfoProvider interface {
 	// Identities returns all identities associated to the provider.
@@ -56,6 +59,13 @@ type InfoProvider interface {
 	Forms(ctx context.Context, toJID, fromJID *jid.JID, node string) ([]xep0004.DataForm, error)
 }
 
+// AccountNodeProvider can be implemented by modules serving their own account disco nodes.
+type AccountNodeProvider interface {
+	// NodeProvider returns the info provider associated to an account node,
+	// or nil in case node is not served by the module.
+	NodeProvider(node string) InfoProvider
+}
+
 const (
 	// ModuleName represents disco module name.
 	ModuleName = "disco"
@@ -196,31 +206,45 @@ func (m *Disco) getDiscoInfo(ctx context.Context, iq *stravaganza.IQ) error {
 	if prov == nil {
 		return nil // modules not set
 	}
+	return ProcessProviderIQ(ctx, m.router, prov, iq)
+}
+
+// ProcessProviderIQ answers a disco info or disco items iq using the information returned by prov.
+// It can be used by components to serve their own disco entities.
+func ProcessProviderIQ(ctx context.Context, rtr router.Router, prov InfoProvider, iq *stravaganza.IQ) error {
+	q := iq.Child("query")
+	if q == nil || !iq.IsGet() {
+		_, _ = rtr.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
 	fromJID := iq.FromJID()
 	toJID := iq.ToJID()
 
 	node := q.Attribute("node")
 	switch q.Attribute(stravaganza.Namespace) {
 	case discoInfoNamespace:
-		return m.sendDiscoInfo(ctx, prov, toJID, fromJID, node, iq)
+		return sendDiscoInfo(ctx, rtr, prov, toJID, fromJID, node, iq)
 	case discoItemsNamespace:
-		return m.sendDiscoItems(ctx, prov, toJID, fromJID, node, iq)
+		return sendDiscoItems(ctx, rtr, prov, toJID, fromJID, node, iq)
 	default:
-		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		_, _ = rtr.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
 		return nil
 	}
 }
 
-func (m *Disco) sendDiscoInfo(ctx context.Context, prov InfoProvider, toJID, fromJID *jid.JID, node string, iq *stravaganza.IQ) error {
+func sendDiscoInfo(ctx context.Context, rtr router.Router, prov InfoProvider, toJID, fromJID *jid.JID, node string, iq *stravaganza.IQ) error {
 	features, err := prov.Features(ctx, toJID, fromJID, node)
 	switch {
 	case err == nil:
 		break
 	case errors.Is(err, errSubscriptionRequired):
-		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.SubscriptionRequired))
+		_, _ = rtr.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.SubscriptionRequired))
+		return nil
+	case errors.Is(err, ErrEntityNotFound):
+		_, _ = rtr.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
 		return nil
 	default:
-		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		_, _ = rtr.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
 		return err
 	}
 	sb := stravaganza.NewBuilder("query").
@@ -253,20 +277,23 @@ func (m *Disco) sendDiscoInfo(ctx context.Context, prov InfoProvider, toJID, fro
 	for _, form := range forms {
 		sb.WithChild(form.Element())
 	}
-	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, sb.Build()))
+	_, _ = rtr.Route(ctx, xmpputil.MakeResultIQ(iq, sb.Build()))
 	return nil
 }
 
-func (m *Disco) sendDiscoItems(ctx context.Context, prov InfoProvider, toJID, fromJID *jid.JID, node string, iq *stravaganza.IQ) error {
+func sendDiscoItems(ctx context.Context, rtr router.Router, prov InfoProvider, toJID, fromJID *jid.JID, node string, iq *stravaganza.IQ) error {
 	items, err := prov.Items(ctx, toJID, fromJID, node)
 	switch {
 	case err == nil:
 		break
 	case errors.Is(err, errSubscriptionRequired):
-		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.SubscriptionRequired))
+		_, _ = rtr.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.SubscriptionRequired))
+		return nil
+	case errors.Is(err, ErrEntityNotFound):
+		_, _ = rtr.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
 		return nil
 	default:
-		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		_, _ = rtr.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
 		return err
 	}
 	qb := stravaganza.NewBuilder("query").
@@ -283,6 +310,6 @@ func (m *Disco) sendDiscoItems(ctx context.Context, prov InfoProvider, toJID, fr
 		}
 		qb.WithChild(itemB.Build())
 	}
-	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, qb.Build()))
+	_, _ = rtr.Route(ctx, xmpputil.MakeResultIQ(iq, qb.Build()))
 	return nil
 }
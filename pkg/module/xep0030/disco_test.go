@@ -25,8 +25,10 @@ import (
 	"github.com/ortuman/jackal/pkg/component"
 	"github.com/ortuman/jackal/pkg/hook"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
 	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
 	"github.com/ortuman/jackal/pkg/module"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
 	"github.com/stretchr/testify/require"
 )
 
@@ -308,3 +310,148 @@ func TestDisco_GetAccountItems(t *testing.T) {
 
 	require.Equal(t, "noelia@jackal.im/chamber", items[0].Attribute("jid"))
 }
+
+type testNodeModule struct {
+	*moduleMock
+	node string
+	prov InfoProvider
+}
+
+func (m *testNodeModule) NodeProvider(node string) InfoProvider {
+	if node != m.node {
+		return nil
+	}
+	return m.prov
+}
+
+func TestDisco_GetAccountNodeInfo(t *testing.T) {
+	// given
+	provMock := &infoProviderMock{}
+	provMock.IdentitiesFunc = func(ctx context.Context, toJID *jid.JID, fromJID *jid.JID, node string) []discomodel.Identity {
+		return []discomodel.Identity{{Category: "automation", Type: "message-list"}}
+	}
+	provMock.FeaturesFunc = func(ctx context.Context, toJID *jid.JID, fromJID *jid.JID, node string) ([]discomodel.Feature, error) {
+		return []discomodel.Feature{"http://jabber.org/protocol/offline"}, nil
+	}
+	provMock.FormsFunc = func(ctx context.Context, toJID *jid.JID, fromJID *jid.JID, node string) ([]xep0004.DataForm, error) {
+		return nil, nil
+	}
+	modMock := &moduleMock{}
+	modMock.AccountFeaturesFunc = func(_ context.Context) ([]string, error) {
+		return []string{"https://jackal.im#feature-1"}, nil
+	}
+
+	routerMock := &routerMock{}
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	hk := hook.NewHooks()
+	d := &Disco{
+		router: routerMock,
+		rosRep: &rosterRepositoryMock{},
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	_ = d.Start(context.Background())
+	defer func() { _ = d.Stop(context.Background()) }()
+
+	modsMock := &modulesMock{}
+	modsMock.AllModulesFunc = func() []module.Module {
+		return []module.Module{&testNodeModule{moduleMock: modMock, node: "http://jabber.org/protocol/offline", prov: provMock}, d}
+	}
+	_, _ = hk.Run(hook.ModulesStarted, &hook.ExecutionContext{
+		Sender:  modsMock,
+		Context: context.Background(),
+	})
+
+	// when
+	for _, node := range []string{"http://jabber.org/protocol/offline", "urn:xmpp:unknown"} {
+		iq, _ := stravaganza.NewIQBuilder().
+			WithAttribute(stravaganza.ID, "id1234").
+			WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+			WithAttribute(stravaganza.To, "ortuman@jackal.im").
+			WithAttribute(stravaganza.Type, stravaganza.GetType).
+			WithChild(
+				stravaganza.NewBuilder("query").
+					WithAttribute(stravaganza.Namespace, discoInfoNamespace).
+					WithAttribute("node", node).
+					Build(),
+			).
+			BuildIQ()
+		_ = d.ProcessIQ(context.Background(), iq)
+	}
+
+	// then
+	require.Len(t, respStanzas, 2)
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Attribute("type"))
+
+	query := respStanzas[0].ChildNamespace("query", discoInfoNamespace)
+	require.NotNil(t, query)
+	require.Equal(t, "automation", query.Child("identity").Attribute("category"))
+	require.Len(t, query.Children("feature"), 1)
+	require.Equal(t, "http://jabber.org/protocol/offline", query.Child("feature").Attribute("var"))
+
+	require.Equal(t, stravaganza.ErrorType, respStanzas[1].Attribute("type"))
+	require.NotNil(t, respStanzas[1].Child("error").Child("item-not-found"))
+}
+
+func TestDisco_ProcessProviderIQ(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	provMock := &infoProviderMock{}
+	provMock.IdentitiesFunc = func(ctx context.Context, toJID *jid.JID, fromJID *jid.JID, node string) []discomodel.Identity {
+		return []discomodel.Identity{{Category: "conference", Type: "text", Name: "Lobby"}}
+	}
+	provMock.FeaturesFunc = func(ctx context.Context, toJID *jid.JID, fromJID *jid.JID, node string) ([]discomodel.Feature, error) {
+		if toJID.Node() != "lobby" {
+			return nil, ErrEntityNotFound
+		}
+		return []discomodel.Feature{"http://jabber.org/protocol/muc"}, nil
+	}
+	provMock.FormsFunc = func(ctx context.Context, toJID *jid.JID, fromJID *jid.JID, node string) ([]xep0004.DataForm, error) {
+		return nil, nil
+	}
+
+	// when
+	iq0 := discoInfoIQ("lobby@conference.jackal.im")
+	iq1 := discoInfoIQ("nowhere@conference.jackal.im")
+
+	err0 := ProcessProviderIQ(context.Background(), routerMock, provMock, iq0)
+	err1 := ProcessProviderIQ(context.Background(), routerMock, provMock, iq1)
+
+	// then
+	require.NoError(t, err0)
+	require.NoError(t, err1)
+	require.Len(t, respStanzas, 2)
+
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Attribute(stravaganza.Type))
+	query := respStanzas[0].ChildNamespace("query", discoInfoNamespace)
+	require.NotNil(t, query)
+	require.Equal(t, "conference", query.Child("identity").Attribute("category"))
+	require.Len(t, query.Children("feature"), 1)
+
+	require.Equal(t, stravaganza.ErrorType, respStanzas[1].Attribute(stravaganza.Type))
+	require.NotNil(t, respStanzas[1].Child("error").Child("item-not-found"))
+}
+
+func discoInfoIQ(to string) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "id1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, to).
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, discoInfoNamespace).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
@@ -56,3 +56,5 @@ type discoComponent interface {
 type discoModule interface {
 	module.Module
 }
+
+//go:generate moq -out info_provider.mock_test.go . InfoProvider:infoProviderMock
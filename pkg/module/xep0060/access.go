@@ -0,0 +1,149 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0060
+
+import (
+	"context"
+
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+)
+
+type accessDenial struct {
+	reason    stanzaerror.Reason
+	condition string
+}
+
+// checkAccess tells whether iq sender is allowed to access node.
+// In case access is denied an error response is sent back.
+func (s *Service) checkAccess(ctx context.Context, node *pubsubmodel.Node, iq *stravaganza.IQ) (bool, error) {
+	denial, err := s.accessDenial(ctx, node, iq.FromJID())
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return false, err
+	}
+	if denial != nil {
+		var appElem stravaganza.Element
+		if len(denial.condition) > 0 {
+			appElem = errorCondition(denial.condition)
+		}
+		s.sendError(ctx, iq, denial.reason, appElem)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *Service) canAccess(ctx context.Context, node *pubsubmodel.Node, jd *jid.JID) (bool, error) {
+	denial, err := s.accessDenial(ctx, node, jd)
+	if err != nil {
+		return false, err
+	}
+	return denial == nil, nil
+}
+
+// accessDenial returns the reason why jd is not allowed to access node, or nil if access is granted.
+func (s *Service) accessDenial(ctx context.Context, node *pubsubmodel.Node, jd *jid.JID) (*accessDenial, error) {
+	switch affiliation(node, jd) {
+	case ownerAffiliation, publisherAffiliation:
+		return nil, nil
+	case outcastAffiliation:
+		return &accessDenial{reason: stanzaerror.Forbidden}, nil
+	}
+	switch node.Options.AccessModel {
+	case OpenAccessModel:
+		return nil, nil
+
+	case WhitelistAccessModel:
+		if affiliation(node, jd) == memberAffiliation {
+			return nil, nil
+		}
+		return &accessDenial{reason: stanzaerror.NotAllowed, condition: "closed-node"}, nil
+
+	case PresenceAccessModel:
+		ri, err := s.ownerRosterItem(ctx, node, jd)
+		if err != nil {
+			return nil, err
+		}
+		if ri != nil && (ri.Subscription == rostermodel.From || ri.Subscription == rostermodel.Both) {
+			return nil, nil
+		}
+		return &accessDenial{reason: stanzaerror.NotAuthorized, condition: "presence-subscription-required"}, nil
+
+	case RosterAccessModel:
+		ri, err := s.ownerRosterItem(ctx, node, jd)
+		if err != nil {
+			return nil, err
+		}
+		if ri != nil {
+			for _, group := range ri.Groups {
+				for _, allowed := range node.Options.RosterGroupsAllowed {
+					if group == allowed {
+						return nil, nil
+					}
+				}
+			}
+		}
+		return &accessDenial{reason: stanzaerror.NotAuthorized, condition: "not-in-roster-group"}, nil
+
+	default:
+		return &accessDenial{reason: stanzaerror.Forbidden}, nil
+	}
+}
+
+// ownerRosterItem returns the node owner roster item associated to jd.
+func (s *Service) ownerRosterItem(ctx context.Context, node *pubsubmodel.Node, jd *jid.JID) (*rostermodel.Item, error) {
+	owner := nodeOwner(node)
+	if owner == nil || !s.hosts.IsLocalHost(owner.Domain()) {
+		return nil, nil
+	}
+	return s.rep.FetchRosterItem(ctx, owner.Node(), jd.ToBareJID().String())
+}
+
+func canPublish(node *pubsubmodel.Node, jd *jid.JID) bool {
+	switch affiliation(node, jd) {
+	case ownerAffiliation, publisherAffiliation:
+		return true
+	case outcastAffiliation:
+		return false
+	}
+	return node.Options.PublishModel == OpenPublishModel
+}
+
+func affiliation(node *pubsubmodel.Node, jd *jid.JID) string {
+	bareJID := jd.ToBareJID().String()
+	for _, aff := range node.Affiliations {
+		if aff.Jid == bareJID {
+			return aff.Affiliation
+		}
+	}
+	return noneAffiliation
+}
+
+func nodeOwner(node *pubsubmodel.Node) *jid.JID {
+	for _, aff := range node.Affiliations {
+		if aff.Affiliation != ownerAffiliation {
+			continue
+		}
+		owner, err := jid.NewWithString(aff.Jid, true)
+		if err != nil {
+			return nil
+		}
+		return owner
+	}
+	return nil
+}
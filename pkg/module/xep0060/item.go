@@ -0,0 +1,353 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0060
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SendLastItem sends the node last published item to jd in case it's allowed to access the node.
+func (s *Service) SendLastItem(ctx context.Context, node *pubsubmodel.Node, jd *jid.JID) error {
+	ok, err := s.canAccess(ctx, node, jd)
+	if err != nil || !ok {
+		return err
+	}
+	items, err := s.rep.FetchNodeItems(ctx, node.Host, node.Name)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	last := items[len(items)-1]
+
+	msg := eventMessage(node.Host, jd, itemsElement(node.Name, itemElement(last, node.Options.DeliverPayloads)))
+	_, _ = s.router.Route(ctx, xmpputil.MakeDelayMessage(msg, last.Stamp.AsTime(), node.Host, ""))
+	return nil
+}
+
+func (s *Service) publish(ctx context.Context, host string, iq *stravaganza.IQ, publish, publishOpts stravaganza.Element) error {
+	nodeID := publish.Attribute("node")
+	if len(nodeID) == 0 {
+		s.sendError(ctx, iq, stanzaerror.BadRequest, errorCondition("nodeid-required"))
+		return nil
+	}
+	var optsForm *xep0004.DataForm
+	if publishOpts != nil {
+		if x := publishOpts.ChildNamespace("x", xep0004.FormNamespace); x != nil {
+			form, err := xep0004.NewFormFromElement(x)
+			if err != nil || form.Fields.ValueForFieldOfType(xep0004.FormType, xep0004.Hidden) != publishOptionsNamespace {
+				s.sendError(ctx, iq, stanzaerror.BadRequest, errorCondition("invalid-options"))
+				return nil
+			}
+			optsForm = form
+		}
+	}
+	itemElems := publish.Children("item")
+	switch {
+	case len(itemElems) == 0:
+		s.sendError(ctx, iq, stanzaerror.BadRequest, errorCondition("item-required"))
+		return nil
+	case len(itemElems) > 1, itemElems[0].ChildrenCount() != 1:
+		s.sendError(ctx, iq, stanzaerror.BadRequest, errorCondition("invalid-payload"))
+		return nil
+	}
+	itemElem := itemElems[0]
+
+	fromJID := iq.FromJID()
+	node, err := s.rep.FetchNode(ctx, host, nodeID)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	switch {
+	case node == nil:
+		if !s.policy.AutoCreate() || !s.policy.CanCreateNode(ctx, host, fromJID) {
+			s.sendError(ctx, iq, stanzaerror.ItemNotFound, nil)
+			return nil
+		}
+		opts := s.policy.DefaultNodeOptions()
+		if optsForm != nil {
+			opts, err = applyNodeConfigForm(opts, optsForm)
+			if err != nil {
+				s.sendError(ctx, iq, stanzaerror.BadRequest, errorCondition("invalid-options"))
+				return nil
+			}
+		}
+		node = newNode(host, nodeID, fromJID, opts)
+		if err := s.rep.UpsertNode(ctx, node); err != nil {
+			s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+			return err
+		}
+
+	case optsForm != nil && !matchesPreconditions(node.Options, optsForm):
+		s.sendError(ctx, iq, stanzaerror.Conflict, errorCondition("precondition-not-met"))
+		return nil
+	}
+	if !canPublish(node, fromJID) {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	itemID := itemElem.Attribute("id")
+	if len(itemID) == 0 {
+		itemID = uuid.New().String()
+	}
+	item := &pubsubmodel.Item{
+		Host:      host,
+		Node:      nodeID,
+		Id:        itemID,
+		Publisher: fromJID.ToBareJID().String(),
+		Payload:   itemElem.AllChildren()[0].Proto(),
+		Stamp:     timestamppb.Now(),
+	}
+	if node.Options.PersistItems {
+		if err := s.rep.UpsertNodeItem(ctx, item); err != nil {
+			s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+			return err
+		}
+		if node.Options.MaxItems > 0 {
+			if err := s.rep.DeleteOldestNodeItems(ctx, host, nodeID, int(node.Options.MaxItems)); err != nil {
+				s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+				return err
+			}
+		}
+	}
+	s.sendResult(ctx, iq, Namespace,
+		stravaganza.NewBuilder("publish").
+			WithAttribute("node", nodeID).
+			WithChild(
+				stravaganza.NewBuilder("item").
+					WithAttribute("id", itemID).
+					Build(),
+			).
+			Build(),
+	)
+	level.Debug(s.logger).Log("msg", "item published", "host", host, "node", nodeID, "id", itemID)
+
+	return s.notify(ctx, node, itemsElement(nodeID, itemElement(item, node.Options.DeliverPayloads)))
+}
+
+func (s *Service) retract(ctx context.Context, host string, iq *stravaganza.IQ, retract stravaganza.Element) error {
+	node, err := s.fetchNode(ctx, host, iq, retract)
+	if err != nil || node == nil {
+		return err
+	}
+	itemElem := retract.Child("item")
+	if itemElem == nil || len(itemElem.Attribute("id")) == 0 {
+		s.sendError(ctx, iq, stanzaerror.BadRequest, errorCondition("item-required"))
+		return nil
+	}
+	itemID := itemElem.Attribute("id")
+
+	items, err := s.rep.FetchNodeItems(ctx, host, node.Name)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	var item *pubsubmodel.Item
+	for _, itm := range items {
+		if itm.Id == itemID {
+			item = itm
+			break
+		}
+	}
+	if item == nil {
+		s.sendError(ctx, iq, stanzaerror.ItemNotFound, nil)
+		return nil
+	}
+	fromJID := iq.FromJID()
+	if affiliation(node, fromJID) != ownerAffiliation && item.Publisher != fromJID.ToBareJID().String() {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	if err := s.rep.DeleteNodeItem(ctx, host, node.Name, itemID); err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	s.sendResult(ctx, iq, "")
+
+	notifyRetract := node.Options.NotifyRetract
+	if v := retract.Attribute("notify"); len(v) > 0 {
+		notifyRetract, _ = strconv.ParseBool(v)
+	}
+	if !notifyRetract {
+		return nil
+	}
+	return s.notify(ctx, node, itemsElement(node.Name,
+		stravaganza.NewBuilder("retract").
+			WithAttribute("id", itemID).
+			Build(),
+	))
+}
+
+func (s *Service) getItems(ctx context.Context, host string, iq *stravaganza.IQ, itemsElem stravaganza.Element) error {
+	node, err := s.fetchNode(ctx, host, iq, itemsElem)
+	if err != nil || node == nil {
+		return err
+	}
+	if ok, err := s.checkAccess(ctx, node, iq); err != nil || !ok {
+		return err
+	}
+	items, err := s.rep.FetchNodeItems(ctx, host, node.Name)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	// filter requested items
+	if requested := itemsElem.Children("item"); len(requested) > 0 {
+		ids := make(map[string]struct{}, len(requested))
+		for _, itemElem := range requested {
+			ids[itemElem.Attribute("id")] = struct{}{}
+		}
+		var filtered []*pubsubmodel.Item
+		for _, item := range items {
+			if _, ok := ids[item.Id]; ok {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+
+	} else if v := itemsElem.Attribute("max_items"); len(v) > 0 {
+		maxItems, err := strconv.Atoi(v)
+		if err != nil || maxItems < 0 {
+			s.sendError(ctx, iq, stanzaerror.BadRequest, nil)
+			return nil
+		}
+		if len(items) > maxItems {
+			items = items[len(items)-maxItems:]
+		}
+	}
+	itemElems := make([]stravaganza.Element, 0, len(items))
+	for _, item := range items {
+		itemElems = append(itemElems, itemElement(item, true))
+	}
+	s.sendResult(ctx, iq, Namespace, itemsElement(node.Name, itemElems...))
+	return nil
+}
+
+// notify sends an event notification to every entity interested in node events.
+func (s *Service) notify(ctx context.Context, node *pubsubmodel.Node, child stravaganza.Element) error {
+	targets, err := s.notificationTargets(ctx, node)
+	if err != nil {
+		return err
+	}
+	s.sendEvent(ctx, node.Host, targets, child)
+	return nil
+}
+
+func (s *Service) sendEvent(ctx context.Context, host string, targets []*jid.JID, child stravaganza.Element) {
+	for _, target := range targets {
+		_, _ = s.router.Route(ctx, eventMessage(host, target, child))
+	}
+}
+
+// notificationTargets returns the set of subscribed entities allowed to receive node event notifications.
+func (s *Service) notificationTargets(ctx context.Context, node *pubsubmodel.Node) ([]*jid.JID, error) {
+	subs, err := s.rep.FetchNodeSubscriptions(ctx, node.Host, node.Name)
+	if err != nil {
+		return nil, err
+	}
+	implicit, err := s.policy.ImplicitSubscribers(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []*jid.JID
+	for _, sub := range subs {
+		if sub.State != subscribedState {
+			continue
+		}
+		jd, err := jid.NewWithString(sub.Jid, true)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, jd)
+	}
+	candidates = append(candidates, implicit...)
+
+	// bare JID subscriptions already reach every entity resource
+	bareJIDs := make(map[string]struct{})
+	for _, jd := range candidates {
+		if jd.IsBare() {
+			bareJIDs[jd.String()] = struct{}{}
+		}
+	}
+	var retVal []*jid.JID
+
+	seen := make(map[string]struct{})
+	for _, jd := range candidates {
+		if _, ok := seen[jd.String()]; ok {
+			continue
+		}
+		seen[jd.String()] = struct{}{}
+
+		if _, ok := bareJIDs[jd.ToBareJID().String()]; ok && jd.IsFull() {
+			continue
+		}
+		ok, err := s.canAccess(ctx, node, jd)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			retVal = append(retVal, jd)
+		}
+	}
+	return retVal, nil
+}
+
+func eventMessage(host string, to *jid.JID, child stravaganza.Element) *stravaganza.Message {
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.From, host).
+		WithAttribute(stravaganza.To, to.String()).
+		WithAttribute(stravaganza.Type, stravaganza.HeadlineType).
+		WithChild(
+			stravaganza.NewBuilder("event").
+				WithAttribute(stravaganza.Namespace, EventNamespace).
+				WithChild(child).
+				Build(),
+		).
+		BuildMessage()
+	return msg
+}
+
+func itemsElement(nodeID string, children ...stravaganza.Element) stravaganza.Element {
+	return stravaganza.NewBuilder("items").
+		WithAttribute("node", nodeID).
+		WithChildren(children...).
+		Build()
+}
+
+func itemElement(item *pubsubmodel.Item, withPayload bool) stravaganza.Element {
+	b := stravaganza.NewBuilder("item").
+		WithAttribute("id", item.Id)
+	if len(item.Publisher) > 0 {
+		b.WithAttribute("publisher", item.Publisher)
+	}
+	if withPayload && item.Payload != nil {
+		b.WithChild(stravaganza.NewBuilderFromProto(item.Payload).Build())
+	}
+	return b.Build()
+}
@@ -0,0 +1,416 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0060
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+)
+
+const (
+	titleField                 = "pubsub#title"
+	accessModelField           = "pubsub#access_model"
+	rosterGroupsAllowedField   = "pubsub#roster_groups_allowed"
+	publishModelField          = "pubsub#publish_model"
+	maxItemsField              = "pubsub#max_items"
+	persistItemsField          = "pubsub#persist_items"
+	deliverPayloadsField       = "pubsub#deliver_payloads"
+	notifyRetractField         = "pubsub#notify_retract"
+	notifyDeleteField          = "pubsub#notify_delete"
+	sendLastPublishedItemField = "pubsub#send_last_published_item"
+
+	maxItemsUnlimited = "max"
+)
+
+var errInvalidOption = errors.New("xep0060: invalid node option value")
+
+func (s *Service) createNode(ctx context.Context, host string, iq *stravaganza.IQ, create, configure stravaganza.Element) error {
+	fromJID := iq.FromJID()
+	if !s.policy.CanCreateNode(ctx, host, fromJID) {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	opts := s.policy.DefaultNodeOptions()
+	if configure != nil {
+		if x := configure.ChildNamespace("x", xep0004.FormNamespace); x != nil {
+			form, err := xep0004.NewFormFromElement(x)
+			if err != nil {
+				s.sendError(ctx, iq, stanzaerror.BadRequest, nil)
+				return nil
+			}
+			opts, err = applyNodeConfigForm(opts, form)
+			if err != nil {
+				s.sendError(ctx, iq, stanzaerror.NotAcceptable, nil)
+				return nil
+			}
+		}
+	}
+	nodeID := create.Attribute("node")
+	instant := len(nodeID) == 0
+	if instant {
+		nodeID = uuid.New().String()
+	}
+	exists, err := s.rep.FetchNode(ctx, host, nodeID)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	if exists != nil {
+		s.sendError(ctx, iq, stanzaerror.Conflict, nil)
+		return nil
+	}
+	node := newNode(host, nodeID, fromJID, opts)
+	if err := s.rep.UpsertNode(ctx, node); err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	if !instant {
+		s.sendResult(ctx, iq, "")
+		return nil
+	}
+	s.sendResult(ctx, iq, Namespace,
+		stravaganza.NewBuilder("create").
+			WithAttribute("node", nodeID).
+			Build(),
+	)
+	return nil
+}
+
+func (s *Service) getNodeConfig(ctx context.Context, host string, iq *stravaganza.IQ, configure stravaganza.Element) error {
+	node, err := s.fetchNode(ctx, host, iq, configure)
+	if err != nil || node == nil {
+		return err
+	}
+	if affiliation(node, iq.FromJID()) != ownerAffiliation {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	s.sendResult(ctx, iq, OwnerNamespace,
+		stravaganza.NewBuilder("configure").
+			WithAttribute("node", node.Name).
+			WithChild(nodeConfigForm(node.Options).Element()).
+			Build(),
+	)
+	return nil
+}
+
+func (s *Service) getDefaultNodeConfig(ctx context.Context, iq *stravaganza.IQ) error {
+	s.sendResult(ctx, iq, OwnerNamespace,
+		stravaganza.NewBuilder("default").
+			WithChild(nodeConfigForm(s.policy.DefaultNodeOptions()).Element()).
+			Build(),
+	)
+	return nil
+}
+
+func (s *Service) configureNode(ctx context.Context, host string, iq *stravaganza.IQ, configure stravaganza.Element) error {
+	node, err := s.fetchNode(ctx, host, iq, configure)
+	if err != nil || node == nil {
+		return err
+	}
+	if affiliation(node, iq.FromJID()) != ownerAffiliation {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	x := configure.ChildNamespace("x", xep0004.FormNamespace)
+	if x == nil {
+		s.sendError(ctx, iq, stanzaerror.BadRequest, nil)
+		return nil
+	}
+	form, err := xep0004.NewFormFromElement(x)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.BadRequest, nil)
+		return nil
+	}
+	switch form.Type {
+	case xep0004.Cancel:
+		s.sendResult(ctx, iq, "")
+		return nil
+
+	case xep0004.Submit:
+		break
+
+	default:
+		s.sendError(ctx, iq, stanzaerror.BadRequest, nil)
+		return nil
+	}
+	opts, err := applyNodeConfigForm(node.Options, form)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.NotAcceptable, nil)
+		return nil
+	}
+	node.Options = opts
+	if err := s.rep.UpsertNode(ctx, node); err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	if !opts.PersistItems {
+		if err := s.rep.DeleteNodeItems(ctx, host, node.Name); err != nil {
+			return err
+		}
+	} else if opts.MaxItems > 0 {
+		if err := s.rep.DeleteOldestNodeItems(ctx, host, node.Name, int(opts.MaxItems)); err != nil {
+			return err
+		}
+	}
+	s.sendResult(ctx, iq, "")
+	return nil
+}
+
+func (s *Service) deleteNode(ctx context.Context, host string, iq *stravaganza.IQ, del stravaganza.Element) error {
+	node, err := s.fetchNode(ctx, host, iq, del)
+	if err != nil || node == nil {
+		return err
+	}
+	if affiliation(node, iq.FromJID()) != ownerAffiliation {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	// notification targets must be collected before subscriptions are gone
+	var targets []*jid.JID
+	if node.Options.NotifyDelete {
+		targets, err = s.notificationTargets(ctx, node)
+		if err != nil {
+			s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+			return err
+		}
+	}
+	if err := s.rep.DeleteNode(ctx, host, node.Name); err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	s.sendResult(ctx, iq, "")
+
+	s.sendEvent(ctx, host, targets,
+		stravaganza.NewBuilder("delete").
+			WithAttribute("node", node.Name).
+			Build(),
+	)
+	return nil
+}
+
+func (s *Service) purgeNode(ctx context.Context, host string, iq *stravaganza.IQ, purge stravaganza.Element) error {
+	node, err := s.fetchNode(ctx, host, iq, purge)
+	if err != nil || node == nil {
+		return err
+	}
+	if affiliation(node, iq.FromJID()) != ownerAffiliation {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	if !node.Options.PersistItems {
+		s.sendError(ctx, iq, stanzaerror.FeatureNotImplemented, unsupportedErrorCondition("persistent-items"))
+		return nil
+	}
+	if err := s.rep.DeleteNodeItems(ctx, host, node.Name); err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	s.sendResult(ctx, iq, "")
+
+	return s.notify(ctx, node,
+		stravaganza.NewBuilder("purge").
+			WithAttribute("node", node.Name).
+			Build(),
+	)
+}
+
+func newNode(host, name string, owner *jid.JID, opts *pubsubmodel.Options) *pubsubmodel.Node {
+	return &pubsubmodel.Node{
+		Host:    host,
+		Name:    name,
+		Options: opts,
+		Affiliations: []*pubsubmodel.Affiliation{
+			{Jid: owner.ToBareJID().String(), Affiliation: ownerAffiliation},
+		},
+	}
+}
+
+func nodeConfigForm(opts *pubsubmodel.Options) *xep0004.DataForm {
+	maxItems := maxItemsUnlimited
+	if opts.MaxItems > 0 {
+		maxItems = strconv.Itoa(int(opts.MaxItems))
+	}
+	return &xep0004.DataForm{
+		Type: xep0004.Form,
+		Fields: []xep0004.Field{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{nodeConfigNamespace}},
+			{Var: titleField, Type: xep0004.TextSingle, Label: "A friendly name for the node", Values: []string{opts.Title}},
+			{
+				Var:    accessModelField,
+				Type:   xep0004.ListSingle,
+				Label:  "Who may subscribe and retrieve items",
+				Values: []string{opts.AccessModel},
+				Options: []xep0004.Option{
+					{Value: OpenAccessModel},
+					{Value: PresenceAccessModel},
+					{Value: RosterAccessModel},
+					{Value: WhitelistAccessModel},
+				},
+			},
+			{Var: rosterGroupsAllowedField, Type: xep0004.ListMulti, Label: "Roster groups allowed to subscribe", Values: opts.RosterGroupsAllowed},
+			{
+				Var:    publishModelField,
+				Type:   xep0004.ListSingle,
+				Label:  "Who may publish items",
+				Values: []string{opts.PublishModel},
+				Options: []xep0004.Option{
+					{Value: PublishersPublishModel},
+					{Value: OpenPublishModel},
+				},
+			},
+			{Var: maxItemsField, Type: xep0004.TextSingle, Label: "Max number of items to persist", Values: []string{maxItems}},
+			{Var: persistItemsField, Type: xep0004.Boolean, Label: "Persist items to storage", Values: []string{boolValue(opts.PersistItems)}},
+			{Var: deliverPayloadsField, Type: xep0004.Boolean, Label: "Deliver payloads with event notifications", Values: []string{boolValue(opts.DeliverPayloads)}},
+			{Var: notifyRetractField, Type: xep0004.Boolean, Label: "Notify subscribers when items are removed from the node", Values: []string{boolValue(opts.NotifyRetract)}},
+			{Var: notifyDeleteField, Type: xep0004.Boolean, Label: "Notify subscribers when the node is deleted", Values: []string{boolValue(opts.NotifyDelete)}},
+			{
+				Var:    sendLastPublishedItemField,
+				Type:   xep0004.ListSingle,
+				Label:  "When to send the last published item",
+				Values: []string{opts.SendLastPublishedItem},
+				Options: []xep0004.Option{
+					{Value: SendLastNever},
+					{Value: SendLastOnSub},
+					{Value: SendLastOnSubAndPresence},
+				},
+			},
+		},
+	}
+}
+
+// applyNodeConfigForm returns the result of applying submitted form values over opts.
+// Fields not present in the form keep their current values.
+func applyNodeConfigForm(opts *pubsubmodel.Options, form *xep0004.DataForm) (*pubsubmodel.Options, error) {
+	retVal := &pubsubmodel.Options{
+		Title:                 opts.Title,
+		AccessModel:           opts.AccessModel,
+		RosterGroupsAllowed:   opts.RosterGroupsAllowed,
+		PublishModel:          opts.PublishModel,
+		MaxItems:              opts.MaxItems,
+		PersistItems:          opts.PersistItems,
+		DeliverPayloads:       opts.DeliverPayloads,
+		NotifyRetract:         opts.NotifyRetract,
+		NotifyDelete:          opts.NotifyDelete,
+		SendLastPublishedItem: opts.SendLastPublishedItem,
+	}
+	for _, field := range form.Fields {
+		var value string
+		if len(field.Values) > 0 {
+			value = field.Values[0]
+		}
+		var err error
+		switch field.Var {
+		case titleField:
+			retVal.Title = value
+		case accessModelField:
+			if !isValidAccessModel(value) {
+				return nil, errInvalidOption
+			}
+			retVal.AccessModel = value
+		case rosterGroupsAllowedField:
+			retVal.RosterGroupsAllowed = field.Values
+		case publishModelField:
+			if value != PublishersPublishModel && value != OpenPublishModel {
+				return nil, errInvalidOption
+			}
+			retVal.PublishModel = value
+		case maxItemsField:
+			retVal.MaxItems, err = parseMaxItems(value)
+		case persistItemsField:
+			retVal.PersistItems, err = strconv.ParseBool(value)
+		case deliverPayloadsField:
+			retVal.DeliverPayloads, err = strconv.ParseBool(value)
+		case notifyRetractField:
+			retVal.NotifyRetract, err = strconv.ParseBool(value)
+		case notifyDeleteField:
+			retVal.NotifyDelete, err = strconv.ParseBool(value)
+		case sendLastPublishedItemField:
+			if value != SendLastNever && value != SendLastOnSub && value != SendLastOnSubAndPresence {
+				return nil, errInvalidOption
+			}
+			retVal.SendLastPublishedItem = value
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return retVal, nil
+}
+
+// matchesPreconditions tells whether every publish-options form value matches current node configuration.
+func matchesPreconditions(opts *pubsubmodel.Options, form *xep0004.DataForm) bool {
+	current := make(map[string]string)
+	for _, field := range nodeConfigForm(opts).Fields {
+		if len(field.Values) > 0 {
+			current[field.Var] = field.Values[0]
+		}
+	}
+	for _, field := range form.Fields {
+		if field.Var == xep0004.FormType {
+			continue
+		}
+		var value string
+		if len(field.Values) > 0 {
+			value = field.Values[0]
+		}
+		currentValue := current[field.Var]
+
+		switch field.Var {
+		case persistItemsField, deliverPayloadsField, notifyRetractField, notifyDeleteField:
+			b0, err0 := strconv.ParseBool(value)
+			b1, _ := strconv.ParseBool(currentValue)
+			if err0 != nil || b0 != b1 {
+				return false
+			}
+		default:
+			if value != currentValue {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func parseMaxItems(value string) (int64, error) {
+	if value == maxItemsUnlimited {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func isValidAccessModel(accessModel string) bool {
+	switch accessModel {
+	case OpenAccessModel, PresenceAccessModel, RosterAccessModel, WhitelistAccessModel:
+		return true
+	default:
+		return false
+	}
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
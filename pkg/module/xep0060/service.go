@@ -0,0 +1,303 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0060
+
+import (
+	"context"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	// Namespace specifies publish-subscribe namespace.
+	Namespace = "http://jabber.org/protocol/pubsub"
+
+	// OwnerNamespace specifies publish-subscribe owner namespace.
+	OwnerNamespace = "http://jabber.org/protocol/pubsub#owner"
+
+	// EventNamespace specifies publish-subscribe event namespace.
+	EventNamespace = "http://jabber.org/protocol/pubsub#event"
+
+	errorsNamespace         = "http://jabber.org/protocol/pubsub#errors"
+	nodeConfigNamespace     = "http://jabber.org/protocol/pubsub#node_config"
+	publishOptionsNamespace = "http://jabber.org/protocol/pubsub#publish-options"
+)
+
+const (
+	// OpenAccessModel allows any entity to subscribe and retrieve items.
+	OpenAccessModel = "open"
+
+	// PresenceAccessModel allows entities subscribed to the owner presence to subscribe and retrieve items.
+	PresenceAccessModel = "presence"
+
+	// RosterAccessModel allows entities in the allowed owner roster groups to subscribe and retrieve items.
+	RosterAccessModel = "roster"
+
+	// WhitelistAccessModel allows only explicitly whitelisted entities to subscribe and retrieve items.
+	WhitelistAccessModel = "whitelist"
+)
+
+const (
+	// PublishersPublishModel allows only owners and publishers to publish items.
+	PublishersPublishModel = "publishers"
+
+	// OpenPublishModel allows any entity to publish items.
+	OpenPublishModel = "open"
+)
+
+const (
+	// SendLastNever disables last published item delivery.
+	SendLastNever = "never"
+
+	// SendLastOnSub delivers last published item on subscription.
+	SendLastOnSub = "on_sub"
+
+	// SendLastOnSubAndPresence delivers last published item on subscription and on subscriber presence.
+	SendLastOnSubAndPresence = "on_sub_and_presence"
+)
+
+const (
+	ownerAffiliation     = "owner"
+	publisherAffiliation = "publisher"
+	memberAffiliation    = "member"
+	noneAffiliation      = "none"
+	outcastAffiliation   = "outcast"
+
+	subscribedState = "subscribed"
+)
+
+// Features contains the set of publish-subscribe features supported by Service.
+var Features = []string{
+	Namespace,
+	Namespace + "#access-open",
+	Namespace + "#access-presence",
+	Namespace + "#access-roster",
+	Namespace + "#access-whitelist",
+	Namespace + "#config-node",
+	Namespace + "#create-and-configure",
+	Namespace + "#create-nodes",
+	Namespace + "#delete-items",
+	Namespace + "#delete-nodes",
+	Namespace + "#instant-nodes",
+	Namespace + "#item-ids",
+	Namespace + "#last-published",
+	Namespace + "#manage-affiliations",
+	Namespace + "#modify-affiliations",
+	Namespace + "#persistent-items",
+	Namespace + "#publish",
+	Namespace + "#publish-options",
+	Namespace + "#purge-nodes",
+	Namespace + "#retract-items",
+	Namespace + "#retrieve-affiliations",
+	Namespace + "#retrieve-default",
+	Namespace + "#retrieve-items",
+	Namespace + "#retrieve-subscriptions",
+	Namespace + "#subscribe",
+}
+
+// Policy defines the behavior that differs among publish-subscribe services.
+type Policy interface {
+	// CanCreateNode tells whether jd is allowed to create nodes on host.
+	CanCreateNode(ctx context.Context, host string, jd *jid.JID) bool
+
+	// AutoCreate tells whether publishing to a non-existing node should create it.
+	AutoCreate() bool
+
+	// DefaultNodeOptions returns the configuration applied to newly created nodes.
+	DefaultNodeOptions() *pubsubmodel.Options
+
+	// ImplicitSubscribers returns the entities to be notified of node events besides the explicit subscribers.
+	ImplicitSubscribers(ctx context.Context, node *pubsubmodel.Node) ([]*jid.JID, error)
+}
+
+// Hosts tells whether a domain is served locally.
+type Hosts interface {
+	IsLocalHost(h string) bool
+}
+
+// Service implements a generic publish-subscribe (XEP-0060) service.
+// Nodes are grouped by host, which identifies the service address (a domain or an account bare JID).
+type Service struct {
+	policy Policy
+	rep    repository.Repository
+	router router.Router
+	hosts  Hosts
+	logger kitlog.Logger
+}
+
+// NewService returns a new initialized publish-subscribe service.
+func NewService(
+	policy Policy,
+	router router.Router,
+	hosts Hosts,
+	rep repository.Repository,
+	logger kitlog.Logger,
+) *Service {
+	return &Service{
+		policy: policy,
+		rep:    rep,
+		router: router,
+		hosts:  hosts,
+		logger: logger,
+	}
+}
+
+// ProcessIQ processes a publish-subscribe iq addressed to host.
+func (s *Service) ProcessIQ(ctx context.Context, host string, iq *stravaganza.IQ) error {
+	if ps := iq.ChildNamespace("pubsub", Namespace); ps != nil {
+		switch {
+		case iq.IsGet():
+			return s.processGet(ctx, host, iq, ps)
+		case iq.IsSet():
+			return s.processSet(ctx, host, iq, ps)
+		}
+		return nil
+	}
+	if ps := iq.ChildNamespace("pubsub", OwnerNamespace); ps != nil {
+		switch {
+		case iq.IsGet():
+			return s.processOwnerGet(ctx, host, iq, ps)
+		case iq.IsSet():
+			return s.processOwnerSet(ctx, host, iq, ps)
+		}
+		return nil
+	}
+	_, _ = s.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+	return nil
+}
+
+func (s *Service) processGet(ctx context.Context, host string, iq *stravaganza.IQ, ps stravaganza.Element) error {
+	switch {
+	case ps.Child("items") != nil:
+		return s.getItems(ctx, host, iq, ps.Child("items"))
+	case ps.Child("subscriptions") != nil:
+		return s.getSubscriptions(ctx, host, iq, ps.Child("subscriptions"))
+	case ps.Child("affiliations") != nil:
+		return s.getAffiliations(ctx, host, iq, ps.Child("affiliations"))
+	default:
+		s.sendError(ctx, iq, stanzaerror.FeatureNotImplemented, nil)
+		return nil
+	}
+}
+
+func (s *Service) processSet(ctx context.Context, host string, iq *stravaganza.IQ, ps stravaganza.Element) error {
+	switch {
+	case ps.Child("create") != nil:
+		return s.createNode(ctx, host, iq, ps.Child("create"), ps.Child("configure"))
+	case ps.Child("publish") != nil:
+		return s.publish(ctx, host, iq, ps.Child("publish"), ps.Child("publish-options"))
+	case ps.Child("retract") != nil:
+		return s.retract(ctx, host, iq, ps.Child("retract"))
+	case ps.Child("subscribe") != nil:
+		return s.subscribe(ctx, host, iq, ps.Child("subscribe"))
+	case ps.Child("unsubscribe") != nil:
+		return s.unsubscribe(ctx, host, iq, ps.Child("unsubscribe"))
+	default:
+		s.sendError(ctx, iq, stanzaerror.FeatureNotImplemented, nil)
+		return nil
+	}
+}
+
+func (s *Service) processOwnerGet(ctx context.Context, host string, iq *stravaganza.IQ, ps stravaganza.Element) error {
+	switch {
+	case ps.Child("configure") != nil:
+		return s.getNodeConfig(ctx, host, iq, ps.Child("configure"))
+	case ps.Child("default") != nil:
+		return s.getDefaultNodeConfig(ctx, iq)
+	case ps.Child("affiliations") != nil:
+		return s.getNodeAffiliations(ctx, host, iq, ps.Child("affiliations"))
+	case ps.Child("subscriptions") != nil:
+		return s.getNodeSubscriptions(ctx, host, iq, ps.Child("subscriptions"))
+	default:
+		s.sendError(ctx, iq, stanzaerror.FeatureNotImplemented, nil)
+		return nil
+	}
+}
+
+func (s *Service) processOwnerSet(ctx context.Context, host string, iq *stravaganza.IQ, ps stravaganza.Element) error {
+	switch {
+	case ps.Child("configure") != nil:
+		return s.configureNode(ctx, host, iq, ps.Child("configure"))
+	case ps.Child("delete") != nil:
+		return s.deleteNode(ctx, host, iq, ps.Child("delete"))
+	case ps.Child("purge") != nil:
+		return s.purgeNode(ctx, host, iq, ps.Child("purge"))
+	case ps.Child("affiliations") != nil:
+		return s.setNodeAffiliations(ctx, host, iq, ps.Child("affiliations"))
+	default:
+		s.sendError(ctx, iq, stanzaerror.FeatureNotImplemented, nil)
+		return nil
+	}
+}
+
+// fetchNode returns the node referenced by elem 'node' attribute.
+// In case the node cannot be retrieved an error response is sent back and a nil node is returned.
+func (s *Service) fetchNode(ctx context.Context, host string, iq *stravaganza.IQ, elem stravaganza.Element) (*pubsubmodel.Node, error) {
+	nodeID := elem.Attribute("node")
+	if len(nodeID) == 0 {
+		s.sendError(ctx, iq, stanzaerror.BadRequest, errorCondition("nodeid-required"))
+		return nil, nil
+	}
+	node, err := s.rep.FetchNode(ctx, host, nodeID)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return nil, err
+	}
+	if node == nil {
+		s.sendError(ctx, iq, stanzaerror.ItemNotFound, nil)
+		return nil, nil
+	}
+	return node, nil
+}
+
+func (s *Service) sendError(ctx context.Context, iq *stravaganza.IQ, reason stanzaerror.Reason, appElem stravaganza.Element) {
+	se := stanzaerror.E(reason, iq)
+	se.ApplicationElement = appElem
+	errStanza, _ := se.Stanza(false)
+	_, _ = s.router.Route(ctx, errStanza)
+}
+
+func (s *Service) sendResult(ctx context.Context, iq *stravaganza.IQ, ns string, children ...stravaganza.Element) {
+	if len(children) == 0 {
+		_, _ = s.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+		return
+	}
+	_, _ = s.router.Route(ctx, xmpputil.MakeResultIQ(iq,
+		stravaganza.NewBuilder("pubsub").
+			WithAttribute(stravaganza.Namespace, ns).
+			WithChildren(children...).
+			Build(),
+	))
+}
+
+func errorCondition(name string) stravaganza.Element {
+	return stravaganza.NewBuilder(name).
+		WithAttribute(stravaganza.Namespace, errorsNamespace).
+		Build()
+}
+
+func unsupportedErrorCondition(feature string) stravaganza.Element {
+	return stravaganza.NewBuilder("unsupported").
+		WithAttribute(stravaganza.Namespace, errorsNamespace).
+		WithAttribute("feature", feature).
+		Build()
+}
@@ -0,0 +1,467 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0060
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/stretchr/testify/require"
+)
+
+type testRouter struct {
+	mu      sync.Mutex
+	stanzas []stravaganza.Stanza
+}
+
+func (r *testRouter) route(_ context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stanzas = append(r.stanzas, stanza)
+	return nil, nil
+}
+
+func (r *testRouter) flush() []stravaganza.Stanza {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := r.stanzas
+	r.stanzas = nil
+	return ret
+}
+
+type testStore struct {
+	nodes map[string]*pubsubmodel.Node
+	items map[string][]*pubsubmodel.Item
+	subs  map[string][]*pubsubmodel.Subscription
+	ris   map[string]*rostermodel.Item
+}
+
+func TestService_CreateInstantNode(t *testing.T) {
+	// given
+	s, rtr, st := testService(nil)
+
+	// when
+	_ = s.ProcessIQ(context.Background(), "pubsub.jackal.im", testIQ("ortuman@jackal.im/yard", "pubsub.jackal.im", stravaganza.SetType,
+		stravaganza.NewBuilder("pubsub").
+			WithAttribute(stravaganza.Namespace, Namespace).
+			WithChild(stravaganza.NewBuilder("create").Build()).
+			Build(),
+	))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+
+	nodeID := stanzas[0].ChildNamespace("pubsub", Namespace).Child("create").Attribute("node")
+	require.NotEmpty(t, nodeID)
+
+	node := st.nodes[nodeID]
+	require.NotNil(t, node)
+	require.Len(t, node.Affiliations, 1)
+	require.Equal(t, "ortuman@jackal.im", node.Affiliations[0].Jid)
+	require.Equal(t, ownerAffiliation, node.Affiliations[0].Affiliation)
+}
+
+func TestService_PublishAutoCreate(t *testing.T) {
+	// given
+	s, rtr, st := testService([]*jid.JID{testJID("noelia@jackal.im/balcony")})
+
+	// when
+	_ = s.ProcessIQ(context.Background(), "ortuman@jackal.im", testPublishIQ("ortuman@jackal.im/yard", "ortuman@jackal.im", "urn:xmpp:tune", "i1", nil))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 2)
+
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+	require.Equal(t, "i1", stanzas[0].ChildNamespace("pubsub", Namespace).Child("publish").Child("item").Attribute("id"))
+
+	require.Equal(t, "noelia@jackal.im/balcony", stanzas[1].ToJID().String())
+	require.Equal(t, "ortuman@jackal.im", stanzas[1].FromJID().String())
+	items := stanzas[1].ChildNamespace("event", EventNamespace).Child("items")
+	require.Equal(t, "urn:xmpp:tune", items.Attribute("node"))
+	require.NotNil(t, items.Child("item").Child("tune"))
+
+	require.NotNil(t, st.nodes["urn:xmpp:tune"])
+	require.Len(t, st.items["urn:xmpp:tune"], 1)
+}
+
+func TestService_PublishPreconditionNotMet(t *testing.T) {
+	// given
+	s, rtr, st := testService(nil)
+	st.nodes["urn:xmpp:tune"] = newNode("ortuman@jackal.im", "urn:xmpp:tune", testJID("ortuman@jackal.im"), testDefaultOptions())
+
+	form := &xep0004.DataForm{
+		Type: xep0004.Submit,
+		Fields: []xep0004.Field{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{publishOptionsNamespace}},
+			{Var: accessModelField, Values: []string{WhitelistAccessModel}},
+		},
+	}
+
+	// when
+	_ = s.ProcessIQ(context.Background(), "ortuman@jackal.im", testPublishIQ("ortuman@jackal.im/yard", "ortuman@jackal.im", "urn:xmpp:tune", "i1", form))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+
+	errElem := stanzas[0].Child("error")
+	require.NotNil(t, errElem.Child("conflict"))
+	require.NotNil(t, errElem.ChildNamespace("precondition-not-met", errorsNamespace))
+	require.Len(t, st.items["urn:xmpp:tune"], 0)
+}
+
+func TestService_GetItemsPresenceAccess(t *testing.T) {
+	// given
+	s, rtr, st := testService(nil)
+
+	opts := testDefaultOptions()
+	opts.AccessModel = PresenceAccessModel
+	st.nodes["urn:xmpp:tune"] = newNode("ortuman@jackal.im", "urn:xmpp:tune", testJID("ortuman@jackal.im"), opts)
+	st.items["urn:xmpp:tune"] = []*pubsubmodel.Item{
+		{Host: "ortuman@jackal.im", Node: "urn:xmpp:tune", Id: "i1", Payload: stravaganza.NewBuilder("tune").Build().Proto()},
+		{Host: "ortuman@jackal.im", Node: "urn:xmpp:tune", Id: "i2", Payload: stravaganza.NewBuilder("tune").Build().Proto()},
+	}
+	st.ris["noelia@jackal.im"] = &rostermodel.Item{Username: "ortuman", Jid: "noelia@jackal.im", Subscription: rostermodel.From}
+
+	getItems := func(from string) *stravaganza.IQ {
+		return testIQ(from, "ortuman@jackal.im", stravaganza.GetType,
+			stravaganza.NewBuilder("pubsub").
+				WithAttribute(stravaganza.Namespace, Namespace).
+				WithChild(
+					stravaganza.NewBuilder("items").
+						WithAttribute("node", "urn:xmpp:tune").
+						WithAttribute("max_items", "1").
+						Build(),
+				).
+				Build(),
+		)
+	}
+
+	// when
+	_ = s.ProcessIQ(context.Background(), "ortuman@jackal.im", getItems("noelia@jackal.im/balcony"))
+	allowed := rtr.flush()
+
+	_ = s.ProcessIQ(context.Background(), "ortuman@jackal.im", getItems("romeo@jackal.im/garden"))
+	denied := rtr.flush()
+
+	// then
+	require.Len(t, allowed, 1)
+	require.Equal(t, stravaganza.ResultType, allowed[0].Type())
+	items := allowed[0].ChildNamespace("pubsub", Namespace).Child("items").Children("item")
+	require.Len(t, items, 1)
+	require.Equal(t, "i2", items[0].Attribute("id"))
+
+	require.Len(t, denied, 1)
+	require.Equal(t, stravaganza.ErrorType, denied[0].Type())
+	require.NotNil(t, denied[0].Child("error").Child("not-authorized"))
+	require.NotNil(t, denied[0].Child("error").ChildNamespace("presence-subscription-required", errorsNamespace))
+}
+
+func TestService_SubscribeWhitelist(t *testing.T) {
+	// given
+	s, rtr, st := testService(nil)
+
+	opts := testDefaultOptions()
+	opts.AccessModel = WhitelistAccessModel
+	node := newNode("pubsub.jackal.im", "news", testJID("ortuman@jackal.im"), opts)
+	node.Affiliations = setAffiliation(node.Affiliations, "noelia@jackal.im", memberAffiliation)
+	st.nodes["news"] = node
+
+	subscribe := func(from string) *stravaganza.IQ {
+		return testIQ(from, "pubsub.jackal.im", stravaganza.SetType,
+			stravaganza.NewBuilder("pubsub").
+				WithAttribute(stravaganza.Namespace, Namespace).
+				WithChild(
+					stravaganza.NewBuilder("subscribe").
+						WithAttribute("node", "news").
+						WithAttribute("jid", testJID(from).ToBareJID().String()).
+						Build(),
+				).
+				Build(),
+		)
+	}
+
+	// when
+	_ = s.ProcessIQ(context.Background(), "pubsub.jackal.im", subscribe("noelia@jackal.im/balcony"))
+	allowed := rtr.flush()
+
+	_ = s.ProcessIQ(context.Background(), "pubsub.jackal.im", subscribe("romeo@jackal.im/garden"))
+	denied := rtr.flush()
+
+	// then
+	require.Len(t, allowed, 1)
+	require.Equal(t, stravaganza.ResultType, allowed[0].Type())
+	sub := allowed[0].ChildNamespace("pubsub", Namespace).Child("subscription")
+	require.Equal(t, "subscribed", sub.Attribute("subscription"))
+	require.Len(t, st.subs["news"], 1)
+
+	require.Len(t, denied, 1)
+	require.NotNil(t, denied[0].Child("error").Child("not-allowed"))
+	require.NotNil(t, denied[0].Child("error").ChildNamespace("closed-node", errorsNamespace))
+}
+
+func TestService_RetractNotify(t *testing.T) {
+	// given
+	s, rtr, st := testService(nil)
+
+	opts := testDefaultOptions()
+	opts.NotifyRetract = true
+	st.nodes["news"] = newNode("pubsub.jackal.im", "news", testJID("ortuman@jackal.im"), opts)
+	st.items["news"] = []*pubsubmodel.Item{
+		{Host: "pubsub.jackal.im", Node: "news", Id: "i1", Publisher: "ortuman@jackal.im"},
+	}
+	st.subs["news"] = []*pubsubmodel.Subscription{
+		{Host: "pubsub.jackal.im", Node: "news", Jid: "noelia@jackal.im", Subid: "s1", State: subscribedState},
+	}
+
+	// when
+	_ = s.ProcessIQ(context.Background(), "pubsub.jackal.im", testIQ("ortuman@jackal.im/yard", "pubsub.jackal.im", stravaganza.SetType,
+		stravaganza.NewBuilder("pubsub").
+			WithAttribute(stravaganza.Namespace, Namespace).
+			WithChild(
+				stravaganza.NewBuilder("retract").
+					WithAttribute("node", "news").
+					WithChild(stravaganza.NewBuilder("item").WithAttribute("id", "i1").Build()).
+					Build(),
+			).
+			Build(),
+	))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 2)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+
+	require.Equal(t, "noelia@jackal.im", stanzas[1].ToJID().String())
+	retract := stanzas[1].ChildNamespace("event", EventNamespace).Child("items").Child("retract")
+	require.Equal(t, "i1", retract.Attribute("id"))
+
+	require.Len(t, st.items["news"], 0)
+}
+
+func TestService_DeleteNode(t *testing.T) {
+	// given
+	s, rtr, st := testService(nil)
+	st.nodes["news"] = newNode("pubsub.jackal.im", "news", testJID("ortuman@jackal.im"), testDefaultOptions())
+
+	deleteNode := func(from string) *stravaganza.IQ {
+		return testIQ(from, "pubsub.jackal.im", stravaganza.SetType,
+			stravaganza.NewBuilder("pubsub").
+				WithAttribute(stravaganza.Namespace, OwnerNamespace).
+				WithChild(stravaganza.NewBuilder("delete").WithAttribute("node", "news").Build()).
+				Build(),
+		)
+	}
+
+	// when
+	_ = s.ProcessIQ(context.Background(), "pubsub.jackal.im", deleteNode("noelia@jackal.im/balcony"))
+	forbidden := rtr.flush()
+
+	_ = s.ProcessIQ(context.Background(), "pubsub.jackal.im", deleteNode("ortuman@jackal.im/yard"))
+	deleted := rtr.flush()
+
+	// then
+	require.Len(t, forbidden, 1)
+	require.NotNil(t, forbidden[0].Child("error").Child("forbidden"))
+
+	require.Len(t, deleted, 1)
+	require.Equal(t, stravaganza.ResultType, deleted[0].Type())
+	require.Nil(t, st.nodes["news"])
+}
+
+func TestService_SetNodeAffiliations(t *testing.T) {
+	// given
+	s, rtr, st := testService(nil)
+	st.nodes["news"] = newNode("pubsub.jackal.im", "news", testJID("ortuman@jackal.im"), testDefaultOptions())
+
+	setAffiliations := func(affs ...[2]string) *stravaganza.IQ {
+		ab := stravaganza.NewBuilder("affiliations").WithAttribute("node", "news")
+		for _, aff := range affs {
+			ab.WithChild(
+				stravaganza.NewBuilder("affiliation").
+					WithAttribute("jid", aff[0]).
+					WithAttribute("affiliation", aff[1]).
+					Build(),
+			)
+		}
+		return testIQ("ortuman@jackal.im/yard", "pubsub.jackal.im", stravaganza.SetType,
+			stravaganza.NewBuilder("pubsub").
+				WithAttribute(stravaganza.Namespace, OwnerNamespace).
+				WithChild(ab.Build()).
+				Build(),
+		)
+	}
+
+	// when
+	_ = s.ProcessIQ(context.Background(), "pubsub.jackal.im", setAffiliations([2]string{"noelia@jackal.im", "publisher"}))
+	added := rtr.flush()
+
+	_ = s.ProcessIQ(context.Background(), "pubsub.jackal.im", setAffiliations([2]string{"ortuman@jackal.im", "none"}))
+	rejected := rtr.flush()
+
+	// then
+	require.Len(t, added, 1)
+	require.Equal(t, stravaganza.ResultType, added[0].Type())
+	require.Equal(t, publisherAffiliation, affiliation(st.nodes["news"], testJID("noelia@jackal.im/balcony")))
+
+	require.Len(t, rejected, 1)
+	require.NotNil(t, rejected[0].Child("error").Child("not-acceptable"))
+	require.Equal(t, ownerAffiliation, affiliation(st.nodes["news"], testJID("ortuman@jackal.im")))
+}
+
+func testService(implicit []*jid.JID) (*Service, *testRouter, *testStore) {
+	rtr := &testRouter{}
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = rtr.route
+
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+	policyMock := &policyMock{}
+	policyMock.CanCreateNodeFunc = func(ctx context.Context, host string, jd *jid.JID) bool { return true }
+	policyMock.AutoCreateFunc = func() bool { return true }
+	policyMock.DefaultNodeOptionsFunc = testDefaultOptions
+	policyMock.ImplicitSubscribersFunc = func(ctx context.Context, node *pubsubmodel.Node) ([]*jid.JID, error) {
+		return implicit, nil
+	}
+
+	st := &testStore{
+		nodes: make(map[string]*pubsubmodel.Node),
+		items: make(map[string][]*pubsubmodel.Item),
+		subs:  make(map[string][]*pubsubmodel.Subscription),
+		ris:   make(map[string]*rostermodel.Item),
+	}
+	repMock := &repositoryMock{}
+	repMock.FetchNodeFunc = func(ctx context.Context, host, name string) (*pubsubmodel.Node, error) {
+		return st.nodes[name], nil
+	}
+	repMock.FetchNodesFunc = func(ctx context.Context, host string) ([]*pubsubmodel.Node, error) {
+		var nodes []*pubsubmodel.Node
+		for _, node := range st.nodes {
+			nodes = append(nodes, node)
+		}
+		return nodes, nil
+	}
+	repMock.UpsertNodeFunc = func(ctx context.Context, node *pubsubmodel.Node) error {
+		st.nodes[node.Name] = node
+		return nil
+	}
+	repMock.DeleteNodeFunc = func(ctx context.Context, host, name string) error {
+		delete(st.nodes, name)
+		delete(st.items, name)
+		delete(st.subs, name)
+		return nil
+	}
+	repMock.UpsertNodeItemFunc = func(ctx context.Context, item *pubsubmodel.Item) error {
+		st.items[item.Node] = append(st.items[item.Node], item)
+		return nil
+	}
+	repMock.FetchNodeItemsFunc = func(ctx context.Context, host, name string) ([]*pubsubmodel.Item, error) {
+		return st.items[name], nil
+	}
+	repMock.DeleteNodeItemFunc = func(ctx context.Context, host, name, id string) error {
+		var items []*pubsubmodel.Item
+		for _, item := range st.items[name] {
+			if item.Id != id {
+				items = append(items, item)
+			}
+		}
+		st.items[name] = items
+		return nil
+	}
+	repMock.DeleteOldestNodeItemsFunc = func(ctx context.Context, host, name string, maxItems int) error {
+		return nil
+	}
+	repMock.UpsertNodeSubscriptionFunc = func(ctx context.Context, sub *pubsubmodel.Subscription) error {
+		st.subs[sub.Node] = append(st.subs[sub.Node], sub)
+		return nil
+	}
+	repMock.FetchNodeSubscriptionsFunc = func(ctx context.Context, host, name string) ([]*pubsubmodel.Subscription, error) {
+		return st.subs[name], nil
+	}
+	repMock.FetchRosterItemFunc = func(ctx context.Context, username, jid string) (*rostermodel.Item, error) {
+		return st.ris[jid], nil
+	}
+	s := &Service{
+		policy: policyMock,
+		rep:    repMock,
+		router: routerMock,
+		hosts:  hostsMock,
+		logger: kitlog.NewNopLogger(),
+	}
+	return s, rtr, st
+}
+
+func testDefaultOptions() *pubsubmodel.Options {
+	return &pubsubmodel.Options{
+		AccessModel:           OpenAccessModel,
+		PublishModel:          PublishersPublishModel,
+		MaxItems:              10,
+		PersistItems:          true,
+		DeliverPayloads:       true,
+		SendLastPublishedItem: SendLastNever,
+	}
+}
+
+func testPublishIQ(from, to, node, itemID string, publishOpts *xep0004.DataForm) *stravaganza.IQ {
+	pb := stravaganza.NewBuilder("pubsub").
+		WithAttribute(stravaganza.Namespace, Namespace).
+		WithChild(
+			stravaganza.NewBuilder("publish").
+				WithAttribute("node", node).
+				WithChild(
+					stravaganza.NewBuilder("item").
+						WithAttribute("id", itemID).
+						WithChild(stravaganza.NewBuilder("tune").Build()).
+						Build(),
+				).
+				Build(),
+		)
+	if publishOpts != nil {
+		pb.WithChild(
+			stravaganza.NewBuilder("publish-options").
+				WithChild(publishOpts.Element()).
+				Build(),
+		)
+	}
+	return testIQ(from, to, stravaganza.SetType, pb.Build())
+}
+
+func testIQ(from, to, typ string, child stravaganza.Element) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "iq-1").
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, to).
+		WithAttribute(stravaganza.Type, typ).
+		WithChild(child).
+		BuildIQ()
+	return iq
+}
+
+func testJID(str string) *jid.JID {
+	jd, _ := jid.NewWithString(str, true)
+	return jd
+}
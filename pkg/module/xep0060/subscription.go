@@ -0,0 +1,284 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0060
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+)
+
+func (s *Service) subscribe(ctx context.Context, host string, iq *stravaganza.IQ, subscribe stravaganza.Element) error {
+	subJID, ok := s.subscriptionJID(ctx, iq, subscribe)
+	if !ok {
+		return nil
+	}
+	node, err := s.fetchNode(ctx, host, iq, subscribe)
+	if err != nil || node == nil {
+		return err
+	}
+	if ok, err := s.checkAccess(ctx, node, iq); err != nil || !ok {
+		return err
+	}
+	sub := &pubsubmodel.Subscription{
+		Host:  host,
+		Node:  node.Name,
+		Jid:   subJID.String(),
+		Subid: uuid.New().String(),
+		State: subscribedState,
+	}
+	if err := s.rep.UpsertNodeSubscription(ctx, sub); err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	s.sendResult(ctx, iq, Namespace, subscriptionElement(sub, true))
+
+	if node.Options.SendLastPublishedItem == SendLastNever {
+		return nil
+	}
+	return s.SendLastItem(ctx, node, subJID)
+}
+
+func (s *Service) unsubscribe(ctx context.Context, host string, iq *stravaganza.IQ, unsubscribe stravaganza.Element) error {
+	subJID, ok := s.subscriptionJID(ctx, iq, unsubscribe)
+	if !ok {
+		return nil
+	}
+	node, err := s.fetchNode(ctx, host, iq, unsubscribe)
+	if err != nil || node == nil {
+		return err
+	}
+	subs, err := s.rep.FetchNodeSubscriptions(ctx, host, node.Name)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	var sub *pubsubmodel.Subscription
+	for _, sb := range subs {
+		if sb.Jid == subJID.String() {
+			sub = sb
+			break
+		}
+	}
+	if sub == nil {
+		s.sendError(ctx, iq, stanzaerror.UnexpectedRequest, errorCondition("not-subscribed"))
+		return nil
+	}
+	if subID := unsubscribe.Attribute("subid"); len(subID) > 0 && subID != sub.Subid {
+		s.sendError(ctx, iq, stanzaerror.NotAcceptable, errorCondition("invalid-subid"))
+		return nil
+	}
+	if err := s.rep.DeleteNodeSubscription(ctx, host, node.Name, sub.Jid); err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	s.sendResult(ctx, iq, "")
+	return nil
+}
+
+func (s *Service) getSubscriptions(ctx context.Context, host string, iq *stravaganza.IQ, subscriptions stravaganza.Element) error {
+	nodes, err := s.requestedNodes(ctx, host, subscriptions)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	fromBareJID := iq.FromJID().ToBareJID()
+
+	sb := stravaganza.NewBuilder("subscriptions")
+	for _, node := range nodes {
+		subs, err := s.rep.FetchNodeSubscriptions(ctx, host, node.Name)
+		if err != nil {
+			s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+			return err
+		}
+		for _, sub := range subs {
+			subJID, err := jid.NewWithString(sub.Jid, true)
+			if err != nil || !subJID.MatchesWithOptions(fromBareJID, jid.MatchesBare) {
+				continue
+			}
+			sb.WithChild(subscriptionElement(sub, true))
+		}
+	}
+	s.sendResult(ctx, iq, Namespace, sb.Build())
+	return nil
+}
+
+func (s *Service) getAffiliations(ctx context.Context, host string, iq *stravaganza.IQ, affiliations stravaganza.Element) error {
+	nodes, err := s.requestedNodes(ctx, host, affiliations)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	ab := stravaganza.NewBuilder("affiliations")
+	for _, node := range nodes {
+		aff := affiliation(node, iq.FromJID())
+		if aff == noneAffiliation {
+			continue
+		}
+		ab.WithChild(
+			stravaganza.NewBuilder("affiliation").
+				WithAttribute("node", node.Name).
+				WithAttribute("affiliation", aff).
+				Build(),
+		)
+	}
+	s.sendResult(ctx, iq, Namespace, ab.Build())
+	return nil
+}
+
+func (s *Service) getNodeSubscriptions(ctx context.Context, host string, iq *stravaganza.IQ, subscriptions stravaganza.Element) error {
+	node, err := s.fetchNode(ctx, host, iq, subscriptions)
+	if err != nil || node == nil {
+		return err
+	}
+	if affiliation(node, iq.FromJID()) != ownerAffiliation {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	subs, err := s.rep.FetchNodeSubscriptions(ctx, host, node.Name)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	sb := stravaganza.NewBuilder("subscriptions").
+		WithAttribute("node", node.Name)
+	for _, sub := range subs {
+		sb.WithChild(subscriptionElement(sub, false))
+	}
+	s.sendResult(ctx, iq, OwnerNamespace, sb.Build())
+	return nil
+}
+
+func (s *Service) getNodeAffiliations(ctx context.Context, host string, iq *stravaganza.IQ, affiliations stravaganza.Element) error {
+	node, err := s.fetchNode(ctx, host, iq, affiliations)
+	if err != nil || node == nil {
+		return err
+	}
+	if affiliation(node, iq.FromJID()) != ownerAffiliation {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	ab := stravaganza.NewBuilder("affiliations").
+		WithAttribute("node", node.Name)
+	for _, aff := range node.Affiliations {
+		ab.WithChild(
+			stravaganza.NewBuilder("affiliation").
+				WithAttribute("jid", aff.Jid).
+				WithAttribute("affiliation", aff.Affiliation).
+				Build(),
+		)
+	}
+	s.sendResult(ctx, iq, OwnerNamespace, ab.Build())
+	return nil
+}
+
+func (s *Service) setNodeAffiliations(ctx context.Context, host string, iq *stravaganza.IQ, affiliations stravaganza.Element) error {
+	node, err := s.fetchNode(ctx, host, iq, affiliations)
+	if err != nil || node == nil {
+		return err
+	}
+	if affiliation(node, iq.FromJID()) != ownerAffiliation {
+		s.sendError(ctx, iq, stanzaerror.Forbidden, nil)
+		return nil
+	}
+	affs := node.Affiliations
+	for _, affElem := range affiliations.Children("affiliation") {
+		jd, err := jid.NewWithString(affElem.Attribute("jid"), false)
+		if err != nil {
+			s.sendError(ctx, iq, stanzaerror.JIDMalformed, nil)
+			return nil
+		}
+		aff := affElem.Attribute("affiliation")
+		switch aff {
+		case ownerAffiliation, publisherAffiliation, memberAffiliation, outcastAffiliation, noneAffiliation:
+			affs = setAffiliation(affs, jd.ToBareJID().String(), aff)
+		default:
+			s.sendError(ctx, iq, stanzaerror.BadRequest, nil)
+			return nil
+		}
+	}
+	// a node must always keep at least one owner
+	var hasOwner bool
+	for _, aff := range affs {
+		hasOwner = hasOwner || aff.Affiliation == ownerAffiliation
+	}
+	if !hasOwner {
+		s.sendError(ctx, iq, stanzaerror.NotAcceptable, nil)
+		return nil
+	}
+	node.Affiliations = affs
+	if err := s.rep.UpsertNode(ctx, node); err != nil {
+		s.sendError(ctx, iq, stanzaerror.InternalServerError, nil)
+		return err
+	}
+	s.sendResult(ctx, iq, "")
+	return nil
+}
+
+// subscriptionJID returns the subscription target JID, validating it matches iq sender.
+func (s *Service) subscriptionJID(ctx context.Context, iq *stravaganza.IQ, elem stravaganza.Element) (*jid.JID, bool) {
+	subJID, err := jid.NewWithString(elem.Attribute("jid"), false)
+	if err != nil {
+		s.sendError(ctx, iq, stanzaerror.BadRequest, errorCondition("jid-required"))
+		return nil, false
+	}
+	if !subJID.MatchesWithOptions(iq.FromJID(), jid.MatchesBare) {
+		s.sendError(ctx, iq, stanzaerror.BadRequest, errorCondition("invalid-jid"))
+		return nil, false
+	}
+	return subJID, true
+}
+
+// requestedNodes returns the node referenced by elem 'node' attribute, or all host nodes in case it's not present.
+func (s *Service) requestedNodes(ctx context.Context, host string, elem stravaganza.Element) ([]*pubsubmodel.Node, error) {
+	nodeID := elem.Attribute("node")
+	if len(nodeID) == 0 {
+		return s.rep.FetchNodes(ctx, host)
+	}
+	node, err := s.rep.FetchNode(ctx, host, nodeID)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	return []*pubsubmodel.Node{node}, nil
+}
+
+// setAffiliation returns a copy of affs where bareJID affiliation is replaced by aff.
+func setAffiliation(affs []*pubsubmodel.Affiliation, bareJID, aff string) []*pubsubmodel.Affiliation {
+	retVal := make([]*pubsubmodel.Affiliation, 0, len(affs)+1)
+	for _, a := range affs {
+		if a.Jid != bareJID {
+			retVal = append(retVal, a)
+		}
+	}
+	if aff != noneAffiliation {
+		retVal = append(retVal, &pubsubmodel.Affiliation{Jid: bareJID, Affiliation: aff})
+	}
+	return retVal
+}
+
+func subscriptionElement(sub *pubsubmodel.Subscription, withNode bool) stravaganza.Element {
+	b := stravaganza.NewBuilder("subscription")
+	if withNode {
+		b.WithAttribute("node", sub.Node)
+	}
+	return b.WithAttribute("jid", sub.Jid).
+		WithAttribute("subid", sub.Subid).
+		WithAttribute("subscription", sub.State).
+		Build()
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0077
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"golang.org/x/time/rate"
+)
+
+// Request contains the information associated to an in-band registration request.
+type Request struct {
+	// Username is the requested account username.
+	Username string
+
+	// Password is the requested account password.
+	Password string
+
+	// RemoteAddr is the network address of the registering client.
+	RemoteAddr net.Addr
+}
+
+// Policy represents a registration policy.
+// Every registered policy gets checked before creating a new account.
+type Policy interface {
+	// Check returns a non-nil error in case the registration request should be rejected.
+	// A *PolicyError can be returned to specify the stanza error reported back to the client.
+	Check(ctx context.Context, req *Request) error
+}
+
+// PolicyFunc is an adapter to allow the use of ordinary functions as registration policies.
+type PolicyFunc func(ctx context.Context, req *Request) error
+
+// Check calls f(ctx, req).
+func (f PolicyFunc) Check(ctx context.Context, req *Request) error {
+	return f(ctx, req)
+}
+
+// PolicyError represents a registration policy rejection.
+type PolicyError struct {
+	// Reason is the stanza error reason reported back to the client.
+	Reason stanzaerror.Reason
+
+	// Text is a human-readable description of the rejection.
+	Text string
+}
+
+// Error satisfies error interface.
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("xep0077: registration rejected: %s", e.Text)
+}
+
+// NewPasswordLengthPolicy returns a policy that rejects passwords shorter than minLength characters.
+func NewPasswordLengthPolicy(minLength int) Policy {
+	return PolicyFunc(func(_ context.Context, req *Request) error {
+		if utf8.RuneCountInString(req.Password) < minLength {
+			return &PolicyError{
+				Reason: stanzaerror.NotAcceptable,
+				Text:   fmt.Sprintf("Password must be at least %d characters long", minLength),
+			}
+		}
+		return nil
+	})
+}
+
+// RateLimitPolicy limits the number of registration attempts a remote IP address can perform.
+type RateLimitPolicy struct {
+	count    int
+	interval time.Duration
+
+	mu        sync.Mutex
+	limiters  map[string]*ipLimiter
+	lastPrune time.Time
+}
+
+type ipLimiter struct {
+	lim      *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimitPolicy returns a policy that allows up to count registration attempts per interval
+// from a single remote IP address.
+func NewRateLimitPolicy(count int, interval time.Duration) *RateLimitPolicy {
+	return &RateLimitPolicy{
+		count:     count,
+		interval:  interval,
+		limiters:  make(map[string]*ipLimiter),
+		lastPrune: time.Now(),
+	}
+}
+
+// Check satisfies Policy interface.
+func (p *RateLimitPolicy) Check(_ context.Context, req *Request) error {
+	if req.RemoteAddr == nil || p.count <= 0 {
+		return nil
+	}
+	ip := remoteIP(req.RemoteAddr)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.prune(now)
+
+	l := p.limiters[ip]
+	if l == nil {
+		l = &ipLimiter{
+			lim: rate.NewLimiter(rate.Every(p.interval/time.Duration(p.count)), p.count),
+		}
+		p.limiters[ip] = l
+	}
+	l.lastSeen = now
+
+	if !l.lim.AllowN(now, 1) {
+		return &PolicyError{
+			Reason: stanzaerror.ResourceConstraint,
+			Text:   "Too many registration attempts, try again later",
+		}
+	}
+	return nil
+}
+
+// prune removes limiters whose bucket would be completely refilled by now.
+func (p *RateLimitPolicy) prune(now time.Time) {
+	if now.Sub(p.lastPrune) < p.interval {
+		return
+	}
+	for ip, l := range p.limiters {
+		if now.Sub(l.lastSeen) >= p.interval {
+			delete(p.limiters, ip)
+		}
+	}
+	p.lastPrune = now
+}
+
+func remoteIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return addr.String()
+		}
+		return host
+	}
+}
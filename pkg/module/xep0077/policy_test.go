@@ -0,0 +1,61 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0077
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitPolicy_Check(t *testing.T) {
+	// given
+	p := NewRateLimitPolicy(2, time.Hour)
+
+	addr0 := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 50001}
+	addr1 := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 50002}
+	addr2 := &net.TCPAddr{IP: net.ParseIP("192.168.0.2"), Port: 50001}
+
+	// when
+	err0 := p.Check(context.Background(), &Request{RemoteAddr: addr0})
+	err1 := p.Check(context.Background(), &Request{RemoteAddr: addr1})
+	err2 := p.Check(context.Background(), &Request{RemoteAddr: addr0})
+	err3 := p.Check(context.Background(), &Request{RemoteAddr: addr2})
+
+	// then
+	require.Nil(t, err0)
+	require.Nil(t, err1)
+	require.NotNil(t, err2)
+	require.Nil(t, err3)
+
+	require.Len(t, p.limiters, 2)
+}
+
+func TestRateLimitPolicy_Prune(t *testing.T) {
+	// given
+	p := NewRateLimitPolicy(1, time.Minute)
+
+	addr := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 50001}
+	_ = p.Check(context.Background(), &Request{RemoteAddr: addr})
+
+	// when
+	p.prune(time.Now().Add(time.Minute * 2))
+
+	// then
+	require.Len(t, p.limiters, 0)
+}
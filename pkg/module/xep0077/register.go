@@ -0,0 +1,586 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0077
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/auth"
+	"github.com/ortuman/jackal/pkg/auth/pepper"
+	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
+	"github.com/ortuman/jackal/pkg/hook"
+	usermodel "github.com/ortuman/jackal/pkg/model/user"
+	"github.com/ortuman/jackal/pkg/module"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/module/xep0401"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	registerNamespace        = "jabber:iq:register"
+	registerFeatureNamespace = "http://jabber.org/features/iq-register"
+	ibrTokenNamespace        = "urn:xmpp:ibr-token:0"
+	parsNamespace            = "urn:xmpp:pars:0"
+
+	registerInstructions = "Choose a username and password to register with this server"
+)
+
+const (
+	// ModuleName represents register module name.
+	ModuleName = "register"
+
+	// XEPNumber represents register XEP number.
+	XEPNumber = "0077"
+)
+
+// Config contains in-band registration module configuration options.
+type Config struct {
+	// DataForm, if true, registration fields will also be offered by means of a data form (XEP-0004).
+	DataForm bool `fig:"data_form"`
+
+	// MinPasswordLength defines the minimum length a registered password must have.
+	MinPasswordLength int `fig:"min_password_length" default:"8"`
+
+	// RateLimit contains per-IP registration rate limiting configuration.
+	RateLimit struct {
+		// Count defines the maximum number of registration attempts allowed within a period.
+		// A zero value disables rate limiting.
+		Count int `fig:"count" default:"3"`
+
+		// Interval defines rate limiting period.
+		Interval time.Duration `fig:"interval" default:"1h"`
+	} `fig:"rate_limit"`
+
+	// InviteOnly, if true, new accounts can only be registered by presenting a valid invite token (XEP-0401).
+	InviteOnly bool `fig:"invite_only"`
+}
+
+// Register represents an in-band registration (XEP-0077) module type.
+type Register struct {
+	cfg      Config
+	router   router.Router
+	resMng   resourcemanager.Manager
+	rep      repository.Repository
+	peppers  *pepper.Keys
+	hk       *hook.Hooks
+	logger   kitlog.Logger
+	pwdPol   Policy
+	policies []Policy
+	invites  *xep0401.Invites
+
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+// New returns a new initialized Register instance.
+func New(
+	cfg Config,
+	router router.Router,
+	resMng resourcemanager.Manager,
+	rep repository.Repository,
+	peppers *pepper.Keys,
+	hk *hook.Hooks,
+	logger kitlog.Logger,
+) *Register {
+	pwdPol := NewPasswordLengthPolicy(cfg.MinPasswordLength)
+	return &Register{
+		cfg:     cfg,
+		router:  router,
+		resMng:  resMng,
+		rep:     rep,
+		peppers: peppers,
+		hk:      hk,
+		logger:  kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+		pwdPol:  pwdPol,
+		policies: []Policy{
+			pwdPol,
+			NewRateLimitPolicy(cfg.RateLimit.Count, cfg.RateLimit.Interval),
+		},
+		invites: xep0401.NewInvites(rep),
+		tokens:  make(map[string]string),
+	}
+}
+
+// AddPolicy registers an additional policy to be checked before creating a new account.
+// Policies should be added before starting the module.
+func (m *Register) AddPolicy(p Policy) {
+	m.policies = append(m.policies, p)
+}
+
+// Name returns register module name.
+func (m *Register) Name() string { return ModuleName }
+
+// StreamFeature returns register module stream feature.
+func (m *Register) StreamFeature(_ context.Context, _ string) (stravaganza.Element, error) {
+	return nil, nil
+}
+
+// ServerFeatures returns register server disco features.
+func (m *Register) ServerFeatures(_ context.Context) ([]string, error) {
+	return []string{registerNamespace}, nil
+}
+
+// AccountFeatures returns register account disco features.
+func (m *Register) AccountFeatures(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// PreAuthStreamFeatures returns the registration features offered to not yet authenticated streams.
+func (m *Register) PreAuthStreamFeatures() []stravaganza.Element {
+	return []stravaganza.Element{
+		stravaganza.NewBuilder("register").
+			WithAttribute(stravaganza.Namespace, registerFeatureNamespace).
+			Build(),
+		stravaganza.NewBuilder("register").
+			WithAttribute(stravaganza.Namespace, ibrTokenNamespace).
+			Build(),
+	}
+}
+
+// MatchesNamespace tells whether namespace matches register module.
+func (m *Register) MatchesNamespace(namespace string, _ bool) bool {
+	return namespace == registerNamespace || namespace == parsNamespace
+}
+
+// ProcessPreAuthIQ process a registration iq received over a not yet authenticated stream.
+func (m *Register) ProcessPreAuthIQ(ctx context.Context, iq *stravaganza.IQ, inf module.PreAuthInfo) (stravaganza.Stanza, error) {
+	if pa := iq.ChildNamespace("preauth", parsNamespace); pa != nil {
+		return m.preAuthenticate(ctx, iq, pa, inf)
+	}
+	q := iq.ChildNamespace("query", registerNamespace)
+	switch {
+	case q == nil:
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest), nil
+
+	case iq.IsGet():
+		return xmpputil.MakeResultIQ(iq, m.registrationQuery()), nil
+
+	case iq.IsSet() && q.Child("remove") != nil:
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.NotAuthorized), nil
+
+	case iq.IsSet():
+		return m.register(ctx, iq, q, inf)
+
+	default:
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest), nil
+	}
+}
+
+// ProcessIQ process a registration iq sent by an already authenticated user.
+func (m *Register) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	fromJID := iq.FromJID()
+	toJID := iq.ToJID()
+	if !toJID.IsServer() && toJID.Node() != fromJID.Node() {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return nil
+	}
+	q := iq.ChildNamespace("query", registerNamespace)
+	switch {
+	case iq.ChildNamespace("preauth", parsNamespace) != nil:
+		// account already exists, so there's nothing to preauthenticate
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.NotAllowed))
+		return nil
+
+	case q == nil:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+
+	case iq.IsGet():
+		return m.getRegistration(ctx, iq)
+
+	case iq.IsSet() && q.Child("remove") != nil:
+		return m.unregister(ctx, iq)
+
+	case iq.IsSet():
+		return m.changePassword(ctx, iq, q)
+
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+}
+
+// Start starts register module.
+func (m *Register) Start(_ context.Context) error {
+	m.hk.AddHook(hook.C2SStreamDisconnected, m.onStreamClosed, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamTerminated, m.onStreamClosed, hook.DefaultPriority)
+
+	level.Info(m.logger).Log("msg", "started register module")
+	return nil
+}
+
+// Stop stops register module.
+func (m *Register) Stop(_ context.Context) error {
+	m.hk.RemoveHook(hook.C2SStreamDisconnected, m.onStreamClosed)
+	m.hk.RemoveHook(hook.C2SStreamTerminated, m.onStreamClosed)
+
+	level.Info(m.logger).Log("msg", "stopped register module")
+	return nil
+}
+
+// onStreamClosed releases any invite token associated to a closing stream.
+// Terminated hook is also observed, given that disconnected one might never be reached by pre-auth streams.
+func (m *Register) onStreamClosed(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+
+	m.mu.Lock()
+	delete(m.tokens, inf.ID)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Register) preAuthenticate(ctx context.Context, iq *stravaganza.IQ, pa stravaganza.Element, inf module.PreAuthInfo) (stravaganza.Stanza, error) {
+	if !iq.IsSet() {
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest), nil
+	}
+	token := pa.Attribute("token")
+
+	inv, err := m.invites.Fetch(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || inv.Domain != iq.ToJID().Domain() {
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound), nil
+	}
+	m.mu.Lock()
+	m.tokens[inf.StreamID.String()] = token
+	m.mu.Unlock()
+
+	return xmpputil.MakeResultIQ(iq, nil), nil
+}
+
+func (m *Register) register(ctx context.Context, iq *stravaganza.IQ, q stravaganza.Element, inf module.PreAuthInfo) (stravaganza.Stanza, error) {
+	m.mu.RLock()
+	token := m.tokens[inf.StreamID.String()]
+	m.mu.RUnlock()
+
+	if len(token) == 0 && m.cfg.InviteOnly {
+		se := stanzaerror.E(stanzaerror.NotAllowed, iq)
+		se.Text = "Registration requires a valid invitation"
+		return se.Stanza(false)
+	}
+	username, password, ok := credentials(q)
+	if !ok {
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest), nil
+	}
+	if len(username) == 0 || len(password) == 0 {
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.NotAcceptable), nil
+	}
+	jd, err := jid.New(username, iq.ToJID().Domain(), "", false)
+	if err != nil {
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.JIDMalformed), nil
+	}
+	username = jd.Node()
+
+	// check registration policies
+	req := &Request{
+		Username:   username,
+		Password:   password,
+		RemoteAddr: inf.RemoteAddr,
+	}
+	for _, p := range m.policies {
+		if err := p.Check(ctx, req); err != nil {
+			return policyErrorStanza(iq, err)
+		}
+	}
+	// prevent concurrent registrations of the same username
+	lockID := registerLockID(username)
+	if err := m.rep.Lock(ctx, lockID); err != nil {
+		return nil, err
+	}
+	defer m.releaseLock(ctx, lockID)
+
+	exists, err := m.rep.UserExists(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.Conflict), nil
+	}
+	var inviter string
+	var redeemed bool
+
+	err = m.rep.InTransaction(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		if len(token) > 0 {
+			inv, err := m.invites.RedeemTx(ctx, tx, token)
+			if err != nil {
+				return err
+			}
+			if inv == nil {
+				return nil
+			}
+			inviter = inv.Inviter
+		}
+		redeemed = true
+		return m.upsertUser(ctx, tx, username, password)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !redeemed {
+		// invite got exhausted or expired in the meantime
+		m.mu.Lock()
+		delete(m.tokens, inf.StreamID.String())
+		m.mu.Unlock()
+
+		return xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound), nil
+	}
+	// run user created hook
+	_, err = m.hk.Run(hook.UserCreated, &hook.ExecutionContext{
+		Info: &hook.UserInfo{
+			Username: username,
+		},
+		Sender:  m,
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(token) > 0 {
+		if err := m.runInviteRedeemedHook(ctx, token, jd, inviter); err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		delete(m.tokens, inf.StreamID.String())
+		m.mu.Unlock()
+	}
+	level.Info(m.logger).Log("msg", "user registered", "username", username, "remote_addr", addrString(inf.RemoteAddr))
+
+	return xmpputil.MakeResultIQ(iq, nil), nil
+}
+
+func (m *Register) getRegistration(ctx context.Context, iq *stravaganza.IQ) error {
+	q := stravaganza.NewBuilder("query").
+		WithAttribute(stravaganza.Namespace, registerNamespace).
+		WithChild(stravaganza.NewBuilder("registered").Build()).
+		WithChild(
+			stravaganza.NewBuilder("username").
+				WithText(iq.FromJID().Node()).
+				Build(),
+		).
+		WithChild(stravaganza.NewBuilder("password").Build()).
+		Build()
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, q))
+	return nil
+}
+
+func (m *Register) changePassword(ctx context.Context, iq *stravaganza.IQ, q stravaganza.Element) error {
+	username, password, ok := credentials(q)
+	if !ok || len(password) == 0 {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	if username != iq.FromJID().Node() {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.NotAllowed))
+		return nil
+	}
+	if err := m.pwdPol.Check(ctx, &Request{Username: username, Password: password}); err != nil {
+		errStanza, err := policyErrorStanza(iq, err)
+		if err != nil {
+			return err
+		}
+		_, _ = m.router.Route(ctx, errStanza)
+		return nil
+	}
+	if err := m.upsertUser(ctx, m.rep, username, password); err != nil {
+		return err
+	}
+	// revoke all issued FAST tokens
+	if err := m.rep.DeleteFastTokens(ctx, username); err != nil {
+		return err
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+
+	level.Info(m.logger).Log("msg", "password changed", "username", username)
+	return nil
+}
+
+func (m *Register) unregister(ctx context.Context, iq *stravaganza.IQ) error {
+	username := iq.FromJID().Node()
+
+	if err := m.rep.DeleteUser(ctx, username); err != nil {
+		return err
+	}
+	if err := m.rep.DeleteFastTokens(ctx, username); err != nil {
+		return err
+	}
+	// run user deleted hook
+	_, err := m.hk.Run(hook.UserDeleted, &hook.ExecutionContext{
+		Info: &hook.UserInfo{
+			Username: username,
+		},
+		Sender:  m,
+		Context: ctx,
+	})
+	if err != nil {
+		return err
+	}
+	// user no longer exists, so skip existence check while replying
+	_, _ = m.router.C2S().Route(ctx, xmpputil.MakeResultIQ(iq, nil), 0)
+
+	// disconnect all user resources
+	rss, err := m.resMng.GetResources(ctx, username)
+	if err != nil {
+		return err
+	}
+	for _, rs := range rss {
+		if err := m.router.C2S().Disconnect(ctx, rs, streamerror.E(streamerror.NotAuthorized)); err != nil {
+			return err
+		}
+	}
+	level.Info(m.logger).Log("msg", "user unregistered", "username", username)
+	return nil
+}
+
+func (m *Register) runInviteRedeemedHook(ctx context.Context, token string, inviteeJID *jid.JID, inviter string) error {
+	inf := &hook.InviteInfo{
+		Token:   token,
+		Invitee: inviteeJID,
+	}
+	if len(inviter) > 0 {
+		inviterJID, err := jid.New(inviter, inviteeJID.Domain(), "", true)
+		if err != nil {
+			return err
+		}
+		inf.Inviter = inviterJID
+	}
+	_, err := m.hk.Run(hook.InviteRedeemed, &hook.ExecutionContext{
+		Info:    inf,
+		Sender:  m,
+		Context: ctx,
+	})
+	return err
+}
+
+func (m *Register) upsertUser(ctx context.Context, rep repository.User, username, password string) error {
+	scram, err := auth.NewScramCredentials(password, m.peppers)
+	if err != nil {
+		return err
+	}
+	return rep.UpsertUser(ctx, &usermodel.User{
+		Username: username,
+		Scram:    scram,
+	})
+}
+
+func (m *Register) releaseLock(ctx context.Context, lockID string) {
+	if err := m.rep.Unlock(ctx, lockID); err != nil {
+		level.Warn(m.logger).Log("msg", "failed to release lock", "err", err)
+	}
+}
+
+func registerLockID(username string) string {
+	return fmt.Sprintf("register:lock:%s", username)
+}
+
+func (m *Register) registrationQuery() stravaganza.Element {
+	b := stravaganza.NewBuilder("query").
+		WithAttribute(stravaganza.Namespace, registerNamespace).
+		WithChild(
+			stravaganza.NewBuilder("instructions").
+				WithText(registerInstructions).
+				Build(),
+		).
+		WithChild(stravaganza.NewBuilder("username").Build()).
+		WithChild(stravaganza.NewBuilder("password").Build())
+
+	if m.cfg.DataForm {
+		form := xep0004.DataForm{
+			Type:         xep0004.Form,
+			Instructions: registerInstructions,
+		}
+		form.Fields = append(form.Fields, xep0004.Field{
+			Type:   xep0004.Hidden,
+			Var:    xep0004.FormType,
+			Values: []string{registerNamespace},
+		})
+		form.Fields = append(form.Fields, xep0004.Field{
+			Type:     xep0004.TextSingle,
+			Var:      "username",
+			Label:    "Username",
+			Required: true,
+		})
+		form.Fields = append(form.Fields, xep0004.Field{
+			Type:     xep0004.TextPrivate,
+			Var:      "password",
+			Label:    "Password",
+			Required: true,
+		})
+		b.WithChild(form.Element())
+	}
+	return b.Build()
+}
+
+// credentials extracts requested username and password from a registration query,
+// either from a submitted data form or from legacy registration fields.
+func credentials(q stravaganza.Element) (username, password string, ok bool) {
+	if x := q.ChildNamespace("x", xep0004.FormNamespace); x != nil {
+		form, err := xep0004.NewFormFromElement(x)
+		if err != nil || form.Type != xep0004.Submit {
+			return "", "", false
+		}
+		if ft := fieldValue(form.Fields, xep0004.FormType); len(ft) > 0 && ft != registerNamespace {
+			return "", "", false
+		}
+		return fieldValue(form.Fields, "username"), fieldValue(form.Fields, "password"), true
+	}
+	if u := q.Child("username"); u != nil {
+		username = u.Text()
+	}
+	if p := q.Child("password"); p != nil {
+		password = p.Text()
+	}
+	return username, password, true
+}
+
+// fieldValue returns the first value of a submitted form field, whatever its declared type is.
+func fieldValue(fields xep0004.Fields, name string) string {
+	for _, f := range fields {
+		if f.Var == name && len(f.Values) > 0 {
+			return f.Values[0]
+		}
+	}
+	return ""
+}
+
+func policyErrorStanza(iq *stravaganza.IQ, err error) (stravaganza.Stanza, error) {
+	var polErr *PolicyError
+	if !errors.As(err, &polErr) {
+		return nil, err
+	}
+	se := stanzaerror.E(polErr.Reason, iq)
+	se.Text = polErr.Text
+	return se.Stanza(false)
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
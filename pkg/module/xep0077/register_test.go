@@ -0,0 +1,539 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0077
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/auth/pepper"
+	"github.com/ortuman/jackal/pkg/hook"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	usermodel "github.com/ortuman/jackal/pkg/model/user"
+	"github.com/ortuman/jackal/pkg/module"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestRegister_GetRegistrationFields(t *testing.T) {
+	// given
+	var cfg Config
+	cfg.DataForm = true
+
+	m := New(cfg, nil, nil, nil, testPeppers(), hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	iq := testRegisterIQ(stravaganza.GetType, "jackal.im", "jackal.im")
+	resp, err := m.ProcessPreAuthIQ(context.Background(), iq, module.PreAuthInfo{})
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, stravaganza.ResultType, resp.Attribute(stravaganza.Type))
+
+	q := resp.ChildNamespace("query", registerNamespace)
+	require.NotNil(t, q)
+	require.NotNil(t, q.Child("username"))
+	require.NotNil(t, q.Child("password"))
+
+	x := q.ChildNamespace("x", xep0004.FormNamespace)
+	require.NotNil(t, x)
+
+	form, err := xep0004.NewFormFromElement(x)
+	require.Nil(t, err)
+	require.Equal(t, xep0004.Form, form.Type)
+	require.Len(t, form.Fields, 3)
+}
+
+func TestRegister_RegisterUser(t *testing.T) {
+	var tcs = map[string]struct {
+		query         stravaganza.Element
+		userExists    bool
+		expectedType  string
+		expectedError string
+		created       bool
+	}{
+		"legacy fields": {
+			query:        testLegacyQuery("ortuman", "a1b2c3d4e5"),
+			expectedType: stravaganza.ResultType,
+			created:      true,
+		},
+		"data form": {
+			query:        testFormQuery("ortuman", "a1b2c3d4e5"),
+			expectedType: stravaganza.ResultType,
+			created:      true,
+		},
+		"missing password": {
+			query:         testLegacyQuery("ortuman", ""),
+			expectedType:  stravaganza.ErrorType,
+			expectedError: "not-acceptable",
+		},
+		"short password": {
+			query:         testLegacyQuery("ortuman", "1234"),
+			expectedType:  stravaganza.ErrorType,
+			expectedError: "not-acceptable",
+		},
+		"malformed username": {
+			query:         testLegacyQuery("ortu@man", "a1b2c3d4e5"),
+			expectedType:  stravaganza.ErrorType,
+			expectedError: "jid-malformed",
+		},
+		"existing user": {
+			query:         testLegacyQuery("ortuman", "a1b2c3d4e5"),
+			userExists:    true,
+			expectedType:  stravaganza.ErrorType,
+			expectedError: "conflict",
+		},
+	}
+	for tName, tc := range tcs {
+		t.Run(tName, func(t *testing.T) {
+			// given
+			var upsertedUsr *usermodel.User
+
+			repMock := testRepository()
+			repMock.UserExistsFunc = func(ctx context.Context, username string) (bool, error) {
+				return tc.userExists, nil
+			}
+			repMock.UpsertUserFunc = func(ctx context.Context, user *usermodel.User) error {
+				upsertedUsr = user
+				return nil
+			}
+			var createdUsername string
+
+			hk := hook.NewHooks()
+			hk.AddHook(hook.UserCreated, func(execCtx *hook.ExecutionContext) error {
+				createdUsername = execCtx.Info.(*hook.UserInfo).Username
+				return nil
+			}, hook.DefaultPriority)
+
+			var cfg Config
+			cfg.MinPasswordLength = 8
+			m := New(cfg, nil, nil, repMock, testPeppers(), hk, kitlog.NewNopLogger())
+
+			// when
+			iq := testRegisterIQ(stravaganza.SetType, "jackal.im", "jackal.im", tc.query)
+			resp, err := m.ProcessPreAuthIQ(context.Background(), iq, module.PreAuthInfo{})
+
+			// then
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedType, resp.Attribute(stravaganza.Type))
+			if len(tc.expectedError) > 0 {
+				require.NotNil(t, resp.Child("error").Child(tc.expectedError))
+			}
+			if tc.created {
+				require.NotNil(t, upsertedUsr)
+				require.Equal(t, "ortuman", upsertedUsr.Username)
+				require.NotNil(t, upsertedUsr.Scram)
+				require.Equal(t, "ortuman", createdUsername)
+			} else {
+				require.Nil(t, upsertedUsr)
+				require.Len(t, createdUsername, 0)
+			}
+		})
+	}
+}
+
+func TestRegister_RegisterRateLimited(t *testing.T) {
+	// given
+	repMock := testRepository()
+	repMock.UserExistsFunc = func(ctx context.Context, username string) (bool, error) {
+		return false, nil
+	}
+	repMock.UpsertUserFunc = func(ctx context.Context, user *usermodel.User) error {
+		return nil
+	}
+	var cfg Config
+	cfg.RateLimit.Count = 1
+	cfg.RateLimit.Interval = time.Hour
+
+	m := New(cfg, nil, nil, repMock, testPeppers(), hook.NewHooks(), kitlog.NewNopLogger())
+
+	addr := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 5222}
+
+	// when
+	iq0 := testRegisterIQ(stravaganza.SetType, "jackal.im", "jackal.im", testLegacyQuery("ortuman", "a1b2c3d4e5"))
+	resp0, err0 := m.ProcessPreAuthIQ(context.Background(), iq0, module.PreAuthInfo{RemoteAddr: addr})
+
+	iq1 := testRegisterIQ(stravaganza.SetType, "jackal.im", "jackal.im", testLegacyQuery("noelia", "a1b2c3d4e5"))
+	resp1, err1 := m.ProcessPreAuthIQ(context.Background(), iq1, module.PreAuthInfo{RemoteAddr: addr})
+
+	// then
+	require.Nil(t, err0)
+	require.Equal(t, stravaganza.ResultType, resp0.Attribute(stravaganza.Type))
+
+	require.Nil(t, err1)
+	require.Equal(t, stravaganza.ErrorType, resp1.Attribute(stravaganza.Type))
+	require.NotNil(t, resp1.Child("error").Child("resource-constraint"))
+
+	require.Len(t, repMock.UpsertUserCalls(), 1)
+}
+
+func TestRegister_CustomPolicy(t *testing.T) {
+	// given
+	repMock := testRepository()
+
+	m := New(Config{}, nil, nil, repMock, testPeppers(), hook.NewHooks(), kitlog.NewNopLogger())
+	m.AddPolicy(PolicyFunc(func(_ context.Context, req *Request) error {
+		return &PolicyError{Reason: stanzaerror.NotAllowed, Text: "reserved username"}
+	}))
+
+	// when
+	iq := testRegisterIQ(stravaganza.SetType, "jackal.im", "jackal.im", testLegacyQuery("admin", "a1b2c3d4e5"))
+	resp, err := m.ProcessPreAuthIQ(context.Background(), iq, module.PreAuthInfo{})
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, stravaganza.ErrorType, resp.Attribute(stravaganza.Type))
+	require.Equal(t, "reserved username", resp.Child("error").Child("text").Text())
+
+	require.Len(t, repMock.UserExistsCalls(), 0)
+}
+
+func TestRegister_InviteOnly(t *testing.T) {
+	// given
+	repMock := testRepository()
+
+	var cfg Config
+	cfg.InviteOnly = true
+
+	m := New(cfg, nil, nil, repMock, testPeppers(), hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	iq := testRegisterIQ(stravaganza.SetType, "jackal.im", "jackal.im", testLegacyQuery("ortuman", "a1b2c3d4e5"))
+	resp, err := m.ProcessPreAuthIQ(context.Background(), iq, module.PreAuthInfo{StreamID: 1})
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, stravaganza.ErrorType, resp.Attribute(stravaganza.Type))
+	require.NotNil(t, resp.Child("error").Child("not-allowed"))
+
+	require.Len(t, repMock.UserExistsCalls(), 0)
+}
+
+func TestRegister_RegisterWithInvite(t *testing.T) {
+	// given
+	inv := &invitemodel.Invite{
+		Token:     "t0k3n",
+		Domain:    "jackal.im",
+		Inviter:   "noelia",
+		MaxUses:   1,
+		ExpiresAt: timestamppb.New(time.Now().Add(time.Hour)),
+	}
+	repMock := testRepository()
+	repMock.FetchInviteFunc = func(ctx context.Context, token string) (*invitemodel.Invite, error) {
+		if token != inv.Token {
+			return nil, nil
+		}
+		return inv, nil
+	}
+	repMock.DeleteInviteFunc = func(ctx context.Context, token string) error {
+		return nil
+	}
+	repMock.UserExistsFunc = func(ctx context.Context, username string) (bool, error) {
+		return false, nil
+	}
+	repMock.UpsertUserFunc = func(ctx context.Context, user *usermodel.User) error {
+		return nil
+	}
+	var redeemInf *hook.InviteInfo
+
+	hk := hook.NewHooks()
+	hk.AddHook(hook.InviteRedeemed, func(execCtx *hook.ExecutionContext) error {
+		redeemInf = execCtx.Info.(*hook.InviteInfo)
+		return nil
+	}, hook.DefaultPriority)
+
+	var cfg Config
+	cfg.InviteOnly = true
+
+	m := New(cfg, nil, nil, repMock, testPeppers(), hk, kitlog.NewNopLogger())
+
+	preAuthInf := module.PreAuthInfo{StreamID: 1}
+
+	// when
+	badPreAuthResp, err0 := m.ProcessPreAuthIQ(context.Background(), testPreAuthIQ("an0th3r"), preAuthInf)
+	preAuthResp, err1 := m.ProcessPreAuthIQ(context.Background(), testPreAuthIQ("t0k3n"), preAuthInf)
+
+	iq := testRegisterIQ(stravaganza.SetType, "jackal.im", "jackal.im", testLegacyQuery("ortuman", "a1b2c3d4e5"))
+	resp, err2 := m.ProcessPreAuthIQ(context.Background(), iq, preAuthInf)
+
+	// then
+	require.Nil(t, err0)
+	require.Equal(t, stravaganza.ErrorType, badPreAuthResp.Attribute(stravaganza.Type))
+	require.NotNil(t, badPreAuthResp.Child("error").Child("item-not-found"))
+
+	require.Nil(t, err1)
+	require.Equal(t, stravaganza.ResultType, preAuthResp.Attribute(stravaganza.Type))
+
+	require.Nil(t, err2)
+	require.Equal(t, stravaganza.ResultType, resp.Attribute(stravaganza.Type))
+
+	require.Len(t, repMock.UpsertUserCalls(), 1)
+	require.Len(t, repMock.DeleteInviteCalls(), 1) // single-use invite got exhausted
+
+	require.Len(t, repMock.LockCalls(), 1)
+	require.Equal(t, "register:lock:ortuman", repMock.LockCalls()[0].LockID)
+	require.Len(t, repMock.InTransactionCalls(), 1) // invite redeemed along with user creation
+
+	require.NotNil(t, redeemInf)
+	require.Equal(t, "t0k3n", redeemInf.Token)
+	require.Equal(t, "noelia@jackal.im", redeemInf.Inviter.String())
+	require.Equal(t, "ortuman@jackal.im", redeemInf.Invitee.String())
+
+	m.mu.RLock()
+	require.Len(t, m.tokens, 0)
+	m.mu.RUnlock()
+}
+
+func TestRegister_ReleaseTokenOnTermination(t *testing.T) {
+	// given
+	repMock := testRepository()
+	repMock.FetchInviteFunc = func(ctx context.Context, token string) (*invitemodel.Invite, error) {
+		return &invitemodel.Invite{Token: token, Domain: "jackal.im", MaxUses: 1, ExpiresAt: timestamppb.New(time.Now().Add(time.Hour))}, nil
+	}
+	hk := hook.NewHooks()
+
+	m := New(Config{}, nil, nil, repMock, testPeppers(), hk, kitlog.NewNopLogger())
+	require.Nil(t, m.Start(context.Background()))
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	preAuthInf := module.PreAuthInfo{StreamID: 1}
+	_, _ = m.ProcessPreAuthIQ(context.Background(), testPreAuthIQ("t0k3n"), preAuthInf)
+
+	m.mu.RLock()
+	tokenCount := len(m.tokens)
+	m.mu.RUnlock()
+
+	// when
+	_, err := hk.Run(hook.C2SStreamTerminated, &hook.ExecutionContext{
+		Info:    &hook.C2SStreamInfo{ID: preAuthInf.StreamID.String()},
+		Context: context.Background(),
+	})
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 1, tokenCount)
+
+	m.mu.RLock()
+	require.Len(t, m.tokens, 0)
+	m.mu.RUnlock()
+}
+
+func TestRegister_ChangePassword(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	repMock := testRepository()
+	repMock.UpsertUserFunc = func(ctx context.Context, user *usermodel.User) error {
+		return nil
+	}
+	repMock.DeleteFastTokensFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+	m := New(Config{}, routerMock, nil, repMock, testPeppers(), hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	iq0 := testRegisterIQ(stravaganza.SetType, "ortuman@jackal.im/balcony", "jackal.im", testLegacyQuery("ortuman", "n3wp4ssw0rd"))
+	_ = m.ProcessIQ(context.Background(), iq0)
+
+	iq1 := testRegisterIQ(stravaganza.SetType, "ortuman@jackal.im/balcony", "jackal.im", testLegacyQuery("noelia", "n3wp4ssw0rd"))
+	_ = m.ProcessIQ(context.Background(), iq1)
+
+	// then
+	require.Len(t, respStanzas, 2)
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Attribute(stravaganza.Type))
+	require.Equal(t, stravaganza.ErrorType, respStanzas[1].Attribute(stravaganza.Type))
+	require.NotNil(t, respStanzas[1].Child("error").Child("not-allowed"))
+
+	require.Len(t, repMock.UpsertUserCalls(), 1)
+	require.Equal(t, "ortuman", repMock.UpsertUserCalls()[0].User.Username)
+	require.Len(t, repMock.DeleteFastTokensCalls(), 1)
+}
+
+func TestRegister_CancelRegistration(t *testing.T) {
+	// given
+	c2sRouterMock := &c2sRouterMock{}
+
+	var respStanzas []stravaganza.Stanza
+	c2sRouterMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza, routingOpts router.RoutingOptions) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	var disconnected []string
+	c2sRouterMock.DisconnectFunc = func(ctx context.Context, res c2smodel.ResourceDesc, streamErr *streamerror.Error) error {
+		disconnected = append(disconnected, res.JID().Resource())
+		return nil
+	}
+	routerMock := &routerMock{}
+	routerMock.C2SFunc = func() router.C2SRouter { return c2sRouterMock }
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+		jd0, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+		jd1, _ := jid.NewWithString("ortuman@jackal.im/chamber", true)
+		return []c2smodel.ResourceDesc{
+			c2smodel.NewResourceDesc("inst-1", jd0, nil, nil),
+			c2smodel.NewResourceDesc("inst-1", jd1, nil, nil),
+		}, nil
+	}
+	repMock := testRepository()
+	repMock.DeleteUserFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+	repMock.DeleteFastTokensFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+	var deletedUsername string
+
+	hk := hook.NewHooks()
+	hk.AddHook(hook.UserDeleted, func(execCtx *hook.ExecutionContext) error {
+		deletedUsername = execCtx.Info.(*hook.UserInfo).Username
+		return nil
+	}, hook.DefaultPriority)
+
+	m := New(Config{}, routerMock, resMngMock, repMock, testPeppers(), hk, kitlog.NewNopLogger())
+
+	// when
+	iq := testRegisterIQ(stravaganza.SetType, "ortuman@jackal.im/balcony", "ortuman@jackal.im",
+		stravaganza.NewBuilder("query").
+			WithAttribute(stravaganza.Namespace, registerNamespace).
+			WithChild(stravaganza.NewBuilder("remove").Build()).
+			Build(),
+	)
+	err := m.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Nil(t, err)
+
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Attribute(stravaganza.Type))
+
+	require.Equal(t, "ortuman", deletedUsername)
+	require.Equal(t, []string{"balcony", "chamber"}, disconnected)
+
+	require.Len(t, repMock.DeleteUserCalls(), 1)
+	require.Len(t, repMock.DeleteFastTokensCalls(), 1)
+}
+
+func TestRegister_CancelRegistrationUnauthenticated(t *testing.T) {
+	// given
+	repMock := testRepository()
+	m := New(Config{}, nil, nil, repMock, testPeppers(), hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	iq := testRegisterIQ(stravaganza.SetType, "jackal.im", "jackal.im",
+		stravaganza.NewBuilder("query").
+			WithAttribute(stravaganza.Namespace, registerNamespace).
+			WithChild(stravaganza.NewBuilder("remove").Build()).
+			Build(),
+	)
+	resp, err := m.ProcessPreAuthIQ(context.Background(), iq, module.PreAuthInfo{})
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, stravaganza.ErrorType, resp.Attribute(stravaganza.Type))
+	require.NotNil(t, resp.Child("error").Child("not-authorized"))
+
+	require.Len(t, repMock.DeleteUserCalls(), 0)
+}
+
+func testRepository() *repositoryMock {
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, repMock)
+	}
+	return repMock
+}
+
+func testRegisterIQ(typ, from, to string, query ...stravaganza.Element) *stravaganza.IQ {
+	q := stravaganza.NewBuilder("query").
+		WithAttribute(stravaganza.Namespace, registerNamespace).
+		Build()
+	if len(query) > 0 {
+		q = query[0]
+	}
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "reg1").
+		WithAttribute(stravaganza.Type, typ).
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, to).
+		WithChild(q).
+		BuildIQ()
+	return iq
+}
+
+func testPreAuthIQ(token string) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "pa1").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithAttribute(stravaganza.From, "jackal.im").
+		WithAttribute(stravaganza.To, "jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("preauth").
+				WithAttribute(stravaganza.Namespace, parsNamespace).
+				WithAttribute("token", token).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
+
+func testLegacyQuery(username, password string) stravaganza.Element {
+	return stravaganza.NewBuilder("query").
+		WithAttribute(stravaganza.Namespace, registerNamespace).
+		WithChild(stravaganza.NewBuilder("username").WithText(username).Build()).
+		WithChild(stravaganza.NewBuilder("password").WithText(password).Build()).
+		Build()
+}
+
+func testFormQuery(username, password string) stravaganza.Element {
+	form := xep0004.DataForm{
+		Type: xep0004.Submit,
+		Fields: xep0004.Fields{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{registerNamespace}},
+			{Var: "username", Values: []string{username}},
+			{Var: "password", Values: []string{password}},
+		},
+	}
+	return stravaganza.NewBuilder("query").
+		WithAttribute(stravaganza.Namespace, registerNamespace).
+		WithChild(form.Element()).
+		Build()
+}
+
+func testPeppers() *pepper.Keys {
+	ks, _ := pepper.NewKeys(pepper.Config{
+		Keys:  map[string]string{"v1": "a-super-secret-pepper-key"},
+		UseID: "v1",
+	})
+	return ks
+}
@@ -0,0 +1,244 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0163
+
+import (
+	"context"
+	"sync"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/host"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/module/xep0060"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+const (
+	// ModuleName represents pep module name.
+	ModuleName = "pep"
+
+	// XEPNumber represents pep XEP number.
+	XEPNumber = "0163"
+)
+
+// Pep represents a personal eventing protocol (XEP-0163) module type.
+//
+// Every local account acts as a virtual publish-subscribe service whose node access is
+// derived from the account roster. Notifications are filtered by the '+notify' features
+// advertised through entity capabilities, thus only local contacts whose capabilities have
+// already been cached will be implicitly notified.
+type Pep struct {
+	svc    *xep0060.Service
+	rep    repository.Repository
+	resMng resourcemanager.Manager
+	hosts  hosts
+	hk     *hook.Hooks
+	logger kitlog.Logger
+
+	mu        sync.Mutex
+	available map[string]struct{}
+}
+
+// New returns a new initialized Pep instance.
+func New(
+	router router.Router,
+	hosts *host.Hosts,
+	resMng resourcemanager.Manager,
+	rep repository.Repository,
+	hk *hook.Hooks,
+	logger kitlog.Logger,
+) *Pep {
+	logger = kitlog.With(logger, "module", ModuleName, "xep", XEPNumber)
+	p := &policy{
+		rep:    rep,
+		resMng: resMng,
+		hosts:  hosts,
+	}
+	return &Pep{
+		svc:       xep0060.NewService(p, router, hosts, rep, logger),
+		rep:       rep,
+		resMng:    resMng,
+		hosts:     hosts,
+		hk:        hk,
+		logger:    logger,
+		available: make(map[string]struct{}),
+	}
+}
+
+// Name returns pep module name.
+func (m *Pep) Name() string { return ModuleName }
+
+// StreamFeature returns pep module stream feature.
+func (m *Pep) StreamFeature(_ context.Context, _ string) (stravaganza.Element, error) {
+	return nil, nil
+}
+
+// ServerFeatures returns pep server disco features.
+func (m *Pep) ServerFeatures(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// AccountFeatures returns pep account disco features.
+func (m *Pep) AccountFeatures(_ context.Context) ([]string, error) {
+	features := append([]string{}, xep0060.Features...)
+	features = append(features,
+		xep0060.Namespace+"#auto-create",
+		xep0060.Namespace+"#auto-subscribe",
+		xep0060.Namespace+"#filtered-notifications",
+	)
+	return features, nil
+}
+
+// MatchesNamespace tells whether namespace matches pep module.
+func (m *Pep) MatchesNamespace(namespace string, serverTarget bool) bool {
+	if serverTarget {
+		return false
+	}
+	return namespace == xep0060.Namespace || namespace == xep0060.OwnerNamespace
+}
+
+// ProcessIQ process a pep iq.
+func (m *Pep) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	return m.svc.ProcessIQ(ctx, iq.ToJID().ToBareJID().String(), iq)
+}
+
+// Start starts pep module.
+func (m *Pep) Start(_ context.Context) error {
+	m.hk.AddHook(hook.C2SStreamPresenceReceived, m.onPresenceRecv, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamDisconnected, m.onDisconnect, hook.DefaultPriority)
+	m.hk.AddHook(hook.UserDeleted, m.onUserDeleted, hook.DefaultPriority)
+
+	level.Info(m.logger).Log("msg", "started pep module")
+	return nil
+}
+
+// Stop stops pep module.
+func (m *Pep) Stop(_ context.Context) error {
+	m.hk.RemoveHook(hook.C2SStreamPresenceReceived, m.onPresenceRecv)
+	m.hk.RemoveHook(hook.C2SStreamDisconnected, m.onDisconnect)
+	m.hk.RemoveHook(hook.UserDeleted, m.onUserDeleted)
+
+	level.Info(m.logger).Log("msg", "stopped pep module")
+	return nil
+}
+
+func (m *Pep) onPresenceRecv(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+
+	pr, ok := inf.Element.(*stravaganza.Presence)
+	if !ok {
+		return nil
+	}
+	fromJID := pr.FromJID()
+	toJID := pr.ToJID()
+	if !toJID.IsBare() || !toJID.MatchesWithOptions(fromJID, jid.MatchesBare) {
+		return nil // not a broadcast presence
+	}
+	if !pr.IsAvailable() {
+		m.mu.Lock()
+		delete(m.available, fromJID.String())
+		m.mu.Unlock()
+		return nil
+	}
+	// last published items are only sent on initial presence
+	m.mu.Lock()
+	_, wasAvailable := m.available[fromJID.String()]
+	m.available[fromJID.String()] = struct{}{}
+	m.mu.Unlock()
+
+	if wasAvailable {
+		return nil
+	}
+	return m.sendLastItems(execCtx.Context, pr)
+}
+
+func (m *Pep) onDisconnect(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+	if inf.JID == nil {
+		return nil
+	}
+	m.mu.Lock()
+	delete(m.available, inf.JID.String())
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Pep) onUserDeleted(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.UserInfo)
+	for _, h := range m.hosts.HostNames() {
+		accountJID, err := jid.New(inf.Username, h, "", true)
+		if err != nil {
+			return err
+		}
+		if err := m.rep.DeleteNodes(execCtx.Context, accountJID.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendLastItems sends to the presence sender the last items published to the nodes it's interested in.
+func (m *Pep) sendLastItems(ctx context.Context, pr *stravaganza.Presence) error {
+	features, err := capsFeatures(ctx, m.rep, pr)
+	if err != nil {
+		return err
+	}
+	if len(features) == 0 {
+		return nil
+	}
+	fromJID := pr.FromJID()
+
+	// own account nodes and those of local contacts the sender is subscribed to
+	accounts := []string{fromJID.ToBareJID().String()}
+
+	ris, err := m.rep.FetchRosterItems(ctx, fromJID.Node())
+	if err != nil {
+		return err
+	}
+	for _, ri := range ris {
+		if ri.Subscription != rostermodel.To && ri.Subscription != rostermodel.Both {
+			continue
+		}
+		contactJID, err := jid.NewWithString(ri.Jid, true)
+		if err != nil || !m.hosts.IsLocalHost(contactJID.Domain()) {
+			continue
+		}
+		accounts = append(accounts, contactJID.String())
+	}
+	for _, account := range accounts {
+		nodes, err := m.rep.FetchNodes(ctx, account)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if node.Options.SendLastPublishedItem != xep0060.SendLastOnSubAndPresence {
+				continue
+			}
+			if _, ok := features[node.Name+notifySuffix]; !ok {
+				continue
+			}
+			if err := m.svc.SendLastItem(ctx, node, fromJID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
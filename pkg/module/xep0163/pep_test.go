@@ -0,0 +1,320 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0163
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	capsmodel "github.com/ortuman/jackal/pkg/model/caps"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/module/xep0060"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const capsNamespace = "http://jabber.org/protocol/caps"
+
+type testRouter struct {
+	mu      sync.Mutex
+	stanzas []stravaganza.Stanza
+}
+
+func (r *testRouter) route(_ context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stanzas = append(r.stanzas, stanza)
+	return nil, nil
+}
+
+func (r *testRouter) flush() []stravaganza.Stanza {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := r.stanzas
+	r.stanzas = nil
+	return ret
+}
+
+func TestPep_PublishNotifiesInterestedContacts(t *testing.T) {
+	// given
+	m, rtr, repMock, resMngMock := testPep()
+
+	nodes := make(map[string]*pubsubmodel.Node)
+	repMock.FetchNodeFunc = func(ctx context.Context, host, name string) (*pubsubmodel.Node, error) {
+		return nodes[host+"/"+name], nil
+	}
+	repMock.UpsertNodeFunc = func(ctx context.Context, node *pubsubmodel.Node) error {
+		nodes[node.Host+"/"+node.Name] = node
+		return nil
+	}
+	repMock.UpsertNodeItemFunc = func(ctx context.Context, item *pubsubmodel.Item) error {
+		return nil
+	}
+	repMock.DeleteOldestNodeItemsFunc = func(ctx context.Context, host, name string, maxItems int) error {
+		return nil
+	}
+	repMock.FetchNodeSubscriptionsFunc = func(ctx context.Context, host, name string) ([]*pubsubmodel.Subscription, error) {
+		return nil, nil
+	}
+	repMock.FetchRosterItemsFunc = func(ctx context.Context, username string) ([]*rostermodel.Item, error) {
+		return []*rostermodel.Item{
+			{Username: "ortuman", Jid: "noelia@jackal.im", Subscription: rostermodel.Both},
+			{Username: "ortuman", Jid: "romeo@jackal.im", Subscription: rostermodel.To},
+			{Username: "ortuman", Jid: "juliet@capulet.lit", Subscription: rostermodel.Both},
+		}, nil
+	}
+	repMock.FetchRosterItemFunc = func(ctx context.Context, username, jid string) (*rostermodel.Item, error) {
+		switch jid {
+		case "noelia@jackal.im":
+			return &rostermodel.Item{Username: "ortuman", Jid: jid, Subscription: rostermodel.Both}, nil
+		case "romeo@jackal.im":
+			return &rostermodel.Item{Username: "ortuman", Jid: jid, Subscription: rostermodel.To}, nil
+		}
+		return nil, nil
+	}
+	resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+		switch username {
+		case "ortuman":
+			return []c2smodel.ResourceDesc{
+				testResource("ortuman@jackal.im/yard", "tune-aware"),
+			}, nil
+		case "noelia":
+			return []c2smodel.ResourceDesc{
+				testResource("noelia@jackal.im/balcony", "tune-aware"),
+				testResource("noelia@jackal.im/chamber", "tune-unaware"),
+			}, nil
+		case "romeo":
+			return []c2smodel.ResourceDesc{
+				testResource("romeo@jackal.im/garden", "tune-aware"),
+			}, nil
+		}
+		return nil, nil
+	}
+
+	// when
+	_ = m.ProcessIQ(context.Background(), testPublishIQ("ortuman@jackal.im/yard", "ortuman@jackal.im"))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 3)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+
+	var targets []string
+	for _, stanza := range stanzas[1:] {
+		require.Equal(t, "ortuman@jackal.im", stanza.FromJID().String())
+		require.NotNil(t, stanza.ChildNamespace("event", xep0060.EventNamespace))
+		targets = append(targets, stanza.ToJID().String())
+	}
+	require.ElementsMatch(t, []string{"ortuman@jackal.im/yard", "noelia@jackal.im/balcony"}, targets)
+
+	node := nodes["ortuman@jackal.im/urn:xmpp:tune"]
+	require.NotNil(t, node)
+	require.Equal(t, xep0060.PresenceAccessModel, node.Options.AccessModel)
+}
+
+func TestPep_PublishToForeignAccount(t *testing.T) {
+	// given
+	m, rtr, repMock, _ := testPep()
+	repMock.FetchNodeFunc = func(ctx context.Context, host, name string) (*pubsubmodel.Node, error) {
+		return nil, nil
+	}
+
+	// when
+	_ = m.ProcessIQ(context.Background(), testPublishIQ("noelia@jackal.im/balcony", "ortuman@jackal.im"))
+
+	// then
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+	require.NotNil(t, stanzas[0].Child("error").Child("item-not-found"))
+}
+
+func TestPep_SendLastItemsOnInitialPresence(t *testing.T) {
+	// given
+	m, rtr, repMock, _ := testPep()
+
+	repMock.FetchRosterItemsFunc = func(ctx context.Context, username string) ([]*rostermodel.Item, error) {
+		return []*rostermodel.Item{
+			{Username: "noelia", Jid: "ortuman@jackal.im", Subscription: rostermodel.Both},
+		}, nil
+	}
+	repMock.FetchRosterItemFunc = func(ctx context.Context, username, jid string) (*rostermodel.Item, error) {
+		return &rostermodel.Item{Username: "ortuman", Jid: jid, Subscription: rostermodel.Both}, nil
+	}
+	repMock.FetchNodesFunc = func(ctx context.Context, host string) ([]*pubsubmodel.Node, error) {
+		if host != "ortuman@jackal.im" {
+			return nil, nil
+		}
+		return []*pubsubmodel.Node{
+			{
+				Host:         "ortuman@jackal.im",
+				Name:         "urn:xmpp:tune",
+				Options:      (&policy{}).DefaultNodeOptions(),
+				Affiliations: []*pubsubmodel.Affiliation{{Jid: "ortuman@jackal.im", Affiliation: "owner"}},
+			},
+		}, nil
+	}
+	repMock.FetchNodeItemsFunc = func(ctx context.Context, host, name string) ([]*pubsubmodel.Item, error) {
+		return []*pubsubmodel.Item{
+			{
+				Host:    host,
+				Node:    name,
+				Id:      "i1",
+				Payload: stravaganza.NewBuilder("tune").Build().Proto(),
+				Stamp:   timestamppb.Now(),
+			},
+		}, nil
+	}
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	pr := testPresence("noelia@jackal.im/balcony", "tune-aware")
+
+	// when
+	_, _ = m.hk.Run(hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+		Info:    &hook.C2SStreamInfo{Element: pr},
+		Context: context.Background(),
+	})
+	initial := rtr.flush()
+
+	_, _ = m.hk.Run(hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+		Info:    &hook.C2SStreamInfo{Element: pr},
+		Context: context.Background(),
+	})
+	subsequent := rtr.flush()
+
+	// then
+	require.Len(t, initial, 1)
+	require.Equal(t, "ortuman@jackal.im", initial[0].FromJID().String())
+	require.Equal(t, "noelia@jackal.im/balcony", initial[0].ToJID().String())
+
+	items := initial[0].ChildNamespace("event", xep0060.EventNamespace).Child("items")
+	require.Equal(t, "urn:xmpp:tune", items.Attribute("node"))
+	require.Equal(t, "i1", items.Child("item").Attribute("id"))
+	require.NotNil(t, initial[0].ChildNamespace("delay", "urn:xmpp:delay"))
+
+	require.Len(t, subsequent, 0)
+}
+
+func TestPep_UserDeleted(t *testing.T) {
+	// given
+	m, _, repMock, _ := testPep()
+
+	var deleted []string
+	repMock.DeleteNodesFunc = func(ctx context.Context, host string) error {
+		deleted = append(deleted, host)
+		return nil
+	}
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	// when
+	_, _ = m.hk.Run(hook.UserDeleted, &hook.ExecutionContext{
+		Info:    &hook.UserInfo{Username: "ortuman"},
+		Context: context.Background(),
+	})
+
+	// then
+	require.Equal(t, []string{"ortuman@jackal.im", "ortuman@jabber.org"}, deleted)
+}
+
+func testPep() (*Pep, *testRouter, *repositoryMock, *resourceManagerMock) {
+	rtr := &testRouter{}
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = rtr.route
+
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" || h == "jabber.org" }
+	hostsMock.HostNamesFunc = func() []string { return []string{"jackal.im", "jabber.org"} }
+
+	repMock := &repositoryMock{}
+	repMock.FetchCapabilitiesFunc = func(ctx context.Context, node, ver string) (*capsmodel.Capabilities, error) {
+		switch ver {
+		case "tune-aware":
+			return &capsmodel.Capabilities{Node: node, Ver: ver, Features: []string{"urn:xmpp:tune+notify"}}, nil
+		case "tune-unaware":
+			return &capsmodel.Capabilities{Node: node, Ver: ver}, nil
+		}
+		return nil, nil
+	}
+	resMngMock := &resourceManagerMock{}
+
+	logger := kitlog.NewNopLogger()
+	m := &Pep{
+		rep:       repMock,
+		resMng:    resMngMock,
+		hosts:     hostsMock,
+		hk:        hook.NewHooks(),
+		logger:    logger,
+		available: make(map[string]struct{}),
+	}
+	p := &policy{rep: repMock, resMng: resMngMock, hosts: hostsMock}
+	m.svc = xep0060.NewService(p, routerMock, hostsMock, repMock, logger)
+	return m, rtr, repMock, resMngMock
+}
+
+func testPublishIQ(from, to string) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "publish-1").
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, to).
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("pubsub").
+				WithAttribute(stravaganza.Namespace, xep0060.Namespace).
+				WithChild(
+					stravaganza.NewBuilder("publish").
+						WithAttribute("node", "urn:xmpp:tune").
+						WithChild(
+							stravaganza.NewBuilder("item").
+								WithChild(stravaganza.NewBuilder("tune").Build()).
+								Build(),
+						).
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
+
+func testPresence(from, capsVer string) *stravaganza.Presence {
+	fromJID, _ := jid.NewWithString(from, true)
+	pr, _ := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, fromJID.String()).
+		WithAttribute(stravaganza.To, fromJID.ToBareJID().String()).
+		WithChild(
+			stravaganza.NewBuilder("c").
+				WithAttribute(stravaganza.Namespace, capsNamespace).
+				WithAttribute("hash", "sha-1").
+				WithAttribute("node", "https://conversations.im").
+				WithAttribute("ver", capsVer).
+				Build(),
+		).
+		BuildPresence()
+	return pr
+}
+
+func testResource(jidStr, capsVer string) c2smodel.ResourceDesc {
+	jd, _ := jid.NewWithString(jidStr, true)
+	return c2smodel.NewResourceDesc("i1", jd, testPresence(jidStr, capsVer), c2smodel.NewInfoMap())
+}
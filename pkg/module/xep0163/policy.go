@@ -0,0 +1,121 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0163
+
+import (
+	"context"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/module/xep0060"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+const notifySuffix = "+notify"
+
+// policy defines personal eventing publish-subscribe service behavior.
+type policy struct {
+	rep    repository.Repository
+	resMng resourcemanager.Manager
+	hosts  hosts
+}
+
+// CanCreateNode satisfies xep0060.Policy interface.
+func (p *policy) CanCreateNode(_ context.Context, host string, jd *jid.JID) bool {
+	return jd.ToBareJID().String() == host
+}
+
+// AutoCreate satisfies xep0060.Policy interface.
+func (p *policy) AutoCreate() bool { return true }
+
+// DefaultNodeOptions satisfies xep0060.Policy interface.
+func (p *policy) DefaultNodeOptions() *pubsubmodel.Options {
+	return &pubsubmodel.Options{
+		AccessModel:           xep0060.PresenceAccessModel,
+		PublishModel:          xep0060.PublishersPublishModel,
+		MaxItems:              1,
+		PersistItems:          true,
+		DeliverPayloads:       true,
+		NotifyRetract:         true,
+		NotifyDelete:          true,
+		SendLastPublishedItem: xep0060.SendLastOnSubAndPresence,
+	}
+}
+
+// ImplicitSubscribers satisfies xep0060.Policy interface.
+// Available resources of the account and its local contacts are implicitly subscribed
+// whenever their entity capabilities include node '+notify' feature.
+func (p *policy) ImplicitSubscribers(ctx context.Context, node *pubsubmodel.Node) ([]*jid.JID, error) {
+	accountJID, err := jid.NewWithString(node.Host, true)
+	if err != nil {
+		return nil, err
+	}
+	usernames := []string{accountJID.Node()}
+
+	ris, err := p.rep.FetchRosterItems(ctx, accountJID.Node())
+	if err != nil {
+		return nil, err
+	}
+	for _, ri := range ris {
+		if ri.Subscription != rostermodel.From && ri.Subscription != rostermodel.Both {
+			continue
+		}
+		contactJID, err := jid.NewWithString(ri.Jid, true)
+		if err != nil || !p.hosts.IsLocalHost(contactJID.Domain()) {
+			continue
+		}
+		usernames = append(usernames, contactJID.Node())
+	}
+	var retVal []*jid.JID
+	for _, username := range usernames {
+		rss, err := p.resMng.GetResources(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range rss {
+			if !res.IsAvailable() {
+				continue
+			}
+			features, err := capsFeatures(ctx, p.rep, res.Presence())
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := features[node.Name+notifySuffix]; ok {
+				retVal = append(retVal, res.JID())
+			}
+		}
+	}
+	return retVal, nil
+}
+
+// capsFeatures returns the set of cached entity capabilities features advertised in pr.
+func capsFeatures(ctx context.Context, rep repository.Capabilities, pr *stravaganza.Presence) (map[string]struct{}, error) {
+	caps := pr.Capabilities()
+	if caps == nil {
+		return nil, nil
+	}
+	ci, err := rep.FetchCapabilities(ctx, caps.Node, caps.Ver)
+	if err != nil || ci == nil {
+		return nil, err
+	}
+	features := make(map[string]struct{}, len(ci.Features))
+	for _, f := range ci.Features {
+		features[f] = struct{}{}
+	}
+	return features, nil
+}
@@ -145,6 +145,43 @@ func (m *Stream) AccountFeatures(_ context.Context) ([]string, error) {
 	return nil, nil
 }
 
+// SASL2InlineFeature returns stream module SASL2 inline feature.
+func (m *Stream) SASL2InlineFeature() stravaganza.Element {
+	return stravaganza.NewBuilder("sm").
+		WithAttribute(stravaganza.Namespace, streamNamespace).
+		Build()
+}
+
+// ProcessSASL2Inline processes a stream resumption request included into a SASL2 authentication.
+func (m *Stream) ProcessSASL2Inline(ctx context.Context, elem stravaganza.Element, stm stream.C2S) (stravaganza.Element, error) {
+	if elem.Name() != "resume" {
+		return failedElement(badRequest, "Malformed element"), nil
+	}
+	reply, sq, err := m.resume(ctx, stm, elem.Attribute("previd"))
+	if err != nil {
+		return nil, err
+	}
+	if sq != nil {
+		// pending stanzas will be sent right after SASL2 success response
+		h, _ := strconv.ParseUint(elem.Attribute("h"), 10, 32)
+		m.sendUnacknowledged(sq, uint32(h))
+	}
+	return reply, nil
+}
+
+// BindInlineFeature returns stream module Bind 2 inline feature namespace.
+func (m *Stream) BindInlineFeature() string {
+	return streamNamespace
+}
+
+// ProcessBindInline enables stream management as part of a Bind 2 request.
+func (m *Stream) ProcessBindInline(ctx context.Context, elem stravaganza.Element, stm stream.C2S) (stravaganza.Element, error) {
+	if elem.Name() != "enable" {
+		return failedElement(badRequest, "Malformed element"), nil
+	}
+	return m.enable(ctx, stm)
+}
+
 // Start starts stream module.
 func (m *Stream) Start(_ context.Context) error {
 	m.hk.AddHook(hook.C2SStreamElementReceived, m.onElementRecv, hook.DefaultPriority)
@@ -254,6 +291,18 @@ func (m *Stream) onDisconnect(execCtx *hook.ExecutionContext) error {
 	level.Info(m.logger).Log("msg", "scheduled stream termination",
 		"id", stm.ID(), "username", stm.Username(), "resource", stm.Resource(),
 	)
+	_, err := m.hk.Run(hook.C2SStreamHibernated, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			ID:       inf.ID,
+			JID:      stm.JID(),
+			Presence: inf.Presence,
+		},
+		Sender:  stm,
+		Context: execCtx.Context,
+	})
+	if err != nil {
+		return err
+	}
 	return hook.ErrStopped
 }
 
@@ -308,16 +357,23 @@ func (m *Stream) processCmd(ctx context.Context, cmd stravaganza.Element, stm st
 }
 
 func (m *Stream) handleEnable(ctx context.Context, stm stream.C2S) error {
+	reply, err := m.enable(ctx, stm)
+	if err != nil {
+		return err
+	}
+	stm.SendElement(reply)
+	return nil
+}
+
+func (m *Stream) enable(ctx context.Context, stm stream.C2S) (stravaganza.Element, error) {
 	if !stm.IsBinded() {
-		sendFailedReply(unexpectedRequest, "", stm)
-		return nil
+		return failedElement(unexpectedRequest, ""), nil
 	}
 	if stm.Info().Bool(enabledInfoKey) {
-		sendFailedReply(unexpectedRequest, "Stream management is already enabled", stm)
-		return nil
+		return failedElement(unexpectedRequest, "Stream management is already enabled"), nil
 	}
 	if err := stm.SetInfoValue(ctx, enabledInfoKey, true); err != nil {
-		return err
+		return nil, err
 	}
 	// generate nonce
 	nonce := make([]byte, nonceLength)
@@ -338,36 +394,44 @@ func (m *Stream) handleEnable(ctx context.Context, stm stream.C2S) error {
 
 	smID := encodeSMID(stm.JID(), nonce)
 
-	stm.SendElement(stravaganza.NewBuilder("enabled").
-		WithAttribute(stravaganza.Namespace, streamNamespace).
-		WithAttribute("id", smID).
-		WithAttribute("resume", "true").
-		Build(),
-	)
 	level.Info(m.logger).Log("msg", "enabled stream management",
 		"smID", smID, "id", stm.ID(), "username", stm.Username(), "resource", stm.Resource(),
 	)
-	return nil
+	return stravaganza.NewBuilder("enabled").
+		WithAttribute(stravaganza.Namespace, streamNamespace).
+		WithAttribute("id", smID).
+		WithAttribute("resume", "true").
+		Build(), nil
 }
 
 func (m *Stream) handleResume(ctx context.Context, stm stream.C2S, h uint32, prevSMID string) error {
+	reply, sq, err := m.resume(ctx, stm, prevSMID)
+	if err != nil {
+		return err
+	}
+	stm.SendElement(reply)
+	if sq != nil {
+		m.sendUnacknowledged(sq, h)
+	}
+	return nil
+}
+
+func (m *Stream) resume(ctx context.Context, stm stream.C2S, prevSMID string) (stravaganza.Element, *streamqueue.Queue, error) {
 	if !stm.IsAuthenticated() {
-		sendFailedReply(unexpectedRequest, "", stm)
-		return nil
+		return failedElement(unexpectedRequest, ""), nil, nil
 	}
 	// perform stream resumption
 	jd, nonce, err := decodeSMID(prevSMID)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	// fetch resource info
 	res, err := m.resMng.GetResource(ctx, jd.Node(), jd.Resource())
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	if res == nil {
-		sendFailedReply(itemNotFound, "", stm)
-		return nil
+		return failedElement(itemNotFound, ""), nil, nil
 	}
 	var sq *streamqueue.Queue
 
@@ -376,12 +440,11 @@ func (m *Stream) handleResume(ctx context.Context, stm stream.C2S, h uint32, pre
 	if res.InstanceID() == instance.ID() { // local retained queue
 		sq = m.stmQueueMap.Get(qk)
 		if sq == nil {
-			sendFailedReply(itemNotFound, "", stm)
-			return nil
+			return failedElement(itemNotFound, ""), nil, nil
 		}
 		// disconnect hibernated c2s stream
 		if err := <-sq.GetStream().Disconnect(streamerror.E(streamerror.Conflict)); err != nil {
-			return err
+			return nil, nil, err
 		}
 		// set new stream
 		sq.SetStream(stm)
@@ -389,11 +452,11 @@ func (m *Stream) handleResume(ctx context.Context, stm stream.C2S, h uint32, pre
 	} else { // transfer retained queue from internal cluster instance
 		conn, err := m.clusterConnMng.GetConnection(res.InstanceID())
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		resp, err := conn.StreamManagement().TransferQueue(ctx, qk)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		sq = streamqueue.New(
 			stm,
@@ -412,31 +475,30 @@ func (m *Stream) handleResume(ctx context.Context, stm stream.C2S, h uint32, pre
 
 	// invalid smID?
 	if !jd.MatchesWithOptions(stm.JID(), jid.MatchesBare) || bytes.Compare(sq.Nonce(), nonce) != 0 {
-		sendFailedReply(itemNotFound, "", stm)
-		return nil
+		return failedElement(itemNotFound, ""), nil, nil
 	}
 
 	// register retained queue
 	m.stmQueueMap.Set(qk, sq)
 
-	// resume stream and send unacknowledged stanzas
+	// resume stream
 	if err := stm.Resume(ctx, res.JID(), res.Presence(), res.Info()); err != nil {
-		return err
+		return nil, nil, err
 	}
-	stm.SendElement(stravaganza.NewBuilder("resumed").
+	level.Info(m.logger).Log("msg", "resumed stream",
+		"smID", prevSMID, "id", stm.ID(), "username", stm.Username(), "resource", stm.Resource(),
+	)
+	return stravaganza.NewBuilder("resumed").
 		WithAttribute(stravaganza.Namespace, streamNamespace).
 		WithAttribute("h", strconv.FormatUint(uint64(sq.InboundH()), 10)).
 		WithAttribute("previd", prevSMID).
-		Build(),
-	)
+		Build(), sq, nil
+}
+
+func (m *Stream) sendUnacknowledged(sq *streamqueue.Queue, h uint32) {
 	sq.Acknowledge(h)
 	sq.SendPending()
 	sq.ScheduleR()
-
-	level.Info(m.logger).Log("msg", "resumed stream",
-		"smID", prevSMID, "id", stm.ID(), "username", stm.Username(), "resource", stm.Resource(),
-	)
-	return nil
 }
 
 func (m *Stream) handleA(stm stream.C2S, h uint32) {
@@ -474,6 +536,10 @@ func (m *Stream) handleR(stm stream.C2S) {
 }
 
 func sendFailedReply(reason string, text string, stm stream.C2S) {
+	_ = stm.SendElement(failedElement(reason, text))
+}
+
+func failedElement(reason string, text string) stravaganza.Element {
 	sb := stravaganza.NewBuilder("failed").
 		WithAttribute(stravaganza.Namespace, streamNamespace).
 		WithChild(
@@ -489,7 +555,7 @@ func sendFailedReply(reason string, text string, stm stream.C2S) {
 				Build(),
 		)
 	}
-	_ = stm.SendElement(sb.Build())
+	return sb.Build()
 }
 
 func encodeSMID(jd *jid.JID, nonce []byte) string {
@@ -137,6 +137,48 @@ func TestStream_Enable(t *testing.T) {
 	sq.CancelTimers()
 }
 
+func TestStream_BindInlineEnable(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.SetInfoValueFunc = func(ctx context.Context, k string, val interface{}) error { return nil }
+	stmMock.IsBindedFunc = func() bool { return true }
+	stmMock.InfoFunc = func() c2smodel.Info { return c2smodel.NewInfoMap() }
+
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hook.NewHooks(),
+		logger:      kitlog.NewNopLogger(),
+	}
+
+	// when
+	elem, err := sm.ProcessBindInline(context.Background(),
+		stravaganza.NewBuilder("enable").
+			WithAttribute(stravaganza.Namespace, streamNamespace).
+			Build(),
+		stmMock,
+	)
+
+	// then
+	require.Nil(t, err)
+	require.NotNil(t, elem)
+
+	require.Equal(t, "enabled", elem.Name())
+	require.Equal(t, streamNamespace, elem.Attribute(stravaganza.Namespace))
+	require.Len(t, stmMock.SendElementCalls(), 0) // included into bound response
+
+	sq := sm.stmQueueMap.Get(queueKey(jd))
+	require.NotNil(t, sq)
+
+	sq.CancelTimers()
+}
+
 func TestStream_InStanza(t *testing.T) {
 	// given
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
@@ -319,6 +361,73 @@ func TestStream_OutStanzaMaxQueueSizeReached(t *testing.T) {
 	require.Equal(t, streamerror.PolicyViolation, streamErr.Reason)
 }
 
+func TestStream_Hibernate(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.InfoFunc = func() c2smodel.Info {
+		return c2smodel.NewInfoMapFromMap(
+			map[string]string{enabledInfoKey: "true"},
+		)
+	}
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		termTms:     make(map[string]*time.Timer),
+		hk:          hk,
+		logger:      kitlog.NewNopLogger(),
+	}
+	sq := streamqueue.New(
+		stmMock, nil, nil, 0, 0, time.Second, time.Minute,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+	defer sq.CancelTimers()
+
+	var hibernatedInf *hook.C2SStreamInfo
+	hk.AddHook(hook.C2SStreamHibernated, func(execCtx *hook.ExecutionContext) error {
+		hibernatedInf = execCtx.Info.(*hook.C2SStreamInfo)
+		return nil
+	}, hook.DefaultPriority)
+
+	pr, _ := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		BuildPresence()
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	halted, err := hk.Run(hook.C2SStreamDisconnected, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			ID:       "c2s:1234",
+			Presence: pr,
+		},
+		Sender:  stmMock,
+		Context: context.Background(),
+	})
+
+	// then
+	require.True(t, halted)
+	require.Nil(t, err)
+
+	require.NotNil(t, hibernatedInf)
+	require.Equal(t, "c2s:1234", hibernatedInf.ID)
+	require.Equal(t, "ortuman@jackal.im/yard", hibernatedInf.JID.String())
+
+	sm.mu.Lock()
+	tm := sm.termTms["c2s:1234"]
+	sm.mu.Unlock()
+	require.NotNil(t, tm)
+	tm.Stop()
+}
+
 func TestStream_SendR(t *testing.T) {
 	// given
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
@@ -628,6 +737,44 @@ func TestStream_Resume(t *testing.T) {
 	require.Equal(t, msgID, sndElements[1].Attribute(stravaganza.ID))
 }
 
+func TestStream_SASL2InlineResumeFailed(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IsAuthenticatedFunc = func() bool { return true }
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourceFunc = func(ctx context.Context, username string, resource string) (c2smodel.ResourceDesc, error) {
+		return nil, nil
+	}
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		resMng:      resMngMock,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hook.NewHooks(),
+		logger:      kitlog.NewNopLogger(),
+	}
+
+	// when
+	elem, err := sm.ProcessSASL2Inline(context.Background(),
+		stravaganza.NewBuilder("resume").
+			WithAttribute(stravaganza.Namespace, streamNamespace).
+			WithAttribute("previd", encodeSMID(jd, testNonce())).
+			WithAttribute("h", "4").
+			Build(),
+		stmMock,
+	)
+
+	// then
+	require.Nil(t, err)
+	require.NotNil(t, elem)
+
+	require.Equal(t, "failed", elem.Name())
+	require.NotNil(t, elem.Child(itemNotFound))
+	require.Len(t, stmMock.SendElementCalls(), 0) // included into SASL2 success response
+}
+
 func TestStream_ResumeRemote(t *testing.T) {
 	// given
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
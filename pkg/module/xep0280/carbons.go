@@ -29,6 +29,7 @@ import (
 	"github.com/ortuman/jackal/pkg/host"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
 	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/router/stream"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 )
 
@@ -93,6 +94,23 @@ func (p *Carbons) AccountFeatures(_ context.Context) ([]string, error) {
 	return nil, nil
 }
 
+// BindInlineFeature returns carbons Bind 2 inline feature namespace.
+func (p *Carbons) BindInlineFeature() string {
+	return carbonsNamespace
+}
+
+// ProcessBindInline enables carbons as part of a Bind 2 request.
+func (p *Carbons) ProcessBindInline(ctx context.Context, elem stravaganza.Element, stm stream.C2S) (stravaganza.Element, error) {
+	if elem.Name() != "enable" {
+		return nil, nil
+	}
+	if err := stm.SetInfoValue(ctx, carbonsEnabledCtxKey, true); err != nil {
+		return nil, err
+	}
+	level.Info(p.logger).Log("msg", "enabled carbons copy", "username", stm.Username(), "resource", stm.Resource())
+	return nil, nil
+}
+
 // Start starts carbons module.
 func (p *Carbons) Start(_ context.Context) error {
 	p.hk.AddHook(hook.C2SStreamWillRouteElement, p.onC2SElementWillRoute, hook.DefaultPriority)
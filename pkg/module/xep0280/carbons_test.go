@@ -97,6 +97,41 @@ func TestCarbons_Enable(t *testing.T) {
 	require.Equal(t, stravaganza.ResultType, respStanzas[0].Attribute(stravaganza.Type))
 }
 
+func TestCarbons_BindInlineEnable(t *testing.T) {
+	// given
+	stmMock := &c2sStreamMock{}
+
+	var setK string
+	var setVal interface{}
+	stmMock.SetInfoValueFunc = func(ctx context.Context, k string, val interface{}) error {
+		setK = k
+		setVal = val
+		return nil
+	}
+	stmMock.UsernameFunc = func() string { return "ortuman" }
+	stmMock.ResourceFunc = func() string { return "yard" }
+
+	c := &Carbons{
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+	// when
+	elem, err := c.ProcessBindInline(context.Background(),
+		stravaganza.NewBuilder("enable").
+			WithAttribute(stravaganza.Namespace, carbonsNamespace).
+			Build(),
+		stmMock,
+	)
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, elem)
+
+	require.Equal(t, carbonsNamespace, c.BindInlineFeature())
+	require.Equal(t, carbonsEnabledCtxKey, setK)
+	require.Equal(t, true, setVal)
+}
+
 func TestCarbons_Disable(t *testing.T) {
 	// given
 	stmMock := &c2sStreamMock{}
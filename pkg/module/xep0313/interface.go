@@ -48,4 +48,5 @@ type c2sStream interface {
 //go:generate moq -out hosts.mock_test.go . hosts
 type hosts interface {
 	IsLocalHost(h string) bool
+	IsAnonymousHost(h string) bool
 }
@@ -40,6 +40,7 @@ const (
 
 	mamNamespace         = "urn:xmpp:mam:2"
 	extendedMamNamespace = "urn:xmpp:mam:2#extended"
+	fulltextNamespace    = "urn:xmpp:fulltext:0"
 
 	archiveRequestedCtxKey = "mam:requested"
 )
@@ -101,7 +102,7 @@ func (m *Mam) ServerFeatures(_ context.Context) ([]string, error) {
 
 // AccountFeatures returns mam account disco features.
 func (m *Mam) AccountFeatures(_ context.Context) ([]string, error) {
-	return []string{mamNamespace, extendedMamNamespace}, nil
+	return []string{mamNamespace, extendedMamNamespace, retractNamespace}, nil
 }
 
 // Start starts mam module.
@@ -146,6 +147,9 @@ func (m *Mam) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
 		return nil
 	}
+	if iq.ChildNamespace("prefs", mamNamespace) != nil {
+		return m.svc.ProcessPrefsIQ(ctx, iq)
+	}
 	return m.svc.ProcessIQ(ctx, iq, func(_ string) error {
 		fromJID := iq.FromJID()
 
@@ -188,7 +192,10 @@ func (m *Mam) onMessageRouted(execCtx *hook.ExecutionContext) error {
 
 func (m *Mam) onUserDeleted(execCtx *hook.ExecutionContext) error {
 	inf := execCtx.Info.(*hook.UserInfo)
-	return m.svc.DeleteArchive(execCtx.Context, inf.Username)
+	if err := m.svc.DeleteArchive(execCtx.Context, inf.Username); err != nil {
+		return err
+	}
+	return m.svc.DeletePreferences(execCtx.Context, inf.Username)
 }
 
 func (m *Mam) handleRoutedMessage(execCtx *hook.ExecutionContext, elem stravaganza.Element) error {
@@ -196,21 +203,39 @@ func (m *Mam) handleRoutedMessage(execCtx *hook.ExecutionContext, elem stravagan
 	if !ok {
 		return nil
 	}
+	if retract := msg.ChildNamespace("retract", retractNamespace); retract != nil && (msg.IsNormal() || msg.IsChat()) {
+		if err := m.retractMessage(execCtx.Context, msg.FromJID(), msg.ToJID(), retract.Attribute("id")); err != nil {
+			return err
+		}
+	}
 	if !IsMessageArchievable(msg) {
 		return nil
 	}
 
 	fromJID := msg.FromJID()
-	if m.hosts.IsLocalHost(fromJID.Domain()) {
+	toJID := msg.ToJID()
+
+	archiveSent, err := m.shouldArchive(execCtx.Context, fromJID, toJID)
+	if err != nil {
+		return err
+	}
+	if archiveSent {
 		sentArchiveID := uuid.New().String()
+		if stanzaID := xmpputil.MessageStanzaID(msg); len(stanzaID) > 0 && !fromJID.MatchesWithOptions(toJID, jid.MatchesBare) {
+			// share recipient stanza ID, so that both archive copies can be referenced by a single identifier.
+			sentArchiveID = stanzaID
+		}
 		archiveMsg := xmpputil.MakeStanzaIDMessage(msg, sentArchiveID, fromJID.ToBareJID().String())
 		if err := m.svc.ArchiveMessage(execCtx.Context, archiveMsg, fromJID.ToBareJID().String(), sentArchiveID); err != nil {
 			return err
 		}
 		execCtx.Context = context.WithValue(execCtx.Context, sentArchiveIDKey, sentArchiveID)
 	}
-	toJID := msg.ToJID()
-	if !m.hosts.IsLocalHost(toJID.Domain()) {
+	archiveReceived, err := m.shouldArchive(execCtx.Context, toJID, fromJID)
+	if err != nil {
+		return err
+	}
+	if !archiveReceived {
 		return nil
 	}
 	recievedArchiveID := xmpputil.MessageStanzaID(msg)
@@ -221,15 +246,64 @@ func (m *Mam) handleRoutedMessage(execCtx *hook.ExecutionContext, elem stravagan
 	return nil
 }
 
+// retractMessage tombstones the message identified by id in both sender and recipient archives,
+// as long as it was originally sent by the retracting entity.
+func (m *Mam) retractMessage(ctx context.Context, fromJID, toJID *jid.JID, id string) error {
+	if len(id) == 0 {
+		return nil
+	}
+	owners := []*jid.JID{fromJID}
+	if !toJID.MatchesWithOptions(fromJID, jid.MatchesBare) {
+		owners = append(owners, toJID)
+	}
+	for _, owner := range owners {
+		if !m.isArchiveHost(owner.Domain()) {
+			continue
+		}
+		archiveMsg, err := m.svc.FetchMessage(ctx, owner.ToBareJID().String(), id)
+		if err != nil {
+			return err
+		}
+		if archiveMsg == nil {
+			continue
+		}
+		senderJID, err := jid.NewWithString(archiveMsg.FromJid, true)
+		if err != nil || !senderJID.MatchesWithOptions(fromJID, jid.MatchesBare) {
+			level.Warn(m.logger).Log("msg", "ignored retraction of a message sent by another entity",
+				"archive_id", archiveMsg.ArchiveId, "id", id, "jid", fromJID.String(),
+			)
+			continue
+		}
+		if err := m.svc.RetractMessage(ctx, archiveMsg, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shouldArchive tells whether a message exchanged with peer should be stored into owner's archive.
+func (m *Mam) shouldArchive(ctx context.Context, owner, peer *jid.JID) (bool, error) {
+	if !m.isArchiveHost(owner.Domain()) {
+		return false, nil
+	}
+	return m.svc.ShouldArchive(ctx, owner.Node(), peer)
+}
+
 func (m *Mam) addRecipientStanzaID(originalMsg *stravaganza.Message) *stravaganza.Message {
 	toJID := originalMsg.ToJID()
-	if !m.hosts.IsLocalHost(toJID.Domain()) {
+	if !m.isArchiveHost(toJID.Domain()) {
 		return originalMsg
 	}
 	archiveID := uuid.New().String()
 	return xmpputil.MakeStanzaIDMessage(originalMsg, archiveID, toJID.ToBareJID().String())
 }
 
+// isArchiveHost tells whether messages belonging to users of h domain should be archived.
+// Ephemeral anonymous users are never archived.
+func (m *Mam) isArchiveHost(h string) bool {
+	return m.hosts.IsLocalHost(h) && !m.hosts.IsAnonymousHost(h)
+}
+
 // IsArchiveRequested determines whether archive has been requested over a C2S stream by inspecting inf parameter.
 func IsArchiveRequested(inf c2smodel.Info) bool {
 	return inf.Bool(archiveRequestedCtxKey)
@@ -79,7 +79,8 @@ func TestMam_FormFields(t *testing.T) {
 	form, _ := xep0004.NewFormFromElement(x)
 	require.NotNil(t, form)
 
-	require.Len(t, form.Fields, 7)
+	require.Len(t, form.Fields, 8)
+	require.Equal(t, "{urn:xmpp:fulltext:0}fulltext", form.Fields[7].Var)
 }
 
 func TestMam_Metadata(t *testing.T) {
@@ -158,9 +159,13 @@ func TestMam_ArchiveMessage(t *testing.T) {
 	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
 		return f(ctx, txMock)
 	}
+	repMock.FetchArchivePreferencesFunc = func(ctx context.Context, username string) (*archivemodel.Preferences, error) {
+		return nil, nil
+	}
 
 	hosts := &hostsMock{}
 	hosts.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+	hosts.IsAnonymousHostFunc = func(h string) bool { return false }
 
 	hk := hook.NewHooks()
 	mam := &Mam{
@@ -195,6 +200,7 @@ func TestMam_ArchiveMessage(t *testing.T) {
 
 	require.Equal(t, "ortuman@jackal.im", archivedMessages[0].ArchiveId)
 	require.Equal(t, "noelia@jackal.im", archivedMessages[1].ArchiveId)
+	require.Equal(t, archivedMessages[0].Id, archivedMessages[1].Id)
 
 	require.Len(t, txMock.DeleteArchiveOldestMessagesCalls(), 2)
 	require.Len(t, txMock.InsertArchiveMessageCalls(), 2)
@@ -203,6 +209,66 @@ func TestMam_ArchiveMessage(t *testing.T) {
 	require.True(t, len(ExtractReceivedArchiveID(execCtx.Context)) > 0)
 }
 
+func TestMam_ArchiveMessageAnonymousUser(t *testing.T) {
+	// given
+	var archivedMessages []*archivemodel.Message
+
+	txMock := &txMock{}
+	txMock.DeleteArchiveOldestMessagesFunc = func(_ context.Context, _ string, _ int) error {
+		return nil
+	}
+	txMock.InsertArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		archivedMessages = append(archivedMessages, message)
+		return nil
+	}
+
+	repMock := &repositoryMock{}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, txMock)
+	}
+	repMock.FetchArchivePreferencesFunc = func(ctx context.Context, username string) (*archivemodel.Preferences, error) {
+		return nil, nil
+	}
+
+	hosts := &hostsMock{}
+	hosts.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" || h == "anon.jackal.im" }
+	hosts.IsAnonymousHostFunc = func(h string) bool { return h == "anon.jackal.im" }
+
+	hk := hook.NewHooks()
+	mam := &Mam{
+		svc:    NewService(nil, hk, repMock, 100, kitlog.NewNopLogger()),
+		hk:     hk,
+		hosts:  hosts,
+		logger: kitlog.NewNopLogger(),
+	}
+	_ = mam.Start(context.Background())
+	t.Cleanup(func() {
+		_ = mam.Stop(context.Background())
+	})
+
+	msg := testMessageStanzaWithParameters("b0", "5c5fa8c6@anon.jackal.im/chamber", "noelia@jackal.im/yard")
+
+	// when
+	execCtx := &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: msg,
+		},
+		Context: context.Background(),
+	}
+	_, err := hk.Run(hook.C2SStreamMessageReceived, execCtx)
+	require.NoError(t, err)
+
+	_, err = hk.Run(hook.C2SStreamMessageRouted, execCtx)
+	require.NoError(t, err)
+
+	// then
+	require.Len(t, archivedMessages, 1)
+	require.Equal(t, "noelia@jackal.im", archivedMessages[0].ArchiveId)
+
+	require.Empty(t, ExtractSentArchiveID(execCtx.Context))
+	require.NotEmpty(t, ExtractReceivedArchiveID(execCtx.Context))
+}
+
 func TestMam_SendArchiveMessages(t *testing.T) {
 	// given
 	archiveMessages := []*archivemodel.Message{
@@ -366,9 +432,13 @@ func TestMam_DeleteArchive(t *testing.T) {
 		deletedArchiveID = archiveID
 		return nil
 	}
+	repMock.DeleteArchivePreferencesFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
 
 	hosts := &hostsMock{}
 	hosts.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+	hosts.IsAnonymousHostFunc = func(h string) bool { return false }
 
 	hk := hook.NewHooks()
 	mam := &Mam{
@@ -396,6 +466,7 @@ func TestMam_DeleteArchive(t *testing.T) {
 	require.Len(t, repMock.DeleteArchiveCalls(), 1)
 
 	require.Equal(t, "ortuman", deletedArchiveID)
+	require.Len(t, repMock.DeleteArchivePreferencesCalls(), 1)
 }
 
 func testMessageStanzaWithParameters(body, from, to string) *stravaganza.Message {
@@ -0,0 +1,186 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0313
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	alwaysBehavior = "always"
+	neverBehavior  = "never"
+	rosterBehavior = "roster"
+)
+
+var errInvalidPrefs = errors.New("xep0313: invalid archiving preferences")
+
+// ProcessPrefsIQ processes a MAM preferences (XEP-0441) IQ targeting requesting user archive.
+func (m *Service) ProcessPrefsIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	username := iq.FromJID().Node()
+
+	switch {
+	case iq.IsGet():
+		return m.getPrefs(ctx, iq, username)
+	case iq.IsSet():
+		return m.setPrefs(ctx, iq, username)
+	}
+	return nil
+}
+
+// ShouldArchive tells whether a message exchanged with peer should be stored into username archive
+// according to user archiving preferences.
+func (m *Service) ShouldArchive(ctx context.Context, username string, peer *jid.JID) (bool, error) {
+	prefs, err := m.rep.FetchArchivePreferences(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	if prefs == nil {
+		return true, nil // archive everything by default
+	}
+	switch {
+	case containsJID(prefs.Never, peer):
+		return false, nil
+	case containsJID(prefs.Always, peer):
+		return true, nil
+	}
+	switch prefs.DefaultBehavior {
+	case neverBehavior:
+		return false, nil
+	case rosterBehavior:
+		ri, err := m.rep.FetchRosterItem(ctx, username, peer.ToBareJID().String())
+		if err != nil {
+			return false, err
+		}
+		return ri != nil, nil
+	default:
+		return true, nil
+	}
+}
+
+// DeletePreferences deletes user archiving preferences.
+func (m *Service) DeletePreferences(ctx context.Context, username string) error {
+	return m.rep.DeleteArchivePreferences(ctx, username)
+}
+
+func (m *Service) getPrefs(ctx context.Context, iq *stravaganza.IQ, username string) error {
+	prefs, err := m.rep.FetchArchivePreferences(ctx, username)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	if prefs == nil {
+		prefs = &archivemodel.Preferences{
+			Username:        username,
+			DefaultBehavior: alwaysBehavior,
+		}
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, prefsElement(prefs)))
+
+	level.Info(m.logger).Log("msg", "fetched archiving preferences", "username", username)
+
+	return nil
+}
+
+func (m *Service) setPrefs(ctx context.Context, iq *stravaganza.IQ, username string) error {
+	prefs, err := prefsFromElement(iq.ChildNamespace("prefs", mamNamespace), username)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	if err := m.rep.UpsertArchivePreferences(ctx, prefs); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, prefsElement(prefs)))
+
+	level.Info(m.logger).Log("msg", "updated archiving preferences", "username", username, "default", prefs.DefaultBehavior)
+
+	return nil
+}
+
+func prefsFromElement(elem stravaganza.Element, username string) (*archivemodel.Preferences, error) {
+	prefs := &archivemodel.Preferences{
+		Username:        username,
+		DefaultBehavior: elem.Attribute("default"),
+	}
+	switch prefs.DefaultBehavior {
+	case alwaysBehavior, neverBehavior, rosterBehavior:
+		break
+	default:
+		return nil, errInvalidPrefs
+	}
+	var err error
+	if prefs.Always, err = jidList(elem.Child("always")); err != nil {
+		return nil, err
+	}
+	if prefs.Never, err = jidList(elem.Child("never")); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func jidList(elem stravaganza.Element) ([]string, error) {
+	if elem == nil {
+		return nil, nil
+	}
+	var ret []string
+	for _, jidElem := range elem.Children("jid") {
+		jd, err := jid.NewWithString(jidElem.Text(), false)
+		if err != nil {
+			return nil, errInvalidPrefs
+		}
+		ret = append(ret, jd.String())
+	}
+	return ret, nil
+}
+
+func prefsElement(prefs *archivemodel.Preferences) stravaganza.Element {
+	return stravaganza.NewBuilder("prefs").
+		WithAttribute(stravaganza.Namespace, mamNamespace).
+		WithAttribute("default", prefs.DefaultBehavior).
+		WithChild(jidListElement("always", prefs.Always)).
+		WithChild(jidListElement("never", prefs.Never)).
+		Build()
+}
+
+func jidListElement(name string, jids []string) stravaganza.Element {
+	b := stravaganza.NewBuilder(name)
+	for _, j := range jids {
+		b.WithChild(
+			stravaganza.NewBuilder("jid").
+				WithText(j).
+				Build(),
+		)
+	}
+	return b.Build()
+}
+
+func containsJID(jids []string, jd *jid.JID) bool {
+	bareJID := jd.ToBareJID().String()
+	for _, j := range jids {
+		if j == bareJID || j == jd.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,224 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0313
+
+import (
+	"context"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMam_SetPreferences(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	var storedPrefs *archivemodel.Preferences
+
+	repMock := &repositoryMock{}
+	repMock.UpsertArchivePreferencesFunc = func(ctx context.Context, prefs *archivemodel.Preferences) error {
+		storedPrefs = prefs
+		return nil
+	}
+	mam := &Mam{
+		svc:    NewService(routerMock, hook.NewHooks(), repMock, 100, kitlog.NewNopLogger()),
+		router: routerMock,
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	_ = mam.ProcessIQ(context.Background(), testPrefsIQ(stravaganza.SetType, "roster", "noelia@jackal.im", "romeo@montague.lit"))
+
+	// then
+	require.NotNil(t, storedPrefs)
+	require.Equal(t, "ortuman", storedPrefs.Username)
+	require.Equal(t, rosterBehavior, storedPrefs.DefaultBehavior)
+	require.Equal(t, []string{"noelia@jackal.im"}, storedPrefs.Always)
+	require.Equal(t, []string{"romeo@montague.lit"}, storedPrefs.Never)
+
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Type())
+
+	prefs := respStanzas[0].ChildNamespace("prefs", mamNamespace)
+	require.NotNil(t, prefs)
+	require.Equal(t, rosterBehavior, prefs.Attribute("default"))
+	require.Equal(t, "noelia@jackal.im", prefs.Child("always").Child("jid").Text())
+	require.Equal(t, "romeo@montague.lit", prefs.Child("never").Child("jid").Text())
+}
+
+func TestMam_SetInvalidPreferences(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	repMock := &repositoryMock{}
+
+	mam := &Mam{
+		svc:    NewService(routerMock, hook.NewHooks(), repMock, 100, kitlog.NewNopLogger()),
+		router: routerMock,
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	_ = mam.ProcessIQ(context.Background(), testPrefsIQ(stravaganza.SetType, "sometimes", "", ""))
+
+	// then
+	require.Len(t, repMock.UpsertArchivePreferencesCalls(), 0)
+
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, respStanzas[0].Type())
+	require.NotNil(t, respStanzas[0].Child("error").Child("bad-request"))
+}
+
+func TestMam_GetDefaultPreferences(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	repMock := &repositoryMock{}
+	repMock.FetchArchivePreferencesFunc = func(ctx context.Context, username string) (*archivemodel.Preferences, error) {
+		return nil, nil
+	}
+	mam := &Mam{
+		svc:    NewService(routerMock, hook.NewHooks(), repMock, 100, kitlog.NewNopLogger()),
+		router: routerMock,
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	_ = mam.ProcessIQ(context.Background(), testPrefsIQ(stravaganza.GetType, "", "", ""))
+
+	// then
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Type())
+
+	prefs := respStanzas[0].ChildNamespace("prefs", mamNamespace)
+	require.NotNil(t, prefs)
+	require.Equal(t, alwaysBehavior, prefs.Attribute("default"))
+}
+
+func TestMam_ArchiveMessageWithPreferences(t *testing.T) {
+	// given
+	var archivedMessages []*archivemodel.Message
+
+	txMock := &txMock{}
+	txMock.DeleteArchiveOldestMessagesFunc = func(_ context.Context, _ string, _ int) error {
+		return nil
+	}
+	txMock.InsertArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		archivedMessages = append(archivedMessages, message)
+		return nil
+	}
+
+	repMock := &repositoryMock{}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, txMock)
+	}
+	repMock.FetchArchivePreferencesFunc = func(ctx context.Context, username string) (*archivemodel.Preferences, error) {
+		switch username {
+		case "ortuman":
+			return &archivemodel.Preferences{Username: username, DefaultBehavior: alwaysBehavior, Never: []string{"noelia@jackal.im"}}, nil
+		case "noelia":
+			return &archivemodel.Preferences{Username: username, DefaultBehavior: rosterBehavior}, nil
+		}
+		return nil, nil
+	}
+	repMock.FetchRosterItemFunc = func(ctx context.Context, username string, jd string) (*rostermodel.Item, error) {
+		if username == "noelia" && jd == "romeo@jackal.im" {
+			return &rostermodel.Item{Username: username, Jid: jd}, nil
+		}
+		return nil, nil
+	}
+
+	hosts := &hostsMock{}
+	hosts.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+	hosts.IsAnonymousHostFunc = func(h string) bool { return false }
+
+	hk := hook.NewHooks()
+	mam := &Mam{
+		svc:    NewService(nil, hk, repMock, 100, kitlog.NewNopLogger()),
+		hk:     hk,
+		hosts:  hosts,
+		logger: kitlog.NewNopLogger(),
+	}
+	_ = mam.Start(context.Background())
+	t.Cleanup(func() {
+		_ = mam.Stop(context.Background())
+	})
+
+	// when
+	for _, msg := range []*stravaganza.Message{
+		testMessageStanzaWithParameters("b0", "ortuman@jackal.im/chamber", "noelia@jackal.im/yard"),
+		testMessageStanzaWithParameters("b1", "romeo@jackal.im/balcony", "noelia@jackal.im/yard"),
+	} {
+		execCtx := &hook.ExecutionContext{
+			Info: &hook.C2SStreamInfo{
+				Element: msg,
+			},
+			Context: context.Background(),
+		}
+		_, err := hk.Run(hook.C2SStreamMessageRouted, execCtx)
+		require.NoError(t, err)
+	}
+
+	// then
+	require.Len(t, archivedMessages, 2)
+
+	require.Equal(t, "romeo@jackal.im", archivedMessages[0].ArchiveId)
+	require.Equal(t, "noelia@jackal.im", archivedMessages[1].ArchiveId)
+	require.Equal(t, "romeo@jackal.im/balcony", archivedMessages[1].FromJid)
+}
+
+func testPrefsIQ(typ, defaultBehavior, always, never string) *stravaganza.IQ {
+	pb := stravaganza.NewBuilder("prefs").
+		WithAttribute(stravaganza.Namespace, mamNamespace)
+	if len(defaultBehavior) > 0 {
+		pb.WithAttribute("default", defaultBehavior)
+	}
+	if len(always) > 0 {
+		pb.WithChild(jidListElement("always", []string{always}))
+	}
+	if len(never) > 0 {
+		pb.WithChild(jidListElement("never", []string{never}))
+	}
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "prefs1").
+		WithAttribute(stravaganza.Type, typ).
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/chamber").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithChild(pb.Build()).
+		BuildIQ()
+	return iq
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0313
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/hook"
+	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
+)
+
+const (
+	retractNamespace  = "urn:xmpp:message-retract:1"
+	moderateNamespace = "urn:xmpp:message-moderate:1"
+	sidNamespace      = "urn:xmpp:sid:0"
+)
+
+// Moderation contains the information associated to a moderated retraction (XEP-0425).
+type Moderation struct {
+	// By is the JID of the moderator performing the retraction.
+	By string
+
+	// Reason is an optional human-readable retraction reason.
+	Reason string
+}
+
+// FetchMessage returns the archived message identified by id, or nil if not found.
+func (m *Service) FetchMessage(ctx context.Context, archiveID, id string) (*archivemodel.Message, error) {
+	messages, err := m.rep.FetchArchiveMessages(ctx, &archivemodel.Filters{Ids: []string{id}}, archiveID)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	return messages[0], nil
+}
+
+// RetractMessage replaces an archived message with a retraction tombstone (XEP-0424).
+// A non-nil moderation marks the retraction as moderated (XEP-0425), keeping moderator and reason
+// within the tombstone as audit trail.
+func (m *Service) RetractMessage(ctx context.Context, archiveMsg *archivemodel.Message, moderation *Moderation) error {
+	tombstone := &archivemodel.Message{
+		ArchiveId: archiveMsg.ArchiveId,
+		Id:        archiveMsg.Id,
+		FromJid:   archiveMsg.FromJid,
+		ToJid:     archiveMsg.ToJid,
+		SenderJid: archiveMsg.SenderJid,
+		Message:   makeTombstone(archiveMsg.Message, time.Now(), moderation).Proto(),
+		Stamp:     archiveMsg.Stamp,
+	}
+	if err := m.rep.UpdateArchiveMessage(ctx, tombstone); err != nil {
+		return err
+	}
+	if moderation != nil {
+		level.Info(m.logger).Log("msg", "moderated archived message",
+			"archive_id", archiveMsg.ArchiveId, "id", archiveMsg.Id, "by", moderation.By, "reason", moderation.Reason,
+		)
+	} else {
+		level.Info(m.logger).Log("msg", "retracted archived message", "archive_id", archiveMsg.ArchiveId, "id", archiveMsg.Id)
+	}
+	return m.runHook(ctx, hook.ArchiveMessageRetracted, &hook.MamInfo{
+		ArchiveID: archiveMsg.ArchiveId,
+		Message:   tombstone,
+	})
+}
+
+// makeTombstone returns a copy of the original message that only keeps its attributes and
+// stanza identifiers along with a retracted element.
+func makeTombstone(original *stravaganza.PBElement, stamp time.Time, moderation *Moderation) stravaganza.Element {
+	orig := stravaganza.NewBuilderFromProto(original).Build()
+
+	b := stravaganza.NewMessageBuilder().
+		WithAttributes(orig.AllAttributes()...)
+	for _, ch := range orig.AllChildren() {
+		if ch.Attribute(stravaganza.Namespace) != sidNamespace {
+			continue // stanza-id and origin-id
+		}
+		b.WithChild(ch)
+	}
+	retractedB := stravaganza.NewBuilder("retracted").
+		WithAttribute(stravaganza.Namespace, retractNamespace).
+		WithAttribute("stamp", stamp.UTC().Format(dateTimeFormat))
+	if moderation != nil {
+		retractedB.WithChild(
+			stravaganza.NewBuilder("moderated").
+				WithAttribute(stravaganza.Namespace, moderateNamespace).
+				WithAttribute("by", moderation.By).
+				Build(),
+		)
+		if len(moderation.Reason) > 0 {
+			retractedB.WithChild(stravaganza.NewBuilder("reason").WithText(moderation.Reason).Build())
+		}
+	}
+	return b.WithChild(retractedB.Build()).Build()
+}
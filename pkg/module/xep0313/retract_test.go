@@ -0,0 +1,176 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0313
+
+import (
+	"context"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/hook"
+	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMam_RetractMessage(t *testing.T) {
+	// given
+	original := testMessageStanzaWithParameters("I'll give thee a wind.", "ortuman@jackal.im/chamber", "noelia@jackal.im/yard")
+
+	var updated []*archivemodel.Message
+
+	repMock := &repositoryMock{}
+	repMock.FetchArchiveMessagesFunc = func(ctx context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error) {
+		return []*archivemodel.Message{{
+			ArchiveId: archiveID,
+			Id:        f.Ids[0],
+			FromJid:   "ortuman@jackal.im/chamber",
+			ToJid:     "noelia@jackal.im/yard",
+			Message:   original.Proto(),
+		}}, nil
+	}
+	repMock.UpdateArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		updated = append(updated, message)
+		return nil
+	}
+	mam, hk := testRetractMam(t, repMock)
+
+	var retracted []string
+	hk.AddHook(hook.ArchiveMessageRetracted, func(execCtx *hook.ExecutionContext) error {
+		retracted = append(retracted, execCtx.Info.(*hook.MamInfo).ArchiveID)
+		return nil
+	}, hook.DefaultPriority)
+
+	// when
+	err := mam.handleRoutedMessage(&hook.ExecutionContext{Context: context.Background()}, testRetractMessage("ortuman@jackal.im/chamber", "noelia@jackal.im/yard", "s1234"))
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, updated, 2)
+	require.Equal(t, []string{"ortuman@jackal.im", "noelia@jackal.im"}, retracted)
+
+	tombstone, err := stravaganza.NewBuilderFromProto(updated[0].Message).BuildMessage()
+	require.NoError(t, err)
+	require.Equal(t, "s1234", updated[0].Id)
+	require.Nil(t, tombstone.Child("body"))
+	require.NotNil(t, tombstone.ChildNamespace("retracted", retractNamespace))
+	require.Equal(t, "ortuman@jackal.im/chamber", tombstone.FromJID().String())
+}
+
+func TestMam_RetractForeignMessage(t *testing.T) {
+	// given
+	original := testMessageStanzaWithParameters("I'll give thee a wind.", "noelia@jackal.im/yard", "ortuman@jackal.im/chamber")
+
+	repMock := &repositoryMock{}
+	repMock.FetchArchiveMessagesFunc = func(ctx context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error) {
+		return []*archivemodel.Message{{
+			ArchiveId: archiveID,
+			Id:        f.Ids[0],
+			FromJid:   "noelia@jackal.im/yard",
+			ToJid:     "ortuman@jackal.im/chamber",
+			Message:   original.Proto(),
+		}}, nil
+	}
+	repMock.UpdateArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		return nil
+	}
+	mam, _ := testRetractMam(t, repMock)
+
+	// when
+	err := mam.handleRoutedMessage(&hook.ExecutionContext{Context: context.Background()}, testRetractMessage("ortuman@jackal.im/chamber", "noelia@jackal.im/yard", "s1234"))
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, repMock.UpdateArchiveMessageCalls(), 0)
+}
+
+func TestService_ModeratedTombstone(t *testing.T) {
+	// given
+	original := testMessageStanzaWithParameters("I'll give thee a wind.", "lobby@conference.jackal.im/noelia", "lobby@conference.jackal.im")
+
+	var updated *archivemodel.Message
+
+	repMock := &repositoryMock{}
+	repMock.UpdateArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		updated = message
+		return nil
+	}
+	svc := NewService(nil, hook.NewHooks(), repMock, 100, kitlog.NewNopLogger())
+
+	// when
+	err := svc.RetractMessage(context.Background(), &archivemodel.Message{
+		ArchiveId: "lobby@conference.jackal.im",
+		Id:        "s1234",
+		Message:   original.Proto(),
+	}, &Moderation{By: "lobby@conference.jackal.im/ortuman", Reason: "Spam"})
+
+	// then
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+
+	tombstone := stravaganza.NewBuilderFromProto(updated.Message).Build()
+	retracted := tombstone.ChildNamespace("retracted", retractNamespace)
+	require.NotNil(t, retracted)
+
+	moderated := retracted.ChildNamespace("moderated", moderateNamespace)
+	require.NotNil(t, moderated)
+	require.Equal(t, "lobby@conference.jackal.im/ortuman", moderated.Attribute("by"))
+	require.Equal(t, "Spam", retracted.Child("reason").Text())
+}
+
+func testRetractMam(t *testing.T, repMock *repositoryMock) (*Mam, *hook.Hooks) {
+	t.Helper()
+
+	txMock := &txMock{}
+	txMock.InsertArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		return nil
+	}
+	txMock.DeleteArchiveOldestMessagesFunc = func(ctx context.Context, archiveID string, maxElements int) error {
+		return nil
+	}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, txMock)
+	}
+	repMock.FetchArchivePreferencesFunc = func(ctx context.Context, username string) (*archivemodel.Preferences, error) {
+		return nil, nil
+	}
+	hosts := &hostsMock{}
+	hosts.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+	hosts.IsAnonymousHostFunc = func(h string) bool { return false }
+
+	hk := hook.NewHooks()
+	return &Mam{
+		svc:    NewService(nil, hk, repMock, 100, kitlog.NewNopLogger()),
+		hk:     hk,
+		hosts:  hosts,
+		logger: kitlog.NewNopLogger(),
+	}, hk
+}
+
+func testRetractMessage(from, to, id string) *stravaganza.Message {
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, to).
+		WithAttribute(stravaganza.Type, stravaganza.ChatType).
+		WithChild(
+			stravaganza.NewBuilder("retract").
+				WithAttribute(stravaganza.Namespace, retractNamespace).
+				WithAttribute(stravaganza.ID, id).
+				Build(),
+		).
+		BuildMessage()
+	return msg
+}
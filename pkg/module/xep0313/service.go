@@ -40,6 +40,8 @@ const (
 
 	defaultPageSize = 50
 	maxPageSize     = 250
+
+	fulltextFieldVar = "{" + fulltextNamespace + "}fulltext"
 )
 
 // Service represents a MAM service.
@@ -70,15 +72,42 @@ func NewService(
 
 // ProcessIQ processes a MAM IQ.
 func (m *Service) ProcessIQ(ctx context.Context, iq *stravaganza.IQ, onArchiveRequestedFn func(archiveID string) error) error {
+	archiveID := iq.FromJID().ToBareJID().String()
+	return m.processIQ(ctx, iq, archiveID, onArchiveRequestedFn)
+}
+
+// ProcessArchiveIQ processes a MAM IQ targeting archiveID archive.
+// Access control is expected to be performed by the caller.
+func (m *Service) ProcessArchiveIQ(ctx context.Context, iq *stravaganza.IQ, archiveID string) error {
+	return m.processIQ(ctx, iq, archiveID, nil)
+}
+
+// RecentMessages returns up to max most recent archived messages, optionally filtered by a starting time.
+func (m *Service) RecentMessages(ctx context.Context, archiveID string, since time.Time, max int) ([]*archivemodel.Message, error) {
+	filters := &archivemodel.Filters{}
+	if !since.IsZero() {
+		filters.Start = timestamppb.New(since)
+	}
+	messages, err := m.rep.FetchArchiveMessages(ctx, filters, archiveID)
+	if err != nil {
+		return nil, err
+	}
+	if max >= 0 && len(messages) > max {
+		messages = messages[len(messages)-max:]
+	}
+	return messages, nil
+}
+
+func (m *Service) processIQ(ctx context.Context, iq *stravaganza.IQ, archiveID string, onArchiveRequestedFn func(archiveID string) error) error {
 	switch {
 	case iq.IsGet() && iq.ChildNamespace("metadata", mamNamespace) != nil:
-		return m.queryMetadata(ctx, iq)
+		return m.queryMetadata(ctx, iq, archiveID)
 
 	case iq.IsGet() && iq.ChildNamespace("query", mamNamespace) != nil:
 		return m.formFields(ctx, iq)
 
 	case iq.IsSet() && iq.ChildNamespace("query", mamNamespace) != nil:
-		if err := m.queryArchive(ctx, iq); err != nil {
+		if err := m.queryArchive(ctx, iq, archiveID); err != nil {
 			return err
 		}
 		if onArchiveRequestedFn != nil {
@@ -91,11 +120,18 @@ func (m *Service) ProcessIQ(ctx context.Context, iq *stravaganza.IQ, onArchiveRe
 
 // ArchiveMessage archives a message.
 func (m *Service) ArchiveMessage(ctx context.Context, message *stravaganza.Message, archiveID, id string) error {
+	return m.ArchiveMessageFrom(ctx, message, archiveID, id, "")
+}
+
+// ArchiveMessageFrom archives a message whose real sender is identified by senderJID,
+// in case message from value doesn't identify it (i.e. MUC occupant messages).
+func (m *Service) ArchiveMessageFrom(ctx context.Context, message *stravaganza.Message, archiveID, id, senderJID string) error {
 	archiveMsg := &archivemodel.Message{
 		ArchiveId: archiveID,
 		Id:        id,
 		FromJid:   message.FromJID().String(),
 		ToJid:     message.ToJID().String(),
+		SenderJid: senderJID,
 		Message:   message.Proto(),
 		Stamp:     timestamppb.Now(),
 	}
@@ -158,6 +194,10 @@ func (m *Service) formFields(ctx context.Context, iq *stravaganza.IQ) error {
 			Validator: &xep0004.OpenValidator{},
 		},
 	})
+	form.Fields = append(form.Fields, xep0004.Field{
+		Type: xep0004.TextSingle,
+		Var:  fulltextFieldVar,
+	})
 
 	qChild := stravaganza.NewBuilder("query").
 		WithAttribute(stravaganza.Namespace, mamNamespace).
@@ -171,9 +211,7 @@ func (m *Service) formFields(ctx context.Context, iq *stravaganza.IQ) error {
 	return nil
 }
 
-func (m *Service) queryMetadata(ctx context.Context, iq *stravaganza.IQ) error {
-	archiveID := iq.FromJID().ToBareJID().String()
-
+func (m *Service) queryMetadata(ctx context.Context, iq *stravaganza.IQ, archiveID string) error {
 	metadata, err := m.rep.FetchArchiveMetadata(ctx, archiveID)
 	if err != nil {
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
@@ -203,7 +241,7 @@ func (m *Service) queryMetadata(ctx context.Context, iq *stravaganza.IQ) error {
 	return nil
 }
 
-func (m *Service) queryArchive(ctx context.Context, iq *stravaganza.IQ) error {
+func (m *Service) queryArchive(ctx context.Context, iq *stravaganza.IQ, archiveID string) error {
 	qChild := iq.ChildNamespace("query", mamNamespace)
 
 	// filter archive result
@@ -218,8 +256,6 @@ func (m *Service) queryArchive(ctx context.Context, iq *stravaganza.IQ) error {
 			return err
 		}
 	}
-	archiveID := iq.FromJID().ToBareJID().String()
-
 	messages, err := m.rep.FetchArchiveMessages(ctx, filters, archiveID)
 	if err != nil {
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
@@ -358,6 +394,9 @@ func formToFilters(fm *xep0004.DataForm) (*archivemodel.Filters, error) {
 	if ids := fm.Fields.ValuesForField("ids"); len(ids) > 0 {
 		retVal.Ids = ids
 	}
+	if fulltext := fm.Fields.ValueForField(fulltextFieldVar); len(fulltext) > 0 {
+		retVal.Fulltext = fulltext
+	}
 	return &retVal, nil
 }
 
@@ -15,9 +15,11 @@
 package xep0313
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	kitlog "github.com/go-kit/log"
 	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
 	"github.com/ortuman/jackal/pkg/module/xep0004"
 	"github.com/stretchr/testify/require"
@@ -81,6 +83,18 @@ func TestManager_FormToFields(t *testing.T) {
 				Ids: []string{"28482-98726-73623", "09af3-cc343-b409f"},
 			},
 		},
+		"fulltext": {
+			form: &xep0004.DataForm{
+				Type: xep0004.Submit,
+				Fields: []xep0004.Field{
+					{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{mamNamespace}},
+					{Var: "{urn:xmpp:fulltext:0}fulltext", Values: []string{"balcony moon"}},
+				},
+			},
+			filters: &archivemodel.Filters{
+				Fulltext: "balcony moon",
+			},
+		},
 	}
 	for tn, tc := range tcs {
 		t.Run(tn, func(t *testing.T) {
@@ -91,3 +105,29 @@ func TestManager_FormToFields(t *testing.T) {
 		})
 	}
 }
+
+func TestService_RecentMessages(t *testing.T) {
+	// given
+	var fetchedFilters *archivemodel.Filters
+
+	repMock := &repositoryMock{}
+	repMock.FetchArchiveMessagesFunc = func(ctx context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error) {
+		fetchedFilters = f
+		return []*archivemodel.Message{{Id: "1"}, {Id: "2"}, {Id: "3"}}, nil
+	}
+	svc := NewService(nil, nil, repMock, 0, kitlog.NewNopLogger())
+
+	since := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	// when
+	messages, err := svc.RecentMessages(context.Background(), "lobby@conference.jackal.im", since, 2)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, "2", messages[0].Id)
+	require.Equal(t, "3", messages[1].Id)
+
+	require.NotNil(t, fetchedFilters)
+	require.Equal(t, since, fetchedFilters.Start.AsTime())
+}
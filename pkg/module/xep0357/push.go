@@ -0,0 +1,382 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0357
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	pushmodel "github.com/ortuman/jackal/pkg/model/push"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	pushNamespace   = "urn:xmpp:push:0"
+	pubSubNamespace = "http://jabber.org/protocol/pubsub"
+
+	summaryFormType        = "urn:xmpp:push:summary"
+	publishOptionsFormType = "http://jabber.org/protocol/pubsub#publish-options"
+)
+
+const (
+	// ModuleName represents push module name.
+	ModuleName = "push"
+
+	// XEPNumber represents push XEP number.
+	XEPNumber = "0357"
+)
+
+// Config contains push module configuration options.
+type Config struct {
+	// IncludeSender tells whether notification summaries should disclose the last message sender.
+	IncludeSender bool `fig:"include_sender"`
+
+	// IncludeBody tells whether notification summaries should disclose the last message body.
+	IncludeBody bool `fig:"include_body"`
+}
+
+// Push represents a push notifications (XEP-0357) module type.
+//
+// Notifications are published to the registered app servers whenever a message is sent over a
+// hibernated stream (XEP-0198) or archived into offline storage.
+type Push struct {
+	cfg    Config
+	router router.Router
+	rep    repository.Repository
+	hk     *hook.Hooks
+	logger kitlog.Logger
+
+	mu         sync.Mutex
+	hibernated map[string]struct{}
+	counts     map[string]int
+}
+
+// New returns a new initialized Push instance.
+func New(
+	cfg Config,
+	router router.Router,
+	rep repository.Repository,
+	hk *hook.Hooks,
+	logger kitlog.Logger,
+) *Push {
+	return &Push{
+		cfg:        cfg,
+		router:     router,
+		rep:        rep,
+		hk:         hk,
+		logger:     kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+		hibernated: make(map[string]struct{}),
+		counts:     make(map[string]int),
+	}
+}
+
+// Name returns push module name.
+func (m *Push) Name() string { return ModuleName }
+
+// StreamFeature returns push module stream feature.
+func (m *Push) StreamFeature(_ context.Context, _ string) (stravaganza.Element, error) {
+	return nil, nil
+}
+
+// ServerFeatures returns push server disco features.
+func (m *Push) ServerFeatures(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// AccountFeatures returns push account disco features.
+func (m *Push) AccountFeatures(_ context.Context) ([]string, error) {
+	return []string{pushNamespace}, nil
+}
+
+// MatchesNamespace tells whether namespace matches push module.
+func (m *Push) MatchesNamespace(namespace string, serverTarget bool) bool {
+	if serverTarget {
+		return false
+	}
+	return namespace == pushNamespace
+}
+
+// ProcessIQ process a push iq.
+func (m *Push) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	fromJID := iq.FromJID()
+	toJID := iq.ToJID()
+
+	if !fromJID.MatchesWithOptions(toJID, jid.MatchesBare) {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return nil
+	}
+	if !iq.IsSet() {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	switch {
+	case iq.ChildNamespace("enable", pushNamespace) != nil:
+		return m.enable(ctx, iq)
+	case iq.ChildNamespace("disable", pushNamespace) != nil:
+		return m.disable(ctx, iq)
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+	return nil
+}
+
+// Start starts push module.
+func (m *Push) Start(_ context.Context) error {
+	m.hk.AddHook(hook.C2SStreamBinded, m.onBinded, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamHibernated, m.onHibernated, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamTerminated, m.onTerminated, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamElementSent, m.onElementSent, hook.DefaultPriority)
+	m.hk.AddHook(hook.OfflineMessageArchived, m.onOfflineMessageArchived, hook.DefaultPriority)
+	m.hk.AddHook(hook.UserDeleted, m.onUserDeleted, hook.DefaultPriority)
+
+	level.Info(m.logger).Log("msg", "started push module")
+	return nil
+}
+
+// Stop stops push module.
+func (m *Push) Stop(_ context.Context) error {
+	m.hk.RemoveHook(hook.C2SStreamBinded, m.onBinded)
+	m.hk.RemoveHook(hook.C2SStreamHibernated, m.onHibernated)
+	m.hk.RemoveHook(hook.C2SStreamTerminated, m.onTerminated)
+	m.hk.RemoveHook(hook.C2SStreamElementSent, m.onElementSent)
+	m.hk.RemoveHook(hook.OfflineMessageArchived, m.onOfflineMessageArchived)
+	m.hk.RemoveHook(hook.UserDeleted, m.onUserDeleted)
+
+	level.Info(m.logger).Log("msg", "stopped push module")
+	return nil
+}
+
+func (m *Push) enable(ctx context.Context, iq *stravaganza.IQ) error {
+	enable := iq.ChildNamespace("enable", pushNamespace)
+
+	appServerJID, err := jid.NewWithString(enable.Attribute("jid"), false)
+	node := enable.Attribute("node")
+	if err != nil || len(node) == 0 {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	var publishOpts *stravaganza.PBElement
+	if x := enable.ChildNamespace("x", xep0004.FormNamespace); x != nil {
+		form, err := xep0004.NewFormFromElement(x)
+		if err != nil || form.Type != xep0004.Submit || form.Fields.ValueForFieldOfType(xep0004.FormType, xep0004.Hidden) != publishOptionsFormType {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+			return nil
+		}
+		publishOpts = x.Proto()
+	}
+	fromJID := iq.FromJID()
+	err = m.rep.UpsertPushRegistration(ctx, &pushmodel.Registration{
+		Username:       fromJID.Node(),
+		Resource:       fromJID.Resource(),
+		Jid:            appServerJID.String(),
+		Node:           node,
+		PublishOptions: publishOpts,
+	})
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+
+	level.Info(m.logger).Log("msg", "push notifications enabled", "jid", fromJID.String(), "app_server", appServerJID.String())
+	return nil
+}
+
+func (m *Push) disable(ctx context.Context, iq *stravaganza.IQ) error {
+	disable := iq.ChildNamespace("disable", pushNamespace)
+
+	appServerJID, err := jid.NewWithString(disable.Attribute("jid"), false)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	node := disable.Attribute("node")
+
+	fromJID := iq.FromJID()
+	regs, err := m.rep.FetchPushRegistrations(ctx, fromJID.Node())
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	for _, reg := range regs {
+		if reg.Jid != appServerJID.String() || (len(node) > 0 && reg.Node != node) {
+			continue
+		}
+		if err := m.rep.DeletePushRegistration(ctx, reg.Username, reg.Jid, reg.Node); err != nil {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+			return err
+		}
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+
+	level.Info(m.logger).Log("msg", "push notifications disabled", "jid", fromJID.String(), "app_server", appServerJID.String())
+	return nil
+}
+
+func (m *Push) onBinded(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+	if inf.JID == nil {
+		return nil
+	}
+	// client is back, reset pending notifications count
+	m.mu.Lock()
+	delete(m.counts, countKey(inf.JID.Node(), inf.JID.Resource()))
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Push) onHibernated(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+
+	m.mu.Lock()
+	m.hibernated[inf.ID] = struct{}{}
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Push) onTerminated(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+
+	m.mu.Lock()
+	delete(m.hibernated, inf.ID)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Push) onElementSent(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+
+	msg, ok := inf.Element.(*stravaganza.Message)
+	if !ok || !isPushable(msg) || inf.JID == nil {
+		return nil
+	}
+	m.mu.Lock()
+	_, isHibernated := m.hibernated[inf.ID]
+	m.mu.Unlock()
+
+	if !isHibernated {
+		return nil
+	}
+	return m.notify(execCtx.Context, inf.JID.ToBareJID(), inf.JID.Resource(), msg)
+}
+
+func (m *Push) onOfflineMessageArchived(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.OfflineInfo)
+	if !isPushable(inf.Message) {
+		return nil
+	}
+	return m.notify(execCtx.Context, inf.Message.ToJID().ToBareJID(), "", inf.Message)
+}
+
+func (m *Push) onUserDeleted(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.UserInfo)
+	return m.rep.DeletePushRegistrations(execCtx.Context, inf.Username)
+}
+
+// notify sends a push notification to every app server registered by account.
+// In case resource is not empty only the registrations enabled by that resource will be notified.
+func (m *Push) notify(ctx context.Context, account *jid.JID, resource string, msg *stravaganza.Message) error {
+	regs, err := m.rep.FetchPushRegistrations(ctx, account.Node())
+	if err != nil {
+		return err
+	}
+	for _, reg := range regs {
+		if len(resource) > 0 && reg.Resource != resource {
+			continue
+		}
+		m.mu.Lock()
+		ck := countKey(reg.Username, reg.Resource)
+		m.counts[ck]++
+		count := m.counts[ck]
+		m.mu.Unlock()
+
+		iq, err := m.notificationIQ(account, reg, count, msg)
+		if err != nil {
+			return err
+		}
+		_, _ = m.router.Route(ctx, iq)
+
+		level.Info(m.logger).Log("msg", "push notification sent", "jid", account.String(), "app_server", reg.Jid)
+	}
+	return nil
+}
+
+func (m *Push) notificationIQ(account *jid.JID, reg *pushmodel.Registration, count int, msg *stravaganza.Message) (*stravaganza.IQ, error) {
+	fields := []xep0004.Field{
+		{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{summaryFormType}},
+		{Var: "message-count", Values: []string{strconv.Itoa(count)}},
+	}
+	if m.cfg.IncludeSender {
+		fields = append(fields, xep0004.Field{Var: "last-message-sender", Values: []string{msg.FromJID().String()}})
+	}
+	if m.cfg.IncludeBody {
+		fields = append(fields, xep0004.Field{Var: "last-message-body", Values: []string{msg.Child("body").Text()}})
+	}
+	summary := xep0004.DataForm{Type: xep0004.Submit, Fields: fields}
+
+	pubSubBuilder := stravaganza.NewBuilder("pubsub").
+		WithAttribute(stravaganza.Namespace, pubSubNamespace).
+		WithChild(
+			stravaganza.NewBuilder("publish").
+				WithAttribute("node", reg.Node).
+				WithChild(
+					stravaganza.NewBuilder("item").
+						WithChild(
+							stravaganza.NewBuilder("notification").
+								WithAttribute(stravaganza.Namespace, pushNamespace).
+								WithChild(summary.Element()).
+								Build(),
+						).
+						Build(),
+				).
+				Build(),
+		)
+	if reg.PublishOptions != nil {
+		pubSubBuilder.WithChild(
+			stravaganza.NewBuilder("publish-options").
+				WithChild(stravaganza.NewBuilderFromProto(reg.PublishOptions).Build()).
+				Build(),
+		)
+	}
+	return stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.From, account.String()).
+		WithAttribute(stravaganza.To, reg.Jid).
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(pubSubBuilder.Build()).
+		BuildIQ()
+}
+
+// isPushable tells whether msg should trigger a push notification.
+func isPushable(msg *stravaganza.Message) bool {
+	if !msg.IsNormal() && !msg.IsChat() {
+		return false
+	}
+	return msg.IsMessageWithBody()
+}
+
+func countKey(username, resource string) string {
+	return username + "/" + resource
+}
@@ -0,0 +1,384 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0357
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/component"
+	"github.com/ortuman/jackal/pkg/hook"
+	pushmodel "github.com/ortuman/jackal/pkg/model/push"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/stretchr/testify/require"
+)
+
+// testAppServer is a stand-in push app server exposed as a local pubsub component.
+type testAppServer struct {
+	mu            sync.Mutex
+	notifications []*stravaganza.IQ
+}
+
+var _ component.Component = (*testAppServer)(nil)
+
+func (s *testAppServer) Host() string { return "push.jackal.im" }
+func (s *testAppServer) Name() string { return "push" }
+
+func (s *testAppServer) ProcessStanza(_ context.Context, stanza stravaganza.Stanza) error {
+	iq, ok := stanza.(*stravaganza.IQ)
+	if !ok || !iq.IsSet() {
+		return nil
+	}
+	if iq.ChildNamespace("pubsub", pubSubNamespace) == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.notifications = append(s.notifications, iq)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *testAppServer) Start(_ context.Context) error { return nil }
+func (s *testAppServer) Stop(_ context.Context) error  { return nil }
+
+func (s *testAppServer) flush() []*stravaganza.IQ {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := s.notifications
+	s.notifications = nil
+	return ret
+}
+
+type testRouter struct {
+	appSrv *testAppServer
+
+	mu      sync.Mutex
+	stanzas []stravaganza.Stanza
+}
+
+func (r *testRouter) route(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+	if stanza.ToJID().Domain() == r.appSrv.Host() {
+		return nil, r.appSrv.ProcessStanza(ctx, stanza)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stanzas = append(r.stanzas, stanza)
+	return nil, nil
+}
+
+func (r *testRouter) flush() []stravaganza.Stanza {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := r.stanzas
+	r.stanzas = nil
+	return ret
+}
+
+func TestPush_Enable(t *testing.T) {
+	// given
+	m, rtr, _, repMock := testPush(Config{})
+
+	var stored *pushmodel.Registration
+	repMock.UpsertPushRegistrationFunc = func(ctx context.Context, reg *pushmodel.Registration) error {
+		stored = reg
+		return nil
+	}
+	iq := testEnableIQ(
+		(&xep0004.DataForm{
+			Type: xep0004.Submit,
+			Fields: []xep0004.Field{
+				{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{publishOptionsFormType}},
+				{Var: "secret", Values: []string{"eruio234vzxc2kla-91"}},
+			},
+		}).Element(),
+	)
+
+	// when
+	err := m.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.NoError(t, err)
+
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+
+	require.NotNil(t, stored)
+	require.Equal(t, "ortuman", stored.Username)
+	require.Equal(t, "yard", stored.Resource)
+	require.Equal(t, "push.jackal.im", stored.Jid)
+	require.Equal(t, "yxs32uqsflafdk3iuqo", stored.Node)
+	require.NotNil(t, stored.PublishOptions)
+}
+
+func TestPush_EnableInvalidForm(t *testing.T) {
+	// given
+	m, rtr, _, repMock := testPush(Config{})
+
+	iq := testEnableIQ(
+		(&xep0004.DataForm{
+			Type: xep0004.Submit,
+			Fields: []xep0004.Field{
+				{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{"urn:xmpp:foo"}},
+			},
+		}).Element(),
+	)
+
+	// when
+	err := m.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.NoError(t, err)
+
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, stanzas[0].Type())
+	require.NotNil(t, stanzas[0].Child("error").Child("bad-request"))
+	require.Len(t, repMock.UpsertPushRegistrationCalls(), 0)
+}
+
+func TestPush_Disable(t *testing.T) {
+	// given
+	m, rtr, _, repMock := testPush(Config{})
+
+	repMock.FetchPushRegistrationsFunc = func(ctx context.Context, username string) ([]*pushmodel.Registration, error) {
+		return []*pushmodel.Registration{
+			{Username: "ortuman", Resource: "yard", Jid: "push.jackal.im", Node: "n1"},
+			{Username: "ortuman", Resource: "balcony", Jid: "push.jackal.im", Node: "n2"},
+			{Username: "ortuman", Resource: "balcony", Jid: "push.jabber.org", Node: "n3"},
+		}, nil
+	}
+	var deleted []string
+	repMock.DeletePushRegistrationFunc = func(ctx context.Context, username, jid, node string) error {
+		deleted = append(deleted, node)
+		return nil
+	}
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "disable-1").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("disable").
+				WithAttribute(stravaganza.Namespace, pushNamespace).
+				WithAttribute("jid", "push.jackal.im").
+				Build(),
+		).
+		BuildIQ()
+
+	// when
+	err := m.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.NoError(t, err)
+
+	stanzas := rtr.flush()
+	require.Len(t, stanzas, 1)
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Type())
+	require.Equal(t, []string{"n1", "n2"}, deleted)
+}
+
+func TestPush_NotifyHibernatedStream(t *testing.T) {
+	// given
+	m, _, appSrv, repMock := testPush(Config{})
+
+	repMock.FetchPushRegistrationsFunc = func(ctx context.Context, username string) ([]*pushmodel.Registration, error) {
+		return []*pushmodel.Registration{
+			{Username: "ortuman", Resource: "yard", Jid: "push.jackal.im", Node: "n1"},
+			{Username: "ortuman", Resource: "balcony", Jid: "push.jackal.im", Node: "n2"},
+		}, nil
+	}
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	yardJID, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+	balconyJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+
+	_, _ = m.hk.Run(hook.C2SStreamHibernated, &hook.ExecutionContext{
+		Info:    &hook.C2SStreamInfo{ID: "c2s:1", JID: yardJID},
+		Context: context.Background(),
+	})
+
+	// when
+	sendMsg := func(id string, jd *jid.JID) {
+		_, _ = m.hk.Run(hook.C2SStreamElementSent, &hook.ExecutionContext{
+			Info:    &hook.C2SStreamInfo{ID: id, JID: jd, Element: testMessage()},
+			Context: context.Background(),
+		})
+	}
+	sendMsg("c2s:1", yardJID)
+	sendMsg("c2s:1", yardJID)
+	sendMsg("c2s:2", balconyJID) // not hibernated
+	beforeBind := appSrv.flush()
+
+	_, _ = m.hk.Run(hook.C2SStreamBinded, &hook.ExecutionContext{
+		Info:    &hook.C2SStreamInfo{ID: "c2s:3", JID: yardJID},
+		Context: context.Background(),
+	})
+	sendMsg("c2s:1", yardJID)
+	afterBind := appSrv.flush()
+
+	_, _ = m.hk.Run(hook.C2SStreamTerminated, &hook.ExecutionContext{
+		Info:    &hook.C2SStreamInfo{ID: "c2s:1", JID: yardJID},
+		Context: context.Background(),
+	})
+	sendMsg("c2s:1", yardJID)
+	afterTerminate := appSrv.flush()
+
+	// then
+	require.Len(t, beforeBind, 2)
+	require.Equal(t, "ortuman@jackal.im", beforeBind[0].FromJID().String())
+	require.Equal(t, "n1", beforeBind[0].ChildNamespace("pubsub", pubSubNamespace).Child("publish").Attribute("node"))
+	require.Equal(t, "1", testSummaryField(t, beforeBind[0], "message-count"))
+	require.Equal(t, "2", testSummaryField(t, beforeBind[1], "message-count"))
+
+	require.Len(t, afterBind, 1)
+	require.Equal(t, "1", testSummaryField(t, afterBind[0], "message-count"))
+
+	require.Len(t, afterTerminate, 0)
+}
+
+func TestPush_NotifyOfflineMessage(t *testing.T) {
+	// given
+	m, _, appSrv, repMock := testPush(Config{IncludeSender: true, IncludeBody: true})
+
+	publishOpts := (&xep0004.DataForm{
+		Type: xep0004.Submit,
+		Fields: []xep0004.Field{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{publishOptionsFormType}},
+			{Var: "secret", Values: []string{"eruio234vzxc2kla-91"}},
+		},
+	}).Element()
+
+	repMock.FetchPushRegistrationsFunc = func(ctx context.Context, username string) ([]*pushmodel.Registration, error) {
+		return []*pushmodel.Registration{
+			{Username: "ortuman", Resource: "yard", Jid: "push.jackal.im", Node: "n1", PublishOptions: publishOpts.Proto()},
+			{Username: "ortuman", Resource: "balcony", Jid: "push.jackal.im", Node: "n2"},
+		}, nil
+	}
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	// when
+	_, _ = m.hk.Run(hook.OfflineMessageArchived, &hook.ExecutionContext{
+		Info:    &hook.OfflineInfo{Username: "ortuman", Message: testMessage()},
+		Context: context.Background(),
+	})
+
+	// then
+	notifications := appSrv.flush()
+	require.Len(t, notifications, 2)
+
+	require.Equal(t, "noelia@jackal.im/balcony", testSummaryField(t, notifications[0], "last-message-sender"))
+	require.Equal(t, "Wherefore art thou, Romeo?", testSummaryField(t, notifications[0], "last-message-body"))
+
+	opts := notifications[0].ChildNamespace("pubsub", pubSubNamespace).Child("publish-options")
+	require.NotNil(t, opts)
+	form, err := xep0004.NewFormFromElement(opts.ChildNamespace("x", xep0004.FormNamespace))
+	require.NoError(t, err)
+	require.Equal(t, "eruio234vzxc2kla-91", form.Fields.ValueForField("secret"))
+
+	require.Nil(t, notifications[1].ChildNamespace("pubsub", pubSubNamespace).Child("publish-options"))
+}
+
+func TestPush_UserDeleted(t *testing.T) {
+	// given
+	m, _, _, repMock := testPush(Config{})
+	repMock.DeletePushRegistrationsFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	// when
+	_, _ = m.hk.Run(hook.UserDeleted, &hook.ExecutionContext{
+		Info:    &hook.UserInfo{Username: "ortuman"},
+		Context: context.Background(),
+	})
+
+	// then
+	require.Len(t, repMock.DeletePushRegistrationsCalls(), 1)
+	require.Equal(t, "ortuman", repMock.DeletePushRegistrationsCalls()[0].Username)
+}
+
+func testPush(cfg Config) (*Push, *testRouter, *testAppServer, *repositoryMock) {
+	appSrv := &testAppServer{}
+	rtr := &testRouter{appSrv: appSrv}
+
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = rtr.route
+
+	repMock := &repositoryMock{}
+
+	m := &Push{
+		cfg:        cfg,
+		router:     routerMock,
+		rep:        repMock,
+		hk:         hook.NewHooks(),
+		logger:     kitlog.NewNopLogger(),
+		hibernated: make(map[string]struct{}),
+		counts:     make(map[string]int),
+	}
+	return m, rtr, appSrv, repMock
+}
+
+func testEnableIQ(form stravaganza.Element) *stravaganza.IQ {
+	eb := stravaganza.NewBuilder("enable").
+		WithAttribute(stravaganza.Namespace, pushNamespace).
+		WithAttribute("jid", "push.jackal.im").
+		WithAttribute("node", "yxs32uqsflafdk3iuqo")
+	if form != nil {
+		eb.WithChild(form)
+	}
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "enable-1").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(eb.Build()).
+		BuildIQ()
+	return iq
+}
+
+func testMessage() *stravaganza.Message {
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.Type, stravaganza.ChatType).
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("Wherefore art thou, Romeo?").
+				Build(),
+		).
+		BuildMessage()
+	return msg
+}
+
+func testSummaryField(t *testing.T, iq *stravaganza.IQ, field string) string {
+	notification := iq.ChildNamespace("pubsub", pubSubNamespace).
+		Child("publish").
+		Child("item").
+		ChildNamespace("notification", pushNamespace)
+	require.NotNil(t, notification)
+
+	form, err := xep0004.NewFormFromElement(notification.ChildNamespace("x", xep0004.FormNamespace))
+	require.NoError(t, err)
+	require.Equal(t, summaryFormType, form.Fields.ValueForFieldOfType(xep0004.FormType, xep0004.Hidden))
+	return form.Fields.ValueForField(field)
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0401
+
+import (
+	"context"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	inviteNamespace   = "urn:xmpp:invite"
+	commandsNamespace = "http://jabber.org/protocol/commands"
+
+	inviteCommandNode = "urn:xmpp:invite#invite"
+)
+
+const (
+	// ModuleName represents invite module name.
+	ModuleName = "invite"
+
+	// XEPNumber represents invite XEP number.
+	XEPNumber = "0401"
+)
+
+// Config contains invite module configuration options.
+type Config struct {
+	// Expiry defines the validity period of user issued invites.
+	Expiry time.Duration `fig:"expiry" default:"168h"`
+
+	// MaxUses defines the number of accounts that can be registered by means of a user issued invite.
+	MaxUses int `fig:"max_uses" default:"1"`
+}
+
+// Invite represents an easy user onboarding (XEP-0401) module type.
+type Invite struct {
+	cfg     Config
+	invites *Invites
+	router  router.Router
+	rep     repository.Repository
+	hk      *hook.Hooks
+	logger  kitlog.Logger
+}
+
+// New returns a new initialized Invite instance.
+func New(
+	cfg Config,
+	router router.Router,
+	rep repository.Repository,
+	hk *hook.Hooks,
+	logger kitlog.Logger,
+) *Invite {
+	return &Invite{
+		cfg:     cfg,
+		invites: NewInvites(rep),
+		router:  router,
+		rep:     rep,
+		hk:      hk,
+		logger:  kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+	}
+}
+
+// Name returns invite module name.
+func (m *Invite) Name() string { return ModuleName }
+
+// StreamFeature returns invite module stream feature.
+func (m *Invite) StreamFeature(_ context.Context, _ string) (stravaganza.Element, error) {
+	return nil, nil
+}
+
+// ServerFeatures returns invite server disco features.
+func (m *Invite) ServerFeatures(_ context.Context) ([]string, error) {
+	return []string{inviteNamespace, commandsNamespace}, nil
+}
+
+// AccountFeatures returns invite account disco features.
+func (m *Invite) AccountFeatures(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// MatchesNamespace tells whether namespace matches invite module.
+func (m *Invite) MatchesNamespace(namespace string, serverTarget bool) bool {
+	if !serverTarget {
+		return false
+	}
+	return namespace == commandsNamespace
+}
+
+// ProcessIQ process an invite command iq.
+func (m *Invite) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	cmd := iq.ChildNamespace("command", commandsNamespace)
+	switch {
+	case cmd == nil || !iq.IsSet():
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+
+	case cmd.Attribute("node") != inviteCommandNode:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
+		return nil
+	}
+	switch cmd.Attribute("action") {
+	case "", "execute", "complete":
+		return m.createInvite(ctx, iq)
+
+	case "cancel":
+		_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, commandElement(cmd.Attribute("sessionid"), "canceled", nil)))
+		return nil
+
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+}
+
+// Start starts invite module.
+func (m *Invite) Start(_ context.Context) error {
+	m.hk.AddHook(hook.UserDeleted, m.onUserDeleted, hook.DefaultPriority)
+
+	level.Info(m.logger).Log("msg", "started invite module")
+	return nil
+}
+
+// Stop stops invite module.
+func (m *Invite) Stop(_ context.Context) error {
+	m.hk.RemoveHook(hook.UserDeleted, m.onUserDeleted)
+
+	level.Info(m.logger).Log("msg", "stopped invite module")
+	return nil
+}
+
+func (m *Invite) onUserDeleted(execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.UserInfo)
+	return m.rep.DeleteInvites(execCtx.Context, inf.Username)
+}
+
+func (m *Invite) createInvite(ctx context.Context, iq *stravaganza.IQ) error {
+	fromJID := iq.FromJID()
+
+	inv, err := m.invites.Issue(ctx, fromJID.Domain(), fromJID.Node(), m.cfg.MaxUses, m.cfg.Expiry)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	form := xep0004.DataForm{
+		Type: xep0004.Result,
+		Fields: xep0004.Fields{
+			{Var: "uri", Label: "Invite URI", Values: []string{URI(inv)}},
+			{Var: "expire", Label: "Invite expiration", Values: []string{inv.ExpiresAt.AsTime().UTC().Format(time.RFC3339)}},
+		},
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, commandElement(uuid.New().String(), "completed", form.Element())))
+
+	level.Info(m.logger).Log("msg", "invite created", "username", fromJID.Node())
+	return nil
+}
+
+func commandElement(sessionID, status string, form stravaganza.Element) stravaganza.Element {
+	b := stravaganza.NewBuilder("command").
+		WithAttribute(stravaganza.Namespace, commandsNamespace).
+		WithAttribute("node", inviteCommandNode).
+		WithAttribute("sessionid", sessionID).
+		WithAttribute("status", status)
+	if form != nil {
+		b.WithChild(form)
+	}
+	return b.Build()
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0401
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvite_CreateInvite(t *testing.T) {
+	// given
+	var upserted *invitemodel.Invite
+
+	repMock := &repositoryMock{}
+	repMock.UpsertInviteFunc = func(ctx context.Context, inv *invitemodel.Invite) error {
+		upserted = inv
+		return nil
+	}
+	var respStanzas []stravaganza.Stanza
+
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m := New(Config{Expiry: time.Hour, MaxUses: 1}, routerMock, repMock, hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	err := m.ProcessIQ(context.Background(), testCommandIQ(inviteCommandNode))
+
+	// then
+	require.Nil(t, err)
+
+	require.NotNil(t, upserted)
+	require.Equal(t, "ortuman", upserted.Inviter)
+	require.Equal(t, "jackal.im", upserted.Domain)
+	require.Equal(t, int32(1), upserted.MaxUses)
+
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Attribute(stravaganza.Type))
+
+	cmd := respStanzas[0].ChildNamespace("command", commandsNamespace)
+	require.NotNil(t, cmd)
+	require.Equal(t, "completed", cmd.Attribute("status"))
+
+	form, err := xep0004.NewFormFromElement(cmd.ChildNamespace("x", xep0004.FormNamespace))
+	require.Nil(t, err)
+	require.Equal(t, URI(upserted), form.Fields.ValueForField("uri"))
+}
+
+func TestInvite_UnknownCommand(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+
+	var respStanzas []stravaganza.Stanza
+
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m := New(Config{}, routerMock, repMock, hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	err := m.ProcessIQ(context.Background(), testCommandIQ("urn:xmpp:invite#create-account"))
+
+	// then
+	require.Nil(t, err)
+
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, respStanzas[0].Attribute(stravaganza.Type))
+	require.NotNil(t, respStanzas[0].Child("error").Child("item-not-found"))
+
+	require.Len(t, repMock.UpsertInviteCalls(), 0)
+}
+
+func TestInvite_UserDeleted(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteInvitesFunc = func(ctx context.Context, inviter string) error {
+		return nil
+	}
+	hk := hook.NewHooks()
+	m := New(Config{}, nil, repMock, hk, kitlog.NewNopLogger())
+
+	// when
+	_ = m.Start(context.Background())
+	_, err := hk.Run(hook.UserDeleted, &hook.ExecutionContext{
+		Info:    &hook.UserInfo{Username: "ortuman"},
+		Context: context.Background(),
+	})
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, repMock.DeleteInvitesCalls(), 1)
+	require.Equal(t, "ortuman", repMock.DeleteInvitesCalls()[0].Inviter)
+}
+
+func testCommandIQ(node string) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "cmd1").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/balcony").
+		WithAttribute(stravaganza.To, "jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("command").
+				WithAttribute(stravaganza.Namespace, commandsNamespace).
+				WithAttribute("node", node).
+				WithAttribute("action", "execute").
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
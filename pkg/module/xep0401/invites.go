@@ -0,0 +1,139 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0401
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const inviteTokenSize = 24
+
+// Invites handles invite token issuance and redemption.
+type Invites struct {
+	rep repository.Repository
+}
+
+// NewInvites returns a new Invites instance.
+func NewInvites(rep repository.Repository) *Invites {
+	return &Invites{rep: rep}
+}
+
+// Issue generates and stores a new invite token for domain.
+// An empty inviter means the invite is issued on behalf of the server administrator,
+// while a zero maxUses value allows the invite to be used an unlimited number of times until it expires.
+func (i *Invites) Issue(ctx context.Context, domain, inviter string, maxUses int, expiry time.Duration) (*invitemodel.Invite, error) {
+	token, err := generateInviteToken()
+	if err != nil {
+		return nil, err
+	}
+	inv := &invitemodel.Invite{
+		Token:     token,
+		Domain:    domain,
+		Inviter:   inviter,
+		MaxUses:   int32(maxUses),
+		ExpiresAt: timestamppb.New(time.Now().Add(expiry)),
+	}
+	if err := i.rep.UpsertInvite(ctx, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Fetch returns the invite associated to token in case it can still be redeemed.
+// A nil invite is returned otherwise.
+func (i *Invites) Fetch(ctx context.Context, token string) (*invitemodel.Invite, error) {
+	if len(token) == 0 {
+		return nil, nil
+	}
+	inv, err := i.rep.FetchInvite(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !isRedeemable(inv, time.Now()) {
+		return nil, nil
+	}
+	return inv, nil
+}
+
+// Redeem consumes one use of the invite associated to token, removing it once exhausted.
+// A nil invite is returned in case the token cannot be redeemed.
+func (i *Invites) Redeem(ctx context.Context, token string) (*invitemodel.Invite, error) {
+	var inv *invitemodel.Invite
+	err := i.rep.InTransaction(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		var err error
+		inv, err = i.RedeemTx(ctx, tx, token)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// RedeemTx behaves like Redeem, but within tx transaction, so that the invite use gets spent
+// only if the rest of tx operations succeed.
+func (i *Invites) RedeemTx(ctx context.Context, tx repository.Transaction, token string) (*invitemodel.Invite, error) {
+	stored, err := tx.FetchInvite(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !isRedeemable(stored, time.Now()) {
+		return nil, nil
+	}
+	stored.Uses++
+
+	if stored.MaxUses > 0 && stored.Uses >= stored.MaxUses {
+		err = tx.DeleteInvite(ctx, token)
+	} else {
+		err = tx.UpsertInvite(ctx, stored)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// URI returns the XMPP URI (XEP-0401) associated to inv invite.
+func URI(inv *invitemodel.Invite) string {
+	token := url.QueryEscape(inv.Token)
+	if len(inv.Inviter) == 0 {
+		return fmt.Sprintf("xmpp:%s?register;preauth=%s", inv.Domain, token)
+	}
+	return fmt.Sprintf("xmpp:%s@%s?roster;preauth=%s;ibr=y", inv.Inviter, inv.Domain, token)
+}
+
+func generateInviteToken() (string, error) {
+	b := make([]byte, inviteTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func isRedeemable(inv *invitemodel.Invite, now time.Time) bool {
+	if inv == nil || inv.ExpiresAt == nil || !now.Before(inv.ExpiresAt.AsTime()) {
+		return false
+	}
+	return inv.MaxUses <= 0 || inv.Uses < inv.MaxUses
+}
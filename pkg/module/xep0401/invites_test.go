@@ -0,0 +1,131 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0401
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestInvites_Issue(t *testing.T) {
+	// given
+	var upserted *invitemodel.Invite
+
+	repMock := &repositoryMock{}
+	repMock.UpsertInviteFunc = func(ctx context.Context, inv *invitemodel.Invite) error {
+		upserted = inv
+		return nil
+	}
+	invites := NewInvites(repMock)
+
+	// when
+	inv, err := invites.Issue(context.Background(), "jackal.im", "ortuman", 1, time.Hour)
+
+	// then
+	require.Nil(t, err)
+	require.NotNil(t, inv)
+	require.Equal(t, inv, upserted)
+
+	require.NotEmpty(t, inv.Token)
+	require.Equal(t, "jackal.im", inv.Domain)
+	require.Equal(t, "ortuman", inv.Inviter)
+	require.Equal(t, int32(1), inv.MaxUses)
+	require.True(t, inv.ExpiresAt.AsTime().After(time.Now()))
+}
+
+func TestInvites_Redeem(t *testing.T) {
+	var tcs = map[string]struct {
+		invite       *invitemodel.Invite
+		redeemed     bool
+		expectUpsert bool
+		expectDelete bool
+	}{
+		"single use": {
+			invite:       &invitemodel.Invite{Token: "t0k3n", MaxUses: 1, ExpiresAt: timestamppb.New(time.Now().Add(time.Hour))},
+			redeemed:     true,
+			expectDelete: true,
+		},
+		"multiple use": {
+			invite:       &invitemodel.Invite{Token: "t0k3n", MaxUses: 3, Uses: 1, ExpiresAt: timestamppb.New(time.Now().Add(time.Hour))},
+			redeemed:     true,
+			expectUpsert: true,
+		},
+		"unlimited use": {
+			invite:       &invitemodel.Invite{Token: "t0k3n", Uses: 10, ExpiresAt: timestamppb.New(time.Now().Add(time.Hour))},
+			redeemed:     true,
+			expectUpsert: true,
+		},
+		"exhausted": {
+			invite: &invitemodel.Invite{Token: "t0k3n", MaxUses: 2, Uses: 2, ExpiresAt: timestamppb.New(time.Now().Add(time.Hour))},
+		},
+		"expired": {
+			invite: &invitemodel.Invite{Token: "t0k3n", MaxUses: 1, ExpiresAt: timestamppb.New(time.Now().Add(-time.Hour))},
+		},
+		"not found": {},
+	}
+	for tName, tc := range tcs {
+		t.Run(tName, func(t *testing.T) {
+			// given
+			repMock := &repositoryMock{}
+			repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+				return f(ctx, repMock)
+			}
+			repMock.FetchInviteFunc = func(ctx context.Context, token string) (*invitemodel.Invite, error) {
+				return tc.invite, nil
+			}
+			repMock.UpsertInviteFunc = func(ctx context.Context, inv *invitemodel.Invite) error {
+				return nil
+			}
+			repMock.DeleteInviteFunc = func(ctx context.Context, token string) error {
+				return nil
+			}
+			invites := NewInvites(repMock)
+
+			// when
+			inv, err := invites.Redeem(context.Background(), "t0k3n")
+
+			// then
+			require.Nil(t, err)
+			require.Equal(t, tc.redeemed, inv != nil)
+
+			var expectedUpserts, expectedDeletes int
+			if tc.expectUpsert {
+				expectedUpserts = 1
+			}
+			if tc.expectDelete {
+				expectedDeletes = 1
+			}
+			require.Len(t, repMock.UpsertInviteCalls(), expectedUpserts)
+			require.Len(t, repMock.DeleteInviteCalls(), expectedDeletes)
+		})
+	}
+}
+
+func TestInvites_URI(t *testing.T) {
+	require.Equal(t,
+		"xmpp:jackal.im?register;preauth=t0k3n",
+		URI(&invitemodel.Invite{Token: "t0k3n", Domain: "jackal.im"}),
+	)
+	require.Equal(t,
+		"xmpp:ortuman@jackal.im?roster;preauth=t0k3n;ibr=y",
+		URI(&invitemodel.Invite{Token: "t0k3n", Domain: "jackal.im", Inviter: "ortuman"}),
+	)
+}
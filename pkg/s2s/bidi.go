@@ -0,0 +1,122 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"context"
+
+	kitlog "github.com/go-kit/log"
+
+	"github.com/jackal-xmpp/runqueue/v2"
+	"github.com/jackal-xmpp/stravaganza"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/router/stream"
+)
+
+// bidiOut exposes the outgoing direction of an incoming stream
+// for which bidirectionality has been negotiated (XEP-0288).
+type bidiOut struct {
+	id stream.S2SOutID
+	in *inS2S
+}
+
+func newBidiOut(in *inS2S, sender, target string) *bidiOut {
+	return &bidiOut{
+		id: stream.S2SOutID{Sender: sender, Target: target},
+		in: in,
+	}
+}
+
+func (b *bidiOut) ID() stream.S2SOutID {
+	return b.id
+}
+
+func (b *bidiOut) SendElement(elem stravaganza.Element) <-chan error {
+	errCh := make(chan error, 1)
+	b.in.rq.Run(func() {
+		ctx, cancel := b.in.requestContext()
+		defer cancel()
+
+		if b.in.getState() == inDisconnected {
+			errCh <- nil
+			return
+		}
+		if err := b.in.sendElement(ctx, elem); err != nil {
+			errCh <- err
+			return
+		}
+		_, err := b.in.hk.Run(hook.S2SOutStreamElementSent, &hook.ExecutionContext{
+			Info: &hook.S2SStreamInfo{
+				ID:      b.id.String(),
+				Sender:  b.id.Sender,
+				Target:  b.id.Target,
+				Element: elem,
+			},
+			Sender:  b,
+			Context: ctx,
+		})
+		errCh <- err
+	})
+	return errCh
+}
+
+func (b *bidiOut) Disconnect(streamErr *streamerror.Error) <-chan error {
+	return b.in.Disconnect(streamErr)
+}
+
+func (b *bidiOut) dial(_ context.Context) error { return nil }
+
+func (b *bidiOut) start() error { return nil }
+
+func (b *bidiOut) ready() bool { return true }
+
+// newBidiInS2S returns an incoming stream used to process the stanzas received over
+// a bidirectional outgoing stream (XEP-0288).
+func (l *SocketListener) newBidiInS2S(out *outS2S) *inS2S {
+	id := nextStreamID()
+
+	stm := &inS2S{
+		id: id,
+		cfg: inConfig{
+			reqTimeout:    l.cfg.RequestTimeout,
+			maxStanzaSize: l.cfg.MaxStanzaSize,
+		},
+		tr:          out.tr,
+		session:     out.session,
+		hosts:       l.hosts,
+		router:      l.router,
+		comps:       l.comps,
+		mods:        l.mods,
+		outProvider: l.outProvider,
+		inHub:       l.inHUB,
+		kv:          l.kv,
+		shapers:     l.shapers,
+		hk:          l.hk,
+		policy:      l.policy,
+		certVrf:     l.certVrf,
+		logger:      kitlog.With(l.logger, "id", id, "bidi", true),
+		rq:          runqueue.New(id.String()),
+		doneCh:      make(chan struct{}),
+		state:       inConnected,
+		sender:      out.target,
+		target:      out.sender,
+	}
+	stm.jd, _ = jid.New("", out.target, "", true)
+	stm.flags.setSecured()
+	stm.flags.setAuthenticated()
+	return stm
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+
+	"github.com/jackal-xmpp/runqueue/v2"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutProvider_RegisterBidi(t *testing.T) {
+	// given
+	op := &OutProvider{
+		policy:     testPolicy(federation.Config{}),
+		outStreams: make(map[string]s2sOut),
+	}
+	in := &inS2S{}
+	bo1 := newBidiOut(in, "jackal.im", "jabber.org")
+	bo2 := newBidiOut(in, "jackal.im", "jabber.org")
+
+	// when
+	ok1 := op.registerBidi(bo1)
+	ok2 := op.registerBidi(bo2)
+
+	out, err := op.GetOut(context.Background(), "jackal.im", "jabber.org")
+
+	// then
+	require.True(t, ok1)
+	require.False(t, ok2)
+	require.Nil(t, err)
+	require.Equal(t, bo1, out)
+
+	op.unregisterBidi(bo2) // not registered
+	require.Len(t, op.outStreams, 1)
+
+	op.unregisterBidi(bo1)
+	require.Len(t, op.outStreams, 0)
+}
+
+func TestInS2S_RegisterBidi(t *testing.T) {
+	// given
+	var registered []stream.S2SOutID
+
+	opMock := &outProviderMock{}
+	opMock.registerBidiFunc = func(stm s2sOut) bool {
+		registered = append(registered, stm.ID())
+		return true
+	}
+	stm := &inS2S{
+		outProvider: opMock,
+		logger:      kitlog.NewNopLogger(),
+	}
+
+	// when
+	stm.registerBidi("jackal.im", "jabber.org") // bidi not negotiated
+
+	stm.flags.setBidi()
+	stm.registerBidi("jackal.im", "jabber.org")
+
+	// then
+	require.Len(t, registered, 1)
+	require.Equal(t, stream.S2SOutID{Sender: "jackal.im", Target: "jabber.org"}, registered[0])
+	require.Len(t, stm.bidiOuts, 1)
+}
+
+func TestOutS2S_RequestBidi(t *testing.T) {
+	// given
+	outBuf := bytes.NewBuffer(nil)
+
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, element stravaganza.Element) error {
+		return element.ToXML(outBuf, true)
+	}
+	stm := &outS2S{
+		sender: "jackal.im",
+		target: "jabber.org",
+		cfg: outConfig{
+			reqTimeout: time.Minute,
+		},
+		typ:      defaultType,
+		state:    outConnected,
+		flags:    flags{fs: fSecured},
+		rq:       runqueue.New("out_s2s:bidi"),
+		session:  ssMock,
+		hk:       hook.NewHooks(),
+		logger:   kitlog.NewNopLogger(),
+		bidiInFn: func(_ *outS2S) *inS2S { return &inS2S{} },
+	}
+
+	// when
+	stm.handleSessionResult(stravaganza.NewBuilder("stream:features").
+		WithChild(
+			stravaganza.NewBuilder("mechanisms").
+				WithAttribute(stravaganza.Namespace, saslNamespace).
+				WithChild(
+					stravaganza.NewBuilder("mechanism").
+						WithText("EXTERNAL").
+						Build(),
+				).
+				Build(),
+		).
+		WithChild(
+			stravaganza.NewBuilder("bidi").
+				WithAttribute(stravaganza.Namespace, bidiFeatureNamespace).
+				Build(),
+		).
+		Build(), nil)
+
+	// then
+	require.Equal(t, `<bidi xmlns='urn:xmpp:bidi'/><auth xmlns='urn:ietf:params:xml:ns:xmpp-sasl' mechanism='EXTERNAL'>amFja2FsLmlt</auth>`, outBuf.String())
+	require.Equal(t, outAuthenticating, stm.getState())
+	require.True(t, stm.flags.isBidi())
+}
+
+func TestOutS2S_BidiIncomingStanza(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var routed stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		routed = stanza
+		return nil, nil
+	}
+	compsMock := &componentsMock{}
+	compsMock.IsComponentHostFunc = func(cHost string) bool { return false }
+
+	hk := hook.NewHooks()
+
+	var received bool
+	hk.AddHook(hook.S2SInStreamMessageReceived, func(execCtx *hook.ExecutionContext) error {
+		received = true
+		return nil
+	}, hook.DefaultPriority)
+
+	stm := &outS2S{
+		sender: "jackal.im",
+		target: "jabber.org",
+		cfg: outConfig{
+			reqTimeout: time.Minute,
+		},
+		typ:    defaultType,
+		state:  outVerifyingDialbackKey,
+		flags:  flags{fs: fSecured | fBidi},
+		rq:     runqueue.New("out_s2s:bidi"),
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	stm.bidiInFn = func(out *outS2S) *inS2S {
+		in := &inS2S{
+			state:  inConnected,
+			sender: out.target,
+			target: out.sender,
+			router: routerMock,
+			comps:  compsMock,
+			hk:     hk,
+			policy: testPolicy(federation.Config{}),
+			logger: kitlog.NewNopLogger(),
+		}
+		in.flags.setSecured()
+		in.flags.setAuthenticated()
+		return in
+	}
+
+	// when
+	stm.handleSessionResult(stravaganza.NewBuilder("db:result").
+		WithAttribute(stravaganza.Type, "valid").
+		WithAttribute(stravaganza.From, "jabber.org").
+		WithAttribute(stravaganza.To, "jackal.im").
+		Build(), nil)
+
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jabber.org/yard").
+		WithAttribute(stravaganza.To, "noelia@jackal.im/hall").
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("I'll give thee a wind.").
+				Build(),
+		).
+		BuildMessage()
+	stm.handleSessionResult(msg, nil)
+
+	// then
+	require.Equal(t, outAuthenticated, stm.getState())
+	require.NotNil(t, stm.bidiIn)
+	require.True(t, received)
+	require.NotNil(t, routed)
+	require.Equal(t, "noelia@jackal.im/hall", routed.ToJID().String())
+}
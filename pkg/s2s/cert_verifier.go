@@ -0,0 +1,241 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	dnsutil "github.com/ortuman/jackal/pkg/util/dns"
+)
+
+var (
+	errNoPeerCertificate = errors.New("s2s: no peer certificate")
+	errPinMismatch       = errors.New("s2s: peer certificate public key does not match any pinned key")
+	errDANEMismatch      = errors.New("s2s: peer certificate does not match any TLSA record")
+	errDANELookup        = errors.New("s2s: TLSA lookup failed")
+)
+
+// CertVerifier validates remote server certificates using a configurable set of trust anchors,
+// per-domain pinned public keys, and optionally POSH documents and DANE records.
+type CertVerifier struct {
+	roots            *x509.CertPool
+	pins             map[string][]string
+	dialbackFallback bool
+
+	posh        POSHFetcher
+	poshTimeout time.Duration
+	tlsa        dnsutil.TLSAResolver
+	daneTimeout time.Duration
+}
+
+// NewCertVerifier returns a new CertVerifier initialized with the passed configuration.
+func NewCertVerifier(cfg TLSConfig) (*CertVerifier, error) {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if len(cfg.CAFile) > 0 {
+		b, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		if !roots.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("s2s: no valid certificates found in %s", cfg.CAFile)
+		}
+	}
+	pins := make(map[string][]string, len(cfg.Pins))
+	for _, pin := range cfg.Pins {
+		domain := strings.ToLower(pin.Domain)
+		for _, spki := range pin.SPKI {
+			b, err := base64.StdEncoding.DecodeString(spki)
+			if err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("s2s: invalid SPKI pin for domain %s: %s", pin.Domain, spki)
+			}
+			pins[domain] = append(pins[domain], spki)
+		}
+	}
+	v := &CertVerifier{
+		roots:            roots,
+		pins:             pins,
+		dialbackFallback: cfg.DialbackFallback,
+		poshTimeout:      cfg.POSH.Timeout,
+		daneTimeout:      cfg.DANE.Timeout,
+	}
+	if cfg.POSH.Enabled {
+		v.posh = NewPOSHFetcher(cfg.POSH.Timeout)
+	}
+	if cfg.DANE.Enabled {
+		v.tlsa = dnsutil.NewTLSAResolver(cfg.DANE.Resolver)
+	}
+	return v, nil
+}
+
+// DialbackFallback tells whether dialback can be used when a peer certificate does not validate.
+func (v *CertVerifier) DialbackFallback() bool {
+	return v.dialbackFallback
+}
+
+// Verify validates certs chain presented by domain.
+// Whenever pinned keys are configured for domain the leaf certificate public key must match one of them,
+// in which case no chain validation is performed.
+// If POSH is enabled, a certificate not valid for domain is accepted as long as domain publishes its fingerprint.
+func (v *CertVerifier) Verify(domain string, certs []*x509.Certificate) error {
+	return v.VerifyService(domain, "", 0, certs)
+}
+
+// VerifyService validates certs chain presented by domain when reached through host and port service.
+// If DANE is enabled and the service publishes DNSSEC signed TLSA records, the certificate must match
+// any of them, in which case no further validation is performed.
+func (v *CertVerifier) VerifyService(domain, host string, port int, certs []*x509.Certificate) error {
+	if len(certs) == 0 {
+		return errNoPeerCertificate
+	}
+	leaf := certs[0]
+
+	if pins, ok := v.pins[strings.ToLower(domain)]; ok {
+		spki := SPKIHash(leaf)
+		for _, pin := range pins {
+			if pin == spki {
+				return nil
+			}
+		}
+		return errPinMismatch
+	}
+	if v.tlsa != nil && len(host) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), v.daneTimeout)
+		records, secure, err := v.tlsa.LookupTLSA(ctx, port, "tcp", host)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("%w: %v", errDANELookup, err)
+		}
+		if records = usableTLSARecords(records); secure && len(records) > 0 {
+			return verifyDANE(domain, host, records, certs)
+		}
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       domain,
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err == nil || v.posh == nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), v.poshTimeout)
+	doc, fetchErr := v.posh.FetchPOSH(ctx, domain)
+	cancel()
+	if fetchErr == nil && doc.Matches(leaf) {
+		return nil
+	}
+	return err
+}
+
+// SPKIHash returns the base64 encoded SHA-256 hash of cert SubjectPublicKeyInfo.
+func SPKIHash(cert *x509.Certificate) string {
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// usableTLSARecords filters out PKIX-TA and PKIX-EE records, as only DANE-TA and DANE-EE
+// certificate usages are supported (RFC 7672, section 3.1.3).
+func usableTLSARecords(records []dnsutil.TLSARecord) []dnsutil.TLSARecord {
+	var usable []dnsutil.TLSARecord
+	for _, rec := range records {
+		switch rec.Usage {
+		case dnsutil.DANETA, dnsutil.DANEEE:
+			usable = append(usable, rec)
+		}
+	}
+	return usable
+}
+
+func verifyDANE(domain, host string, records []dnsutil.TLSARecord, certs []*x509.Certificate) error {
+	leaf := certs[0]
+	for _, rec := range records {
+		switch rec.Usage {
+		case dnsutil.DANEEE:
+			// no name checks nor validity period checks are performed (RFC 7672, section 3.1.1)
+			if rec.Matches(leaf) {
+				return nil
+			}
+
+		case dnsutil.DANETA:
+			for _, ta := range certs[1:] {
+				if !rec.Matches(ta) {
+					continue
+				}
+				roots := x509.NewCertPool()
+				roots.AddCert(ta)
+				intermediates := x509.NewCertPool()
+				for _, cert := range certs[1:] {
+					intermediates.AddCert(cert)
+				}
+				// reference identifier can be either source domain or service host (RFC 7673, section 4)
+				for _, name := range []string{domain, host} {
+					_, err := leaf.Verify(x509.VerifyOptions{
+						DNSName:       name,
+						Roots:         roots,
+						Intermediates: intermediates,
+						KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+					})
+					if err == nil {
+						return nil
+					}
+				}
+			}
+		}
+	}
+	return errDANEMismatch
+}
+
+func certFailureReason(err error) string {
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.Is(err, errNoPeerCertificate):
+		return "no_certificate"
+	case errors.Is(err, errPinMismatch):
+		return "pin_mismatch"
+	case errors.Is(err, errDANEMismatch):
+		return "dane_mismatch"
+	case errors.Is(err, errDANELookup):
+		return "dane_lookup_failed"
+	case errors.As(err, &unknownAuthErr):
+		return "unknown_authority"
+	case errors.As(err, &hostnameErr):
+		return "hostname_mismatch"
+	case errors.As(err, &invalidErr):
+		if invalidErr.Reason == x509.Expired {
+			return "expired"
+		}
+		return "invalid"
+	default:
+		return "invalid"
+	}
+}
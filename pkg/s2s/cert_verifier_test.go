@@ -0,0 +1,239 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	dnsutil "github.com/ortuman/jackal/pkg/util/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertVerifier_CAFile(t *testing.T) {
+	// given
+	ca, caKey := testCertificate(t, "Test CA", nil, nil, nil)
+	leaf, _ := testCertificate(t, "jabber.org", []string{"jabber.org"}, ca, caKey)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600)
+	require.Nil(t, err)
+
+	v, err := NewCertVerifier(TLSConfig{CAFile: caFile})
+	require.Nil(t, err)
+
+	// then
+	require.Nil(t, v.Verify("jabber.org", []*x509.Certificate{leaf}))
+
+	err = v.Verify("jackal.im", []*x509.Certificate{leaf})
+	require.Equal(t, "hostname_mismatch", certFailureReason(err))
+
+	err = v.Verify("jabber.org", nil)
+	require.Equal(t, "no_certificate", certFailureReason(err))
+}
+
+func TestCertVerifier_UnknownAuthority(t *testing.T) {
+	// given
+	ca, caKey := testCertificate(t, "Test CA", nil, nil, nil)
+	leaf, _ := testCertificate(t, "jabber.org", []string{"jabber.org"}, ca, caKey)
+
+	v, err := NewCertVerifier(TLSConfig{})
+	require.Nil(t, err)
+
+	// when
+	err = v.Verify("jabber.org", []*x509.Certificate{leaf})
+
+	// then
+	require.Equal(t, "unknown_authority", certFailureReason(err))
+}
+
+func TestCertVerifier_Pins(t *testing.T) {
+	// given
+	selfSigned, _ := testCertificate(t, "jabber.org", []string{"jabber.org"}, nil, nil)
+	other, _ := testCertificate(t, "jabber.org", []string{"jabber.org"}, nil, nil)
+
+	v, err := NewCertVerifier(TLSConfig{
+		Pins: []PinConfig{
+			{Domain: "Jabber.org", SPKI: []string{SPKIHash(selfSigned)}},
+		},
+	})
+	require.Nil(t, err)
+
+	// then
+	require.Nil(t, v.Verify("jabber.org", []*x509.Certificate{selfSigned}))
+
+	err = v.Verify("jabber.org", []*x509.Certificate{other})
+	require.Equal(t, "pin_mismatch", certFailureReason(err))
+
+	_, err = NewCertVerifier(TLSConfig{
+		Pins: []PinConfig{{Domain: "jabber.org", SPKI: []string{"foo"}}},
+	})
+	require.NotNil(t, err)
+}
+
+func TestCertVerifier_POSH(t *testing.T) {
+	// given
+	cert, _ := testCertificate(t, "xmpp.hosting.net", []string{"xmpp.hosting.net"}, nil, nil)
+	otherCert, _ := testCertificate(t, "xmpp.hosting.net", []string{"xmpp.hosting.net"}, nil, nil)
+
+	fp := sha256.Sum256(cert.Raw)
+	fetcher := &poshFetcherMock{}
+	fetcher.FetchPOSHFunc = func(ctx context.Context, domain string) (*POSHDocument, error) {
+		if domain != "jackal.im" {
+			return nil, errors.New("not found")
+		}
+		return &POSHDocument{
+			Fingerprints: []map[string]string{{"sha-256": base64.StdEncoding.EncodeToString(fp[:])}},
+			Expires:      3600,
+		}, nil
+	}
+	v, _ := NewCertVerifier(TLSConfig{})
+	v.posh = fetcher
+
+	// then
+	require.Nil(t, v.Verify("jackal.im", []*x509.Certificate{cert}))
+
+	err := v.Verify("jackal.im", []*x509.Certificate{otherCert})
+	require.Equal(t, "hostname_mismatch", certFailureReason(err))
+
+	err = v.Verify("jabber.org", []*x509.Certificate{cert})
+	require.Equal(t, "hostname_mismatch", certFailureReason(err))
+}
+
+func TestCertVerifier_DANE(t *testing.T) {
+	// given
+	ca, caKey := testCertificate(t, "Hosting CA", nil, nil, nil)
+	leaf, _ := testCertificate(t, "xmpp.hosting.net", []string{"xmpp.hosting.net"}, ca, caKey)
+	otherCert, _ := testCertificate(t, "xmpp.hosting.net", []string{"xmpp.hosting.net"}, nil, nil)
+
+	spkiHash := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	caHash := sha256.Sum256(ca.Raw)
+
+	var tlsaRecords []dnsutil.TLSARecord
+	var secure bool
+
+	resolver := &tlsaResolverMock{}
+	resolver.LookupTLSAFunc = func(ctx context.Context, port int, proto string, host string) ([]dnsutil.TLSARecord, bool, error) {
+		if host != "xmpp.hosting.net" || port != 5269 {
+			return nil, false, nil
+		}
+		return tlsaRecords, secure, nil
+	}
+	v, _ := NewCertVerifier(TLSConfig{})
+	v.tlsa = resolver
+	v.daneTimeout = time.Second
+
+	// DANE-EE
+	tlsaRecords = []dnsutil.TLSARecord{
+		{Usage: dnsutil.DANEEE, Selector: dnsutil.SelectorSPKI, MatchingType: dnsutil.MatchingSHA256, Data: spkiHash[:]},
+	}
+	secure = true
+	require.Nil(t, v.VerifyService("jackal.im", "xmpp.hosting.net", 5269, []*x509.Certificate{leaf}))
+
+	err := v.VerifyService("jackal.im", "xmpp.hosting.net", 5269, []*x509.Certificate{otherCert})
+	require.Equal(t, "dane_mismatch", certFailureReason(err))
+
+	// not DNSSEC signed records
+	secure = false
+	err = v.VerifyService("jackal.im", "xmpp.hosting.net", 5269, []*x509.Certificate{leaf})
+	require.Equal(t, "hostname_mismatch", certFailureReason(err))
+
+	// DANE-TA
+	tlsaRecords = []dnsutil.TLSARecord{
+		{Usage: dnsutil.DANETA, Selector: dnsutil.SelectorCert, MatchingType: dnsutil.MatchingSHA256, Data: caHash[:]},
+	}
+	secure = true
+	require.Nil(t, v.VerifyService("jackal.im", "xmpp.hosting.net", 5269, []*x509.Certificate{leaf, ca}))
+
+	err = v.VerifyService("jackal.im", "xmpp.hosting.net", 5269, []*x509.Certificate{leaf})
+	require.Equal(t, "dane_mismatch", certFailureReason(err))
+
+	// lookup failure
+	resolver.LookupTLSAFunc = func(ctx context.Context, port int, proto string, host string) ([]dnsutil.TLSARecord, bool, error) {
+		return nil, false, errors.New("SERVFAIL")
+	}
+	err = v.VerifyService("jackal.im", "xmpp.hosting.net", 5269, []*x509.Certificate{leaf})
+	require.Equal(t, "dane_lookup_failed", certFailureReason(err))
+}
+
+func TestOutS2S_VerifyConnection(t *testing.T) {
+	// given
+	cert, _ := testCertificate(t, "jabber.org", []string{"jabber.org"}, nil, nil)
+
+	strictVrf, _ := NewCertVerifier(TLSConfig{})
+	fallbackVrf, _ := NewCertVerifier(TLSConfig{DialbackFallback: true})
+
+	strict := &outS2S{
+		target: "jabber.org",
+		cfg:    outConfig{certVerifier: strictVrf},
+		logger: kitlog.NewNopLogger(),
+	}
+	fallback := &outS2S{
+		target: "jabber.org",
+		cfg:    outConfig{certVerifier: fallbackVrf},
+		logger: kitlog.NewNopLogger(),
+	}
+	cs := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+	// then
+	require.NotNil(t, strict.verifyConnection(cs))
+	require.False(t, strict.flags.isUntrustedCertificate())
+
+	require.Nil(t, fallback.verifyConnection(cs))
+	require.True(t, fallback.flags.isUntrustedCertificate())
+}
+
+func testCertificate(t *testing.T, cn string, dnsNames []string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if len(dnsNames) == 0 {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	return cert, key
+}
@@ -61,4 +61,76 @@ type OutConfig struct {
 
 	// MaxStanzaSize is the maximum size a listener incoming stanza may have.
 	MaxStanzaSize int `fig:"max_stanza_size" default:"131072"`
+
+	// Queue defines outgoing stanza queue configuration.
+	Queue OutQueueConfig `fig:"queue"`
+}
+
+// OutQueueConfig defines the configuration of the queue holding outgoing stanzas while a remote server is unreachable.
+type OutQueueConfig struct {
+	// Disabled, if true, stanzas will be bounced as soon as a remote server can't be reached.
+	Disabled bool `fig:"disabled"`
+
+	// TTL defines the maximum amount of time a stanza is held before being bounced.
+	TTL time.Duration `fig:"ttl" default:"2m"`
+
+	// MaxSize defines the maximum number of stanzas held per remote domain.
+	MaxSize int `fig:"max_size" default:"1000"`
+
+	// MinBackoff defines the initial connection retry interval.
+	MinBackoff time.Duration `fig:"min_backoff" default:"1s"`
+
+	// MaxBackoff defines the maximum connection retry interval.
+	MaxBackoff time.Duration `fig:"max_backoff" default:"30s"`
+}
+
+// TLSConfig defines S2S peer certificate validation configuration.
+type TLSConfig struct {
+	// CAFile is the path to a PEM encoded CA bundle trusted in addition to system root CAs.
+	CAFile string `fig:"ca_file"`
+
+	// Pins contains per-domain pinned public keys.
+	Pins []PinConfig `fig:"pins"`
+
+	// DialbackFallback, if true, dialback authentication will be used whenever peer certificate does not validate.
+	DialbackFallback bool `fig:"dialback_fallback"`
+
+	// POSH defines POSH (RFC 7711) delegated domain verification configuration.
+	POSH POSHConfig `fig:"posh"`
+
+	// DANE defines DANE (RFC 7673) verification configuration.
+	DANE DANEConfig `fig:"dane"`
+}
+
+// POSHConfig defines POSH verification configuration.
+type POSHConfig struct {
+	// Enabled, if true, a certificate not valid for a remote domain will be accepted as long as it matches
+	// the fingerprints published in the domain POSH document.
+	Enabled bool `fig:"enabled"`
+
+	// Timeout defines POSH document retrieval timeout.
+	Timeout time.Duration `fig:"timeout" default:"5s"`
+}
+
+// DANEConfig defines DANE verification configuration.
+type DANEConfig struct {
+	// Enabled, if true, outgoing connection certificates will be validated against DNSSEC signed TLSA records
+	// published for the dialed service.
+	Enabled bool `fig:"enabled"`
+
+	// Resolver is the DNSSEC validating resolver address used to lookup TLSA records.
+	// If empty, first system configured nameserver will be used.
+	Resolver string `fig:"resolver"`
+
+	// Timeout defines TLSA lookup timeout.
+	Timeout time.Duration `fig:"timeout" default:"5s"`
+}
+
+// PinConfig defines the set of public keys pinned to a remote domain.
+type PinConfig struct {
+	// Domain is the remote domain name.
+	Domain string `fig:"domain"`
+
+	// SPKI contains base64 encoded SHA-256 hashes of pinned SubjectPublicKeyInfo values.
+	SPKI []string `fig:"spki"`
 }
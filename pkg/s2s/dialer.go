@@ -33,6 +33,9 @@ const (
 
 type dialer interface {
 	DialContext(ctx context.Context, remoteDomain string) (conn net.Conn, usesTLS bool, err error)
+
+	// dialedService returns the host and port of the last dialed remote service.
+	dialedService() (host string, port int)
 }
 
 type srvResolveFunc func(service, proto, name string) (cname string, addrs []*net.SRV, err error)
@@ -42,6 +45,9 @@ type outDialer struct {
 	srvResolve srvResolveFunc
 	dialCtx    dialFunc
 	dialTLSCtx dialFunc
+
+	host string
+	port int
 }
 
 func newDialer(timeout time.Duration, tlsCfg *tls.Config) *outDialer {
@@ -69,10 +75,15 @@ func (d *outDialer) DialContext(ctx context.Context, remoteDomain string) (conn
 	if err == nil {
 		return conn, false, nil
 	}
+	d.host, d.port = remoteDomain, 5269
 	conn, err = d.dialCtx(ctx, "tcp", net.JoinHostPort(remoteDomain, "5269"))
 	return conn, false, err
 }
 
+func (d *outDialer) dialedService() (host string, port int) {
+	return d.host, d.port
+}
+
 func (d *outDialer) dialSRV(ctx context.Context, remoteDomain, service string, dialTLS bool) (net.Conn, error) {
 	_, addrs, err := d.srvResolve(service, "tcp", remoteDomain)
 	if err != nil {
@@ -85,6 +96,8 @@ func (d *outDialer) dialSRV(ctx context.Context, remoteDomain, service string, d
 		host := strings.TrimSuffix(addr.Target, ".")
 		port := strconv.Itoa(int(addr.Port))
 
+		d.host, d.port = host, int(addr.Port)
+
 		var dialFn dialFunc
 		switch dialTLS {
 		case true:
@@ -85,6 +85,10 @@ func TestDialer_Success(t *testing.T) {
 	require.Nil(t, err)
 	require.NotNil(t, out)
 	require.False(t, isTLS)
+
+	host, port := d.dialedService()
+	require.Equal(t, "xmpp.jabber.org", host)
+	require.Equal(t, 5269, port)
 }
 
 func TestDialer_TLSSuccess(t *testing.T) {
@@ -0,0 +1,320 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidConfig will be returned in case a policy update contains an invalid setting.
+var ErrInvalidConfig = errors.New("federation: invalid config")
+
+const (
+	// DenylistMode allows federation with every remote domain except the ones explicitly denied.
+	DenylistMode = "denylist"
+
+	// AllowlistMode allows federation only with explicitly allowed remote domains.
+	AllowlistMode = "allowlist"
+)
+
+const (
+	// AnyAuth accepts both certificate based (SASL EXTERNAL) and dialback authentication.
+	AnyAuth = "any"
+
+	// CertificateAuth requires remote domain to authenticate by means of a certificate (SASL EXTERNAL).
+	CertificateAuth = "certificate"
+
+	// DialbackAuth requires remote domain to authenticate by means of server dialback.
+	DialbackAuth = "dialback"
+)
+
+// List identifies the domain list a domain pattern belongs to.
+type List int
+
+const (
+	// NoList removes a domain pattern from both allow and deny lists.
+	NoList List = iota
+
+	// AllowList identifies the allow list.
+	AllowList
+
+	// DenyList identifies the deny list.
+	DenyList
+)
+
+// DomainConfig contains per-domain federation settings.
+type DomainConfig struct {
+	// Domain is the domain pattern these settings apply to.
+	Domain string `fig:"domain"`
+
+	// Auth defines the authentication method required to the domain.
+	Auth string `fig:"auth" default:"any"`
+}
+
+// Config contains federation policy configuration.
+type Config struct {
+	// Mode defines federation policy mode.
+	Mode string `fig:"mode" default:"denylist"`
+
+	// Allow contains the allowed domain patterns.
+	Allow []string `fig:"allow"`
+
+	// Deny contains the denied domain patterns.
+	Deny []string `fig:"deny"`
+
+	// Domains contains per-domain settings.
+	Domains []DomainConfig `fig:"domains"`
+}
+
+// Policy decides whether a remote domain is allowed to federate and how it should authenticate.
+//
+// Domain patterns are either a fully qualified domain name, a '*.' prefixed wildcard
+// matching every subdomain of the given domain, or a single '*' matching any domain.
+type Policy struct {
+	mu  sync.RWMutex
+	cfg Config
+}
+
+// New returns a new federation policy initialized with the passed configuration.
+func New(cfg Config) (*Policy, error) {
+	c, err := normalizeConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{cfg: c}, nil
+}
+
+// IsAllowed tells whether federation with domain is permitted.
+func (p *Policy) IsAllowed(domain string) bool {
+	domain = strings.ToLower(domain)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if matchesAny(p.cfg.Deny, domain) {
+		return false
+	}
+	if p.cfg.Mode == AllowlistMode {
+		return matchesAny(p.cfg.Allow, domain)
+	}
+	return true
+}
+
+// AuthMethod returns the authentication method required to domain.
+func (p *Policy) AuthMethod(domain string) string {
+	domain = strings.ToLower(domain)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	auth := AnyAuth
+	bestScore := -1
+	for _, dc := range p.cfg.Domains {
+		score := matchScore(dc.Domain, domain)
+		if score > bestScore {
+			auth = dc.Auth
+			bestScore = score
+		}
+	}
+	return auth
+}
+
+// Config returns a copy of the current policy configuration.
+func (p *Policy) Config() Config {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return Config{
+		Mode:    p.cfg.Mode,
+		Allow:   append([]string(nil), p.cfg.Allow...),
+		Deny:    append([]string(nil), p.cfg.Deny...),
+		Domains: append([]DomainConfig(nil), p.cfg.Domains...),
+	}
+}
+
+// Reset replaces the whole policy configuration.
+func (p *Policy) Reset(cfg Config) error {
+	c, err := normalizeConfig(cfg)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.cfg = c
+	p.mu.Unlock()
+	return nil
+}
+
+// SetMode updates policy mode.
+func (p *Policy) SetMode(mode string) error {
+	if err := validateMode(mode); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.cfg.Mode = mode
+	p.mu.Unlock()
+	return nil
+}
+
+// SetDomain places a domain pattern into the passed list and sets its required authentication method.
+// An empty auth value removes any per-domain authentication setting.
+func (p *Policy) SetDomain(domain string, list List, auth string) error {
+	domain = strings.ToLower(domain)
+	if err := validatePattern(domain); err != nil {
+		return err
+	}
+	if len(auth) > 0 {
+		if err := validateAuth(auth); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cfg.Allow = removePattern(p.cfg.Allow, domain)
+	p.cfg.Deny = removePattern(p.cfg.Deny, domain)
+	switch list {
+	case AllowList:
+		p.cfg.Allow = append(p.cfg.Allow, domain)
+	case DenyList:
+		p.cfg.Deny = append(p.cfg.Deny, domain)
+	case NoList:
+		break
+	default:
+		return fmt.Errorf("%w: unrecognized list: %d", ErrInvalidConfig, list)
+	}
+
+	domains := make([]DomainConfig, 0, len(p.cfg.Domains)+1)
+	for _, dc := range p.cfg.Domains {
+		if dc.Domain != domain {
+			domains = append(domains, dc)
+		}
+	}
+	if len(auth) > 0 {
+		domains = append(domains, DomainConfig{Domain: domain, Auth: auth})
+	}
+	p.cfg.Domains = domains
+	return nil
+}
+
+func normalizeConfig(cfg Config) (Config, error) {
+	var c Config
+
+	c.Mode = cfg.Mode
+	if len(c.Mode) == 0 {
+		c.Mode = DenylistMode
+	}
+	if err := validateMode(c.Mode); err != nil {
+		return Config{}, err
+	}
+	for _, pattern := range cfg.Allow {
+		pattern = strings.ToLower(pattern)
+		if err := validatePattern(pattern); err != nil {
+			return Config{}, err
+		}
+		c.Allow = append(c.Allow, pattern)
+	}
+	for _, pattern := range cfg.Deny {
+		pattern = strings.ToLower(pattern)
+		if err := validatePattern(pattern); err != nil {
+			return Config{}, err
+		}
+		c.Deny = append(c.Deny, pattern)
+	}
+	for _, dc := range cfg.Domains {
+		dc.Domain = strings.ToLower(dc.Domain)
+		if err := validatePattern(dc.Domain); err != nil {
+			return Config{}, err
+		}
+		if len(dc.Auth) == 0 {
+			dc.Auth = AnyAuth
+		}
+		if err := validateAuth(dc.Auth); err != nil {
+			return Config{}, err
+		}
+		c.Domains = append(c.Domains, dc)
+	}
+	return c, nil
+}
+
+func validateMode(mode string) error {
+	switch mode {
+	case AllowlistMode, DenylistMode:
+		return nil
+	default:
+		return fmt.Errorf("%w: unrecognized mode: %s", ErrInvalidConfig, mode)
+	}
+}
+
+func validateAuth(auth string) error {
+	switch auth {
+	case AnyAuth, CertificateAuth, DialbackAuth:
+		return nil
+	default:
+		return fmt.Errorf("%w: unrecognized auth method: %s", ErrInvalidConfig, auth)
+	}
+}
+
+func validatePattern(pattern string) error {
+	if len(pattern) == 0 {
+		return fmt.Errorf("%w: empty domain pattern", ErrInvalidConfig)
+	}
+	if pattern == "*" {
+		return nil
+	}
+	name := strings.TrimPrefix(pattern, "*.")
+	if len(name) == 0 || strings.Contains(name, "*") {
+		return fmt.Errorf("%w: invalid domain pattern: %s", ErrInvalidConfig, pattern)
+	}
+	return nil
+}
+
+func removePattern(patterns []string, pattern string) []string {
+	var ret []string
+	for _, p := range patterns {
+		if p != pattern {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+func matchesAny(patterns []string, domain string) bool {
+	for _, pattern := range patterns {
+		if matchScore(pattern, domain) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchScore returns how specifically pattern matches domain, or -1 if it doesn't match at all.
+func matchScore(pattern, domain string) int {
+	switch {
+	case pattern == "*":
+		return 0
+	case strings.HasPrefix(pattern, "*."):
+		if strings.HasSuffix(domain, pattern[1:]) {
+			return len(pattern) - 1
+		}
+		return -1
+	case pattern == domain:
+		return len(pattern) + 1
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_DenylistMode(t *testing.T) {
+	// given
+	p, err := New(Config{
+		Deny: []string{"evil.org", "*.spam.net"},
+	})
+	require.Nil(t, err)
+
+	// then
+	require.True(t, p.IsAllowed("jabber.org"))
+	require.False(t, p.IsAllowed("evil.org"))
+	require.False(t, p.IsAllowed("EVIL.org"))
+	require.False(t, p.IsAllowed("a.spam.net"))
+	require.True(t, p.IsAllowed("spam.net"))
+}
+
+func TestPolicy_AllowlistMode(t *testing.T) {
+	// given
+	p, err := New(Config{
+		Mode:  AllowlistMode,
+		Allow: []string{"jabber.org", "*.example.net"},
+		Deny:  []string{"bad.example.net"},
+	})
+	require.Nil(t, err)
+
+	// then
+	require.True(t, p.IsAllowed("jabber.org"))
+	require.True(t, p.IsAllowed("chat.example.net"))
+	require.False(t, p.IsAllowed("bad.example.net"))
+	require.False(t, p.IsAllowed("example.net"))
+	require.False(t, p.IsAllowed("other.org"))
+}
+
+func TestPolicy_AuthMethod(t *testing.T) {
+	// given
+	p, err := New(Config{
+		Domains: []DomainConfig{
+			{Domain: "*", Auth: CertificateAuth},
+			{Domain: "*.legacy.org", Auth: DialbackAuth},
+			{Domain: "secure.legacy.org", Auth: CertificateAuth},
+		},
+	})
+	require.Nil(t, err)
+
+	// then
+	require.Equal(t, CertificateAuth, p.AuthMethod("jabber.org"))
+	require.Equal(t, DialbackAuth, p.AuthMethod("old.legacy.org"))
+	require.Equal(t, CertificateAuth, p.AuthMethod("secure.legacy.org"))
+}
+
+func TestPolicy_InvalidConfig(t *testing.T) {
+	_, err := New(Config{Mode: "foo"})
+	require.NotNil(t, err)
+
+	_, err = New(Config{Deny: []string{"*.*.org"}})
+	require.NotNil(t, err)
+
+	_, err = New(Config{Domains: []DomainConfig{{Domain: "jabber.org", Auth: "foo"}}})
+	require.NotNil(t, err)
+}
+
+func TestPolicy_RuntimeUpdate(t *testing.T) {
+	// given
+	p, err := New(Config{})
+	require.Nil(t, err)
+
+	// when
+	require.Nil(t, p.SetDomain("Jabber.org", DenyList, DialbackAuth))
+
+	// then
+	require.False(t, p.IsAllowed("jabber.org"))
+	require.Equal(t, DialbackAuth, p.AuthMethod("jabber.org"))
+
+	// when
+	require.Nil(t, p.SetMode(AllowlistMode))
+	require.Nil(t, p.SetDomain("jabber.org", AllowList, ""))
+
+	// then
+	require.True(t, p.IsAllowed("jabber.org"))
+	require.Equal(t, AnyAuth, p.AuthMethod("jabber.org"))
+
+	cfg := p.Config()
+	require.Equal(t, AllowlistMode, cfg.Mode)
+	require.Equal(t, []string{"jabber.org"}, cfg.Allow)
+	require.Len(t, cfg.Deny, 0)
+	require.Len(t, cfg.Domains, 0)
+
+	require.NotNil(t, p.SetMode("foo"))
+}
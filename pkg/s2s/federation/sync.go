@@ -0,0 +1,153 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/ortuman/jackal/pkg/cluster/kv"
+	kvtypes "github.com/ortuman/jackal/pkg/cluster/kv/types"
+)
+
+const policyKey = "federation://policy"
+
+// Sync shares federation policy updates among cluster instances by storing them into the cluster KV store.
+// In case no cluster KV store has been configured updates only apply to the local instance and are not persisted.
+type Sync struct {
+	policy    *Policy
+	kv        kv.KV
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	logger    kitlog.Logger
+
+	mu sync.Mutex
+}
+
+// NewSync returns a new initialized Sync instance.
+func NewSync(policy *Policy, kv kv.KV, logger kitlog.Logger) *Sync {
+	ctx, cancelFn := context.WithCancel(context.Background())
+	return &Sync{
+		policy:    policy,
+		kv:        kv,
+		ctx:       ctx,
+		ctxCancel: cancelFn,
+		logger:    logger,
+	}
+}
+
+// Config returns a copy of the current policy configuration.
+func (s *Sync) Config() Config {
+	return s.policy.Config()
+}
+
+// SetMode updates policy mode on every cluster instance.
+func (s *Sync) SetMode(ctx context.Context, mode string) error {
+	return s.update(ctx, func(p *Policy) error {
+		return p.SetMode(mode)
+	})
+}
+
+// SetDomain updates domain pattern policy settings on every cluster instance.
+func (s *Sync) SetDomain(ctx context.Context, domain string, list List, auth string) error {
+	return s.update(ctx, func(p *Policy) error {
+		return p.SetDomain(domain, list, auth)
+	})
+}
+
+// Start starts federation policy sync.
+func (s *Sync) Start(ctx context.Context) error {
+	ch := make(chan error, 1)
+
+	go func() {
+		wCh := s.kv.Watch(s.ctx, policyKey, false)
+
+		// stored policy takes precedence over configuration file settings
+		b, err := s.kv.Get(ctx, policyKey)
+		if err != nil {
+			ch <- err
+			return
+		}
+		if len(b) > 0 {
+			if err := s.apply(b); err != nil {
+				ch <- err
+				return
+			}
+		}
+		close(ch) // signal update
+
+		// watch changes
+		for wResp := range wCh {
+			if err := wResp.Err; err != nil {
+				level.Warn(s.logger).Log("msg", "error occurred watching federation policy", "err", err)
+				continue
+			}
+			for _, ev := range wResp.Events {
+				if ev.Type != kvtypes.Put {
+					continue
+				}
+				if err := s.apply(ev.Val); err != nil {
+					level.Warn(s.logger).Log("msg", "failed to apply federation policy changes", "err", err)
+				}
+			}
+		}
+	}()
+	if err := <-ch; err != nil {
+		return err
+	}
+	level.Info(s.logger).Log("msg", "started federation policy sync")
+	return nil
+}
+
+// Stop stops federation policy sync.
+func (s *Sync) Stop(_ context.Context) error {
+	// stop watching changes...
+	s.ctxCancel()
+
+	level.Info(s.logger).Log("msg", "stopped federation policy sync")
+	return nil
+}
+
+func (s *Sync) update(ctx context.Context, fn func(p *Policy) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// apply changes over a scratch copy, so that nothing changes in case the update can't be stored
+	p := &Policy{cfg: s.policy.Config()}
+	if err := fn(p); err != nil {
+		return err
+	}
+	cfg := p.Config()
+
+	b, err := json.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+	if err := s.kv.Put(ctx, policyKey, string(b)); err != nil {
+		return err
+	}
+	return s.policy.Reset(cfg)
+}
+
+func (s *Sync) apply(b []byte) error {
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return err
+	}
+	return s.policy.Reset(cfg)
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	kvtypes "github.com/ortuman/jackal/pkg/cluster/kv/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSync_LoadStoredPolicy(t *testing.T) {
+	// given
+	p, _ := New(Config{})
+
+	kvMock := &kvMock{}
+	kvMock.WatchFunc = func(ctx context.Context, prefix string, withPrevVal bool) <-chan kvtypes.WatchResp {
+		return make(chan kvtypes.WatchResp)
+	}
+	kvMock.GetFunc = func(ctx context.Context, key string) ([]byte, error) {
+		return []byte(`{"Mode":"denylist","Deny":["evil.org"]}`), nil
+	}
+	s := NewSync(p, kvMock, kitlog.NewNopLogger())
+
+	// when
+	err := s.Start(context.Background())
+	defer func() { _ = s.Stop(context.Background()) }()
+
+	// then
+	require.Nil(t, err)
+	require.False(t, p.IsAllowed("evil.org"))
+	require.True(t, p.IsAllowed("jabber.org"))
+}
+
+func TestSync_Update(t *testing.T) {
+	// given
+	p, _ := New(Config{})
+
+	var stored string
+	kvMock := &kvMock{}
+	kvMock.PutFunc = func(ctx context.Context, key string, value string) error {
+		stored = value
+		return nil
+	}
+	s := NewSync(p, kvMock, kitlog.NewNopLogger())
+
+	// when
+	err1 := s.SetDomain(context.Background(), "evil.org", DenyList, CertificateAuth)
+	err2 := s.SetMode(context.Background(), "foo")
+
+	// then
+	require.Nil(t, err1)
+	require.ErrorIs(t, err2, ErrInvalidConfig)
+
+	require.Len(t, kvMock.PutCalls(), 1)
+	require.Equal(t, policyKey, kvMock.PutCalls()[0].Key)
+	require.Equal(t, `{"Mode":"denylist","Allow":null,"Deny":["evil.org"],"Domains":[{"Domain":"evil.org","Auth":"certificate"}]}`, stored)
+
+	require.False(t, p.IsAllowed("evil.org"))
+	require.Equal(t, DenylistMode, p.Config().Mode)
+}
+
+func TestSync_WatchChanges(t *testing.T) {
+	// given
+	p, _ := New(Config{})
+
+	wCh := make(chan kvtypes.WatchResp, 1)
+	kvMock := &kvMock{}
+	kvMock.WatchFunc = func(ctx context.Context, prefix string, withPrevVal bool) <-chan kvtypes.WatchResp {
+		return wCh
+	}
+	kvMock.GetFunc = func(ctx context.Context, key string) ([]byte, error) {
+		return nil, nil
+	}
+	s := NewSync(p, kvMock, kitlog.NewNopLogger())
+
+	_ = s.Start(context.Background())
+	defer func() { _ = s.Stop(context.Background()) }()
+
+	// when
+	wCh <- kvtypes.WatchResp{
+		Events: []kvtypes.WatchEvent{
+			{Type: kvtypes.Put, Key: policyKey, Val: []byte(`{"Mode":"allowlist","Allow":["jabber.org"]}`)},
+		},
+	}
+	time.Sleep(time.Millisecond * 100) // wait for update
+
+	// then
+	require.True(t, p.IsAllowed("jabber.org"))
+	require.False(t, p.IsAllowed("jackal.im"))
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
 	xmppsession "github.com/ortuman/jackal/pkg/session"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/transport"
@@ -76,8 +77,11 @@ type inS2S struct {
 	kv           kv.KV
 	shapers      shaper.Shapers
 	hk           *hook.Hooks
+	policy       *federation.Policy
+	certVrf      *CertVerifier
 	logger       kitlog.Logger
 	rq           *runqueue.RunQueue
+	bidiOuts     []*bidiOut
 	discTm       *time.Timer
 	doneCh       chan struct{}
 	sendDisabled bool
@@ -101,6 +105,8 @@ func newInS2S(
 	kv kv.KV,
 	shapers shaper.Shapers,
 	hk *hook.Hooks,
+	policy *federation.Policy,
+	certVrf *CertVerifier,
 	logger kitlog.Logger,
 	cfg inConfig,
 ) (*inS2S, error) {
@@ -138,6 +144,8 @@ func newInS2S(
 		kv:          kv,
 		shapers:     shapers,
 		hk:          hk,
+		policy:      policy,
+		certVrf:     certVrf,
 		logger:      sLogger,
 		rq:          runqueue.New(id.String()),
 		doneCh:      make(chan struct{}),
@@ -266,6 +274,11 @@ func (s *inS2S) handleConnecting(ctx context.Context, elem stravaganza.Element)
 	s.jd, _ = jid.New("", s.sender, "", true)
 	s.session.SetFromJID(s.jd)
 
+	if len(s.sender) > 0 && !s.policy.IsAllowed(s.sender) {
+		return s.disconnect(ctx, streamerror.E(streamerror.PolicyViolation))
+	}
+	auth := s.policy.AuthMethod(s.sender)
+
 	fb := stravaganza.NewBuilder("stream:features")
 	fb.WithAttribute("xmlns:stream", streamNamespace)
 	fb.WithAttribute("version", "1.0")
@@ -285,7 +298,7 @@ func (s *inS2S) handleConnecting(ctx context.Context, elem stravaganza.Element)
 		}
 		return s.session.Send(ctx, fb.Build())
 	}
-	if !s.flags.isAuthenticated() {
+	if !s.flags.isAuthenticated() && auth != federation.DialbackAuth {
 		fb.WithChild(stravaganza.NewBuilder("mechanisms").
 			WithAttribute(stravaganza.Namespace, saslNamespace).
 			WithChild(
@@ -296,10 +309,18 @@ func (s *inS2S) handleConnecting(ctx context.Context, elem stravaganza.Element)
 			Build(),
 		)
 	}
-	fb.WithChild(stravaganza.NewBuilder("dialback").
-		WithAttribute(stravaganza.Namespace, dialbackNamespace).
-		Build(),
-	)
+	if auth != federation.CertificateAuth {
+		fb.WithChild(stravaganza.NewBuilder("dialback").
+			WithAttribute(stravaganza.Namespace, dialbackNamespace).
+			Build(),
+		)
+	}
+	if !s.flags.isAuthenticated() {
+		fb.WithChild(stravaganza.NewBuilder("bidi").
+			WithAttribute(stravaganza.Namespace, bidiFeatureNamespace).
+			Build(),
+		)
+	}
 	s.setState(inConnected)
 	if err := s.session.OpenStream(ctx); err != nil {
 		return err
@@ -321,8 +342,18 @@ func (s *inS2S) handleConnected(ctx context.Context, elem stravaganza.Element) e
 	case elem.Name() == "db:verify":
 		return s.verifyDialbackKey(ctx, elem)
 
+	case elem.Name() == "bidi" && elem.Attribute(stravaganza.Namespace) == bidiNamespace:
+		if !s.flags.isAuthenticated() && !s.flags.isDialbackKeyAuthorized() {
+			s.flags.setBidi()
+		}
+		return nil
+
 	default:
 		if s.flags.isAuthenticated() || s.flags.isDialbackKeyAuthorized() {
+			// policy may have changed at runtime
+			if !s.policy.IsAllowed(s.sender) {
+				return s.disconnect(ctx, streamerror.E(streamerror.PolicyViolation))
+			}
 			// post element received event
 			hi := &hook.S2SStreamInfo{
 				ID:      s.ID().String(),
@@ -550,19 +581,32 @@ func (s *inS2S) authenticate(ctx context.Context, elem stravaganza.Element) erro
 	if elem.Attribute(stravaganza.Namespace) != saslNamespace {
 		return s.disconnect(ctx, streamerror.E(streamerror.InvalidNamespace))
 	}
-	if elem.Attribute("mechanism") != "EXTERNAL" {
+	if !s.policy.IsAllowed(s.sender) {
+		return s.disconnect(ctx, streamerror.E(streamerror.PolicyViolation))
+	}
+	if elem.Attribute("mechanism") != "EXTERNAL" || s.policy.AuthMethod(s.sender) == federation.DialbackAuth {
 		return s.failAuthentication(ctx, "invalid-mechanism", "")
 	}
 	// validate initiating server certificate
-	certs := s.tr.PeerCertificates()
-	for _, cert := range certs {
-		for _, dnsName := range cert.DNSNames {
-			if dnsName == s.sender {
-				return s.finishAuthentication(ctx)
-			}
-		}
+	if err := s.verifyPeerCertificate(s.sender); err != nil {
+		return s.failAuthentication(ctx, "bad-protocol", "Failed to get peer certificate")
 	}
-	return s.failAuthentication(ctx, "bad-protocol", "Failed to get peer certificate")
+	return s.finishAuthentication(ctx)
+}
+
+func (s *inS2S) verifyPeerCertificate(domain string) error {
+	err := s.certVrf.Verify(domain, s.tr.PeerCertificates())
+	if err != nil {
+		reason := certFailureReason(err)
+		reportCertificateVerificationFailure("in", reason)
+
+		level.Info(s.logger).Log("msg", "failed to verify S2S peer certificate",
+			"domain", domain,
+			"reason", reason,
+			"err", err,
+		)
+	}
+	return err
 }
 
 func (s *inS2S) failAuthentication(ctx context.Context, reason, text string) error {
@@ -594,6 +638,7 @@ func (s *inS2S) finishAuthentication(ctx context.Context) error {
 		"target", s.target)
 
 	s.flags.setAuthenticated()
+	s.registerBidi(s.target, s.sender)
 	s.restartSession()
 
 	return s.sendElement(ctx, stravaganza.NewBuilder("success").
@@ -609,6 +654,15 @@ func (s *inS2S) authorizeDialbackKey(ctx context.Context, elem stravaganza.Eleme
 	elemFrom := elem.Attribute(stravaganza.From)
 	elemTo := elem.Attribute(stravaganza.To)
 
+	if !s.policy.IsAllowed(elemFrom) {
+		return s.disconnect(ctx, streamerror.E(streamerror.PolicyViolation))
+	}
+	if s.policy.AuthMethod(elemFrom) == federation.CertificateAuth {
+		return s.sendElement(ctx, stanzaerror.E(stanzaerror.NotAuthorized, elem).Element())
+	}
+	if !s.certVrf.DialbackFallback() && s.verifyPeerCertificate(elemFrom) != nil {
+		return s.sendElement(ctx, stanzaerror.E(stanzaerror.NotAuthorized, elem).Element())
+	}
 	dbParams := DialbackParams{
 		StreamID: s.session.StreamID(),
 		From:     elemTo,
@@ -652,6 +706,7 @@ func (s *inS2S) handleDialbackResult(ctx context.Context, from, to string, dbRes
 			"target", s.target,
 		)
 		s.flags.setDialbackKeyAuthorized()
+		s.registerBidi(to, from)
 	} else {
 		sb.WithAttribute(stravaganza.Type, "invalid")
 	}
@@ -707,6 +762,19 @@ func (s *inS2S) verifyDialbackKey(ctx context.Context, elem stravaganza.Element)
 	return s.sendElement(ctx, sb.Build())
 }
 
+func (s *inS2S) registerBidi(sender, target string) {
+	if !s.flags.isBidi() {
+		return
+	}
+	bo := newBidiOut(s, sender, target)
+	if !s.outProvider.registerBidi(bo) {
+		return
+	}
+	s.bidiOuts = append(s.bidiOuts, bo)
+
+	level.Info(s.logger).Log("msg", "registered bidirectional S2S incoming stream", "sender", sender, "target", target)
+}
+
 func (s *inS2S) proceedStartTLS(ctx context.Context, elem stravaganza.Element) error {
 	if elem.Attribute(stravaganza.Namespace) != tlsNamespace {
 		return s.disconnect(ctx, streamerror.E(streamerror.InvalidNamespace))
@@ -793,6 +861,9 @@ func (s *inS2S) close(ctx context.Context) error {
 	}
 	// unregister S2S stream
 	s.inHub.unregister(s)
+	for _, bo := range s.bidiOuts {
+		s.outProvider.unregisterBidi(bo)
+	}
 
 	level.Info(s.logger).Log("msg", "unregistered S2S incoming stream",
 		"sender", s.sender,
@@ -34,6 +34,7 @@ import (
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
 	"github.com/ortuman/jackal/pkg/transport"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
@@ -98,6 +99,7 @@ func TestInS2S_HandleSessionElement(t *testing.T) {
 		kvGetFn          func(ctx context.Context, key string) ([]byte, error)
 		routeError       error
 		flags            uint8
+		policy           federation.Config
 		waitBeforeAssert time.Duration
 
 		// expectations
@@ -132,7 +134,7 @@ func TestInS2S_HandleSessionElement(t *testing.T) {
 					WithAttribute(stravaganza.Version, "1.0").
 					Build(), nil
 			},
-			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:server' xmlns:stream='http://etherx.jabber.org/streams' id='s2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>EXTERNAL</mechanism></mechanisms><dialback xmlns='urn:xmpp:features:dialback'/></stream:features>`,
+			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:server' xmlns:stream='http://etherx.jabber.org/streams' id='s2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>EXTERNAL</mechanism></mechanisms><dialback xmlns='urn:xmpp:features:dialback'/><bidi xmlns='urn:xmpp:features:bidi'/></stream:features>`,
 			expectedState:  inConnected,
 		},
 		{
@@ -150,6 +152,44 @@ func TestInS2S_HandleSessionElement(t *testing.T) {
 			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:server' xmlns:stream='http://etherx.jabber.org/streams' id='s2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><dialback xmlns='urn:xmpp:features:dialback'/></stream:features>`,
 			expectedState:  inConnected,
 		},
+		{
+			name:  "Connecting/FederationDenied",
+			state: inConnecting,
+			flags: fSecured,
+			policy: federation.Config{
+				Deny: []string{"*.evil.org"},
+			},
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("stream:stream").
+					WithAttribute(stravaganza.Namespace, "jabber:server").
+					WithAttribute(stravaganza.StreamNamespace, "http://etherx.jabber.org/streams").
+					WithAttribute(stravaganza.From, "xmpp.evil.org").
+					WithAttribute(stravaganza.To, "localhost").
+					WithAttribute(stravaganza.Version, "1.0").
+					Build(), nil
+			},
+			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:server' xmlns:stream='http://etherx.jabber.org/streams' id='s2s1' from='localhost' version='1.0'><stream:error><policy-violation xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error></stream:stream>`,
+			expectedState:  inDisconnected,
+		},
+		{
+			name:  "Connecting/CertificateAuthRequired",
+			state: inConnecting,
+			flags: fSecured,
+			policy: federation.Config{
+				Domains: []federation.DomainConfig{{Domain: "jabber.org", Auth: federation.CertificateAuth}},
+			},
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("stream:stream").
+					WithAttribute(stravaganza.Namespace, "jabber:server").
+					WithAttribute(stravaganza.StreamNamespace, "http://etherx.jabber.org/streams").
+					WithAttribute(stravaganza.From, "jabber.org").
+					WithAttribute(stravaganza.To, "localhost").
+					WithAttribute(stravaganza.Version, "1.0").
+					Build(), nil
+			},
+			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:server' xmlns:stream='http://etherx.jabber.org/streams' id='s2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>EXTERNAL</mechanism></mechanisms><bidi xmlns='urn:xmpp:features:bidi'/></stream:features>`,
+			expectedState:  inConnected,
+		},
 		{
 			name:  "Connected/StartTLS",
 			state: inConnected,
@@ -161,6 +201,18 @@ func TestInS2S_HandleSessionElement(t *testing.T) {
 			expectedOutput: `<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>`,
 			expectedState:  inConnecting,
 		},
+		{
+			name:  "Connected/Bidi",
+			state: inConnected,
+			flags: fSecured,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("bidi").
+					WithAttribute(stravaganza.Namespace, bidiNamespace).
+					Build(), nil
+			},
+			expectedState: inConnected,
+			expectedFlags: fSecured | fBidi,
+		},
 		{
 			name:   "Connected/Authenticate",
 			state:  inConnected,
@@ -232,6 +284,41 @@ func TestInS2S_HandleSessionElement(t *testing.T) {
 			expectedState:    inConnected,
 			expectedFlags:    fSecured | fAuthenticated | fDialbackKeyAuthorized,
 		},
+		{
+			name:   "Connected/AuthorizeDialbackKeyCertificateAuthRequired",
+			state:  inConnected,
+			sender: "jabber.org",
+			target: "jackal.im",
+			flags:  fSecured,
+			policy: federation.Config{
+				Domains: []federation.DomainConfig{{Domain: "jabber.org", Auth: federation.CertificateAuth}},
+			},
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("db:result").
+					WithAttribute(stravaganza.From, "jabber.org").
+					WithAttribute(stravaganza.To, "jackal.im").
+					WithText("7b909f82401feae55b75289e73d73d0889f1713ae838817feed18bdf427eb03c").
+					Build(), nil
+			},
+			expectedOutput: `<db:result from='jackal.im' to='jabber.org' type='error'>7b909f82401feae55b75289e73d73d0889f1713ae838817feed18bdf427eb03c<error code='405' type='auth'><not-authorized xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></db:result>`,
+			expectedState:  inConnected,
+		},
+		{
+			name:   "Connected/AuthorizeDialbackKeyUntrustedCertificate",
+			state:  inConnected,
+			sender: "konuro.net",
+			target: "jackal.im",
+			flags:  fSecured,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("db:result").
+					WithAttribute(stravaganza.From, "konuro.net").
+					WithAttribute(stravaganza.To, "jackal.im").
+					WithText("7b909f82401feae55b75289e73d73d0889f1713ae838817feed18bdf427eb03c").
+					Build(), nil
+			},
+			expectedOutput: `<db:result from='jackal.im' to='konuro.net' type='error'>7b909f82401feae55b75289e73d73d0889f1713ae838817feed18bdf427eb03c<error code='405' type='auth'><not-authorized xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></db:result>`,
+			expectedState:  inConnected,
+		},
 		{
 			name:  "Connected/RouteIQSuccess",
 			state: inConnected,
@@ -325,12 +412,13 @@ func TestInS2S_HandleSessionElement(t *testing.T) {
 			modsMock := &modulesMock{}
 
 			// transport mock
+			cert, _ := testCertificate(t, "jabber.org", []string{"jabber.org"}, nil, nil)
 			trMock.PeerCertificatesFunc = func() []*x509.Certificate {
-				cert := &x509.Certificate{
-					DNSNames: []string{"jabber.org"},
-				}
 				return []*x509.Certificate{cert}
 			}
+			certVrf, _ := NewCertVerifier(TLSConfig{
+				Pins: []PinConfig{{Domain: "jabber.org", SPKI: []string{SPKIHash(cert)}}},
+			})
 			trMock.TypeFunc = func() transport.Type { return transport.Socket }
 			trMock.StartTLSFunc = func(cfg *tls.Config, asClient bool) {}
 			trMock.SetReadRateLimiterFunc = func(rLim *rate.Limiter) error { return nil }
@@ -428,6 +516,9 @@ func TestInS2S_HandleSessionElement(t *testing.T) {
 				kv:          kvMock,
 				router:      routerMock,
 				mods:        modsMock,
+				policy:      testPolicy(tt.policy),
+				inHub:       NewInHub(kitlog.NewNopLogger()),
+				certVrf:     certVrf,
 				comps:       compsMock,
 				session:     ssMock,
 				outProvider: outProviderMock,
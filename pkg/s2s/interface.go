@@ -25,6 +25,7 @@ import (
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 	"github.com/ortuman/jackal/pkg/transport"
+	dnsutil "github.com/ortuman/jackal/pkg/util/dns"
 )
 
 //go:generate moq -out kv.mock_test.go . kvStorage:kvMock
@@ -37,6 +38,11 @@ type globalRouter interface {
 	router.Router
 }
 
+//go:generate moq -out c2srouter.mock_test.go . c2sRouter
+type c2sRouter interface {
+	router.C2SRouter
+}
+
 //go:generate moq -out transport.mock_test.go . s2sTransport:transportMock
 type s2sTransport interface {
 	transport.Transport
@@ -86,6 +92,9 @@ type outProvider interface {
 	DialbackSecret() string
 	GetOut(ctx context.Context, sender, target string) (stream.S2SOut, error)
 	GetDialback(ctx context.Context, sender, target string, params DialbackParams) (stream.S2SDialback, error)
+
+	registerBidi(stm s2sOut) bool
+	unregisterBidi(stm s2sOut)
 }
 
 //go:generate moq -out s2sin.mock_test.go . s2sIn
@@ -98,6 +107,7 @@ type s2sOut interface {
 	stream.S2SOut
 	dial(ctx context.Context) error
 	start() error
+	ready() bool
 }
 
 //go:generate moq -out s2sdialback.mock_test.go . s2sDialback
@@ -106,3 +116,13 @@ type s2sDialback interface {
 	dial(ctx context.Context) error
 	start() error
 }
+
+//go:generate moq -out poshfetcher.mock_test.go . poshFetcher
+type poshFetcher interface {
+	POSHFetcher
+}
+
+//go:generate moq -out tlsaresolver.mock_test.go . tlsaResolver
+type tlsaResolver interface {
+	dnsutil.TLSAResolver
+}
@@ -106,6 +106,24 @@ var (
 		},
 		[]string{"instance"},
 	)
+	s2sOutgoingQueueLength = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "jackal",
+			Subsystem: "s2s",
+			Name:      "outgoing_queue_length",
+			Help:      "Number of outgoing stanzas held while remote server is unreachable.",
+		},
+		[]string{"instance", "sender", "domain"},
+	)
+	s2sCertificateVerificationFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "s2s",
+			Name:      "certificate_verification_failures_total",
+			Help:      "The total number of peer certificate verification failures.",
+		},
+		[]string{"instance", "direction", "reason"},
+	)
 )
 
 func init() {
@@ -118,6 +136,8 @@ func init() {
 	prometheus.MustRegister(s2sIncomingRequestDurationBucket)
 	prometheus.MustRegister(s2sIncomingTotalConnections)
 	prometheus.MustRegister(s2sOutgoingTotalConnections)
+	prometheus.MustRegister(s2sOutgoingQueueLength)
+	prometheus.MustRegister(s2sCertificateVerificationFailures)
 }
 
 func reportIncomingConnectionRegistered() {
@@ -182,3 +202,25 @@ func reportTotalOutgoingConnections(totalConns int) {
 	}
 	s2sOutgoingTotalConnections.With(metricLabel).Set(float64(totalConns))
 }
+
+func reportOutgoingQueueLength(sender, domain string, length int) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+		"sender":   sender,
+		"domain":   domain,
+	}
+	if length == 0 {
+		s2sOutgoingQueueLength.Delete(metricLabel)
+		return
+	}
+	s2sOutgoingQueueLength.With(metricLabel).Set(float64(length))
+}
+
+func reportCertificateVerificationFailure(direction, reason string) {
+	metricLabel := prometheus.Labels{
+		"instance":  instance.ID(),
+		"direction": direction,
+		"reason":    reason,
+	}
+	s2sCertificateVerificationFailures.With(metricLabel).Inc()
+}
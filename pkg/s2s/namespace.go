@@ -19,4 +19,7 @@ const (
 	saslNamespace     = "urn:ietf:params:xml:ns:xmpp-sasl"
 	tlsNamespace      = "urn:ietf:params:xml:ns:xmpp-tls"
 	dialbackNamespace = "urn:xmpp:features:dialback"
+
+	bidiFeatureNamespace = "urn:xmpp:features:bidi"
+	bidiNamespace        = "urn:xmpp:bidi"
 )
@@ -36,6 +36,7 @@ import (
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
 	xmppsession "github.com/ortuman/jackal/pkg/session"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/transport"
@@ -93,6 +94,8 @@ type outConfig struct {
 	dialTimeout   time.Duration
 	reqTimeout    time.Duration
 	maxStanzaSize int
+	auth          string
+	certVerifier  *CertVerifier
 }
 
 type outS2S struct {
@@ -113,6 +116,8 @@ type outS2S struct {
 	hk       *hook.Hooks
 	logger   kitlog.Logger
 	rq       *runqueue.RunQueue
+	bidiInFn func(out *outS2S) *inS2S
+	bidiIn   *inS2S
 
 	mu           sync.RWMutex
 	state        outState
@@ -144,8 +149,9 @@ func newOutS2S(
 		shapers: shapers,
 		hk:      hk,
 		logger:  kitlog.With(logger, "sender", sender, "target", target),
-		dialer:  newDialer(cfg.dialTimeout, tlsCfg),
 	}
+	tlsCfg.VerifyConnection = stm.verifyConnection
+	stm.dialer = newDialer(cfg.dialTimeout, tlsCfg)
 	stm.rq = runqueue.New(stm.ID().String())
 	return stm
 }
@@ -168,11 +174,12 @@ func newDialbackS2S(
 		tlsCfg:   tlsCfg,
 		cfg:      cfg,
 		dbParams: dbParams,
-		dialer:   newDialer(cfg.dialTimeout, tlsCfg),
 		dbResCh:  make(chan stream.DialbackResult, 1),
 		shapers:  shapers,
 		logger:   logger,
 	}
+	tlsCfg.VerifyConnection = stm.verifyConnection
+	stm.dialer = newDialer(cfg.dialTimeout, tlsCfg)
 	stm.rq = runqueue.New(stm.ID().String())
 	return stm
 }
@@ -332,6 +339,8 @@ func (s *outS2S) handleElement(ctx context.Context, elem stravaganza.Element) er
 		err = s.handleVerifyingDialbackKey(ctx, elem)
 	case outAuthorizingDialbackKey:
 		err = s.handleAuthorizingDialbackKey(ctx, elem)
+	case outAuthenticated:
+		err = s.handleAuthenticated(ctx, elem)
 	}
 	reportIncomingRequest(
 		elem.Name(),
@@ -367,8 +376,19 @@ func (s *outS2S) handleConnected(ctx context.Context, elem stravaganza.Element)
 	}
 	switch s.typ {
 	case defaultType:
+		if hasBidiFeature(elem) && s.bidiInFn != nil {
+			// request bidirectional stream (XEP-0288)
+			err := s.sendElement(ctx, stravaganza.NewBuilder("bidi").
+				WithAttribute(stravaganza.Namespace, bidiNamespace).
+				Build(),
+			)
+			if err != nil {
+				return err
+			}
+			s.flags.setBidi()
+		}
 		switch {
-		case hasExternalAuthMechanism(elem):
+		case hasExternalAuthMechanism(elem) && s.cfg.auth != federation.DialbackAuth && !s.flags.isUntrustedCertificate():
 			s.setState(outAuthenticating)
 			return s.sendElement(ctx, stravaganza.NewBuilder("auth").
 				WithAttribute(stravaganza.Namespace, saslNamespace).
@@ -377,7 +397,7 @@ func (s *outS2S) handleConnected(ctx context.Context, elem stravaganza.Element)
 				Build(),
 			)
 
-		case hasDialbackFeature(elem):
+		case hasDialbackFeature(elem) && s.cfg.auth != federation.CertificateAuth:
 			streamID := s.session.StreamID()
 
 			// register dialback request
@@ -416,6 +436,10 @@ func (s *outS2S) handleConnected(ctx context.Context, elem stravaganza.Element)
 	return nil
 }
 
+func (s *outS2S) ready() bool {
+	return s.getState() == outAuthenticated
+}
+
 func (s *outS2S) handleSecuring(ctx context.Context, elem stravaganza.Element) error {
 	if elem.Name() != "proceed" {
 		return s.disconnect(ctx, streamerror.E(streamerror.UnsupportedStanzaType))
@@ -431,6 +455,29 @@ func (s *outS2S) handleSecuring(ctx context.Context, elem stravaganza.Element) e
 	return s.session.OpenStream(ctx)
 }
 
+func (s *outS2S) verifyConnection(cs tls.ConnectionState) error {
+	var host string
+	var port int
+	if s.dialer != nil {
+		host, port = s.dialer.dialedService()
+	}
+	err := s.cfg.certVerifier.VerifyService(s.target, host, port, cs.PeerCertificates)
+	if err == nil {
+		return nil
+	}
+	reason := certFailureReason(err)
+	reportCertificateVerificationFailure("out", reason)
+
+	if !s.cfg.certVerifier.DialbackFallback() {
+		level.Warn(s.logger).Log("msg", "failed to verify S2S peer certificate", "reason", reason, "err", err)
+		return err
+	}
+	level.Warn(s.logger).Log("msg", "failed to verify S2S peer certificate, falling back to dialback", "reason", reason, "err", err)
+
+	s.flags.setUntrustedCertificate()
+	return nil
+}
+
 func (s *outS2S) handleAuthenticating(ctx context.Context, elem stravaganza.Element) error {
 	if elem.Attribute(stravaganza.Namespace) != saslNamespace {
 		return s.disconnect(ctx, streamerror.E(streamerror.InvalidNamespace))
@@ -485,6 +532,14 @@ func (s *outS2S) handleAuthorizingDialbackKey(ctx context.Context, elem stravaga
 	}
 }
 
+func (s *outS2S) handleAuthenticated(ctx context.Context, elem stravaganza.Element) error {
+	if s.bidiIn == nil {
+		return nil
+	}
+	// process incoming traffic as any other incoming stream would do
+	return s.bidiIn.handleConnected(ctx, elem)
+}
+
 func (s *outS2S) handleSessionError(ctx context.Context, err error) {
 	switch err {
 	case xmppparser.ErrStreamClosedByPeer:
@@ -499,6 +554,11 @@ func (s *outS2S) handleSessionError(ctx context.Context, err error) {
 func (s *outS2S) finishAuthentication(ctx context.Context) error {
 	s.setState(outAuthenticated)
 
+	if s.flags.isBidi() {
+		s.bidiIn = s.bidiInFn(s)
+		level.Info(s.logger).Log("msg", "negotiated bidirectional S2S out stream")
+	}
+
 	// send pending elements
 	for _, elem := range s.pendingQueue {
 		if err := s.sendElement(ctx, elem); err != nil {
@@ -622,6 +682,10 @@ func hasExternalAuthMechanism(streamFeatures stravaganza.Element) bool {
 	return false
 }
 
+func hasBidiFeature(streamFeatures stravaganza.Element) bool {
+	return streamFeatures.ChildNamespace("bidi", bidiFeatureNamespace) != nil
+}
+
 func hasDialbackFeature(streamFeatures stravaganza.Element) bool {
 	return streamFeatures.ChildrenNamespace("dialback", dialbackNamespace) != nil
 }
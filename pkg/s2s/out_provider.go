@@ -17,6 +17,7 @@ package s2s
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"sync"
 	"time"
 
@@ -24,14 +25,18 @@ import (
 
 	"github.com/go-kit/log/level"
 
+	"github.com/jackal-xmpp/stravaganza"
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/ortuman/jackal/pkg/cluster/kv"
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
 	"github.com/ortuman/jackal/pkg/shaper"
 )
 
+var errFederationNotAllowed = errors.New("s2s: federation not allowed by policy")
+
 // OutProvider is an outgoing S2S stream provider.
 type OutProvider struct {
 	cfg     OutConfig
@@ -39,6 +44,8 @@ type OutProvider struct {
 	kv      kv.KV
 	shapers shaper.Shapers
 	hk      *hook.Hooks
+	policy  *federation.Policy
+	certVrf *CertVerifier
 	logger  kitlog.Logger
 
 	mu         sync.RWMutex
@@ -47,6 +54,9 @@ type OutProvider struct {
 
 	newOutFn func(sender, target string) s2sOut
 	newDbFn  func(sender, target string, dbParam DialbackParams) s2sDialback
+	bidiInFn func(out *outS2S) *inS2S
+
+	undeliveredFn func(sender, target string, elems []stravaganza.Element)
 }
 
 // NewOutProvider creates and initializes a new OutProvider instance.
@@ -56,6 +66,8 @@ func NewOutProvider(
 	kv kv.KV,
 	shapers shaper.Shapers,
 	hk *hook.Hooks,
+	policy *federation.Policy,
+	certVrf *CertVerifier,
 	logger kitlog.Logger,
 ) *OutProvider {
 	op := &OutProvider{
@@ -64,6 +76,8 @@ func NewOutProvider(
 		shapers:    shapers,
 		kv:         kv,
 		hk:         hk,
+		policy:     policy,
+		certVrf:    certVrf,
 		logger:     logger,
 		outStreams: make(map[string]s2sOut),
 		doneCh:     make(chan chan struct{}),
@@ -80,6 +94,9 @@ func (p *OutProvider) DialbackSecret() string {
 
 // GetOut returns associated outgoing S2S stream given a sender-target pair domain.
 func (p *OutProvider) GetOut(ctx context.Context, sender, target string) (stream.S2SOut, error) {
+	if !p.policy.IsAllowed(target) {
+		return nil, errFederationNotAllowed
+	}
 	domainPair := getDomainPair(sender, target)
 
 	p.mu.RLock()
@@ -185,16 +202,45 @@ func (p *OutProvider) Stop(ctx context.Context) error {
 	return nil
 }
 
+func (p *OutProvider) registerBidi(stm s2sOut) bool {
+	id := stm.ID()
+	domainPair := getDomainPair(id.Sender, id.Target)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.outStreams[domainPair]; ok {
+		return false // keep using already established outgoing stream
+	}
+	p.outStreams[domainPair] = stm
+	return true
+}
+
+func (p *OutProvider) unregisterBidi(stm s2sOut) {
+	id := stm.ID()
+	domainPair := getDomainPair(id.Sender, id.Target)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.outStreams[domainPair] == stm {
+		delete(p.outStreams, domainPair)
+	}
+}
+
 func (p *OutProvider) unregister(stm *outS2S) {
 	id := stm.ID()
 	domainPair := getDomainPair(id.Sender, id.Target)
 	p.mu.Lock()
 	delete(p.outStreams, domainPair)
 	p.mu.Unlock()
+
+	// stream closed before being able to deliver pending stanzas
+	if len(stm.pendingQueue) > 0 && p.undeliveredFn != nil {
+		p.undeliveredFn(id.Sender, id.Target, stm.pendingQueue)
+	}
 }
 
 func (p *OutProvider) newOutS2S(sender, target string) s2sOut {
-	return newOutS2S(
+	stm := newOutS2S(
 		sender,
 		target,
 		p.tlsConfig(target),
@@ -209,8 +255,12 @@ func (p *OutProvider) newOutS2S(sender, target string) s2sOut {
 			dialTimeout:   p.cfg.DialTimeout,
 			reqTimeout:    p.cfg.RequestTimeout,
 			maxStanzaSize: p.cfg.MaxStanzaSize,
+			auth:          p.policy.AuthMethod(target),
+			certVerifier:  p.certVrf,
 		},
 	)
+	stm.bidiInFn = p.bidiInFn
+	return stm
 }
 
 func (p *OutProvider) newDialbackS2S(sender, target string, dbParams DialbackParams) s2sDialback {
@@ -226,6 +276,7 @@ func (p *OutProvider) newDialbackS2S(sender, target string, dbParams DialbackPar
 			dialTimeout:   p.cfg.DialTimeout,
 			reqTimeout:    p.cfg.RequestTimeout,
 			maxStanzaSize: p.cfg.MaxStanzaSize,
+			certVerifier:  p.certVrf,
 		},
 		dbParams,
 	)
@@ -235,6 +286,8 @@ func (p *OutProvider) tlsConfig(serverName string) *tls.Config {
 	return &tls.Config{
 		ServerName:   serverName,
 		Certificates: p.hosts.Certificates(),
+		// peer certificate is validated by the outgoing stream in order to honor custom trust anchors and pinned keys
+		InsecureSkipVerify: true,
 	}
 }
 
@@ -19,12 +19,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ortuman/jackal/pkg/s2s/federation"
 	"github.com/stretchr/testify/require"
 )
 
 func TestOutProvider_GetOut(t *testing.T) {
 	// given
 	op := &OutProvider{
+		policy:     testPolicy(federation.Config{}),
 		outStreams: make(map[string]s2sOut),
 	}
 	var out *s2sOutMock
@@ -48,6 +50,30 @@ func TestOutProvider_GetOut(t *testing.T) {
 	require.Len(t, conn1.(*s2sOutMock).dialCalls(), 1)
 }
 
+func TestOutProvider_GetOutNotAllowed(t *testing.T) {
+	// given
+	op := &OutProvider{
+		policy: testPolicy(federation.Config{
+			Mode:  federation.AllowlistMode,
+			Allow: []string{"jabber.org"},
+		}),
+		outStreams: make(map[string]s2sOut),
+	}
+	op.newOutFn = func(sender, target string) s2sOut {
+		out := &s2sOutMock{}
+		out.dialFunc = func(ctx context.Context) error { return nil }
+		out.startFunc = func() error { return nil }
+		return out
+	}
+
+	// when
+	_, err := op.GetOut(context.Background(), "jackal.im", "evil.org")
+
+	// then
+	require.Equal(t, errFederationNotAllowed, err)
+	require.Len(t, op.outStreams, 0)
+}
+
 func TestOutProvider_GetDialback(t *testing.T) {
 	// given
 	op := &OutProvider{
@@ -75,3 +101,8 @@ func TestOutProvider_GetDialback(t *testing.T) {
 	require.Len(t, conn2.(*s2sDialbackMock).startCalls(), 1)
 	require.Len(t, conn2.(*s2sDialbackMock).dialCalls(), 1)
 }
+
+func testPolicy(cfg federation.Config) *federation.Policy {
+	p, _ := federation.New(cfg)
+	return p
+}
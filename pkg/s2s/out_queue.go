@@ -0,0 +1,234 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/ortuman/jackal/pkg/router"
+)
+
+const bounceTimeout = time.Second * 5
+
+type queuedStanza struct {
+	stanza   stravaganza.Stanza
+	queuedAt time.Time
+}
+
+// outQueue holds the stanzas addressed to an unreachable remote domain.
+type outQueue struct {
+	sender  string
+	target  string
+	stanzas []queuedStanza
+}
+
+func (r *s2sRouter) isQueueEnabled() bool {
+	return !r.queueCfg.Disabled && r.queueCfg.TTL > 0
+}
+
+// enqueue holds stanza into sender-target pair queue, creating it if required and create is true.
+// It returns false if stanza hasn't been queued.
+func (r *s2sRouter) enqueue(sender, target string, stanza stravaganza.Stanza, create bool) (bool, error) {
+	domainPair := getDomainPair(sender, target)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q := r.queues[domainPair]
+	if q == nil {
+		if !create {
+			return false, nil
+		}
+		q = &outQueue{sender: sender, target: target}
+		r.queues[domainPair] = q
+
+		go r.retry(sender, target)
+
+		level.Info(r.logger).Log("msg", "holding outgoing S2S stanzas until remote server becomes reachable",
+			"sender", sender, "target", target,
+		)
+	}
+	if len(q.stanzas) >= r.queueCfg.MaxSize {
+		return true, router.ErrRemoteServerTimeout
+	}
+	q.stanzas = append(q.stanzas, queuedStanza{stanza: stanza, queuedAt: time.Now()})
+	reportOutgoingQueueLength(sender, target, len(q.stanzas))
+	return true, nil
+}
+
+// requeue holds those stanzas that couldn't be delivered by a closed outgoing stream.
+func (r *s2sRouter) requeue(sender, target string, elems []stravaganza.Element) {
+	for _, elem := range elems {
+		stanza, ok := elem.(stravaganza.Stanza)
+		if !ok {
+			continue
+		}
+		if r.isQueueEnabled() {
+			if _, err := r.enqueue(sender, target, stanza, true); err == nil {
+				continue
+			}
+		}
+		r.bounce(stanza)
+	}
+}
+
+func (r *s2sRouter) retry(sender, target string) {
+	backoff := r.queueCfg.MinBackoff
+	nextAttempt := time.Now().Add(backoff)
+	for {
+		// wake up earlier in case any held stanza expires before next attempt
+		untilExpiration, ok := r.untilExpiration(sender, target)
+		if !ok {
+			return
+		}
+		wait := time.Until(nextAttempt)
+		if untilExpiration < wait {
+			wait = untilExpiration
+		}
+		select {
+		case <-time.After(wait):
+			break
+		case <-r.doneCh:
+			return
+		}
+		if time.Now().Before(nextAttempt) {
+			if released := r.expire(sender, target); released {
+				return
+			}
+			continue
+		}
+		stm, err := r.outProvider.GetOut(context.Background(), sender, target)
+		if err == nil {
+			if out, ok := stm.(s2sOut); !ok || out.ready() {
+				r.flush(sender, target, stm.SendElement)
+				return
+			}
+			backoff = r.queueCfg.MinBackoff // wait for stream to be negotiated
+		} else {
+			level.Debug(r.logger).Log("msg", "failed to reach remote server",
+				"sender", sender, "target", target, "err", err,
+			)
+			backoff *= 2
+			if backoff > r.queueCfg.MaxBackoff {
+				backoff = r.queueCfg.MaxBackoff
+			}
+		}
+		nextAttempt = time.Now().Add(backoff)
+
+		if released := r.expire(sender, target); released {
+			return
+		}
+	}
+}
+
+// untilExpiration returns the time left for the oldest held stanza to expire,
+// and false in case there's no queue for the sender-target pair.
+func (r *s2sRouter) untilExpiration(sender, target string) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q := r.queues[getDomainPair(sender, target)]
+	if q == nil {
+		return 0, false
+	}
+	if len(q.stanzas) == 0 {
+		return 0, true
+	}
+	d := time.Until(q.stanzas[0].queuedAt.Add(r.queueCfg.TTL))
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+func (r *s2sRouter) flush(sender, target string, sendFn func(elem stravaganza.Element) <-chan error) {
+	domainPair := getDomainPair(sender, target)
+
+	r.mu.Lock()
+	q := r.queues[domainPair]
+	delete(r.queues, domainPair)
+	reportOutgoingQueueLength(sender, target, 0)
+	r.mu.Unlock()
+
+	if q == nil {
+		return
+	}
+	// send once the queue has been released, so that concurrent routing isn't blocked meanwhile
+	for _, qs := range q.stanzas {
+		_ = sendFn(qs.stanza)
+	}
+	level.Info(r.logger).Log("msg", "delivered held outgoing S2S stanzas",
+		"sender", sender, "target", target, "count", len(q.stanzas),
+	)
+}
+
+// expire bounces expired stanzas, and returns true in case the queue ended up being released.
+func (r *s2sRouter) expire(sender, target string) bool {
+	domainPair := getDomainPair(sender, target)
+
+	r.mu.Lock()
+	q := r.queues[domainPair]
+	if q == nil {
+		r.mu.Unlock()
+		return true
+	}
+	var expired []stravaganza.Stanza
+	var held []queuedStanza
+	for _, qs := range q.stanzas {
+		if time.Since(qs.queuedAt) >= r.queueCfg.TTL {
+			expired = append(expired, qs.stanza)
+			continue
+		}
+		held = append(held, qs)
+	}
+	q.stanzas = held
+
+	released := len(held) == 0
+	if released {
+		delete(r.queues, domainPair)
+	}
+	reportOutgoingQueueLength(sender, target, len(held))
+	r.mu.Unlock()
+
+	if len(expired) > 0 {
+		level.Info(r.logger).Log("msg", "bouncing expired outgoing S2S stanzas",
+			"sender", sender, "target", target, "count", len(expired),
+		)
+	}
+	for _, stanza := range expired {
+		r.bounce(stanza)
+	}
+	return released
+}
+
+func (r *s2sRouter) bounce(stanza stravaganza.Stanza) {
+	if stanza.Attribute(stravaganza.Type) == stravaganza.ErrorType {
+		return // never bounce an error
+	}
+	errStanza, err := stanzaerror.E(stanzaerror.RemoteServerTimeout, stanza).Stanza(false)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), bounceTimeout)
+	defer cancel()
+
+	if _, err := r.c2sRouter.Route(ctx, errStanza, 0); err != nil {
+		level.Debug(r.logger).Log("msg", "failed to bounce outgoing S2S stanza", "err", err)
+	}
+}
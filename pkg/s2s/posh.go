@@ -0,0 +1,180 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	poshWellKnownPath = "/.well-known/posh/xmpp-server.json"
+
+	poshMaxDocumentSize = 64 * 1024
+	poshMaxCacheTTL     = time.Hour * 24
+)
+
+var errPOSHRedirectLoop = errors.New("s2s: POSH redirect points to another redirect")
+
+// POSHDocument represents a POSH document (RFC 7711) published by a domain.
+type POSHDocument struct {
+	// Fingerprints contains the set of certificate fingerprints, each one keyed by its hash algorithm name.
+	Fingerprints []map[string]string `json:"fingerprints,omitempty"`
+
+	// Expires is the number of seconds the document can be cached.
+	Expires int64 `json:"expires"`
+
+	// URL, if set, references the document where the actual fingerprints are published.
+	URL string `json:"url,omitempty"`
+}
+
+// Matches tells whether cert matches any of document fingerprints.
+func (d *POSHDocument) Matches(cert *x509.Certificate) bool {
+	for _, fp := range d.Fingerprints {
+		for alg, b64 := range fp {
+			var h []byte
+			switch strings.ToLower(alg) {
+			case "sha-256":
+				s := sha256.Sum256(cert.Raw)
+				h = s[:]
+			case "sha-512":
+				s := sha512.Sum512(cert.Raw)
+				h = s[:]
+			default:
+				continue
+			}
+			b, err := base64.StdEncoding.DecodeString(b64)
+			if err != nil {
+				continue
+			}
+			if subtle.ConstantTimeCompare(h, b) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// POSHFetcher defines POSH document retrieval interface.
+type POSHFetcher interface {
+	// FetchPOSH returns the xmpp-server POSH document published by domain.
+	FetchPOSH(ctx context.Context, domain string) (*POSHDocument, error)
+}
+
+type cachedPOSHDocument struct {
+	doc       *POSHDocument
+	expiresAt time.Time
+}
+
+type httpPOSHFetcher struct {
+	client *http.Client
+
+	mu    sync.RWMutex
+	cache map[string]cachedPOSHDocument
+}
+
+// NewPOSHFetcher returns a POSHFetcher that retrieves documents over HTTPS, caching them
+// as long as indicated by their expiration value.
+func NewPOSHFetcher(timeout time.Duration) POSHFetcher {
+	return &httpPOSHFetcher{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+				return http.ErrUseLastResponse // HTTP redirects are not allowed (RFC 7711, section 3.2)
+			},
+		},
+		cache: make(map[string]cachedPOSHDocument),
+	}
+}
+
+func (f *httpPOSHFetcher) FetchPOSH(ctx context.Context, domain string) (*POSHDocument, error) {
+	domain = strings.ToLower(domain)
+
+	f.mu.RLock()
+	cached, ok := f.cache[domain]
+	f.mu.RUnlock()
+
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.doc, nil
+	}
+	doc, err := f.fetch(ctx, (&url.URL{Scheme: "https", Host: domain, Path: poshWellKnownPath}).String())
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.URL) > 0 {
+		u, err := url.Parse(doc.URL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "https" {
+			return nil, fmt.Errorf("s2s: POSH redirect must use https scheme: %s", doc.URL)
+		}
+		redirectDoc, err := f.fetch(ctx, doc.URL)
+		if err != nil {
+			return nil, err
+		}
+		if len(redirectDoc.URL) > 0 {
+			return nil, errPOSHRedirectLoop
+		}
+		// use the shortest expiration of both documents
+		if doc.Expires < redirectDoc.Expires {
+			redirectDoc.Expires = doc.Expires
+		}
+		doc = redirectDoc
+	}
+	if ttl := time.Duration(doc.Expires) * time.Second; ttl > 0 {
+		if ttl > poshMaxCacheTTL {
+			ttl = poshMaxCacheTTL
+		}
+		f.mu.Lock()
+		f.cache[domain] = cachedPOSHDocument{doc: doc, expiresAt: time.Now().Add(ttl)}
+		f.mu.Unlock()
+	}
+	return doc, nil
+}
+
+func (f *httpPOSHFetcher) fetch(ctx context.Context, u string) (*POSHDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("s2s: unexpected POSH response status code: %d", resp.StatusCode)
+	}
+	var doc POSHDocument
+	if err := json.NewDecoder(io.LimitReader(resp.Body, poshMaxDocumentSize)).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
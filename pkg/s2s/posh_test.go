@@ -0,0 +1,104 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestPOSHFetcher_Fetch(t *testing.T) {
+	// given
+	cert, _ := testCertificate(t, "xmpp.hosting.net", []string{"xmpp.hosting.net"}, nil, nil)
+	fp := sha256.Sum256(cert.Raw)
+
+	var requested []string
+	f := NewPOSHFetcher(time.Second).(*httpPOSHFetcher)
+	f.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"fingerprints":[{"sha-256":"` + base64.StdEncoding.EncodeToString(fp[:]) + `"}],"expires":3600}`)),
+		}, nil
+	})
+
+	// when
+	doc1, err1 := f.FetchPOSH(context.Background(), "jackal.im")
+	doc2, err2 := f.FetchPOSH(context.Background(), "jackal.im")
+
+	// then
+	require.Nil(t, err1)
+	require.Nil(t, err2)
+	require.Equal(t, []string{"https://jackal.im/.well-known/posh/xmpp-server.json"}, requested) // cached
+
+	require.True(t, doc1.Matches(cert))
+	require.Equal(t, doc1, doc2)
+}
+
+func TestPOSHFetcher_FetchRedirect(t *testing.T) {
+	// given
+	f := NewPOSHFetcher(time.Second).(*httpPOSHFetcher)
+	f.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body string
+		switch req.URL.Host {
+		case "jackal.im":
+			body = `{"url":"https://hosting.net/posh/jackal.im.json","expires":60}`
+		default:
+			body = `{"fingerprints":[{"sha-256":"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}],"expires":3600}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	// when
+	doc, err := f.FetchPOSH(context.Background(), "jackal.im")
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, doc.Fingerprints, 1)
+	require.Equal(t, int64(60), doc.Expires)
+}
+
+func TestPOSHFetcher_FetchNotFound(t *testing.T) {
+	// given
+	f := NewPOSHFetcher(time.Second).(*httpPOSHFetcher)
+	f.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+
+	// when
+	doc, err := f.FetchPOSH(context.Background(), "jackal.im")
+
+	// then
+	require.Nil(t, doc)
+	require.NotNil(t, err)
+}
@@ -17,6 +17,9 @@ package s2s
 import (
 	"context"
 	"errors"
+	"sync"
+
+	kitlog "github.com/go-kit/log"
 
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/ortuman/jackal/pkg/router"
@@ -24,23 +27,48 @@ import (
 
 type s2sRouter struct {
 	outProvider outProvider
+	c2sRouter   router.C2SRouter
+	queueCfg    OutQueueConfig
+	logger      kitlog.Logger
+
+	mu     sync.Mutex
+	queues map[string]*outQueue
+	doneCh chan struct{}
 }
 
 // NewRouter creates and returns an initialized S2S router.
-func NewRouter(outProvider *OutProvider) router.S2SRouter {
-	return &s2sRouter{
+// Whenever a remote server can't be reached, routed stanzas will be held and delivered
+// as soon as connection gets established, or bounced to their senders through c2sRouter once expired.
+func NewRouter(outProvider *OutProvider, c2sRouter router.C2SRouter, queueCfg OutQueueConfig, logger kitlog.Logger) router.S2SRouter {
+	r := &s2sRouter{
 		outProvider: outProvider,
+		c2sRouter:   c2sRouter,
+		queueCfg:    queueCfg,
+		logger:      logger,
+		queues:      make(map[string]*outQueue),
+		doneCh:      make(chan struct{}),
 	}
+	outProvider.undeliveredFn = r.requeue
+	return r
 }
 
 func (r *s2sRouter) Route(ctx context.Context, stanza stravaganza.Stanza, senderDomain string) error {
 	remoteJID := stanza.ToJID()
 	targetDomain := remoteJID.Domain()
 
+	// remote server still unreachable?
+	if queued, err := r.enqueue(senderDomain, targetDomain, stanza, false); queued {
+		return err
+	}
 	stm, err := r.outProvider.GetOut(ctx, senderDomain, targetDomain)
 	switch {
 	case err == nil:
 		break
+	case errors.Is(err, errFederationNotAllowed):
+		return router.ErrRemoteServerNotFound
+	case r.isQueueEnabled():
+		_, err := r.enqueue(senderDomain, targetDomain, stanza, true)
+		return err
 	case errors.Is(err, errServerTimeout):
 		return router.ErrRemoteServerTimeout
 	default:
@@ -55,5 +83,8 @@ func (r *s2sRouter) Start(_ context.Context) error {
 }
 
 func (r *s2sRouter) Stop(_ context.Context) error {
+	if r.doneCh != nil {
+		close(r.doneCh)
+	}
 	return nil
 }
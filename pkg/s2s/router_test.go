@@ -17,9 +17,13 @@ package s2s
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	kitlog "github.com/go-kit/log"
 	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 	"github.com/stretchr/testify/require"
@@ -81,6 +85,159 @@ func TestS2sRouter_RouteServerGenericError(t *testing.T) {
 	require.Equal(t, router.ErrRemoteServerNotFound, err)
 }
 
+func TestS2sRouter_RouteQueuedUntilReachable(t *testing.T) {
+	// given
+	out := &s2sOutMock{}
+	out.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		return nil
+	}
+	out.readyFunc = func() bool { return true }
+
+	var attempts int32
+	op := &outProviderMock{}
+	op.GetOutFunc = func(ctx context.Context, sender string, target string) (stream.S2SOut, error) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return nil, errServerTimeout
+		}
+		return out, nil
+	}
+	r := &s2sRouter{
+		outProvider: op,
+		queueCfg:    testQueueConfig(),
+		logger:      kitlog.NewNopLogger(),
+		queues:      make(map[string]*outQueue),
+		doneCh:      make(chan struct{}),
+	}
+	defer func() { _ = r.Stop(context.Background()) }()
+
+	// when
+	err1 := r.Route(context.Background(), testMessageStanza(), "jackal.im")
+	err2 := r.Route(context.Background(), testMessageStanza(), "jackal.im")
+
+	time.Sleep(time.Millisecond * 250) // wait for retries
+
+	// then
+	require.Nil(t, err1)
+	require.Nil(t, err2)
+	require.Len(t, out.SendElementCalls(), 2)
+	require.Equal(t, 0, r.queueLen())
+}
+
+func TestS2sRouter_RouteQueuedExpired(t *testing.T) {
+	// given
+	op := &outProviderMock{}
+	op.GetOutFunc = func(ctx context.Context, sender string, target string) (stream.S2SOut, error) {
+		return nil, errServerTimeout
+	}
+	c2sRouterMock := &c2sRouterMock{}
+	c2sRouterMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza, routingOpts router.RoutingOptions) ([]jid.JID, error) {
+		return nil, nil
+	}
+	r := &s2sRouter{
+		outProvider: op,
+		c2sRouter:   c2sRouterMock,
+		queueCfg:    testQueueConfig(),
+		logger:      kitlog.NewNopLogger(),
+		queues:      make(map[string]*outQueue),
+		doneCh:      make(chan struct{}),
+	}
+	defer func() { _ = r.Stop(context.Background()) }()
+
+	// when
+	err := r.Route(context.Background(), testMessageStanza(), "jackal.im")
+
+	time.Sleep(time.Millisecond * 500) // wait for expiration
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 0, r.queueLen())
+
+	routeCalls := c2sRouterMock.RouteCalls()
+	require.Len(t, routeCalls, 1)
+
+	bounced := routeCalls[0].Stanza
+	require.Equal(t, stravaganza.ErrorType, bounced.Attribute(stravaganza.Type))
+	require.Equal(t, "noelia@jackal.im/yard", bounced.Attribute(stravaganza.To))
+	require.NotNil(t, bounced.Child("error").ChildNamespace("remote-server-timeout", "urn:ietf:params:xml:ns:xmpp-stanzas"))
+}
+
+func TestS2sRouter_RouteQueuedExpiredBeforeRetry(t *testing.T) {
+	// given
+	op := &outProviderMock{}
+	op.GetOutFunc = func(ctx context.Context, sender string, target string) (stream.S2SOut, error) {
+		return nil, errServerTimeout
+	}
+	c2sRouterMock := &c2sRouterMock{}
+	c2sRouterMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza, routingOpts router.RoutingOptions) ([]jid.JID, error) {
+		return nil, nil
+	}
+	cfg := testQueueConfig()
+	cfg.MinBackoff = time.Minute
+	cfg.MaxBackoff = time.Minute
+
+	r := &s2sRouter{
+		outProvider: op,
+		c2sRouter:   c2sRouterMock,
+		queueCfg:    cfg,
+		logger:      kitlog.NewNopLogger(),
+		queues:      make(map[string]*outQueue),
+		doneCh:      make(chan struct{}),
+	}
+	defer func() { _ = r.Stop(context.Background()) }()
+
+	// when
+	err := r.Route(context.Background(), testMessageStanza(), "jackal.im")
+
+	time.Sleep(time.Millisecond * 500) // wait for expiration
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 0, r.queueLen())
+	require.Len(t, c2sRouterMock.RouteCalls(), 1)
+}
+
+func TestS2sRouter_RouteQueueFull(t *testing.T) {
+	// given
+	op := &outProviderMock{}
+	op.GetOutFunc = func(ctx context.Context, sender string, target string) (stream.S2SOut, error) {
+		return nil, errServerTimeout
+	}
+	cfg := testQueueConfig()
+	cfg.MaxSize = 1
+
+	r := &s2sRouter{
+		outProvider: op,
+		queueCfg:    cfg,
+		logger:      kitlog.NewNopLogger(),
+		queues:      make(map[string]*outQueue),
+		doneCh:      make(chan struct{}),
+	}
+	defer func() { _ = r.Stop(context.Background()) }()
+
+	// when
+	err1 := r.Route(context.Background(), testMessageStanza(), "jackal.im")
+	err2 := r.Route(context.Background(), testMessageStanza(), "jackal.im")
+
+	// then
+	require.Nil(t, err1)
+	require.Equal(t, router.ErrRemoteServerTimeout, err2)
+}
+
+func (r *s2sRouter) queueLen() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.queues)
+}
+
+func testQueueConfig() OutQueueConfig {
+	return OutQueueConfig{
+		TTL:        time.Millisecond * 200,
+		MaxSize:    100,
+		MinBackoff: time.Millisecond * 10,
+		MaxBackoff: time.Millisecond * 40,
+	}
+}
+
 func testMessageStanza() *stravaganza.Message {
 	b := stravaganza.NewMessageBuilder()
 	b.WithAttribute("from", "noelia@jackal.im/yard")
@@ -32,6 +32,7 @@ import (
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/s2s/federation"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/transport"
 )
@@ -52,6 +53,8 @@ type SocketListener struct {
 	kv            kv.KV
 	shapers       shaper.Shapers
 	hk            *hook.Hooks
+	policy        *federation.Policy
+	certVrf       *CertVerifier
 	logger        kitlog.Logger
 	connHandlerFn func(conn net.Conn)
 
@@ -71,6 +74,8 @@ func NewListeners(
 	kv kv.KV,
 	shapers shaper.Shapers,
 	hk *hook.Hooks,
+	policy *federation.Policy,
+	certVrf *CertVerifier,
 	logger kitlog.Logger,
 ) []*SocketListener {
 	var listeners []*SocketListener
@@ -86,10 +91,16 @@ func NewListeners(
 			inHub,
 			shapers,
 			hk,
+			policy,
+			certVrf,
 			logger,
 		)
 		listeners = append(listeners, ln)
 	}
+	if len(listeners) > 0 {
+		// let outgoing streams process incoming traffic (XEP-0288)
+		outProvider.bidiInFn = listeners[0].newBidiInS2S
+	}
 	return listeners
 }
 
@@ -104,6 +115,8 @@ func newSocketListener(
 	hub *InHub,
 	shapers shaper.Shapers,
 	hk *hook.Hooks,
+	policy *federation.Policy,
+	certVrf *CertVerifier,
 	logger kitlog.Logger,
 ) *SocketListener {
 	ln := &SocketListener{
@@ -117,6 +130,8 @@ func newSocketListener(
 		inHUB:       hub,
 		shapers:     shapers,
 		hk:          hk,
+		policy:      policy,
+		certVrf:     certVrf,
 		logger:      logger,
 	}
 	ln.connHandlerFn = ln.handleConn
@@ -181,6 +196,8 @@ func (l *SocketListener) handleConn(conn net.Conn) {
 		l.kv,
 		l.shapers,
 		l.hk,
+		l.policy,
+		l.certVrf,
 		l.logger,
 		inConfig{
 			reqTimeout:    l.cfg.RequestTimeout,
@@ -201,9 +218,14 @@ func (l *SocketListener) handleConn(conn net.Conn) {
 }
 
 func (l *SocketListener) getTLSConfig() *tls.Config {
+	// peer certificate is validated once remote domain is known
+	clientAuth := tls.RequireAnyClientCert
+	if l.certVrf.DialbackFallback() {
+		clientAuth = tls.RequestClientCert
+	}
 	return &tls.Config{
 		Certificates: l.hosts.Certificates(),
-		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientAuth:   clientAuth,
 		MinVersion:   tls.VersionTLS12,
 	}
 }
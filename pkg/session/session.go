@@ -52,6 +52,7 @@ const (
 	jabberComponentNamespace = "jabber:component:accept"
 	streamNamespace          = "http://etherx.jabber.org/streams"
 	dialbackNamespace        = "jabber:server:dialback"
+	framingNamespace         = "urn:ietf:params:xml:ns:xmpp-framing"
 )
 
 var (
@@ -138,6 +139,7 @@ func (ss *Session) OpenStream(ctx context.Context) error {
 		return errAlreadyOpened
 	}
 	var b *stravaganza.Builder
+	var selfClosed bool
 
 	buf := &strings.Builder{}
 	switch ss.tr.Type() {
@@ -151,6 +153,23 @@ func (ss *Session) OpenStream(ctx context.Context) error {
 		}
 		buf.WriteString(`<?xml version='1.0'?>`)
 
+	case transport.WebSocket:
+		if ss.typ != C2SSession {
+			return errUnsupportedTransport
+		}
+		b = stravaganza.NewBuilder("open")
+		b.WithAttribute(stravaganza.Namespace, framingNamespace)
+		b.WithAttribute(stravaganza.Version, "1.0")
+		selfClosed = true
+
+	case transport.BOSH:
+		if ss.typ != C2SSession {
+			return errUnsupportedTransport
+		}
+		// stream header is conveyed by BOSH session creation response
+		ss.opened = true
+		return nil
+
 	default:
 		return errUnsupportedTransport
 	}
@@ -164,7 +183,7 @@ func (ss *Session) OpenStream(ctx context.Context) error {
 	}
 
 	elem := b.Build()
-	if err := elem.ToXML(buf, false); err != nil {
+	if err := elem.ToXML(buf, selfClosed); err != nil {
 		return err
 	}
 	if err := ss.sendString(ctx, buf.String()); err != nil {
@@ -214,9 +233,13 @@ func (ss *Session) Close(ctx context.Context) error {
 	switch ss.tr.Type() {
 	case transport.Socket:
 		outStr = "</stream:stream>"
+	case transport.WebSocket:
+		outStr = `<close xmlns="` + framingNamespace + `"/>`
 	}
-	if err := ss.sendString(ctx, outStr); err != nil {
-		return err
+	if len(outStr) > 0 {
+		if err := ss.sendString(ctx, outStr); err != nil {
+			return err
+		}
 	}
 	ss.opened = false
 	ss.started = false
@@ -229,6 +252,9 @@ func (ss *Session) Send(ctx context.Context, elem stravaganza.Element) error {
 		level.Debug(ss.logger).Log("msg", fmt.Sprintf("SND: %v", elem))
 	}
 	ss.setWriteDeadline(ctx)
+	if ss.tr.Type() == transport.WebSocket {
+		elem = framedElement(elem)
+	}
 	if err := elem.ToXML(ss.tr, true); err != nil {
 		return err
 	}
@@ -249,6 +275,9 @@ func (ss *Session) Receive() (stravaganza.Element, error) {
 		if elem.Name() == "stream:error" {
 			return nil, nil // ignore stream error incoming element
 		}
+		if ss.started && isFramingElement(elem, "close") && ss.tr.Type() != transport.Socket {
+			return nil, xmppparser.ErrStreamClosedByPeer
+		}
 	default:
 		return nil, nil
 	}
@@ -303,6 +332,14 @@ func (ss *Session) validateStreamElement(elem stravaganza.Element) error {
 		if ns != ss.namespace() || streamNs != streamNamespace {
 			return streamerror.E(streamerror.InvalidNamespace)
 		}
+
+	case transport.WebSocket, transport.BOSH:
+		if elem.Name() != "open" {
+			return streamerror.E(streamerror.UnsupportedStanzaType)
+		}
+		if elem.Attribute(stravaganza.Namespace) != framingNamespace {
+			return streamerror.E(streamerror.InvalidNamespace)
+		}
 	}
 	switch ss.typ {
 	case ComponentSession:
@@ -446,10 +483,27 @@ func getParser(tr transport.Transport, maxStanzaSize int) *xmppparser.Parser {
 	switch tr.Type() {
 	case transport.Socket:
 		pm = xmppparser.SocketStream
+	case transport.WebSocket, transport.BOSH:
+		pm = xmppparser.DefaultMode // every framed element is a complete XML document
 	}
 	return xmppparser.New(tr, pm, maxStanzaSize)
 }
 
+// framedElement returns a copy of elem declaring its stream namespace prefix,
+// as every WebSocket message must be a standalone XML document (RFC 7395, section 3.3.3).
+func framedElement(elem stravaganza.Element) stravaganza.Element {
+	if !strings.HasPrefix(elem.Name(), "stream:") || len(elem.Attribute(stravaganza.StreamNamespace)) > 0 {
+		return elem
+	}
+	return stravaganza.NewBuilderFromElement(elem).
+		WithAttribute(stravaganza.StreamNamespace, streamNamespace).
+		Build()
+}
+
+func isFramingElement(elem stravaganza.Element, name string) bool {
+	return elem.Name() == name && elem.Attribute(stravaganza.Namespace) == framingNamespace
+}
+
 func mapErrorToSessionError(err error) error {
 	switch err {
 	case ratelimiter.ErrReadLimitExcedeed:
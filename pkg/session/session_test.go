@@ -396,3 +396,184 @@ func TestSession_ReceiveUnsupportedStanza(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, stanzaerror.BadRequest, se.Reason)
 }
+
+func TestSession_OpenWebSocketStream(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.WebSocket }
+	trMock.FlushFunc = func() error { return nil }
+
+	buf := bytes.NewBuffer(nil)
+	trMock.WriteStringFunc = func(s string) (int, error) {
+		return buf.WriteString(s)
+	}
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:      C2SSession,
+		id:       "ss-1",
+		streamID: "stm-1",
+		cfg:      Config{MaxStanzaSize: 4096},
+		tr:       trMock,
+		hosts:    &hostsMock{},
+		pr:       &xmppParserMock{},
+		jd:       *ssJID,
+	}
+
+	// when
+	err := ss.OpenStream(context.Background())
+
+	// then
+	require.Nil(t, err)
+
+	expectedOutput := `<open xmlns='urn:ietf:params:xml:ns:xmpp-framing' version='1.0' from='jackal.im' id='stm-1'/>`
+	require.Equal(t, expectedOutput, buf.String())
+}
+
+func TestSession_CloseWebSocket(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.WebSocket }
+	trMock.FlushFunc = func() error { return nil }
+
+	buf := bytes.NewBuffer(nil)
+	trMock.WriteStringFunc = func(s string) (int, error) {
+		return buf.WriteString(s)
+	}
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:    C2SSession,
+		id:     "ss-1",
+		cfg:    Config{MaxStanzaSize: 4096},
+		tr:     trMock,
+		hosts:  &hostsMock{},
+		pr:     &xmppParserMock{},
+		jd:     *ssJID,
+		opened: true,
+	}
+
+	// when
+	err := ss.Close(context.Background())
+
+	// then
+	require.Nil(t, err)
+
+	expectedOutput := `<close xmlns="urn:ietf:params:xml:ns:xmpp-framing"/>`
+	require.Equal(t, expectedOutput, buf.String())
+}
+
+func TestSession_SendWebSocketStreamElement(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.WebSocket }
+	trMock.FlushFunc = func() error { return nil }
+
+	buf := bytes.NewBuffer(nil)
+	trMock.WriteFunc = func(p []byte) (int, error) {
+		return buf.Write(p)
+	}
+	trMock.WriteStringFunc = func(s string) (int, error) {
+		return buf.WriteString(s)
+	}
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:    C2SSession,
+		id:     "ss-1",
+		cfg:    Config{MaxStanzaSize: 4096},
+		tr:     trMock,
+		hosts:  &hostsMock{},
+		pr:     &xmppParserMock{},
+		jd:     *ssJID,
+		opened: true,
+	}
+
+	// when
+	err := ss.Send(context.Background(), streamerror.E(streamerror.SystemShutdown).Element())
+
+	// then
+	require.Nil(t, err)
+
+	expectedOutput := `<stream:error xmlns:stream='http://etherx.jabber.org/streams'><system-shutdown xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error>`
+	require.Equal(t, expectedOutput, buf.String())
+}
+
+func TestSession_ReceiveWebSocketStream(t *testing.T) {
+	// given
+	hMock := &hostsMock{}
+	trMock := &transportMock{}
+	prMock := &xmppParserMock{}
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:    C2SSession,
+		id:     "ss-1",
+		cfg:    Config{MaxStanzaSize: 4096},
+		tr:     trMock,
+		hosts:  hMock,
+		pr:     prMock,
+		jd:     *ssJID,
+		opened: true,
+	}
+	hMock.IsLocalHostFunc = func(domain string) bool { return domain == "jackal.im" }
+	trMock.TypeFunc = func() transport.Type { return transport.WebSocket }
+
+	prMock.ParseFunc = func() (stravaganza.Element, error) {
+		return stravaganza.NewBuilder("open").
+			WithAttribute(stravaganza.Namespace, framingNamespace).
+			WithAttribute(stravaganza.To, "jackal.im").
+			WithAttribute(stravaganza.Version, "1.0").
+			Build(), nil
+	}
+
+	// when
+	elem, err := ss.Receive()
+
+	// then
+	require.Nil(t, err)
+	require.NotNil(t, elem)
+	require.Equal(t, "open", elem.Name())
+
+	// when
+	prMock.ParseFunc = func() (stravaganza.Element, error) {
+		return stravaganza.NewBuilder("close").
+			WithAttribute(stravaganza.Namespace, framingNamespace).
+			Build(), nil
+	}
+	_, err = ss.Receive()
+
+	// then
+	require.Equal(t, xmppparser.ErrStreamClosedByPeer, err)
+}
+
+func TestSession_OpenAndCloseBOSHStream(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.BOSH }
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:   C2SSession,
+		id:    "ss-1",
+		cfg:   Config{MaxStanzaSize: 4096},
+		tr:    trMock,
+		hosts: &hostsMock{},
+		pr:    &xmppParserMock{},
+		jd:    *ssJID,
+	}
+
+	// when
+	openErr := ss.OpenStream(context.Background())
+	opened := ss.opened
+
+	closeErr := ss.Close(context.Background())
+
+	// then
+	require.Nil(t, openErr)
+	require.Nil(t, closeErr)
+
+	require.True(t, opened)
+	require.False(t, ss.opened)
+	require.Len(t, trMock.WriteStringCalls(), 0)
+}
@@ -15,8 +15,13 @@
 package boltdb
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/jackal-xmpp/stravaganza/jid"
@@ -24,7 +29,11 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
-const archiveStampFormat = "2006-01-02T15:04:05Z"
+const (
+	archiveStampFormat = "2006-01-02T15:04:05Z"
+
+	archivePrefsKey = "prefs"
+)
 
 type boltDBArchiveRep struct {
 	tx *bolt.Tx
@@ -40,7 +49,11 @@ func (r *boltDBArchiveRep) InsertArchiveMessage(_ context.Context, message *arch
 		bucket: archiveBucket(message.ArchiveId),
 		obj:    message,
 	}
-	return op.do()
+	k, err := op.doWithKey()
+	if err != nil {
+		return err
+	}
+	return r.indexMessage(message.ArchiveId, k, message)
 }
 
 func (r *boltDBArchiveRep) FetchArchiveMetadata(_ context.Context, archiveID string) (metadata *archivemodel.Metadata, err error) {
@@ -75,10 +88,22 @@ func (r *boltDBArchiveRep) FetchArchiveMetadata(_ context.Context, archiveID str
 func (r *boltDBArchiveRep) FetchArchiveMessages(_ context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error) {
 	var retVal []*archivemodel.Message
 
+	var matchedKeys map[string]struct{}
+	if len(f.Fulltext) > 0 {
+		matchedKeys = r.searchIndex(archiveID, f.Fulltext)
+		if len(matchedKeys) == 0 {
+			return nil, nil
+		}
+	}
 	op := iterKeysOp{
 		tx:     r.tx,
 		bucket: archiveBucket(archiveID),
 		iterFn: func(k, b []byte) error {
+			if matchedKeys != nil {
+				if _, ok := matchedKeys[string(k)]; !ok {
+					return nil
+				}
+			}
 			var msg archivemodel.Message
 			if err := proto.Unmarshal(b, &msg); err != nil {
 				return err
@@ -93,6 +118,35 @@ func (r *boltDBArchiveRep) FetchArchiveMessages(_ context.Context, f *archivemod
 	return applyFilters(retVal, f)
 }
 
+func (r *boltDBArchiveRep) UpdateArchiveMessage(_ context.Context, message *archivemodel.Message) error {
+	b := r.tx.Bucket([]byte(archiveBucket(message.ArchiveId)))
+	if b == nil {
+		return nil
+	}
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		var msg archivemodel.Message
+		if err := proto.Unmarshal(v, &msg); err != nil {
+			return err
+		}
+		if msg.Id != message.Id {
+			continue
+		}
+		if err := r.unindexMessage(message.ArchiveId, k, &msg); err != nil {
+			return err
+		}
+		p, err := message.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := b.Put(k, p); err != nil {
+			return err
+		}
+		return r.indexMessage(message.ArchiveId, k, message)
+	}
+	return nil
+}
+
 func (r *boltDBArchiveRep) DeleteArchiveOldestMessages(_ context.Context, archiveID string, maxElements int) error {
 	bucketID := archiveBucket(archiveID)
 
@@ -114,12 +168,20 @@ func (r *boltDBArchiveRep) DeleteArchiveOldestMessages(_ context.Context, archiv
 	var oldKeys [][]byte
 
 	c = b.Cursor()
-	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+	for k, v := c.First(); k != nil; k, v = c.Next() {
 		if count <= maxElements {
 			break
 		}
 		count--
 		oldKeys = append(oldKeys, k)
+
+		var msg archivemodel.Message
+		if err := proto.Unmarshal(v, &msg); err != nil {
+			return err
+		}
+		if err := r.unindexMessage(archiveID, k, &msg); err != nil {
+			return err
+		}
 	}
 	// delete old values
 	for _, k := range oldKeys {
@@ -131,6 +193,10 @@ func (r *boltDBArchiveRep) DeleteArchiveOldestMessages(_ context.Context, archiv
 }
 
 func (r *boltDBArchiveRep) DeleteArchive(_ context.Context, archiveID string) error {
+	err := r.tx.DeleteBucket([]byte(archiveIndexBucket(archiveID)))
+	if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return err
+	}
 	op := delBucketOp{
 		tx:     r.tx,
 		bucket: archiveBucket(archiveID),
@@ -138,10 +204,178 @@ func (r *boltDBArchiveRep) DeleteArchive(_ context.Context, archiveID string) er
 	return op.do()
 }
 
+func (r *boltDBArchiveRep) FetchArchiveIDs(_ context.Context) ([]string, error) {
+	var retVal []string
+
+	op := iterBucketsOp{
+		tx:     r.tx,
+		prefix: archiveBucket(""),
+		iterFn: func(archiveID string) error {
+			retVal = append(retVal, archiveID)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBArchiveRep) DeleteArchiveMessagesBefore(_ context.Context, archiveID string, before time.Time) (int, error) {
+	op := delKeysOp{
+		tx:     r.tx,
+		bucket: archiveBucket(archiveID),
+		filterFn: func(k, b []byte) (bool, error) {
+			var msg archivemodel.Message
+			if err := proto.Unmarshal(b, &msg); err != nil {
+				return false, err
+			}
+			if !msg.Stamp.AsTime().Before(before) {
+				return false, nil
+			}
+			return true, r.unindexMessage(archiveID, k, &msg)
+		},
+	}
+	return op.do()
+}
+
+func (r *boltDBArchiveRep) UpsertArchivePreferences(_ context.Context, prefs *archivemodel.Preferences) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: archivePrefsBucket(prefs.Username),
+		key:    archivePrefsKey,
+		obj:    prefs,
+	}
+	return op.do()
+}
+
+func (r *boltDBArchiveRep) FetchArchivePreferences(_ context.Context, username string) (*archivemodel.Preferences, error) {
+	op := fetchKeyOp{
+		tx:     r.tx,
+		bucket: archivePrefsBucket(username),
+		key:    archivePrefsKey,
+		obj:    &archivemodel.Preferences{},
+	}
+	obj, err := op.do()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case obj != nil:
+		return obj.(*archivemodel.Preferences), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (r *boltDBArchiveRep) DeleteArchivePreferences(_ context.Context, username string) error {
+	op := delBucketOp{
+		tx:     r.tx,
+		bucket: archivePrefsBucket(username),
+	}
+	return op.do()
+}
+
+// indexMessage adds message body terms to the archive inverted index.
+// Index entries are keyed by term and archive message key, so that all messages containing
+// a given term can be retrieved by a single prefix scan.
+func (r *boltDBArchiveRep) indexMessage(archiveID string, k []byte, message *archivemodel.Message) error {
+	terms := tokenize(message.Body())
+	if len(terms) == 0 {
+		return nil
+	}
+	b, err := r.tx.CreateBucketIfNotExists([]byte(archiveIndexBucket(archiveID)))
+	if err != nil {
+		return err
+	}
+	for _, term := range terms {
+		if err := b.Put(archiveIndexKey(term, k), []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *boltDBArchiveRep) unindexMessage(archiveID string, k []byte, message *archivemodel.Message) error {
+	b := r.tx.Bucket([]byte(archiveIndexBucket(archiveID)))
+	if b == nil {
+		return nil
+	}
+	for _, term := range tokenize(message.Body()) {
+		if err := b.Delete(archiveIndexKey(term, k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchIndex returns the keys of all archive messages whose body contains every term in text.
+func (r *boltDBArchiveRep) searchIndex(archiveID, text string) map[string]struct{} {
+	b := r.tx.Bucket([]byte(archiveIndexBucket(archiveID)))
+	if b == nil {
+		return nil
+	}
+	var retVal map[string]struct{}
+	for _, term := range tokenize(text) {
+		prefix := archiveIndexKey(term, nil)
+
+		termKeys := make(map[string]struct{})
+
+		c := b.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			msgKey := string(k[len(prefix):])
+			if retVal != nil {
+				if _, ok := retVal[msgKey]; !ok {
+					continue
+				}
+			}
+			termKeys[msgKey] = struct{}{}
+		}
+		if len(termKeys) == 0 {
+			return nil
+		}
+		retVal = termKeys
+	}
+	return retVal
+}
+
+func archiveIndexKey(term string, k []byte) []byte {
+	retVal := make([]byte, 0, len(term)+1+len(k))
+	retVal = append(retVal, term...)
+	retVal = append(retVal, 0)
+	return append(retVal, k...)
+}
+
+// tokenize splits text into its unique lowercased words.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	var retVal []string
+
+	seen := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		if _, ok := seen[w]; ok {
+			continue
+		}
+		seen[w] = struct{}{}
+		retVal = append(retVal, w)
+	}
+	return retVal
+}
+
+func archiveIndexBucket(archiveID string) string {
+	return fmt.Sprintf("archive_idx:%s", archiveID)
+}
+
 func archiveBucket(archiveID string) string {
 	return fmt.Sprintf("archive:%s", archiveID)
 }
 
+func archivePrefsBucket(username string) string {
+	return fmt.Sprintf("archive_prefs:%s", username)
+}
+
 // InsertArchiveMessage inserts a new message element into an archive queue.
 func (r *Repository) InsertArchiveMessage(ctx context.Context, message *archivemodel.Message) error {
 	return r.db.Update(func(tx *bolt.Tx) error {
@@ -167,6 +401,13 @@ func (r *Repository) FetchArchiveMessages(ctx context.Context, f *archivemodel.F
 	return
 }
 
+// UpdateArchiveMessage replaces the content of an already archived message.
+func (r *Repository) UpdateArchiveMessage(ctx context.Context, message *archivemodel.Message) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newArchiveRep(tx).UpdateArchiveMessage(ctx, message)
+	})
+}
+
 // DeleteArchiveOldestMessages trims archive oldest messages up to a maxElements total count.
 func (r *Repository) DeleteArchiveOldestMessages(ctx context.Context, archiveID string, maxElements int) error {
 	return r.db.Update(func(tx *bolt.Tx) error {
@@ -181,6 +422,47 @@ func (r *Repository) DeleteArchive(ctx context.Context, archiveID string) error
 	})
 }
 
+// FetchArchiveIDs returns the identifiers of all non-empty archives.
+func (r *Repository) FetchArchiveIDs(ctx context.Context) (archiveIDs []string, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		archiveIDs, err = newArchiveRep(tx).FetchArchiveIDs(ctx)
+		return err
+	})
+	return
+}
+
+// DeleteArchiveMessagesBefore deletes archive messages stored before a given time.
+func (r *Repository) DeleteArchiveMessagesBefore(ctx context.Context, archiveID string, before time.Time) (count int, err error) {
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		count, err = newArchiveRep(tx).DeleteArchiveMessagesBefore(ctx, archiveID, before)
+		return err
+	})
+	return
+}
+
+// UpsertArchivePreferences inserts or updates user archiving preferences.
+func (r *Repository) UpsertArchivePreferences(ctx context.Context, prefs *archivemodel.Preferences) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newArchiveRep(tx).UpsertArchivePreferences(ctx, prefs)
+	})
+}
+
+// FetchArchivePreferences retrieves user archiving preferences.
+func (r *Repository) FetchArchivePreferences(ctx context.Context, username string) (prefs *archivemodel.Preferences, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		prefs, err = newArchiveRep(tx).FetchArchivePreferences(ctx, username)
+		return err
+	})
+	return
+}
+
+// DeleteArchivePreferences deletes user archiving preferences.
+func (r *Repository) DeleteArchivePreferences(ctx context.Context, username string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newArchiveRep(tx).DeleteArchivePreferences(ctx, username)
+	})
+}
+
 func applyFilters(messages []*archivemodel.Message, f *archivemodel.Filters) ([]*archivemodel.Message, error) {
 	retVal := messages
 
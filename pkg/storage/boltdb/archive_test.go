@@ -16,6 +16,7 @@ package boltdb
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -132,6 +133,45 @@ func TestBoltDB_DeleteArchive(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestBoltDB_ArchivePreferences(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBArchiveRep{tx: tx}
+
+		prefs, err := rep.FetchArchivePreferences(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Nil(t, prefs)
+
+		err = rep.UpsertArchivePreferences(context.Background(), &archivemodel.Preferences{
+			Username:        "ortuman",
+			DefaultBehavior: "roster",
+			Always:          []string{"noelia@jackal.im"},
+			Never:           []string{"romeo@jackal.im"},
+		})
+		require.NoError(t, err)
+
+		prefs, err = rep.FetchArchivePreferences(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.NotNil(t, prefs)
+		require.Equal(t, "roster", prefs.DefaultBehavior)
+		require.Equal(t, []string{"noelia@jackal.im"}, prefs.Always)
+		require.Equal(t, []string{"romeo@jackal.im"}, prefs.Never)
+
+		require.NoError(t, rep.DeleteArchivePreferences(context.Background(), "ortuman"))
+
+		prefs, err = rep.FetchArchivePreferences(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Nil(t, prefs)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 func TestBoltDB_DeleteArchiveOldestMessages(t *testing.T) {
 	t.Parallel()
 
@@ -222,6 +262,19 @@ func TestBoltDB_FetchArchiveMessages(t *testing.T) {
 			},
 			expectedResultIDs: []string{"m0"},
 		},
+		"filtering by fulltext": {
+			filters: &archivemodel.Filters{
+				Fulltext: "B2",
+			},
+			expectedResultIDs: []string{"m2"},
+		},
+		"filtering by fulltext and jid": {
+			filters: &archivemodel.Filters{
+				With:     "noelia@jackal.im",
+				Fulltext: "b2",
+			},
+			expectedResultIDs: nil,
+		},
 	}
 	for tn, tc := range tcs {
 		t.Run(tn, func(t *testing.T) {
@@ -290,3 +343,146 @@ func TestBoltDB_FetchArchiveMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestBoltDB_DeleteArchiveMessagesBefore(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	now := time.Now()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBArchiveRep{tx: tx}
+
+		for _, stamp := range []time.Time{now.Add(-time.Hour * 48), now.Add(-time.Hour * 24), now} {
+			err := rep.InsertArchiveMessage(context.Background(), &archivemodel.Message{
+				ArchiveId: "ortuman@jackal.im",
+				Message:   testMessageStanza().Proto(),
+				Stamp:     timestamppb.New(stamp),
+			})
+			require.NoError(t, err)
+		}
+		err := rep.InsertArchiveMessage(context.Background(), &archivemodel.Message{
+			ArchiveId: "noelia@jackal.im",
+			Message:   testMessageStanza().Proto(),
+			Stamp:     timestamppb.New(now.Add(-time.Hour * 48)),
+		})
+		require.NoError(t, err)
+
+		archiveIDs, err := rep.FetchArchiveIDs(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"noelia@jackal.im", "ortuman@jackal.im"}, archiveIDs)
+
+		count, err := rep.DeleteArchiveMessagesBefore(context.Background(), "ortuman@jackal.im", now.Add(-time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+		require.Equal(t, 1, countBucketElements(t, tx, archiveBucket("ortuman@jackal.im")))
+
+		count, err = rep.DeleteArchiveMessagesBefore(context.Background(), "noelia@jackal.im", now.Add(-time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		archiveIDs, err = rep.FetchArchiveIDs(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"ortuman@jackal.im"}, archiveIDs)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_FulltextIndex(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBArchiveRep{tx: tx}
+
+		bodies := []string{
+			"But, soft! what light through yonder window breaks?",
+			"It is the east, and Juliet is the sun.",
+			"Arise, fair sun, and kill the envious moon.",
+		}
+		for i, body := range bodies {
+			err := rep.InsertArchiveMessage(context.Background(), &archivemodel.Message{
+				ArchiveId: "ortuman@jackal.im",
+				Id:        fmt.Sprintf("m%d", i),
+				Stamp:     timestamppb.New(time.Date(2022, 01, i+1, 00, 00, 00, 00, time.UTC)),
+				Message:   testMessageStanzaWithParameters(body, "noelia@jackal.im/yard", "ortuman@jackal.im/balcony").Proto(),
+			})
+			require.NoError(t, err)
+		}
+		messages, err := rep.FetchArchiveMessages(context.Background(), &archivemodel.Filters{Fulltext: "sun"}, "ortuman@jackal.im")
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+
+		messages, err = rep.FetchArchiveMessages(context.Background(), &archivemodel.Filters{Fulltext: "Juliet, sun"}, "ortuman@jackal.im")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, "m1", messages[0].Id)
+
+		// deleted messages are removed from the index
+		err = rep.DeleteArchiveOldestMessages(context.Background(), "ortuman@jackal.im", 1)
+		require.NoError(t, err)
+
+		require.Equal(t, 8, countBucketElements(t, tx, archiveIndexBucket("ortuman@jackal.im")))
+
+		messages, err = rep.FetchArchiveMessages(context.Background(), &archivemodel.Filters{Fulltext: "sun"}, "ortuman@jackal.im")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, "m2", messages[0].Id)
+
+		err = rep.DeleteArchive(context.Background(), "ortuman@jackal.im")
+		require.NoError(t, err)
+		require.Nil(t, tx.Bucket([]byte(archiveIndexBucket("ortuman@jackal.im"))))
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_UpdateArchiveMessage(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBArchiveRep{tx: tx}
+
+		for i, body := range []string{"first witch", "second witch"} {
+			err := rep.InsertArchiveMessage(context.Background(), &archivemodel.Message{
+				ArchiveId: "ortuman@jackal.im",
+				Id:        fmt.Sprintf("m%d", i),
+				Message:   testMessageStanzaWithParameters(body, "noelia@jackal.im/yard", "ortuman@jackal.im/balcony").Proto(),
+			})
+			require.NoError(t, err)
+		}
+		err := rep.UpdateArchiveMessage(context.Background(), &archivemodel.Message{
+			ArchiveId: "ortuman@jackal.im",
+			Id:        "m0",
+			Message:   testMessageStanzaWithParameters("third witch", "noelia@jackal.im/yard", "ortuman@jackal.im/balcony").Proto(),
+		})
+		require.NoError(t, err)
+
+		messages, err := rep.FetchArchiveMessages(context.Background(), &archivemodel.Filters{}, "ortuman@jackal.im")
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		require.Equal(t, "m0", messages[0].Id)
+		require.Equal(t, "third witch", messages[0].Body())
+
+		// index reflects updated content
+		messages, err = rep.FetchArchiveMessages(context.Background(), &archivemodel.Filters{Fulltext: "first"}, "ortuman@jackal.im")
+		require.NoError(t, err)
+		require.Len(t, messages, 0)
+
+		messages, err = rep.FetchArchiveMessages(context.Background(), &archivemodel.Filters{Fulltext: "third"}, "ortuman@jackal.im")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"fmt"
+
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	bolt "go.etcd.io/bbolt"
+)
+
+type boltDBFastTokenRep struct {
+	tx *bolt.Tx
+}
+
+func newFastTokenRep(tx *bolt.Tx) *boltDBFastTokenRep {
+	return &boltDBFastTokenRep{tx: tx}
+}
+
+func (r *boltDBFastTokenRep) UpsertFastToken(_ context.Context, token *fastmodel.Token) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: fastTokenBucket(token.Username),
+		key:    token.ClientId,
+		obj:    token,
+	}
+	return op.do()
+}
+
+func (r *boltDBFastTokenRep) FetchFastTokens(_ context.Context, username string) ([]*fastmodel.Token, error) {
+	var retVal []*fastmodel.Token
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: fastTokenBucket(username),
+		iterFn: func(_, b []byte) error {
+			var token fastmodel.Token
+			if err := token.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			retVal = append(retVal, &token)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBFastTokenRep) DeleteFastToken(_ context.Context, username, clientID string) error {
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: fastTokenBucket(username),
+		key:    clientID,
+	}
+	return op.do()
+}
+
+func (r *boltDBFastTokenRep) DeleteFastTokens(_ context.Context, username string) error {
+	op := delBucketOp{
+		tx:     r.tx,
+		bucket: fastTokenBucket(username),
+	}
+	return op.do()
+}
+
+func fastTokenBucket(username string) string {
+	return fmt.Sprintf("fast:%s", username)
+}
+
+// UpsertFastToken satisfies repository.FastToken interface.
+func (r *Repository) UpsertFastToken(ctx context.Context, token *fastmodel.Token) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newFastTokenRep(tx).UpsertFastToken(ctx, token)
+	})
+}
+
+// FetchFastTokens satisfies repository.FastToken interface.
+func (r *Repository) FetchFastTokens(ctx context.Context, username string) (tokens []*fastmodel.Token, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		tokens, err = newFastTokenRep(tx).FetchFastTokens(ctx, username)
+		return err
+	})
+	return
+}
+
+// DeleteFastToken satisfies repository.FastToken interface.
+func (r *Repository) DeleteFastToken(ctx context.Context, username, clientID string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newFastTokenRep(tx).DeleteFastToken(ctx, username, clientID)
+	})
+}
+
+// DeleteFastTokens satisfies repository.FastToken interface.
+func (r *Repository) DeleteFastTokens(ctx context.Context, username string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newFastTokenRep(tx).DeleteFastTokens(ctx, username)
+	})
+}
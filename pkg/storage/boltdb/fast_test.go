@@ -0,0 +1,102 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"testing"
+
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltDB_UpsertAndFetchFastTokens(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBFastTokenRep{tx: tx}
+
+		err := rep.UpsertFastToken(context.Background(), &fastmodel.Token{
+			Username: "ortuman",
+			ClientId: "client-1",
+			Token:    "t0k3n-1",
+		})
+		require.NoError(t, err)
+
+		err = rep.UpsertFastToken(context.Background(), &fastmodel.Token{
+			Username: "ortuman",
+			ClientId: "client-2",
+			Token:    "t0k3n-2",
+		})
+		require.NoError(t, err)
+
+		err = rep.UpsertFastToken(context.Background(), &fastmodel.Token{
+			Username:      "ortuman",
+			ClientId:      "client-1",
+			Token:         "t0k3n-3",
+			PreviousToken: "t0k3n-1",
+		})
+		require.NoError(t, err)
+
+		tokens, err := rep.FetchFastTokens(context.Background(), "ortuman")
+		require.NoError(t, err)
+
+		require.Len(t, tokens, 2)
+
+		require.Equal(t, "t0k3n-3", tokens[0].Token)
+		require.Equal(t, "t0k3n-1", tokens[0].PreviousToken)
+		require.Equal(t, "t0k3n-2", tokens[1].Token)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_DeleteFastTokens(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBFastTokenRep{tx: tx}
+
+		for _, clientID := range []string{"client-1", "client-2", "client-3"} {
+			err := rep.UpsertFastToken(context.Background(), &fastmodel.Token{
+				Username: "ortuman",
+				ClientId: clientID,
+			})
+			require.NoError(t, err)
+		}
+		err := rep.DeleteFastToken(context.Background(), "ortuman", "client-2")
+		require.NoError(t, err)
+
+		tokens, err := rep.FetchFastTokens(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Len(t, tokens, 2)
+
+		err = rep.DeleteFastTokens(context.Background(), "ortuman")
+		require.NoError(t, err)
+
+		tokens, err = rep.FetchFastTokens(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Len(t, tokens, 0)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	bolt "go.etcd.io/bbolt"
+)
+
+const invitesBucket = "invites"
+
+type boltDBInviteRep struct {
+	tx *bolt.Tx
+}
+
+func newInviteRep(tx *bolt.Tx) *boltDBInviteRep {
+	return &boltDBInviteRep{tx: tx}
+}
+
+func (r *boltDBInviteRep) UpsertInvite(_ context.Context, inv *invitemodel.Invite) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: invitesBucket,
+		key:    inv.Token,
+		obj:    inv,
+	}
+	return op.do()
+}
+
+func (r *boltDBInviteRep) FetchInvite(_ context.Context, token string) (*invitemodel.Invite, error) {
+	op := fetchKeyOp{
+		tx:     r.tx,
+		bucket: invitesBucket,
+		key:    token,
+		obj:    &invitemodel.Invite{},
+	}
+	obj, err := op.do()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case obj != nil:
+		return obj.(*invitemodel.Invite), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (r *boltDBInviteRep) DeleteInvite(_ context.Context, token string) error {
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: invitesBucket,
+		key:    token,
+	}
+	return op.do()
+}
+
+func (r *boltDBInviteRep) DeleteInvites(_ context.Context, inviter string) error {
+	var tokens []string
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: invitesBucket,
+		iterFn: func(k, b []byte) error {
+			var inv invitemodel.Invite
+			if err := inv.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			if inv.Inviter == inviter {
+				tokens = append(tokens, string(k))
+			}
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		delOp := delKeyOp{
+			tx:     r.tx,
+			bucket: invitesBucket,
+			key:    token,
+		}
+		if err := delOp.do(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpsertInvite satisfies repository.Invite interface.
+func (r *Repository) UpsertInvite(ctx context.Context, inv *invitemodel.Invite) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newInviteRep(tx).UpsertInvite(ctx, inv)
+	})
+}
+
+// FetchInvite satisfies repository.Invite interface.
+func (r *Repository) FetchInvite(ctx context.Context, token string) (inv *invitemodel.Invite, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		inv, err = newInviteRep(tx).FetchInvite(ctx, token)
+		return err
+	})
+	return
+}
+
+// DeleteInvite satisfies repository.Invite interface.
+func (r *Repository) DeleteInvite(ctx context.Context, token string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newInviteRep(tx).DeleteInvite(ctx, token)
+	})
+}
+
+// DeleteInvites satisfies repository.Invite interface.
+func (r *Repository) DeleteInvites(ctx context.Context, inviter string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newInviteRep(tx).DeleteInvites(ctx, inviter)
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"testing"
+
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltDB_UpsertAndFetchInvite(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBInviteRep{tx: tx}
+
+		err := rep.UpsertInvite(context.Background(), &invitemodel.Invite{
+			Token:   "t0k3n",
+			Domain:  "jackal.im",
+			Inviter: "ortuman",
+			MaxUses: 2,
+		})
+		require.NoError(t, err)
+
+		err = rep.UpsertInvite(context.Background(), &invitemodel.Invite{
+			Token:   "t0k3n",
+			Domain:  "jackal.im",
+			Inviter: "ortuman",
+			MaxUses: 2,
+			Uses:    1,
+		})
+		require.NoError(t, err)
+
+		inv, err := rep.FetchInvite(context.Background(), "t0k3n")
+		require.NoError(t, err)
+		require.NotNil(t, inv)
+		require.Equal(t, "ortuman", inv.Inviter)
+		require.Equal(t, int32(1), inv.Uses)
+
+		inv, err = rep.FetchInvite(context.Background(), "n0t-f0und")
+		require.NoError(t, err)
+		require.Nil(t, inv)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_DeleteInvites(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBInviteRep{tx: tx}
+
+		for _, inv := range []*invitemodel.Invite{
+			{Token: "t0k3n-1", Inviter: "ortuman"},
+			{Token: "t0k3n-2", Inviter: "ortuman"},
+			{Token: "t0k3n-3", Inviter: "noelia"},
+			{Token: "t0k3n-4", Inviter: "ortuman"},
+		} {
+			require.NoError(t, rep.UpsertInvite(context.Background(), inv))
+		}
+		err := rep.DeleteInvite(context.Background(), "t0k3n-1")
+		require.NoError(t, err)
+
+		inv, err := rep.FetchInvite(context.Background(), "t0k3n-1")
+		require.NoError(t, err)
+		require.Nil(t, inv)
+
+		err = rep.DeleteInvites(context.Background(), "ortuman")
+		require.NoError(t, err)
+
+		for _, token := range []string{"t0k3n-2", "t0k3n-4"} {
+			inv, err := rep.FetchInvite(context.Background(), token)
+			require.NoError(t, err)
+			require.Nil(t, inv)
+		}
+		inv, err = rep.FetchInvite(context.Background(), "t0k3n-3")
+		require.NoError(t, err)
+		require.NotNil(t, inv)
+		return nil
+	})
+	require.NoError(t, err)
+}
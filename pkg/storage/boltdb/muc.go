@@ -0,0 +1,218 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+	bolt "go.etcd.io/bbolt"
+)
+
+const mucRoomsBucket = "muc_rooms"
+
+type boltDBMucRep struct {
+	tx *bolt.Tx
+}
+
+func newMucRep(tx *bolt.Tx) *boltDBMucRep {
+	return &boltDBMucRep{tx: tx}
+}
+
+func (r *boltDBMucRep) UpsertRoom(_ context.Context, room *mucmodel.Room) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: mucRoomsBucket,
+		key:    room.Jid,
+		obj:    room,
+	}
+	return op.do()
+}
+
+func (r *boltDBMucRep) FetchRoom(_ context.Context, roomJID string) (*mucmodel.Room, error) {
+	op := fetchKeyOp{
+		tx:     r.tx,
+		bucket: mucRoomsBucket,
+		key:    roomJID,
+		obj:    &mucmodel.Room{},
+	}
+	obj, err := op.do()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case obj != nil:
+		return obj.(*mucmodel.Room), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (r *boltDBMucRep) FetchRooms(_ context.Context, host string) ([]*mucmodel.Room, error) {
+	var retVal []*mucmodel.Room
+
+	suffix := "@" + host
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: mucRoomsBucket,
+		iterFn: func(k, b []byte) error {
+			if !strings.HasSuffix(string(k), suffix) {
+				return nil
+			}
+			var room mucmodel.Room
+			if err := room.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			retVal = append(retVal, &room)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBMucRep) DeleteRoom(_ context.Context, roomJID string) error {
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: mucRoomsBucket,
+		key:    roomJID,
+	}
+	return op.do()
+}
+
+func (r *boltDBMucRep) UpsertRoomAffiliation(_ context.Context, aff *mucmodel.Affiliation) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: mucAffiliationsBucket(aff.RoomJid),
+		key:    aff.Jid,
+		obj:    aff,
+	}
+	return op.do()
+}
+
+func (r *boltDBMucRep) FetchRoomAffiliations(_ context.Context, roomJID string) ([]*mucmodel.Affiliation, error) {
+	var retVal []*mucmodel.Affiliation
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: mucAffiliationsBucket(roomJID),
+		iterFn: func(_, b []byte) error {
+			var aff mucmodel.Affiliation
+			if err := aff.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			retVal = append(retVal, &aff)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBMucRep) DeleteRoomAffiliation(_ context.Context, roomJID, jid string) error {
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: mucAffiliationsBucket(roomJID),
+		key:    jid,
+	}
+	return op.do()
+}
+
+func (r *boltDBMucRep) DeleteRoomAffiliations(_ context.Context, roomJID string) error {
+	existsOp := bucketExistsOp{
+		tx:     r.tx,
+		bucket: mucAffiliationsBucket(roomJID),
+	}
+	if !existsOp.do() {
+		return nil
+	}
+	op := delBucketOp{
+		tx:     r.tx,
+		bucket: mucAffiliationsBucket(roomJID),
+	}
+	return op.do()
+}
+
+func mucAffiliationsBucket(roomJID string) string {
+	return fmt.Sprintf("muc_affiliations:%s", roomJID)
+}
+
+// UpsertRoom satisfies repository.Muc interface.
+func (r *Repository) UpsertRoom(ctx context.Context, room *mucmodel.Room) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newMucRep(tx).UpsertRoom(ctx, room)
+	})
+}
+
+// FetchRoom satisfies repository.Muc interface.
+func (r *Repository) FetchRoom(ctx context.Context, roomJID string) (room *mucmodel.Room, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		room, err = newMucRep(tx).FetchRoom(ctx, roomJID)
+		return err
+	})
+	return
+}
+
+// FetchRooms satisfies repository.Muc interface.
+func (r *Repository) FetchRooms(ctx context.Context, host string) (rooms []*mucmodel.Room, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		rooms, err = newMucRep(tx).FetchRooms(ctx, host)
+		return err
+	})
+	return
+}
+
+// DeleteRoom satisfies repository.Muc interface.
+func (r *Repository) DeleteRoom(ctx context.Context, roomJID string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newMucRep(tx).DeleteRoom(ctx, roomJID)
+	})
+}
+
+// UpsertRoomAffiliation satisfies repository.Muc interface.
+func (r *Repository) UpsertRoomAffiliation(ctx context.Context, aff *mucmodel.Affiliation) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newMucRep(tx).UpsertRoomAffiliation(ctx, aff)
+	})
+}
+
+// FetchRoomAffiliations satisfies repository.Muc interface.
+func (r *Repository) FetchRoomAffiliations(ctx context.Context, roomJID string) (affs []*mucmodel.Affiliation, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		affs, err = newMucRep(tx).FetchRoomAffiliations(ctx, roomJID)
+		return err
+	})
+	return
+}
+
+// DeleteRoomAffiliation satisfies repository.Muc interface.
+func (r *Repository) DeleteRoomAffiliation(ctx context.Context, roomJID, jid string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newMucRep(tx).DeleteRoomAffiliation(ctx, roomJID, jid)
+	})
+}
+
+// DeleteRoomAffiliations satisfies repository.Muc interface.
+func (r *Repository) DeleteRoomAffiliations(ctx context.Context, roomJID string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newMucRep(tx).DeleteRoomAffiliations(ctx, roomJID)
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"testing"
+
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltDB_UpsertAndFetchRoom(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBMucRep{tx: tx}
+
+		for _, room := range []*mucmodel.Room{
+			{Jid: "lobby@conference.jackal.im", Config: &mucmodel.RoomConfig{Name: "Lobby"}},
+			{Jid: "dev@conference.jackal.im", Config: &mucmodel.RoomConfig{Name: "Dev"}, Subject: "Go"},
+			{Jid: "lobby@muc.jabber.org", Config: &mucmodel.RoomConfig{Name: "Lobby"}},
+		} {
+			require.NoError(t, rep.UpsertRoom(context.Background(), room))
+		}
+
+		room, err := rep.FetchRoom(context.Background(), "dev@conference.jackal.im")
+		require.NoError(t, err)
+		require.NotNil(t, room)
+		require.Equal(t, "Dev", room.Config.Name)
+		require.Equal(t, "Go", room.Subject)
+
+		rooms, err := rep.FetchRooms(context.Background(), "conference.jackal.im")
+		require.NoError(t, err)
+		require.Len(t, rooms, 2)
+
+		err = rep.DeleteRoom(context.Background(), "dev@conference.jackal.im")
+		require.NoError(t, err)
+
+		room, err = rep.FetchRoom(context.Background(), "dev@conference.jackal.im")
+		require.NoError(t, err)
+		require.Nil(t, room)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_RoomAffiliations(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBMucRep{tx: tx}
+
+		for _, aff := range []*mucmodel.Affiliation{
+			{RoomJid: "lobby@conference.jackal.im", Jid: "ortuman@jackal.im", Affiliation: "owner"},
+			{RoomJid: "lobby@conference.jackal.im", Jid: "noelia@jackal.im", Affiliation: "member"},
+			{RoomJid: "dev@conference.jackal.im", Jid: "noelia@jackal.im", Affiliation: "admin"},
+		} {
+			require.NoError(t, rep.UpsertRoomAffiliation(context.Background(), aff))
+		}
+
+		affs, err := rep.FetchRoomAffiliations(context.Background(), "lobby@conference.jackal.im")
+		require.NoError(t, err)
+		require.Len(t, affs, 2)
+
+		err = rep.DeleteRoomAffiliation(context.Background(), "lobby@conference.jackal.im", "noelia@jackal.im")
+		require.NoError(t, err)
+
+		affs, err = rep.FetchRoomAffiliations(context.Background(), "lobby@conference.jackal.im")
+		require.NoError(t, err)
+		require.Len(t, affs, 1)
+		require.Equal(t, "owner", affs[0].Affiliation)
+
+		require.NoError(t, rep.DeleteRoomAffiliations(context.Background(), "lobby@conference.jackal.im"))
+		require.NoError(t, rep.DeleteRoomAffiliations(context.Background(), "lobby@conference.jackal.im"))
+
+		affs, err = rep.FetchRoomAffiliations(context.Background(), "lobby@conference.jackal.im")
+		require.NoError(t, err)
+		require.Len(t, affs, 0)
+
+		affs, err = rep.FetchRoomAffiliations(context.Background(), "dev@conference.jackal.im")
+		require.NoError(t, err)
+		require.Len(t, affs, 1)
+		return nil
+	})
+	require.NoError(t, err)
+}
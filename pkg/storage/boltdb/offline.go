@@ -17,12 +17,16 @@ package boltdb
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/jackal-xmpp/stravaganza"
+	offlinemodel "github.com/ortuman/jackal/pkg/model/offline"
 	bolt "go.etcd.io/bbolt"
 )
 
+const delayNamespace = "urn:xmpp:delay"
+
 type boltDBOfflineRep struct {
 	tx *bolt.Tx
 }
@@ -48,6 +52,23 @@ func (r *boltDBOfflineRep) CountOfflineMessages(_ context.Context, username stri
 	return op.do()
 }
 
+func (r *boltDBOfflineRep) CountOfflineBytes(_ context.Context, username string) (int, error) {
+	var size int
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: offlineBucket(username),
+		iterFn: func(_, b []byte) error {
+			size += len(b)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
 func (r *boltDBOfflineRep) FetchOfflineMessages(_ context.Context, username string) ([]*stravaganza.Message, error) {
 	var retVal []*stravaganza.Message
 
@@ -55,11 +76,7 @@ func (r *boltDBOfflineRep) FetchOfflineMessages(_ context.Context, username stri
 		tx:     r.tx,
 		bucket: offlineBucket(username),
 		iterFn: func(_, b []byte) error {
-			var elem stravaganza.PBElement
-			if err := proto.Unmarshal(b, &elem); err != nil {
-				return err
-			}
-			msg, err := stravaganza.NewBuilderFromProto(&elem).BuildMessage()
+			msg, err := unmarshalOfflineMessage(b)
 			if err != nil {
 				return err
 			}
@@ -81,6 +98,101 @@ func (r *boltDBOfflineRep) DeleteOfflineMessages(_ context.Context, username str
 	return op.do()
 }
 
+func (r *boltDBOfflineRep) FetchOfflineItems(_ context.Context, username string) ([]*offlinemodel.Item, error) {
+	var retVal []*offlinemodel.Item
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: offlineBucket(username),
+		iterFn: func(k, b []byte) error {
+			msg, err := unmarshalOfflineMessage(b)
+			if err != nil {
+				return err
+			}
+			retVal = append(retVal, &offlinemodel.Item{Node: string(k), Message: msg})
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBOfflineRep) FetchOfflineItem(_ context.Context, username, node string) (*offlinemodel.Item, error) {
+	b := r.tx.Bucket([]byte(offlineBucket(username)))
+	if b == nil {
+		return nil, nil
+	}
+	data := b.Get([]byte(node))
+	if data == nil {
+		return nil, nil
+	}
+	msg, err := unmarshalOfflineMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	return &offlinemodel.Item{Node: node, Message: msg}, nil
+}
+
+func (r *boltDBOfflineRep) DeleteOfflineItem(_ context.Context, username, node string) error {
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: offlineBucket(username),
+		key:    node,
+	}
+	return op.do()
+}
+
+func (r *boltDBOfflineRep) FetchOfflineUsernames(_ context.Context) ([]string, error) {
+	var retVal []string
+
+	op := iterBucketsOp{
+		tx:     r.tx,
+		prefix: offlineBucket(""),
+		iterFn: func(username string) error {
+			retVal = append(retVal, username)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBOfflineRep) DeleteOfflineMessagesBefore(_ context.Context, username string, before time.Time) (int, error) {
+	op := delKeysOp{
+		tx:     r.tx,
+		bucket: offlineBucket(username),
+		filterFn: func(_, b []byte) (bool, error) {
+			var elem stravaganza.PBElement
+			if err := proto.Unmarshal(b, &elem); err != nil {
+				return false, err
+			}
+			// offline messages are stamped with a delay element (XEP-0203) when stored
+			delay := stravaganza.NewBuilderFromProto(&elem).Build().ChildNamespace("delay", delayNamespace)
+			if delay == nil {
+				return false, nil
+			}
+			stamp, err := time.Parse(time.RFC3339, delay.Attribute("stamp"))
+			if err != nil {
+				return false, nil
+			}
+			return stamp.Before(before), nil
+		},
+	}
+	return op.do()
+}
+
+func unmarshalOfflineMessage(b []byte) (*stravaganza.Message, error) {
+	var elem stravaganza.PBElement
+	if err := proto.Unmarshal(b, &elem); err != nil {
+		return nil, err
+	}
+	return stravaganza.NewBuilderFromProto(&elem).BuildMessage()
+}
+
 func offlineBucket(username string) string {
 	return fmt.Sprintf("offline:%s", username)
 }
@@ -101,6 +213,15 @@ func (r *Repository) CountOfflineMessages(ctx context.Context, username string)
 	return
 }
 
+// CountOfflineBytes satisfies repository.Offline interface.
+func (r *Repository) CountOfflineBytes(ctx context.Context, username string) (size int, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		size, err = newOfflineRep(tx).CountOfflineBytes(ctx, username)
+		return err
+	})
+	return
+}
+
 // FetchOfflineMessages satisfies repository.Offline interface.
 func (r *Repository) FetchOfflineMessages(ctx context.Context, username string) (msg []*stravaganza.Message, err error) {
 	err = r.db.View(func(tx *bolt.Tx) error {
@@ -116,3 +237,46 @@ func (r *Repository) DeleteOfflineMessages(ctx context.Context, username string)
 		return newOfflineRep(tx).DeleteOfflineMessages(ctx, username)
 	})
 }
+
+// FetchOfflineItems satisfies repository.Offline interface.
+func (r *Repository) FetchOfflineItems(ctx context.Context, username string) (items []*offlinemodel.Item, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		items, err = newOfflineRep(tx).FetchOfflineItems(ctx, username)
+		return err
+	})
+	return
+}
+
+// FetchOfflineItem satisfies repository.Offline interface.
+func (r *Repository) FetchOfflineItem(ctx context.Context, username, node string) (item *offlinemodel.Item, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		item, err = newOfflineRep(tx).FetchOfflineItem(ctx, username, node)
+		return err
+	})
+	return
+}
+
+// DeleteOfflineItem satisfies repository.Offline interface.
+func (r *Repository) DeleteOfflineItem(ctx context.Context, username, node string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newOfflineRep(tx).DeleteOfflineItem(ctx, username, node)
+	})
+}
+
+// FetchOfflineUsernames satisfies repository.Offline interface.
+func (r *Repository) FetchOfflineUsernames(ctx context.Context) (usernames []string, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		usernames, err = newOfflineRep(tx).FetchOfflineUsernames(ctx)
+		return err
+	})
+	return
+}
+
+// DeleteOfflineMessagesBefore satisfies repository.Offline interface.
+func (r *Repository) DeleteOfflineMessagesBefore(ctx context.Context, username string, before time.Time) (count int, err error) {
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		count, err = newOfflineRep(tx).DeleteOfflineMessagesBefore(ctx, username, before)
+		return err
+	})
+	return
+}
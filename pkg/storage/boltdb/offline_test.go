@@ -17,7 +17,9 @@ package boltdb
 import (
 	"context"
 	"testing"
+	"time"
 
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 	"github.com/stretchr/testify/require"
 	bolt "go.etcd.io/bbolt"
 )
@@ -72,6 +74,14 @@ func TestBoltDB_CountOfflineMessages(t *testing.T) {
 		require.NoError(t, err)
 
 		require.Equal(t, 2, cnt)
+
+		b0, _ := m0.MarshalBinary()
+		b1, _ := m1.MarshalBinary()
+
+		size, err := rep.CountOfflineBytes(context.Background(), "ortuman")
+		require.NoError(t, err)
+
+		require.Equal(t, len(b0)+len(b1), size)
 		return nil
 	})
 	require.NoError(t, err)
@@ -109,3 +119,82 @@ func TestBoltDB_DeleteOfflineMessages(t *testing.T) {
 	})
 	require.NoError(t, err)
 }
+
+func TestBoltDB_FetchAndDeleteOfflineItems(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBOfflineRep{tx: tx}
+
+		m0 := testMessageStanza()
+		m1 := testMessageStanza()
+
+		err := rep.InsertOfflineMessage(context.Background(), m0, "ortuman")
+		require.NoError(t, err)
+
+		err = rep.InsertOfflineMessage(context.Background(), m1, "ortuman")
+		require.NoError(t, err)
+
+		items, err := rep.FetchOfflineItems(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.NotEqual(t, items[0].Node, items[1].Node)
+
+		item, err := rep.FetchOfflineItem(context.Background(), "ortuman", items[1].Node)
+		require.NoError(t, err)
+		require.NotNil(t, item)
+		require.Equal(t, items[1].Node, item.Node)
+
+		err = rep.DeleteOfflineItem(context.Background(), "ortuman", items[0].Node)
+		require.NoError(t, err)
+
+		item, err = rep.FetchOfflineItem(context.Background(), "ortuman", items[0].Node)
+		require.NoError(t, err)
+		require.Nil(t, item)
+
+		cnt, err := rep.CountOfflineMessages(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Equal(t, 1, cnt)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_DeleteOfflineMessagesBefore(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	now := time.Now()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBOfflineRep{tx: tx}
+
+		m0 := xmpputil.MakeDelayMessage(testMessageStanza(), now.Add(-time.Hour*48), "jackal.im", "Offline Storage")
+		m1 := xmpputil.MakeDelayMessage(testMessageStanza(), now, "jackal.im", "Offline Storage")
+
+		err := rep.InsertOfflineMessage(context.Background(), m0, "ortuman")
+		require.NoError(t, err)
+
+		err = rep.InsertOfflineMessage(context.Background(), m1, "ortuman")
+		require.NoError(t, err)
+
+		usernames, err := rep.FetchOfflineUsernames(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"ortuman"}, usernames)
+
+		count, err := rep.DeleteOfflineMessagesBefore(context.Background(), "ortuman", now.Add(-time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		cnt, err := rep.CountOfflineMessages(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Equal(t, 1, cnt)
+		return nil
+	})
+	require.NoError(t, err)
+}
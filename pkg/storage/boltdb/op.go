@@ -15,6 +15,7 @@
 package boltdb
 
 import (
+	"bytes"
 	"fmt"
 
 	bolt "go.etcd.io/bbolt"
@@ -48,19 +49,28 @@ type insertSeqOp struct {
 }
 
 func (op insertSeqOp) do() error {
+	_, err := op.doWithKey()
+	return err
+}
+
+func (op insertSeqOp) doWithKey() ([]byte, error) {
 	b, err := op.tx.CreateBucketIfNotExists([]byte(op.bucket))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	p, err := op.obj.MarshalBinary()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	seq, err := b.NextSequence()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	k := []byte(fmt.Sprintf("%d", seq))
+	if err := b.Put(k, p); err != nil {
+		return nil, err
 	}
-	return b.Put([]byte(fmt.Sprintf("%d", seq)), p)
+	return k, nil
 }
 
 type delBucketOp struct {
@@ -156,3 +166,61 @@ func (op iterKeysOp) do() error {
 	}
 	return nil
 }
+
+type iterBucketsOp struct {
+	tx     *bolt.Tx
+	prefix string
+	iterFn func(suffix string) error
+}
+
+func (op iterBucketsOp) do() error {
+	prefix := []byte(op.prefix)
+	return op.tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if !bytes.HasPrefix(name, prefix) {
+			return nil
+		}
+		if k, _ := b.Cursor().First(); k == nil {
+			return nil // skip empty buckets
+		}
+		return op.iterFn(string(name[len(prefix):]))
+	})
+}
+
+type delKeysOp struct {
+	tx       *bolt.Tx
+	bucket   string
+	filterFn func(k, v []byte) (bool, error)
+}
+
+func (op delKeysOp) do() (int, error) {
+	b := op.tx.Bucket([]byte(op.bucket))
+	if b == nil {
+		return 0, nil
+	}
+	var delKeys [][]byte
+	var total int
+
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		total++
+		ok, err := op.filterFn(k, v)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			delKeys = append(delKeys, k)
+		}
+	}
+	if len(delKeys) == total && total > 0 {
+		if err := op.tx.DeleteBucket([]byte(op.bucket)); err != nil {
+			return 0, err
+		}
+		return total, nil
+	}
+	for _, k := range delKeys {
+		if err := b.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(delKeys), nil
+}
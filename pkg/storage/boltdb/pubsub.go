@@ -0,0 +1,342 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	bolt "go.etcd.io/bbolt"
+)
+
+type boltDBPubSubRep struct {
+	tx *bolt.Tx
+}
+
+func newPubSubRep(tx *bolt.Tx) *boltDBPubSubRep {
+	return &boltDBPubSubRep{tx: tx}
+}
+
+func (r *boltDBPubSubRep) UpsertNode(_ context.Context, node *pubsubmodel.Node) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: pubSubNodesBucket(node.Host),
+		key:    node.Name,
+		obj:    node,
+	}
+	return op.do()
+}
+
+func (r *boltDBPubSubRep) FetchNode(_ context.Context, host, name string) (*pubsubmodel.Node, error) {
+	op := fetchKeyOp{
+		tx:     r.tx,
+		bucket: pubSubNodesBucket(host),
+		key:    name,
+		obj:    &pubsubmodel.Node{},
+	}
+	obj, err := op.do()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case obj != nil:
+		return obj.(*pubsubmodel.Node), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (r *boltDBPubSubRep) FetchNodes(_ context.Context, host string) ([]*pubsubmodel.Node, error) {
+	var retVal []*pubsubmodel.Node
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: pubSubNodesBucket(host),
+		iterFn: func(_, b []byte) error {
+			var node pubsubmodel.Node
+			if err := node.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			retVal = append(retVal, &node)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBPubSubRep) DeleteNode(_ context.Context, host, name string) error {
+	if err := r.deleteBucket(pubSubItemsBucket(host, name)); err != nil {
+		return err
+	}
+	if err := r.deleteBucket(pubSubSubscriptionsBucket(host, name)); err != nil {
+		return err
+	}
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: pubSubNodesBucket(host),
+		key:    name,
+	}
+	return op.do()
+}
+
+func (r *boltDBPubSubRep) DeleteNodes(ctx context.Context, host string) error {
+	nodes, err := r.FetchNodes(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := r.deleteBucket(pubSubItemsBucket(host, node.Name)); err != nil {
+			return err
+		}
+		if err := r.deleteBucket(pubSubSubscriptionsBucket(host, node.Name)); err != nil {
+			return err
+		}
+	}
+	return r.deleteBucket(pubSubNodesBucket(host))
+}
+
+func (r *boltDBPubSubRep) UpsertNodeItem(_ context.Context, item *pubsubmodel.Item) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: pubSubItemsBucket(item.Host, item.Node),
+		key:    item.Id,
+		obj:    item,
+	}
+	return op.do()
+}
+
+func (r *boltDBPubSubRep) FetchNodeItems(_ context.Context, host, name string) ([]*pubsubmodel.Item, error) {
+	var retVal []*pubsubmodel.Item
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: pubSubItemsBucket(host, name),
+		iterFn: func(_, b []byte) error {
+			var item pubsubmodel.Item
+			if err := item.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			retVal = append(retVal, &item)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(retVal, func(i, j int) bool {
+		return retVal[i].Stamp.AsTime().Before(retVal[j].Stamp.AsTime())
+	})
+	return retVal, nil
+}
+
+func (r *boltDBPubSubRep) DeleteNodeItem(_ context.Context, host, name, id string) error {
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: pubSubItemsBucket(host, name),
+		key:    id,
+	}
+	return op.do()
+}
+
+func (r *boltDBPubSubRep) DeleteNodeItems(_ context.Context, host, name string) error {
+	return r.deleteBucket(pubSubItemsBucket(host, name))
+}
+
+func (r *boltDBPubSubRep) DeleteOldestNodeItems(ctx context.Context, host, name string, maxItems int) error {
+	items, err := r.FetchNodeItems(ctx, host, name)
+	if err != nil {
+		return err
+	}
+	if len(items) <= maxItems {
+		return nil
+	}
+	for _, item := range items[:len(items)-maxItems] {
+		if err := r.DeleteNodeItem(ctx, host, name, item.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *boltDBPubSubRep) UpsertNodeSubscription(_ context.Context, sub *pubsubmodel.Subscription) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: pubSubSubscriptionsBucket(sub.Host, sub.Node),
+		key:    sub.Jid,
+		obj:    sub,
+	}
+	return op.do()
+}
+
+func (r *boltDBPubSubRep) FetchNodeSubscriptions(_ context.Context, host, name string) ([]*pubsubmodel.Subscription, error) {
+	var retVal []*pubsubmodel.Subscription
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: pubSubSubscriptionsBucket(host, name),
+		iterFn: func(_, b []byte) error {
+			var sub pubsubmodel.Subscription
+			if err := sub.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			retVal = append(retVal, &sub)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBPubSubRep) DeleteNodeSubscription(_ context.Context, host, name, jid string) error {
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: pubSubSubscriptionsBucket(host, name),
+		key:    jid,
+	}
+	return op.do()
+}
+
+func (r *boltDBPubSubRep) deleteBucket(bucket string) error {
+	existsOp := bucketExistsOp{
+		tx:     r.tx,
+		bucket: bucket,
+	}
+	if !existsOp.do() {
+		return nil
+	}
+	op := delBucketOp{
+		tx:     r.tx,
+		bucket: bucket,
+	}
+	return op.do()
+}
+
+func pubSubNodesBucket(host string) string {
+	return fmt.Sprintf("pubsub_nodes:%s", host)
+}
+
+func pubSubItemsBucket(host, name string) string {
+	return fmt.Sprintf("pubsub_items:%s:%s", host, name)
+}
+
+func pubSubSubscriptionsBucket(host, name string) string {
+	return fmt.Sprintf("pubsub_subscriptions:%s:%s", host, name)
+}
+
+// UpsertNode satisfies repository.PubSub interface.
+func (r *Repository) UpsertNode(ctx context.Context, node *pubsubmodel.Node) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPubSubRep(tx).UpsertNode(ctx, node)
+	})
+}
+
+// FetchNode satisfies repository.PubSub interface.
+func (r *Repository) FetchNode(ctx context.Context, host, name string) (node *pubsubmodel.Node, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		node, err = newPubSubRep(tx).FetchNode(ctx, host, name)
+		return err
+	})
+	return
+}
+
+// FetchNodes satisfies repository.PubSub interface.
+func (r *Repository) FetchNodes(ctx context.Context, host string) (nodes []*pubsubmodel.Node, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		nodes, err = newPubSubRep(tx).FetchNodes(ctx, host)
+		return err
+	})
+	return
+}
+
+// DeleteNode satisfies repository.PubSub interface.
+func (r *Repository) DeleteNode(ctx context.Context, host, name string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPubSubRep(tx).DeleteNode(ctx, host, name)
+	})
+}
+
+// DeleteNodes satisfies repository.PubSub interface.
+func (r *Repository) DeleteNodes(ctx context.Context, host string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPubSubRep(tx).DeleteNodes(ctx, host)
+	})
+}
+
+// UpsertNodeItem satisfies repository.PubSub interface.
+func (r *Repository) UpsertNodeItem(ctx context.Context, item *pubsubmodel.Item) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPubSubRep(tx).UpsertNodeItem(ctx, item)
+	})
+}
+
+// FetchNodeItems satisfies repository.PubSub interface.
+func (r *Repository) FetchNodeItems(ctx context.Context, host, name string) (items []*pubsubmodel.Item, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		items, err = newPubSubRep(tx).FetchNodeItems(ctx, host, name)
+		return err
+	})
+	return
+}
+
+// DeleteNodeItem satisfies repository.PubSub interface.
+func (r *Repository) DeleteNodeItem(ctx context.Context, host, name, id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPubSubRep(tx).DeleteNodeItem(ctx, host, name, id)
+	})
+}
+
+// DeleteNodeItems satisfies repository.PubSub interface.
+func (r *Repository) DeleteNodeItems(ctx context.Context, host, name string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPubSubRep(tx).DeleteNodeItems(ctx, host, name)
+	})
+}
+
+// DeleteOldestNodeItems satisfies repository.PubSub interface.
+func (r *Repository) DeleteOldestNodeItems(ctx context.Context, host, name string, maxItems int) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPubSubRep(tx).DeleteOldestNodeItems(ctx, host, name, maxItems)
+	})
+}
+
+// UpsertNodeSubscription satisfies repository.PubSub interface.
+func (r *Repository) UpsertNodeSubscription(ctx context.Context, sub *pubsubmodel.Subscription) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPubSubRep(tx).UpsertNodeSubscription(ctx, sub)
+	})
+}
+
+// FetchNodeSubscriptions satisfies repository.PubSub interface.
+func (r *Repository) FetchNodeSubscriptions(ctx context.Context, host, name string) (subs []*pubsubmodel.Subscription, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		subs, err = newPubSubRep(tx).FetchNodeSubscriptions(ctx, host, name)
+		return err
+	})
+	return
+}
+
+// DeleteNodeSubscription satisfies repository.PubSub interface.
+func (r *Repository) DeleteNodeSubscription(ctx context.Context, host, name, jid string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPubSubRep(tx).DeleteNodeSubscription(ctx, host, name, jid)
+	})
+}
@@ -0,0 +1,168 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestBoltDB_UpsertAndFetchNode(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBPubSubRep{tx: tx}
+
+		for _, node := range []*pubsubmodel.Node{
+			{Host: "ortuman@jackal.im", Name: "urn:xmpp:avatar:data", Options: &pubsubmodel.Options{AccessModel: "presence"}},
+			{Host: "ortuman@jackal.im", Name: "urn:xmpp:avatar:metadata", Options: &pubsubmodel.Options{AccessModel: "open"}},
+			{Host: "noelia@jackal.im", Name: "urn:xmpp:avatar:data"},
+		} {
+			require.NoError(t, rep.UpsertNode(context.Background(), node))
+		}
+
+		node, err := rep.FetchNode(context.Background(), "ortuman@jackal.im", "urn:xmpp:avatar:metadata")
+		require.NoError(t, err)
+		require.NotNil(t, node)
+		require.Equal(t, "open", node.Options.AccessModel)
+
+		nodes, err := rep.FetchNodes(context.Background(), "ortuman@jackal.im")
+		require.NoError(t, err)
+		require.Len(t, nodes, 2)
+
+		err = rep.DeleteNode(context.Background(), "ortuman@jackal.im", "urn:xmpp:avatar:metadata")
+		require.NoError(t, err)
+
+		node, err = rep.FetchNode(context.Background(), "ortuman@jackal.im", "urn:xmpp:avatar:metadata")
+		require.NoError(t, err)
+		require.Nil(t, node)
+
+		err = rep.DeleteNodes(context.Background(), "ortuman@jackal.im")
+		require.NoError(t, err)
+
+		nodes, err = rep.FetchNodes(context.Background(), "ortuman@jackal.im")
+		require.NoError(t, err)
+		require.Len(t, nodes, 0)
+
+		nodes, err = rep.FetchNodes(context.Background(), "noelia@jackal.im")
+		require.NoError(t, err)
+		require.Len(t, nodes, 1)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_NodeItems(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	now := time.Now()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBPubSubRep{tx: tx}
+
+		require.NoError(t, rep.UpsertNode(context.Background(), &pubsubmodel.Node{Host: "pubsub.jackal.im", Name: "news"}))
+
+		for i, id := range []string{"i3", "i1", "i2"} {
+			item := &pubsubmodel.Item{
+				Host:    "pubsub.jackal.im",
+				Node:    "news",
+				Id:      id,
+				Payload: stravaganza.NewBuilder("entry").WithText(id).Build().Proto(),
+				Stamp:   timestamppb.New(now.Add(time.Duration(i) * time.Second)),
+			}
+			require.NoError(t, rep.UpsertNodeItem(context.Background(), item))
+		}
+
+		items, err := rep.FetchNodeItems(context.Background(), "pubsub.jackal.im", "news")
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+		require.Equal(t, "i3", items[0].Id)
+		require.Equal(t, "i2", items[2].Id)
+
+		require.NoError(t, rep.DeleteOldestNodeItems(context.Background(), "pubsub.jackal.im", "news", 2))
+
+		items, err = rep.FetchNodeItems(context.Background(), "pubsub.jackal.im", "news")
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.Equal(t, "i1", items[0].Id)
+
+		require.NoError(t, rep.DeleteNodeItem(context.Background(), "pubsub.jackal.im", "news", "i1"))
+
+		items, err = rep.FetchNodeItems(context.Background(), "pubsub.jackal.im", "news")
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+
+		require.NoError(t, rep.DeleteNodeItems(context.Background(), "pubsub.jackal.im", "news"))
+
+		items, err = rep.FetchNodeItems(context.Background(), "pubsub.jackal.im", "news")
+		require.NoError(t, err)
+		require.Len(t, items, 0)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_NodeSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBPubSubRep{tx: tx}
+
+		require.NoError(t, rep.UpsertNode(context.Background(), &pubsubmodel.Node{Host: "pubsub.jackal.im", Name: "news"}))
+
+		for _, sub := range []*pubsubmodel.Subscription{
+			{Host: "pubsub.jackal.im", Node: "news", Jid: "ortuman@jackal.im", Subid: "s1", State: "subscribed"},
+			{Host: "pubsub.jackal.im", Node: "news", Jid: "noelia@jackal.im", Subid: "s2", State: "subscribed"},
+		} {
+			require.NoError(t, rep.UpsertNodeSubscription(context.Background(), sub))
+		}
+
+		subs, err := rep.FetchNodeSubscriptions(context.Background(), "pubsub.jackal.im", "news")
+		require.NoError(t, err)
+		require.Len(t, subs, 2)
+
+		require.NoError(t, rep.DeleteNodeSubscription(context.Background(), "pubsub.jackal.im", "news", "noelia@jackal.im"))
+
+		subs, err = rep.FetchNodeSubscriptions(context.Background(), "pubsub.jackal.im", "news")
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		require.Equal(t, "ortuman@jackal.im", subs[0].Jid)
+
+		// deleting node should remove all its subscriptions
+		require.NoError(t, rep.DeleteNode(context.Background(), "pubsub.jackal.im", "news"))
+
+		subs, err = rep.FetchNodeSubscriptions(context.Background(), "pubsub.jackal.im", "news")
+		require.NoError(t, err)
+		require.Len(t, subs, 0)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"fmt"
+
+	pushmodel "github.com/ortuman/jackal/pkg/model/push"
+	bolt "go.etcd.io/bbolt"
+)
+
+type boltDBPushRep struct {
+	tx *bolt.Tx
+}
+
+func newPushRep(tx *bolt.Tx) *boltDBPushRep {
+	return &boltDBPushRep{tx: tx}
+}
+
+func (r *boltDBPushRep) UpsertPushRegistration(_ context.Context, reg *pushmodel.Registration) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: pushBucket(reg.Username),
+		key:    pushKey(reg.Jid, reg.Node),
+		obj:    reg,
+	}
+	return op.do()
+}
+
+func (r *boltDBPushRep) DeletePushRegistration(_ context.Context, username, jid, node string) error {
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: pushBucket(username),
+		key:    pushKey(jid, node),
+	}
+	return op.do()
+}
+
+func (r *boltDBPushRep) FetchPushRegistrations(_ context.Context, username string) ([]*pushmodel.Registration, error) {
+	var retVal []*pushmodel.Registration
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: pushBucket(username),
+		iterFn: func(_, b []byte) error {
+			var reg pushmodel.Registration
+			if err := reg.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			retVal = append(retVal, &reg)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBPushRep) DeletePushRegistrations(_ context.Context, username string) error {
+	op := delBucketOp{
+		tx:     r.tx,
+		bucket: pushBucket(username),
+	}
+	return op.do()
+}
+
+func pushBucket(username string) string {
+	return fmt.Sprintf("push:%s", username)
+}
+
+func pushKey(jid, node string) string {
+	return fmt.Sprintf("%s:%s", jid, node)
+}
+
+// UpsertPushRegistration satisfies repository.Push interface.
+func (r *Repository) UpsertPushRegistration(ctx context.Context, reg *pushmodel.Registration) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPushRep(tx).UpsertPushRegistration(ctx, reg)
+	})
+}
+
+// DeletePushRegistration satisfies repository.Push interface.
+func (r *Repository) DeletePushRegistration(ctx context.Context, username, jid, node string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPushRep(tx).DeletePushRegistration(ctx, username, jid, node)
+	})
+}
+
+// FetchPushRegistrations satisfies repository.Push interface.
+func (r *Repository) FetchPushRegistrations(ctx context.Context, username string) (regs []*pushmodel.Registration, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		regs, err = newPushRep(tx).FetchPushRegistrations(ctx, username)
+		return err
+	})
+	return
+}
+
+// DeletePushRegistrations satisfies repository.Push interface.
+func (r *Repository) DeletePushRegistrations(ctx context.Context, username string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newPushRep(tx).DeletePushRegistrations(ctx, username)
+	})
+}
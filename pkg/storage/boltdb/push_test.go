@@ -0,0 +1,103 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	pushmodel "github.com/ortuman/jackal/pkg/model/push"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltDB_UpsertAndFetchPushRegistrations(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBPushRep{tx: tx}
+
+		err := rep.UpsertPushRegistration(context.Background(), &pushmodel.Registration{
+			Username: "ortuman",
+			Resource: "yard",
+			Jid:      "push.jackal.im",
+			Node:     "n1",
+			PublishOptions: stravaganza.NewBuilder("x").
+				WithAttribute(stravaganza.Namespace, "jabber:x:data").
+				Build().
+				Proto(),
+		})
+		require.NoError(t, err)
+
+		err = rep.UpsertPushRegistration(context.Background(), &pushmodel.Registration{
+			Username: "ortuman",
+			Resource: "balcony",
+			Jid:      "push.jackal.im",
+			Node:     "n2",
+		})
+		require.NoError(t, err)
+
+		regs, err := rep.FetchPushRegistrations(context.Background(), "ortuman")
+		require.NoError(t, err)
+
+		require.Len(t, regs, 2)
+		require.Equal(t, "yard", regs[0].Resource)
+		require.Equal(t, "x", regs[0].PublishOptions.Name)
+		require.Equal(t, "balcony", regs[1].Resource)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_DeletePushRegistration(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBPushRep{tx: tx}
+
+		for _, node := range []string{"n1", "n2"} {
+			err := rep.UpsertPushRegistration(context.Background(), &pushmodel.Registration{
+				Username: "ortuman",
+				Resource: "yard",
+				Jid:      "push.jackal.im",
+				Node:     node,
+			})
+			require.NoError(t, err)
+		}
+		err := rep.DeletePushRegistration(context.Background(), "ortuman", "push.jackal.im", "n1")
+		require.NoError(t, err)
+
+		regs, err := rep.FetchPushRegistrations(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Len(t, regs, 1)
+		require.Equal(t, "n2", regs[0].Node)
+
+		err = rep.DeletePushRegistrations(context.Background(), "ortuman")
+		require.NoError(t, err)
+
+		regs, err = rep.FetchPushRegistrations(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Len(t, regs, 0)
+		return nil
+	})
+	require.NoError(t, err)
+}
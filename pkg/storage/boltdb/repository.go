@@ -32,6 +32,11 @@ type Config struct {
 // Repository represents a BoltDB repository implementation.
 type Repository struct {
 	repository.User
+	repository.FastToken
+	repository.Invite
+	repository.Muc
+	repository.PubSub
+	repository.Push
 	repository.Last
 	repository.Capabilities
 	repository.Offline
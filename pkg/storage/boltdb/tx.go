@@ -21,6 +21,11 @@ import (
 
 type repTx struct {
 	repository.User
+	repository.FastToken
+	repository.Invite
+	repository.Muc
+	repository.PubSub
+	repository.Push
 	repository.Last
 	repository.Capabilities
 	repository.Offline
@@ -35,6 +40,11 @@ type repTx struct {
 func newRepTx(tx *bolt.Tx) *repTx {
 	return &repTx{
 		User:         newUserRep(tx),
+		FastToken:    newFastTokenRep(tx),
+		Invite:       newInviteRep(tx),
+		Muc:          newMucRep(tx),
+		PubSub:       newPubSubRep(tx),
+		Push:         newPushRep(tx),
 		Last:         newLastRep(tx),
 		Capabilities: newCapsRep(tx),
 		Offline:      newOfflineRep(tx),
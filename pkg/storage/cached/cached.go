@@ -61,6 +61,11 @@ type Cache interface {
 // CachedRepository is cached Repository implementation.
 type CachedRepository struct {
 	repository.User
+	repository.FastToken
+	repository.Invite
+	repository.Muc
+	repository.PubSub
+	repository.Push
 	repository.Last
 	repository.Capabilities
 	repository.Offline
@@ -92,6 +97,11 @@ func New(cfg Config, rep repository.Repository, logger kitlog.Logger) (repositor
 		BlockList:    &cachedBlockListRep{c: c, rep: rep, logger: logger},
 		Roster:       &cachedRosterRep{c: c, rep: rep, logger: logger},
 		VCard:        &cachedVCardRep{c: c, rep: rep, logger: logger},
+		FastToken:    rep,
+		Invite:       rep,
+		Muc:          rep,
+		PubSub:       rep,
+		Push:         rep,
 		Archive:      rep,
 		Offline:      rep,
 		Locker:       rep,
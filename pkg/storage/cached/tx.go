@@ -20,6 +20,11 @@ import (
 
 type cachedTx struct {
 	repository.User
+	repository.FastToken
+	repository.Invite
+	repository.Muc
+	repository.PubSub
+	repository.Push
 	repository.Last
 	repository.Capabilities
 	repository.Offline
@@ -40,6 +45,11 @@ func newCacheTx(c Cache, tx repository.Transaction) *cachedTx {
 		BlockList:    &cachedBlockListRep{c: c, rep: tx},
 		Roster:       &cachedRosterRep{c: c, rep: tx},
 		VCard:        &cachedVCardRep{c: c, rep: tx},
+		FastToken:    tx,
+		Invite:       tx,
+		Muc:          tx,
+		PubSub:       tx,
+		Push:         tx,
 		Archive:      tx,
 		Offline:      tx,
 		Locker:       tx,
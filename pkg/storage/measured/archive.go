@@ -48,6 +48,13 @@ func (m *measuredArchiveRep) FetchArchiveMessages(ctx context.Context, f *archiv
 	return
 }
 
+func (m *measuredArchiveRep) UpdateArchiveMessage(ctx context.Context, message *archivemodel.Message) error {
+	t0 := time.Now()
+	err := m.rep.UpdateArchiveMessage(ctx, message)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
 func (m *measuredArchiveRep) DeleteArchiveOldestMessages(ctx context.Context, archiveID string, maxElements int) error {
 	t0 := time.Now()
 	err := m.rep.DeleteArchiveOldestMessages(ctx, archiveID, maxElements)
@@ -61,3 +68,38 @@ func (m *measuredArchiveRep) DeleteArchive(ctx context.Context, archiveID string
 	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
 	return err
 }
+
+func (m *measuredArchiveRep) UpsertArchivePreferences(ctx context.Context, prefs *archivemodel.Preferences) error {
+	t0 := time.Now()
+	err := m.rep.UpsertArchivePreferences(ctx, prefs)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredArchiveRep) FetchArchivePreferences(ctx context.Context, username string) (prefs *archivemodel.Preferences, err error) {
+	t0 := time.Now()
+	prefs, err = m.rep.FetchArchivePreferences(ctx, username)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return
+}
+
+func (m *measuredArchiveRep) DeleteArchivePreferences(ctx context.Context, username string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteArchivePreferences(ctx, username)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredArchiveRep) FetchArchiveIDs(ctx context.Context) (archiveIDs []string, err error) {
+	t0 := time.Now()
+	archiveIDs, err = m.rep.FetchArchiveIDs(ctx)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return
+}
+
+func (m *measuredArchiveRep) DeleteArchiveMessagesBefore(ctx context.Context, archiveID string, before time.Time) (count int, err error) {
+	t0 := time.Now()
+	count, err = m.rep.DeleteArchiveMessagesBefore(ctx, archiveID, before)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return
+}
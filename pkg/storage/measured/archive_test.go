@@ -17,6 +17,7 @@ package measuredrepository
 import (
 	"context"
 	"testing"
+	"time"
 
 	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
 	"github.com/stretchr/testify/require"
@@ -67,6 +68,22 @@ func TestMeasuredArchiveRep_FetchArchiveMessages(t *testing.T) {
 	require.Len(t, repMock.FetchArchiveMessagesCalls(), 1)
 }
 
+func TestMeasuredArchiveRep_UpdateArchiveMessage(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpdateArchiveMessageFunc = func(ctx context.Context, message *archivemodel.Message) error {
+		return nil
+	}
+	m := &measuredArchiveRep{rep: repMock}
+
+	// when
+	err := m.UpdateArchiveMessage(context.Background(), &archivemodel.Message{})
+
+	// then
+	require.Len(t, repMock.UpdateArchiveMessageCalls(), 1)
+	require.NoError(t, err)
+}
+
 func TestMeasuredArchiveRep_DeleteArchiveOldestMessages(t *testing.T) {
 	// given
 	repMock := &repositoryMock{}
@@ -97,3 +114,78 @@ func TestMeasuredArchiveRep_DeleteArchive(t *testing.T) {
 	// then
 	require.Len(t, repMock.DeleteArchiveCalls(), 1)
 }
+
+func TestMeasuredArchiveRep_UpsertArchivePreferences(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertArchivePreferencesFunc = func(ctx context.Context, prefs *archivemodel.Preferences) error {
+		return nil
+	}
+	m := &measuredArchiveRep{rep: repMock}
+
+	// when
+	_ = m.UpsertArchivePreferences(context.Background(), &archivemodel.Preferences{})
+
+	// then
+	require.Len(t, repMock.UpsertArchivePreferencesCalls(), 1)
+}
+
+func TestMeasuredArchiveRep_FetchArchivePreferences(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchArchivePreferencesFunc = func(ctx context.Context, username string) (*archivemodel.Preferences, error) {
+		return nil, nil
+	}
+	m := &measuredArchiveRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchArchivePreferences(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.FetchArchivePreferencesCalls(), 1)
+}
+
+func TestMeasuredArchiveRep_DeleteArchivePreferences(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteArchivePreferencesFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+	m := &measuredArchiveRep{rep: repMock}
+
+	// when
+	_ = m.DeleteArchivePreferences(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.DeleteArchivePreferencesCalls(), 1)
+}
+
+func TestMeasuredArchiveRep_FetchArchiveIDs(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchArchiveIDsFunc = func(ctx context.Context) ([]string, error) {
+		return nil, nil
+	}
+	m := &measuredArchiveRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchArchiveIDs(context.Background())
+
+	// then
+	require.Len(t, repMock.FetchArchiveIDsCalls(), 1)
+}
+
+func TestMeasuredArchiveRep_DeleteArchiveMessagesBefore(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteArchiveMessagesBeforeFunc = func(ctx context.Context, archiveID string, before time.Time) (int, error) {
+		return 0, nil
+	}
+	m := &measuredArchiveRep{rep: repMock}
+
+	// when
+	_, _ = m.DeleteArchiveMessagesBefore(context.Background(), "a1234", time.Now())
+
+	// then
+	require.Len(t, repMock.DeleteArchiveMessagesBeforeCalls(), 1)
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"time"
+
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type measuredFastTokenRep struct {
+	rep  repository.FastToken
+	inTx bool
+}
+
+func (m *measuredFastTokenRep) UpsertFastToken(ctx context.Context, token *fastmodel.Token) error {
+	t0 := time.Now()
+	err := m.rep.UpsertFastToken(ctx, token)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredFastTokenRep) FetchFastTokens(ctx context.Context, username string) (tokens []*fastmodel.Token, err error) {
+	t0 := time.Now()
+	tokens, err = m.rep.FetchFastTokens(ctx, username)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return
+}
+
+func (m *measuredFastTokenRep) DeleteFastToken(ctx context.Context, username, clientID string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteFastToken(ctx, username, clientID)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredFastTokenRep) DeleteFastTokens(ctx context.Context, username string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteFastTokens(ctx, username)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
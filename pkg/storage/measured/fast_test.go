@@ -0,0 +1,86 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"testing"
+
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasuredFastTokenRep_UpsertFastToken(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertFastTokenFunc = func(ctx context.Context, token *fastmodel.Token) error {
+		return nil
+	}
+	m := &measuredFastTokenRep{rep: repMock}
+
+	// when
+	_ = m.UpsertFastToken(context.Background(), &fastmodel.Token{
+		Username: "ortuman",
+		ClientId: "d4565fa7-4d72-4749-b3d3-740edbf87770",
+	})
+
+	// then
+	require.Len(t, repMock.UpsertFastTokenCalls(), 1)
+}
+
+func TestMeasuredFastTokenRep_FetchFastTokens(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchFastTokensFunc = func(ctx context.Context, username string) ([]*fastmodel.Token, error) {
+		return nil, nil
+	}
+	m := &measuredFastTokenRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchFastTokens(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.FetchFastTokensCalls(), 1)
+}
+
+func TestMeasuredFastTokenRep_DeleteFastToken(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteFastTokenFunc = func(ctx context.Context, username, clientID string) error {
+		return nil
+	}
+	m := &measuredFastTokenRep{rep: repMock}
+
+	// when
+	_ = m.DeleteFastToken(context.Background(), "ortuman", "d4565fa7-4d72-4749-b3d3-740edbf87770")
+
+	// then
+	require.Len(t, repMock.DeleteFastTokenCalls(), 1)
+}
+
+func TestMeasuredFastTokenRep_DeleteFastTokens(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteFastTokensFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+	m := &measuredFastTokenRep{rep: repMock}
+
+	// when
+	_ = m.DeleteFastTokens(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.DeleteFastTokensCalls(), 1)
+}
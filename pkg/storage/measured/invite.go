@@ -0,0 +1,56 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"time"
+
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type measuredInviteRep struct {
+	rep  repository.Invite
+	inTx bool
+}
+
+func (m *measuredInviteRep) UpsertInvite(ctx context.Context, inv *invitemodel.Invite) error {
+	t0 := time.Now()
+	err := m.rep.UpsertInvite(ctx, inv)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredInviteRep) FetchInvite(ctx context.Context, token string) (inv *invitemodel.Invite, err error) {
+	t0 := time.Now()
+	inv, err = m.rep.FetchInvite(ctx, token)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return
+}
+
+func (m *measuredInviteRep) DeleteInvite(ctx context.Context, token string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteInvite(ctx, token)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredInviteRep) DeleteInvites(ctx context.Context, inviter string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteInvites(ctx, inviter)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"testing"
+
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasuredInviteRep_UpsertInvite(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertInviteFunc = func(ctx context.Context, inv *invitemodel.Invite) error {
+		return nil
+	}
+	m := &measuredInviteRep{rep: repMock}
+
+	// when
+	_ = m.UpsertInvite(context.Background(), &invitemodel.Invite{
+		Token:   "t0k3n",
+		Inviter: "ortuman",
+	})
+
+	// then
+	require.Len(t, repMock.UpsertInviteCalls(), 1)
+}
+
+func TestMeasuredInviteRep_FetchInvite(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchInviteFunc = func(ctx context.Context, token string) (*invitemodel.Invite, error) {
+		return nil, nil
+	}
+	m := &measuredInviteRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchInvite(context.Background(), "t0k3n")
+
+	// then
+	require.Len(t, repMock.FetchInviteCalls(), 1)
+}
+
+func TestMeasuredInviteRep_DeleteInvite(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteInviteFunc = func(ctx context.Context, token string) error {
+		return nil
+	}
+	m := &measuredInviteRep{rep: repMock}
+
+	// when
+	_ = m.DeleteInvite(context.Background(), "t0k3n")
+
+	// then
+	require.Len(t, repMock.DeleteInviteCalls(), 1)
+}
+
+func TestMeasuredInviteRep_DeleteInvites(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteInvitesFunc = func(ctx context.Context, inviter string) error {
+		return nil
+	}
+	m := &measuredInviteRep{rep: repMock}
+
+	// when
+	_ = m.DeleteInvites(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.DeleteInvitesCalls(), 1)
+}
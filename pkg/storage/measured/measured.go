@@ -33,6 +33,11 @@ const (
 // Measured is measured Repository implementation.
 type Measured struct {
 	measuredUserRep
+	measuredFastTokenRep
+	measuredInviteRep
+	measuredMucRep
+	measuredPubSubRep
+	measuredPushRep
 	measuredLastRep
 	measuredCapabilitiesRep
 	measuredOfflineRep
@@ -49,6 +54,11 @@ type Measured struct {
 func New(rep repository.Repository) repository.Repository {
 	return &Measured{
 		measuredUserRep:         measuredUserRep{rep: rep},
+		measuredFastTokenRep:    measuredFastTokenRep{rep: rep},
+		measuredInviteRep:       measuredInviteRep{rep: rep},
+		measuredMucRep:          measuredMucRep{rep: rep},
+		measuredPubSubRep:       measuredPubSubRep{rep: rep},
+		measuredPushRep:         measuredPushRep{rep: rep},
 		measuredLastRep:         measuredLastRep{rep: rep},
 		measuredCapabilitiesRep: measuredCapabilitiesRep{rep: rep},
 		measuredOfflineRep:      measuredOfflineRep{rep: rep},
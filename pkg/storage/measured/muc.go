@@ -0,0 +1,84 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"time"
+
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type measuredMucRep struct {
+	rep  repository.Muc
+	inTx bool
+}
+
+func (m *measuredMucRep) UpsertRoom(ctx context.Context, room *mucmodel.Room) error {
+	t0 := time.Now()
+	err := m.rep.UpsertRoom(ctx, room)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredMucRep) FetchRoom(ctx context.Context, roomJID string) (*mucmodel.Room, error) {
+	t0 := time.Now()
+	room, err := m.rep.FetchRoom(ctx, roomJID)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return room, err
+}
+
+func (m *measuredMucRep) FetchRooms(ctx context.Context, host string) ([]*mucmodel.Room, error) {
+	t0 := time.Now()
+	rooms, err := m.rep.FetchRooms(ctx, host)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return rooms, err
+}
+
+func (m *measuredMucRep) DeleteRoom(ctx context.Context, roomJID string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteRoom(ctx, roomJID)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredMucRep) UpsertRoomAffiliation(ctx context.Context, aff *mucmodel.Affiliation) error {
+	t0 := time.Now()
+	err := m.rep.UpsertRoomAffiliation(ctx, aff)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredMucRep) FetchRoomAffiliations(ctx context.Context, roomJID string) ([]*mucmodel.Affiliation, error) {
+	t0 := time.Now()
+	affs, err := m.rep.FetchRoomAffiliations(ctx, roomJID)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return affs, err
+}
+
+func (m *measuredMucRep) DeleteRoomAffiliation(ctx context.Context, roomJID, jid string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteRoomAffiliation(ctx, roomJID, jid)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredMucRep) DeleteRoomAffiliations(ctx context.Context, roomJID string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteRoomAffiliations(ctx, roomJID)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"testing"
+
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasuredMucRep_UpsertRoom(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertRoomFunc = func(ctx context.Context, room *mucmodel.Room) error {
+		return nil
+	}
+	m := &measuredMucRep{rep: repMock}
+
+	// when
+	_ = m.UpsertRoom(context.Background(), &mucmodel.Room{Jid: "lobby@conference.jackal.im"})
+
+	// then
+	require.Len(t, repMock.UpsertRoomCalls(), 1)
+}
+
+func TestMeasuredMucRep_FetchRoom(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchRoomFunc = func(ctx context.Context, roomJID string) (*mucmodel.Room, error) {
+		return nil, nil
+	}
+	m := &measuredMucRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchRoom(context.Background(), "lobby@conference.jackal.im")
+
+	// then
+	require.Len(t, repMock.FetchRoomCalls(), 1)
+}
+
+func TestMeasuredMucRep_FetchRooms(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchRoomsFunc = func(ctx context.Context, host string) ([]*mucmodel.Room, error) {
+		return nil, nil
+	}
+	m := &measuredMucRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchRooms(context.Background(), "conference.jackal.im")
+
+	// then
+	require.Len(t, repMock.FetchRoomsCalls(), 1)
+}
+
+func TestMeasuredMucRep_DeleteRoom(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteRoomFunc = func(ctx context.Context, roomJID string) error {
+		return nil
+	}
+	m := &measuredMucRep{rep: repMock}
+
+	// when
+	_ = m.DeleteRoom(context.Background(), "lobby@conference.jackal.im")
+
+	// then
+	require.Len(t, repMock.DeleteRoomCalls(), 1)
+}
+
+func TestMeasuredMucRep_UpsertRoomAffiliation(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertRoomAffiliationFunc = func(ctx context.Context, aff *mucmodel.Affiliation) error {
+		return nil
+	}
+	m := &measuredMucRep{rep: repMock}
+
+	// when
+	_ = m.UpsertRoomAffiliation(context.Background(), &mucmodel.Affiliation{RoomJid: "lobby@conference.jackal.im"})
+
+	// then
+	require.Len(t, repMock.UpsertRoomAffiliationCalls(), 1)
+}
+
+func TestMeasuredMucRep_FetchRoomAffiliations(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchRoomAffiliationsFunc = func(ctx context.Context, roomJID string) ([]*mucmodel.Affiliation, error) {
+		return nil, nil
+	}
+	m := &measuredMucRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchRoomAffiliations(context.Background(), "lobby@conference.jackal.im")
+
+	// then
+	require.Len(t, repMock.FetchRoomAffiliationsCalls(), 1)
+}
+
+func TestMeasuredMucRep_DeleteRoomAffiliation(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteRoomAffiliationFunc = func(ctx context.Context, roomJID, jid string) error {
+		return nil
+	}
+	m := &measuredMucRep{rep: repMock}
+
+	// when
+	_ = m.DeleteRoomAffiliation(context.Background(), "lobby@conference.jackal.im", "noelia@jackal.im")
+
+	// then
+	require.Len(t, repMock.DeleteRoomAffiliationCalls(), 1)
+}
+
+func TestMeasuredMucRep_DeleteRoomAffiliations(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteRoomAffiliationsFunc = func(ctx context.Context, roomJID string) error {
+		return nil
+	}
+	m := &measuredMucRep{rep: repMock}
+
+	// when
+	_ = m.DeleteRoomAffiliations(context.Background(), "lobby@conference.jackal.im")
+
+	// then
+	require.Len(t, repMock.DeleteRoomAffiliationsCalls(), 1)
+}
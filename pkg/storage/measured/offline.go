@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/jackal-xmpp/stravaganza"
+	offlinemodel "github.com/ortuman/jackal/pkg/model/offline"
 	"github.com/ortuman/jackal/pkg/storage/repository"
 )
 
@@ -41,6 +42,13 @@ func (m *measuredOfflineRep) CountOfflineMessages(ctx context.Context, username
 	return count, err
 }
 
+func (m *measuredOfflineRep) CountOfflineBytes(ctx context.Context, username string) (int, error) {
+	t0 := time.Now()
+	size, err := m.rep.CountOfflineBytes(ctx, username)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return size, err
+}
+
 func (m *measuredOfflineRep) FetchOfflineMessages(ctx context.Context, username string) ([]*stravaganza.Message, error) {
 	t0 := time.Now()
 	ms, err := m.rep.FetchOfflineMessages(ctx, username)
@@ -54,3 +62,38 @@ func (m *measuredOfflineRep) DeleteOfflineMessages(ctx context.Context, username
 	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
 	return err
 }
+
+func (m *measuredOfflineRep) FetchOfflineItems(ctx context.Context, username string) ([]*offlinemodel.Item, error) {
+	t0 := time.Now()
+	items, err := m.rep.FetchOfflineItems(ctx, username)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return items, err
+}
+
+func (m *measuredOfflineRep) FetchOfflineItem(ctx context.Context, username, node string) (*offlinemodel.Item, error) {
+	t0 := time.Now()
+	item, err := m.rep.FetchOfflineItem(ctx, username, node)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return item, err
+}
+
+func (m *measuredOfflineRep) DeleteOfflineItem(ctx context.Context, username, node string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteOfflineItem(ctx, username, node)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredOfflineRep) FetchOfflineUsernames(ctx context.Context) ([]string, error) {
+	t0 := time.Now()
+	usernames, err := m.rep.FetchOfflineUsernames(ctx)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return usernames, err
+}
+
+func (m *measuredOfflineRep) DeleteOfflineMessagesBefore(ctx context.Context, username string, before time.Time) (int, error) {
+	t0 := time.Now()
+	count, err := m.rep.DeleteOfflineMessagesBefore(ctx, username, before)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return count, err
+}
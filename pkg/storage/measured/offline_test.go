@@ -17,8 +17,10 @@ package measuredrepository
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/jackal-xmpp/stravaganza"
+	offlinemodel "github.com/ortuman/jackal/pkg/model/offline"
 	"github.com/stretchr/testify/require"
 )
 
@@ -53,6 +55,21 @@ func TestMeasuredOfflineRep_CountOfflineMessage(t *testing.T) {
 	require.Equal(t, 1, c)
 }
 
+func TestMeasuredOfflineRep_CountOfflineBytes(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.CountOfflineBytesFunc = func(ctx context.Context, username string) (int, error) {
+		return 0, nil
+	}
+	m := &measuredOfflineRep{rep: repMock}
+
+	// when
+	_, _ = m.CountOfflineBytes(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.CountOfflineBytesCalls(), 1)
+}
+
 func TestMeasuredOfflineRep_FetchOfflineMessage(t *testing.T) {
 	// given
 	repMock := &repositoryMock{}
@@ -82,3 +99,78 @@ func TestMeasuredOfflineRep_DeleteOfflineMessage(t *testing.T) {
 	// then
 	require.Len(t, repMock.DeleteOfflineMessagesCalls(), 1)
 }
+
+func TestMeasuredOfflineRep_FetchOfflineItems(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchOfflineItemsFunc = func(ctx context.Context, username string) ([]*offlinemodel.Item, error) {
+		return nil, nil
+	}
+	m := &measuredOfflineRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchOfflineItems(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.FetchOfflineItemsCalls(), 1)
+}
+
+func TestMeasuredOfflineRep_FetchOfflineItem(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchOfflineItemFunc = func(ctx context.Context, username, node string) (*offlinemodel.Item, error) {
+		return nil, nil
+	}
+	m := &measuredOfflineRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchOfflineItem(context.Background(), "ortuman", "1")
+
+	// then
+	require.Len(t, repMock.FetchOfflineItemCalls(), 1)
+}
+
+func TestMeasuredOfflineRep_DeleteOfflineItem(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteOfflineItemFunc = func(ctx context.Context, username, node string) error {
+		return nil
+	}
+	m := &measuredOfflineRep{rep: repMock}
+
+	// when
+	_ = m.DeleteOfflineItem(context.Background(), "ortuman", "1")
+
+	// then
+	require.Len(t, repMock.DeleteOfflineItemCalls(), 1)
+}
+
+func TestMeasuredOfflineRep_FetchOfflineUsernames(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchOfflineUsernamesFunc = func(ctx context.Context) ([]string, error) {
+		return nil, nil
+	}
+	m := &measuredOfflineRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchOfflineUsernames(context.Background())
+
+	// then
+	require.Len(t, repMock.FetchOfflineUsernamesCalls(), 1)
+}
+
+func TestMeasuredOfflineRep_DeleteOfflineMessagesBefore(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteOfflineMessagesBeforeFunc = func(ctx context.Context, username string, before time.Time) (int, error) {
+		return 0, nil
+	}
+	m := &measuredOfflineRep{rep: repMock}
+
+	// when
+	_, _ = m.DeleteOfflineMessagesBefore(context.Background(), "ortuman", time.Now())
+
+	// then
+	require.Len(t, repMock.DeleteOfflineMessagesBeforeCalls(), 1)
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"time"
+
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type measuredPubSubRep struct {
+	rep  repository.PubSub
+	inTx bool
+}
+
+func (m *measuredPubSubRep) UpsertNode(ctx context.Context, node *pubsubmodel.Node) error {
+	t0 := time.Now()
+	err := m.rep.UpsertNode(ctx, node)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredPubSubRep) FetchNode(ctx context.Context, host, name string) (*pubsubmodel.Node, error) {
+	t0 := time.Now()
+	node, err := m.rep.FetchNode(ctx, host, name)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return node, err
+}
+
+func (m *measuredPubSubRep) FetchNodes(ctx context.Context, host string) ([]*pubsubmodel.Node, error) {
+	t0 := time.Now()
+	nodes, err := m.rep.FetchNodes(ctx, host)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return nodes, err
+}
+
+func (m *measuredPubSubRep) DeleteNode(ctx context.Context, host, name string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteNode(ctx, host, name)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredPubSubRep) DeleteNodes(ctx context.Context, host string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteNodes(ctx, host)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredPubSubRep) UpsertNodeItem(ctx context.Context, item *pubsubmodel.Item) error {
+	t0 := time.Now()
+	err := m.rep.UpsertNodeItem(ctx, item)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredPubSubRep) FetchNodeItems(ctx context.Context, host, name string) ([]*pubsubmodel.Item, error) {
+	t0 := time.Now()
+	items, err := m.rep.FetchNodeItems(ctx, host, name)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return items, err
+}
+
+func (m *measuredPubSubRep) DeleteNodeItem(ctx context.Context, host, name, id string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteNodeItem(ctx, host, name, id)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredPubSubRep) DeleteNodeItems(ctx context.Context, host, name string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteNodeItems(ctx, host, name)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredPubSubRep) DeleteOldestNodeItems(ctx context.Context, host, name string, maxItems int) error {
+	t0 := time.Now()
+	err := m.rep.DeleteOldestNodeItems(ctx, host, name, maxItems)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredPubSubRep) UpsertNodeSubscription(ctx context.Context, sub *pubsubmodel.Subscription) error {
+	t0 := time.Now()
+	err := m.rep.UpsertNodeSubscription(ctx, sub)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredPubSubRep) FetchNodeSubscriptions(ctx context.Context, host, name string) ([]*pubsubmodel.Subscription, error) {
+	t0 := time.Now()
+	subs, err := m.rep.FetchNodeSubscriptions(ctx, host, name)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return subs, err
+}
+
+func (m *measuredPubSubRep) DeleteNodeSubscription(ctx context.Context, host, name, jid string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteNodeSubscription(ctx, host, name, jid)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"testing"
+
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasuredPubSubRep_UpsertNode(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertNodeFunc = func(ctx context.Context, node *pubsubmodel.Node) error {
+		return nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_ = m.UpsertNode(context.Background(), &pubsubmodel.Node{Host: "pubsub.jackal.im", Name: "news"})
+
+	// then
+	require.Len(t, repMock.UpsertNodeCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_FetchNode(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchNodeFunc = func(ctx context.Context, host, name string) (*pubsubmodel.Node, error) {
+		return nil, nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchNode(context.Background(), "pubsub.jackal.im", "news")
+
+	// then
+	require.Len(t, repMock.FetchNodeCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_FetchNodes(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchNodesFunc = func(ctx context.Context, host string) ([]*pubsubmodel.Node, error) {
+		return nil, nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchNodes(context.Background(), "pubsub.jackal.im")
+
+	// then
+	require.Len(t, repMock.FetchNodesCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_DeleteNode(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteNodeFunc = func(ctx context.Context, host, name string) error {
+		return nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_ = m.DeleteNode(context.Background(), "pubsub.jackal.im", "news")
+
+	// then
+	require.Len(t, repMock.DeleteNodeCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_DeleteNodes(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteNodesFunc = func(ctx context.Context, host string) error {
+		return nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_ = m.DeleteNodes(context.Background(), "ortuman@jackal.im")
+
+	// then
+	require.Len(t, repMock.DeleteNodesCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_UpsertNodeItem(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertNodeItemFunc = func(ctx context.Context, item *pubsubmodel.Item) error {
+		return nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_ = m.UpsertNodeItem(context.Background(), &pubsubmodel.Item{Host: "pubsub.jackal.im", Node: "news", Id: "i1"})
+
+	// then
+	require.Len(t, repMock.UpsertNodeItemCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_FetchNodeItems(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchNodeItemsFunc = func(ctx context.Context, host, name string) ([]*pubsubmodel.Item, error) {
+		return nil, nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchNodeItems(context.Background(), "pubsub.jackal.im", "news")
+
+	// then
+	require.Len(t, repMock.FetchNodeItemsCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_DeleteNodeItem(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteNodeItemFunc = func(ctx context.Context, host, name, id string) error {
+		return nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_ = m.DeleteNodeItem(context.Background(), "pubsub.jackal.im", "news", "i1")
+
+	// then
+	require.Len(t, repMock.DeleteNodeItemCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_DeleteNodeItems(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteNodeItemsFunc = func(ctx context.Context, host, name string) error {
+		return nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_ = m.DeleteNodeItems(context.Background(), "pubsub.jackal.im", "news")
+
+	// then
+	require.Len(t, repMock.DeleteNodeItemsCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_DeleteOldestNodeItems(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteOldestNodeItemsFunc = func(ctx context.Context, host, name string, maxItems int) error {
+		return nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_ = m.DeleteOldestNodeItems(context.Background(), "pubsub.jackal.im", "news", 10)
+
+	// then
+	require.Len(t, repMock.DeleteOldestNodeItemsCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_UpsertNodeSubscription(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertNodeSubscriptionFunc = func(ctx context.Context, sub *pubsubmodel.Subscription) error {
+		return nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_ = m.UpsertNodeSubscription(context.Background(), &pubsubmodel.Subscription{Host: "pubsub.jackal.im", Node: "news", Jid: "ortuman@jackal.im"})
+
+	// then
+	require.Len(t, repMock.UpsertNodeSubscriptionCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_FetchNodeSubscriptions(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchNodeSubscriptionsFunc = func(ctx context.Context, host, name string) ([]*pubsubmodel.Subscription, error) {
+		return nil, nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchNodeSubscriptions(context.Background(), "pubsub.jackal.im", "news")
+
+	// then
+	require.Len(t, repMock.FetchNodeSubscriptionsCalls(), 1)
+}
+
+func TestMeasuredPubSubRep_DeleteNodeSubscription(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteNodeSubscriptionFunc = func(ctx context.Context, host, name, jid string) error {
+		return nil
+	}
+	m := &measuredPubSubRep{rep: repMock}
+
+	// when
+	_ = m.DeleteNodeSubscription(context.Background(), "pubsub.jackal.im", "news", "ortuman@jackal.im")
+
+	// then
+	require.Len(t, repMock.DeleteNodeSubscriptionCalls(), 1)
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"time"
+
+	pushmodel "github.com/ortuman/jackal/pkg/model/push"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type measuredPushRep struct {
+	rep  repository.Push
+	inTx bool
+}
+
+func (m *measuredPushRep) UpsertPushRegistration(ctx context.Context, reg *pushmodel.Registration) (err error) {
+	t0 := time.Now()
+	err = m.rep.UpsertPushRegistration(ctx, reg)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return
+}
+
+func (m *measuredPushRep) DeletePushRegistration(ctx context.Context, username, jid, node string) (err error) {
+	t0 := time.Now()
+	err = m.rep.DeletePushRegistration(ctx, username, jid, node)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return
+}
+
+func (m *measuredPushRep) FetchPushRegistrations(ctx context.Context, username string) (regs []*pushmodel.Registration, err error) {
+	t0 := time.Now()
+	regs, err = m.rep.FetchPushRegistrations(ctx, username)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return
+}
+
+func (m *measuredPushRep) DeletePushRegistrations(ctx context.Context, username string) (err error) {
+	t0 := time.Now()
+	err = m.rep.DeletePushRegistrations(ctx, username)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return
+}
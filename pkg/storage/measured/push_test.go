@@ -0,0 +1,83 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"testing"
+
+	pushmodel "github.com/ortuman/jackal/pkg/model/push"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasuredPushRep_UpsertPushRegistration(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertPushRegistrationFunc = func(ctx context.Context, reg *pushmodel.Registration) error {
+		return nil
+	}
+	m := New(repMock)
+
+	// when
+	_ = m.UpsertPushRegistration(context.Background(), &pushmodel.Registration{})
+
+	// then
+	require.Len(t, repMock.UpsertPushRegistrationCalls(), 1)
+}
+
+func TestMeasuredPushRep_FetchPushRegistrations(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchPushRegistrationsFunc = func(ctx context.Context, username string) ([]*pushmodel.Registration, error) {
+		return nil, nil
+	}
+	m := New(repMock)
+
+	// when
+	_, _ = m.FetchPushRegistrations(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.FetchPushRegistrationsCalls(), 1)
+}
+
+func TestMeasuredPushRep_DeletePushRegistration(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeletePushRegistrationFunc = func(ctx context.Context, username, jid, node string) error {
+		return nil
+	}
+	m := New(repMock)
+
+	// when
+	_ = m.DeletePushRegistration(context.Background(), "ortuman", "push.jackal.im", "n1")
+
+	// then
+	require.Len(t, repMock.DeletePushRegistrationCalls(), 1)
+}
+
+func TestMeasuredPushRep_DeletePushRegistrations(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeletePushRegistrationsFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+	m := New(repMock)
+
+	// when
+	_ = m.DeletePushRegistrations(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.DeletePushRegistrationsCalls(), 1)
+}
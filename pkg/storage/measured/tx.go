@@ -18,6 +18,11 @@ import "github.com/ortuman/jackal/pkg/storage/repository"
 
 type measuredTx struct {
 	repository.User
+	repository.FastToken
+	repository.Invite
+	repository.Muc
+	repository.PubSub
+	repository.Push
 	repository.Last
 	repository.Capabilities
 	repository.Offline
@@ -32,6 +37,11 @@ type measuredTx struct {
 func newMeasuredTx(tx repository.Transaction) *measuredTx {
 	return &measuredTx{
 		User:         &measuredUserRep{rep: tx, inTx: true},
+		FastToken:    &measuredFastTokenRep{rep: tx, inTx: true},
+		Invite:       &measuredInviteRep{rep: tx, inTx: true},
+		Muc:          &measuredMucRep{rep: tx, inTx: true},
+		PubSub:       &measuredPubSubRep{rep: tx, inTx: true},
+		Push:         &measuredPushRep{rep: tx, inTx: true},
 		Last:         &measuredLastRep{rep: tx, inTx: true},
 		Capabilities: &measuredCapabilitiesRep{rep: tx, inTx: true},
 		Offline:      &measuredOfflineRep{rep: tx, inTx: true},
@@ -26,12 +26,14 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/lib/pq"
 	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
-	archiveTableName = "archives"
+	archiveTableName      = "archives"
+	archivePrefsTableName = "archive_preferences"
 
 	archiveStampFormat = "2006-01-02T15:04:05Z"
 )
@@ -51,7 +53,7 @@ func (r *pgSQLArchiveRep) InsertArchiveMessage(ctx context.Context, message *arc
 
 	q := sq.Insert(archiveTableName).
 		Prefix(noLoadBalancePrefix).
-		Columns("archive_id", "id", `"from"`, "from_bare", `"to"`, "to_bare", "message").
+		Columns("archive_id", "id", `"from"`, "from_bare", `"to"`, "to_bare", "sender", "message", "body").
 		Values(
 			message.ArchiveId,
 			message.Id,
@@ -59,7 +61,9 @@ func (r *pgSQLArchiveRep) InsertArchiveMessage(ctx context.Context, message *arc
 			fromJID.ToBareJID().String(),
 			toJID.String(),
 			toJID.ToBareJID().String(),
+			message.SenderJid,
 			b,
+			message.Body(),
 		)
 
 	_, err = q.RunWith(r.conn).ExecContext(ctx)
@@ -100,7 +104,7 @@ func (r *pgSQLArchiveRep) FetchArchiveMetadata(ctx context.Context, archiveID st
 }
 
 func (r *pgSQLArchiveRep) FetchArchiveMessages(ctx context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error) {
-	q := sq.Select("id", `"from"`, `"to"`, "message", "created_at").
+	q := sq.Select("id", `"from"`, `"to"`, "sender", "message", "created_at").
 		From(archiveTableName).
 		Where(filtersToPred(f, archiveID)).
 		OrderBy("created_at").
@@ -119,6 +123,23 @@ func (r *pgSQLArchiveRep) FetchArchiveMessages(ctx context.Context, f *archivemo
 	return retVal, err
 }
 
+func (r *pgSQLArchiveRep) UpdateArchiveMessage(ctx context.Context, message *archivemodel.Message) error {
+	b, err := proto.Marshal(message.Message)
+	if err != nil {
+		return err
+	}
+	q := sq.Update(archiveTableName).
+		Prefix(noLoadBalancePrefix).
+		Set("message", b).
+		Set("body", message.Body()).
+		Where(sq.And{
+			sq.Eq{"archive_id": message.ArchiveId},
+			sq.Eq{"id": message.Id},
+		})
+	_, err = q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
 func (r *pgSQLArchiveRep) DeleteArchiveOldestMessages(ctx context.Context, archiveID string, maxElements int) error {
 	q := sq.Delete(archiveTableName).
 		Prefix(noLoadBalancePrefix).
@@ -138,6 +159,81 @@ func (r *pgSQLArchiveRep) DeleteArchive(ctx context.Context, archiveID string) e
 	return err
 }
 
+func (r *pgSQLArchiveRep) FetchArchiveIDs(ctx context.Context) ([]string, error) {
+	q := sq.Select("DISTINCT archive_id").
+		From(archiveTableName)
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []string
+	for rows.Next() {
+		var archiveID string
+		if err := rows.Scan(&archiveID); err != nil {
+			return nil, err
+		}
+		retVal = append(retVal, archiveID)
+	}
+	return retVal, nil
+}
+
+func (r *pgSQLArchiveRep) DeleteArchiveMessagesBefore(ctx context.Context, archiveID string, before time.Time) (int, error) {
+	q := sq.Delete(archiveTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{
+			sq.Eq{"archive_id": archiveID},
+			sq.Lt{"created_at": before},
+		})
+	res, err := q.RunWith(r.conn).ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (r *pgSQLArchiveRep) UpsertArchivePreferences(ctx context.Context, prefs *archivemodel.Preferences) error {
+	_, err := sq.Insert(archivePrefsTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("username", "default_behavior", "always", "never").
+		Values(prefs.Username, prefs.DefaultBehavior, pq.Array(prefs.Always), pq.Array(prefs.Never)).
+		Suffix("ON CONFLICT (username) DO UPDATE SET default_behavior = $2, always = $3, never = $4").
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLArchiveRep) FetchArchivePreferences(ctx context.Context, username string) (*archivemodel.Preferences, error) {
+	row := sq.Select("username", "default_behavior", "always", "never").
+		From(archivePrefsTableName).
+		Where(sq.Eq{"username": username}).
+		RunWith(r.conn).QueryRowContext(ctx)
+
+	var prefs archivemodel.Preferences
+	err := row.Scan(&prefs.Username, &prefs.DefaultBehavior, pq.Array(&prefs.Always), pq.Array(&prefs.Never))
+	switch err {
+	case nil:
+		return &prefs, nil
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *pgSQLArchiveRep) DeleteArchivePreferences(ctx context.Context, username string) error {
+	q := sq.Delete(archivePrefsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Eq{"username": username})
+	_, err := q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
 func filtersToPred(f *archivemodel.Filters, archiveID string) (interface{}, error) {
 	pred := sq.And{
 		sq.Eq{"archive_id": archiveID},
@@ -169,6 +265,11 @@ func filtersToPred(f *archivemodel.Filters, archiveID string) (interface{}, erro
 		}
 	}
 
+	// filtering by body content
+	if len(f.Fulltext) > 0 {
+		pred = append(pred, sq.Expr("body_tsv @@ plainto_tsquery('simple', ?)", f.Fulltext))
+	}
+
 	// filtering by timestamp
 	if f.Start != nil {
 		// due to higher precision of database timestamp we need to add an extra offset to discard first message.
@@ -201,7 +302,7 @@ func scanArchiveMessage(scanner rowsScanner, archiveID string) (*archivemodel.Me
 	var b []byte
 	var tm time.Time
 
-	if err := scanner.Scan(&ret.Id, &ret.FromJid, &ret.ToJid, &b, &tm); err != nil {
+	if err := scanner.Scan(&ret.Id, &ret.FromJid, &ret.ToJid, &ret.SenderJid, &b, &tm); err != nil {
 		return nil, err
 	}
 	sb, err := stravaganza.NewBuilderFromBinary(b)
@@ -23,6 +23,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/golang/protobuf/proto"
 	"github.com/jackal-xmpp/stravaganza"
+	"github.com/lib/pq"
 	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -50,8 +51,8 @@ func TestPgSQLArchive_InsertArchiveMessage(t *testing.T) {
 	msgBytes, _ := proto.Marshal(aMsg.Message)
 
 	s, mock := newArchiveMock()
-	mock.ExpectExec(`INSERT INTO archives \(archive_id,id,"from",from_bare,"to",to_bare,message\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\)`).
-		WithArgs("ortuman", "id1234", "ortuman@jackal.im/local", "ortuman@jackal.im", "ortuman@jabber.org/remote", "ortuman@jabber.org", msgBytes).
+	mock.ExpectExec(`INSERT INTO archives \(archive_id,id,"from",from_bare,"to",to_bare,sender,message,body\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9\)`).
+		WithArgs("ortuman", "id1234", "ortuman@jackal.im/local", "ortuman@jackal.im", "ortuman@jabber.org/remote", "ortuman@jabber.org", "", msgBytes, "I'll give thee a wind.").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// when
@@ -101,47 +102,52 @@ func TestPgSQLArchive_FetchArchiveMessages(t *testing.T) {
 		"by bare jid": {
 			filters:     &archivemodel.Filters{With: "noelia@jackal.im"},
 			withArgs:    []driver.Value{"ortuman", "noelia@jackal.im", "noelia@jackal.im"},
-			expectQuery: `SELECT id, "from", "to", message, created_at FROM archives WHERE \(archive_id = \$1 AND \(to_bare = \$2 OR from_bare = \$3\)\) ORDER BY created_at`,
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND \(to_bare = \$2 OR from_bare = \$3\)\) ORDER BY created_at`,
 		},
 		"by full jid": {
 			filters:     &archivemodel.Filters{With: "noelia@jackal.im/yard"},
 			withArgs:    []driver.Value{"ortuman", "noelia@jackal.im/yard", "noelia@jackal.im/yard"},
-			expectQuery: `SELECT id, "from", "to", message, created_at FROM archives WHERE \(archive_id = \$1 AND \("to" = \$2 OR "from" = \$3\)\) ORDER BY created_at`,
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND \("to" = \$2 OR "from" = \$3\)\) ORDER BY created_at`,
 		},
 		"by ids": {
 			filters:     &archivemodel.Filters{Ids: []string{"id1234", "id5678"}},
 			withArgs:    []driver.Value{"ortuman", "id1234", "id5678"},
-			expectQuery: `SELECT id, "from", "to", message, created_at FROM archives WHERE \(archive_id = \$1 AND id IN \(\$2,\$3\)\) ORDER BY created_at`,
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND id IN \(\$2,\$3\)\) ORDER BY created_at`,
 		},
 		"by before id": {
 			filters:     &archivemodel.Filters{BeforeId: "id1234"},
 			withArgs:    []driver.Value{"ortuman", "id1234", "ortuman"},
-			expectQuery: `SELECT id, "from", "to", message, created_at FROM archives WHERE \(archive_id = \$1 AND \(serial < \(SELECT serial FROM archives WHERE "id" = \$2 AND archive_id = \$3\)\)\) ORDER BY created_at`,
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND \(serial < \(SELECT serial FROM archives WHERE "id" = \$2 AND archive_id = \$3\)\)\) ORDER BY created_at`,
 		},
 		"by after id": {
 			filters:     &archivemodel.Filters{AfterId: "id1234"},
 			withArgs:    []driver.Value{"ortuman", "id1234", "ortuman"},
-			expectQuery: `SELECT id, "from", "to", message, created_at FROM archives WHERE \(archive_id = \$1 AND \(serial > \(SELECT serial FROM archives WHERE "id" = \$2 AND archive_id = \$3\)\)\) ORDER BY created_at`,
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND \(serial > \(SELECT serial FROM archives WHERE "id" = \$2 AND archive_id = \$3\)\)\) ORDER BY created_at`,
 		},
 		"by before and after id": {
 			filters:     &archivemodel.Filters{BeforeId: "id1234", AfterId: "id5678"},
 			withArgs:    []driver.Value{"ortuman", "id1234", "ortuman", "id5678", "ortuman"},
-			expectQuery: `SELECT id, "from", "to", message, created_at FROM archives WHERE \(archive_id = \$1 AND \(serial < \(SELECT serial FROM archives WHERE "id" = \$2 AND archive_id = \$3\)\) AND \(serial > \(SELECT serial FROM archives WHERE "id" = \$4 AND archive_id = \$5\)\)\) ORDER BY created_at`,
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND \(serial < \(SELECT serial FROM archives WHERE "id" = \$2 AND archive_id = \$3\)\) AND \(serial > \(SELECT serial FROM archives WHERE "id" = \$4 AND archive_id = \$5\)\)\) ORDER BY created_at`,
+		},
+		"by fulltext": {
+			filters:     &archivemodel.Filters{Fulltext: "balcony moon"},
+			withArgs:    []driver.Value{"ortuman", "balcony moon"},
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND body_tsv @@ plainto_tsquery\('simple', \$2\)\) ORDER BY created_at`,
 		},
 		"by start timestamp": {
 			filters:     &archivemodel.Filters{Start: timestamppb.New(starTm)},
 			withArgs:    []driver.Value{"ortuman", toEpoch(timestamppb.New(starTm)) + float64(time.Millisecond)},
-			expectQuery: `SELECT id, "from", "to", message, created_at FROM archives WHERE \(archive_id = \$1 AND EXTRACT\(epoch FROM created_at\) > \$2\) ORDER BY created_at`,
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND EXTRACT\(epoch FROM created_at\) > \$2\) ORDER BY created_at`,
 		},
 		"by end timestamp": {
 			filters:     &archivemodel.Filters{End: timestamppb.New(endTm)},
 			withArgs:    []driver.Value{"ortuman", toEpoch(timestamppb.New(endTm))},
-			expectQuery: `SELECT id, "from", "to", message, created_at FROM archives WHERE \(archive_id = \$1 AND EXTRACT\(epoch FROM created_at\) < \$2\) ORDER BY created_at`,
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND EXTRACT\(epoch FROM created_at\) < \$2\) ORDER BY created_at`,
 		},
 		"by start and end timestamp": {
 			filters:     &archivemodel.Filters{Start: timestamppb.New(starTm), End: timestamppb.New(endTm)},
 			withArgs:    []driver.Value{"ortuman", toEpoch(timestamppb.New(starTm)) + float64(time.Millisecond), toEpoch(timestamppb.New(endTm))},
-			expectQuery: `SELECT id, "from", "to", message, created_at FROM archives WHERE \(archive_id = \$1 AND EXTRACT\(epoch FROM created_at\) > \$2 AND EXTRACT\(epoch FROM created_at\) < \$3\) ORDER BY created_at`,
+			expectQuery: `SELECT id, "from", "to", sender, message, created_at FROM archives WHERE \(archive_id = \$1 AND EXTRACT\(epoch FROM created_at\) > \$2 AND EXTRACT\(epoch FROM created_at\) < \$3\) ORDER BY created_at`,
 		},
 	}
 	for tn, tc := range tcs {
@@ -159,8 +165,8 @@ func TestPgSQLArchive_FetchArchiveMessages(t *testing.T) {
 			msgBytes, _ := msg.MarshalBinary()
 			tmNow := time.Date(2022, time.July, 6, 14, 7, 43, 167051000, time.UTC)
 
-			rows := sqlmock.NewRows([]string{"id", "from", "to", "message", "created_at"}).
-				AddRow("id1234", "ortuman@jackal.im", "noelia@jackal.im", msgBytes, tmNow)
+			rows := sqlmock.NewRows([]string{"id", "from", "to", "sender", "message", "created_at"}).
+				AddRow("id1234", "ortuman@jackal.im", "noelia@jackal.im", "", msgBytes, tmNow)
 
 			s, mock := newArchiveMock()
 			mock.ExpectQuery(tc.expectQuery).
@@ -196,6 +202,33 @@ func TestPgSQLArchive_DeleteArchiveOldestMessages(t *testing.T) {
 	require.Nil(t, mock.ExpectationsWereMet())
 }
 
+func TestPgSQLArchive_UpdateArchiveMessage(t *testing.T) {
+	// given
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute("from", "noelia@jackal.im/yard").
+		WithAttribute("to", "ortuman@jackal.im/balcony").
+		BuildMessage()
+
+	aMsg := &archivemodel.Message{
+		ArchiveId: "ortuman@jackal.im",
+		Id:        "id1234",
+		Message:   msg.Proto(),
+	}
+	msgBytes, _ := proto.Marshal(aMsg.Message)
+
+	s, mock := newArchiveMock()
+	mock.ExpectExec(`UPDATE archives SET message = \$1, body = \$2 WHERE \(archive_id = \$3 AND id = \$4\)`).
+		WithArgs(msgBytes, "", "ortuman@jackal.im", "id1234").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpdateArchiveMessage(context.Background(), aMsg)
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
 func TestPgSQLArchive_DeleteArchive(t *testing.T) {
 	// given
 	s, mock := newArchiveMock()
@@ -211,6 +244,100 @@ func TestPgSQLArchive_DeleteArchive(t *testing.T) {
 	require.Nil(t, mock.ExpectationsWereMet())
 }
 
+func TestPgSQLArchive_FetchArchiveIDs(t *testing.T) {
+	// given
+	s, mock := newArchiveMock()
+	mock.ExpectQuery(`SELECT DISTINCT archive_id FROM archives`).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"archive_id"}).
+				AddRow("ortuman@jackal.im").
+				AddRow("noelia@jackal.im"),
+		)
+
+	// when
+	archiveIDs, err := s.FetchArchiveIDs(context.Background())
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Equal(t, []string{"ortuman@jackal.im", "noelia@jackal.im"}, archiveIDs)
+}
+
+func TestPgSQLArchive_DeleteArchiveMessagesBefore(t *testing.T) {
+	// given
+	before := time.Date(2022, 01, 01, 00, 00, 00, 00, time.UTC)
+
+	s, mock := newArchiveMock()
+	mock.ExpectExec(`DELETE FROM archives WHERE \(archive_id = \$1 AND created_at < \$2\)`).
+		WithArgs("ortuman@jackal.im", before).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+
+	// when
+	count, err := s.DeleteArchiveMessagesBefore(context.Background(), "ortuman@jackal.im", before)
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Equal(t, 5, count)
+}
+
+func TestPgSQLArchive_UpsertArchivePreferences(t *testing.T) {
+	// given
+	prefs := &archivemodel.Preferences{
+		Username:        "ortuman",
+		DefaultBehavior: "roster",
+		Always:          []string{"noelia@jackal.im"},
+		Never:           []string{"romeo@jackal.im"},
+	}
+	s, mock := newArchiveMock()
+	mock.ExpectExec(`INSERT INTO archive_preferences \(username,default_behavior,always,never\) VALUES \(\$1,\$2,\$3,\$4\) ON CONFLICT \(username\) DO UPDATE SET default_behavior = \$2, always = \$3, never = \$4`).
+		WithArgs(prefs.Username, prefs.DefaultBehavior, pq.Array(prefs.Always), pq.Array(prefs.Never)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// when
+	err := s.UpsertArchivePreferences(context.Background(), prefs)
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLArchive_FetchArchivePreferences(t *testing.T) {
+	// given
+	s, mock := newArchiveMock()
+	mock.ExpectQuery(`SELECT username, default_behavior, always, never FROM archive_preferences WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnRows(sqlmock.NewRows([]string{"username", "default_behavior", "always", "never"}).
+			AddRow("ortuman", "roster", pq.Array([]string{"noelia@jackal.im"}), pq.Array([]string{})),
+		)
+
+	// when
+	prefs, err := s.FetchArchivePreferences(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, err)
+	require.NotNil(t, prefs)
+	require.Equal(t, "roster", prefs.DefaultBehavior)
+	require.Equal(t, []string{"noelia@jackal.im"}, prefs.Always)
+
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLArchive_DeleteArchivePreferences(t *testing.T) {
+	// given
+	s, mock := newArchiveMock()
+	mock.ExpectExec(`DELETE FROM archive_preferences WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// when
+	err := s.DeleteArchivePreferences(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
 func newArchiveMock() (*pgSQLArchiveRep, sqlmock.Sqlmock) {
 	s, sqlMock := newPgSQLMock()
 	return &pgSQLArchiveRep{conn: s}, sqlMock
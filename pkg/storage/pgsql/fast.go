@@ -0,0 +1,111 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const fastTokensTableName = "fast_tokens"
+
+type pgSQLFastTokenRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *pgSQLFastTokenRep) UpsertFastToken(ctx context.Context, token *fastmodel.Token) error {
+	var prevExpiresAt sql.NullTime
+	if token.PreviousExpiresAt != nil {
+		prevExpiresAt = sql.NullTime{Time: token.PreviousExpiresAt.AsTime(), Valid: true}
+	}
+	_, err := sq.Insert(fastTokensTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("username", "client_id", "mechanism", "token", "expires_at", "previous_token", "previous_expires_at").
+		Values(
+			token.Username,
+			token.ClientId,
+			token.Mechanism,
+			token.Token,
+			token.ExpiresAt.AsTime(),
+			token.PreviousToken,
+			prevExpiresAt,
+		).
+		Suffix("ON CONFLICT (username, client_id) DO UPDATE SET mechanism = $3, token = $4, expires_at = $5, previous_token = $6, previous_expires_at = $7").
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLFastTokenRep) FetchFastTokens(ctx context.Context, username string) ([]*fastmodel.Token, error) {
+	q := sq.Select("username", "client_id", "mechanism", "token", "expires_at", "previous_token", "previous_expires_at").
+		From(fastTokensTableName).
+		Where(sq.Eq{"username": username}).
+		OrderBy("created_at")
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*fastmodel.Token
+	for rows.Next() {
+		var token fastmodel.Token
+		var expiresAt time.Time
+		var prevExpiresAt sql.NullTime
+
+		if err := rows.Scan(
+			&token.Username,
+			&token.ClientId,
+			&token.Mechanism,
+			&token.Token,
+			&expiresAt,
+			&token.PreviousToken,
+			&prevExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		token.ExpiresAt = timestamppb.New(expiresAt)
+		if prevExpiresAt.Valid {
+			token.PreviousExpiresAt = timestamppb.New(prevExpiresAt.Time)
+		}
+		retVal = append(retVal, &token)
+	}
+	return retVal, rows.Err()
+}
+
+func (r *pgSQLFastTokenRep) DeleteFastToken(ctx context.Context, username, clientID string) error {
+	_, err := sq.Delete(fastTokensTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{sq.Eq{"username": username}, sq.Eq{"client_id": clientID}}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLFastTokenRep) DeleteFastTokens(ctx context.Context, username string) error {
+	_, err := sq.Delete(fastTokensTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Eq{"username": username}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
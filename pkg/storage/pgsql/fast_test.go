@@ -0,0 +1,118 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestPgSQLFastToken_Upsert(t *testing.T) {
+	// given
+	expiresAt := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	s, mock := newFastTokenMock()
+	mock.ExpectExec(`INSERT INTO fast_tokens \(username,client_id,mechanism,token,expires_at,previous_token,previous_expires_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\) ON CONFLICT \(username, client_id\) DO UPDATE SET mechanism = \$3, token = \$4, expires_at = \$5, previous_token = \$6, previous_expires_at = \$7`).
+		WithArgs("ortuman", "d4565fa7", "HT-SHA-256-NONE", "s3cr3t", expiresAt, "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpsertFastToken(context.Background(), &fastmodel.Token{
+		Username:  "ortuman",
+		ClientId:  "d4565fa7",
+		Mechanism: "HT-SHA-256-NONE",
+		Token:     "s3cr3t",
+		ExpiresAt: timestamppb.New(expiresAt),
+	})
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLFastToken_Fetch(t *testing.T) {
+	// given
+	var fastTokenColumns = []string{"username", "client_id", "mechanism", "token", "expires_at", "previous_token", "previous_expires_at"}
+
+	expiresAt := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	prevExpiresAt := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	s, mock := newFastTokenMock()
+	mock.ExpectQuery(`SELECT username, client_id, mechanism, token, expires_at, previous_token, previous_expires_at FROM fast_tokens WHERE username = \$1 ORDER BY created_at`).
+		WithArgs("ortuman").
+		WillReturnRows(
+			sqlmock.NewRows(fastTokenColumns).
+				AddRow("ortuman", "d4565fa7", "HT-SHA-256-NONE", "s3cr3t", expiresAt, "0ld", prevExpiresAt).
+				AddRow("ortuman", "a1f3b0c2", "HT-SHA-256-UNIQ", "t0k3n", expiresAt, "", nil),
+		)
+
+	// when
+	tokens, err := s.FetchFastTokens(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.Len(t, tokens, 2)
+
+	require.Equal(t, "d4565fa7", tokens[0].ClientId)
+	require.Equal(t, "s3cr3t", tokens[0].Token)
+	require.Equal(t, expiresAt, tokens[0].ExpiresAt.AsTime())
+	require.Equal(t, "0ld", tokens[0].PreviousToken)
+	require.Equal(t, prevExpiresAt, tokens[0].PreviousExpiresAt.AsTime())
+
+	require.Equal(t, "HT-SHA-256-UNIQ", tokens[1].Mechanism)
+	require.Nil(t, tokens[1].PreviousExpiresAt)
+}
+
+func TestPgSQLFastToken_Delete(t *testing.T) {
+	// given
+	s, mock := newFastTokenMock()
+	mock.ExpectExec(`DELETE FROM fast_tokens WHERE \(username = \$1 AND client_id = \$2\)`).
+		WithArgs("ortuman", "d4565fa7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteFastToken(context.Background(), "ortuman", "d4565fa7")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLFastToken_DeleteAll(t *testing.T) {
+	// given
+	s, mock := newFastTokenMock()
+	mock.ExpectExec(`DELETE FROM fast_tokens WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteFastTokens(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func newFastTokenMock() (*pgSQLFastTokenRep, sqlmock.Sqlmock) {
+	s, sqlMock := newPgSQLMock()
+	return &pgSQLFastTokenRep{conn: s}, sqlMock
+}
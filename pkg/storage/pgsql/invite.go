@@ -0,0 +1,90 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const invitesTableName = "invites"
+
+type pgSQLInviteRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *pgSQLInviteRep) UpsertInvite(ctx context.Context, inv *invitemodel.Invite) error {
+	_, err := sq.Insert(invitesTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("token", "domain", "inviter", "max_uses", "uses", "expires_at").
+		Values(
+			inv.Token,
+			inv.Domain,
+			inv.Inviter,
+			inv.MaxUses,
+			inv.Uses,
+			inv.ExpiresAt.AsTime(),
+		).
+		Suffix("ON CONFLICT (token) DO UPDATE SET domain = $2, inviter = $3, max_uses = $4, uses = $5, expires_at = $6").
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLInviteRep) FetchInvite(ctx context.Context, token string) (*invitemodel.Invite, error) {
+	var inv invitemodel.Invite
+	var expiresAt time.Time
+
+	err := sq.Select("token", "domain", "inviter", "max_uses", "uses", "expires_at").
+		From(invitesTableName).
+		Where(sq.Eq{"token": token}).
+		RunWith(r.conn).
+		QueryRowContext(ctx).
+		Scan(&inv.Token, &inv.Domain, &inv.Inviter, &inv.MaxUses, &inv.Uses, &expiresAt)
+
+	switch err {
+	case nil:
+		inv.ExpiresAt = timestamppb.New(expiresAt)
+		return &inv, nil
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *pgSQLInviteRep) DeleteInvite(ctx context.Context, token string) error {
+	_, err := sq.Delete(invitesTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Eq{"token": token}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLInviteRep) DeleteInvites(ctx context.Context, inviter string) error {
+	_, err := sq.Delete(invitesTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Eq{"inviter": inviter}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestPgSQLInvite_Upsert(t *testing.T) {
+	// given
+	expiresAt := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	s, mock := newInviteMock()
+	mock.ExpectExec(`INSERT INTO invites \(token,domain,inviter,max_uses,uses,expires_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\) ON CONFLICT \(token\) DO UPDATE SET domain = \$2, inviter = \$3, max_uses = \$4, uses = \$5, expires_at = \$6`).
+		WithArgs("t0k3n", "jackal.im", "ortuman", int32(2), int32(1), expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpsertInvite(context.Background(), &invitemodel.Invite{
+		Token:     "t0k3n",
+		Domain:    "jackal.im",
+		Inviter:   "ortuman",
+		MaxUses:   2,
+		Uses:      1,
+		ExpiresAt: timestamppb.New(expiresAt),
+	})
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLInvite_Fetch(t *testing.T) {
+	// given
+	var inviteColumns = []string{"token", "domain", "inviter", "max_uses", "uses", "expires_at"}
+
+	expiresAt := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	s, mock := newInviteMock()
+	mock.ExpectQuery(`SELECT token, domain, inviter, max_uses, uses, expires_at FROM invites WHERE token = \$1`).
+		WithArgs("t0k3n").
+		WillReturnRows(
+			sqlmock.NewRows(inviteColumns).
+				AddRow("t0k3n", "jackal.im", "ortuman", 2, 1, expiresAt),
+		)
+
+	// when
+	inv, err := s.FetchInvite(context.Background(), "t0k3n")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.NotNil(t, inv)
+
+	require.Equal(t, "jackal.im", inv.Domain)
+	require.Equal(t, "ortuman", inv.Inviter)
+	require.Equal(t, int32(2), inv.MaxUses)
+	require.Equal(t, int32(1), inv.Uses)
+	require.Equal(t, expiresAt, inv.ExpiresAt.AsTime())
+}
+
+func TestPgSQLInvite_FetchNotFound(t *testing.T) {
+	// given
+	var inviteColumns = []string{"token", "domain", "inviter", "max_uses", "uses", "expires_at"}
+
+	s, mock := newInviteMock()
+	mock.ExpectQuery(`SELECT token, domain, inviter, max_uses, uses, expires_at FROM invites WHERE token = \$1`).
+		WithArgs("t0k3n").
+		WillReturnRows(sqlmock.NewRows(inviteColumns))
+
+	// when
+	inv, err := s.FetchInvite(context.Background(), "t0k3n")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.Nil(t, inv)
+}
+
+func TestPgSQLInvite_Delete(t *testing.T) {
+	// given
+	s, mock := newInviteMock()
+	mock.ExpectExec(`DELETE FROM invites WHERE token = \$1`).
+		WithArgs("t0k3n").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteInvite(context.Background(), "t0k3n")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLInvite_DeleteAll(t *testing.T) {
+	// given
+	s, mock := newInviteMock()
+	mock.ExpectExec(`DELETE FROM invites WHERE inviter = \$1`).
+		WithArgs("ortuman").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteInvites(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func newInviteMock() (*pgSQLInviteRep, sqlmock.Sqlmock) {
+	s, sqlMock := newPgSQLMock()
+	return &pgSQLInviteRep{conn: s}, sqlMock
+}
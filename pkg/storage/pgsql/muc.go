@@ -0,0 +1,170 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+)
+
+const (
+	mucRoomsTableName        = "muc_rooms"
+	mucAffiliationsTableName = "muc_affiliations"
+)
+
+type pgSQLMucRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *pgSQLMucRep) UpsertRoom(ctx context.Context, room *mucmodel.Room) error {
+	b, err := proto.Marshal(room.Config)
+	if err != nil {
+		return err
+	}
+	roomJID, err := jid.NewWithString(room.Jid, true)
+	if err != nil {
+		return err
+	}
+	_, err = sq.Insert(mucRoomsTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("jid", "host", "config", "subject").
+		Values(room.Jid, roomJID.Domain(), b, room.Subject).
+		Suffix("ON CONFLICT (jid) DO UPDATE SET config = $3, subject = $4").
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLMucRep) FetchRoom(ctx context.Context, roomJID string) (*mucmodel.Room, error) {
+	var room mucmodel.Room
+	var b []byte
+
+	err := sq.Select("jid", "config", "subject").
+		From(mucRoomsTableName).
+		Where(sq.Eq{"jid": roomJID}).
+		RunWith(r.conn).
+		QueryRowContext(ctx).
+		Scan(&room.Jid, &b, &room.Subject)
+
+	switch err {
+	case nil:
+		var cfg mucmodel.RoomConfig
+		if err := proto.Unmarshal(b, &cfg); err != nil {
+			return nil, err
+		}
+		room.Config = &cfg
+		return &room, nil
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *pgSQLMucRep) FetchRooms(ctx context.Context, host string) ([]*mucmodel.Room, error) {
+	rows, err := sq.Select("jid", "config", "subject").
+		From(mucRoomsTableName).
+		Where(sq.Eq{"host": host}).
+		OrderBy("jid").
+		RunWith(r.conn).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*mucmodel.Room
+	for rows.Next() {
+		var room mucmodel.Room
+		var b []byte
+
+		if err := rows.Scan(&room.Jid, &b, &room.Subject); err != nil {
+			return nil, err
+		}
+		var cfg mucmodel.RoomConfig
+		if err := proto.Unmarshal(b, &cfg); err != nil {
+			return nil, err
+		}
+		room.Config = &cfg
+		retVal = append(retVal, &room)
+	}
+	return retVal, nil
+}
+
+func (r *pgSQLMucRep) DeleteRoom(ctx context.Context, roomJID string) error {
+	_, err := sq.Delete(mucRoomsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Eq{"jid": roomJID}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLMucRep) UpsertRoomAffiliation(ctx context.Context, aff *mucmodel.Affiliation) error {
+	_, err := sq.Insert(mucAffiliationsTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("room_jid", "jid", "affiliation", "reason").
+		Values(aff.RoomJid, aff.Jid, aff.Affiliation, aff.Reason).
+		Suffix("ON CONFLICT (room_jid, jid) DO UPDATE SET affiliation = $3, reason = $4").
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLMucRep) FetchRoomAffiliations(ctx context.Context, roomJID string) ([]*mucmodel.Affiliation, error) {
+	rows, err := sq.Select("room_jid", "jid", "affiliation", "reason").
+		From(mucAffiliationsTableName).
+		Where(sq.Eq{"room_jid": roomJID}).
+		OrderBy("jid").
+		RunWith(r.conn).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*mucmodel.Affiliation
+	for rows.Next() {
+		var aff mucmodel.Affiliation
+		if err := rows.Scan(&aff.RoomJid, &aff.Jid, &aff.Affiliation, &aff.Reason); err != nil {
+			return nil, err
+		}
+		retVal = append(retVal, &aff)
+	}
+	return retVal, nil
+}
+
+func (r *pgSQLMucRep) DeleteRoomAffiliation(ctx context.Context, roomJID, jid string) error {
+	_, err := sq.Delete(mucAffiliationsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{sq.Eq{"room_jid": roomJID}, sq.Eq{"jid": jid}}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLMucRep) DeleteRoomAffiliations(ctx context.Context, roomJID string) error {
+	_, err := sq.Delete(mucAffiliationsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Eq{"room_jid": roomJID}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
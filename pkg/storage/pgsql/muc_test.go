@@ -0,0 +1,191 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/protobuf/proto"
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgSQLMuc_UpsertRoom(t *testing.T) {
+	// given
+	cfg := &mucmodel.RoomConfig{Name: "Lobby", Persistent: true}
+	b, _ := proto.Marshal(cfg)
+
+	s, mock := newMucMock()
+	mock.ExpectExec(`INSERT INTO muc_rooms \(jid,host,config,subject\) VALUES \(\$1,\$2,\$3,\$4\) ON CONFLICT \(jid\) DO UPDATE SET config = \$3, subject = \$4`).
+		WithArgs("lobby@conference.jackal.im", "conference.jackal.im", b, "Welcome").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpsertRoom(context.Background(), &mucmodel.Room{
+		Jid:     "lobby@conference.jackal.im",
+		Config:  cfg,
+		Subject: "Welcome",
+	})
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLMuc_FetchRoom(t *testing.T) {
+	// given
+	var roomColumns = []string{"jid", "config", "subject"}
+
+	b, _ := proto.Marshal(&mucmodel.RoomConfig{Name: "Lobby", Persistent: true})
+
+	s, mock := newMucMock()
+	mock.ExpectQuery(`SELECT jid, config, subject FROM muc_rooms WHERE jid = \$1`).
+		WithArgs("lobby@conference.jackal.im").
+		WillReturnRows(
+			sqlmock.NewRows(roomColumns).
+				AddRow("lobby@conference.jackal.im", b, "Welcome"),
+		)
+
+	// when
+	room, err := s.FetchRoom(context.Background(), "lobby@conference.jackal.im")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.NotNil(t, room)
+
+	require.Equal(t, "Lobby", room.Config.Name)
+	require.True(t, room.Config.Persistent)
+	require.Equal(t, "Welcome", room.Subject)
+}
+
+func TestPgSQLMuc_FetchRooms(t *testing.T) {
+	// given
+	var roomColumns = []string{"jid", "config", "subject"}
+
+	b, _ := proto.Marshal(&mucmodel.RoomConfig{Name: "Lobby"})
+
+	s, mock := newMucMock()
+	mock.ExpectQuery(`SELECT jid, config, subject FROM muc_rooms WHERE host = \$1 ORDER BY jid`).
+		WithArgs("conference.jackal.im").
+		WillReturnRows(
+			sqlmock.NewRows(roomColumns).
+				AddRow("dev@conference.jackal.im", b, "").
+				AddRow("lobby@conference.jackal.im", b, ""),
+		)
+
+	// when
+	rooms, err := s.FetchRooms(context.Background(), "conference.jackal.im")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.Len(t, rooms, 2)
+}
+
+func TestPgSQLMuc_DeleteRoom(t *testing.T) {
+	// given
+	s, mock := newMucMock()
+	mock.ExpectExec(`DELETE FROM muc_rooms WHERE jid = \$1`).
+		WithArgs("lobby@conference.jackal.im").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteRoom(context.Background(), "lobby@conference.jackal.im")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLMuc_UpsertRoomAffiliation(t *testing.T) {
+	// given
+	s, mock := newMucMock()
+	mock.ExpectExec(`INSERT INTO muc_affiliations \(room_jid,jid,affiliation,reason\) VALUES \(\$1,\$2,\$3,\$4\) ON CONFLICT \(room_jid, jid\) DO UPDATE SET affiliation = \$3, reason = \$4`).
+		WithArgs("lobby@conference.jackal.im", "ortuman@jackal.im", "owner", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpsertRoomAffiliation(context.Background(), &mucmodel.Affiliation{
+		RoomJid:     "lobby@conference.jackal.im",
+		Jid:         "ortuman@jackal.im",
+		Affiliation: "owner",
+	})
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLMuc_FetchRoomAffiliations(t *testing.T) {
+	// given
+	var affColumns = []string{"room_jid", "jid", "affiliation", "reason"}
+
+	s, mock := newMucMock()
+	mock.ExpectQuery(`SELECT room_jid, jid, affiliation, reason FROM muc_affiliations WHERE room_jid = \$1 ORDER BY jid`).
+		WithArgs("lobby@conference.jackal.im").
+		WillReturnRows(
+			sqlmock.NewRows(affColumns).
+				AddRow("lobby@conference.jackal.im", "noelia@jackal.im", "outcast", "spam").
+				AddRow("lobby@conference.jackal.im", "ortuman@jackal.im", "owner", ""),
+		)
+
+	// when
+	affs, err := s.FetchRoomAffiliations(context.Background(), "lobby@conference.jackal.im")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.Len(t, affs, 2)
+	require.Equal(t, "outcast", affs[0].Affiliation)
+	require.Equal(t, "spam", affs[0].Reason)
+}
+
+func TestPgSQLMuc_DeleteRoomAffiliation(t *testing.T) {
+	// given
+	s, mock := newMucMock()
+	mock.ExpectExec(`DELETE FROM muc_affiliations WHERE \(room_jid = \$1 AND jid = \$2\)`).
+		WithArgs("lobby@conference.jackal.im", "noelia@jackal.im").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteRoomAffiliation(context.Background(), "lobby@conference.jackal.im", "noelia@jackal.im")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLMuc_DeleteRoomAffiliations(t *testing.T) {
+	// given
+	s, mock := newMucMock()
+	mock.ExpectExec(`DELETE FROM muc_affiliations WHERE room_jid = \$1`).
+		WithArgs("lobby@conference.jackal.im").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteRoomAffiliations(context.Background(), "lobby@conference.jackal.im")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func newMucMock() (*pgSQLMucRep, sqlmock.Sqlmock) {
+	s, sqlMock := newPgSQLMock()
+	return &pgSQLMucRep{conn: s}, sqlMock
+}
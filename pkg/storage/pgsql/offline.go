@@ -16,10 +16,14 @@ package pgsqlrepository
 
 import (
 	"context"
+	"database/sql"
+	"strconv"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	kitlog "github.com/go-kit/log"
 	"github.com/jackal-xmpp/stravaganza"
+	offlinemodel "github.com/ortuman/jackal/pkg/model/offline"
 )
 
 const offlineMessagesTableName = "offline_messages"
@@ -56,6 +60,19 @@ func (r *pgSQLOfflineRep) CountOfflineMessages(ctx context.Context, username str
 	return count, nil
 }
 
+func (r *pgSQLOfflineRep) CountOfflineBytes(ctx context.Context, username string) (int, error) {
+	var size int
+
+	q := sq.Select("COALESCE(SUM(OCTET_LENGTH(message)), 0)").
+		From(offlineMessagesTableName).
+		Where(sq.Eq{"username": username})
+
+	if err := q.RunWith(r.conn).QueryRowContext(ctx).Scan(&size); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
 func (r *pgSQLOfflineRep) FetchOfflineMessages(ctx context.Context, username string) ([]*stravaganza.Message, error) {
 	q := sq.Select("message").
 		From(offlineMessagesTableName).
@@ -74,11 +91,7 @@ func (r *pgSQLOfflineRep) FetchOfflineMessages(ctx context.Context, username str
 		if err := rows.Scan(&b); err != nil {
 			return nil, err
 		}
-		sb, err := stravaganza.NewBuilderFromBinary(b)
-		if err != nil {
-			return nil, err
-		}
-		msg, err := sb.BuildMessage()
+		msg, err := unmarshalOfflineMessage(b)
 		if err != nil {
 			return nil, err
 		}
@@ -94,3 +107,119 @@ func (r *pgSQLOfflineRep) DeleteOfflineMessages(ctx context.Context, username st
 	_, err := q.RunWith(r.conn).ExecContext(ctx)
 	return err
 }
+
+func (r *pgSQLOfflineRep) FetchOfflineItems(ctx context.Context, username string) ([]*offlinemodel.Item, error) {
+	q := sq.Select("id", "message").
+		From(offlineMessagesTableName).
+		Where(sq.Eq{"username": username}).
+		OrderBy("id")
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var items []*offlinemodel.Item
+	for rows.Next() {
+		var id int64
+		var b []byte
+		if err := rows.Scan(&id, &b); err != nil {
+			return nil, err
+		}
+		msg, err := unmarshalOfflineMessage(b)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &offlinemodel.Item{
+			Node:    strconv.FormatInt(id, 10),
+			Message: msg,
+		})
+	}
+	return items, nil
+}
+
+func (r *pgSQLOfflineRep) FetchOfflineItem(ctx context.Context, username, node string) (*offlinemodel.Item, error) {
+	id, err := strconv.ParseInt(node, 10, 64)
+	if err != nil {
+		return nil, nil // not a valid node identifier
+	}
+	row := sq.Select("message").
+		From(offlineMessagesTableName).
+		Where(sq.And{sq.Eq{"username": username}, sq.Eq{"id": id}}).
+		RunWith(r.conn).QueryRowContext(ctx)
+
+	var b []byte
+	err = row.Scan(&b)
+	switch err {
+	case nil:
+		msg, err := unmarshalOfflineMessage(b)
+		if err != nil {
+			return nil, err
+		}
+		return &offlinemodel.Item{Node: node, Message: msg}, nil
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *pgSQLOfflineRep) DeleteOfflineItem(ctx context.Context, username, node string) error {
+	id, err := strconv.ParseInt(node, 10, 64)
+	if err != nil {
+		return nil // not a valid node identifier
+	}
+	q := sq.Delete(offlineMessagesTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{sq.Eq{"username": username}, sq.Eq{"id": id}})
+	_, err = q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLOfflineRep) FetchOfflineUsernames(ctx context.Context) ([]string, error) {
+	q := sq.Select("DISTINCT username").
+		From(offlineMessagesTableName)
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var usernames []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		usernames = append(usernames, username)
+	}
+	return usernames, nil
+}
+
+func (r *pgSQLOfflineRep) DeleteOfflineMessagesBefore(ctx context.Context, username string, before time.Time) (int, error) {
+	q := sq.Delete(offlineMessagesTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{
+			sq.Eq{"username": username},
+			sq.Lt{"created_at": before},
+		})
+	res, err := q.RunWith(r.conn).ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func unmarshalOfflineMessage(b []byte) (*stravaganza.Message, error) {
+	sb, err := stravaganza.NewBuilderFromBinary(b)
+	if err != nil {
+		return nil, err
+	}
+	return sb.BuildMessage()
+}
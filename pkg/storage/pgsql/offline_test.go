@@ -17,6 +17,7 @@ package pgsqlrepository
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jackal-xmpp/stravaganza"
@@ -69,6 +70,25 @@ func TestPgSQLOffline_CountOfflineMessage(t *testing.T) {
 	require.Nil(t, mock.ExpectationsWereMet())
 }
 
+func TestPgSQLOffline_CountOfflineBytes(t *testing.T) {
+	// given
+	s, mock := newOfflineMock()
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(OCTET_LENGTH\(message\)\), 0\) FROM offline_messages WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"size"}).AddRow(1024),
+		)
+
+	// when
+	size, err := s.CountOfflineBytes(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 1024, size)
+
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
 func TestPgSQLOffline_FetchOfflineMessage(t *testing.T) {
 	// given
 	b := stravaganza.NewMessageBuilder()
@@ -119,3 +139,116 @@ func newOfflineMock() (*pgSQLOfflineRep, sqlmock.Sqlmock) {
 	s, sqlMock := newPgSQLMock()
 	return &pgSQLOfflineRep{conn: s}, sqlMock
 }
+
+func TestPgSQLOffline_FetchOfflineUsernames(t *testing.T) {
+	// given
+	s, mock := newOfflineMock()
+	mock.ExpectQuery(`SELECT DISTINCT username FROM offline_messages`).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"username"}).AddRow("ortuman").AddRow("noelia"),
+		)
+
+	// when
+	usernames, err := s.FetchOfflineUsernames(context.Background())
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Equal(t, []string{"ortuman", "noelia"}, usernames)
+}
+
+func TestPgSQLOffline_DeleteOfflineMessagesBefore(t *testing.T) {
+	// given
+	before := time.Date(2022, 01, 01, 00, 00, 00, 00, time.UTC)
+
+	s, mock := newOfflineMock()
+	mock.ExpectExec(`DELETE FROM offline_messages WHERE \(username = \$1 AND created_at < \$2\)`).
+		WithArgs("ortuman", before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	// when
+	count, err := s.DeleteOfflineMessagesBefore(context.Background(), "ortuman", before)
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Equal(t, 3, count)
+}
+
+func TestPgSQLOffline_FetchOfflineItems(t *testing.T) {
+	// given
+	msg := testOfflineMessage()
+	msgBytes, _ := msg.MarshalBinary()
+
+	s, mock := newOfflineMock()
+	mock.ExpectQuery(`SELECT id, message FROM offline_messages WHERE username = \$1 ORDER BY id`).
+		WithArgs("ortuman").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "message"}).AddRow(7, msgBytes).AddRow(9, msgBytes),
+		)
+
+	// when
+	items, err := s.FetchOfflineItems(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Len(t, items, 2)
+	require.Equal(t, "7", items[0].Node)
+	require.Equal(t, "9", items[1].Node)
+}
+
+func TestPgSQLOffline_FetchOfflineItem(t *testing.T) {
+	// given
+	msg := testOfflineMessage()
+	msgBytes, _ := msg.MarshalBinary()
+
+	s, mock := newOfflineMock()
+	mock.ExpectQuery(`SELECT message FROM offline_messages WHERE \(username = \$1 AND id = \$2\)`).
+		WithArgs("ortuman", int64(7)).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"message"}).AddRow(msgBytes),
+		)
+
+	// when
+	item, err := s.FetchOfflineItem(context.Background(), "ortuman", "7")
+	invalidItem, invalidErr := s.FetchOfflineItem(context.Background(), "ortuman", "foo")
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.NotNil(t, item)
+	require.Equal(t, "7", item.Node)
+	require.Equal(t, "noelia@jackal.im/yard", item.Message.FromJID().String())
+
+	require.Nil(t, invalidErr)
+	require.Nil(t, invalidItem)
+}
+
+func TestPgSQLOffline_DeleteOfflineItem(t *testing.T) {
+	// given
+	s, mock := newOfflineMock()
+	mock.ExpectExec(`DELETE FROM offline_messages WHERE \(username = \$1 AND id = \$2\)`).
+		WithArgs("ortuman", int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteOfflineItem(context.Background(), "ortuman", "7")
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func testOfflineMessage() *stravaganza.Message {
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "noelia@jackal.im/yard")
+	b.WithAttribute("to", "ortuman@jackal.im/balcony")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	msg, _ := b.BuildMessage()
+	return msg
+}
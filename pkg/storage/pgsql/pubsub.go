@@ -0,0 +1,242 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/jackal-xmpp/stravaganza"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	pubSubNodesTableName         = "pubsub_nodes"
+	pubSubItemsTableName         = "pubsub_items"
+	pubSubSubscriptionsTableName = "pubsub_subscriptions"
+)
+
+type pgSQLPubSubRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *pgSQLPubSubRep) UpsertNode(ctx context.Context, node *pubsubmodel.Node) error {
+	b, err := proto.Marshal(node)
+	if err != nil {
+		return err
+	}
+	_, err = sq.Insert(pubSubNodesTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("host", "name", "node").
+		Values(node.Host, node.Name, b).
+		Suffix("ON CONFLICT (host, name) DO UPDATE SET node = $3").
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPubSubRep) FetchNode(ctx context.Context, host, name string) (*pubsubmodel.Node, error) {
+	var b []byte
+
+	err := sq.Select("node").
+		From(pubSubNodesTableName).
+		Where(sq.And{sq.Eq{"host": host}, sq.Eq{"name": name}}).
+		RunWith(r.conn).
+		QueryRowContext(ctx).
+		Scan(&b)
+
+	switch err {
+	case nil:
+		var node pubsubmodel.Node
+		if err := proto.Unmarshal(b, &node); err != nil {
+			return nil, err
+		}
+		return &node, nil
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *pgSQLPubSubRep) FetchNodes(ctx context.Context, host string) ([]*pubsubmodel.Node, error) {
+	rows, err := sq.Select("node").
+		From(pubSubNodesTableName).
+		Where(sq.Eq{"host": host}).
+		OrderBy("name").
+		RunWith(r.conn).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*pubsubmodel.Node
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var node pubsubmodel.Node
+		if err := proto.Unmarshal(b, &node); err != nil {
+			return nil, err
+		}
+		retVal = append(retVal, &node)
+	}
+	return retVal, nil
+}
+
+func (r *pgSQLPubSubRep) DeleteNode(ctx context.Context, host, name string) error {
+	_, err := sq.Delete(pubSubNodesTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{sq.Eq{"host": host}, sq.Eq{"name": name}}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPubSubRep) DeleteNodes(ctx context.Context, host string) error {
+	_, err := sq.Delete(pubSubNodesTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Eq{"host": host}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPubSubRep) UpsertNodeItem(ctx context.Context, item *pubsubmodel.Item) error {
+	b, err := proto.Marshal(item.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = sq.Insert(pubSubItemsTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("host", "node", "item_id", "publisher", "payload").
+		Values(item.Host, item.Node, item.Id, item.Publisher, b).
+		Suffix("ON CONFLICT (host, node, item_id) DO UPDATE SET publisher = $4, payload = $5").
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPubSubRep) FetchNodeItems(ctx context.Context, host, name string) ([]*pubsubmodel.Item, error) {
+	rows, err := sq.Select("host", "node", "item_id", "publisher", "payload", "updated_at").
+		From(pubSubItemsTableName).
+		Where(sq.And{sq.Eq{"host": host}, sq.Eq{"node": name}}).
+		OrderBy("updated_at").
+		RunWith(r.conn).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*pubsubmodel.Item
+	for rows.Next() {
+		var item pubsubmodel.Item
+		var b []byte
+		var stamp time.Time
+
+		if err := rows.Scan(&item.Host, &item.Node, &item.Id, &item.Publisher, &b, &stamp); err != nil {
+			return nil, err
+		}
+		var payload stravaganza.PBElement
+		if err := proto.Unmarshal(b, &payload); err != nil {
+			return nil, err
+		}
+		item.Payload = &payload
+		item.Stamp = timestamppb.New(stamp)
+
+		retVal = append(retVal, &item)
+	}
+	return retVal, nil
+}
+
+func (r *pgSQLPubSubRep) DeleteNodeItem(ctx context.Context, host, name, id string) error {
+	_, err := sq.Delete(pubSubItemsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{sq.Eq{"host": host}, sq.Eq{"node": name}, sq.Eq{"item_id": id}}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPubSubRep) DeleteNodeItems(ctx context.Context, host, name string) error {
+	_, err := sq.Delete(pubSubItemsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{sq.Eq{"host": host}, sq.Eq{"node": name}}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPubSubRep) DeleteOldestNodeItems(ctx context.Context, host, name string, maxItems int) error {
+	_, err := sq.Delete(pubSubItemsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{
+			sq.Eq{"host": host},
+			sq.Eq{"node": name},
+			sq.Expr(`item_id NOT IN (SELECT item_id FROM pubsub_items WHERE host = $3 AND node = $4 ORDER BY updated_at DESC LIMIT $5 OFFSET 0)`, host, name, maxItems),
+		}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPubSubRep) UpsertNodeSubscription(ctx context.Context, sub *pubsubmodel.Subscription) error {
+	_, err := sq.Insert(pubSubSubscriptionsTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("host", "node", "jid", "subid", "state").
+		Values(sub.Host, sub.Node, sub.Jid, sub.Subid, sub.State).
+		Suffix("ON CONFLICT (host, node, jid) DO UPDATE SET subid = $4, state = $5").
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPubSubRep) FetchNodeSubscriptions(ctx context.Context, host, name string) ([]*pubsubmodel.Subscription, error) {
+	rows, err := sq.Select("host", "node", "jid", "subid", "state").
+		From(pubSubSubscriptionsTableName).
+		Where(sq.And{sq.Eq{"host": host}, sq.Eq{"node": name}}).
+		OrderBy("jid").
+		RunWith(r.conn).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*pubsubmodel.Subscription
+	for rows.Next() {
+		var sub pubsubmodel.Subscription
+		if err := rows.Scan(&sub.Host, &sub.Node, &sub.Jid, &sub.Subid, &sub.State); err != nil {
+			return nil, err
+		}
+		retVal = append(retVal, &sub)
+	}
+	return retVal, nil
+}
+
+func (r *pgSQLPubSubRep) DeleteNodeSubscription(ctx context.Context, host, name, jid string) error {
+	_, err := sq.Delete(pubSubSubscriptionsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{sq.Eq{"host": host}, sq.Eq{"node": name}, sq.Eq{"jid": jid}}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
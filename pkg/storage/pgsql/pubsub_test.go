@@ -0,0 +1,282 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/protobuf/proto"
+	"github.com/jackal-xmpp/stravaganza"
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgSQLPubSub_UpsertNode(t *testing.T) {
+	// given
+	node := &pubsubmodel.Node{
+		Host:    "ortuman@jackal.im",
+		Name:    "urn:xmpp:avatar:metadata",
+		Options: &pubsubmodel.Options{AccessModel: "presence"},
+	}
+	b, _ := proto.Marshal(node)
+
+	s, mock := newPubSubMock()
+	mock.ExpectExec(`INSERT INTO pubsub_nodes \(host,name,node\) VALUES \(\$1,\$2,\$3\) ON CONFLICT \(host, name\) DO UPDATE SET node = \$3`).
+		WithArgs("ortuman@jackal.im", "urn:xmpp:avatar:metadata", b).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpsertNode(context.Background(), node)
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPubSub_FetchNode(t *testing.T) {
+	// given
+	b, _ := proto.Marshal(&pubsubmodel.Node{
+		Host:    "ortuman@jackal.im",
+		Name:    "urn:xmpp:avatar:metadata",
+		Options: &pubsubmodel.Options{AccessModel: "presence"},
+	})
+
+	s, mock := newPubSubMock()
+	mock.ExpectQuery(`SELECT node FROM pubsub_nodes WHERE \(host = \$1 AND name = \$2\)`).
+		WithArgs("ortuman@jackal.im", "urn:xmpp:avatar:metadata").
+		WillReturnRows(sqlmock.NewRows([]string{"node"}).AddRow(b))
+
+	// when
+	node, err := s.FetchNode(context.Background(), "ortuman@jackal.im", "urn:xmpp:avatar:metadata")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.NotNil(t, node)
+	require.Equal(t, "presence", node.Options.AccessModel)
+}
+
+func TestPgSQLPubSub_FetchNodes(t *testing.T) {
+	// given
+	b0, _ := proto.Marshal(&pubsubmodel.Node{Host: "pubsub.jackal.im", Name: "news"})
+	b1, _ := proto.Marshal(&pubsubmodel.Node{Host: "pubsub.jackal.im", Name: "sports"})
+
+	s, mock := newPubSubMock()
+	mock.ExpectQuery(`SELECT node FROM pubsub_nodes WHERE host = \$1 ORDER BY name`).
+		WithArgs("pubsub.jackal.im").
+		WillReturnRows(sqlmock.NewRows([]string{"node"}).AddRow(b0).AddRow(b1))
+
+	// when
+	nodes, err := s.FetchNodes(context.Background(), "pubsub.jackal.im")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.Len(t, nodes, 2)
+	require.Equal(t, "sports", nodes[1].Name)
+}
+
+func TestPgSQLPubSub_DeleteNode(t *testing.T) {
+	// given
+	s, mock := newPubSubMock()
+	mock.ExpectExec(`DELETE FROM pubsub_nodes WHERE \(host = \$1 AND name = \$2\)`).
+		WithArgs("pubsub.jackal.im", "news").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteNode(context.Background(), "pubsub.jackal.im", "news")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPubSub_DeleteNodes(t *testing.T) {
+	// given
+	s, mock := newPubSubMock()
+	mock.ExpectExec(`DELETE FROM pubsub_nodes WHERE host = \$1`).
+		WithArgs("ortuman@jackal.im").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteNodes(context.Background(), "ortuman@jackal.im")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPubSub_UpsertNodeItem(t *testing.T) {
+	// given
+	payload := stravaganza.NewBuilder("entry").WithText("Hello").Build()
+	b, _ := proto.Marshal(payload.Proto())
+
+	s, mock := newPubSubMock()
+	mock.ExpectExec(`INSERT INTO pubsub_items \(host,node,item_id,publisher,payload\) VALUES \(\$1,\$2,\$3,\$4,\$5\) ON CONFLICT \(host, node, item_id\) DO UPDATE SET publisher = \$4, payload = \$5`).
+		WithArgs("pubsub.jackal.im", "news", "i1", "ortuman@jackal.im", b).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpsertNodeItem(context.Background(), &pubsubmodel.Item{
+		Host:      "pubsub.jackal.im",
+		Node:      "news",
+		Id:        "i1",
+		Publisher: "ortuman@jackal.im",
+		Payload:   payload.Proto(),
+	})
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPubSub_FetchNodeItems(t *testing.T) {
+	// given
+	var itemColumns = []string{"host", "node", "item_id", "publisher", "payload", "updated_at"}
+
+	b, _ := proto.Marshal(stravaganza.NewBuilder("entry").WithText("Hello").Build().Proto())
+	now := time.Now()
+
+	s, mock := newPubSubMock()
+	mock.ExpectQuery(`SELECT host, node, item_id, publisher, payload, updated_at FROM pubsub_items WHERE \(host = \$1 AND node = \$2\) ORDER BY updated_at`).
+		WithArgs("pubsub.jackal.im", "news").
+		WillReturnRows(
+			sqlmock.NewRows(itemColumns).
+				AddRow("pubsub.jackal.im", "news", "i1", "ortuman@jackal.im", b, now),
+		)
+
+	// when
+	items, err := s.FetchNodeItems(context.Background(), "pubsub.jackal.im", "news")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, "i1", items[0].Id)
+	require.Equal(t, "Hello", items[0].Payload.Text)
+	require.True(t, items[0].Stamp.AsTime().Equal(now))
+}
+
+func TestPgSQLPubSub_DeleteNodeItem(t *testing.T) {
+	// given
+	s, mock := newPubSubMock()
+	mock.ExpectExec(`DELETE FROM pubsub_items WHERE \(host = \$1 AND node = \$2 AND item_id = \$3\)`).
+		WithArgs("pubsub.jackal.im", "news", "i1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteNodeItem(context.Background(), "pubsub.jackal.im", "news", "i1")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPubSub_DeleteNodeItems(t *testing.T) {
+	// given
+	s, mock := newPubSubMock()
+	mock.ExpectExec(`DELETE FROM pubsub_items WHERE \(host = \$1 AND node = \$2\)`).
+		WithArgs("pubsub.jackal.im", "news").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteNodeItems(context.Background(), "pubsub.jackal.im", "news")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPubSub_DeleteOldestNodeItems(t *testing.T) {
+	// given
+	s, mock := newPubSubMock()
+	mock.ExpectExec(`DELETE FROM pubsub_items WHERE \(host = \$1 AND node = \$2 AND item_id NOT IN \(SELECT item_id FROM pubsub_items WHERE host = \$3 AND node = \$4 ORDER BY updated_at DESC LIMIT \$5 OFFSET 0\)\)`).
+		WithArgs("pubsub.jackal.im", "news", "pubsub.jackal.im", "news", 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteOldestNodeItems(context.Background(), "pubsub.jackal.im", "news", 10)
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPubSub_UpsertNodeSubscription(t *testing.T) {
+	// given
+	s, mock := newPubSubMock()
+	mock.ExpectExec(`INSERT INTO pubsub_subscriptions \(host,node,jid,subid,state\) VALUES \(\$1,\$2,\$3,\$4,\$5\) ON CONFLICT \(host, node, jid\) DO UPDATE SET subid = \$4, state = \$5`).
+		WithArgs("pubsub.jackal.im", "news", "noelia@jackal.im", "s1", "subscribed").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpsertNodeSubscription(context.Background(), &pubsubmodel.Subscription{
+		Host:  "pubsub.jackal.im",
+		Node:  "news",
+		Jid:   "noelia@jackal.im",
+		Subid: "s1",
+		State: "subscribed",
+	})
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPubSub_FetchNodeSubscriptions(t *testing.T) {
+	// given
+	var subColumns = []string{"host", "node", "jid", "subid", "state"}
+
+	s, mock := newPubSubMock()
+	mock.ExpectQuery(`SELECT host, node, jid, subid, state FROM pubsub_subscriptions WHERE \(host = \$1 AND node = \$2\) ORDER BY jid`).
+		WithArgs("pubsub.jackal.im", "news").
+		WillReturnRows(
+			sqlmock.NewRows(subColumns).
+				AddRow("pubsub.jackal.im", "news", "noelia@jackal.im", "s1", "subscribed").
+				AddRow("pubsub.jackal.im", "news", "ortuman@jackal.im", "s2", "subscribed"),
+		)
+
+	// when
+	subs, err := s.FetchNodeSubscriptions(context.Background(), "pubsub.jackal.im", "news")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.Len(t, subs, 2)
+	require.Equal(t, "s2", subs[1].Subid)
+}
+
+func TestPgSQLPubSub_DeleteNodeSubscription(t *testing.T) {
+	// given
+	s, mock := newPubSubMock()
+	mock.ExpectExec(`DELETE FROM pubsub_subscriptions WHERE \(host = \$1 AND node = \$2 AND jid = \$3\)`).
+		WithArgs("pubsub.jackal.im", "news", "noelia@jackal.im").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteNodeSubscription(context.Background(), "pubsub.jackal.im", "news", "noelia@jackal.im")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func newPubSubMock() (*pgSQLPubSubRep, sqlmock.Sqlmock) {
+	s, sqlMock := newPgSQLMock()
+	return &pgSQLPubSubRep{conn: s}, sqlMock
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/jackal-xmpp/stravaganza"
+	pushmodel "github.com/ortuman/jackal/pkg/model/push"
+)
+
+const (
+	pushRegistrationsTableName = "push_registrations"
+)
+
+type pgSQLPushRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *pgSQLPushRep) UpsertPushRegistration(ctx context.Context, reg *pushmodel.Registration) error {
+	var opts []byte
+	if reg.PublishOptions != nil {
+		b, err := proto.Marshal(reg.PublishOptions)
+		if err != nil {
+			return err
+		}
+		opts = b
+	}
+	_, err := sq.Insert(pushRegistrationsTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("username", "resource", "jid", "node", "publish_options").
+		Values(reg.Username, reg.Resource, reg.Jid, reg.Node, opts).
+		Suffix("ON CONFLICT (username, jid, node) DO UPDATE SET resource = $2, publish_options = $5").
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPushRep) DeletePushRegistration(ctx context.Context, username, jid, node string) error {
+	_, err := sq.Delete(pushRegistrationsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.And{sq.Eq{"username": username}, sq.Eq{"jid": jid}, sq.Eq{"node": node}}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLPushRep) FetchPushRegistrations(ctx context.Context, username string) ([]*pushmodel.Registration, error) {
+	rows, err := sq.Select("username", "resource", "jid", "node", "publish_options").
+		From(pushRegistrationsTableName).
+		Where(sq.Eq{"username": username}).
+		OrderBy("created_at").
+		RunWith(r.conn).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*pushmodel.Registration
+	for rows.Next() {
+		var reg pushmodel.Registration
+		var opts []byte
+
+		if err := rows.Scan(&reg.Username, &reg.Resource, &reg.Jid, &reg.Node, &opts); err != nil {
+			return nil, err
+		}
+		if len(opts) > 0 {
+			var pbElem stravaganza.PBElement
+			if err := proto.Unmarshal(opts, &pbElem); err != nil {
+				return nil, err
+			}
+			reg.PublishOptions = &pbElem
+		}
+		retVal = append(retVal, &reg)
+	}
+	return retVal, nil
+}
+
+func (r *pgSQLPushRep) DeletePushRegistrations(ctx context.Context, username string) error {
+	_, err := sq.Delete(pushRegistrationsTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Eq{"username": username}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
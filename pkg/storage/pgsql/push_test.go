@@ -0,0 +1,100 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pushmodel "github.com/ortuman/jackal/pkg/model/push"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgSQLPush_Upsert(t *testing.T) {
+	// given
+	s, mock := newPushMock()
+	mock.ExpectExec(`INSERT INTO push_registrations \(username,resource,jid,node,publish_options\) VALUES \(\$1,\$2,\$3,\$4,\$5\) ON CONFLICT \(username, jid, node\) DO UPDATE SET resource = \$2, publish_options = \$5`).
+		WithArgs("ortuman", "yard", "push.jackal.im", "n1", []byte(nil)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpsertPushRegistration(context.Background(), &pushmodel.Registration{
+		Username: "ortuman",
+		Resource: "yard",
+		Jid:      "push.jackal.im",
+		Node:     "n1",
+	})
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPush_Fetch(t *testing.T) {
+	// given
+	var pushColumns = []string{"username", "resource", "jid", "node", "publish_options"}
+	s, mock := newPushMock()
+	mock.ExpectQuery(`SELECT username, resource, jid, node, publish_options FROM push_registrations WHERE username = \$1 ORDER BY created_at`).
+		WithArgs("ortuman").
+		WillReturnRows(
+			sqlmock.NewRows(pushColumns).AddRow("ortuman", "yard", "push.jackal.im", "n1", nil),
+		)
+
+	// when
+	regs, err := s.FetchPushRegistrations(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.Len(t, regs, 1)
+	require.Equal(t, "n1", regs[0].Node)
+	require.Nil(t, regs[0].PublishOptions)
+}
+
+func TestPgSQLPush_Delete(t *testing.T) {
+	// given
+	s, mock := newPushMock()
+	mock.ExpectExec(`DELETE FROM push_registrations WHERE \(username = \$1 AND jid = \$2 AND node = \$3\)`).
+		WithArgs("ortuman", "push.jackal.im", "n1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeletePushRegistration(context.Background(), "ortuman", "push.jackal.im", "n1")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLPush_DeleteAll(t *testing.T) {
+	// given
+	s, mock := newPushMock()
+	mock.ExpectExec(`DELETE FROM push_registrations WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeletePushRegistrations(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func newPushMock() (*pgSQLPushRep, sqlmock.Sqlmock) {
+	s, sqlMock := newPgSQLMock()
+	return &pgSQLPushRep{conn: s}, sqlMock
+}
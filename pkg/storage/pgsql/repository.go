@@ -50,6 +50,11 @@ type Config struct {
 // Repository represents a PgSQL repository implementation.
 type Repository struct {
 	repository.User
+	repository.FastToken
+	repository.Invite
+	repository.Muc
+	repository.PubSub
+	repository.Push
 	repository.Last
 	repository.Capabilities
 	repository.Offline
@@ -114,6 +119,11 @@ func (r *Repository) Start(ctx context.Context) error {
 	level.Info(r.logger).Log("msg", "dialed PgSQL connection", "host", r.host)
 
 	r.User = &pgSQLUserRep{conn: db, logger: r.logger}
+	r.FastToken = &pgSQLFastTokenRep{conn: db, logger: r.logger}
+	r.Invite = &pgSQLInviteRep{conn: db, logger: r.logger}
+	r.Muc = &pgSQLMucRep{conn: db, logger: r.logger}
+	r.PubSub = &pgSQLPubSubRep{conn: db, logger: r.logger}
+	r.Push = &pgSQLPushRep{conn: db, logger: r.logger}
 	r.Last = &pgSQLLastRep{conn: db, logger: r.logger}
 	r.Capabilities = &pgSQLCapabilitiesRep{conn: db, logger: r.logger}
 	r.Offline = &pgSQLOfflineRep{conn: db, logger: r.logger}
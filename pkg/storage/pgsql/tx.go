@@ -22,6 +22,11 @@ import (
 
 type repTx struct {
 	repository.User
+	repository.FastToken
+	repository.Invite
+	repository.Muc
+	repository.PubSub
+	repository.Push
 	repository.Last
 	repository.Capabilities
 	repository.Offline
@@ -36,6 +41,11 @@ type repTx struct {
 func newRepTx(tx *sql.Tx) *repTx {
 	return &repTx{
 		User:         &pgSQLUserRep{conn: tx},
+		FastToken:    &pgSQLFastTokenRep{conn: tx},
+		Invite:       &pgSQLInviteRep{conn: tx},
+		Muc:          &pgSQLMucRep{conn: tx},
+		PubSub:       &pgSQLPubSubRep{conn: tx},
+		Push:         &pgSQLPushRep{conn: tx},
 		Last:         &pgSQLLastRep{conn: tx},
 		Capabilities: &pgSQLCapabilitiesRep{conn: tx},
 		Offline:      &pgSQLOfflineRep{conn: tx},
@@ -16,6 +16,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	archivemodel "github.com/ortuman/jackal/pkg/model/archive"
 )
@@ -31,9 +32,28 @@ type Archive interface {
 	// FetchArchiveMessages fetches archive asscociated messages applying the passed f filters.
 	FetchArchiveMessages(ctx context.Context, f *archivemodel.Filters, archiveID string) ([]*archivemodel.Message, error)
 
+	// UpdateArchiveMessage replaces the content of an already archived message.
+	UpdateArchiveMessage(ctx context.Context, message *archivemodel.Message) error
+
 	// DeleteArchiveOldestMessages trims archive oldest messages up to a maxElements total count.
 	DeleteArchiveOldestMessages(ctx context.Context, archiveID string, maxElements int) error
 
 	// DeleteArchive clears an archive queue.
 	DeleteArchive(ctx context.Context, archiveID string) error
+
+	// FetchArchiveIDs returns the identifiers of all non-empty archives.
+	FetchArchiveIDs(ctx context.Context) ([]string, error)
+
+	// DeleteArchiveMessagesBefore deletes archive messages stored before a given time
+	// and returns the number of deleted messages.
+	DeleteArchiveMessagesBefore(ctx context.Context, archiveID string, before time.Time) (int, error)
+
+	// UpsertArchivePreferences inserts or updates user archiving preferences.
+	UpsertArchivePreferences(ctx context.Context, prefs *archivemodel.Preferences) error
+
+	// FetchArchivePreferences retrieves user archiving preferences.
+	FetchArchivePreferences(ctx context.Context, username string) (*archivemodel.Preferences, error)
+
+	// DeleteArchivePreferences deletes user archiving preferences.
+	DeleteArchivePreferences(ctx context.Context, username string) error
 }
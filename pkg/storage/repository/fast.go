@@ -0,0 +1,36 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"context"
+
+	fastmodel "github.com/ortuman/jackal/pkg/model/fast"
+)
+
+// FastToken defines FAST authentication token repository operations.
+type FastToken interface {
+	// UpsertFastToken upserts a FAST token entity into storage.
+	UpsertFastToken(ctx context.Context, token *fastmodel.Token) error
+
+	// FetchFastTokens retrieves from storage all FAST token entities associated to a user.
+	FetchFastTokens(ctx context.Context, username string) ([]*fastmodel.Token, error)
+
+	// DeleteFastToken removes the FAST token entity issued to a user-agent from storage.
+	DeleteFastToken(ctx context.Context, username, clientID string) error
+
+	// DeleteFastTokens removes all FAST token entities associated to a user.
+	DeleteFastTokens(ctx context.Context, username string) error
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"context"
+
+	invitemodel "github.com/ortuman/jackal/pkg/model/invite"
+)
+
+// Invite defines invite token repository operations.
+type Invite interface {
+	// UpsertInvite upserts an invite entity into storage.
+	UpsertInvite(ctx context.Context, inv *invitemodel.Invite) error
+
+	// FetchInvite retrieves from storage the invite entity associated to a token.
+	FetchInvite(ctx context.Context, token string) (*invitemodel.Invite, error)
+
+	// DeleteInvite removes the invite entity associated to a token from storage.
+	DeleteInvite(ctx context.Context, token string) error
+
+	// DeleteInvites removes all invite entities issued by a user.
+	DeleteInvites(ctx context.Context, inviter string) error
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"context"
+
+	mucmodel "github.com/ortuman/jackal/pkg/model/muc"
+)
+
+// Muc defines multi-user chat room repository operations.
+type Muc interface {
+	// UpsertRoom upserts a MUC room entity into storage.
+	UpsertRoom(ctx context.Context, room *mucmodel.Room) error
+
+	// FetchRoom retrieves from storage the MUC room entity associated to roomJID.
+	FetchRoom(ctx context.Context, roomJID string) (*mucmodel.Room, error)
+
+	// FetchRooms retrieves from storage all MUC room entities hosted by a MUC service.
+	FetchRooms(ctx context.Context, host string) ([]*mucmodel.Room, error)
+
+	// DeleteRoom removes a MUC room entity from storage.
+	DeleteRoom(ctx context.Context, roomJID string) error
+
+	// UpsertRoomAffiliation upserts a MUC room affiliation entity into storage.
+	UpsertRoomAffiliation(ctx context.Context, aff *mucmodel.Affiliation) error
+
+	// FetchRoomAffiliations retrieves from storage all affiliation entities associated to a MUC room.
+	FetchRoomAffiliations(ctx context.Context, roomJID string) ([]*mucmodel.Affiliation, error)
+
+	// DeleteRoomAffiliation removes a MUC room affiliation entity from storage.
+	DeleteRoomAffiliation(ctx context.Context, roomJID, jid string) error
+
+	// DeleteRoomAffiliations removes all affiliation entities associated to a MUC room.
+	DeleteRoomAffiliations(ctx context.Context, roomJID string) error
+}
@@ -16,8 +16,10 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackal-xmpp/stravaganza"
+	offlinemodel "github.com/ortuman/jackal/pkg/model/offline"
 )
 
 // Offline defines user offline repository operations.
@@ -28,9 +30,28 @@ type Offline interface {
 	// CountOfflineMessages returns current length of user's offline queue.
 	CountOfflineMessages(ctx context.Context, username string) (int, error)
 
+	// CountOfflineBytes returns current size in bytes of user's offline queue.
+	CountOfflineBytes(ctx context.Context, username string) (int, error)
+
 	// FetchOfflineMessages retrieves from repository current user offline queue.
 	FetchOfflineMessages(ctx context.Context, username string) ([]*stravaganza.Message, error)
 
 	// DeleteOfflineMessages clears a user offline queue.
 	DeleteOfflineMessages(ctx context.Context, username string) error
+
+	// FetchOfflineItems retrieves from repository current user offline queue along with each message node identifier.
+	FetchOfflineItems(ctx context.Context, username string) ([]*offlinemodel.Item, error)
+
+	// FetchOfflineItem retrieves a single offline queue message given its node identifier.
+	FetchOfflineItem(ctx context.Context, username, node string) (*offlinemodel.Item, error)
+
+	// DeleteOfflineItem deletes a single offline queue message given its node identifier.
+	DeleteOfflineItem(ctx context.Context, username, node string) error
+
+	// FetchOfflineUsernames returns the usernames of all non-empty offline queues.
+	FetchOfflineUsernames(ctx context.Context) ([]string, error)
+
+	// DeleteOfflineMessagesBefore deletes user's offline messages stored before a given time
+	// and returns the number of deleted messages.
+	DeleteOfflineMessagesBefore(ctx context.Context, username string, before time.Time) (int, error)
 }
@@ -0,0 +1,63 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"context"
+
+	pubsubmodel "github.com/ortuman/jackal/pkg/model/pubsub"
+)
+
+// PubSub defines publish-subscribe node repository operations.
+type PubSub interface {
+	// UpsertNode upserts a pubsub node entity into storage.
+	UpsertNode(ctx context.Context, node *pubsubmodel.Node) error
+
+	// FetchNode retrieves from storage a pubsub node entity.
+	FetchNode(ctx context.Context, host, name string) (*pubsubmodel.Node, error)
+
+	// FetchNodes retrieves from storage all pubsub node entities associated to a host.
+	FetchNodes(ctx context.Context, host string) ([]*pubsubmodel.Node, error)
+
+	// DeleteNode removes a pubsub node entity from storage, along with its items and subscriptions.
+	DeleteNode(ctx context.Context, host, name string) error
+
+	// DeleteNodes removes all pubsub node entities associated to a host.
+	DeleteNodes(ctx context.Context, host string) error
+
+	// UpsertNodeItem upserts a pubsub node item into storage.
+	UpsertNodeItem(ctx context.Context, item *pubsubmodel.Item) error
+
+	// FetchNodeItems retrieves from storage all items associated to a pubsub node, oldest first.
+	FetchNodeItems(ctx context.Context, host, name string) ([]*pubsubmodel.Item, error)
+
+	// DeleteNodeItem removes a pubsub node item from storage.
+	DeleteNodeItem(ctx context.Context, host, name, id string) error
+
+	// DeleteNodeItems removes all items associated to a pubsub node.
+	DeleteNodeItems(ctx context.Context, host, name string) error
+
+	// DeleteOldestNodeItems keeps only the maxItems most recent items associated to a pubsub node.
+	DeleteOldestNodeItems(ctx context.Context, host, name string, maxItems int) error
+
+	// UpsertNodeSubscription upserts a pubsub node subscription into storage.
+	UpsertNodeSubscription(ctx context.Context, sub *pubsubmodel.Subscription) error
+
+	// FetchNodeSubscriptions retrieves from storage all subscriptions associated to a pubsub node.
+	FetchNodeSubscriptions(ctx context.Context, host, name string) ([]*pubsubmodel.Subscription, error)
+
+	// DeleteNodeSubscription removes a pubsub node subscription from storage.
+	DeleteNodeSubscription(ctx context.Context, host, name, jid string) error
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"context"
+
+	pushmodel "github.com/ortuman/jackal/pkg/model/push"
+)
+
+// Push defines storage operations for push notification registrations.
+type Push interface {
+	// UpsertPushRegistration upserts a push registration entity into storage.
+	UpsertPushRegistration(ctx context.Context, reg *pushmodel.Registration) error
+
+	// DeletePushRegistration deletes a push registration entity from storage.
+	DeletePushRegistration(ctx context.Context, username, jid, node string) error
+
+	// FetchPushRegistrations retrieves from storage all push registrations associated to a user.
+	FetchPushRegistrations(ctx context.Context, username string) ([]*pushmodel.Registration, error)
+
+	// DeletePushRegistrations deletes all push registrations associated to a user.
+	DeletePushRegistrations(ctx context.Context, username string) error
+}
@@ -40,6 +40,11 @@ type Transaction interface {
 type baseRepository interface {
 	Archive
 	User
+	FastToken
+	Invite
+	Muc
+	PubSub
+	Push
 	Last
 	Capabilities
 	Offline
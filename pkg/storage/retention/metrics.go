@@ -0,0 +1,67 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import (
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	archiveEntryType = "archive"
+	offlineEntryType = "offline"
+)
+
+var (
+	retentionPurgedEntries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "retention",
+			Name:      "purged_entries_total",
+			Help:      "The total number of purged repository entries.",
+		},
+		[]string{"instance", "type"},
+	)
+	retentionRunDurationBucket = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "jackal",
+			Subsystem: "retention",
+			Name:      "run_duration_bucket",
+			Help:      "Bucketed histogram of retention purge runs duration.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 24),
+		},
+		[]string{"instance"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(retentionPurgedEntries)
+	prometheus.MustRegister(retentionRunDurationBucket)
+}
+
+func reportPurgedEntries(typ string, count int) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+		"type":     typ,
+	}
+	retentionPurgedEntries.With(metricLabel).Add(float64(count))
+}
+
+func reportRunDuration(durationInSecs float64) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+	}
+	retentionRunDurationBucket.With(metricLabel).Observe(durationInSecs)
+}
@@ -0,0 +1,259 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import (
+	"context"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza/jid"
+)
+
+const (
+	purgeLockID = "retention:purge"
+
+	// lockTimeout bounds how long a node waits for the purge lock before skipping a run,
+	// which is expected whenever another cluster node is already purging.
+	lockTimeout = time.Second
+)
+
+// Config contains retention scheduler configuration.
+type Config struct {
+	// Interval defines how often expired entries are purged.
+	Interval time.Duration `fig:"interval" default:"1h"`
+
+	// Archive defines how long archived messages are kept. A zero value keeps them forever.
+	Archive time.Duration `fig:"archive"`
+
+	// Offline defines how long offline messages are kept. A zero value keeps them forever.
+	Offline time.Duration `fig:"offline"`
+
+	// Hosts contains per host policy overrides.
+	Hosts []HostPolicy `fig:"hosts"`
+
+	// Users contains per user policy overrides.
+	Users []UserPolicy `fig:"users"`
+}
+
+// IsEnabled tells whether any retention policy has been configured.
+func (c Config) IsEnabled() bool {
+	return c.Archive > 0 || c.Offline > 0 || len(c.Hosts) > 0 || len(c.Users) > 0
+}
+
+// HostPolicy overrides the archive retention policy of a given domain.
+// Offline queues are not bound to a domain, so they can only be overridden per user.
+type HostPolicy struct {
+	// Domain is the archive domain the policy applies to (e.g. jackal.im or conference.jackal.im).
+	Domain string `fig:"domain"`
+
+	// Archive defines how long archived messages are kept. A zero value keeps them forever.
+	Archive *time.Duration `fig:"archive"`
+}
+
+// UserPolicy overrides the retention policy of a given local user.
+type UserPolicy struct {
+	// Username is the local user the policy applies to.
+	Username string `fig:"username"`
+
+	// Archive defines how long archived messages are kept. A zero value keeps them forever.
+	Archive *time.Duration `fig:"archive"`
+
+	// Offline defines how long offline messages are kept. A zero value keeps them forever.
+	Offline *time.Duration `fig:"offline"`
+}
+
+// Scheduler periodically purges archive and offline messages older than the configured policy.
+//
+// In a clustered deployment every node runs a scheduler, but only the one holding the purge lock
+// performs each run.
+type Scheduler struct {
+	cfg    Config
+	rep    retentionRepository
+	hosts  hosts
+	logger kitlog.Logger
+
+	hostPolicies map[string]HostPolicy
+	userPolicies map[string]UserPolicy
+
+	doneCh chan chan struct{}
+}
+
+// New returns a new initialized retention scheduler instance.
+func New(
+	cfg Config,
+	rep retentionRepository,
+	hosts hosts,
+	logger kitlog.Logger,
+) *Scheduler {
+	hostPolicies := make(map[string]HostPolicy, len(cfg.Hosts))
+	for _, p := range cfg.Hosts {
+		hostPolicies[p.Domain] = p
+	}
+	userPolicies := make(map[string]UserPolicy, len(cfg.Users))
+	for _, p := range cfg.Users {
+		userPolicies[p.Username] = p
+	}
+	return &Scheduler{
+		cfg:          cfg,
+		rep:          rep,
+		hosts:        hosts,
+		logger:       kitlog.With(logger, "service", "retention"),
+		hostPolicies: hostPolicies,
+		userPolicies: userPolicies,
+		doneCh:       make(chan chan struct{}),
+	}
+}
+
+// Start starts retention scheduler.
+func (s *Scheduler) Start(_ context.Context) error {
+	go s.loop()
+
+	level.Info(s.logger).Log("msg", "started retention scheduler", "interval", s.cfg.Interval)
+	return nil
+}
+
+// Stop stops retention scheduler.
+func (s *Scheduler) Stop(_ context.Context) error {
+	ch := make(chan struct{})
+	s.doneCh <- ch
+	<-ch
+
+	level.Info(s.logger).Log("msg", "stopped retention scheduler")
+	return nil
+}
+
+func (s *Scheduler) loop() {
+	interval := s.cfg.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	tc := time.NewTicker(interval)
+	defer tc.Stop()
+
+	for {
+		select {
+		case <-tc.C:
+			if err := s.run(context.Background()); err != nil {
+				level.Warn(s.logger).Log("msg", "failed to purge expired messages", "err", err)
+			}
+
+		case ch := <-s.doneCh:
+			close(ch)
+			return
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context) error {
+	lockCtx, cancel := context.WithTimeout(ctx, lockTimeout)
+	err := s.rep.Lock(lockCtx, purgeLockID)
+	cancel()
+
+	if err != nil {
+		if lockCtx.Err() != nil {
+			level.Debug(s.logger).Log("msg", "purge lock held by another node, skipping run")
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		if err := s.rep.Unlock(ctx, purgeLockID); err != nil {
+			level.Warn(s.logger).Log("msg", "failed to release lock", "err", err)
+		}
+	}()
+
+	t0 := time.Now()
+
+	archiveCount, err := s.purgeArchives(ctx, t0)
+	if err != nil {
+		return err
+	}
+	offlineCount, err := s.purgeOfflineQueues(ctx, t0)
+	if err != nil {
+		return err
+	}
+	reportRunDuration(time.Since(t0).Seconds())
+
+	level.Info(s.logger).Log("msg", "purged expired messages", "archive", archiveCount, "offline", offlineCount)
+	return nil
+}
+
+func (s *Scheduler) purgeArchives(ctx context.Context, now time.Time) (int, error) {
+	archiveIDs, err := s.rep.FetchArchiveIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var total int
+	for _, archiveID := range archiveIDs {
+		maxAge := s.archiveMaxAge(archiveID)
+		if maxAge <= 0 {
+			continue
+		}
+		count, err := s.rep.DeleteArchiveMessagesBefore(ctx, archiveID, now.Add(-maxAge))
+		if err != nil {
+			return total, err
+		}
+		reportPurgedEntries(archiveEntryType, count)
+		total += count
+	}
+	return total, nil
+}
+
+func (s *Scheduler) purgeOfflineQueues(ctx context.Context, now time.Time) (int, error) {
+	usernames, err := s.rep.FetchOfflineUsernames(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var total int
+	for _, username := range usernames {
+		maxAge := s.offlineMaxAge(username)
+		if maxAge <= 0 {
+			continue
+		}
+		count, err := s.rep.DeleteOfflineMessagesBefore(ctx, username, now.Add(-maxAge))
+		if err != nil {
+			return total, err
+		}
+		reportPurgedEntries(offlineEntryType, count)
+		total += count
+	}
+	return total, nil
+}
+
+func (s *Scheduler) archiveMaxAge(archiveID string) time.Duration {
+	archiveJID, err := jid.NewWithString(archiveID, true)
+	if err != nil {
+		return s.cfg.Archive
+	}
+	// user policies only apply to local user archives, not to rooms or remote entities.
+	if s.hosts.IsLocalHost(archiveJID.Domain()) {
+		if p, ok := s.userPolicies[archiveJID.Node()]; ok && p.Archive != nil {
+			return *p.Archive
+		}
+	}
+	if p, ok := s.hostPolicies[archiveJID.Domain()]; ok && p.Archive != nil {
+		return *p.Archive
+	}
+	return s.cfg.Archive
+}
+
+func (s *Scheduler) offlineMaxAge(username string) time.Duration {
+	if p, ok := s.userPolicies[username]; ok && p.Offline != nil {
+		return *p.Offline
+	}
+	return s.cfg.Offline
+}
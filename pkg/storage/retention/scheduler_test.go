@@ -0,0 +1,123 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_PurgeArchives(t *testing.T) {
+	// given
+	forever := time.Duration(0)
+	week := time.Hour * 24 * 7
+
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.FetchArchiveIDsFunc = func(ctx context.Context) ([]string, error) {
+		return []string{"ortuman@jackal.im", "noelia@jackal.im", "lobby@conference.jackal.im", "ortuman@conference.jackal.im"}, nil
+	}
+	maxAges := make(map[string]time.Duration)
+	repMock.DeleteArchiveMessagesBeforeFunc = func(ctx context.Context, archiveID string, before time.Time) (int, error) {
+		maxAges[archiveID] = time.Since(before).Round(time.Hour)
+		return 1, nil
+	}
+	repMock.FetchOfflineUsernamesFunc = func(ctx context.Context) ([]string, error) { return nil, nil }
+
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+	s := New(Config{
+		Archive: time.Hour * 24 * 90,
+		Hosts: []HostPolicy{
+			{Domain: "conference.jackal.im", Archive: &week},
+		},
+		Users: []UserPolicy{
+			{Username: "ortuman", Archive: &forever},
+		},
+	}, repMock, hostsMock, kitlog.NewNopLogger())
+
+	// when
+	err := s.run(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, repMock.LockCalls(), 1)
+	require.Len(t, repMock.UnlockCalls(), 1)
+	require.Equal(t, map[string]time.Duration{
+		"noelia@jackal.im":             time.Hour * 24 * 90,
+		"lobby@conference.jackal.im":   week,
+		"ortuman@conference.jackal.im": week,
+	}, maxAges)
+}
+
+func TestScheduler_PurgeOfflineQueues(t *testing.T) {
+	// given
+	day := time.Hour * 24
+
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.FetchArchiveIDsFunc = func(ctx context.Context) ([]string, error) { return nil, nil }
+	repMock.FetchOfflineUsernamesFunc = func(ctx context.Context) ([]string, error) {
+		return []string{"ortuman", "noelia"}, nil
+	}
+	maxAges := make(map[string]time.Duration)
+	repMock.DeleteOfflineMessagesBeforeFunc = func(ctx context.Context, username string, before time.Time) (int, error) {
+		maxAges[username] = time.Since(before).Round(time.Hour)
+		return 2, nil
+	}
+
+	s := New(Config{
+		Offline: time.Hour * 24 * 30,
+		Users: []UserPolicy{
+			{Username: "noelia", Offline: &day},
+		},
+	}, repMock, &hostsMock{}, kitlog.NewNopLogger())
+
+	// when
+	err := s.run(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, map[string]time.Duration{
+		"ortuman": time.Hour * 24 * 30,
+		"noelia":  day,
+	}, maxAges)
+}
+
+func TestScheduler_SkipRunWhenLocked(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	s := New(Config{Archive: time.Hour}, repMock, &hostsMock{}, kitlog.NewNopLogger())
+
+	// when
+	err := s.run(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, repMock.UnlockCalls(), 0)
+	require.Len(t, repMock.FetchArchiveIDsCalls(), 0)
+}
@@ -23,6 +23,7 @@ import (
 	measuredrepository "github.com/ortuman/jackal/pkg/storage/measured"
 	pgsqlrepository "github.com/ortuman/jackal/pkg/storage/pgsql"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/storage/retention"
 )
 
 const (
@@ -32,10 +33,11 @@ const (
 
 // Config contains generic storage configuration.
 type Config struct {
-	Type   string                  `fig:"type" default:"boltdb"`
-	PgSQL  pgsqlrepository.Config  `fig:"pgsql"`
-	BoltDB boltdb.Config           `fig:"boltdb"`
-	Cache  cachedrepository.Config `fig:"cache"`
+	Type      string                  `fig:"type" default:"boltdb"`
+	PgSQL     pgsqlrepository.Config  `fig:"pgsql"`
+	BoltDB    boltdb.Config           `fig:"boltdb"`
+	Cache     cachedrepository.Config `fig:"cache"`
+	Retention retention.Config        `fig:"retention"`
 }
 
 // New returns an initialized repository.Repository derived from cfg configuration.
@@ -0,0 +1,450 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/transport/compress"
+	"github.com/ortuman/jackal/pkg/util/ratelimiter"
+	"golang.org/x/time/rate"
+)
+
+const (
+	boshNamespace    = "http://jabber.org/protocol/httpbind"
+	xboshNamespace   = "urn:xmpp:xbosh"
+	framingNamespace = "urn:ietf:params:xml:ns:xmpp-framing"
+	streamNamespace  = "http://etherx.jabber.org/streams"
+
+	boshVersion = "1.11"
+)
+
+var errInvalidBOSHBody = errors.New("transport: invalid BOSH body element")
+
+type boshRequest struct {
+	rid    int64
+	respCh chan []byte
+}
+
+type boshTransport struct {
+	sid        string
+	domain     string
+	wait       time.Duration
+	hold       int
+	inactivity time.Duration
+	polling    time.Duration
+	peerCerts  []*x509.Certificate
+	remoteAddr net.Addr
+	onCloseFn  func(sid string)
+
+	lr *ratelimiter.Reader
+	rd *bufio.Reader
+	wb bytes.Buffer
+
+	mu           sync.Mutex
+	inCond       *sync.Cond
+	in           bytes.Buffer
+	out          [][]byte
+	held         []*boshRequest
+	lastRID      int64
+	pendingBods  map[int64]stravaganza.Element
+	cachedResps  map[int64][]byte
+	lastReqAt    time.Time
+	lastReqEmpty bool
+	created      bool
+	closed       bool
+	inactivityTm *time.Timer
+	keepAliveHnd func()
+}
+
+func newBOSHTransport(
+	sid string,
+	rid int64,
+	domain string,
+	wait time.Duration,
+	hold int,
+	inactivity time.Duration,
+	polling time.Duration,
+	peerCerts []*x509.Certificate,
+	remoteAddr net.Addr,
+	onCloseFn func(sid string),
+) *boshTransport {
+	t := &boshTransport{
+		sid:         sid,
+		domain:      domain,
+		wait:        wait,
+		hold:        hold,
+		inactivity:  inactivity,
+		polling:     polling,
+		peerCerts:   peerCerts,
+		remoteAddr:  remoteAddr,
+		onCloseFn:   onCloseFn,
+		lastRID:     rid - 1,
+		pendingBods: make(map[int64]stravaganza.Element),
+		cachedResps: make(map[int64][]byte),
+	}
+	t.inCond = sync.NewCond(&t.mu)
+	t.lr = ratelimiter.NewReader(readerFunc(t.readIncoming))
+	t.rd = bufio.NewReaderSize(t.lr, readBufferSize)
+	return t
+}
+
+func (t *boshTransport) Read(p []byte) (n int, err error) {
+	return t.rd.Read(p)
+}
+
+func (t *boshTransport) ReadByte() (byte, error) {
+	return t.rd.ReadByte()
+}
+
+func (t *boshTransport) Write(p []byte) (n int, err error) {
+	return t.wb.Write(p)
+}
+
+func (t *boshTransport) WriteString(str string) (int, error) {
+	return t.wb.WriteString(str)
+}
+
+func (t *boshTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	if t.inactivityTm != nil {
+		t.inactivityTm.Stop()
+	}
+	// respond to every held request, delivering any pending payload within a terminate body
+	for i, req := range t.held {
+		var payload [][]byte
+		if i == 0 {
+			payload = t.out
+			t.out = nil
+		}
+		req.respCh <- t.buildBody(payload, true)
+	}
+	t.held = nil
+	t.inCond.Broadcast()
+	t.mu.Unlock()
+
+	if t.onCloseFn != nil {
+		t.onCloseFn(t.sid)
+	}
+	return nil
+}
+
+func (t *boshTransport) Type() Type {
+	return BOSH
+}
+
+func (t *boshTransport) Flush() error {
+	if t.wb.Len() == 0 {
+		return nil
+	}
+	b := make([]byte, t.wb.Len())
+	copy(b, t.wb.Bytes())
+	t.wb.Reset()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return io.ErrClosedPipe
+	}
+	t.out = append(t.out, b)
+	if len(t.held) > 0 {
+		t.respondOldest()
+	}
+	return nil
+}
+
+func (t *boshTransport) SetReadRateLimiter(rLim *rate.Limiter) error {
+	t.lr.SetReadRateLimiter(rLim)
+	return nil
+}
+
+func (t *boshTransport) SetWriteDeadline(_ time.Time) error {
+	return nil
+}
+
+func (t *boshTransport) SetConnectDeadlineHandler(_ func()) {
+	// session creation request has already been received at this point
+}
+
+func (t *boshTransport) SetKeepAliveDeadlineHandler(hnd func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keepAliveHnd = hnd
+}
+
+func (t *boshTransport) StartTLS(_ *tls.Config, _ bool) {
+	// TLS must be negotiated at the HTTP connection level (XEP-0206, section 7)
+}
+
+func (t *boshTransport) EnableCompression(_ compress.Level) {
+	// stream compression is delegated to HTTP content encoding
+}
+
+func (t *boshTransport) SupportsChannelBinding() bool {
+	return false // requests can be sent over several HTTP connections
+}
+
+func (t *boshTransport) ChannelBindingBytes(_ ChannelBindingMechanism) []byte {
+	return nil
+}
+
+func (t *boshTransport) PeerCertificates() []*x509.Certificate {
+	return t.peerCerts
+}
+
+func (t *boshTransport) RemoteAddr() net.Addr {
+	return t.remoteAddr
+}
+
+// handleRequest processes an incoming BOSH request body, blocking until a response is available.
+func (t *boshTransport) handleRequest(body stravaganza.Element) []byte {
+	rid, err := strconv.ParseInt(body.Attribute("rid"), 10, 64)
+	if err != nil {
+		return terminateBody("bad-request")
+	}
+	req := &boshRequest{rid: rid, respCh: make(chan []byte, 1)}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return terminateBody("item-not-found")
+	}
+	switch {
+	case rid <= t.lastRID:
+		// retransmitted request
+		resp, ok := t.cachedResps[rid]
+		t.mu.Unlock()
+		if !ok {
+			return terminateBody("item-not-found")
+		}
+		return resp
+
+	case rid > t.lastRID+int64(t.hold)+1:
+		// request outside of the allowed window (XEP-0124, section 14.2)
+		t.mu.Unlock()
+		_ = t.Close()
+		return terminateBody("item-not-found")
+	}
+	// empty requests sent more frequently than allowed (XEP-0124, section 12)
+	now := time.Now()
+	isEmpty := isEmptyBody(body)
+	if isEmpty && t.lastReqEmpty && len(t.held) >= t.hold && now.Sub(t.lastReqAt) < t.polling {
+		t.mu.Unlock()
+		_ = t.Close()
+		return terminateBody("policy-violation")
+	}
+	t.lastReqAt = now
+	t.lastReqEmpty = isEmpty
+
+	if t.inactivityTm != nil {
+		t.inactivityTm.Stop()
+	}
+	t.pendingBods[rid] = body
+	for {
+		nextBody, ok := t.pendingBods[t.lastRID+1]
+		if !ok {
+			break
+		}
+		t.lastRID++
+		delete(t.pendingBods, t.lastRID)
+		t.processBody(nextBody)
+	}
+	t.held = append(t.held, req)
+	switch {
+	case len(t.out) > 0:
+		t.respondOldest()
+	case len(t.held) > t.hold:
+		t.respondOldest()
+	}
+	t.mu.Unlock()
+
+	var resp []byte
+	select {
+	case resp = <-req.respCh:
+		break
+	case <-time.After(t.wait):
+		t.mu.Lock()
+		if t.removeHeld(req) {
+			resp = t.buildBody(nil, false)
+		} else {
+			resp = <-req.respCh
+		}
+		t.mu.Unlock()
+	}
+
+	t.mu.Lock()
+	t.cachedResps[rid] = resp
+	delete(t.cachedResps, rid-int64(t.hold)-1)
+	if len(t.held) == 0 && !t.closed {
+		t.scheduleInactivityTimeout()
+	}
+	t.mu.Unlock()
+
+	return resp
+}
+
+func (t *boshTransport) processBody(body stravaganza.Element) {
+	switch {
+	case len(body.Attribute("sid")) == 0:
+		// session creation request
+		t.writeIncoming(stravaganza.NewBuilder("open").
+			WithAttribute(stravaganza.Namespace, framingNamespace).
+			WithAttribute(stravaganza.To, body.Attribute(stravaganza.To)).
+			WithAttribute(stravaganza.Version, body.Attribute("xmpp:version")).
+			Build(),
+		)
+
+	case body.Attribute("xmpp:restart") == "true":
+		t.writeIncoming(stravaganza.NewBuilder("open").
+			WithAttribute(stravaganza.Namespace, framingNamespace).
+			WithAttribute(stravaganza.To, t.domain).
+			WithAttribute(stravaganza.Version, "1.0").
+			Build(),
+		)
+
+	default:
+		for _, child := range body.AllChildren() {
+			t.writeIncoming(child)
+		}
+		if body.Attribute(stravaganza.Type) == "terminate" {
+			t.writeIncoming(stravaganza.NewBuilder("close").
+				WithAttribute(stravaganza.Namespace, framingNamespace).
+				Build(),
+			)
+		}
+	}
+}
+
+func (t *boshTransport) writeIncoming(elem stravaganza.Element) {
+	_ = elem.ToXML(&t.in, true)
+	t.inCond.Broadcast()
+}
+
+func (t *boshTransport) readIncoming(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for t.in.Len() == 0 && !t.closed {
+		t.inCond.Wait()
+	}
+	if t.in.Len() == 0 {
+		return 0, io.EOF
+	}
+	return t.in.Read(p)
+}
+
+func (t *boshTransport) respondOldest() {
+	req := t.held[0]
+	t.held = t.held[1:]
+
+	req.respCh <- t.buildBody(t.out, false)
+	t.out = nil
+}
+
+func (t *boshTransport) removeHeld(req *boshRequest) bool {
+	for i, r := range t.held {
+		if r == req {
+			t.held = append(t.held[:i], t.held[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *boshTransport) scheduleInactivityTimeout() {
+	if t.keepAliveHnd == nil {
+		return
+	}
+	t.inactivityTm = time.AfterFunc(t.inactivity, t.keepAliveHnd)
+}
+
+func (t *boshTransport) buildBody(payload [][]byte, terminate bool) []byte {
+	buf := &strings.Builder{}
+	buf.WriteString(`<body`)
+	writeAttribute(buf, "xmlns", boshNamespace)
+	writeAttribute(buf, "xmlns:stream", streamNamespace)
+	if !t.created {
+		// session creation response attributes (XEP-0124, section 7.1)
+		writeAttribute(buf, "xmlns:xmpp", xboshNamespace)
+		writeAttribute(buf, "xmpp:version", "1.0")
+		writeAttribute(buf, "sid", t.sid)
+		writeAttribute(buf, "from", t.domain)
+		writeAttribute(buf, "ver", boshVersion)
+		writeAttribute(buf, "wait", strconv.Itoa(int(t.wait.Seconds())))
+		writeAttribute(buf, "hold", strconv.Itoa(t.hold))
+		writeAttribute(buf, "requests", strconv.Itoa(t.hold+1))
+		writeAttribute(buf, "inactivity", strconv.Itoa(int(t.inactivity.Seconds())))
+		writeAttribute(buf, "polling", strconv.Itoa(int(t.polling.Seconds())))
+		t.created = true
+	}
+	if terminate {
+		writeAttribute(buf, "type", "terminate")
+	}
+	if len(payload) == 0 {
+		buf.WriteString(`/>`)
+		return []byte(buf.String())
+	}
+	buf.WriteString(`>`)
+	for _, b := range payload {
+		buf.Write(b)
+	}
+	buf.WriteString(`</body>`)
+	return []byte(buf.String())
+}
+
+func isEmptyBody(body stravaganza.Element) bool {
+	return body.ChildrenCount() == 0 &&
+		len(body.Attribute("sid")) > 0 &&
+		len(body.Attribute(stravaganza.Type)) == 0 &&
+		len(body.Attribute("xmpp:restart")) == 0
+}
+
+func terminateBody(condition string) []byte {
+	buf := &strings.Builder{}
+	buf.WriteString(`<body`)
+	writeAttribute(buf, "xmlns", boshNamespace)
+	writeAttribute(buf, "type", "terminate")
+	writeAttribute(buf, "condition", condition)
+	buf.WriteString(`/>`)
+	return []byte(buf.String())
+}
+
+func writeAttribute(buf *strings.Builder, name, value string) {
+	buf.WriteString(` ` + name + `='`)
+	_ = xml.EscapeText(buf, []byte(value))
+	buf.WriteString(`'`)
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,225 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	xmppparser "github.com/jackal-xmpp/stravaganza/parser"
+)
+
+// BOSHConfig contains BOSH connection manager configuration.
+type BOSHConfig struct {
+	// MaxWait is the longest time a request will be held before being responded.
+	MaxWait time.Duration
+
+	// MaxHold is the maximum number of requests that can be held simultaneously.
+	MaxHold int
+
+	// Inactivity is the longest allowed time without pending requests before terminating a session.
+	Inactivity time.Duration
+
+	// Polling is the shortest allowed interval between client polling requests.
+	Polling time.Duration
+
+	// MaxRequestSize is the maximum size an incoming request body may have.
+	MaxRequestSize int
+
+	// MaxSessions is the maximum number of simultaneously active sessions. Zero means no limit.
+	MaxSessions int
+
+	// AllowedOrigins contains the set of origins allowed to perform cross-origin requests.
+	AllowedOrigins []string
+}
+
+type localHosts interface {
+	IsLocalHost(h string) bool
+}
+
+// BOSHHandler is an HTTP handler that keeps a pool of BOSH sessions (XEP-0124/XEP-0206),
+// exposing each one of them as a stream transport.
+type BOSHHandler struct {
+	cfg         BOSHConfig
+	hosts       localHosts
+	sessionHndl func(tr Transport)
+
+	mu       sync.RWMutex
+	sessions map[string]*boshTransport
+}
+
+// NewBOSHHandler returns a BOSH HTTP handler that will invoke sessionHandler
+// on every newly created session transport addressed to any of the local hosts.
+func NewBOSHHandler(cfg BOSHConfig, hosts localHosts, sessionHandler func(tr Transport)) *BOSHHandler {
+	return &BOSHHandler{
+		cfg:         cfg,
+		hosts:       hosts,
+		sessionHndl: sessionHandler,
+		sessions:    make(map[string]*boshTransport),
+	}
+}
+
+// ServeHTTP satisfies http.Handler interface.
+func (h *BOSHHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.setCORSHeaders(w, r) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+		return
+	case http.MethodPost:
+		break
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := h.readBody(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var resp []byte
+
+	sid := body.Attribute("sid")
+	if len(sid) == 0 {
+		resp = h.createSession(r, body)
+	} else {
+		h.mu.RLock()
+		tr := h.sessions[sid]
+		h.mu.RUnlock()
+
+		if tr != nil {
+			resp = tr.handleRequest(body)
+		} else {
+			resp = terminateBody("item-not-found")
+		}
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	_, _ = w.Write(resp)
+}
+
+// Len returns the number of active BOSH sessions.
+func (h *BOSHHandler) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.sessions)
+}
+
+func (h *BOSHHandler) createSession(r *http.Request, body stravaganza.Element) []byte {
+	rid, err := strconv.ParseInt(body.Attribute("rid"), 10, 64)
+	if err != nil {
+		return terminateBody("bad-request")
+	}
+	domain := body.Attribute(stravaganza.To)
+	if len(domain) == 0 {
+		return terminateBody("improper-addressing")
+	}
+	if !h.hosts.IsLocalHost(domain) {
+		return terminateBody("host-unknown")
+	}
+	wait := h.cfg.MaxWait
+	if v, err := strconv.Atoi(body.Attribute("wait")); err == nil && time.Duration(v) < wait/time.Second {
+		if v < 0 {
+			v = 0
+		}
+		wait = time.Duration(v) * time.Second
+	}
+	hold := h.cfg.MaxHold
+	if v, err := strconv.Atoi(body.Attribute("hold")); err == nil && v < hold {
+		if v < 0 {
+			v = 0
+		}
+		hold = v
+	}
+	var peerCerts []*x509.Certificate
+	if r.TLS != nil {
+		peerCerts = r.TLS.PeerCertificates
+	}
+	var remoteAddr net.Addr
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		remoteAddr = addr
+	}
+	tr := newBOSHTransport(
+		uuid.New().String(),
+		rid,
+		domain,
+		wait,
+		hold,
+		h.cfg.Inactivity,
+		h.cfg.Polling,
+		peerCerts,
+		remoteAddr,
+		h.removeSession,
+	)
+	h.mu.Lock()
+	if h.cfg.MaxSessions > 0 && len(h.sessions) >= h.cfg.MaxSessions {
+		h.mu.Unlock()
+		return terminateBody("policy-violation")
+	}
+	h.sessions[tr.sid] = tr
+	h.mu.Unlock()
+
+	go h.sessionHndl(tr)
+
+	return tr.handleRequest(body)
+}
+
+func (h *BOSHHandler) removeSession(sid string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.sessions, sid)
+}
+
+func (h *BOSHHandler) readBody(r *http.Request) (stravaganza.Element, error) {
+	rd := io.Reader(r.Body)
+	if h.cfg.MaxRequestSize > 0 {
+		rd = io.LimitReader(r.Body, int64(h.cfg.MaxRequestSize))
+	}
+	elem, err := xmppparser.New(rd, xmppparser.DefaultMode, h.cfg.MaxRequestSize).Parse()
+	if err != nil {
+		return nil, err
+	}
+	if elem.Name() != "body" || elem.Attribute(stravaganza.Namespace) != boshNamespace {
+		return nil, errInvalidBOSHBody
+	}
+	return elem, nil
+}
+
+func (h *BOSHHandler) setCORSHeaders(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 || len(h.cfg.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowedOrigin := range h.cfg.AllowedOrigins {
+		if origin != allowedOrigin {
+			continue
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Max-Age", "86400")
+		return true
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	xmppparser "github.com/jackal-xmpp/stravaganza/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBOSH(t *testing.T) {
+	// given
+	trCh := make(chan Transport, 1)
+
+	h := NewBOSHHandler(BOSHConfig{
+		MaxWait:        time.Second * 5,
+		MaxHold:        1,
+		Inactivity:     time.Minute,
+		Polling:        time.Second * 2,
+		MaxRequestSize: 4096,
+	}, testBOSHHosts{}, func(tr Transport) {
+		trCh <- tr
+	})
+
+	// when
+	respCh := postBOSHBody(h, `<body content='text/xml; charset=utf-8' hold='1' rid='1000' to='jackal.im' wait='60' xml:lang='en' xmpp:version='1.0' xmlns='http://jabber.org/protocol/httpbind' xmlns:xmpp='urn:xmpp:xbosh'/>`)
+
+	tr := <-trCh
+	pr := xmppparser.New(tr, xmppparser.DefaultMode, 4096)
+
+	openElem, err := pr.Parse()
+	require.Nil(t, err)
+
+	_, _ = io.WriteString(tr, `<stream:features/>`)
+	require.Nil(t, tr.Flush())
+
+	resp := <-respCh
+
+	// then
+	require.Equal(t, BOSH, tr.Type())
+	require.Equal(t, 1, h.Len())
+
+	require.Equal(t, "open", openElem.Name())
+	require.Equal(t, "jackal.im", openElem.Attribute(stravaganza.To))
+	require.Equal(t, "1.0", openElem.Attribute(stravaganza.Version))
+
+	require.Contains(t, resp, `from='jackal.im'`)
+	require.Contains(t, resp, `wait='5'`)
+	require.Contains(t, resp, `hold='1'`)
+	require.Contains(t, resp, `<stream:features/></body>`)
+
+	sid := regexp.MustCompile(`sid='([^']+)'`).FindStringSubmatch(resp)[1]
+
+	// when
+	respCh = postBOSHBody(h, `<body rid='1001' sid='`+sid+`' xmlns='http://jabber.org/protocol/httpbind'><message xmlns='jabber:client' to='noelia@jackal.im'><body>Hi!</body></message></body>`)
+
+	msgElem, err := pr.Parse()
+	require.Nil(t, err)
+
+	_, _ = io.WriteString(tr, `<message from='noelia@jackal.im'/>`)
+	require.Nil(t, tr.Flush())
+
+	resp = <-respCh
+
+	// then
+	require.Equal(t, "message", msgElem.Name())
+	require.Equal(t, `<body xmlns='http://jabber.org/protocol/httpbind' xmlns:stream='http://etherx.jabber.org/streams'><message from='noelia@jackal.im'/></body>`, resp)
+
+	// when
+	respCh = postBOSHBody(h, `<body rid='1001' sid='`+sid+`' xmlns='http://jabber.org/protocol/httpbind'/>`)
+
+	// then
+	require.Equal(t, resp, <-respCh) // retransmitted request
+
+	// when
+	respCh = postBOSHBody(h, `<body rid='1002' sid='`+sid+`' type='terminate' xmlns='http://jabber.org/protocol/httpbind'/>`)
+
+	closeElem, err := pr.Parse()
+	require.Nil(t, err)
+
+	require.Nil(t, tr.Close())
+
+	resp = <-respCh
+
+	// then
+	require.Equal(t, "close", closeElem.Name())
+	require.Equal(t, `<body xmlns='http://jabber.org/protocol/httpbind' xmlns:stream='http://etherx.jabber.org/streams' type='terminate'/>`, resp)
+	require.Equal(t, 0, h.Len())
+
+	_, err = pr.Parse()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestBOSH_UnknownSession(t *testing.T) {
+	// given
+	h := NewBOSHHandler(BOSHConfig{MaxWait: time.Second, MaxHold: 1}, testBOSHHosts{}, func(_ Transport) {})
+
+	// when
+	resp := <-postBOSHBody(h, `<body rid='1001' sid='foo' xmlns='http://jabber.org/protocol/httpbind'/>`)
+
+	// then
+	require.Equal(t, `<body xmlns='http://jabber.org/protocol/httpbind' type='terminate' condition='item-not-found'/>`, resp)
+}
+
+func TestBOSH_EmptyResponseOnWaitExpiration(t *testing.T) {
+	// given
+	trCh := make(chan Transport, 1)
+
+	h := NewBOSHHandler(BOSHConfig{MaxWait: time.Millisecond * 250, MaxHold: 1, Inactivity: time.Minute}, testBOSHHosts{}, func(tr Transport) {
+		trCh <- tr
+	})
+
+	// when
+	respCh := postBOSHBody(h, `<body hold='1' rid='1' to='jackal.im' wait='60' xmpp:version='1.0' xmlns='http://jabber.org/protocol/httpbind' xmlns:xmpp='urn:xmpp:xbosh'/>`)
+	<-trCh
+
+	resp := <-respCh
+
+	// then
+	require.True(t, strings.HasSuffix(resp, `polling='0'/>`))
+}
+
+func TestBOSH_NegativeWaitAndHold(t *testing.T) {
+	// given
+	trCh := make(chan Transport, 1)
+
+	h := NewBOSHHandler(BOSHConfig{MaxWait: time.Second * 5, MaxHold: 1, Inactivity: time.Minute}, testBOSHHosts{}, func(tr Transport) {
+		trCh <- tr
+	})
+
+	// when
+	respCh := postBOSHBody(h, `<body hold='-1' rid='1' to='jackal.im' wait='-10' xmpp:version='1.0' xmlns='http://jabber.org/protocol/httpbind' xmlns:xmpp='urn:xmpp:xbosh'/>`)
+	<-trCh
+
+	resp := <-respCh
+
+	// then
+	require.Contains(t, resp, `wait='0'`)
+	require.Contains(t, resp, `hold='0'`)
+}
+
+func TestBOSH_UnknownHost(t *testing.T) {
+	// given
+	var created bool
+	h := NewBOSHHandler(BOSHConfig{MaxWait: time.Second, MaxHold: 1}, testBOSHHosts{}, func(_ Transport) {
+		created = true
+	})
+
+	// when
+	resp := <-postBOSHBody(h, `<body rid='1' to="x'&gt;&lt;script/&gt;" wait='60' xmlns='http://jabber.org/protocol/httpbind'/>`)
+
+	// then
+	require.False(t, created)
+	require.Equal(t, 0, h.Len())
+	require.Equal(t, `<body xmlns='http://jabber.org/protocol/httpbind' type='terminate' condition='host-unknown'/>`, resp)
+}
+
+func TestBOSH_EscapedAttributes(t *testing.T) {
+	// given
+	tr := newBOSHTransport("sid", 1, "x'><script/>", time.Second, 1, time.Minute, 0, nil, nil, nil)
+
+	// when
+	b := string(tr.buildBody(nil, false))
+
+	// then
+	require.Contains(t, b, `from='x&#39;&gt;&lt;script/&gt;'`)
+	require.NotContains(t, b, `<script/>`)
+}
+
+func TestBOSH_MaxSessions(t *testing.T) {
+	// given
+	h := NewBOSHHandler(BOSHConfig{MaxWait: time.Millisecond * 100, MaxHold: 1, Inactivity: time.Minute, MaxSessions: 1}, testBOSHHosts{}, func(_ Transport) {})
+
+	// when
+	resp1 := <-postBOSHBody(h, `<body hold='1' rid='1' to='jackal.im' wait='60' xmlns='http://jabber.org/protocol/httpbind'/>`)
+	resp2 := <-postBOSHBody(h, `<body hold='1' rid='1' to='jackal.im' wait='60' xmlns='http://jabber.org/protocol/httpbind'/>`)
+
+	// then
+	require.Contains(t, resp1, `sid='`)
+	require.Equal(t, `<body xmlns='http://jabber.org/protocol/httpbind' type='terminate' condition='policy-violation'/>`, resp2)
+	require.Equal(t, 1, h.Len())
+}
+
+func TestBOSH_PollingTooFrequently(t *testing.T) {
+	// given
+	h := NewBOSHHandler(BOSHConfig{MaxWait: time.Second, MaxHold: 0, Inactivity: time.Minute, Polling: time.Minute}, testBOSHHosts{}, func(_ Transport) {})
+
+	resp := <-postBOSHBody(h, `<body hold='0' rid='1' to='jackal.im' wait='60' xmlns='http://jabber.org/protocol/httpbind'/>`)
+	sid := regexp.MustCompile(`sid='([^']+)'`).FindStringSubmatch(resp)[1]
+
+	// when
+	resp1 := <-postBOSHBody(h, `<body rid='2' sid='`+sid+`' xmlns='http://jabber.org/protocol/httpbind'/>`)
+	resp2 := <-postBOSHBody(h, `<body rid='3' sid='`+sid+`' xmlns='http://jabber.org/protocol/httpbind'/>`)
+
+	// then
+	require.Equal(t, `<body xmlns='http://jabber.org/protocol/httpbind' xmlns:stream='http://etherx.jabber.org/streams'/>`, resp1)
+	require.Equal(t, `<body xmlns='http://jabber.org/protocol/httpbind' type='terminate' condition='policy-violation'/>`, resp2)
+	require.Equal(t, 0, h.Len())
+}
+
+func postBOSHBody(h http.Handler, body string) <-chan string {
+	respCh := make(chan string, 1)
+	go func() {
+		req := httptest.NewRequest(http.MethodPost, "/http-bind", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		respCh <- rec.Body.String()
+	}()
+	return respCh
+}
+
+type testBOSHHosts struct{}
+
+func (testBOSHHosts) IsLocalHost(h string) bool { return h == "jackal.im" }
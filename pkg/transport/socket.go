@@ -205,6 +205,10 @@ func (s *socketTransport) PeerCertificates() []*x509.Certificate {
 	return st.PeerCertificates
 }
 
+func (s *socketTransport) RemoteAddr() net.Addr {
+	return s.conn.RemoteAddr()
+}
+
 func (s *socketTransport) grabBuffWriter() {
 	if s.bw != nil {
 		return
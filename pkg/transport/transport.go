@@ -18,6 +18,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"io"
+	"net"
 	"time"
 
 	"github.com/ortuman/jackal/pkg/transport/compress"
@@ -30,6 +31,12 @@ type Type int
 const (
 	// Socket represents a socket transport type.
 	Socket Type = iota + 1
+
+	// WebSocket represents a websocket transport type.
+	WebSocket
+
+	// BOSH represents a BOSH transport type.
+	BOSH
 )
 
 // String returns TransportType string representation.
@@ -37,6 +44,10 @@ func (tt Type) String() string {
 	switch tt {
 	case Socket:
 		return "socket"
+	case WebSocket:
+		return "websocket"
+	case BOSH:
+		return "bosh"
 	}
 	return ""
 }
@@ -91,6 +102,9 @@ type Transport interface {
 
 	// PeerCertificates returns the certificate chain presented by remote peer.
 	PeerCertificates() []*x509.Certificate
+
+	// RemoteAddr returns the remote peer network address.
+	RemoteAddr() net.Addr
 }
 
 type tlsStateQueryable interface {
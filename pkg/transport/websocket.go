@@ -0,0 +1,212 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ortuman/jackal/pkg/transport/compress"
+	"github.com/ortuman/jackal/pkg/util/ratelimiter"
+	"golang.org/x/time/rate"
+)
+
+const webSocketCloseTimeout = time.Second
+
+type webSocketTransport struct {
+	wsConn *webSocketConn
+	conn   *deadlineConn
+	lr     *ratelimiter.Reader
+	rd     *bufio.Reader
+	wb     bytes.Buffer
+}
+
+// NewWebSocketTransport creates a WebSocket class stream transport.
+func NewWebSocketTransport(conn *websocket.Conn, connectTimeout, keepAliveTimeout time.Duration) Transport {
+	wsConn := &webSocketConn{conn: conn}
+	dConn := newDeadlineConn(wsConn, connectTimeout, keepAliveTimeout)
+	lr := ratelimiter.NewReader(dConn)
+	return &webSocketTransport{
+		wsConn: wsConn,
+		conn:   dConn,
+		lr:     lr,
+		rd:     bufio.NewReaderSize(lr, readBufferSize),
+	}
+}
+
+func (w *webSocketTransport) Read(p []byte) (n int, err error) {
+	return w.rd.Read(p)
+}
+
+func (w *webSocketTransport) ReadByte() (byte, error) {
+	return w.rd.ReadByte()
+}
+
+func (w *webSocketTransport) Write(p []byte) (n int, err error) {
+	return w.wb.Write(p)
+}
+
+func (w *webSocketTransport) WriteString(str string) (int, error) {
+	return w.wb.WriteString(str)
+}
+
+func (w *webSocketTransport) Close() error {
+	_ = w.wsConn.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(webSocketCloseTimeout),
+	)
+	return w.conn.Close()
+}
+
+func (w *webSocketTransport) Type() Type {
+	return WebSocket
+}
+
+func (w *webSocketTransport) Flush() error {
+	if w.wb.Len() == 0 {
+		return errNoWriteFlush
+	}
+	// every flushed buffer is sent as a single WebSocket message (RFC 7395, section 3.3.3)
+	_, err := w.conn.Write(w.wb.Bytes())
+	w.wb.Reset()
+	return err
+}
+
+func (w *webSocketTransport) SetReadRateLimiter(rLim *rate.Limiter) error {
+	w.lr.SetReadRateLimiter(rLim)
+	return nil
+}
+
+func (w *webSocketTransport) SetWriteDeadline(d time.Time) error {
+	return w.conn.SetWriteDeadline(d)
+}
+
+func (w *webSocketTransport) SetConnectDeadlineHandler(hnd func()) {
+	w.conn.setConnectDeadlineHandler(hnd)
+}
+
+func (w *webSocketTransport) SetKeepAliveDeadlineHandler(hnd func()) {
+	w.conn.setReadDeadlineHandler(hnd)
+}
+
+func (w *webSocketTransport) StartTLS(_ *tls.Config, _ bool) {
+	// TLS must be negotiated at the WebSocket connection level (RFC 7395, section 3.5)
+}
+
+func (w *webSocketTransport) EnableCompression(_ compress.Level) {
+	// stream compression is not allowed over WebSocket (RFC 7395, section 3.6)
+}
+
+func (w *webSocketTransport) SupportsChannelBinding() bool {
+	_, ok := w.wsConn.netConn().(tlsStateQueryable)
+	return ok
+}
+
+func (w *webSocketTransport) ChannelBindingBytes(mechanism ChannelBindingMechanism) []byte {
+	conn, ok := w.wsConn.netConn().(tlsStateQueryable)
+	if !ok {
+		return nil
+	}
+	switch mechanism {
+	case TLSUnique:
+		connSt := conn.ConnectionState()
+		return connSt.TLSUnique
+	case TLSExporter:
+		connSt := conn.ConnectionState()
+		ekm, err := connSt.ExportKeyingMaterial("EXPORTER-Channel-Binding", nil, 32)
+		if err != nil {
+			return nil
+		}
+		return ekm
+	default:
+		break
+	}
+	return nil
+}
+
+func (w *webSocketTransport) PeerCertificates() []*x509.Certificate {
+	conn, ok := w.wsConn.netConn().(tlsStateQueryable)
+	if !ok {
+		return nil
+	}
+	st := conn.ConnectionState()
+	return st.PeerCertificates
+}
+
+func (w *webSocketTransport) RemoteAddr() net.Addr {
+	return w.wsConn.RemoteAddr()
+}
+
+// webSocketConn adapts a WebSocket connection to the net.Conn interface,
+// exposing incoming messages as a contiguous byte stream.
+type webSocketConn struct {
+	conn *websocket.Conn
+	rd   io.Reader
+}
+
+func (c *webSocketConn) Read(p []byte) (n int, err error) {
+	for {
+		if c.rd == nil {
+			_, rd, err := c.conn.NextReader()
+			if err != nil {
+				if _, ok := err.(*websocket.CloseError); ok {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+			c.rd = rd
+		}
+		n, err = c.rd.Read(p)
+		if err == io.EOF {
+			c.rd = nil // message fully read
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *webSocketConn) Write(p []byte) (n int, err error) {
+	if err := c.conn.WriteMessage(websocket.TextMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *webSocketConn) Close() error                       { return c.conn.Close() }
+func (c *webSocketConn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
+func (c *webSocketConn) RemoteAddr() net.Addr               { return c.conn.RemoteAddr() }
+func (c *webSocketConn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *webSocketConn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+func (c *webSocketConn) SetDeadline(t time.Time) error {
+	if err := c.conn.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.conn.SetWriteDeadline(t)
+}
+
+func (c *webSocketConn) netConn() net.Conn {
+	return c.conn.UnderlyingConn()
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ortuman/jackal/pkg/transport/compress"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocket(t *testing.T) {
+	// given
+	trCh := make(chan Transport, 1)
+
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		trCh <- NewWebSocketTransport(conn, time.Minute, time.Minute)
+	}))
+	defer srv.Close()
+
+	cliConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.Nil(t, err)
+	defer func() { _ = cliConn.Close() }()
+
+	tr := <-trCh
+
+	// when
+	str := `<open xmlns="urn:ietf:params:xml:ns:xmpp-framing" to="jackal.im" version="1.0"/>`
+	_, _ = io.WriteString(tr, str)
+	require.Nil(t, tr.Flush())
+
+	_ = cliConn.WriteMessage(websocket.TextMessage, []byte(`<elem xmlns="exodus:ns">`))
+	_ = cliConn.WriteMessage(websocket.TextMessage, []byte(`</elem>`))
+
+	// then
+	mt, msg, err := cliConn.ReadMessage()
+	require.Nil(t, err)
+	require.Equal(t, websocket.TextMessage, mt)
+	require.Equal(t, str, string(msg))
+
+	buf := make([]byte, 4096)
+	n, err := io.ReadAtLeast(tr, buf, len(`<elem xmlns="exodus:ns"></elem>`))
+	require.Nil(t, err)
+	require.Equal(t, `<elem xmlns="exodus:ns"></elem>`, string(buf[:n]))
+
+	require.Equal(t, WebSocket, tr.Type())
+	require.Equal(t, errNoWriteFlush, tr.Flush())
+
+	tr.EnableCompression(compress.BestCompression)
+	require.False(t, tr.SupportsChannelBinding())
+	require.Nil(t, tr.ChannelBindingBytes(TLSExporter))
+	require.Nil(t, tr.PeerCertificates())
+
+	// peer close
+	_ = cliConn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+	_, err = tr.Read(buf)
+	require.Equal(t, io.EOF, err)
+
+	require.Nil(t, tr.Close())
+}
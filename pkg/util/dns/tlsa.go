@@ -0,0 +1,293 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TLSA certificate usages (RFC 7218).
+const (
+	PKIXTA = 0
+	PKIXEE = 1
+	DANETA = 2
+	DANEEE = 3
+)
+
+// TLSA selectors (RFC 7218).
+const (
+	SelectorCert = 0
+	SelectorSPKI = 1
+)
+
+// TLSA matching types (RFC 7218).
+const (
+	MatchingFull   = 0
+	MatchingSHA256 = 1
+	MatchingSHA512 = 2
+)
+
+const (
+	typeTLSA = dnsmessage.Type(52)
+
+	maxUDPPayloadSize = 4096
+	resolvConfFile    = "/etc/resolv.conf"
+)
+
+var errBadTLSARecord = errors.New("bad TLSA record format")
+
+// TLSARecord represents a DNS TLSA resource record (RFC 6698).
+type TLSARecord struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+// Matches tells whether cert matches TLSA record selector and association data.
+func (r TLSARecord) Matches(cert *x509.Certificate) bool {
+	var b []byte
+	switch r.Selector {
+	case SelectorCert:
+		b = cert.Raw
+	case SelectorSPKI:
+		b = cert.RawSubjectPublicKeyInfo
+	default:
+		return false
+	}
+	switch r.MatchingType {
+	case MatchingFull:
+		break
+	case MatchingSHA256:
+		h := sha256.Sum256(b)
+		b = h[:]
+	case MatchingSHA512:
+		h := sha512.Sum512(b)
+		b = h[:]
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare(b, r.Data) == 1
+}
+
+// TLSAResolver defines TLSA record lookup interface.
+type TLSAResolver interface {
+	// LookupTLSA returns the TLSA records associated to a host service, and whether or not
+	// the answer has been authenticated by a DNSSEC validating resolver.
+	LookupTLSA(ctx context.Context, port int, proto, host string) (records []TLSARecord, secure bool, err error)
+}
+
+type tlsaResolver struct {
+	server     string
+	exchangeFn func(ctx context.Context, network string, msg []byte) ([]byte, error)
+}
+
+// NewTLSAResolver returns a TLSAResolver that queries server nameserver.
+// Since DNSSEC validation is delegated to server, it should be a trusted validating resolver,
+// ideally running on the local host.
+// In case server is empty, first system configured nameserver will be used.
+func NewTLSAResolver(server string) TLSAResolver {
+	if len(server) == 0 {
+		server = systemNameserver()
+	}
+	r := &tlsaResolver{server: server}
+	r.exchangeFn = r.exchange
+	return r
+}
+
+func (r *tlsaResolver) LookupTLSA(ctx context.Context, port int, proto, host string) ([]TLSARecord, bool, error) {
+	name, err := dnsmessage.NewName(fmt.Sprintf("_%d._%s.%s.", port, proto, strings.TrimSuffix(host, ".")))
+	if err != nil {
+		return nil, false, err
+	}
+	q, err := newTLSAQuery(name)
+	if err != nil {
+		return nil, false, err
+	}
+	b, err := r.exchangeFn(ctx, "udp", q)
+	if err != nil {
+		return nil, false, err
+	}
+	var p dnsmessage.Parser
+	hdr, err := p.Start(b)
+	if err != nil {
+		return nil, false, err
+	}
+	if hdr.Truncated { // retry over TCP
+		b, err = r.exchangeFn(ctx, "tcp", q)
+		if err != nil {
+			return nil, false, err
+		}
+		hdr, err = p.Start(b)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if hdr.ID != binary.BigEndian.Uint16(q) {
+		return nil, false, errors.New("dns: TLSA lookup response ID mismatch")
+	}
+	switch hdr.RCode {
+	case dnsmessage.RCodeSuccess:
+		break
+	case dnsmessage.RCodeNameError:
+		return nil, hdr.AuthenticData, nil
+	default:
+		return nil, false, fmt.Errorf("dns: TLSA lookup failed: %s", hdr.RCode)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, false, err
+	}
+	var records []TLSARecord
+	for {
+		rh, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if rh.Type != typeTLSA {
+			if err := p.SkipAnswer(); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		res, err := p.UnknownResource()
+		if err != nil {
+			return nil, false, err
+		}
+		rec, err := parseTLSARecord(res.Data)
+		if err != nil {
+			return nil, false, err
+		}
+		records = append(records, rec)
+	}
+	return records, hdr.AuthenticData, nil
+}
+
+func (r *tlsaResolver) exchange(ctx context.Context, network string, msg []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, r.server)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if network == "udp" {
+		if _, err := conn.Write(msg); err != nil {
+			return nil, err
+		}
+		b := make([]byte, maxUDPPayloadSize)
+		n, err := conn.Read(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+	// TCP messages are prefixed with a two byte length field (RFC 1035, section 4.2.2)
+	b := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(b, uint16(len(msg)))
+	copy(b[2:], msg)
+	if _, err := conn.Write(b); err != nil {
+		return nil, err
+	}
+	var l [2]byte
+	if _, err := io.ReadFull(conn, l[:]); err != nil {
+		return nil, err
+	}
+	b = make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(conn, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func newTLSAQuery(name dnsmessage.Name) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:               uint16(rand.Intn(1 << 16)),
+		RecursionDesired: true,
+		AuthenticData:    true, // request AD bit (RFC 6840, section 5.7)
+	})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: name, Type: typeTLSA, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	if err := b.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	var rh dnsmessage.ResourceHeader
+	if err := rh.SetEDNS0(maxUDPPayloadSize, dnsmessage.RCodeSuccess, true); err != nil {
+		return nil, err
+	}
+	if err := b.OPTResource(rh, dnsmessage.OPTResource{}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+func parseTLSARecord(b []byte) (TLSARecord, error) {
+	if len(b) < 4 {
+		return TLSARecord{}, errBadTLSARecord
+	}
+	return TLSARecord{
+		Usage:        b[0],
+		Selector:     b[1],
+		MatchingType: b[2],
+		Data:         b[3:],
+	}, nil
+}
+
+func systemNameserver() string {
+	f, err := os.Open(resolvConfFile)
+	if err != nil {
+		return "127.0.0.1:53"
+	}
+	defer func() { _ = f.Close() }()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if net.ParseIP(fields[1]) == nil {
+			continue
+		}
+		return net.JoinHostPort(fields[1], strconv.Itoa(53))
+	}
+	return "127.0.0.1:53"
+}
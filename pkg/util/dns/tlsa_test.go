@@ -0,0 +1,115 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestTLSAResolver_Lookup(t *testing.T) {
+	// given
+	spkiHash := sha256.Sum256([]byte("spki"))
+
+	var queried dnsmessage.Question
+	r := &tlsaResolver{}
+	r.exchangeFn = func(_ context.Context, network string, msg []byte) ([]byte, error) {
+		var p dnsmessage.Parser
+		_, err := p.Start(msg)
+		require.Nil(t, err)
+		queried, err = p.Question()
+		require.Nil(t, err)
+
+		return testTLSAResponse(t, binary.BigEndian.Uint16(msg), queried, true, append([]byte{DANEEE, SelectorSPKI, MatchingSHA256}, spkiHash[:]...)), nil
+	}
+
+	// when
+	records, secure, err := r.LookupTLSA(context.Background(), 5269, "tcp", "xmpp.jackal.im")
+
+	// then
+	require.Nil(t, err)
+	require.True(t, secure)
+	require.Equal(t, "_5269._tcp.xmpp.jackal.im.", queried.Name.String())
+	require.Equal(t, typeTLSA, queried.Type)
+
+	require.Len(t, records, 1)
+	require.Equal(t, TLSARecord{
+		Usage:        DANEEE,
+		Selector:     SelectorSPKI,
+		MatchingType: MatchingSHA256,
+		Data:         spkiHash[:],
+	}, records[0])
+}
+
+func TestTLSAResolver_LookupInsecure(t *testing.T) {
+	// given
+	r := &tlsaResolver{}
+	r.exchangeFn = func(_ context.Context, network string, msg []byte) ([]byte, error) {
+		var p dnsmessage.Parser
+		_, _ = p.Start(msg)
+		q, _ := p.Question()
+
+		return testTLSAResponse(t, binary.BigEndian.Uint16(msg), q, false, []byte{DANEEE, SelectorCert, MatchingFull, 0x01}), nil
+	}
+
+	// when
+	records, secure, err := r.LookupTLSA(context.Background(), 5269, "tcp", "xmpp.jackal.im")
+
+	// then
+	require.Nil(t, err)
+	require.False(t, secure)
+	require.Len(t, records, 1)
+}
+
+func TestTLSARecord_Matches(t *testing.T) {
+	// given
+	cert := &x509.Certificate{
+		Raw:                     []byte("certificate"),
+		RawSubjectPublicKeyInfo: []byte("spki"),
+	}
+	certHash := sha256.Sum256(cert.Raw)
+	spkiHash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	// then
+	require.True(t, TLSARecord{Selector: SelectorCert, MatchingType: MatchingFull, Data: cert.Raw}.Matches(cert))
+	require.True(t, TLSARecord{Selector: SelectorCert, MatchingType: MatchingSHA256, Data: certHash[:]}.Matches(cert))
+	require.True(t, TLSARecord{Selector: SelectorSPKI, MatchingType: MatchingSHA256, Data: spkiHash[:]}.Matches(cert))
+	require.False(t, TLSARecord{Selector: SelectorSPKI, MatchingType: MatchingSHA256, Data: certHash[:]}.Matches(cert))
+	require.False(t, TLSARecord{Selector: SelectorSPKI, MatchingType: 9, Data: spkiHash[:]}.Matches(cert))
+}
+
+func testTLSAResponse(t *testing.T, id uint16, q dnsmessage.Question, secure bool, rData []byte) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:            id,
+		Response:      true,
+		AuthenticData: secure,
+	})
+	require.Nil(t, b.StartQuestions())
+	require.Nil(t, b.Question(q))
+	require.Nil(t, b.StartAnswers())
+	require.Nil(t, b.UnknownResource(
+		dnsmessage.ResourceHeader{Name: q.Name, Type: typeTLSA, Class: dnsmessage.ClassINET, TTL: 300},
+		dnsmessage.UnknownResource{Type: typeTLSA, Data: rData},
+	))
+	msg, err := b.Finish()
+	require.Nil(t, err)
+	return msg
+}
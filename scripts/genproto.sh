@@ -13,6 +13,7 @@ go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.1
 
 FILES=(
   "admin/v1/users.proto"
+  "admin/v1/invites.proto"
   "c2s/v1/resourceinfo.proto"
   "cluster/v1/cluster.proto"
   "model/v1/archive.proto"
@@ -21,6 +22,11 @@ FILES=(
   "model/v1/blocklist.proto"
   "model/v1/caps.proto"
   "model/v1/roster.proto"
+  "model/v1/fast.proto"
+  "model/v1/invite.proto"
+  "model/v1/muc.proto"
+  "model/v1/pubsub.proto"
+  "model/v1/push.proto"
 )
 
 for file in "${FILES[@]}"; do